@@ -13,6 +13,7 @@ import (
 
 	authHttp "goNexttask/internal/auth/interface/http"
 	ncApp "goNexttask/internal/nc/application"
+	ncConnector "goNexttask/internal/nc/infrastructure/connector"
 	// ncDomain "goNexttask/internal/nc/domain"
 	ncHttp "goNexttask/internal/nc/interface/http"
 	ncInfra "goNexttask/internal/nc/infrastructure"
@@ -69,12 +70,63 @@ func main() {
 	productionRepo := prodInfra.NewPostgresProductionOrderRepository(db)
 	ncProgramRepo := ncInfra.NewPostgresNCProgramRepository(db)
 	machineRepo := ncInfra.NewPostgresMachineRepository(db)
+	machineStatusHistoryRepo := ncInfra.NewPostgresMachineStatusHistoryRepository(db)
+	telemetryRepo := ncInfra.NewPostgresTelemetryRepository(db)
+	connectorConfigRepo := ncInfra.NewPostgresConnectorConfigRepository(db)
+	ncJobRepo := ncInfra.NewPostgresNCJobRepository(db)
+	programPerformanceRepo := ncInfra.NewPostgresProgramPerformanceRepository(db)
+	deploymentRepo := ncInfra.NewPostgresDeploymentRepository(db)
+	toolLibraryRepo := ncInfra.NewPostgresToolLibraryRepository(db)
+	toolMagazineRepo := ncInfra.NewPostgresToolMagazineRepository(db)
+	offsetRepo := ncInfra.NewPostgresOffsetRepository(db)
+	machineAlarmRepo := ncInfra.NewPostgresMachineAlarmRepository(db)
+	alarmCatalogRepo := ncInfra.NewPostgresAlarmCatalogRepository(db)
+	signerKeyRepo := ncInfra.NewPostgresSignerKeyRepository(db)
 	inspectionRepo := qualityInfra.NewPostgresInspectionRepository(db)
+	inspectionPlanRepo := qualityInfra.NewPostgresInspectionPlanRepository(db)
+	controlLimitsRepo := qualityInfra.NewPostgresControlLimitsRepository(db)
+	qualityAlertRepo := qualityInfra.NewPostgresQualityAlertRepository(db)
+	nonconformanceRepo := qualityInfra.NewPostgresNonconformanceRepository(db)
+	capaRepo := qualityInfra.NewPostgresCAPARepository(db)
+	genealogyRepo := qualityInfra.NewPostgresGenealogyRepository(db)
+
+	// Initialize event publishers
+	ncEventPublisher := ncInfra.NewLogEventPublisher()
+	qualityEventPublisher := qualityInfra.NewLogEventPublisher()
 
 	// Initialize use cases
 	productionUseCase := prodApp.NewProductionUseCase(productionRepo)
-	ncUseCase := ncApp.NewNCUseCase(ncProgramRepo, machineRepo)
-	qualityUseCase := qualityApp.NewQualityUseCase(inspectionRepo)
+	ncUseCase := ncApp.NewNCUseCase(ncApp.NCUseCaseDeps{
+		ProgramRepo:     ncProgramRepo,
+		MachineRepo:     machineRepo,
+		HistoryRepo:     machineStatusHistoryRepo,
+		TelemetryRepo:   telemetryRepo,
+		ConnectorRepo:   connectorConfigRepo,
+		JobRepo:         ncJobRepo,
+		DeploymentRepo:  deploymentRepo,
+		ToolRepo:        toolLibraryRepo,
+		MagazineRepo:    toolMagazineRepo,
+		OffsetRepo:      offsetRepo,
+		AlarmRepo:       machineAlarmRepo,
+		CatalogRepo:     alarmCatalogRepo,
+		SignerKeyRepo:   signerKeyRepo,
+		PerformanceRepo: programPerformanceRepo,
+		Transferer:      ncInfra.NewLogProgramTransferer(),
+		OffsetWriter:    ncInfra.NewLogOffsetWriter(),
+		Publisher:       ncEventPublisher,
+	})
+	qualityUseCase := qualityApp.NewQualityUseCase(
+		inspectionRepo,
+		inspectionPlanRepo,
+		controlLimitsRepo,
+		qualityAlertRepo,
+		nonconformanceRepo,
+		capaRepo,
+		genealogyRepo,
+		qualityInfra.NewProductionOrderFeedback(productionUseCase),
+		qualityInfra.NewNCManufacturingHistory(ncUseCase),
+		qualityEventPublisher,
+	)
 
 	// Initialize handlers
 	authHandler := authHttp.NewAuthHandler(db, jwtManager, passwordManager)
@@ -122,6 +174,45 @@ func main() {
 		w.Write([]byte(`{"status":"healthy","database":"connected","tables":"exists"}`))
 	}).Methods("GET")
 
+	// Start background workers
+	workerCtx, stopWorkers := context.WithCancel(context.Background())
+	defer stopWorkers()
+
+	heartbeatMonitor := ncApp.NewHeartbeatMonitor(
+		ncProgramRepo,
+		machineRepo,
+		machineStatusHistoryRepo,
+		ncJobRepo,
+		toolMagazineRepo,
+		ncEventPublisher,
+		getEnvDuration("MACHINE_HEARTBEAT_TIMEOUT", 60*time.Second),
+		getEnvDuration("MACHINE_HEARTBEAT_CHECK_INTERVAL", 15*time.Second),
+	)
+	go heartbeatMonitor.Run(workerCtx)
+
+	capaReminder := qualityApp.NewCAPAReminder(
+		capaRepo,
+		nonconformanceRepo,
+		qualityAlertRepo,
+		qualityEventPublisher,
+		getEnvDuration("CAPA_REMINDER_REPEAT", 24*time.Hour),
+		getEnvDuration("CAPA_REMINDER_CHECK_INTERVAL", time.Hour),
+	)
+	go capaReminder.Run(workerCtx)
+
+	deploymentWorkersDone := make(chan struct{})
+	go func() {
+		ncUseCase.RunDeploymentWorkers(workerCtx)
+		close(deploymentWorkersDone)
+	}()
+
+	connectorManager := ncConnector.NewManager(connectorConfigRepo, ncUseCase)
+	go func() {
+		if err := connectorManager.Run(workerCtx); err != nil {
+			log.Printf("Machine connectors stopped: %v", err)
+		}
+	}()
+
 	// Setup server
 	srv := &http.Server{
 		Addr:         ":" + getEnv("PORT", "8080"),
@@ -145,6 +236,7 @@ func main() {
 	<-quit
 
 	log.Println("Shutting down server...")
+	stopWorkers()
 
 	// Graceful shutdown
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
@@ -154,6 +246,13 @@ func main() {
 		log.Fatalf("Server forced to shutdown: %v", err)
 	}
 
+	// 送信中の展開の状態が記録されるまで待つ
+	select {
+	case <-deploymentWorkersDone:
+	case <-ctx.Done():
+		log.Println("Deployment workers did not stop in time")
+	}
+
 	log.Println("Server exited")
 }
 
@@ -164,6 +263,16 @@ func getEnv(key, defaultValue string) string {
 	return defaultValue
 }
 
+func getEnvDuration(key string, defaultValue time.Duration) time.Duration {
+	if value := os.Getenv(key); value != "" {
+		if d, err := time.ParseDuration(value); err == nil {
+			return d
+		}
+		log.Printf("Invalid duration for %s: %q, using default %s", key, value, defaultValue)
+	}
+	return defaultValue
+}
+
 // Stub implementations for missing repositories
 // These need to be implemented in their respective infrastructure packages
 
@@ -0,0 +1,94 @@
+// mtconnect-stub は記録済みの MTConnect XML を返すローカル検証用エージェント。
+//
+//	go run ./cmd/mtconnect-stub -addr :5000 -dir ./cmd/mtconnect-stub/recordings
+//
+// /current は current.xml を返し、/sample?interval=N は sample_*.xml を
+// ファイル名順に multipart/x-mixed-replace で繰り返し配信する。
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+func main() {
+	addr := flag.String("addr", ":5000", "listen address")
+	dir := flag.String("dir", "cmd/mtconnect-stub/recordings", "directory containing current.xml and sample_*.xml")
+	flag.Parse()
+
+	current, err := os.ReadFile(filepath.Join(*dir, "current.xml"))
+	if err != nil {
+		log.Fatalf("Failed to read current.xml: %v", err)
+	}
+
+	files, err := filepath.Glob(filepath.Join(*dir, "sample_*.xml"))
+	if err != nil || len(files) == 0 {
+		log.Fatalf("No sample_*.xml found in %s", *dir)
+	}
+	sort.Strings(files)
+
+	samples := make([][]byte, len(files))
+	for i, f := range files {
+		if samples[i], err = os.ReadFile(f); err != nil {
+			log.Fatalf("Failed to read %s: %v", f, err)
+		}
+	}
+
+	http.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/current"):
+			writeXML(w, current)
+		case strings.HasSuffix(r.URL.Path, "/sample"):
+			serveSample(w, r, samples)
+		default:
+			http.NotFound(w, r)
+		}
+	})
+
+	log.Printf("MTConnect stub agent listening on %s (%d samples)", *addr, len(samples))
+	log.Fatal(http.ListenAndServe(*addr, nil))
+}
+
+func writeXML(w http.ResponseWriter, body []byte) {
+	w.Header().Set("Content-Type", "application/xml")
+	w.Write(body)
+}
+
+func serveSample(w http.ResponseWriter, r *http.Request, samples [][]byte) {
+	interval, err := strconv.Atoi(r.URL.Query().Get("interval"))
+	if err != nil || interval <= 0 {
+		writeXML(w, samples[0])
+		return
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming not supported", http.StatusInternalServerError)
+		return
+	}
+
+	const boundary = "mtconnect-stub-boundary"
+	w.Header().Set("Content-Type", "multipart/x-mixed-replace;boundary="+boundary)
+
+	for i := 0; ; i++ {
+		body := samples[i%len(samples)]
+		fmt.Fprintf(w, "--%s\r\nContent-Type: application/xml\r\nContent-Length: %d\r\n\r\n", boundary, len(body))
+		w.Write(body)
+		fmt.Fprint(w, "\r\n")
+		flusher.Flush()
+
+		select {
+		case <-r.Context().Done():
+			return
+		case <-time.After(time.Duration(interval) * time.Millisecond):
+		}
+	}
+}
@@ -3,9 +3,15 @@ module goNexttask
 go 1.21
 
 require (
-	github.com/gorilla/mux v1.8.1
 	github.com/golang-jwt/jwt/v5 v5.2.0
-	github.com/lib/pq v1.10.9
 	github.com/google/uuid v1.5.0
+	github.com/gopcua/opcua v0.5.3
+	github.com/gorilla/mux v1.8.1
+	github.com/lib/pq v1.10.9
 	golang.org/x/crypto v0.18.0
-)
\ No newline at end of file
+)
+
+require (
+	github.com/pkg/errors v0.9.1 // indirect
+	golang.org/x/exp v0.0.0-20230522175609-2e198f4a06a1 // indirect
+)
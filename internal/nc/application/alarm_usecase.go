@@ -0,0 +1,198 @@
+package application
+
+import (
+	"context"
+	"goNexttask/internal/nc/domain"
+	"sort"
+	"strings"
+	"time"
+)
+
+type AlarmCatalogEntryInput struct {
+	ControllerModel   string
+	Code              string
+	Description       string
+	RecommendedAction string
+}
+
+type AlarmCatalogEntryOutput struct {
+	ControllerModel   string
+	Code              string
+	Description       string
+	RecommendedAction string
+	UpdatedAt         string
+}
+
+type MachineAlarmsInput struct {
+	MachineID  string
+	From       time.Time
+	To         time.Time
+	Code       string
+	Severity   string
+	ActiveOnly bool
+}
+
+type MachineAlarmOutput struct {
+	ID                string
+	MachineID         string
+	Code              string
+	Severity          string
+	Message           string
+	Source            string
+	JobID             string
+	RaisedAt          string
+	ClearedAt         string
+	DurationSec       float64
+	Description       string
+	RecommendedAction string
+}
+
+// AlarmSummaryOutput はアラーム番号ごとの発生回数と累計時間
+type AlarmSummaryOutput struct {
+	Code             string
+	Severity         string
+	Description      string
+	Occurrences      int
+	TotalDurationSec float64
+	LastRaisedAt     string
+}
+
+type MachineAlarmsOutput struct {
+	MachineID       string
+	ControllerModel string
+	Alarms          []*MachineAlarmOutput
+	Summary         []*AlarmSummaryOutput
+}
+
+// GetMachineAlarms は期間中に発生していたアラームを新しい順に返す。機種のアラームカタログの説明・推奨処置を付け、
+// 繰り返し発生するアラームの分析用にアラーム番号ごとの発生回数を多い順に集計する
+func (uc *NCUseCase) GetMachineAlarms(ctx context.Context, input MachineAlarmsInput) (*MachineAlarmsOutput, error) {
+	machine, err := uc.machineRepo.FindByID(ctx, domain.MachineID(input.MachineID))
+	if err != nil {
+		return nil, err
+	}
+
+	severity := domain.AlarmSeverity(input.Severity)
+	if severity != "" && !severity.IsValid() {
+		return nil, domain.ErrInvalidAlarmFilter
+	}
+
+	alarms, err := uc.alarmRepo.FindByMachineID(ctx, machine.ID, domain.AlarmFilter{
+		From:       input.From,
+		To:         input.To,
+		Code:       strings.ToUpper(strings.TrimSpace(input.Code)),
+		Severity:   severity,
+		ActiveOnly: input.ActiveOnly,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	catalog := map[string]*domain.AlarmCatalogEntry{}
+	if machine.ControllerModel != "" {
+		if catalog, err = uc.catalogRepo.FindByControllerModel(ctx, machine.ControllerModel); err != nil {
+			return nil, err
+		}
+	}
+
+	now := time.Now()
+	output := &MachineAlarmsOutput{
+		MachineID:       string(machine.ID),
+		ControllerModel: machine.ControllerModel,
+		Alarms:          make([]*MachineAlarmOutput, len(alarms)),
+	}
+	summaries := make(map[string]*AlarmSummaryOutput)
+	for i, alarm := range alarms {
+		output.Alarms[i] = convertToMachineAlarmOutput(alarm, catalog[alarm.Code], now)
+
+		summary, ok := summaries[alarm.Key()]
+		if !ok {
+			// 新しい順に並んでいるため最初のアラームが最後の発生
+			summary = &AlarmSummaryOutput{
+				Code:         alarm.Code,
+				Severity:     string(alarm.Severity),
+				Description:  output.Alarms[i].Description,
+				LastRaisedAt: output.Alarms[i].RaisedAt,
+			}
+			if summary.Description == "" {
+				summary.Description = alarm.Message
+			}
+			summaries[alarm.Key()] = summary
+			output.Summary = append(output.Summary, summary)
+		}
+		summary.Occurrences++
+		summary.TotalDurationSec += output.Alarms[i].DurationSec
+	}
+	sort.SliceStable(output.Summary, func(i, j int) bool {
+		return output.Summary[i].Occurrences > output.Summary[j].Occurrences
+	})
+
+	return output, nil
+}
+
+// SaveAlarmCatalogEntry はコントローラー機種のアラーム番号の説明と推奨処置を登録・更新する
+func (uc *NCUseCase) SaveAlarmCatalogEntry(ctx context.Context, input AlarmCatalogEntryInput) (*AlarmCatalogEntryOutput, error) {
+	entry, err := domain.NewAlarmCatalogEntry(input.ControllerModel, input.Code, input.Description, input.RecommendedAction)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := uc.catalogRepo.Save(ctx, entry); err != nil {
+		return nil, err
+	}
+
+	return convertToAlarmCatalogEntryOutput(entry), nil
+}
+
+func (uc *NCUseCase) GetAlarmCatalog(ctx context.Context, controllerModel string) ([]*AlarmCatalogEntryOutput, error) {
+	entries, err := uc.catalogRepo.FindByControllerModel(ctx, controllerModel)
+	if err != nil {
+		return nil, err
+	}
+
+	outputs := make([]*AlarmCatalogEntryOutput, 0, len(entries))
+	for _, entry := range entries {
+		outputs = append(outputs, convertToAlarmCatalogEntryOutput(entry))
+	}
+	sort.Slice(outputs, func(i, j int) bool {
+		return outputs[i].Code < outputs[j].Code
+	})
+
+	return outputs, nil
+}
+
+func (uc *NCUseCase) DeleteAlarmCatalogEntry(ctx context.Context, controllerModel, code string) error {
+	return uc.catalogRepo.Delete(ctx, controllerModel, strings.ToUpper(code))
+}
+
+func convertToMachineAlarmOutput(alarm *domain.MachineAlarm, entry *domain.AlarmCatalogEntry, now time.Time) *MachineAlarmOutput {
+	output := &MachineAlarmOutput{
+		ID:          string(alarm.ID),
+		MachineID:   string(alarm.MachineID),
+		Code:        alarm.Code,
+		Severity:    string(alarm.Severity),
+		Message:     alarm.Message,
+		Source:      alarm.Source,
+		JobID:       string(alarm.JobID),
+		RaisedAt:    alarm.RaisedAt.Format("2006-01-02T15:04:05Z"),
+		DurationSec: alarm.Duration(now).Seconds(),
+	}
+	if alarm.ClearedAt != nil {
+		output.ClearedAt = alarm.ClearedAt.Format("2006-01-02T15:04:05Z")
+	}
+	if entry != nil {
+		output.Description = entry.Description
+		output.RecommendedAction = entry.RecommendedAction
+	}
+	return output
+}
+
+func convertToAlarmCatalogEntryOutput(entry *domain.AlarmCatalogEntry) *AlarmCatalogEntryOutput {
+	return &AlarmCatalogEntryOutput{
+		ControllerModel:   entry.ControllerModel,
+		Code:              entry.Code,
+		Description:       entry.Description,
+		RecommendedAction: entry.RecommendedAction,
+		UpdatedAt:         entry.UpdatedAt.Format("2006-01-02T15:04:05Z"),
+	}
+}
@@ -0,0 +1,160 @@
+package application
+
+import (
+	"context"
+	"goNexttask/internal/nc/domain"
+)
+
+type CompatibilityMatrixInput struct {
+	PartID    string
+	ProgramID string
+	MachineID string
+	// ReleasedOnly はリリース済みのプログラムのみを対象にする
+	ReleasedOnly bool
+}
+
+// ProgramRequirementsOutput はプログラムの宣言とプログラム本体から読み取った要求
+type ProgramRequirementsOutput struct {
+	ProgramID            string
+	Name                 string
+	Version              string
+	PartID               string
+	Status               string
+	MachineCompatibility []string
+	RequiredCapabilities []string
+	Axes                 []string
+	MaxSpindleSpeed      int
+	Options              []string
+	Macros               []string
+}
+
+type MachineCapabilitiesOutput struct {
+	MachineID       string
+	Name            string
+	Type            string
+	Capabilities    []string
+	Axes            []string
+	MaxSpindleSpeed int
+	Options         []string
+	Macros          []string
+}
+
+type CompatibilityIssueOutput struct {
+	Requirement string
+	Required    string
+	Available   string
+	Message     string
+}
+
+type CompatibilityOutput struct {
+	ProgramID  string
+	MachineID  string
+	Compatible bool
+	Mismatches []*CompatibilityIssueOutput
+	Unverified []*CompatibilityIssueOutput
+}
+
+type CompatibilityMatrixOutput struct {
+	Programs []*ProgramRequirementsOutput
+	Machines []*MachineCapabilitiesOutput
+	// Cells はプログラム × 機械の全組み合わせの判定結果（プログラム順、機械順）
+	Cells []*CompatibilityOutput
+}
+
+// GetCompatibilityMatrix はプログラムと稼働中の機械の全組み合わせについて適合を判定し、不適合の理由を返す
+func (uc *NCUseCase) GetCompatibilityMatrix(ctx context.Context, input CompatibilityMatrixInput) (*CompatibilityMatrixOutput, error) {
+	var programs []*domain.NCProgram
+	var err error
+	switch {
+	case input.ProgramID != "":
+		program, err := uc.programRepo.FindByID(ctx, domain.NCProgramID(input.ProgramID))
+		if err != nil {
+			return nil, err
+		}
+		programs = []*domain.NCProgram{program}
+	case input.PartID != "":
+		programs, err = uc.programRepo.FindByPartID(ctx, input.PartID)
+	default:
+		programs, err = uc.programRepo.FindAll(ctx)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var machines []*domain.Machine
+	if input.MachineID != "" {
+		machine, err := uc.machineRepo.FindByID(ctx, domain.MachineID(input.MachineID))
+		if err != nil {
+			return nil, err
+		}
+		machines = []*domain.Machine{machine}
+	} else if machines, err = uc.machineRepo.FindAll(ctx); err != nil {
+		return nil, err
+	}
+
+	output := &CompatibilityMatrixOutput{
+		Programs: []*ProgramRequirementsOutput{},
+		Machines: make([]*MachineCapabilitiesOutput, len(machines)),
+		Cells:    []*CompatibilityOutput{},
+	}
+	for i, machine := range machines {
+		output.Machines[i] = &MachineCapabilitiesOutput{
+			MachineID:       string(machine.ID),
+			Name:            machine.Name,
+			Type:            machine.Type,
+			Capabilities:    machine.Capabilities,
+			Axes:            machine.Control.Axes,
+			MaxSpindleSpeed: machine.MaxSpindleSpeed,
+			Options:         machine.Control.Options,
+			Macros:          machine.Control.Macros,
+		}
+	}
+
+	for _, program := range programs {
+		if input.ReleasedOnly && !program.IsReleased() {
+			continue
+		}
+		requirements := program.Requirements()
+		output.Programs = append(output.Programs, &ProgramRequirementsOutput{
+			ProgramID:            string(program.ID),
+			Name:                 program.Name,
+			Version:              program.Version,
+			PartID:               program.PartID,
+			Status:               string(program.Status),
+			MachineCompatibility: program.MachineCompatibility,
+			RequiredCapabilities: program.RequiredCapabilities,
+			Axes:                 requirements.Axes,
+			MaxSpindleSpeed:      requirements.MaxSpindleSpeed,
+			Options:              requirements.Options,
+			Macros:               requirements.Macros,
+		})
+		for _, machine := range machines {
+			output.Cells = append(output.Cells, convertToCompatibilityOutput(domain.CheckCompatibility(program, machine)))
+		}
+	}
+
+	return output, nil
+}
+
+func convertToCompatibilityOutput(compatibility *domain.Compatibility) *CompatibilityOutput {
+	return &CompatibilityOutput{
+		ProgramID:  string(compatibility.ProgramID),
+		MachineID:  string(compatibility.MachineID),
+		Compatible: compatibility.IsCompatible(),
+		Mismatches: convertToCompatibilityIssueOutputs(compatibility.Mismatches),
+		Unverified: convertToCompatibilityIssueOutputs(compatibility.Unverified),
+	}
+}
+
+func convertToCompatibilityIssueOutputs(issues []domain.CompatibilityIssue) []*CompatibilityIssueOutput {
+	outputs := make([]*CompatibilityIssueOutput, len(issues))
+	for i, issue := range issues {
+		outputs[i] = &CompatibilityIssueOutput{
+			Requirement: issue.Requirement,
+			Required:    issue.Required,
+			Available:   issue.Available,
+			Message:     issue.Message,
+		}
+	}
+	return outputs
+}
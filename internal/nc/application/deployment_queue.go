@@ -0,0 +1,174 @@
+package application
+
+import (
+	"context"
+	"errors"
+	"goNexttask/internal/nc/domain"
+	"log"
+	"sync"
+	"time"
+)
+
+// 1件の展開で送信（再送を含む）に掛けられる時間の上限
+const deploymentTimeout = 10 * time.Minute
+
+// 機械が加工中などで空いていない場合に展開を再実行するまでの間隔
+const machineBusyRetryInterval = 30 * time.Second
+
+// DeploymentQueue は機械ごとの展開待ち行列。機械ごとに1つのワーカーが登録順に実行するため、
+// 同じ機械へ複数のプログラムが同時に送信されることはない。機械が加工中などの間は先頭の展開から待たせる
+type DeploymentQueue struct {
+	transferService *domain.NCTransferService
+	deploymentRepo  domain.DeploymentRepository
+
+	mu sync.Mutex
+	// ctx は Run の ctx。Run が呼ばれるまではワーカーを起動せず待ち行列に積むだけにする
+	ctx     context.Context
+	pending map[domain.MachineID][]domain.DeploymentID
+	active  map[domain.MachineID]bool
+	running map[domain.DeploymentID]context.CancelCauseFunc
+	workers sync.WaitGroup
+}
+
+func newDeploymentQueue(transferService *domain.NCTransferService, deploymentRepo domain.DeploymentRepository) *DeploymentQueue {
+	return &DeploymentQueue{
+		transferService: transferService,
+		deploymentRepo:  deploymentRepo,
+		pending:         make(map[domain.MachineID][]domain.DeploymentID),
+		active:          make(map[domain.MachineID]bool),
+		running:         make(map[domain.DeploymentID]context.CancelCauseFunc),
+	}
+}
+
+// Run は永続化された送信待ちの展開を復元してワーカーを起動し、ctx がキャンセルされ
+// 実行中の展開が終わるまでブロックする。停止時に残った送信待ちは次回の起動時に再開する
+func (q *DeploymentQueue) Run(ctx context.Context) {
+	deployments, err := q.deploymentRepo.FindByStatus(ctx, domain.DeploymentQueued, domain.DeploymentTransferring)
+	if err != nil {
+		// 復元できなくても新しい展開は受け付ける
+		log.Printf("Failed to restore queued deployments: %v", err)
+	}
+
+	q.mu.Lock()
+	for _, deployment := range deployments {
+		if deployment.Status == domain.DeploymentTransferring {
+			// 前回の停止時に送信中だった展開。送信結果が不明なため失敗とし、同じ冪等キーでの再実行に委ねる
+			if err := q.transferService.AbandonDeployment(ctx, deployment, "interrupted by server shutdown"); err != nil {
+				log.Printf("Failed to abandon deployment %s: %v", deployment.ID, err)
+			}
+			continue
+		}
+		q.pending[deployment.MachineID] = append(q.pending[deployment.MachineID], deployment.ID)
+	}
+	q.ctx = ctx
+	for machineID := range q.pending {
+		q.startWorker(machineID)
+	}
+	q.mu.Unlock()
+
+	<-ctx.Done()
+	q.workers.Wait()
+}
+
+// Enqueue は送信待ちの展開を機械の待ち行列の末尾に追加する
+func (q *DeploymentQueue) Enqueue(deployment *domain.ProgramDeployment) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	q.pending[deployment.MachineID] = append(q.pending[deployment.MachineID], deployment.ID)
+	if q.ctx != nil && q.ctx.Err() == nil {
+		q.startWorker(deployment.MachineID)
+	}
+}
+
+// Cancel は展開を取り消す。cause は domain.ErrDeploymentCancelled をラップしたエラー。
+// 送信中の場合は送信を中断させ、状態の記録はワーカーが行う
+func (q *DeploymentQueue) Cancel(ctx context.Context, id domain.DeploymentID, cause error) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if cancel, ok := q.running[id]; ok {
+		cancel(cause)
+		return nil
+	}
+
+	// ワーカーが取り出す前に状態を更新するため、ロックを保持したまま取り消す
+	_, err := q.transferService.CancelDeployment(ctx, id, cause.Error())
+	return err
+}
+
+// startWorker は機械のワーカーが動いていなければ起動する。q.mu を保持して呼ぶこと
+func (q *DeploymentQueue) startWorker(machineID domain.MachineID) {
+	if q.active[machineID] {
+		return
+	}
+	q.active[machineID] = true
+	q.workers.Add(1)
+	go q.work(machineID)
+}
+
+// work は機械の待ち行列が空になるまで展開を1件ずつ実行する
+func (q *DeploymentQueue) work(machineID domain.MachineID) {
+	defer q.workers.Done()
+
+	for {
+		q.mu.Lock()
+		ids := q.pending[machineID]
+		if len(ids) == 0 || q.ctx.Err() != nil {
+			delete(q.active, machineID)
+			q.mu.Unlock()
+			return
+		}
+		id := ids[0]
+		if len(ids) == 1 {
+			delete(q.pending, machineID)
+		} else {
+			q.pending[machineID] = ids[1:]
+		}
+		ctx, cancel := context.WithCancelCause(q.ctx)
+		q.running[id] = cancel
+		q.mu.Unlock()
+
+		err := q.execute(ctx, id)
+
+		q.mu.Lock()
+		delete(q.running, id)
+		busy := errors.Is(err, domain.ErrMachineBusy)
+		if cause := context.Cause(ctx); busy && errors.Is(cause, domain.ErrDeploymentCancelled) {
+			// ErrMachineBusy を返した後に Cancel が実行中の ctx をキャンセルした。送信待ちに戻さず取り消す
+			busy = false
+			if _, err := q.transferService.CancelDeployment(context.WithoutCancel(ctx), id, cause.Error()); err != nil {
+				log.Printf("Failed to cancel deployment %s: %v", id, err)
+			}
+		}
+		if busy {
+			// 送信待ちのまま先頭に戻し、後続の展開も順番を保って待たせる。待つ間の取り消しは Cancel が記録する
+			q.pending[machineID] = append([]domain.DeploymentID{id}, q.pending[machineID]...)
+		}
+		q.mu.Unlock()
+		cancel(nil)
+
+		if busy {
+			select {
+			case <-q.ctx.Done():
+			case <-time.After(machineBusyRetryInterval):
+			}
+		}
+	}
+}
+
+func (q *DeploymentQueue) execute(ctx context.Context, id domain.DeploymentID) error {
+	ctx, cancel := context.WithTimeout(ctx, deploymentTimeout)
+	defer cancel()
+
+	deployment, err := q.transferService.ExecuteDeployment(ctx, id)
+	if errors.Is(err, domain.ErrMachineBusy) {
+		return err
+	}
+	if err != nil {
+		log.Printf("Deployment %s ended with error: %v", id, err)
+		return err
+	}
+	log.Printf("Deployment %s to machine %s %s (job=%s)", deployment.ID, deployment.MachineID, deployment.Status, deployment.JobID)
+	return nil
+}
@@ -0,0 +1,109 @@
+package application
+
+import (
+	"context"
+	"fmt"
+	"goNexttask/internal/nc/domain"
+	"time"
+)
+
+type TransferAttemptOutput struct {
+	Number     int
+	StartedAt  string
+	DurationMs int64
+	BytesSent  int64
+	Error      string
+}
+
+type DeploymentOutput struct {
+	ID                string
+	IdempotencyKey    string
+	MachineID         string
+	ProgramID         string
+	FileHash          string
+	ProductionOrderID string
+	JobID             string
+	Status            string
+	Attempts          []TransferAttemptOutput
+	BytesSent         int64
+	ErrorMessage      string
+	CreatedAt         string
+	CompletedAt       string
+	// Replayed は冪等キーにより既存の展開結果を返した場合 true
+	Replayed bool
+}
+
+// RunDeploymentWorkers は機械ごとの展開ワーカーを ctx がキャンセルされるまで動かす
+func (uc *NCUseCase) RunDeploymentWorkers(ctx context.Context) {
+	uc.deploymentQueue.Run(ctx)
+}
+
+func (uc *NCUseCase) GetDeployment(ctx context.Context, deploymentID string) (*DeploymentOutput, error) {
+	deployment, err := uc.deploymentRepo.FindByID(ctx, domain.DeploymentID(deploymentID))
+	if err != nil {
+		return nil, err
+	}
+
+	return convertToDeploymentOutput(deployment), nil
+}
+
+// CancelDeployment は展開を取り消す。送信中の場合は中断を要求し、取り消しの完了はポーリングで確認する
+func (uc *NCUseCase) CancelDeployment(ctx context.Context, deploymentID, cancelledBy string) (*DeploymentOutput, error) {
+	cause := domain.ErrDeploymentCancelled
+	if cancelledBy != "" {
+		cause = fmt.Errorf("%w by %s", domain.ErrDeploymentCancelled, cancelledBy)
+	}
+	if err := uc.deploymentQueue.Cancel(ctx, domain.DeploymentID(deploymentID), cause); err != nil {
+		return nil, err
+	}
+
+	return uc.GetDeployment(ctx, deploymentID)
+}
+
+func (uc *NCUseCase) GetMachineDeployments(ctx context.Context, machineID string, from, to time.Time) ([]*DeploymentOutput, error) {
+	if _, err := uc.machineRepo.FindByID(ctx, domain.MachineID(machineID)); err != nil {
+		return nil, err
+	}
+
+	deployments, err := uc.deploymentRepo.FindByMachineID(ctx, domain.MachineID(machineID), from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	outputs := make([]*DeploymentOutput, len(deployments))
+	for i, deployment := range deployments {
+		outputs[i] = convertToDeploymentOutput(deployment)
+	}
+
+	return outputs, nil
+}
+
+func convertToDeploymentOutput(deployment *domain.ProgramDeployment) *DeploymentOutput {
+	output := &DeploymentOutput{
+		ID:                string(deployment.ID),
+		IdempotencyKey:    deployment.IdempotencyKey,
+		MachineID:         string(deployment.MachineID),
+		ProgramID:         string(deployment.ProgramID),
+		FileHash:          deployment.FileHash,
+		ProductionOrderID: deployment.ProductionOrderID,
+		JobID:             string(deployment.JobID),
+		Status:            string(deployment.Status),
+		Attempts:          make([]TransferAttemptOutput, len(deployment.Attempts)),
+		BytesSent:         deployment.BytesSent(),
+		ErrorMessage:      deployment.ErrorMessage,
+		CreatedAt:         deployment.CreatedAt.Format("2006-01-02T15:04:05Z"),
+	}
+	for i, attempt := range deployment.Attempts {
+		output.Attempts[i] = TransferAttemptOutput{
+			Number:     attempt.Number,
+			StartedAt:  attempt.StartedAt.Format("2006-01-02T15:04:05Z"),
+			DurationMs: attempt.Duration.Milliseconds(),
+			BytesSent:  attempt.BytesSent,
+			Error:      attempt.Error,
+		}
+	}
+	if deployment.CompletedAt != nil {
+		output.CompletedAt = deployment.CompletedAt.Format("2006-01-02T15:04:05Z")
+	}
+	return output
+}
@@ -0,0 +1,113 @@
+package application
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"goNexttask/internal/nc/domain"
+)
+
+// TranslateProgramInput は方言変換の要求。TargetDialect を省略した場合は TargetMachineID のコントローラーから判定する。
+// TargetMachineID の機械タイプで T ワードの形式（旋盤の T0101 か工具番号のみか）を決める
+type TranslateProgramInput struct {
+	ProgramID       string
+	TargetDialect   string
+	TargetMachineID string
+	// Version は変換版のバージョン。省略時は変換元のバージョンに方言名を付ける
+	Version   string
+	CreatedBy string
+}
+
+type UntranslatedConstructOutput struct {
+	Line      int
+	Text      string
+	Construct string
+	Reason    string
+}
+
+// TranslationOutput は変換で作成したドラフト版と変換結果
+type TranslationOutput struct {
+	Program       *NCProgramOutput
+	SourceDialect string
+	TargetDialect string
+	Translated    map[string]int
+	Untranslated  []UntranslatedConstructOutput
+	// Complete はすべての行を変換できた場合 true。false の場合は承認前に未変換の行を確認する
+	Complete bool
+}
+
+// TranslateProgram はプログラムを別のコントローラー系列の方言へ変換し、変換元を記録したドラフト版として登録する
+func (uc *NCUseCase) TranslateProgram(ctx context.Context, input TranslateProgramInput) (*TranslationOutput, error) {
+	source, err := uc.programRepo.FindByID(ctx, domain.NCProgramID(input.ProgramID))
+	if err != nil {
+		return nil, err
+	}
+
+	target, machineType, err := uc.translationTarget(ctx, input)
+	if err != nil {
+		return nil, err
+	}
+
+	program, report, err := source.Translate(target, machineType, input.Version, input.CreatedBy)
+	if err != nil {
+		return nil, err
+	}
+
+	existing, err := uc.programRepo.FindByNameAndVersion(ctx, program.Name, program.Version)
+	if err != nil && !errors.Is(err, domain.ErrNCProgramNotFound) {
+		return nil, err
+	}
+	if existing != nil {
+		return nil, fmt.Errorf("%w: %s %s (%s)", domain.ErrProgramAlreadyExists, existing.Name, existing.Version, existing.ID)
+	}
+
+	if err := uc.programRepo.Save(ctx, program); err != nil {
+		return nil, err
+	}
+
+	output := &TranslationOutput{
+		Program:       convertToNCProgramOutput(program),
+		SourceDialect: string(report.From),
+		TargetDialect: string(report.To),
+		Translated:    report.Translated,
+		Complete:      report.IsComplete(),
+	}
+	for _, u := range report.Untranslated {
+		output.Untranslated = append(output.Untranslated, UntranslatedConstructOutput{
+			Line:      u.Line,
+			Text:      u.Text,
+			Construct: u.Construct,
+			Reason:    u.Reason,
+		})
+	}
+
+	return output, nil
+}
+
+// translationTarget は変換先の方言と、T ワードの形式を決める変換先の機械タイプ（機械を指定しない場合は空）を返す
+func (uc *NCUseCase) translationTarget(ctx context.Context, input TranslateProgramInput) (domain.Dialect, string, error) {
+	var machine *domain.Machine
+	if input.TargetMachineID != "" {
+		var err error
+		if machine, err = uc.machineRepo.FindByID(ctx, domain.MachineID(input.TargetMachineID)); err != nil {
+			return "", "", err
+		}
+	}
+
+	if input.TargetDialect != "" {
+		dialect, err := domain.ParseDialect(input.TargetDialect)
+		if err != nil || machine == nil {
+			return dialect, "", err
+		}
+		return dialect, machine.Type, nil
+	}
+	if machine == nil {
+		return "", "", fmt.Errorf("%w: target dialect or machine is required", domain.ErrInvalidDialect)
+	}
+
+	dialect := domain.DialectForController(machine.ControllerModel)
+	if dialect == "" {
+		return "", "", fmt.Errorf("%w: controller %q of machine %s", domain.ErrInvalidDialect, machine.ControllerModel, machine.ID)
+	}
+	return dialect, machine.Type, nil
+}
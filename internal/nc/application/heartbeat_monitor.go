@@ -0,0 +1,91 @@
+package application
+
+import (
+	"context"
+	"goNexttask/internal/nc/domain"
+	"log"
+	"time"
+)
+
+// HeartbeatMonitor は最終ハートビートを定期的に評価し、
+// 一定時間応答のない機械をオフラインとして扱うウォッチドッグ
+type HeartbeatMonitor struct {
+	machineRepo    domain.MachineRepository
+	statusRecorder *domain.MachineStatusRecorder
+	timeout        time.Duration
+	interval       time.Duration
+}
+
+func NewHeartbeatMonitor(
+	programRepo domain.NCProgramRepository,
+	machineRepo domain.MachineRepository,
+	historyRepo domain.MachineStatusHistoryRepository,
+	jobRepo domain.NCJobRepository,
+	magazineRepo domain.ToolMagazineRepository,
+	publisher domain.EventPublisher,
+	timeout time.Duration,
+	interval time.Duration,
+) *HeartbeatMonitor {
+	toolLife := domain.NewToolLifeService(programRepo, machineRepo, magazineRepo, publisher)
+	jobTracker := domain.NewNCJobTracker(jobRepo, toolLife, publisher)
+	return &HeartbeatMonitor{
+		machineRepo:    machineRepo,
+		statusRecorder: domain.NewMachineStatusRecorder(historyRepo, jobTracker, publisher),
+		timeout:        timeout,
+		interval:       interval,
+	}
+}
+
+// Run は ctx がキャンセルされるまで監視を続ける
+func (m *HeartbeatMonitor) Run(ctx context.Context) {
+	ticker := time.NewTicker(m.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := m.Check(ctx); err != nil {
+				log.Printf("Heartbeat check failed: %v", err)
+			}
+		}
+	}
+}
+
+// Check はハートビートが途絶えた機械をオフラインにする
+func (m *HeartbeatMonitor) Check(ctx context.Context) error {
+	machines, err := m.machineRepo.FindAll(ctx)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	for _, machine := range machines {
+		if machine.Status.RunningState == domain.StateOffline || !machine.IsHeartbeatExpired(now, m.timeout) {
+			continue
+		}
+
+		// 読み込んだ後に届いたハートビートや状態の変化を上書きしないよう、途絶えたままの場合のみ更新する
+		previous, err := m.machineRepo.MarkOfflineIfStale(ctx, machine.ID, now.Add(-m.timeout))
+		if err != nil {
+			return err
+		}
+		if previous == nil {
+			continue
+		}
+
+		machine.Status = *previous
+		change, err := machine.MarkOffline()
+		if err != nil {
+			log.Printf("Failed to mark machine %s offline: %v", machine.ID, err)
+			continue
+		}
+		if err := m.statusRecorder.Record(ctx, change); err != nil {
+			return err
+		}
+		log.Printf("Machine %s marked offline (last heartbeat: %s)", machine.ID, machine.Status.LastHeartbeat.Format(time.RFC3339))
+	}
+
+	return nil
+}
@@ -0,0 +1,53 @@
+package application
+
+import (
+	"context"
+	"goNexttask/internal/nc/domain"
+	"time"
+)
+
+func (uc *NCUseCase) GetJob(ctx context.Context, jobID string) (*NCJobOutput, error) {
+	job, err := uc.jobRepo.FindByID(ctx, domain.NCJobID(jobID))
+	if err != nil {
+		return nil, err
+	}
+
+	return convertToNCJobOutput(job), nil
+}
+
+func (uc *NCUseCase) GetMachineJobs(ctx context.Context, machineID string, from, to time.Time) ([]*NCJobOutput, error) {
+	if _, err := uc.machineRepo.FindByID(ctx, domain.MachineID(machineID)); err != nil {
+		return nil, err
+	}
+
+	jobs, err := uc.jobRepo.FindByMachineID(ctx, domain.MachineID(machineID), from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	outputs := make([]*NCJobOutput, len(jobs))
+	for i, job := range jobs {
+		outputs[i] = convertToNCJobOutput(job)
+	}
+
+	return outputs, nil
+}
+
+func convertToNCJobOutput(job *domain.NCJob) *NCJobOutput {
+	output := &NCJobOutput{
+		ID:                string(job.ID),
+		ProgramID:         string(job.ProgramID),
+		ProgramHash:       job.ProgramHash,
+		MachineID:         string(job.MachineID),
+		ProductionOrderID: job.ProductionOrderID,
+		Status:            string(job.Status),
+		StartedAt:         job.StartedAt.Format("2006-01-02T15:04:05Z"),
+		ProducedCount:     job.ProducedCount,
+		Alarms:            job.Alarms,
+		ErrorMessage:      job.ErrorMessage,
+	}
+	if job.EndedAt != nil {
+		output.EndedAt = job.EndedAt.Format("2006-01-02T15:04:05Z")
+	}
+	return output
+}
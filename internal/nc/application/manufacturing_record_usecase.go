@@ -0,0 +1,131 @@
+package application
+
+import (
+	"context"
+	"goNexttask/internal/nc/domain"
+	"time"
+)
+
+// ManufacturingRecordOutput は製品を加工したジョブと、そのプログラムが使う工具・オフセット
+type ManufacturingRecordOutput struct {
+	Job            *NCJobOutput
+	ProgramName    string
+	ProgramVersion string
+	Tools          []*MountedToolOutput
+	Offsets        []*MachineOffsetOutput
+}
+
+// MountedToolOutput はマガジンに装着された工具アセンブリと、その時点までの使用実績
+type MountedToolOutput struct {
+	ToolNumber   int
+	Description  string
+	AssemblyID   string
+	AssemblyName string
+	CutTimeSec   float64
+	PartCount    int
+	LoadedAt     string
+}
+
+// GetManufacturingRecord は製造オーダー（省略時は機械）で at までに開始した最新のジョブと、
+// 機械のマガジン・オフセット登録簿のうちそのプログラムが使う工具番号の内容を返す。
+// プログラムから工具を抽出できない場合はマガジン・登録簿のすべてを返す
+func (uc *NCUseCase) GetManufacturingRecord(ctx context.Context, productionOrderID, machineID string, at time.Time) (*ManufacturingRecordOutput, error) {
+	job, err := uc.findJobBefore(ctx, productionOrderID, domain.MachineID(machineID), at)
+	if err != nil {
+		return nil, err
+	}
+
+	program, err := uc.programRepo.FindByID(ctx, job.ProgramID)
+	if err != nil {
+		return nil, err
+	}
+
+	machine, err := uc.machineRepo.FindByID(ctx, job.MachineID)
+	if err != nil {
+		return nil, err
+	}
+
+	descriptions := make(map[int]string)
+	for _, requirement := range program.RequiredToolsOn(machine.Type) {
+		descriptions[requirement.ToolNumber] = requirement.Description
+	}
+	uses := func(toolNumber int) bool {
+		_, ok := descriptions[toolNumber]
+		return len(descriptions) == 0 || ok
+	}
+
+	magazine, err := uc.magazineRepo.FindByMachineID(ctx, job.MachineID)
+	if err != nil {
+		return nil, err
+	}
+	assemblies, err := uc.toolRepo.FindAllAssemblies(ctx)
+	if err != nil {
+		return nil, err
+	}
+	assemblyNames := make(map[domain.ToolAssemblyID]string, len(assemblies))
+	for _, assembly := range assemblies {
+		assemblyNames[assembly.ID] = assembly.Name
+	}
+
+	output := &ManufacturingRecordOutput{
+		Job:            convertToNCJobOutput(job),
+		ProgramName:    program.Name,
+		ProgramVersion: program.Version,
+	}
+
+	for _, tool := range magazine {
+		if !uses(tool.ToolNumber) {
+			continue
+		}
+		output.Tools = append(output.Tools, &MountedToolOutput{
+			ToolNumber:   tool.ToolNumber,
+			Description:  descriptions[tool.ToolNumber],
+			AssemblyID:   string(tool.AssemblyID),
+			AssemblyName: assemblyNames[tool.AssemblyID],
+			CutTimeSec:   tool.CutTime.Seconds(),
+			PartCount:    tool.PartCount,
+			LoadedAt:     tool.LoadedAt.Format("2006-01-02T15:04:05Z"),
+		})
+	}
+
+	offsets, err := uc.offsetRepo.FindByMachineID(ctx, job.MachineID)
+	if err != nil {
+		return nil, err
+	}
+	for _, offset := range offsets {
+		// ワークオフセットはプログラムの工具に関係なくすべて含める
+		if offset.Register.Kind() == domain.ToolOffset && !uses(offset.Register.ToolNumber()) {
+			continue
+		}
+		output.Offsets = append(output.Offsets, convertToMachineOffsetOutput(offset))
+	}
+
+	return output, nil
+}
+
+// findJobBefore は at までに開始したジョブのうち最新のものを返す。該当が無い場合は ErrNCJobNotFound
+func (uc *NCUseCase) findJobBefore(ctx context.Context, productionOrderID string, machineID domain.MachineID, at time.Time) (*domain.NCJob, error) {
+	var jobs []*domain.NCJob
+	var err error
+	switch {
+	case productionOrderID != "":
+		jobs, err = uc.jobRepo.FindByProductionOrderID(ctx, productionOrderID)
+	case machineID != "":
+		jobs, err = uc.jobRepo.FindByMachineID(ctx, machineID, time.Time{}, at.Add(time.Second))
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	for _, job := range jobs {
+		if job.StartedAt.After(at) {
+			continue
+		}
+		if machineID != "" && job.MachineID != machineID {
+			continue
+		}
+		return job, nil
+	}
+
+	return nil, domain.ErrNCJobNotFound
+}
@@ -3,13 +3,20 @@ package application
 import (
 	"context"
 	"goNexttask/internal/nc/domain"
+	"time"
+
+	"github.com/google/uuid"
 )
 
 type RegisterNCProgramInput struct {
 	Name                 string
 	Version              string
+	PartID               string
 	Content              []byte
 	MachineCompatibility []string
+	RequiredCapabilities []string
+	// Dialect は空の場合 fanuc
+	Dialect              string
 	CreatedBy            string
 }
 
@@ -17,71 +24,259 @@ type NCProgramOutput struct {
 	ID                   string
 	Name                 string
 	Version              string
+	PartID               string
+	Dialect              string
+	SourceProgramID      string
 	FileHash             string
+	Signature            string
+	SignerKeyID          string
+	SignedBy             string
+	SignedAt             string
 	MachineCompatibility []string
+	RequiredCapabilities []string
+	Status               string
+	ApprovedBy           string
+	ApprovedAt           string
 	CreatedBy            string
 	CreatedAt            string
 }
 
 type DeployProgramInput struct {
-	ProgramID string
-	MachineID string
+	ProgramID         string
+	MachineID         string
+	ProductionOrderID string
+	IdempotencyKey    string
+}
+
+type NCJobOutput struct {
+	ID                string
+	ProgramID         string
+	ProgramHash       string
+	MachineID         string
+	ProductionOrderID string
+	Status            string
+	StartedAt         string
+	EndedAt           string
+	ProducedCount     int
+	Alarms            []string
+	ErrorMessage      string
 }
 
 type MachineStatusOutput struct {
-	ID           string
-	Name         string
-	IP           string
-	Type         string
+	ID            string
+	Name          string
+	IP            string
+	Type          string
+	RunningState  string
+	CurrentJobID  string
+	LastHeartbeat string
+	ErrorMessage  string
+}
+
+type MachineInput struct {
+	ID               string
+	Name             string
+	IP               string
+	Type             string
+	Capabilities     []string
+	AxisTravelX      float64
+	AxisTravelY      float64
+	AxisTravelZ      float64
+	MaxSpindleSpeed  int
+	ToolMagazineSize int
+	ControllerModel  string
+	Axes             []string
+	ControlOptions   []string
+	Macros           []string
+}
+
+type MachineOutput struct {
+	ID               string
+	Name             string
+	IP               string
+	Type             string
+	Capabilities     []string
+	AxisTravelX      float64
+	AxisTravelY      float64
+	AxisTravelZ      float64
+	MaxSpindleSpeed  int
+	ToolMagazineSize int
+	ControllerModel  string
+	Axes             []string
+	ControlOptions   []string
+	Macros           []string
+	RunningState     string
+	DecommissionedAt string
+	CreatedAt        string
+	UpdatedAt        string
+}
+
+type UpdateMachineStatusInput struct {
+	MachineID    string
 	RunningState string
 	CurrentJobID string
-	LastHeartbeat string
+	ErrorMessage string
+	// ProducedCount は実行中ジョブの加工数（通知された場合のみ）
+	ProducedCount *int
+}
+
+type MachineStatusChangeOutput struct {
+	MachineID    string
+	FromState    string
+	ToState      string
+	JobID        string
+	ErrorMessage string
+	ChangedAt    string
+}
+
+type ConnectorConfigInput struct {
+	MachineID      string
+	Protocol       string
+	Endpoint       string
+	DeviceName     string
+	PollIntervalMs int
+	NodeIDs        map[string]string
+	StateMapping   map[string]string
+	Enabled        bool
+}
+
+type ConnectorConfigOutput struct {
+	MachineID      string
+	Protocol       string
+	Endpoint       string
+	DeviceName     string
+	PollIntervalMs int64
+	NodeIDs        map[string]string
+	StateMapping   map[string]string
+	Enabled        bool
+	UpdatedAt      string
+}
+
+type TelemetryPointOutput struct {
+	DataItem     string
+	Category     string
+	Value        string
+	NumericValue *float64
+	Source       string
+	Timestamp    string
 }
 
 type NCUseCase struct {
 	programRepo     domain.NCProgramRepository
 	machineRepo     domain.MachineRepository
+	historyRepo     domain.MachineStatusHistoryRepository
+	telemetryRepo   domain.TelemetryRepository
+	connectorRepo   domain.ConnectorConfigRepository
+	jobRepo         domain.NCJobRepository
+	deploymentRepo  domain.DeploymentRepository
+	toolRepo        domain.ToolLibraryRepository
+	magazineRepo    domain.ToolMagazineRepository
+	offsetRepo      domain.OffsetRepository
+	alarmRepo       domain.MachineAlarmRepository
+	catalogRepo     domain.AlarmCatalogRepository
+	signerKeyRepo   domain.SignerKeyRepository
+	toolLife        *domain.ToolLifeService
+	offsetRegistry  *domain.OffsetRegistryService
+	alarmRecorder   *domain.AlarmRecorder
+	jobTracker      *domain.NCJobTracker
+	statusRecorder  *domain.MachineStatusRecorder
 	transferService *domain.NCTransferService
+	deploymentQueue *DeploymentQueue
+}
+
+// NCUseCaseDeps は NCUseCase が使うリポジトリと外部連携。同じ型の引数の取り違えを防ぐためフィールド名で指定する
+type NCUseCaseDeps struct {
+	ProgramRepo     domain.NCProgramRepository
+	MachineRepo     domain.MachineRepository
+	HistoryRepo     domain.MachineStatusHistoryRepository
+	TelemetryRepo   domain.TelemetryRepository
+	ConnectorRepo   domain.ConnectorConfigRepository
+	JobRepo         domain.NCJobRepository
+	DeploymentRepo  domain.DeploymentRepository
+	ToolRepo        domain.ToolLibraryRepository
+	MagazineRepo    domain.ToolMagazineRepository
+	OffsetRepo      domain.OffsetRepository
+	AlarmRepo       domain.MachineAlarmRepository
+	CatalogRepo     domain.AlarmCatalogRepository
+	SignerKeyRepo   domain.SignerKeyRepository
+	PerformanceRepo domain.ProgramPerformanceRepository
+	Transferer      domain.ProgramTransferer
+	OffsetWriter    domain.OffsetWriter
+	Publisher       domain.EventPublisher
 }
 
-func NewNCUseCase(programRepo domain.NCProgramRepository, machineRepo domain.MachineRepository) *NCUseCase {
+func NewNCUseCase(deps NCUseCaseDeps) *NCUseCase {
+	toolLife := domain.NewToolLifeService(deps.ProgramRepo, deps.MachineRepo, deps.MagazineRepo, deps.Publisher)
+	jobTracker := domain.NewNCJobTracker(deps.JobRepo, toolLife, deps.Publisher)
+	statusRecorder := domain.NewMachineStatusRecorder(deps.HistoryRepo, jobTracker, deps.Publisher)
+	transferService := domain.NewNCTransferService(deps.ProgramRepo, deps.MachineRepo, deps.DeploymentRepo, deps.Transferer, jobTracker, statusRecorder, toolLife, deps.PerformanceRepo, deps.SignerKeyRepo)
 	return &NCUseCase{
-		programRepo:     programRepo,
-		machineRepo:     machineRepo,
-		transferService: domain.NewNCTransferService(programRepo, machineRepo),
+		programRepo:     deps.ProgramRepo,
+		machineRepo:     deps.MachineRepo,
+		historyRepo:     deps.HistoryRepo,
+		telemetryRepo:   deps.TelemetryRepo,
+		connectorRepo:   deps.ConnectorRepo,
+		jobRepo:         deps.JobRepo,
+		deploymentRepo:  deps.DeploymentRepo,
+		toolRepo:        deps.ToolRepo,
+		magazineRepo:    deps.MagazineRepo,
+		offsetRepo:      deps.OffsetRepo,
+		alarmRepo:       deps.AlarmRepo,
+		catalogRepo:     deps.CatalogRepo,
+		signerKeyRepo:   deps.SignerKeyRepo,
+		toolLife:        toolLife,
+		offsetRegistry:  domain.NewOffsetRegistryService(deps.OffsetRepo, deps.MachineRepo, deps.OffsetWriter, deps.Publisher),
+		alarmRecorder:   domain.NewAlarmRecorder(deps.AlarmRepo, deps.JobRepo, deps.Publisher),
+		jobTracker:      jobTracker,
+		statusRecorder:  statusRecorder,
+		transferService: transferService,
+		deploymentQueue: newDeploymentQueue(transferService, deps.DeploymentRepo),
 	}
 }
 
 func (uc *NCUseCase) RegisterNCProgram(ctx context.Context, input RegisterNCProgramInput) (*NCProgramOutput, error) {
+	dialect, err := domain.ParseDialect(input.Dialect)
+	if err != nil {
+		return nil, err
+	}
+
 	program := domain.NewNCProgram(
 		input.Name,
 		input.Version,
+		input.PartID,
 		input.Content,
 		input.MachineCompatibility,
+		input.RequiredCapabilities,
 		input.CreatedBy,
 	)
+	program.Dialect = dialect
 	
 	if err := uc.programRepo.Save(ctx, program); err != nil {
 		return nil, err
 	}
 	
-	return &NCProgramOutput{
-		ID:                   string(program.ID),
-		Name:                 program.Name,
-		Version:              program.Version,
-		FileHash:             program.FileHash,
-		MachineCompatibility: program.MachineCompatibility,
-		CreatedBy:            program.CreatedBy,
-		CreatedAt:            program.CreatedAt.Format("2006-01-02T15:04:05Z"),
-	}, nil
+	return convertToNCProgramOutput(program), nil
 }
 
-func (uc *NCUseCase) DeployProgram(ctx context.Context, input DeployProgramInput) error {
-	return uc.transferService.TransferProgram(
+// DeployProgram は展開要求を機械の待ち行列に登録する。転送はワーカーが非同期に行う
+func (uc *NCUseCase) DeployProgram(ctx context.Context, input DeployProgramInput) (*DeploymentOutput, error) {
+	deployment, replayed, err := uc.transferService.RequestDeployment(
 		ctx,
 		domain.NCProgramID(input.ProgramID),
 		domain.MachineID(input.MachineID),
+		input.ProductionOrderID,
+		input.IdempotencyKey,
 	)
+	if err != nil {
+		return nil, err
+	}
+	if !replayed {
+		uc.deploymentQueue.Enqueue(deployment)
+	}
+	
+	output := convertToDeploymentOutput(deployment)
+	output.Replayed = replayed
+	return output, nil
 }
 
 func (uc *NCUseCase) GetMachineStatus(ctx context.Context, machineID string) (*MachineStatusOutput, error) {
@@ -98,6 +293,7 @@ func (uc *NCUseCase) GetMachineStatus(ctx context.Context, machineID string) (*M
 		RunningState:  string(machine.Status.RunningState),
 		CurrentJobID:  machine.Status.CurrentJobID,
 		LastHeartbeat: machine.Status.LastHeartbeat.Format("2006-01-02T15:04:05Z"),
+		ErrorMessage:  machine.Status.ErrorMessage,
 	}, nil
 }
 
@@ -109,26 +305,212 @@ func (uc *NCUseCase) GetAllPrograms(ctx context.Context) ([]*NCProgramOutput, er
 	
 	outputs := make([]*NCProgramOutput, len(programs))
 	for i, program := range programs {
-		outputs[i] = &NCProgramOutput{
-			ID:                   string(program.ID),
-			Name:                 program.Name,
-			Version:              program.Version,
-			FileHash:             program.FileHash,
-			MachineCompatibility: program.MachineCompatibility,
-			CreatedBy:            program.CreatedBy,
-			CreatedAt:            program.CreatedAt.Format("2006-01-02T15:04:05Z"),
-		}
+		outputs[i] = convertToNCProgramOutput(program)
 	}
 	
 	return outputs, nil
 }
 
-func (uc *NCUseCase) UpdateMachineStatus(ctx context.Context, machineID string, status domain.MachineStatus) error {
+func (uc *NCUseCase) UpdateMachineStatus(ctx context.Context, input UpdateMachineStatusInput) error {
+	machine, err := uc.machineRepo.FindByID(ctx, domain.MachineID(input.MachineID))
+	if err != nil {
+		return err
+	}
+	
+	// ジョブの終了判定より前に加工数を反映する
+	if input.ProducedCount != nil {
+		if err := uc.jobTracker.ReportProducedCount(ctx, machine.ID, *input.ProducedCount); err != nil {
+			return err
+		}
+	}
+	
+	change, err := machine.UpdateStatus(domain.MachineStatus{
+		RunningState: domain.MachineRunningState(input.RunningState),
+		CurrentJobID: input.CurrentJobID,
+		ErrorMessage: input.ErrorMessage,
+	})
+	if err != nil {
+		return err
+	}
+	
+	// ハートビートや仕様の変更を上書きしないよう稼働状態の列のみ更新する
+	if err := uc.machineRepo.UpdateStatus(ctx, machine); err != nil {
+		return err
+	}
+	
+	return uc.statusRecorder.Record(ctx, change)
+}
+
+// RecordHeartbeat は生存通知を記録する。読み込んだ後に届いた状態の変化を上書きしないよう、
+// 最終ハートビートとオフラインからの復帰のみ更新する
+func (uc *NCUseCase) RecordHeartbeat(ctx context.Context, machineID string) error {
 	machine, err := uc.machineRepo.FindByID(ctx, domain.MachineID(machineID))
 	if err != nil {
 		return err
 	}
+	if machine.IsDecommissioned() {
+		return domain.ErrMachineDecommissioned
+	}
 	
-	machine.UpdateStatus(status)
+	now := time.Now()
+	previous, err := uc.machineRepo.UpdateHeartbeat(ctx, machine.ID, now)
+	if err != nil {
+		return err
+	}
+	if previous == nil {
+		return nil
+	}
+	
+	machine.Status = *previous
+	change, err := machine.RecordHeartbeat(now)
+	if err != nil {
+		return err
+	}
+	
+	return uc.statusRecorder.Record(ctx, change)
+}
+
+func (uc *NCUseCase) GetMachineStatusHistory(ctx context.Context, machineID string, from, to time.Time) ([]*MachineStatusChangeOutput, error) {
+	if _, err := uc.machineRepo.FindByID(ctx, domain.MachineID(machineID)); err != nil {
+		return nil, err
+	}
+	
+	changes, err := uc.historyRepo.FindByMachineID(ctx, domain.MachineID(machineID), from, to)
+	if err != nil {
+		return nil, err
+	}
+	
+	outputs := make([]*MachineStatusChangeOutput, len(changes))
+	for i, change := range changes {
+		outputs[i] = &MachineStatusChangeOutput{
+			MachineID:    string(change.MachineID),
+			FromState:    string(change.FromState),
+			ToState:      string(change.ToState),
+			JobID:        change.JobID,
+			ErrorMessage: change.ErrorMessage,
+			ChangedAt:    change.ChangedAt.Format("2006-01-02T15:04:05Z"),
+		}
+	}
+	
+	return outputs, nil
+}
+
+func (uc *NCUseCase) RegisterMachine(ctx context.Context, input MachineInput) (*MachineOutput, error) {
+	id := input.ID
+	if id == "" {
+		id = "machine-" + uuid.New().String()[:8]
+	}
+
+	if _, err := uc.machineRepo.FindByID(ctx, domain.MachineID(id)); err == nil {
+		return nil, domain.ErrMachineAlreadyExists
+	} else if err != domain.ErrMachineNotFound {
+		return nil, err
+	}
+
+	machine := domain.NewMachine(domain.MachineID(id), input.Name, input.IP, input.Type, input.Capabilities)
+	machine.AxisTravel = domain.AxisTravel{X: input.AxisTravelX, Y: input.AxisTravelY, Z: input.AxisTravelZ}
+	machine.MaxSpindleSpeed = input.MaxSpindleSpeed
+	machine.ToolMagazineSize = input.ToolMagazineSize
+	machine.ControllerModel = input.ControllerModel
+	machine.Control = domain.NewControlSpec(input.Axes, input.ControlOptions, input.Macros)
+
+	if err := machine.Validate(); err != nil {
+		return nil, err
+	}
+
+	if err := uc.machineRepo.Save(ctx, machine); err != nil {
+		return nil, err
+	}
+
+	return convertToMachineOutput(machine), nil
+}
+
+func (uc *NCUseCase) GetMachine(ctx context.Context, machineID string) (*MachineOutput, error) {
+	machine, err := uc.machineRepo.FindByID(ctx, domain.MachineID(machineID))
+	if err != nil {
+		return nil, err
+	}
+
+	return convertToMachineOutput(machine), nil
+}
+
+func (uc *NCUseCase) GetAllMachines(ctx context.Context) ([]*MachineOutput, error) {
+	machines, err := uc.machineRepo.FindAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	outputs := make([]*MachineOutput, len(machines))
+	for i, machine := range machines {
+		outputs[i] = convertToMachineOutput(machine)
+	}
+
+	return outputs, nil
+}
+
+func (uc *NCUseCase) UpdateMachine(ctx context.Context, input MachineInput) (*MachineOutput, error) {
+	machine, err := uc.machineRepo.FindByID(ctx, domain.MachineID(input.ID))
+	if err != nil {
+		return nil, err
+	}
+
+	err = machine.UpdateSpec(
+		input.Name,
+		input.IP,
+		input.Type,
+		input.Capabilities,
+		domain.AxisTravel{X: input.AxisTravelX, Y: input.AxisTravelY, Z: input.AxisTravelZ},
+		input.MaxSpindleSpeed,
+		input.ToolMagazineSize,
+		input.ControllerModel,
+		domain.NewControlSpec(input.Axes, input.ControlOptions, input.Macros),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := uc.machineRepo.Update(ctx, machine); err != nil {
+		return nil, err
+	}
+
+	return convertToMachineOutput(machine), nil
+}
+
+func (uc *NCUseCase) DecommissionMachine(ctx context.Context, machineID string) error {
+	machine, err := uc.machineRepo.FindByID(ctx, domain.MachineID(machineID))
+	if err != nil {
+		return err
+	}
+
+	if err := machine.Decommission(); err != nil {
+		return err
+	}
+
 	return uc.machineRepo.Update(ctx, machine)
-}
\ No newline at end of file
+}
+
+func convertToMachineOutput(machine *domain.Machine) *MachineOutput {
+	output := &MachineOutput{
+		ID:               string(machine.ID),
+		Name:             machine.Name,
+		IP:               machine.IP,
+		Type:             machine.Type,
+		Capabilities:     machine.Capabilities,
+		AxisTravelX:      machine.AxisTravel.X,
+		AxisTravelY:      machine.AxisTravel.Y,
+		AxisTravelZ:      machine.AxisTravel.Z,
+		MaxSpindleSpeed:  machine.MaxSpindleSpeed,
+		ToolMagazineSize: machine.ToolMagazineSize,
+		ControllerModel:  machine.ControllerModel,
+		Axes:             machine.Control.Axes,
+		ControlOptions:   machine.Control.Options,
+		Macros:           machine.Control.Macros,
+		RunningState:     string(machine.Status.RunningState),
+		CreatedAt:        machine.CreatedAt.Format("2006-01-02T15:04:05Z"),
+		UpdatedAt:        machine.UpdatedAt.Format("2006-01-02T15:04:05Z"),
+	}
+	if machine.DecommissionedAt != nil {
+		output.DecommissionedAt = machine.DecommissionedAt.Format("2006-01-02T15:04:05Z")
+	}
+	return output
+}
@@ -0,0 +1,191 @@
+package application
+
+import (
+	"context"
+	"goNexttask/internal/nc/domain"
+)
+
+type ProposeOffsetChangeInput struct {
+	MachineID  string
+	Register   string
+	Mode       string
+	Values     map[string]float64
+	Reason     string
+	ProposedBy string
+}
+
+type MachineOffsetOutput struct {
+	MachineID string
+	Register  string
+	Kind      string
+	Values    map[string]float64
+	Version   int
+	ChangeID  string
+	UpdatedBy string
+	UpdatedAt string
+}
+
+type OffsetChangeOutput struct {
+	ID            string
+	MachineID     string
+	Register      string
+	Kind          string
+	Mode          string
+	Values        map[string]float64
+	BaseVersion   int
+	Previous      map[string]float64
+	Result        map[string]float64
+	Status        string
+	Reason        string
+	ProposedBy    string
+	ProposedAt    string
+	ReviewedBy    string
+	ReviewedAt    string
+	ReviewComment string
+	AppliedAt     string
+	ErrorMessage  string
+}
+
+// GetMachineOffsets は機械のオフセット登録簿の現在の内容を返す
+func (uc *NCUseCase) GetMachineOffsets(ctx context.Context, machineID string) ([]*MachineOffsetOutput, error) {
+	if _, err := uc.machineRepo.FindByID(ctx, domain.MachineID(machineID)); err != nil {
+		return nil, err
+	}
+
+	offsets, err := uc.offsetRepo.FindByMachineID(ctx, domain.MachineID(machineID))
+	if err != nil {
+		return nil, err
+	}
+
+	outputs := make([]*MachineOffsetOutput, len(offsets))
+	for i, offset := range offsets {
+		outputs[i] = convertToMachineOffsetOutput(offset)
+	}
+
+	return outputs, nil
+}
+
+// ProposeOffsetChange はオフセットの変更を提案する。反映には提案者以外の承認が必要
+func (uc *NCUseCase) ProposeOffsetChange(ctx context.Context, input ProposeOffsetChangeInput) (*OffsetChangeOutput, error) {
+	register, err := domain.ParseOffsetRegister(input.Register)
+	if err != nil {
+		return nil, err
+	}
+
+	mode := domain.OffsetChangeMode(input.Mode)
+	if mode == "" {
+		mode = domain.OffsetAbsolute
+	}
+
+	change, err := uc.offsetRegistry.Propose(
+		ctx,
+		domain.MachineID(input.MachineID),
+		register,
+		mode,
+		domain.OffsetValues(input.Values),
+		input.Reason,
+		input.ProposedBy,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return convertToOffsetChangeOutput(change), nil
+}
+
+// ApproveOffsetChange は変更を承認し、コントローラーへ書き込んで登録簿に反映する。
+// 書き込みに失敗した変更は push_failed として残り、再度の承認で再送できる
+func (uc *NCUseCase) ApproveOffsetChange(ctx context.Context, changeID, approvedBy, comment string) (*OffsetChangeOutput, error) {
+	change, err := uc.offsetRegistry.Approve(ctx, domain.OffsetChangeID(changeID), approvedBy, comment)
+	if err != nil {
+		return nil, err
+	}
+
+	return convertToOffsetChangeOutput(change), nil
+}
+
+func (uc *NCUseCase) RejectOffsetChange(ctx context.Context, changeID, rejectedBy, comment string) (*OffsetChangeOutput, error) {
+	change, err := uc.offsetRegistry.Reject(ctx, domain.OffsetChangeID(changeID), rejectedBy, comment)
+	if err != nil {
+		return nil, err
+	}
+
+	return convertToOffsetChangeOutput(change), nil
+}
+
+func (uc *NCUseCase) GetOffsetChange(ctx context.Context, changeID string) (*OffsetChangeOutput, error) {
+	change, err := uc.offsetRepo.FindChangeByID(ctx, domain.OffsetChangeID(changeID))
+	if err != nil {
+		return nil, err
+	}
+
+	return convertToOffsetChangeOutput(change), nil
+}
+
+// GetOffsetChanges はオフセット変更の履歴を新しい順に返す。register / status は省略可
+func (uc *NCUseCase) GetOffsetChanges(ctx context.Context, machineID, register, status string) ([]*OffsetChangeOutput, error) {
+	if _, err := uc.machineRepo.FindByID(ctx, domain.MachineID(machineID)); err != nil {
+		return nil, err
+	}
+
+	var parsed domain.OffsetRegister
+	if register != "" {
+		var err error
+		if parsed, err = domain.ParseOffsetRegister(register); err != nil {
+			return nil, err
+		}
+	}
+
+	changes, err := uc.offsetRepo.FindChanges(ctx, domain.MachineID(machineID), parsed, domain.OffsetChangeStatus(status))
+	if err != nil {
+		return nil, err
+	}
+
+	outputs := make([]*OffsetChangeOutput, len(changes))
+	for i, change := range changes {
+		outputs[i] = convertToOffsetChangeOutput(change)
+	}
+
+	return outputs, nil
+}
+
+func convertToMachineOffsetOutput(offset *domain.MachineOffset) *MachineOffsetOutput {
+	return &MachineOffsetOutput{
+		MachineID: string(offset.MachineID),
+		Register:  string(offset.Register),
+		Kind:      string(offset.Register.Kind()),
+		Values:    offset.Values,
+		Version:   offset.Version,
+		ChangeID:  string(offset.ChangeID),
+		UpdatedBy: offset.UpdatedBy,
+		UpdatedAt: offset.UpdatedAt.Format("2006-01-02T15:04:05Z"),
+	}
+}
+
+func convertToOffsetChangeOutput(change *domain.OffsetChange) *OffsetChangeOutput {
+	output := &OffsetChangeOutput{
+		ID:            string(change.ID),
+		MachineID:     string(change.MachineID),
+		Register:      string(change.Register),
+		Kind:          string(change.Register.Kind()),
+		Mode:          string(change.Mode),
+		Values:        change.Values,
+		BaseVersion:   change.BaseVersion,
+		Previous:      change.Previous,
+		Result:        change.Result,
+		Status:        string(change.Status),
+		Reason:        change.Reason,
+		ProposedBy:    change.ProposedBy,
+		ProposedAt:    change.ProposedAt.Format("2006-01-02T15:04:05Z"),
+		ReviewedBy:    change.ReviewedBy,
+		ReviewComment: change.ReviewComment,
+		ErrorMessage:  change.ErrorMessage,
+	}
+	if change.ReviewedAt != nil {
+		output.ReviewedAt = change.ReviewedAt.Format("2006-01-02T15:04:05Z")
+	}
+	if change.AppliedAt != nil {
+		output.AppliedAt = change.AppliedAt.Format("2006-01-02T15:04:05Z")
+	}
+	return output
+}
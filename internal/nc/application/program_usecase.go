@@ -0,0 +1,151 @@
+package application
+
+import (
+	"context"
+	"encoding/base64"
+	"goNexttask/internal/nc/domain"
+)
+
+type ProgramCandidateOutput struct {
+	Program         *NCProgramOutput
+	Eligible        bool
+	FirstPassYield  *float64
+	InspectionCount int
+	CompletedJobs   int
+	AvgCycleTimeSec *float64
+	Reasons         []string
+}
+
+type ProgramRecommendationOutput struct {
+	PartID     string
+	MachineID  string
+	Selected   *ProgramCandidateOutput
+	Candidates []*ProgramCandidateOutput
+}
+
+// SignProgramInput は承認者の鍵による署名。Signature は SigningPayload に対する Ed25519 署名
+type SignProgramInput struct {
+	ProgramID   string
+	SignedBy    string
+	SignerKeyID string
+	Signature   []byte
+}
+
+// ApproveProgram は承認者の署名を検証し、ドラフトのプログラムをリリースする
+func (uc *NCUseCase) ApproveProgram(ctx context.Context, input SignProgramInput) (*NCProgramOutput, error) {
+	program, err := uc.programRepo.FindByID(ctx, domain.NCProgramID(input.ProgramID))
+	if err != nil {
+		return nil, err
+	}
+
+	key, err := uc.signerKeyRepo.FindByID(ctx, domain.SignerKeyID(input.SignerKeyID))
+	if err != nil {
+		return nil, err
+	}
+
+	if err := program.Approve(input.SignedBy, key, input.Signature); err != nil {
+		return nil, err
+	}
+
+	if err := uc.programRepo.Update(ctx, program); err != nil {
+		return nil, err
+	}
+
+	return convertToNCProgramOutput(program), nil
+}
+
+// SignProgram はリリース済みのプログラムに署名し直す（署名鍵の失効後や署名導入前にリリースしたプログラム）
+func (uc *NCUseCase) SignProgram(ctx context.Context, input SignProgramInput) (*NCProgramOutput, error) {
+	program, err := uc.programRepo.FindByID(ctx, domain.NCProgramID(input.ProgramID))
+	if err != nil {
+		return nil, err
+	}
+	if !program.IsReleased() {
+		return nil, domain.ErrInvalidProgramStatus
+	}
+
+	key, err := uc.signerKeyRepo.FindByID(ctx, domain.SignerKeyID(input.SignerKeyID))
+	if err != nil {
+		return nil, err
+	}
+
+	if err := program.Sign(key, input.Signature, input.SignedBy); err != nil {
+		return nil, err
+	}
+
+	if err := uc.programRepo.Update(ctx, program); err != nil {
+		return nil, err
+	}
+
+	return convertToNCProgramOutput(program), nil
+}
+
+func (uc *NCUseCase) RecommendProgram(ctx context.Context, partID, machineID string) (*ProgramRecommendationOutput, error) {
+	recommendation, err := uc.transferService.SelectOptimalProgram(ctx, partID, domain.MachineID(machineID))
+	if err != nil {
+		return nil, err
+	}
+
+	output := &ProgramRecommendationOutput{
+		PartID:     recommendation.PartID,
+		MachineID:  string(recommendation.MachineID),
+		Candidates: make([]*ProgramCandidateOutput, len(recommendation.Candidates)),
+	}
+	for i, candidate := range recommendation.Candidates {
+		output.Candidates[i] = convertToProgramCandidateOutput(candidate)
+		if candidate == recommendation.Selected {
+			output.Selected = output.Candidates[i]
+		}
+	}
+
+	return output, nil
+}
+
+func convertToProgramCandidateOutput(candidate *domain.ProgramCandidate) *ProgramCandidateOutput {
+	p := candidate.Performance
+	output := &ProgramCandidateOutput{
+		Program:         convertToNCProgramOutput(candidate.Program),
+		Eligible:        candidate.Eligible,
+		InspectionCount: p.InspectionCount,
+		CompletedJobs:   p.CompletedJobs,
+		Reasons:         candidate.Reasons,
+	}
+	if fpy, ok := p.FirstPassYield(); ok {
+		output.FirstPassYield = &fpy
+	}
+	if p.CompletedJobs > 0 {
+		seconds := p.AvgCycleTime.Seconds()
+		output.AvgCycleTimeSec = &seconds
+	}
+	return output
+}
+
+func convertToNCProgramOutput(program *domain.NCProgram) *NCProgramOutput {
+	output := &NCProgramOutput{
+		ID:                   string(program.ID),
+		Name:                 program.Name,
+		Version:              program.Version,
+		PartID:               program.PartID,
+		Dialect:              string(program.Dialect),
+		SourceProgramID:      string(program.SourceProgramID),
+		FileHash:             program.FileHash,
+		SignerKeyID:          string(program.SignerKeyID),
+		SignedBy:             program.SignedBy,
+		MachineCompatibility: program.MachineCompatibility,
+		RequiredCapabilities: program.RequiredCapabilities,
+		Status:               string(program.Status),
+		ApprovedBy:           program.ApprovedBy,
+		CreatedBy:            program.CreatedBy,
+		CreatedAt:            program.CreatedAt.Format("2006-01-02T15:04:05Z"),
+	}
+	if program.ApprovedAt != nil {
+		output.ApprovedAt = program.ApprovedAt.Format("2006-01-02T15:04:05Z")
+	}
+	if program.IsSigned() {
+		output.Signature = base64.StdEncoding.EncodeToString(program.Signature)
+	}
+	if program.SignedAt != nil {
+		output.SignedAt = program.SignedAt.Format("2006-01-02T15:04:05Z")
+	}
+	return output
+}
@@ -0,0 +1,147 @@
+package application
+
+import (
+	"context"
+	"encoding/base64"
+	"goNexttask/internal/nc/domain"
+)
+
+type RegisterSignerKeyInput struct {
+	Owner     string
+	PublicKey string
+	CreatedBy string
+}
+
+type RevokeSignerKeyInput struct {
+	KeyID     string
+	RevokedBy string
+	Reason    string
+	// AnyOwner は本人以外の鍵の失効を許可する（管理者）
+	AnyOwner bool
+}
+
+type SignerKeyOutput struct {
+	ID           string
+	Owner        string
+	PublicKey    string
+	Status       string
+	CreatedBy    string
+	CreatedAt    string
+	RevokedBy    string
+	RevokedAt    string
+	RevokeReason string
+}
+
+// ProgramSignatureOutput は署名対象のバイト列と現在の署名の検証結果
+type ProgramSignatureOutput struct {
+	ProgramID   string
+	FileHash    string
+	Payload     string
+	Signature   string
+	SignerKeyID string
+	SignedBy    string
+	SignedAt    string
+	Valid       bool
+	// Error は検証に失敗した理由
+	Error string
+}
+
+// RegisterSignerKey は承認者の Ed25519 公開鍵を登録する
+func (uc *NCUseCase) RegisterSignerKey(ctx context.Context, input RegisterSignerKeyInput) (*SignerKeyOutput, error) {
+	key, err := domain.NewSignerKey(input.Owner, input.PublicKey, input.CreatedBy)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := uc.signerKeyRepo.Save(ctx, key); err != nil {
+		return nil, err
+	}
+
+	return convertToSignerKeyOutput(key), nil
+}
+
+func (uc *NCUseCase) GetSignerKeys(ctx context.Context, owner string) ([]*SignerKeyOutput, error) {
+	keys, err := uc.signerKeyRepo.FindAll(ctx, owner)
+	if err != nil {
+		return nil, err
+	}
+
+	outputs := make([]*SignerKeyOutput, len(keys))
+	for i, key := range keys {
+		outputs[i] = convertToSignerKeyOutput(key)
+	}
+
+	return outputs, nil
+}
+
+// RevokeSignerKey は署名鍵を失効させる。失効した鍵で署名したプログラムは署名し直すまで展開できない
+func (uc *NCUseCase) RevokeSignerKey(ctx context.Context, input RevokeSignerKeyInput) (*SignerKeyOutput, error) {
+	key, err := uc.signerKeyRepo.FindByID(ctx, domain.SignerKeyID(input.KeyID))
+	if err != nil {
+		return nil, err
+	}
+	if !input.AnyOwner && key.Owner != input.RevokedBy {
+		return nil, domain.ErrSignerKeyOwnerMismatch
+	}
+
+	if err := key.Revoke(input.RevokedBy, input.Reason); err != nil {
+		return nil, err
+	}
+
+	if err := uc.signerKeyRepo.Update(ctx, key); err != nil {
+		return nil, err
+	}
+
+	return convertToSignerKeyOutput(key), nil
+}
+
+// GetProgramSignature は承認者が署名するバイト列と、署名済みの場合はその検証結果を返す
+func (uc *NCUseCase) GetProgramSignature(ctx context.Context, programID string) (*ProgramSignatureOutput, error) {
+	program, err := uc.programRepo.FindByID(ctx, domain.NCProgramID(programID))
+	if err != nil {
+		return nil, err
+	}
+
+	output := &ProgramSignatureOutput{
+		ProgramID:   string(program.ID),
+		FileHash:    program.FileHash,
+		Payload:     string(program.SigningPayload()),
+		SignerKeyID: string(program.SignerKeyID),
+		SignedBy:    program.SignedBy,
+	}
+	if program.SignedAt != nil {
+		output.SignedAt = program.SignedAt.Format("2006-01-02T15:04:05Z")
+	}
+
+	var key *domain.SignerKey
+	if program.IsSigned() {
+		output.Signature = base64.StdEncoding.EncodeToString(program.Signature)
+		if key, err = uc.signerKeyRepo.FindByID(ctx, program.SignerKeyID); err != nil && err != domain.ErrSignerKeyNotFound {
+			return nil, err
+		}
+	}
+	if err := program.VerifySignature(key); err != nil {
+		output.Error = err.Error()
+	} else {
+		output.Valid = true
+	}
+
+	return output, nil
+}
+
+func convertToSignerKeyOutput(key *domain.SignerKey) *SignerKeyOutput {
+	output := &SignerKeyOutput{
+		ID:           string(key.ID),
+		Owner:        key.Owner,
+		PublicKey:    key.EncodedPublicKey(),
+		Status:       string(key.Status),
+		CreatedBy:    key.CreatedBy,
+		CreatedAt:    key.CreatedAt.Format("2006-01-02T15:04:05Z"),
+		RevokedBy:    key.RevokedBy,
+		RevokeReason: key.RevokeReason,
+	}
+	if key.RevokedAt != nil {
+		output.RevokedAt = key.RevokedAt.Format("2006-01-02T15:04:05Z")
+	}
+	return output
+}
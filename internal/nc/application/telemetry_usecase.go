@@ -0,0 +1,161 @@
+package application
+
+import (
+	"context"
+	"errors"
+	"goNexttask/internal/nc/domain"
+	"log"
+	"strings"
+	"time"
+)
+
+// IngestObservation はコネクタが取得した機械の状態を取り込む。
+// 受信自体をハートビートとして扱い、稼働状態の変化は UpdateMachineStatus を通して反映する
+func (uc *NCUseCase) IngestObservation(ctx context.Context, observation domain.MachineObservation) error {
+	machineID := string(observation.MachineID)
+
+	if observation.RunningState != domain.StateOffline {
+		if err := uc.RecordHeartbeat(ctx, machineID); err != nil {
+			return err
+		}
+	}
+
+	// ジョブの終了判定より前に加工数を反映する
+	if observation.PartCount != nil {
+		if err := uc.jobTracker.ReportPartCounter(ctx, observation.MachineID, *observation.PartCount); err != nil {
+			return err
+		}
+	}
+
+	// アラームはジョブの終了判定より前に記録し、発生時に実行中だったジョブと紐付ける。
+	// オフライン中は発生中のアラームが分からないため記録を変えない
+	if observation.RunningState != domain.StateOffline {
+		if err := uc.alarmRecorder.Sync(ctx, observation.MachineID, observation.Source, observation.Alarms, observation.ObservedAt); err != nil {
+			return err
+		}
+	}
+
+	if observation.RunningState != "" {
+		machine, err := uc.machineRepo.FindByID(ctx, observation.MachineID)
+		if err != nil {
+			return err
+		}
+
+		labels := make([]string, len(observation.Alarms))
+		for i, alarm := range observation.Alarms {
+			labels[i] = alarm.Label()
+		}
+		errorMessage := strings.Join(labels, "; ")
+		if machine.Status.RunningState != observation.RunningState || machine.Status.ErrorMessage != errorMessage {
+			err := uc.UpdateMachineStatus(ctx, UpdateMachineStatusInput{
+				MachineID:    machineID,
+				RunningState: string(observation.RunningState),
+				CurrentJobID: machine.Status.CurrentJobID,
+				ErrorMessage: errorMessage,
+			})
+			// コントローラー側の状態は遷移ルールに従わない場合があるため、記録のみ行い取り込みは継続する
+			if errors.Is(err, domain.ErrInvalidStatusTransition) {
+				log.Printf("Ignored %s state %s for machine %s: %v", observation.Source, observation.RunningState, machineID, err)
+			} else if err != nil {
+				return err
+			}
+		}
+	}
+
+	return uc.telemetryRepo.Append(ctx, observation.Points)
+}
+
+func (uc *NCUseCase) GetTelemetry(ctx context.Context, machineID, dataItem string, from, to time.Time) ([]*TelemetryPointOutput, error) {
+	if _, err := uc.machineRepo.FindByID(ctx, domain.MachineID(machineID)); err != nil {
+		return nil, err
+	}
+
+	points, err := uc.telemetryRepo.FindByMachineID(ctx, domain.MachineID(machineID), dataItem, from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	outputs := make([]*TelemetryPointOutput, len(points))
+	for i, point := range points {
+		outputs[i] = &TelemetryPointOutput{
+			DataItem:     point.DataItem,
+			Category:     string(point.Category),
+			Value:        point.Value,
+			NumericValue: point.NumericValue,
+			Source:       point.Source,
+			Timestamp:    point.Timestamp.Format("2006-01-02T15:04:05.000Z07:00"),
+		}
+	}
+
+	return outputs, nil
+}
+
+// ConfigureConnector は機械のテレメトリ収集設定を登録・更新する。反映はサーバー再起動時
+func (uc *NCUseCase) ConfigureConnector(ctx context.Context, input ConnectorConfigInput) (*ConnectorConfigOutput, error) {
+	machine, err := uc.machineRepo.FindByID(ctx, domain.MachineID(input.MachineID))
+	if err != nil {
+		return nil, err
+	}
+	if machine.IsDecommissioned() {
+		return nil, domain.ErrMachineDecommissioned
+	}
+
+	var stateMapping map[string]domain.MachineRunningState
+	if len(input.StateMapping) > 0 {
+		stateMapping = make(map[string]domain.MachineRunningState, len(input.StateMapping))
+		for value, state := range input.StateMapping {
+			stateMapping[value] = domain.MachineRunningState(state)
+		}
+	}
+
+	config, err := domain.NewConnectorConfig(
+		machine.ID,
+		domain.ConnectorProtocol(input.Protocol),
+		input.Endpoint,
+		input.DeviceName,
+		time.Duration(input.PollIntervalMs)*time.Millisecond,
+		input.NodeIDs,
+		stateMapping,
+		input.Enabled,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := uc.connectorRepo.Save(ctx, config); err != nil {
+		return nil, err
+	}
+
+	return convertToConnectorConfigOutput(config), nil
+}
+
+func (uc *NCUseCase) GetConnectorConfig(ctx context.Context, machineID string) (*ConnectorConfigOutput, error) {
+	config, err := uc.connectorRepo.FindByMachineID(ctx, domain.MachineID(machineID))
+	if err != nil {
+		return nil, err
+	}
+
+	return convertToConnectorConfigOutput(config), nil
+}
+
+func convertToConnectorConfigOutput(config *domain.ConnectorConfig) *ConnectorConfigOutput {
+	var stateMapping map[string]string
+	if len(config.StateMapping) > 0 {
+		stateMapping = make(map[string]string, len(config.StateMapping))
+		for value, state := range config.StateMapping {
+			stateMapping[value] = string(state)
+		}
+	}
+
+	return &ConnectorConfigOutput{
+		MachineID:      string(config.MachineID),
+		Protocol:       string(config.Protocol),
+		Endpoint:       config.Endpoint,
+		DeviceName:     config.DeviceName,
+		PollIntervalMs: config.PollInterval.Milliseconds(),
+		NodeIDs:        config.NodeIDs,
+		StateMapping:   stateMapping,
+		Enabled:        config.Enabled,
+		UpdatedAt:      config.UpdatedAt.Format("2006-01-02T15:04:05Z"),
+	}
+}
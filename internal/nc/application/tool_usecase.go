@@ -0,0 +1,264 @@
+package application
+
+import (
+	"context"
+	"goNexttask/internal/nc/domain"
+	"time"
+)
+
+type ToolItemInput struct {
+	Kind        string
+	Code        string
+	Description string
+}
+
+type ToolItemOutput struct {
+	ID          string
+	Kind        string
+	Code        string
+	Description string
+	CreatedAt   string
+}
+
+type ToolAssemblyInput struct {
+	Name           string
+	HolderID       string
+	CuttingItemID  string
+	LifeCutTimeSec float64
+	LifePartCount  int
+}
+
+type ToolAssemblyOutput struct {
+	ID             string
+	Name           string
+	HolderID       string
+	CuttingItemID  string
+	LifeCutTimeSec float64
+	LifePartCount  int
+	CreatedAt      string
+}
+
+type ToolRequirementOutput struct {
+	ToolNumber  int
+	Description string
+}
+
+type MagazineToolOutput struct {
+	MachineID           string
+	ToolNumber          int
+	AssemblyID          string
+	LifeCutTimeSec      float64
+	LifePartCount       int
+	CutTimeSec          float64
+	PartCount           int
+	RemainingCutTimeSec float64
+	RemainingPartCount  int
+	WornOut             bool
+	LoadedAt            string
+	UpdatedAt           string
+}
+
+func (uc *NCUseCase) CreateToolItem(ctx context.Context, input ToolItemInput) (*ToolItemOutput, error) {
+	item, err := domain.NewToolItem(domain.ToolItemKind(input.Kind), input.Code, input.Description)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := uc.toolRepo.SaveItem(ctx, item); err != nil {
+		return nil, err
+	}
+
+	return convertToToolItemOutput(item), nil
+}
+
+func (uc *NCUseCase) GetToolItems(ctx context.Context) ([]*ToolItemOutput, error) {
+	items, err := uc.toolRepo.FindAllItems(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	outputs := make([]*ToolItemOutput, len(items))
+	for i, item := range items {
+		outputs[i] = convertToToolItemOutput(item)
+	}
+
+	return outputs, nil
+}
+
+func (uc *NCUseCase) CreateToolAssembly(ctx context.Context, input ToolAssemblyInput) (*ToolAssemblyOutput, error) {
+	var holder *domain.ToolItem
+	if input.HolderID != "" {
+		item, err := uc.toolRepo.FindItemByID(ctx, domain.ToolItemID(input.HolderID))
+		if err != nil {
+			return nil, err
+		}
+		holder = item
+	}
+
+	cuttingItem, err := uc.toolRepo.FindItemByID(ctx, domain.ToolItemID(input.CuttingItemID))
+	if err != nil {
+		return nil, err
+	}
+
+	life := domain.ToolLifeLimit{
+		CutTime:   time.Duration(input.LifeCutTimeSec * float64(time.Second)),
+		PartCount: input.LifePartCount,
+	}
+	assembly, err := domain.NewToolAssembly(input.Name, holder, cuttingItem, life)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := uc.toolRepo.SaveAssembly(ctx, assembly); err != nil {
+		return nil, err
+	}
+
+	return convertToToolAssemblyOutput(assembly), nil
+}
+
+func (uc *NCUseCase) GetToolAssemblies(ctx context.Context) ([]*ToolAssemblyOutput, error) {
+	assemblies, err := uc.toolRepo.FindAllAssemblies(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	outputs := make([]*ToolAssemblyOutput, len(assemblies))
+	for i, assembly := range assemblies {
+		outputs[i] = convertToToolAssemblyOutput(assembly)
+	}
+
+	return outputs, nil
+}
+
+// GetProgramTools はプログラム本体から抽出した使用工具を返す。
+// machineID を指定した場合はその機械のタイプで T ワードの工具番号を読む
+func (uc *NCUseCase) GetProgramTools(ctx context.Context, programID, machineID string) ([]*ToolRequirementOutput, error) {
+	program, err := uc.programRepo.FindByID(ctx, domain.NCProgramID(programID))
+	if err != nil {
+		return nil, err
+	}
+
+	requirements := program.RequiredTools()
+	if machineID != "" {
+		machine, err := uc.machineRepo.FindByID(ctx, domain.MachineID(machineID))
+		if err != nil {
+			return nil, err
+		}
+		requirements = program.RequiredToolsOn(machine.Type)
+	}
+	outputs := make([]*ToolRequirementOutput, len(requirements))
+	for i, requirement := range requirements {
+		outputs[i] = &ToolRequirementOutput{
+			ToolNumber:  requirement.ToolNumber,
+			Description: requirement.Description,
+		}
+	}
+
+	return outputs, nil
+}
+
+// LoadTool は工具アセンブリを機械のマガジンへ装着する。装着済みの工具は置き換え、工具寿命は新品から数える
+func (uc *NCUseCase) LoadTool(ctx context.Context, machineID string, toolNumber int, assemblyID string) (*MagazineToolOutput, error) {
+	machine, err := uc.machineRepo.FindByID(ctx, domain.MachineID(machineID))
+	if err != nil {
+		return nil, err
+	}
+
+	assembly, err := uc.toolRepo.FindAssemblyByID(ctx, domain.ToolAssemblyID(assemblyID))
+	if err != nil {
+		return nil, err
+	}
+
+	tool, err := domain.LoadTool(machine, toolNumber, assembly)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := uc.magazineRepo.Save(ctx, tool); err != nil {
+		return nil, err
+	}
+
+	return convertToMagazineToolOutput(tool), nil
+}
+
+func (uc *NCUseCase) UnloadTool(ctx context.Context, machineID string, toolNumber int) error {
+	if _, err := uc.machineRepo.FindByID(ctx, domain.MachineID(machineID)); err != nil {
+		return err
+	}
+
+	return uc.magazineRepo.Delete(ctx, domain.MachineID(machineID), toolNumber)
+}
+
+func (uc *NCUseCase) GetMagazine(ctx context.Context, machineID string) ([]*MagazineToolOutput, error) {
+	if _, err := uc.machineRepo.FindByID(ctx, domain.MachineID(machineID)); err != nil {
+		return nil, err
+	}
+
+	tools, err := uc.magazineRepo.FindByMachineID(ctx, domain.MachineID(machineID))
+	if err != nil {
+		return nil, err
+	}
+
+	outputs := make([]*MagazineToolOutput, len(tools))
+	for i, tool := range tools {
+		outputs[i] = convertToMagazineToolOutput(tool)
+	}
+
+	return outputs, nil
+}
+
+// ReportToolUsage は工具ごとの切削時間・加工数の実績を加算する
+func (uc *NCUseCase) ReportToolUsage(ctx context.Context, machineID string, toolNumber int, cutTimeSec float64, partCount int) (*MagazineToolOutput, error) {
+	tool, err := uc.toolLife.ReportUsage(
+		ctx,
+		domain.MachineID(machineID),
+		toolNumber,
+		time.Duration(cutTimeSec*float64(time.Second)),
+		partCount,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return convertToMagazineToolOutput(tool), nil
+}
+
+func convertToToolItemOutput(item *domain.ToolItem) *ToolItemOutput {
+	return &ToolItemOutput{
+		ID:          string(item.ID),
+		Kind:        string(item.Kind),
+		Code:        item.Code,
+		Description: item.Description,
+		CreatedAt:   item.CreatedAt.Format("2006-01-02T15:04:05Z"),
+	}
+}
+
+func convertToToolAssemblyOutput(assembly *domain.ToolAssembly) *ToolAssemblyOutput {
+	return &ToolAssemblyOutput{
+		ID:             string(assembly.ID),
+		Name:           assembly.Name,
+		HolderID:       string(assembly.HolderID),
+		CuttingItemID:  string(assembly.CuttingItemID),
+		LifeCutTimeSec: assembly.Life.CutTime.Seconds(),
+		LifePartCount:  assembly.Life.PartCount,
+		CreatedAt:      assembly.CreatedAt.Format("2006-01-02T15:04:05Z"),
+	}
+}
+
+func convertToMagazineToolOutput(tool *domain.MagazineTool) *MagazineToolOutput {
+	remaining := tool.RemainingLife()
+	return &MagazineToolOutput{
+		MachineID:           string(tool.MachineID),
+		ToolNumber:          tool.ToolNumber,
+		AssemblyID:          string(tool.AssemblyID),
+		LifeCutTimeSec:      tool.Life.CutTime.Seconds(),
+		LifePartCount:       tool.Life.PartCount,
+		CutTimeSec:          tool.CutTime.Seconds(),
+		PartCount:           tool.PartCount,
+		RemainingCutTimeSec: remaining.CutTime.Seconds(),
+		RemainingPartCount:  remaining.PartCount,
+		WornOut:             tool.IsWornOut(),
+		LoadedAt:            tool.LoadedAt.Format("2006-01-02T15:04:05Z"),
+		UpdatedAt:           tool.UpdatedAt.Format("2006-01-02T15:04:05Z"),
+	}
+}
@@ -0,0 +1,156 @@
+package domain
+
+import (
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type AlarmSeverity string
+
+const (
+	// AlarmFault は加工を停止させるアラーム
+	AlarmFault AlarmSeverity = "fault"
+	// AlarmWarning は加工を継続できる警告
+	AlarmWarning AlarmSeverity = "warning"
+)
+
+func (s AlarmSeverity) IsValid() bool {
+	return s == AlarmFault || s == AlarmWarning
+}
+
+// ObservedAlarm はコネクタが機械から取得した発生中のアラーム
+type ObservedAlarm struct {
+	Code     string
+	Severity AlarmSeverity
+	// Message はコントローラーが表示するアラーム文
+	Message string
+}
+
+// アラーム文の先頭のアラーム番号 "SV0401 V-READY OFF"、"EX1001: DOOR OPEN"、"ALARM 1001"
+var alarmCodePattern = regexp.MustCompile(`(?i)^(?:ALARM\s+)?([A-Z]{0,4}\d{3,6})\b\s*:?\s*(.*)$`)
+
+// ParseAlarmText はコントローラーのアラーム文から先頭のアラーム番号を取り出す。番号が無い場合 Code は空
+func ParseAlarmText(text string, severity AlarmSeverity) ObservedAlarm {
+	text = strings.TrimSpace(text)
+	alarm := ObservedAlarm{Severity: severity, Message: text}
+	if m := alarmCodePattern.FindStringSubmatch(text); m != nil {
+		alarm.Code = strings.ToUpper(m[1])
+		alarm.Message = strings.TrimSpace(m[2])
+	}
+	return alarm
+}
+
+// Key は発生中のアラームを突き合わせるキー。番号の無いアラームはアラーム文で識別する
+func (a ObservedAlarm) Key() string {
+	if a.Code != "" {
+		return a.Code
+	}
+	return a.Message
+}
+
+// Label は稼働状態のエラーメッセージに使う表記
+func (a ObservedAlarm) Label() string {
+	switch {
+	case a.Code == "":
+		return a.Message
+	case a.Message == "":
+		return a.Code
+	default:
+		return a.Code + " " + a.Message
+	}
+}
+
+type MachineAlarmID string
+
+// MachineAlarm は機械で発生したアラームの発生から解除までの記録
+type MachineAlarm struct {
+	ID        MachineAlarmID
+	MachineID MachineID
+	Code      string
+	Severity  AlarmSeverity
+	Message   string
+	Source    string
+	// JobID は発生時に実行中だったジョブ
+	JobID     NCJobID
+	RaisedAt  time.Time
+	ClearedAt *time.Time
+}
+
+func RaiseAlarm(machineID MachineID, source string, observed ObservedAlarm, jobID NCJobID, raisedAt time.Time) *MachineAlarm {
+	severity := observed.Severity
+	if !severity.IsValid() {
+		severity = AlarmFault
+	}
+	return &MachineAlarm{
+		ID:        MachineAlarmID("alarm-" + uuid.New().String()[:8]),
+		MachineID: machineID,
+		Code:      observed.Code,
+		Severity:  severity,
+		Message:   observed.Message,
+		Source:    source,
+		JobID:     jobID,
+		RaisedAt:  raisedAt,
+	}
+}
+
+func (a *MachineAlarm) Key() string {
+	return ObservedAlarm{Code: a.Code, Message: a.Message}.Key()
+}
+
+func (a *MachineAlarm) IsActive() bool {
+	return a.ClearedAt == nil
+}
+
+// Clear はアラームの解除を記録する。解除時刻は発生時刻より前にしない
+func (a *MachineAlarm) Clear(clearedAt time.Time) {
+	if !a.IsActive() {
+		return
+	}
+	if clearedAt.Before(a.RaisedAt) {
+		clearedAt = a.RaisedAt
+	}
+	a.ClearedAt = &clearedAt
+}
+
+// Duration は発生から解除（発生中の場合は now）までの時間
+func (a *MachineAlarm) Duration(now time.Time) time.Duration {
+	if a.ClearedAt != nil {
+		return a.ClearedAt.Sub(a.RaisedAt)
+	}
+	return now.Sub(a.RaisedAt)
+}
+
+// AlarmFilter はアラーム履歴の検索条件。期間は発生中だった時間が [From, To) と重なるものを対象とする
+type AlarmFilter struct {
+	From       time.Time
+	To         time.Time
+	Code       string
+	Severity   AlarmSeverity
+	ActiveOnly bool
+}
+
+// AlarmCatalogEntry はコントローラー機種ごとのアラーム番号の説明と推奨処置
+type AlarmCatalogEntry struct {
+	ControllerModel   string
+	Code              string
+	Description       string
+	RecommendedAction string
+	UpdatedAt         time.Time
+}
+
+func NewAlarmCatalogEntry(controllerModel, code, description, recommendedAction string) (*AlarmCatalogEntry, error) {
+	entry := &AlarmCatalogEntry{
+		ControllerModel:   strings.TrimSpace(controllerModel),
+		Code:              strings.ToUpper(strings.TrimSpace(code)),
+		Description:       strings.TrimSpace(description),
+		RecommendedAction: strings.TrimSpace(recommendedAction),
+		UpdatedAt:         time.Now(),
+	}
+	if entry.ControllerModel == "" || entry.Code == "" || entry.Description == "" {
+		return nil, ErrInvalidAlarmCatalogEntry
+	}
+	return entry, nil
+}
@@ -0,0 +1,85 @@
+package domain
+
+import (
+	"context"
+	"time"
+)
+
+// AlarmRecorder はコネクタが取得した発生中のアラームと記録済みのアラームを突き合わせ、
+// 新たに発生したアラームの記録と解除されたアラームの解除時刻の記録を行う
+type AlarmRecorder struct {
+	alarmRepo MachineAlarmRepository
+	jobRepo   NCJobRepository
+	publisher EventPublisher
+}
+
+func NewAlarmRecorder(alarmRepo MachineAlarmRepository, jobRepo NCJobRepository, publisher EventPublisher) *AlarmRecorder {
+	return &AlarmRecorder{
+		alarmRepo: alarmRepo,
+		jobRepo:   jobRepo,
+		publisher: publisher,
+	}
+}
+
+// Sync は observed を機械で発生中のアラームの全量として記録に反映する
+func (r *AlarmRecorder) Sync(ctx context.Context, machineID MachineID, source string, observed []ObservedAlarm, observedAt time.Time) error {
+	active, err := r.alarmRepo.FindActiveByMachineID(ctx, machineID)
+	if err != nil {
+		return err
+	}
+
+	recorded := make(map[string]*MachineAlarm, len(active))
+	for _, alarm := range active {
+		recorded[alarm.Key()] = alarm
+	}
+
+	var raised []ObservedAlarm
+	current := make(map[string]bool, len(observed))
+	for _, alarm := range observed {
+		key := alarm.Key()
+		if key == "" || current[key] {
+			continue
+		}
+		current[key] = true
+		if _, ok := recorded[key]; !ok {
+			raised = append(raised, alarm)
+		}
+	}
+
+	for key, alarm := range recorded {
+		if current[key] {
+			continue
+		}
+		alarm.Clear(observedAt)
+		if err := r.alarmRepo.Update(ctx, alarm); err != nil {
+			return err
+		}
+		if err := r.publisher.Publish(ctx, NewAlarmEvent(EventAlarmCleared, alarm)); err != nil {
+			return err
+		}
+	}
+
+	if len(raised) == 0 {
+		return nil
+	}
+
+	var jobID NCJobID
+	job, err := r.jobRepo.FindActiveByMachineID(ctx, machineID)
+	if err != nil && err != ErrNCJobNotFound {
+		return err
+	}
+	if job != nil {
+		jobID = job.ID
+	}
+
+	for _, observed := range raised {
+		alarm := RaiseAlarm(machineID, source, observed, jobID, observedAt)
+		if err := r.alarmRepo.Save(ctx, alarm); err != nil {
+			return err
+		}
+		if err := r.publisher.Publish(ctx, NewAlarmEvent(EventAlarmRaised, alarm)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
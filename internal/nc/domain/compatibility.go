@@ -0,0 +1,201 @@
+package domain
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ControlSpec は機械が宣言する制御装置の仕様
+type ControlSpec struct {
+	// Axes は機械の制御軸（X Y Z A B C U V W）
+	Axes []string
+	// Options は搭載しているオプション機能の G コード（G05.1, G43.4 等）
+	Options []string
+	// Macros はインストール済みのカスタムマクロ（O9810 等）
+	Macros []string
+}
+
+// NewControlSpec は表記ゆれ（小文字、"G5.1"、"9810"）をそろえた制御仕様を返す。解釈できない値は Validate で拒否される
+func NewControlSpec(axes, options, macros []string) ControlSpec {
+	spec := ControlSpec{}
+	for _, axis := range axes {
+		spec.Axes = append(spec.Axes, strings.ToUpper(strings.TrimSpace(axis)))
+	}
+	for _, option := range options {
+		if code, ok := normalizeGCode(option); ok {
+			option = code
+		}
+		spec.Options = append(spec.Options, option)
+	}
+	for _, macro := range macros {
+		if number, ok := normalizeMacro(macro); ok {
+			macro = number
+		}
+		spec.Macros = append(spec.Macros, macro)
+	}
+	return spec
+}
+
+var machineAxes = map[string]bool{
+	"X": true, "Y": true, "Z": true, "A": true, "B": true, "C": true, "U": true, "V": true, "W": true,
+}
+
+func (c ControlSpec) Validate() error {
+	seen := make(map[string]bool)
+	for _, axis := range c.Axes {
+		if !machineAxes[axis] || seen["axis:"+axis] {
+			return ErrInvalidControlSpec
+		}
+		seen["axis:"+axis] = true
+	}
+	for _, option := range c.Options {
+		if code, ok := normalizeGCode(option); !ok || code != option || seen["option:"+option] {
+			return ErrInvalidControlSpec
+		}
+		seen["option:"+option] = true
+	}
+	for _, macro := range c.Macros {
+		if number, ok := normalizeMacro(macro); !ok || number != macro || seen["macro:"+macro] {
+			return ErrInvalidControlSpec
+		}
+		seen["macro:"+macro] = true
+	}
+	return nil
+}
+
+// 要求の種類
+const (
+	RequirementMachineType  = "machine_type"
+	RequirementCapability   = "capability"
+	RequirementAxis         = "axis"
+	RequirementSpindleSpeed = "spindle_speed"
+	RequirementOption       = "option"
+	RequirementMacro        = "macro"
+	RequirementDialect      = "dialect"
+)
+
+// CompatibilityIssue は機械が満たさない（または確認できない）プログラムの要求
+type CompatibilityIssue struct {
+	Requirement string
+	Required    string
+	Available   string
+	Message     string
+}
+
+// Compatibility はプログラムと機械の適合判定の結果
+type Compatibility struct {
+	ProgramID    NCProgramID
+	MachineID    MachineID
+	Requirements ProgramRequirements
+	Mismatches   []CompatibilityIssue
+	// Unverified は機械側の仕様が登録されておらず確認できなかった要求。展開は妨げない
+	Unverified []CompatibilityIssue
+}
+
+func (c *Compatibility) IsCompatible() bool {
+	return len(c.Mismatches) == 0
+}
+
+// Reasons は不適合の理由を返す
+func (c *Compatibility) Reasons() []string {
+	reasons := make([]string, len(c.Mismatches))
+	for i, mismatch := range c.Mismatches {
+		reasons[i] = mismatch.Message
+	}
+	return reasons
+}
+
+// Err は不適合の場合に理由を含む ErrIncompatibleProgram を返す
+func (c *Compatibility) Err() error {
+	if c.IsCompatible() {
+		return nil
+	}
+	return fmt.Errorf("%w: %s", ErrIncompatibleProgram, strings.Join(c.Reasons(), "; "))
+}
+
+// CheckCompatibility はプログラムの宣言（機械タイプ・加工能力）とプログラム本体から読み取った要求を
+// 機械の仕様と照合する。機械タイプはプログラムが対象タイプを宣言している場合のみ制限として扱う
+func CheckCompatibility(program *NCProgram, machine *Machine) *Compatibility {
+	result := &Compatibility{
+		ProgramID:    program.ID,
+		MachineID:    machine.ID,
+		Requirements: program.Requirements(),
+	}
+	mismatch := func(requirement, required, available, message string) {
+		result.Mismatches = append(result.Mismatches, CompatibilityIssue{
+			Requirement: requirement, Required: required, Available: available, Message: message,
+		})
+	}
+	unverified := func(requirement, required, message string) {
+		result.Unverified = append(result.Unverified, CompatibilityIssue{
+			Requirement: requirement, Required: required, Message: message,
+		})
+	}
+
+	if !program.IsCompatibleWith(machine.Type) {
+		mismatch(RequirementMachineType, strings.Join(program.MachineCompatibility, ", "), machine.Type,
+			fmt.Sprintf("program targets machine types %s, machine is %s", strings.Join(program.MachineCompatibility, ", "), machine.Type))
+	}
+	for _, capability := range program.MissingCapabilities(machine.Capabilities) {
+		mismatch(RequirementCapability, capability, strings.Join(machine.Capabilities, ", "),
+			"machine lacks capability "+capability)
+	}
+
+	// 方言はコントローラー機種名から判定する。判定できない機種は確認できない要求として扱う
+	switch dialect := DialectForController(machine.ControllerModel); {
+	case dialect == "":
+		unverified(RequirementDialect, string(program.Dialect), "machine controller dialect is unknown")
+	case dialect != program.Dialect:
+		mismatch(RequirementDialect, string(program.Dialect), string(dialect),
+			fmt.Sprintf("program is written for %s, machine controller is %s", program.Dialect, dialect))
+	}
+
+	requirements := result.Requirements
+	if len(requirements.Axes) > 0 {
+		if len(machine.Control.Axes) == 0 {
+			unverified(RequirementAxis, strings.Join(requirements.Axes, " "), "machine axes are not registered")
+		} else {
+			for _, axis := range missingValues(requirements.Axes, machine.Control.Axes) {
+				mismatch(RequirementAxis, axis, strings.Join(machine.Control.Axes, " "),
+					fmt.Sprintf("program commands %s axis, machine has %s", axis, strings.Join(machine.Control.Axes, " ")))
+			}
+		}
+	}
+
+	if requirements.MaxSpindleSpeed > 0 {
+		required := strconv.Itoa(requirements.MaxSpindleSpeed)
+		switch {
+		case machine.MaxSpindleSpeed == 0:
+			unverified(RequirementSpindleSpeed, required, "machine max spindle speed is not registered")
+		case requirements.MaxSpindleSpeed > machine.MaxSpindleSpeed:
+			mismatch(RequirementSpindleSpeed, required, strconv.Itoa(machine.MaxSpindleSpeed),
+				fmt.Sprintf("program spindle speed S%d exceeds machine max %d min-1", requirements.MaxSpindleSpeed, machine.MaxSpindleSpeed))
+		}
+	}
+
+	for _, option := range missingValues(requirements.Options, machine.Control.Options) {
+		mismatch(RequirementOption, option, strings.Join(machine.Control.Options, ", "),
+			fmt.Sprintf("machine lacks option %s (%s)", option, controlOptionCodes[option]))
+	}
+	for _, macro := range missingValues(requirements.Macros, machine.Control.Macros) {
+		mismatch(RequirementMacro, macro, strings.Join(machine.Control.Macros, ", "),
+			"macro "+macro+" is not installed on machine")
+	}
+
+	return result
+}
+
+func missingValues(required, available []string) []string {
+	has := make(map[string]bool, len(available))
+	for _, a := range available {
+		has[a] = true
+	}
+	var result []string
+	for _, r := range required {
+		if !has[r] {
+			result = append(result, r)
+		}
+	}
+	return result
+}
@@ -0,0 +1,98 @@
+package domain
+
+import (
+	"net/url"
+	"strings"
+	"time"
+)
+
+type ConnectorProtocol string
+
+const (
+	ProtocolMTConnect ConnectorProtocol = "mtconnect"
+	ProtocolOPCUA     ConnectorProtocol = "opcua"
+)
+
+// OPC UA で購読するノードの役割
+const (
+	OPCUANodeState       = "state"
+	OPCUANodeProgram     = "program"
+	OPCUANodePartCount   = "partCount"
+	OPCUANodeAlarm       = "alarm"
+	OPCUANodeSpindleLoad = "spindleLoad"
+)
+
+var validOPCUANodeRoles = map[string]bool{
+	OPCUANodeState:       true,
+	OPCUANodeProgram:     true,
+	OPCUANodePartCount:   true,
+	OPCUANodeAlarm:       true,
+	OPCUANodeSpindleLoad: true,
+}
+
+// ConnectorConfig は機械ごとのテレメトリ収集設定
+type ConnectorConfig struct {
+	MachineID    MachineID
+	Protocol     ConnectorProtocol
+	Endpoint     string
+	DeviceName   string
+	PollInterval time.Duration
+	// NodeIDs は OPC UA の役割ごとのノードID（例: "state" → "ns=2;s=Machine.State"）
+	NodeIDs map[string]string
+	// StateMapping はコントローラー固有の状態値を稼働状態へ対応付ける（例: "3" → "alarm"）
+	StateMapping map[string]MachineRunningState
+	Enabled      bool
+	UpdatedAt    time.Time
+}
+
+func NewConnectorConfig(machineID MachineID, protocol ConnectorProtocol, endpoint, deviceName string, pollInterval time.Duration, nodeIDs map[string]string, stateMapping map[string]MachineRunningState, enabled bool) (*ConnectorConfig, error) {
+	config := &ConnectorConfig{
+		MachineID:    machineID,
+		Protocol:     protocol,
+		Endpoint:     endpoint,
+		DeviceName:   deviceName,
+		PollInterval: pollInterval,
+		NodeIDs:      nodeIDs,
+		StateMapping: stateMapping,
+		Enabled:      enabled,
+		UpdatedAt:    time.Now(),
+	}
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+	return config, nil
+}
+
+func (c *ConnectorConfig) Validate() error {
+	switch c.Protocol {
+	case ProtocolMTConnect:
+		u, err := url.Parse(c.Endpoint)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return ErrInvalidConnectorConfig
+		}
+	case ProtocolOPCUA:
+		u, err := url.Parse(c.Endpoint)
+		if err != nil || u.Scheme != "opc.tcp" || u.Host == "" {
+			return ErrInvalidConnectorConfig
+		}
+		if strings.TrimSpace(c.NodeIDs[OPCUANodeState]) == "" {
+			return ErrInvalidConnectorConfig
+		}
+		for role, nodeID := range c.NodeIDs {
+			if !validOPCUANodeRoles[role] || strings.TrimSpace(nodeID) == "" {
+				return ErrInvalidConnectorConfig
+			}
+		}
+		for _, state := range c.StateMapping {
+			if !state.IsValid() {
+				return ErrInvalidConnectorConfig
+			}
+		}
+	default:
+		return ErrInvalidConnectorConfig
+	}
+	if c.PollInterval <= 0 {
+		c.PollInterval = time.Second
+	}
+	return nil
+}
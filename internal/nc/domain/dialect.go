@@ -0,0 +1,58 @@
+package domain
+
+import "strings"
+
+// Dialect は NC プログラムの方言（コントローラー系列）
+type Dialect string
+
+const (
+	// DialectFanuc は FANUC 系の G コード（Makino Professional 等の FANUC 互換を含む）
+	DialectFanuc Dialect = "fanuc"
+	// DialectSiemens は SINUMERIK 840D 系
+	DialectSiemens Dialect = "siemens"
+	// DialectHeidenhain は TNC の対話形プログラム
+	DialectHeidenhain Dialect = "heidenhain"
+)
+
+func (d Dialect) IsValid() bool {
+	return d == DialectFanuc || d == DialectSiemens || d == DialectHeidenhain
+}
+
+// ParseDialect は方言名を読み取る。空の場合は FANUC とみなす
+func ParseDialect(s string) (Dialect, error) {
+	d := Dialect(strings.ToLower(strings.TrimSpace(s)))
+	if d == "" {
+		return DialectFanuc, nil
+	}
+	if !d.IsValid() {
+		return "", ErrInvalidDialect
+	}
+	return d, nil
+}
+
+// コントローラー機種名に含まれる語と方言の対応（先に一致したものを採用）
+var controllerDialects = []struct {
+	keyword string
+	dialect Dialect
+}{
+	{"SINUMERIK", DialectSiemens},
+	{"SIEMENS", DialectSiemens},
+	{"840D", DialectSiemens},
+	{"HEIDENHAIN", DialectHeidenhain},
+	{"TNC", DialectHeidenhain},
+	{"FANUC", DialectFanuc},
+	{"MAKINO", DialectFanuc},
+	{"PROFESSIONAL", DialectFanuc},
+}
+
+// DialectForController はマシン登録のコントローラー機種名（"CELOS / FANUC 31i-B5" 等）から方言を判定する。
+// 判定できない場合は空を返す
+func DialectForController(controllerModel string) Dialect {
+	model := strings.ToUpper(controllerModel)
+	for _, c := range controllerDialects {
+		if strings.Contains(model, c.keyword) {
+			return c.dialect
+		}
+	}
+	return ""
+}
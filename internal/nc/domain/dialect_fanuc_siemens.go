@@ -0,0 +1,597 @@
+package domain
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// SINUMERIK でも同じ意味で使える G コード
+var portableGCodes = map[string]bool{
+	"G00": true, "G01": true, "G02": true, "G03": true, "G09": true,
+	"G17": true, "G18": true, "G19": true,
+	"G40": true, "G41": true, "G42": true,
+	"G53": true, "G54": true, "G55": true, "G56": true, "G57": true,
+	"G90": true, "G91": true, "G94": true, "G95": true, "G96": true, "G97": true,
+}
+
+// 変換に対応していない FANUC の固定サイクル（旋削複合形固定サイクルを含む）
+var fanucCannedCycles = map[string]bool{
+	"G70": true, "G71": true, "G72": true, "G73": true, "G74": true, "G75": true, "G76": true,
+	"G84": true, "G85": true, "G86": true, "G87": true, "G88": true, "G89": true,
+}
+
+// FANUC の演算子・関数と SINUMERIK の対応
+var fanucOperators = map[string]string{
+	"GT": ">", "LT": "<", "GE": ">=", "LE": "<=", "EQ": "==", "NE": "<>", "FIX": "TRUNC",
+}
+
+// 変換後の式で使える SINUMERIK の関数・論理演算子
+var siemensFunctions = map[string]bool{
+	"SIN": true, "COS": true, "TAN": true, "ASIN": true, "ACOS": true, "SQRT": true, "ABS": true,
+	"ROUND": true, "TRUNC": true, "LN": true, "EXP": true, "AND": true, "OR": true, "XOR": true, "MOD": true,
+}
+
+var (
+	fanucComment       = regexp.MustCompile(`\(([^)]*)\)`)
+	fanucProgramNumber = regexp.MustCompile(`^O(\d+)$`)
+	fanucAssignment    = regexp.MustCompile(`^#(\d+)\s*=\s*(.+)$`)
+	fanucWhile         = regexp.MustCompile(`^WHILE\s*\[(.*)\]\s*DO\s*\d$`)
+	fanucEnd           = regexp.MustCompile(`^END\s*\d$`)
+	fanucIfGoto        = regexp.MustCompile(`^IF\s*\[(.*)\]\s*GOTO\s*(\d+)$`)
+	fanucGoto          = regexp.MustCompile(`^GOTO\s*(\d+)$`)
+	fanucStatement     = regexp.MustCompile(`^(IF|WHILE|GOTO|END|DO|#)`)
+	fanucVariable      = regexp.MustCompile(`#(\d+)`)
+	fanucOperator      = regexp.MustCompile(`\b(GT|LT|GE|LE|EQ|NE|FIX)\b`)
+	expressionName     = regexp.MustCompile(`\b[A-Z]{2,}\b`)
+)
+
+// ncWord はアドレスと値の組。expr は値が変数や [式] のもの
+type ncWord struct {
+	address string
+	value   string
+	expr    bool
+}
+
+// scanFanucWords はブロックをアドレスと値に分解する
+func scanFanucWords(code string) ([]ncWord, bool) {
+	var words []ncWord
+	i := 0
+	for i < len(code) {
+		c := code[i]
+		if c == ' ' || c == '\t' {
+			i++
+			continue
+		}
+		if c < 'A' || c > 'Z' {
+			return nil, false
+		}
+		w := ncWord{address: string(c)}
+		i++
+		for i < len(code) && code[i] == ' ' {
+			i++
+		}
+
+		switch {
+		case i < len(code) && code[i] == '[':
+			start, depth := i, 0
+			for ; i < len(code); i++ {
+				if code[i] == '[' {
+					depth++
+				} else if code[i] == ']' {
+					if depth--; depth == 0 {
+						break
+					}
+				}
+			}
+			if i >= len(code) {
+				return nil, false
+			}
+			w.value, w.expr = code[start+1:i], true
+			i++
+		case i < len(code) && (code[i] == '#' || (code[i] == '-' && i+1 < len(code) && code[i+1] == '#')):
+			j := strings.IndexByte(code[i:], '#') + i + 1
+			start := j
+			for j < len(code) && code[j] >= '0' && code[j] <= '9' {
+				j++
+			}
+			if j == start {
+				return nil, false
+			}
+			w.value, w.expr = code[i:j], true
+			i = j
+		default:
+			j := i
+			for j < len(code) && strings.IndexByte("0123456789.+-", code[j]) >= 0 {
+				j++
+			}
+			if j == i || !numberPattern.MatchString(code[i:j]) {
+				return nil, false
+			}
+			w.value = code[i:j]
+			i = j
+		}
+		words = append(words, w)
+	}
+	return words, true
+}
+
+// fanucToSiemens は FANUC から SINUMERIK への変換。固定サイクルの復帰点を決めるためモーダル状態を追跡する
+type fanucToSiemens struct {
+	translationState
+	absolute        bool
+	returnToInitial bool
+	lastZ           *float64
+	activeTool      int
+	toolWords       toolWordFormat
+	cycleActive     bool
+	tcpActive       bool
+}
+
+func newFanucToSiemens(report *TranslationReport, toolWords toolWordFormat) *fanucToSiemens {
+	return &fanucToSiemens{
+		translationState: translationState{report: report},
+		toolWords:        toolWords,
+		absolute:         true,
+		returnToInitial:  true,
+	}
+}
+
+func (t *fanucToSiemens) state() *translationState {
+	return &t.translationState
+}
+
+func (t *fanucToSiemens) commentOut(line string) string {
+	return "; UNTRANSLATED: " + strings.TrimSpace(line)
+}
+
+func (t *fanucToSiemens) finish(lines []string) []string {
+	for len(lines) > 0 && lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+func (t *fanucToSiemens) translateLine(line string) []string {
+	line = strings.TrimSpace(line)
+	if line == "" {
+		return []string{""}
+	}
+	if strings.HasPrefix(line, "%") {
+		t.translated(ConstructProgramDelimiter)
+		return nil
+	}
+
+	var comments []string
+	for _, m := range fanucComment.FindAllStringSubmatch(line, -1) {
+		if c := strings.TrimSpace(m[1]); c != "" {
+			comments = append(comments, c)
+		}
+	}
+	code := strings.ToUpper(strings.TrimSpace(fanucComment.ReplaceAllString(line, " ")))
+	comment := ""
+	if len(comments) > 0 {
+		comment = "; " + strings.Join(comments, " ")
+		t.translated(ConstructComment)
+	}
+	if code == "" {
+		return []string{comment}
+	}
+
+	prefix, code := splitBlockNumber(code)
+	if m := fanucProgramNumber.FindStringSubmatch(code); m != nil {
+		t.translated(ConstructProgramNumber)
+		return []string{joinBlock("; O"+m[1], strings.Join(comments, " "))}
+	}
+	if statement, ok := t.macroStatement(code); ok {
+		return []string{joinBlock(prefix+statement, comment)}
+	}
+
+	words, ok := scanFanucWords(code)
+	if !ok {
+		t.untranslatable(ConstructSyntax, "block could not be parsed")
+		return nil
+	}
+
+	blocks := t.translateWords(words)
+	if len(blocks) == 0 {
+		return []string{joinBlock(prefix, comment)}
+	}
+	blocks[0] = prefix + blocks[0]
+	blocks[len(blocks)-1] = joinBlock(blocks[len(blocks)-1], comment)
+	return blocks
+}
+
+// macroStatement はカスタムマクロの代入・制御文を R パラメータの文に変換する
+func (t *fanucToSiemens) macroStatement(code string) (string, bool) {
+	if m := fanucAssignment.FindStringSubmatch(code); m != nil {
+		n, _ := strconv.Atoi(m[1])
+		if n < 1 || n >= 1000 {
+			t.untranslatable(ConstructMacroVariable, fmt.Sprintf("system variable #%d has no R parameter equivalent", n))
+			return "", true
+		}
+		t.translated(ConstructMacroVariable)
+		return fmt.Sprintf("R%d=%s", n, t.expression(m[2])), true
+	}
+	if m := fanucWhile.FindStringSubmatch(code); m != nil {
+		t.translated(ConstructMacroStatement)
+		return "WHILE " + t.expression(m[1]), true
+	}
+	if fanucEnd.MatchString(code) {
+		t.translated(ConstructMacroStatement)
+		return "ENDWHILE", true
+	}
+	if m := fanucIfGoto.FindStringSubmatch(code); m != nil {
+		t.translated(ConstructMacroStatement)
+		return fmt.Sprintf("IF %s GOTO N%s", t.expression(m[1]), m[2]), true
+	}
+	if m := fanucGoto.FindStringSubmatch(code); m != nil {
+		t.translated(ConstructMacroStatement)
+		return "GOTO N" + m[1], true
+	}
+	if fanucStatement.MatchString(code) {
+		t.untranslatable(ConstructMacroStatement, "unsupported macro statement")
+		return "", true
+	}
+	return "", false
+}
+
+// expression はマクロの式を SINUMERIK の式に変換する
+func (t *fanucToSiemens) expression(expr string) string {
+	if strings.Contains(expr, "#[") {
+		t.untranslatable(ConstructMacroVariable, "indirect variable reference has no R parameter equivalent")
+		return expr
+	}
+	expr = fanucVariable.ReplaceAllStringFunc(expr, func(v string) string {
+		n, _ := strconv.Atoi(v[1:])
+		if n < 1 || n >= 1000 {
+			t.untranslatable(ConstructMacroVariable, fmt.Sprintf("system variable %s has no R parameter equivalent", v))
+			return v
+		}
+		return "R" + v[1:]
+	})
+	expr = strings.NewReplacer("[", "(", "]", ")").Replace(expr)
+	expr = fanucOperator.ReplaceAllStringFunc(expr, func(op string) string {
+		return fanucOperators[op]
+	})
+	for _, name := range expressionName.FindAllString(expr, -1) {
+		if !siemensFunctions[name] {
+			t.untranslatable(ConstructMacroStatement, "function "+name+" has no SINUMERIK equivalent")
+		}
+	}
+	t.translated(ConstructMacroVariable)
+	return strings.TrimSpace(expr)
+}
+
+func (t *fanucToSiemens) translateWords(words []ncWord) []string {
+	gCodes := make(map[string]bool)
+	for _, w := range words {
+		if w.address == "G" && !w.expr {
+			if code, ok := normalizeGCode(w.value); ok {
+				gCodes[code] = true
+			}
+		}
+	}
+	switch {
+	case gCodes["G90"]:
+		t.absolute = true
+	case gCodes["G91"]:
+		t.absolute = false
+	}
+	switch {
+	case gCodes["G98"]:
+		t.returnToInitial = true
+	case gCodes["G99"]:
+		t.returnToInitial = false
+	}
+
+	for _, code := range []string{"G81", "G82", "G83"} {
+		if gCodes[code] {
+			return t.cannedCycle(code, words)
+		}
+	}
+	if t.cycleActive && !gCodes["G80"] && (findWord(words, "Z") != nil || findWord(words, "R") != nil) {
+		t.untranslatable(ConstructCannedCycle, "changing the canned cycle depth or R plane requires a new cycle call")
+		return nil
+	}
+
+	used := make([]bool, len(words))
+	var out []string
+	// G コードが使うアドレス（P、H 等）を先に取り出すため、G コードを先に変換する
+	for i, w := range words {
+		if w.address != "G" {
+			continue
+		}
+		used[i] = true
+		if w.expr {
+			t.untranslatable(ConstructGCode, "G code given by a variable")
+			continue
+		}
+		code, _ := normalizeGCode(w.value)
+		out = append(out, t.gCode(code, words, used)...)
+	}
+	for i, w := range words {
+		if used[i] {
+			continue
+		}
+		used[i] = true
+		out = append(out, t.word(w, words, used)...)
+	}
+
+	if z := findWord(words, "Z"); z != nil && !gCodes["G53"] {
+		if v, err := strconv.ParseFloat(z.value, 64); err == nil && !z.expr && t.absolute {
+			t.lastZ = &v
+		} else {
+			t.lastZ = nil
+		}
+	}
+
+	if len(out) == 0 {
+		return nil
+	}
+	return []string{strings.Join(out, " ")}
+}
+
+func (t *fanucToSiemens) gCode(code string, words []ncWord, used []bool) []string {
+	switch {
+	case portableGCodes[code]:
+		return []string{siemensGCode(code)}
+	case code == "G98" || code == "G99":
+		// SINUMERIK では復帰点をサイクルの引数で指定する
+		return nil
+	case code == "G20" || code == "G21":
+		t.translated(ConstructUnits)
+		if code == "G20" {
+			return []string{"G70"}
+		}
+		return []string{"G71"}
+	case code == "G58":
+		t.translated(ConstructWorkOffset)
+		return []string{"G505"}
+	case code == "G59":
+		t.translated(ConstructWorkOffset)
+		return []string{"G506"}
+	case code == "G54.1":
+		p := takeWord(words, used, "P")
+		n, err := wordInt(p)
+		if err != nil || n < 1 || n > 48 {
+			t.untranslatable(ConstructWorkOffset, "G54.1 requires P1-P48")
+			return nil
+		}
+		t.translated(ConstructWorkOffset)
+		return []string{fmt.Sprintf("G%d", 506+n)}
+	case code == "G04":
+		var seconds float64
+		if p := takeWord(words, used, "P"); p != nil && !p.expr {
+			ms, _ := strconv.ParseFloat(p.value, 64)
+			seconds = ms / 1000
+		} else if x := takeWord(words, used, "X"); x != nil && !x.expr {
+			seconds, _ = strconv.ParseFloat(x.value, 64)
+		} else {
+			t.untranslatable(ConstructDwell, "dwell time must be a constant P or X")
+			return nil
+		}
+		t.translated(ConstructDwell)
+		return []string{"G4", "F" + formatNumber(seconds)}
+	case code == "G43":
+		if t.toolOffset(takeWord(words, used, "H")) {
+			return []string{"D1"}
+		}
+		return nil
+	case code == "G43.4" || code == "G43.5":
+		takeWord(words, used, "H")
+		t.tcpActive = true
+		t.translated(ConstructToolCenterPoint)
+		return []string{"TRAORI"}
+	case code == "G49":
+		if t.tcpActive {
+			t.tcpActive = false
+			t.translated(ConstructToolCenterPoint)
+			return []string{"TRAFOOF"}
+		}
+		t.translated(ConstructToolOffset)
+		return []string{"D0"}
+	case code == "G80":
+		t.cycleActive = false
+		t.translated(ConstructCannedCycle)
+		return []string{"MCALL"}
+	case code == "G65" || code == "G66" || code == "G67":
+		t.untranslatable(ConstructMacroCall, "custom macro calls need an equivalent SINUMERIK cycle")
+	case code == "G10":
+		t.untranslatable(ConstructDataSetting, "programmable data input (G10) has no direct equivalent")
+	case code == "G28" || code == "G30":
+		t.untranslatable(ConstructReferenceReturn, "reference point return must use the machine's SINUMERIK home position")
+	case fanucCannedCycles[code]:
+		t.untranslatable(ConstructCannedCycle, code+" canned cycle is not supported")
+	default:
+		t.untranslatable(ConstructGCode, code+" has no SINUMERIK mapping")
+	}
+	return nil
+}
+
+// toolOffset は H/D 番号が現在の工具番号と一致する場合のみ SINUMERIK の刃先 D1 に対応付ける
+func (t *fanucToSiemens) toolOffset(w *ncWord) bool {
+	n, err := wordInt(w)
+	if err != nil || t.activeTool == 0 || n != t.activeTool {
+		t.untranslatable(ConstructToolOffset, "offset number must match the active tool to map to a SINUMERIK cutting edge")
+		return false
+	}
+	t.translated(ConstructToolOffset)
+	return true
+}
+
+func (t *fanucToSiemens) word(w ncWord, words []ncWord, used []bool) []string {
+	if w.expr {
+		if w.address == "T" || w.address == "M" {
+			t.untranslatable(ConstructMacroVariable, w.address+" word given by a variable")
+			return nil
+		}
+		return []string{w.address + "=" + t.expression(w.value)}
+	}
+
+	switch w.address {
+	case "T":
+		digits := strings.TrimLeft(w.value, "+")
+		n, err := strconv.Atoi(digits)
+		if err != nil || n < 0 {
+			t.untranslatable(ConstructToolCall, "invalid tool number")
+			return nil
+		}
+		// 旋盤の T0101 は工具番号と補正番号、マシニングセンターの T123 は工具番号
+		if len(digits) >= 3 {
+			switch t.toolWords {
+			case toolWordTurning:
+				tool, _ := strconv.Atoi(digits[:len(digits)-2])
+				offset, _ := strconv.Atoi(digits[len(digits)-2:])
+				t.translated(ConstructToolCall)
+				t.activeTool = tool
+				return []string{fmt.Sprintf("T%d", tool), fmt.Sprintf("D%d", offset)}
+			case toolWordUnknown:
+				t.untranslatable(ConstructToolCall, "target machine type is unknown, T"+digits+" may include an offset number")
+				return nil
+			}
+		}
+		t.translated(ConstructToolCall)
+		t.activeTool = n
+		return []string{fmt.Sprintf("T%d", n)}
+	case "M":
+		n, _ := strconv.Atoi(w.value)
+		switch n {
+		case 98:
+			p, err := wordInt(takeWord(words, used, "P"))
+			if err != nil {
+				t.untranslatable(ConstructSubprogram, "M98 requires a P program number")
+				return nil
+			}
+			t.translated(ConstructSubprogram)
+			return []string{fmt.Sprintf("L%d", p)}
+		case 99:
+			t.translated(ConstructSubprogram)
+			return []string{"M17"}
+		}
+		return []string{fmt.Sprintf("M%d", n)}
+	case "D":
+		if t.toolOffset(&w) {
+			return []string{"D1"}
+		}
+		return nil
+	case "H", "P", "Q", "L", "K", "O":
+		// 変換できなかった G コードの引数は G コードとして報告済み
+		if !t.failed {
+			t.untranslatable(ConstructSyntax, w.address+" word has no SINUMERIK equivalent in this block")
+		}
+		return nil
+	}
+	return []string{w.address + w.value}
+}
+
+// cannedCycle は G81/G82/G83 を MCALL CYCLE81/82/83 と位置決めブロックに変換する
+func (t *fanucToSiemens) cannedCycle(code string, words []ncWord) []string {
+	if !t.absolute {
+		t.untranslatable(ConstructCannedCycle, "incremental canned cycles are not supported")
+		return nil
+	}
+
+	values := make(map[string]float64)
+	for _, w := range words {
+		switch {
+		case w.address == "G":
+			if g, _ := normalizeGCode(w.value); g != code && g != "G90" && g != "G98" && g != "G99" {
+				t.untranslatable(ConstructCannedCycle, g+" cannot be combined with a canned cycle")
+				return nil
+			}
+			continue
+		case w.expr:
+			t.untranslatable(ConstructCannedCycle, "canned cycle parameters must be constants")
+			return nil
+		case strings.Contains("XYZRPQF", w.address):
+			values[w.address], _ = strconv.ParseFloat(w.value, 64)
+		default:
+			t.untranslatable(ConstructCannedCycle, w.address+" word is not supported in canned cycles")
+			return nil
+		}
+	}
+	z, hasZ := values["Z"]
+	r, hasR := values["R"]
+	if !hasZ || !hasR {
+		t.untranslatable(ConstructCannedCycle, "canned cycle requires Z and R")
+		return nil
+	}
+	rtp := r
+	if t.returnToInitial {
+		if t.lastZ == nil {
+			t.untranslatable(ConstructCannedCycle, "initial level for G98 is unknown")
+			return nil
+		}
+		rtp = *t.lastZ
+	}
+
+	var call string
+	switch code {
+	case "G81":
+		call = fmt.Sprintf("CYCLE81(%s,%s,0,%s)", formatNumber(rtp), formatNumber(r), formatNumber(z))
+	case "G82":
+		call = fmt.Sprintf("CYCLE82(%s,%s,0,%s,,%s)", formatNumber(rtp), formatNumber(r), formatNumber(z), formatNumber(values["P"]/1000))
+	case "G83":
+		q, ok := values["Q"]
+		if !ok || q <= 0 {
+			t.untranslatable(ConstructCannedCycle, "G83 requires a positive Q")
+			return nil
+		}
+		call = fmt.Sprintf("CYCLE83(%s,%s,0,%s,,%s,,0,0,0,1,1)", formatNumber(rtp), formatNumber(r), formatNumber(z), formatNumber(r-q))
+	}
+
+	var blocks []string
+	if f, ok := values["F"]; ok {
+		blocks = append(blocks, "F"+formatNumber(f))
+	}
+	blocks = append(blocks, "MCALL "+call)
+	var position []string
+	for _, axis := range []string{"X", "Y"} {
+		if v, ok := values[axis]; ok {
+			position = append(position, axis+formatNumber(v))
+		}
+	}
+	if len(position) > 0 {
+		blocks = append(blocks, "G0 "+strings.Join(position, " "))
+	}
+	t.cycleActive = true
+	t.translated(ConstructCannedCycle)
+	return blocks
+}
+
+// siemensGCode は G コードを SINUMERIK の表記（"G00" → "G0"）にする
+func siemensGCode(code string) string {
+	if len(code) == 3 && code[1] == '0' {
+		return "G" + code[2:]
+	}
+	return code
+}
+
+func findWord(words []ncWord, address string) *ncWord {
+	for i := range words {
+		if words[i].address == address {
+			return &words[i]
+		}
+	}
+	return nil
+}
+
+// takeWord は未使用のアドレスを取り出して使用済みにする
+func takeWord(words []ncWord, used []bool, address string) *ncWord {
+	for i := range words {
+		if !used[i] && words[i].address == address {
+			used[i] = true
+			return &words[i]
+		}
+	}
+	return nil
+}
+
+func wordInt(w *ncWord) (int, error) {
+	if w == nil || w.expr {
+		return 0, ErrUnsupportedTranslation
+	}
+	return strconv.Atoi(strings.TrimSuffix(w.value, "."))
+}
@@ -0,0 +1,509 @@
+package domain
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// SINUMERIK の比較演算子と FANUC の対応
+var siemensOperators = map[string]string{
+	">=": " GE ", "<=": " LE ", "==": " EQ ", "<>": " NE ", ">": " GT ", "<": " LT ",
+}
+
+// 変換後の式で使える FANUC の関数・演算子
+var fanucFunctions = map[string]bool{
+	"SIN": true, "COS": true, "TAN": true, "ASIN": true, "ACOS": true, "ATAN": true, "SQRT": true, "ABS": true,
+	"ROUND": true, "FIX": true, "FUP": true, "LN": true, "EXP": true, "AND": true, "OR": true, "XOR": true, "MOD": true,
+	"GT": true, "LT": true, "GE": true, "LE": true, "EQ": true, "NE": true,
+}
+
+// 小数点を付ける寸法アドレス
+const dimensionAddresses = "XYZABCUVWIJK"
+
+var (
+	siemensProgramNumber = regexp.MustCompile(`^O(\d+)\s*(.*)$`)
+	siemensWhile         = regexp.MustCompile(`^WHILE\s+(.+)$`)
+	siemensIfGoto        = regexp.MustCompile(`^IF\s+(.+?)\s+GOTO[FB]?\s+N?(\d+)$`)
+	siemensGoto          = regexp.MustCompile(`^GOTO[FB]?\s+N?(\d+)$`)
+	siemensStatement     = regexp.MustCompile(`^(IF|ELSE|ENDIF|WHILE|ENDWHILE|REPEAT|UNTIL|LOOP|ENDLOOP|FOR|ENDFOR|GOTO[FBC]?|CASE|DEF|PROC|DEFINE|MSG|STOPRE)\b`)
+	siemensAssignment    = regexp.MustCompile(`^R(\d+)=(.+)$`)
+	siemensAddressExpr   = regexp.MustCompile(`^([A-Z])=(.+)$`)
+	siemensWord          = regexp.MustCompile(`^([A-Z])([-+]?(?:\d+\.?\d*|\.\d+))$`)
+	siemensCycle         = regexp.MustCompile(`^CYCLE(8[123])\((.*)\)$`)
+	siemensVariable      = regexp.MustCompile(`\bR(\d+)\b`)
+	siemensComparison    = regexp.MustCompile(`>=|<=|==|<>|>|<`)
+	siemensOperatorSpace = regexp.MustCompile(`\s*([=+*/])\s*|\s*-\s+`)
+)
+
+// siemensToFanuc は SINUMERIK から FANUC への変換。MCALL の固定サイクルは次の位置決めブロックに付ける
+type siemensToFanuc struct {
+	translationState
+	started      bool
+	activeTool   int
+	toolWords    toolWordFormat
+	whileDepth   int
+	pendingCycle []string
+	cycleActive  bool
+}
+
+func newSiemensToFanuc(report *TranslationReport, toolWords toolWordFormat) *siemensToFanuc {
+	return &siemensToFanuc{translationState: translationState{report: report}, toolWords: toolWords}
+}
+
+func (t *siemensToFanuc) state() *translationState {
+	return &t.translationState
+}
+
+func (t *siemensToFanuc) commentOut(line string) string {
+	return "(UNTRANSLATED: " + fanucCommentText(line) + ")"
+}
+
+// finish はプログラムの前後に "%" を付ける
+func (t *siemensToFanuc) finish(lines []string) []string {
+	for len(lines) > 0 && lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	t.report.Translated[ConstructProgramDelimiter]++
+	return append(append([]string{"%"}, lines...), "%")
+}
+
+func (t *siemensToFanuc) translateLine(line string) []string {
+	line = strings.TrimSpace(line)
+	code, comment, _ := strings.Cut(line, ";")
+	code = strings.ToUpper(strings.TrimSpace(code))
+	comment = strings.TrimSpace(comment)
+	if code == "" {
+		if comment == "" {
+			return []string{""}
+		}
+		if m := siemensProgramNumber.FindStringSubmatch(comment); m != nil && !t.started {
+			t.started = true
+			t.translated(ConstructProgramNumber)
+			if m[2] == "" {
+				return []string{"O" + m[1]}
+			}
+			return []string{"O" + m[1] + " (" + fanucCommentText(m[2]) + ")"}
+		}
+		t.translated(ConstructComment)
+		return []string{"(" + fanucCommentText(comment) + ")"}
+	}
+
+	t.started = true
+	suffix := ""
+	if comment != "" {
+		suffix = "(" + fanucCommentText(comment) + ")"
+		t.translated(ConstructComment)
+	}
+	prefix, code := splitBlockNumber(code)
+	if strings.Contains(code, "$") {
+		t.untranslatable(ConstructMacroVariable, "system variables have no FANUC equivalent")
+		return nil
+	}
+	if statement, ok := t.statement(code); ok {
+		return []string{joinBlock(prefix+statement, suffix)}
+	}
+
+	blocks := t.translateTokens(splitSiemensTokens(code))
+	if len(blocks) == 0 {
+		return []string{joinBlock(prefix, suffix)}
+	}
+	blocks[0] = prefix + blocks[0]
+	blocks[len(blocks)-1] = joinBlock(blocks[len(blocks)-1], suffix)
+	return blocks
+}
+
+// statement は制御文をカスタムマクロの文に変換する
+func (t *siemensToFanuc) statement(code string) (string, bool) {
+	if m := siemensWhile.FindStringSubmatch(code); m != nil {
+		if t.whileDepth == 3 {
+			t.untranslatable(ConstructMacroStatement, "FANUC allows at most three nested WHILE loops")
+			return "", true
+		}
+		t.whileDepth++
+		t.translated(ConstructMacroStatement)
+		return fmt.Sprintf("WHILE [%s] DO%d", t.expression(m[1]), t.whileDepth), true
+	}
+	if code == "ENDWHILE" {
+		if t.whileDepth == 0 {
+			t.untranslatable(ConstructMacroStatement, "ENDWHILE without WHILE")
+			return "", true
+		}
+		t.whileDepth--
+		t.translated(ConstructMacroStatement)
+		return fmt.Sprintf("END%d", t.whileDepth+1), true
+	}
+	if m := siemensIfGoto.FindStringSubmatch(code); m != nil {
+		t.translated(ConstructMacroStatement)
+		return fmt.Sprintf("IF [%s] GOTO%s", t.expression(m[1]), m[2]), true
+	}
+	if m := siemensGoto.FindStringSubmatch(code); m != nil {
+		t.translated(ConstructMacroStatement)
+		return "GOTO" + m[1], true
+	}
+	if siemensStatement.MatchString(code) {
+		t.untranslatable(ConstructMacroStatement, "statement has no FANUC custom macro equivalent")
+		return "", true
+	}
+	return "", false
+}
+
+// expression は R パラメータの式をカスタムマクロの式に変換する
+func (t *siemensToFanuc) expression(expr string) string {
+	expr = siemensVariable.ReplaceAllStringFunc(expr, func(v string) string {
+		n, _ := strconv.Atoi(v[1:])
+		if n < 1 || n >= 1000 {
+			t.untranslatable(ConstructMacroVariable, fmt.Sprintf("%s has no FANUC common variable equivalent", v))
+			return v
+		}
+		return "#" + v[1:]
+	})
+	expr = strings.NewReplacer("(", "[", ")", "]").Replace(expr)
+	expr = siemensComparison.ReplaceAllStringFunc(expr, func(op string) string {
+		return siemensOperators[op]
+	})
+	expr = strings.ReplaceAll(expr, "TRUNC", "FIX")
+	for _, name := range expressionName.FindAllString(expr, -1) {
+		if !fanucFunctions[name] {
+			t.untranslatable(ConstructMacroStatement, "function "+name+" has no FANUC equivalent")
+		}
+	}
+	t.translated(ConstructMacroVariable)
+	return strings.Join(strings.Fields(expr), " ")
+}
+
+func (t *siemensToFanuc) translateTokens(tokens []string) []string {
+	assignments := len(tokens) > 0
+	for _, token := range tokens {
+		assignments = assignments && siemensAssignment.MatchString(token)
+	}
+	if assignments {
+		blocks := make([]string, len(tokens))
+		for i, token := range tokens {
+			m := siemensAssignment.FindStringSubmatch(token)
+			blocks[i] = "#" + m[1] + "=" + t.expression(m[2])
+		}
+		return blocks
+	}
+
+	var out, cycle []string
+	var mcall, dwell, radiusComp bool
+	toolWithEdge := false
+	for _, token := range tokens {
+		if m := siemensWord.FindStringSubmatch(token); m != nil {
+			switch m[1] {
+			case "T":
+				toolWithEdge = true
+			case "G":
+				if g, _ := normalizeGCode(m[2]); g == "G41" || g == "G42" {
+					radiusComp = true
+				}
+			}
+		}
+	}
+	toolWithEdge = toolWithEdge && hasSiemensWord(tokens, "D")
+
+	for _, token := range tokens {
+		switch {
+		case token == "MCALL":
+			mcall = true
+		case siemensCycle.MatchString(token):
+			m := siemensCycle.FindStringSubmatch(token)
+			if cycle = t.cycle(m[1], m[2]); cycle == nil {
+				return nil
+			}
+		case strings.HasPrefix(token, "CYCLE"):
+			t.untranslatable(ConstructCannedCycle, strings.SplitN(token, "(", 2)[0]+" has no FANUC canned cycle equivalent")
+		case token == "TRAORI":
+			t.translated(ConstructToolCenterPoint)
+			out = append(out, "G43.4")
+			if t.activeTool > 0 {
+				out = append(out, fmt.Sprintf("H%02d", t.activeTool))
+			}
+		case token == "TRAFOOF":
+			t.translated(ConstructToolCenterPoint)
+			out = append(out, "G49")
+		case token == "RET":
+			t.translated(ConstructSubprogram)
+			out = append(out, "M99")
+		case siemensAssignment.MatchString(token):
+			t.untranslatable(ConstructMacroVariable, "parameter assignments must be in their own block")
+		case siemensAddressExpr.MatchString(token):
+			m := siemensAddressExpr.FindStringSubmatch(token)
+			out = append(out, m[1]+"["+t.expression(m[2])+"]")
+		case siemensWord.MatchString(token):
+			m := siemensWord.FindStringSubmatch(token)
+			words, isDwell := t.word(m[1], m[2], dwell, toolWithEdge, radiusComp, tokens)
+			dwell = dwell || isDwell
+			out = append(out, words...)
+		default:
+			t.untranslatable(ConstructKeyword, token+" has no FANUC equivalent")
+		}
+	}
+	if t.failed {
+		return nil
+	}
+
+	positioned := hasSiemensWord(tokens, "X") || hasSiemensWord(tokens, "Y")
+	switch {
+	case mcall && cycle != nil:
+		t.translated(ConstructCannedCycle)
+		if positioned {
+			t.cycleActive = true
+			return []string{cycle[0], strings.Join(append(cycle[1:], withoutMotionCodes(out)...), " ")}
+		}
+		t.pendingCycle = cycle[1:]
+		return append([]string{cycle[0]}, nonEmptyBlocks(out)...)
+	case mcall:
+		t.translated(ConstructCannedCycle)
+		wasActive := t.cycleActive || t.pendingCycle != nil
+		t.cycleActive, t.pendingCycle = false, nil
+		if wasActive {
+			out = append(out, "G80")
+		}
+		return nonEmptyBlocks(out)
+	case cycle != nil:
+		t.translated(ConstructCannedCycle)
+		return []string{cycle[0], strings.Join(append(cycle[1:], withoutMotionCodes(out)...), " "), "G80"}
+	case t.pendingCycle != nil && positioned:
+		out = append(append([]string{}, t.pendingCycle...), withoutMotionCodes(out)...)
+		t.cycleActive, t.pendingCycle = true, nil
+	case t.cycleActive:
+		out = withoutMotionCodes(out)
+	}
+	return nonEmptyBlocks(out)
+}
+
+// word は 1 つのアドレスを変換する。2 つめの戻り値はドウェル (G4) の開始
+func (t *siemensToFanuc) word(address, value string, dwell, toolWithEdge, radiusComp bool, tokens []string) ([]string, bool) {
+	v, _ := strconv.ParseFloat(value, 64)
+	switch address {
+	case "G":
+		code, _ := normalizeGCode(value)
+		return t.gCode(code), code == "G04"
+	case "F":
+		if dwell {
+			t.translated(ConstructDwell)
+			return []string{"X" + formatFanucNumber(v)}, false
+		}
+		return []string{"F" + value}, false
+	case "S":
+		if dwell {
+			t.untranslatable(ConstructDwell, "dwell in spindle revolutions has no FANUC equivalent")
+			return nil, false
+		}
+		return []string{"S" + value}, false
+	case "T":
+		t.activeTool = int(v)
+		// 旋盤は刃先を補正番号として T0501 にする。マシニングセンターでは D を工具長補正にする
+		if toolWithEdge {
+			switch t.toolWords {
+			case toolWordTurning:
+				edge, _ := strconv.Atoi(siemensWordValue(tokens, "D"))
+				t.translated(ConstructToolCall)
+				return []string{fmt.Sprintf("T%02d%02d", t.activeTool, edge)}, false
+			case toolWordUnknown:
+				t.untranslatable(ConstructToolCall, "target machine type is unknown, T with D may be a turning tool offset or a tool length offset")
+				return nil, false
+			}
+		}
+		t.translated(ConstructToolCall)
+		return []string{fmt.Sprintf("T%d", t.activeTool)}, false
+	case "D":
+		switch {
+		case toolWithEdge && t.toolWords == toolWordTurning:
+			return nil, false
+		case v == 0:
+			t.translated(ConstructToolOffset)
+			return []string{"G49"}, false
+		case t.activeTool == 0:
+			t.untranslatable(ConstructToolOffset, "active tool is unknown for the offset number")
+			return nil, false
+		}
+		t.translated(ConstructToolOffset)
+		if radiusComp {
+			return []string{fmt.Sprintf("D%02d", t.activeTool)}, false
+		}
+		return []string{"G43", fmt.Sprintf("H%02d", t.activeTool)}, false
+	case "L":
+		t.translated(ConstructSubprogram)
+		return []string{"M98", "P" + value}, false
+	case "M":
+		if v == 17 {
+			t.translated(ConstructSubprogram)
+			return []string{"M99"}, false
+		}
+		return []string{"M" + value}, false
+	}
+	if strings.Contains(dimensionAddresses, address) {
+		if !strings.Contains(value, ".") {
+			t.translated(ConstructDecimalPoint)
+			value += "."
+		}
+		return []string{address + value}, false
+	}
+	t.untranslatable(ConstructSyntax, address+" word has no FANUC equivalent")
+	return nil, false
+}
+
+func (t *siemensToFanuc) gCode(code string) []string {
+	number, _ := strconv.Atoi(strings.TrimPrefix(code, "G"))
+	switch {
+	case portableGCodes[code]:
+		return []string{code}
+	case code == "G04":
+		return []string{"G04"}
+	case code == "G70" || code == "G700":
+		t.translated(ConstructUnits)
+		return []string{"G20"}
+	case code == "G71" || code == "G710":
+		t.translated(ConstructUnits)
+		return []string{"G21"}
+	case code == "G505":
+		t.translated(ConstructWorkOffset)
+		return []string{"G58"}
+	case code == "G506":
+		t.translated(ConstructWorkOffset)
+		return []string{"G59"}
+	case number >= 507 && number <= 554:
+		t.translated(ConstructWorkOffset)
+		return []string{"G54.1", fmt.Sprintf("P%d", number-506)}
+	}
+	t.untranslatable(ConstructGCode, code+" has no FANUC mapping")
+	return nil
+}
+
+// cycle は CYCLE81/82/83 の引数を FANUC の固定サイクルに変換する。
+// 先頭の要素は初期点レベルへの位置決めブロック、残りはサイクルのアドレス
+func (t *siemensToFanuc) cycle(number, arguments string) []string {
+	args := strings.Split(arguments, ",")
+	values := make([]*float64, len(args))
+	for i, arg := range args {
+		if arg = strings.TrimSpace(arg); arg == "" {
+			continue
+		}
+		v, err := strconv.ParseFloat(arg, 64)
+		if err != nil {
+			t.untranslatable(ConstructCannedCycle, "cycle parameters must be constants")
+			return nil
+		}
+		values[i] = &v
+	}
+	arg := func(i int) (float64, bool) {
+		if i < len(values) && values[i] != nil {
+			return *values[i], true
+		}
+		return 0, false
+	}
+
+	rtp, hasRTP := arg(0)
+	rfp, hasRFP := arg(1)
+	sdis, _ := arg(2)
+	dp, hasDP := arg(3)
+	if !hasDP {
+		if dpr, ok := arg(4); ok {
+			dp, hasDP = rfp-dpr, true
+		}
+	}
+	if !hasRTP || !hasRFP || !hasDP {
+		t.untranslatable(ConstructCannedCycle, "CYCLE"+number+" requires RTP, RFP and DP")
+		return nil
+	}
+	rPlane := rfp + sdis
+
+	pre := "G00 Z" + formatFanucNumber(rtp)
+	retract := "G98"
+	if rtp == rPlane {
+		retract = "G99"
+	}
+	words := []string{pre, retract}
+	switch number {
+	case "81":
+		words = append(words, "G81")
+	case "82":
+		dtb, _ := arg(5)
+		words = append(words, "G82", fmt.Sprintf("P%d", int(math.Round(dtb*1000))))
+	case "83":
+		fdep, ok := arg(5)
+		if !ok {
+			if fdpr, hasFDPR := arg(6); hasFDPR {
+				fdep, ok = rfp-fdpr, true
+			}
+		}
+		if dam, _ := arg(7); !ok || rfp-fdep <= 0 || dam != 0 {
+			t.untranslatable(ConstructCannedCycle, "CYCLE83 requires a first drilling depth and no degression")
+			return nil
+		}
+		code := "G83"
+		if vari, ok := arg(10); ok && vari == 0 {
+			code = "G73"
+		}
+		words = append(words, code, "Q"+formatFanucNumber(rfp-fdep))
+	}
+	return append(words, "Z"+formatFanucNumber(dp), "R"+formatFanucNumber(rPlane))
+}
+
+// splitSiemensTokens はブロックを空白で分割する（括弧内の空白と演算子前後の空白は区切りにしない）
+func splitSiemensTokens(code string) []string {
+	code = siemensOperatorSpace.ReplaceAllStringFunc(code, strings.TrimSpace)
+	var tokens []string
+	var current strings.Builder
+	depth := 0
+	for _, r := range code {
+		switch {
+		case r == '(':
+			depth++
+		case r == ')':
+			depth--
+		case (r == ' ' || r == '\t') && depth == 0:
+			if current.Len() > 0 {
+				tokens = append(tokens, current.String())
+				current.Reset()
+			}
+			continue
+		}
+		current.WriteRune(r)
+	}
+	if current.Len() > 0 {
+		tokens = append(tokens, current.String())
+	}
+	return tokens
+}
+
+func hasSiemensWord(tokens []string, address string) bool {
+	return siemensWordValue(tokens, address) != ""
+}
+
+func siemensWordValue(tokens []string, address string) string {
+	for _, token := range tokens {
+		if m := siemensWord.FindStringSubmatch(token); m != nil && m[1] == address {
+			return m[2]
+		}
+	}
+	return ""
+}
+
+// fanucCommentText は FANUC のコメントに入れられない括弧を置き換える
+func fanucCommentText(text string) string {
+	return strings.NewReplacer("(", "[", ")", "]").Replace(strings.TrimSpace(text))
+}
+
+// withoutMotionCodes は固定サイクルのブロックから G00/G01 を除く（FANUC では 01 群の G コードでサイクルが取り消されるため）
+func withoutMotionCodes(words []string) []string {
+	var kept []string
+	for _, w := range words {
+		if w != "G00" && w != "G01" {
+			kept = append(kept, w)
+		}
+	}
+	return kept
+}
+
+func nonEmptyBlocks(words []string) []string {
+	if len(words) == 0 {
+		return nil
+	}
+	return []string{strings.Join(words, " ")}
+}
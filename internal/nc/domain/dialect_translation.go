@@ -0,0 +1,225 @@
+package domain
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// 方言変換で扱う構文の種類
+const (
+	ConstructComment          = "comment"
+	ConstructProgramDelimiter = "program_delimiter"
+	ConstructProgramNumber    = "program_number"
+	ConstructUnits            = "units"
+	ConstructWorkOffset       = "work_offset"
+	ConstructCannedCycle      = "canned_cycle"
+	ConstructMacroVariable    = "macro_variable"
+	ConstructMacroStatement   = "macro_statement"
+	ConstructMacroCall        = "macro_call"
+	ConstructSubprogram       = "subprogram"
+	ConstructDwell            = "dwell"
+	ConstructToolCall         = "tool_call"
+	ConstructToolOffset       = "tool_offset"
+	ConstructToolCenterPoint  = "tool_center_point"
+	ConstructDecimalPoint     = "decimal_point"
+	ConstructDataSetting      = "data_setting"
+	ConstructReferenceReturn  = "reference_return"
+	ConstructGCode            = "g_code"
+	ConstructKeyword          = "keyword"
+	ConstructSyntax           = "syntax"
+)
+
+// UntranslatedConstruct は変換できなかった構文。該当行は変換先の方言のコメントとして残す
+type UntranslatedConstruct struct {
+	Line      int
+	Text      string
+	Construct string
+	Reason    string
+}
+
+// TranslationReport は方言変換の結果。Translated は変換した構文の種類ごとの件数
+type TranslationReport struct {
+	From         Dialect
+	To           Dialect
+	Translated   map[string]int
+	Untranslated []UntranslatedConstruct
+}
+
+// IsComplete はすべての行を変換できたか判定する
+func (r *TranslationReport) IsComplete() bool {
+	return len(r.Untranslated) == 0
+}
+
+// lineTranslator は方言の組み合わせごとの行単位の変換
+type lineTranslator interface {
+	state() *translationState
+	translateLine(line string) []string
+	// commentOut は変換できなかった行を変換先のコメントにする
+	commentOut(line string) string
+	finish(lines []string) []string
+}
+
+// toolWordFormat は変換先の機械タイプによる T ワードの形式
+type toolWordFormat int
+
+const (
+	// toolWordUnknown は機械タイプが不明で T ワードの形式を決められない
+	toolWordUnknown toolWordFormat = iota
+	// toolWordMilling は T ワード全体が工具番号（マシニングセンターの T123 は工具番号 123）
+	toolWordMilling
+	// toolWordTurning は下2桁が補正番号（旋盤の T0101 は工具番号 1、補正番号 1）
+	toolWordTurning
+)
+
+func toolWordFormatFor(machineType string) toolWordFormat {
+	switch {
+	case !validMachineTypes[machineType]:
+		return toolWordUnknown
+	case UsesToolOffsetWords(machineType):
+		return toolWordTurning
+	default:
+		return toolWordMilling
+	}
+}
+
+// TranslateProgramContent は NC プログラムを別の方言へ変換する。
+// 対応する組み合わせは FANUC ⇔ SINUMERIK で、変換できない行は "UNTRANSLATED:" を付けたコメントとして残し、報告に含める。
+// T ワードの形式は変換先の machineType で決め、不明な場合は形式により意味が変わる T ワードを変換しない
+func TranslateProgramContent(content string, from, to Dialect, machineType string) (string, *TranslationReport, error) {
+	report := &TranslationReport{From: from, To: to, Translated: make(map[string]int)}
+
+	toolWords := toolWordFormatFor(machineType)
+	var translator lineTranslator
+	switch {
+	case from == DialectFanuc && to == DialectSiemens:
+		translator = newFanucToSiemens(report, toolWords)
+	case from == DialectSiemens && to == DialectFanuc:
+		translator = newSiemensToFanuc(report, toolWords)
+	default:
+		return "", nil, ErrUnsupportedTranslation
+	}
+
+	var lines []string
+	for i, line := range strings.Split(strings.ReplaceAll(content, "\r\n", "\n"), "\n") {
+		s := translator.state()
+		s.begin(i+1, line)
+		translated := translator.translateLine(line)
+		if s.failed {
+			translated = []string{translator.commentOut(line)}
+		} else {
+			s.commit()
+		}
+		lines = append(lines, translated...)
+	}
+	lines = translator.finish(lines)
+
+	return strings.Join(lines, "\n"), report, nil
+}
+
+// translationState は行の変換中の状態。行に変換できない構文があれば、その行で変換した件数は数えない
+type translationState struct {
+	report  *TranslationReport
+	line    int
+	text    string
+	failed  bool
+	pending map[string]int
+}
+
+func (s *translationState) begin(line int, text string) {
+	s.line = line
+	s.text = strings.TrimSpace(text)
+	s.failed = false
+	s.pending = make(map[string]int)
+}
+
+func (s *translationState) commit() {
+	for construct, n := range s.pending {
+		s.report.Translated[construct] += n
+	}
+}
+
+func (s *translationState) translated(construct string) {
+	s.pending[construct]++
+}
+
+func (s *translationState) untranslatable(construct, reason string) {
+	s.failed = true
+	s.report.Untranslated = append(s.report.Untranslated, UntranslatedConstruct{
+		Line:      s.line,
+		Text:      s.text,
+		Construct: construct,
+		Reason:    reason,
+	})
+}
+
+var (
+	blockNumber   = regexp.MustCompile(`^N(\d+)\s*`)
+	numberPattern = regexp.MustCompile(`^[-+]?(?:\d+\.?\d*|\.\d+)$`)
+)
+
+// splitBlockNumber はブロックの先頭のシーケンス番号 "N100 " を切り出す
+func splitBlockNumber(code string) (string, string) {
+	if m := blockNumber.FindString(code); m != "" {
+		return strings.TrimSpace(m) + " ", code[len(m):]
+	}
+	return "", code
+}
+
+func joinBlock(parts ...string) string {
+	var nonEmpty []string
+	for _, part := range parts {
+		if part = strings.TrimSpace(part); part != "" {
+			nonEmpty = append(nonEmpty, part)
+		}
+	}
+	return strings.Join(nonEmpty, " ")
+}
+
+func formatNumber(v float64) string {
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
+
+// formatFanucNumber は小数点を付けた寸法値を返す（小数点の無い値は最小設定単位とみなすコントローラーがあるため）
+func formatFanucNumber(v float64) string {
+	s := formatNumber(v)
+	if !strings.Contains(s, ".") {
+		s += "."
+	}
+	return s
+}
+
+// Translate はプログラムを別の方言へ変換した新しいドラフト版を作成する。
+// バージョンを省略すると変換元のバージョンに方言名を付ける（v3.2.1 → v3.2.1-siemens）。
+// machineType は変換先の機械タイプで、省略時はプログラムの対象の機械タイプが同じ T ワードの形式であればそれを使う
+func (p *NCProgram) Translate(to Dialect, machineType, version, createdBy string) (*NCProgram, *TranslationReport, error) {
+	if machineType == "" {
+		machineType = p.toolWordMachineType()
+	}
+	content, report, err := TranslateProgramContent(string(p.Content), p.Dialect, to, machineType)
+	if err != nil {
+		return nil, nil, err
+	}
+	if version == "" {
+		version = p.Version + "-" + string(to)
+	}
+
+	translated := NewNCProgram(p.Name, version, p.PartID, []byte(content), p.MachineCompatibility, p.RequiredCapabilities, createdBy)
+	translated.Dialect = to
+	translated.SourceProgramID = p.ID
+	return translated, report, nil
+}
+
+// toolWordMachineType は対象の機械タイプの T ワードの形式がすべて同じ場合にその1つを返す。決められない場合は空
+func (p *NCProgram) toolWordMachineType() string {
+	if len(p.MachineCompatibility) == 0 {
+		return ""
+	}
+	format := toolWordFormatFor(p.MachineCompatibility[0])
+	for _, machineType := range p.MachineCompatibility[1:] {
+		if toolWordFormatFor(machineType) != format {
+			return ""
+		}
+	}
+	return p.MachineCompatibility[0]
+}
@@ -0,0 +1,167 @@
+package domain
+
+import (
+	"context"
+	"time"
+)
+
+type EventType string
+
+const (
+	EventNCProgramDeployed    EventType = "NCProgramDeployed"
+	EventMachineStatusChanged EventType = "MachineStatusChanged"
+	EventNCJobCompleted       EventType = "NCJobCompleted"
+	EventNCJobError           EventType = "NCJobError"
+	EventToolLifeExpired      EventType = "ToolLifeExpired"
+	EventOffsetApplied        EventType = "OffsetApplied"
+	EventAlarmRaised          EventType = "AlarmRaised"
+	EventAlarmCleared         EventType = "AlarmCleared"
+)
+
+type DomainEvent interface {
+	GetEventType() EventType
+	GetOccurredAt() time.Time
+	GetAggregateID() string
+}
+
+// EventPublisher はドメインイベントをメッセージバス等へ配信する
+type EventPublisher interface {
+	Publish(ctx context.Context, event DomainEvent) error
+}
+
+type MachineEvent struct {
+	EventType  EventType
+	MachineID  MachineID
+	OccurredAt time.Time
+	Payload    map[string]interface{}
+}
+
+func (e MachineEvent) GetEventType() EventType {
+	return e.EventType
+}
+
+func (e MachineEvent) GetOccurredAt() time.Time {
+	return e.OccurredAt
+}
+
+func (e MachineEvent) GetAggregateID() string {
+	return string(e.MachineID)
+}
+
+func NewMachineStatusChangedEvent(change *MachineStatusChange) DomainEvent {
+	return MachineEvent{
+		EventType:  EventMachineStatusChanged,
+		MachineID:  change.MachineID,
+		OccurredAt: change.ChangedAt,
+		Payload: map[string]interface{}{
+			"fromState":    change.FromState,
+			"toState":      change.ToState,
+			"jobID":        change.JobID,
+			"errorMessage": change.ErrorMessage,
+		},
+	}
+}
+
+// NewToolLifeExpiredEvent は装着工具が寿命に達したことを通知する
+func NewToolLifeExpiredEvent(tool *MagazineTool) DomainEvent {
+	return MachineEvent{
+		EventType:  EventToolLifeExpired,
+		MachineID:  tool.MachineID,
+		OccurredAt: tool.UpdatedAt,
+		Payload: map[string]interface{}{
+			"toolNumber":     tool.ToolNumber,
+			"assemblyId":     tool.AssemblyID,
+			"cutTimeSec":     tool.CutTime.Seconds(),
+			"partCount":      tool.PartCount,
+			"lifeCutTimeSec": tool.Life.CutTime.Seconds(),
+			"lifePartCount":  tool.Life.PartCount,
+		},
+	}
+}
+
+// NewOffsetAppliedEvent は承認されたオフセット変更がコントローラーへ反映されたことを通知する
+func NewOffsetAppliedEvent(change *OffsetChange) DomainEvent {
+	return MachineEvent{
+		EventType:  EventOffsetApplied,
+		MachineID:  change.MachineID,
+		OccurredAt: *change.AppliedAt,
+		Payload: map[string]interface{}{
+			"changeId":   change.ID,
+			"register":   change.Register,
+			"mode":       change.Mode,
+			"previous":   change.Previous,
+			"result":     change.Result,
+			"reason":     change.Reason,
+			"proposedBy": change.ProposedBy,
+			"approvedBy": change.ReviewedBy,
+		},
+	}
+}
+
+// NewAlarmEvent は機械のアラームの発生（AlarmRaised）・解除（AlarmCleared）を通知する
+func NewAlarmEvent(eventType EventType, alarm *MachineAlarm) DomainEvent {
+	occurredAt := alarm.RaisedAt
+	if alarm.ClearedAt != nil {
+		occurredAt = *alarm.ClearedAt
+	}
+	return MachineEvent{
+		EventType:  eventType,
+		MachineID:  alarm.MachineID,
+		OccurredAt: occurredAt,
+		Payload: map[string]interface{}{
+			"alarmId":  alarm.ID,
+			"code":     alarm.Code,
+			"severity": alarm.Severity,
+			"message":  alarm.Message,
+			"jobId":    alarm.JobID,
+		},
+	}
+}
+
+type NCJobEvent struct {
+	EventType  EventType
+	JobID      NCJobID
+	OccurredAt time.Time
+	Payload    map[string]interface{}
+}
+
+func (e NCJobEvent) GetEventType() EventType {
+	return e.EventType
+}
+
+func (e NCJobEvent) GetOccurredAt() time.Time {
+	return e.OccurredAt
+}
+
+func (e NCJobEvent) GetAggregateID() string {
+	return string(e.JobID)
+}
+
+// NewNCJobEvent は NCJobCompleted / NCJobError を生成する
+func NewNCJobEvent(eventType EventType, job *NCJob) DomainEvent {
+	occurredAt := job.UpdatedAt
+	if job.EndedAt != nil {
+		occurredAt = *job.EndedAt
+	}
+
+	payload := map[string]interface{}{
+		"machineId":         job.MachineID,
+		"jobId":             job.ID,
+		"programId":         job.ProgramID,
+		"programHash":       job.ProgramHash,
+		"productionOrderId": job.ProductionOrderID,
+		"producedCount":     job.ProducedCount,
+		"status":            job.Status,
+	}
+	if eventType == EventNCJobError {
+		payload["errorMessage"] = job.ErrorMessage
+		payload["alarms"] = job.Alarms
+	}
+
+	return NCJobEvent{
+		EventType:  eventType,
+		JobID:      job.ID,
+		OccurredAt: occurredAt,
+		Payload:    payload,
+	}
+}
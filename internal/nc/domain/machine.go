@@ -1,26 +1,71 @@
 package domain
 
-import "time"
+import (
+	"net"
+	"strings"
+	"time"
+)
 
 type MachineID string
 
 type MachineRunningState string
 
 const (
-	StateRunning MachineRunningState = "running"
-	StateStopped MachineRunningState = "stopped"
-	StateError   MachineRunningState = "error"
+	StateRunning     MachineRunningState = "running"
+	StateStopped     MachineRunningState = "stopped"
+	StateError       MachineRunningState = "error"
+	StateIdle        MachineRunningState = "idle"
+	StateSetup       MachineRunningState = "setup"
+	StateMaintenance MachineRunningState = "maintenance"
+	StateAlarm       MachineRunningState = "alarm"
+	StateOffline     MachineRunningState = "offline"
 )
 
+// 登録可能な機械タイプ
+var validMachineTypes = map[string]bool{
+	"CNC-3AXIS":   true,
+	"CNC-5AXIS":   true,
+	"LATHE":       true,
+	"MILL-TURN":   true,
+	"GRINDER":     true,
+	"GEAR-HOBBER": true,
+}
+
+// 登録可能な加工能力
+var validCapabilities = map[string]bool{
+	"milling":   true,
+	"drilling":  true,
+	"turning":   true,
+	"tapping":   true,
+	"boring":    true,
+	"grinding":  true,
+	"hobbing":   true,
+	"threading": true,
+	"probing":   true,
+}
+
 type Machine struct {
-	ID           MachineID
-	Name         string
-	IP           string
-	Type         string
-	Capabilities []string
-	Status       MachineStatus
-	CreatedAt    time.Time
-	UpdatedAt    time.Time
+	ID               MachineID
+	Name             string
+	IP               string
+	Type             string
+	Capabilities     []string
+	AxisTravel       AxisTravel
+	MaxSpindleSpeed  int
+	ToolMagazineSize int
+	ControllerModel  string
+	Control          ControlSpec
+	Status           MachineStatus
+	DecommissionedAt *time.Time
+	CreatedAt        time.Time
+	UpdatedAt        time.Time
+}
+
+// AxisTravel は各軸のストローク(mm)
+type AxisTravel struct {
+	X float64
+	Y float64
+	Z float64
 }
 
 type MachineStatus struct {
@@ -47,25 +92,150 @@ func NewMachine(id MachineID, name, ip, machineType string, capabilities []strin
 	}
 }
 
-func (m *Machine) UpdateStatus(status MachineStatus) {
-	m.Status = status
+// Validate は機械の登録情報を検証する
+func (m *Machine) Validate() error {
+	if strings.TrimSpace(string(m.ID)) == "" || strings.TrimSpace(m.Name) == "" {
+		return ErrInvalidMachine
+	}
+	if net.ParseIP(m.IP) == nil {
+		return ErrInvalidMachineIP
+	}
+	if !validMachineTypes[m.Type] {
+		return ErrInvalidMachineType
+	}
+	if len(m.Capabilities) == 0 {
+		return ErrInvalidCapability
+	}
+	seen := make(map[string]bool, len(m.Capabilities))
+	for _, c := range m.Capabilities {
+		if !validCapabilities[c] || seen[c] {
+			return ErrInvalidCapability
+		}
+		seen[c] = true
+	}
+	if m.AxisTravel.X < 0 || m.AxisTravel.Y < 0 || m.AxisTravel.Z < 0 ||
+		m.MaxSpindleSpeed < 0 || m.ToolMagazineSize < 0 {
+		return ErrInvalidMachineSpec
+	}
+	return m.Control.Validate()
+}
+
+// UpdateSpec は機械の登録情報を変更する
+func (m *Machine) UpdateSpec(name, ip, machineType string, capabilities []string, travel AxisTravel, maxSpindleSpeed, toolMagazineSize int, controllerModel string, control ControlSpec) error {
+	if m.IsDecommissioned() {
+		return ErrMachineDecommissioned
+	}
+	m.Name = name
+	m.IP = ip
+	m.Type = machineType
+	m.Capabilities = capabilities
+	m.AxisTravel = travel
+	m.MaxSpindleSpeed = maxSpindleSpeed
+	m.ToolMagazineSize = toolMagazineSize
+	m.ControllerModel = controllerModel
+	m.Control = control
 	m.UpdatedAt = time.Now()
+	return m.Validate()
 }
 
-func (m *Machine) IsAvailable() bool {
-	return m.Status.RunningState == StateStopped
+// Decommission は機械を論理削除する。過去のジョブからの参照は維持される
+func (m *Machine) Decommission() error {
+	if m.IsDecommissioned() {
+		return ErrMachineDecommissioned
+	}
+	if m.Status.RunningState == StateRunning {
+		return ErrMachineNotAvailable
+	}
+	now := time.Now()
+	m.DecommissionedAt = &now
+	m.UpdatedAt = now
+	return nil
+}
+
+func (m *Machine) IsDecommissioned() bool {
+	return m.DecommissionedAt != nil
 }
 
-func (m *Machine) StartJob(jobID string) {
-	m.Status.RunningState = StateRunning
-	m.Status.CurrentJobID = jobID
-	m.Status.LastHeartbeat = time.Now()
+// UpdateStatus は状態遷移を検証してステータスを更新する。
+// 稼働状態が変化した場合は履歴として記録すべき変更を返す
+func (m *Machine) UpdateStatus(status MachineStatus) (*MachineStatusChange, error) {
+	if m.IsDecommissioned() {
+		return nil, ErrMachineDecommissioned
+	}
+	if !status.RunningState.IsValid() {
+		return nil, ErrInvalidRunningState
+	}
+	if !m.Status.RunningState.CanTransitionTo(status.RunningState) {
+		return nil, ErrInvalidStatusTransition
+	}
+
+	if status.LastHeartbeat.IsZero() {
+		status.LastHeartbeat = time.Now()
+	}
+
+	var change *MachineStatusChange
+	if m.Status.RunningState != status.RunningState {
+		change = newMachineStatusChange(m.ID, m.Status.RunningState, status)
+	}
+
+	m.Status = status
 	m.UpdatedAt = time.Now()
+	return change, nil
 }
 
-func (m *Machine) StopJob() {
-	m.Status.RunningState = StateStopped
-	m.Status.CurrentJobID = ""
-	m.Status.LastHeartbeat = time.Now()
+// RecordHeartbeat はコントローラー／エッジエージェントからの生存通知を記録する。
+// オフライン判定中の機械は停止状態として復帰させる
+func (m *Machine) RecordHeartbeat(at time.Time) (*MachineStatusChange, error) {
+	if m.IsDecommissioned() {
+		return nil, ErrMachineDecommissioned
+	}
+	if m.Status.RunningState == StateOffline {
+		return m.UpdateStatus(MachineStatus{
+			RunningState:  StateStopped,
+			LastHeartbeat: at,
+		})
+	}
+	m.Status.LastHeartbeat = at
 	m.UpdatedAt = time.Now()
-}
\ No newline at end of file
+	return nil, nil
+}
+
+// IsHeartbeatExpired は最終ハートビートから timeout 以上経過しているかを返す
+func (m *Machine) IsHeartbeatExpired(now time.Time, timeout time.Duration) bool {
+	return now.Sub(m.Status.LastHeartbeat) >= timeout
+}
+
+// MarkOffline はハートビート途絶により機械をオフラインにする
+func (m *Machine) MarkOffline() (*MachineStatusChange, error) {
+	return m.UpdateStatus(MachineStatus{
+		RunningState:  StateOffline,
+		CurrentJobID:  m.Status.CurrentJobID,
+		LastHeartbeat: m.Status.LastHeartbeat,
+		ErrorMessage:  "heartbeat timeout",
+	})
+}
+
+func (m *Machine) IsAvailable() bool {
+	if m.IsDecommissioned() {
+		return false
+	}
+	return m.Status.RunningState == StateStopped || m.Status.RunningState == StateIdle
+}
+
+// IsBusy は加工中・段取り中・アラームなどで一時的に空いていないか判定する。オフライン・廃止した機械は含まない
+func (m *Machine) IsBusy() bool {
+	return !m.IsDecommissioned() && m.Status.RunningState != StateOffline && !m.IsAvailable()
+}
+
+func (m *Machine) StartJob(jobID string) (*MachineStatusChange, error) {
+	return m.UpdateStatus(MachineStatus{
+		RunningState: StateRunning,
+		CurrentJobID: jobID,
+	})
+}
+
+func (m *Machine) StopJob() (*MachineStatusChange, error) {
+	return m.UpdateStatus(MachineStatus{
+		RunningState: StateStopped,
+	})
+}
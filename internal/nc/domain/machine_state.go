@@ -0,0 +1,53 @@
+package domain
+
+import "time"
+
+// 稼働状態の許可された遷移。同一状態への遷移（ハートビート更新等）は常に許可する
+var machineStateTransitions = map[MachineRunningState][]MachineRunningState{
+	StateStopped:     {StateIdle, StateSetup, StateRunning, StateMaintenance, StateAlarm, StateError, StateOffline},
+	StateIdle:        {StateStopped, StateSetup, StateRunning, StateMaintenance, StateAlarm, StateError, StateOffline},
+	StateSetup:       {StateStopped, StateIdle, StateRunning, StateAlarm, StateError, StateOffline},
+	StateRunning:     {StateStopped, StateIdle, StateAlarm, StateError, StateOffline},
+	StateAlarm:       {StateStopped, StateIdle, StateSetup, StateMaintenance, StateError, StateOffline},
+	StateError:       {StateStopped, StateMaintenance, StateAlarm, StateOffline},
+	StateMaintenance: {StateStopped, StateSetup, StateOffline},
+	StateOffline:     {StateStopped, StateIdle, StateSetup, StateRunning, StateMaintenance, StateAlarm, StateError},
+}
+
+func (s MachineRunningState) IsValid() bool {
+	_, ok := machineStateTransitions[s]
+	return ok
+}
+
+func (s MachineRunningState) CanTransitionTo(next MachineRunningState) bool {
+	if s == next {
+		return true
+	}
+	for _, allowed := range machineStateTransitions[s] {
+		if allowed == next {
+			return true
+		}
+	}
+	return false
+}
+
+// MachineStatusChange は稼働状態の変化履歴（追記のみ）
+type MachineStatusChange struct {
+	MachineID    MachineID
+	FromState    MachineRunningState
+	ToState      MachineRunningState
+	JobID        string
+	ErrorMessage string
+	ChangedAt    time.Time
+}
+
+func newMachineStatusChange(machineID MachineID, from MachineRunningState, to MachineStatus) *MachineStatusChange {
+	return &MachineStatusChange{
+		MachineID:    machineID,
+		FromState:    from,
+		ToState:      to.RunningState,
+		JobID:        to.CurrentJobID,
+		ErrorMessage: to.ErrorMessage,
+		ChangedAt:    time.Now(),
+	}
+}
@@ -0,0 +1,33 @@
+package domain
+
+import "context"
+
+// MachineStatusRecorder は稼働状態の変化を履歴へ追記し、MachineStatusChanged を発行する。
+// あわせて実行中のジョブへ状態変化を反映する
+type MachineStatusRecorder struct {
+	historyRepo MachineStatusHistoryRepository
+	jobTracker  *NCJobTracker
+	publisher   EventPublisher
+}
+
+func NewMachineStatusRecorder(historyRepo MachineStatusHistoryRepository, jobTracker *NCJobTracker, publisher EventPublisher) *MachineStatusRecorder {
+	return &MachineStatusRecorder{
+		historyRepo: historyRepo,
+		jobTracker:  jobTracker,
+		publisher:   publisher,
+	}
+}
+
+// Record は change が nil（状態変化なし）の場合は何もしない
+func (r *MachineStatusRecorder) Record(ctx context.Context, change *MachineStatusChange) error {
+	if change == nil {
+		return nil
+	}
+	if err := r.historyRepo.Append(ctx, change); err != nil {
+		return err
+	}
+	if err := r.publisher.Publish(ctx, NewMachineStatusChangedEvent(change)); err != nil {
+		return err
+	}
+	return r.jobTracker.Track(ctx, change)
+}
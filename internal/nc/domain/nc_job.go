@@ -0,0 +1,142 @@
+package domain
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type NCJobID string
+
+type NCJobStatus string
+
+const (
+	JobRunning   NCJobStatus = "running"
+	JobCompleted NCJobStatus = "completed"
+	JobError     NCJobStatus = "error"
+	JobAborted   NCJobStatus = "aborted"
+)
+
+// NCJob はプログラム転送から加工終了までの1回の実行
+type NCJob struct {
+	ID                NCJobID
+	ProgramID         NCProgramID
+	ProgramHash       string
+	MachineID         MachineID
+	ProductionOrderID string
+	Status            NCJobStatus
+	StartedAt         time.Time
+	EndedAt           *time.Time
+	ProducedCount     int
+	// PartCounterStart はジョブ開始後に最初に観測したコントローラーの累積加工数
+	PartCounterStart *int
+	Alarms           []string
+	ErrorMessage     string
+	CreatedAt        time.Time
+	UpdatedAt        time.Time
+}
+
+func NewNCJob(program *NCProgram, machineID MachineID, productionOrderID string) *NCJob {
+	now := time.Now()
+	return &NCJob{
+		ID:                NCJobID("job-" + uuid.New().String()[:8]),
+		ProgramID:         program.ID,
+		ProgramHash:       program.FileHash,
+		MachineID:         machineID,
+		ProductionOrderID: productionOrderID,
+		Status:            JobRunning,
+		StartedAt:         now,
+		CreatedAt:         now,
+		UpdatedAt:         now,
+	}
+}
+
+func (j *NCJob) IsFinished() bool {
+	return j.Status != JobRunning
+}
+
+// ReportProducedCount はコントローラーから通知されたジョブ内の加工数を記録する
+func (j *NCJob) ReportProducedCount(count int) error {
+	if j.IsFinished() {
+		return ErrNCJobFinished
+	}
+	if count < 0 {
+		return ErrInvalidProducedCount
+	}
+	j.ProducedCount = count
+	j.UpdatedAt = time.Now()
+	return nil
+}
+
+// UpdatePartCounter はコネクタが取得した累積加工数からジョブ内の加工数を求める。
+// カウンターがリセットされた場合はそれまでの加工数を引き継ぐ。加工数が変化した場合 true を返す
+func (j *NCJob) UpdatePartCounter(counter int) bool {
+	if j.IsFinished() {
+		return false
+	}
+	if j.PartCounterStart == nil {
+		start := counter
+		j.PartCounterStart = &start
+		j.UpdatedAt = time.Now()
+		return true
+	}
+	if counter < *j.PartCounterStart {
+		start := counter - j.ProducedCount
+		j.PartCounterStart = &start
+	}
+	produced := counter - *j.PartCounterStart
+	if produced == j.ProducedCount {
+		return false
+	}
+	j.ProducedCount = produced
+	j.UpdatedAt = time.Now()
+	return true
+}
+
+func (j *NCJob) RecordAlarm(message string) {
+	if message == "" {
+		return
+	}
+	for _, alarm := range j.Alarms {
+		if alarm == message {
+			return
+		}
+	}
+	j.Alarms = append(j.Alarms, message)
+	j.UpdatedAt = time.Now()
+}
+
+func (j *NCJob) Complete(at time.Time) (DomainEvent, error) {
+	if err := j.finish(JobCompleted, at); err != nil {
+		return nil, err
+	}
+	return NewNCJobEvent(EventNCJobCompleted, j), nil
+}
+
+func (j *NCJob) Fail(at time.Time, message string) (DomainEvent, error) {
+	j.RecordAlarm(message)
+	if err := j.finish(JobError, at); err != nil {
+		return nil, err
+	}
+	j.ErrorMessage = message
+	return NewNCJobEvent(EventNCJobError, j), nil
+}
+
+// Abort は段取り替えや次のジョブの開始などで加工が中断されたジョブを終了する
+func (j *NCJob) Abort(at time.Time, reason string) (DomainEvent, error) {
+	if err := j.finish(JobAborted, at); err != nil {
+		return nil, err
+	}
+	j.ErrorMessage = reason
+	return NewNCJobEvent(EventNCJobError, j), nil
+}
+
+func (j *NCJob) finish(status NCJobStatus, at time.Time) error {
+	if j.IsFinished() {
+		return ErrNCJobFinished
+	}
+	j.Status = status
+	j.EndedAt = &at
+	j.UpdatedAt = time.Now()
+	return nil
+}
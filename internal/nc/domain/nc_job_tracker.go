@@ -0,0 +1,115 @@
+package domain
+
+import "context"
+
+// NCJobTracker は機械の状態変化や加工数の通知を実行中のジョブへ反映し、
+// ジョブの終了時に NCJobCompleted / NCJobError を発行して工具寿命へ加工実績を反映する
+type NCJobTracker struct {
+	jobRepo   NCJobRepository
+	toolLife  *ToolLifeService
+	publisher EventPublisher
+}
+
+func NewNCJobTracker(jobRepo NCJobRepository, toolLife *ToolLifeService, publisher EventPublisher) *NCJobTracker {
+	return &NCJobTracker{
+		jobRepo:   jobRepo,
+		toolLife:  toolLife,
+		publisher: publisher,
+	}
+}
+
+// Start はジョブを登録する。同じ機械に終了していないジョブが残っている場合は中断として閉じる
+func (t *NCJobTracker) Start(ctx context.Context, job *NCJob) error {
+	previous, err := t.jobRepo.FindActiveByMachineID(ctx, job.MachineID)
+	if err != nil && err != ErrNCJobNotFound {
+		return err
+	}
+	if previous != nil {
+		event, err := previous.Abort(job.StartedAt, "superseded by job "+string(job.ID))
+		if err != nil {
+			return err
+		}
+		if err := t.finish(ctx, previous, event); err != nil {
+			return err
+		}
+	}
+
+	return t.jobRepo.Save(ctx, job)
+}
+
+// Track は稼働状態の変化に応じて実行中のジョブを終了させる。
+// オフラインからの復帰は加工結果が分からないため、アラーム以外ではジョブを閉じない
+func (t *NCJobTracker) Track(ctx context.Context, change *MachineStatusChange) error {
+	job, err := t.jobRepo.FindActiveByMachineID(ctx, change.MachineID)
+	if err == ErrNCJobNotFound {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	var event DomainEvent
+	switch change.ToState {
+	case StateError, StateAlarm:
+		event, err = job.Fail(change.ChangedAt, change.ErrorMessage)
+	case StateIdle, StateStopped:
+		if change.FromState != StateRunning {
+			return nil
+		}
+		event, err = job.Complete(change.ChangedAt)
+	case StateSetup, StateMaintenance:
+		if change.FromState != StateRunning {
+			return nil
+		}
+		event, err = job.Abort(change.ChangedAt, "interrupted by "+string(change.ToState))
+	default:
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	return t.finish(ctx, job, event)
+}
+
+// ReportProducedCount は機械から通知されたジョブ内の加工数を実行中のジョブへ記録する
+func (t *NCJobTracker) ReportProducedCount(ctx context.Context, machineID MachineID, count int) error {
+	job, err := t.jobRepo.FindActiveByMachineID(ctx, machineID)
+	if err == ErrNCJobNotFound {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	if err := job.ReportProducedCount(count); err != nil {
+		return err
+	}
+	return t.jobRepo.Update(ctx, job)
+}
+
+// ReportPartCounter はコネクタが取得した累積加工数を実行中のジョブへ反映する
+func (t *NCJobTracker) ReportPartCounter(ctx context.Context, machineID MachineID, counter int) error {
+	job, err := t.jobRepo.FindActiveByMachineID(ctx, machineID)
+	if err == ErrNCJobNotFound {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	if !job.UpdatePartCounter(counter) {
+		return nil
+	}
+	return t.jobRepo.Update(ctx, job)
+}
+
+func (t *NCJobTracker) finish(ctx context.Context, job *NCJob, event DomainEvent) error {
+	if err := t.jobRepo.Update(ctx, job); err != nil {
+		return err
+	}
+	if err := t.publisher.Publish(ctx, event); err != nil {
+		return err
+	}
+	return t.toolLife.RecordJobUsage(ctx, job)
+}
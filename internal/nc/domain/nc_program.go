@@ -3,23 +3,49 @@ package domain
 import (
 	"crypto/sha256"
 	"encoding/hex"
+	"strconv"
+	"strings"
 	"time"
 )
 
 type NCProgramID string
 
+type NCProgramStatus string
+
+const (
+	ProgramDraft    NCProgramStatus = "draft"
+	ProgramApproved NCProgramStatus = "approved"
+	ProgramObsolete NCProgramStatus = "obsolete"
+)
+
 type NCProgram struct {
 	ID                   NCProgramID
 	Name                 string
 	Version              string
+	PartID               string
+	// Dialect は対象コントローラー系列の方言
+	Dialect              Dialect
+	// SourceProgramID は方言変換で作成した場合の変換元プログラム
+	SourceProgramID      NCProgramID
 	FileHash             string
+	// Signature は承認者の Ed25519 署名（SigningPayload に対する）
+	Signature            []byte
+	SignerKeyID          SignerKeyID
+	SignedBy             string
+	SignedAt             *time.Time
+	Content              []byte
 	MachineCompatibility []string
+	// RequiredCapabilities は加工に必要な機械の加工能力（milling, turning 等）
+	RequiredCapabilities []string
+	Status               NCProgramStatus
+	ApprovedBy           string
+	ApprovedAt           *time.Time
 	CreatedBy            string
 	CreatedAt            time.Time
 	UpdatedAt            time.Time
 }
 
-func NewNCProgram(name, version string, content []byte, machineCompatibility []string, createdBy string) *NCProgram {
+func NewNCProgram(name, version, partID string, content []byte, machineCompatibility, requiredCapabilities []string, createdBy string) *NCProgram {
 	hash := sha256.Sum256(content)
 	hashStr := hex.EncodeToString(hash[:])
 	
@@ -28,19 +54,101 @@ func NewNCProgram(name, version string, content []byte, machineCompatibility []s
 		ID:                   NCProgramID("ncprog-" + hashStr[:8]),
 		Name:                 name,
 		Version:              version,
+		PartID:               partID,
+		Dialect:              DialectFanuc,
 		FileHash:             hashStr,
+		Content:              content,
 		MachineCompatibility: machineCompatibility,
+		RequiredCapabilities: requiredCapabilities,
+		Status:               ProgramDraft,
 		CreatedBy:            createdBy,
 		CreatedAt:            now,
 		UpdatedAt:            now,
 	}
 }
 
+// Approve は承認者の署名を記録してプログラムをリリースし、機械への展開対象にする
+func (p *NCProgram) Approve(approvedBy string, key *SignerKey, signature []byte) error {
+	if p.Status != ProgramDraft {
+		return ErrInvalidProgramStatus
+	}
+	if err := p.Sign(key, signature, approvedBy); err != nil {
+		return err
+	}
+	now := time.Now()
+	p.Status = ProgramApproved
+	p.ApprovedBy = approvedBy
+	p.ApprovedAt = &now
+	p.UpdatedAt = now
+	return nil
+}
+
+func (p *NCProgram) IsReleased() bool {
+	return p.Status == ProgramApproved
+}
+
+// MissingCapabilities は機械が持たない必要加工能力を返す
+func (p *NCProgram) MissingCapabilities(capabilities []string) []string {
+	available := make(map[string]bool, len(capabilities))
+	for _, c := range capabilities {
+		available[c] = true
+	}
+	var missing []string
+	for _, c := range p.RequiredCapabilities {
+		if !available[c] {
+			missing = append(missing, c)
+		}
+	}
+	return missing
+}
+
+// IsCompatibleWith はプログラムが対象とする機械タイプか判定する。対象タイプを宣言していなければ制限しない
 func (p *NCProgram) IsCompatibleWith(machineType string) bool {
+	if len(p.MachineCompatibility) == 0 {
+		return true
+	}
 	for _, mt := range p.MachineCompatibility {
 		if mt == machineType {
 			return true
 		}
 	}
 	return false
-}
\ No newline at end of file
+}
+
+// CompareVersions はバージョン文字列（v3.2.1, v5.2.0-adaptive 等）を数値部分で比較する。
+// a が新しければ正、古ければ負、同じなら 0 を返す
+func CompareVersions(a, b string) int {
+	pa, pb := versionNumbers(a), versionNumbers(b)
+	for i := 0; i < len(pa) || i < len(pb); i++ {
+		var x, y int
+		if i < len(pa) {
+			x = pa[i]
+		}
+		if i < len(pb) {
+			y = pb[i]
+		}
+		if x != y {
+			if x > y {
+				return 1
+			}
+			return -1
+		}
+	}
+	return strings.Compare(a, b)
+}
+
+func versionNumbers(version string) []int {
+	version = strings.TrimPrefix(strings.ToLower(strings.TrimSpace(version)), "v")
+	if i := strings.IndexAny(version, "-+"); i >= 0 {
+		version = version[:i]
+	}
+	var numbers []int
+	for _, part := range strings.Split(version, ".") {
+		n, err := strconv.Atoi(part)
+		if err != nil {
+			break
+		}
+		numbers = append(numbers, n)
+	}
+	return numbers
+}
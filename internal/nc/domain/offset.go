@@ -0,0 +1,292 @@
+package domain
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type OffsetKind string
+
+const (
+	// ToolOffset は補正番号ごとの工具長・工具径とその摩耗量
+	ToolOffset OffsetKind = "tool"
+	// WorkOffset は G54〜G59 のワーク座標系
+	WorkOffset OffsetKind = "work"
+)
+
+// オフセットの項目
+const (
+	OffsetLength     = "length"
+	OffsetRadius     = "radius"
+	OffsetLengthWear = "lengthWear"
+	OffsetRadiusWear = "radiusWear"
+	OffsetX          = "x"
+	OffsetY          = "y"
+	OffsetZ          = "z"
+	OffsetA          = "a"
+	OffsetB          = "b"
+	OffsetC          = "c"
+)
+
+var offsetFields = map[OffsetKind]map[string]bool{
+	ToolOffset: {OffsetLength: true, OffsetRadius: true, OffsetLengthWear: true, OffsetRadiusWear: true},
+	WorkOffset: {OffsetX: true, OffsetY: true, OffsetZ: true, OffsetA: true, OffsetB: true, OffsetC: true},
+}
+
+var (
+	toolOffsetRegister = regexp.MustCompile(`^T(\d{1,3})$`)
+	workOffsetRegister = regexp.MustCompile(`^G5[4-9]$`)
+)
+
+// OffsetRegister はオフセットの格納先。工具オフセットは "T01"（補正番号）、ワークオフセットは "G54"〜"G59"
+type OffsetRegister string
+
+// ParseOffsetRegister は格納先を検証し、補正番号を2桁以上にそろえる（"t1" → "T01"）
+func ParseOffsetRegister(s string) (OffsetRegister, error) {
+	s = strings.ToUpper(strings.TrimSpace(s))
+	if workOffsetRegister.MatchString(s) {
+		return OffsetRegister(s), nil
+	}
+	if m := toolOffsetRegister.FindStringSubmatch(s); m != nil {
+		if number, err := strconv.Atoi(m[1]); err == nil && number > 0 {
+			return OffsetRegister(fmt.Sprintf("T%02d", number)), nil
+		}
+	}
+	return "", ErrInvalidOffsetRegister
+}
+
+func (r OffsetRegister) Kind() OffsetKind {
+	if strings.HasPrefix(string(r), "G") {
+		return WorkOffset
+	}
+	return ToolOffset
+}
+
+// ToolNumber は工具オフセットの補正番号を返す。ワークオフセットは 0
+func (r OffsetRegister) ToolNumber() int {
+	if m := toolOffsetRegister.FindStringSubmatch(string(r)); m != nil {
+		number, _ := strconv.Atoi(m[1])
+		return number
+	}
+	return 0
+}
+
+// OffsetValues はオフセットの項目ごとの値（mm、回転軸は度）
+type OffsetValues map[string]float64
+
+func (v OffsetValues) validate(kind OffsetKind) error {
+	if len(v) == 0 {
+		return ErrInvalidOffsetValues
+	}
+	for field, value := range v {
+		if !offsetFields[kind][field] || math.IsNaN(value) || math.IsInf(value, 0) {
+			return fmt.Errorf("%w: %s", ErrInvalidOffsetValues, field)
+		}
+	}
+	return nil
+}
+
+func (v OffsetValues) clone() OffsetValues {
+	cloned := make(OffsetValues, len(v))
+	for field, value := range v {
+		cloned[field] = value
+	}
+	return cloned
+}
+
+// Fields は項目名を名前順に返す
+func (v OffsetValues) Fields() []string {
+	fields := make([]string, 0, len(v))
+	for field := range v {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+	return fields
+}
+
+// MachineOffset は機械のオフセット登録簿の1件。承認された変更が反映されるたびに版が上がる
+type MachineOffset struct {
+	MachineID MachineID
+	Register  OffsetRegister
+	Values    OffsetValues
+	Version   int
+	ChangeID  OffsetChangeID
+	UpdatedBy string
+	UpdatedAt time.Time
+}
+
+type OffsetChangeID string
+
+type OffsetChangeStatus string
+
+const (
+	OffsetChangeProposed OffsetChangeStatus = "proposed"
+	// OffsetChangeApproved は承認済みでコントローラーへ書き込み中
+	OffsetChangeApproved   OffsetChangeStatus = "approved"
+	OffsetChangeApplied    OffsetChangeStatus = "applied"
+	OffsetChangeRejected   OffsetChangeStatus = "rejected"
+	OffsetChangePushFailed OffsetChangeStatus = "push_failed"
+)
+
+type OffsetChangeMode string
+
+const (
+	// OffsetAbsolute は指定した値で置き換える
+	OffsetAbsolute OffsetChangeMode = "absolute"
+	// OffsetIncremental は現在値に加算する（摩耗補正の追い込み等）
+	OffsetIncremental OffsetChangeMode = "incremental"
+)
+
+// OffsetChange はオフセット変更の提案と、その承認・反映の記録
+type OffsetChange struct {
+	ID        OffsetChangeID
+	MachineID MachineID
+	Register  OffsetRegister
+	Mode      OffsetChangeMode
+	// Values は変更する項目のみ。指定しない項目は現在値のまま
+	Values OffsetValues
+	// BaseVersion は提案時に参照した登録簿の版（未登録は 0）
+	BaseVersion int
+	// Previous / Result は反映前後の値。承認時に確定する
+	Previous      OffsetValues
+	Result        OffsetValues
+	Status        OffsetChangeStatus
+	Reason        string
+	ProposedBy    string
+	ProposedAt    time.Time
+	ReviewedBy    string
+	ReviewedAt    *time.Time
+	ReviewComment string
+	AppliedAt     *time.Time
+	ErrorMessage  string
+}
+
+// ProposeOffsetChange は変更を提案する。変更理由と提案者は監査のため必須
+func ProposeOffsetChange(machine *Machine, register OffsetRegister, mode OffsetChangeMode, values OffsetValues, current *MachineOffset, reason, proposedBy string) (*OffsetChange, error) {
+	if machine.IsDecommissioned() {
+		return nil, ErrMachineDecommissioned
+	}
+	if mode != OffsetAbsolute && mode != OffsetIncremental {
+		return nil, ErrInvalidOffsetChange
+	}
+	if strings.TrimSpace(reason) == "" || strings.TrimSpace(proposedBy) == "" {
+		return nil, ErrInvalidOffsetChange
+	}
+	if err := values.validate(register.Kind()); err != nil {
+		return nil, err
+	}
+
+	change := &OffsetChange{
+		ID:         OffsetChangeID("ofs-" + uuid.New().String()[:8]),
+		MachineID:  machine.ID,
+		Register:   register,
+		Mode:       mode,
+		Values:     values.clone(),
+		Status:     OffsetChangeProposed,
+		Reason:     strings.TrimSpace(reason),
+		ProposedBy: proposedBy,
+		ProposedAt: time.Now(),
+	}
+	if current != nil {
+		change.BaseVersion = current.Version
+	}
+	return change, nil
+}
+
+// Approve は提案を承認する。提案者本人は承認できない。書き込みに失敗した変更は再承認で再送できる
+func (c *OffsetChange) Approve(approvedBy, comment string) error {
+	if c.Status != OffsetChangeProposed && c.Status != OffsetChangePushFailed {
+		return ErrInvalidOffsetChangeStatus
+	}
+	if approvedBy == "" || approvedBy == c.ProposedBy {
+		return ErrOffsetSelfApproval
+	}
+	now := time.Now()
+	c.Status = OffsetChangeApproved
+	c.ReviewedBy = approvedBy
+	c.ReviewedAt = &now
+	c.ReviewComment = comment
+	c.ErrorMessage = ""
+	return nil
+}
+
+func (c *OffsetChange) Reject(rejectedBy, comment string) error {
+	if c.Status != OffsetChangeProposed && c.Status != OffsetChangePushFailed {
+		return ErrInvalidOffsetChangeStatus
+	}
+	if rejectedBy == "" {
+		return ErrInvalidOffsetChange
+	}
+	now := time.Now()
+	c.Status = OffsetChangeRejected
+	c.ReviewedBy = rejectedBy
+	c.ReviewedAt = &now
+	c.ReviewComment = comment
+	return nil
+}
+
+// Resolve は現在の登録内容に変更を適用した次の版を返す。
+// 置き換えの変更は提案後に他の変更が反映されていれば ErrOffsetVersionConflict とする（加算は最新の値に加える）
+func (c *OffsetChange) Resolve(current *MachineOffset) (*MachineOffset, error) {
+	if c.Status != OffsetChangeApproved {
+		return nil, ErrInvalidOffsetChangeStatus
+	}
+
+	previous := OffsetValues{}
+	version := 0
+	if current != nil {
+		previous = current.Values.clone()
+		version = current.Version
+	}
+	if c.Mode == OffsetAbsolute && version != c.BaseVersion {
+		return nil, ErrOffsetVersionConflict
+	}
+
+	result := previous.clone()
+	for field, value := range c.Values {
+		if c.Mode == OffsetIncremental {
+			value += previous[field]
+		}
+		result[field] = value
+	}
+
+	c.BaseVersion = version
+	c.Previous = previous
+	c.Result = result
+	return &MachineOffset{
+		MachineID: c.MachineID,
+		Register:  c.Register,
+		Values:    result.clone(),
+		Version:   version + 1,
+		ChangeID:  c.ID,
+		UpdatedBy: c.ReviewedBy,
+		UpdatedAt: time.Now(),
+	}, nil
+}
+
+// MarkApplied はコントローラーへの書き込みが完了したことを記録する
+func (c *OffsetChange) MarkApplied(offset *MachineOffset) error {
+	if c.Status != OffsetChangeApproved {
+		return ErrInvalidOffsetChangeStatus
+	}
+	c.Status = OffsetChangeApplied
+	c.AppliedAt = &offset.UpdatedAt
+	return nil
+}
+
+// MarkPushFailed はコントローラーへの書き込みに失敗したことを記録する。登録簿は変更しない
+func (c *OffsetChange) MarkPushFailed(reason string) error {
+	if c.Status != OffsetChangeApproved {
+		return ErrInvalidOffsetChangeStatus
+	}
+	c.Status = OffsetChangePushFailed
+	c.ErrorMessage = reason
+	return nil
+}
@@ -0,0 +1,143 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// OffsetWriter は機械のコントローラーへオフセットを書き込むコネクタのフック
+type OffsetWriter interface {
+	WriteOffset(ctx context.Context, machine *Machine, offset *MachineOffset) error
+}
+
+// OffsetRegistryService は機械ごとのオフセット登録簿を、提案・承認・コントローラーへの書き込みを経て更新する
+type OffsetRegistryService struct {
+	offsetRepo  OffsetRepository
+	machineRepo MachineRepository
+	writer      OffsetWriter
+	publisher   EventPublisher
+
+	// 同じ変更・格納先への承認と却下が並行しないよう直列化する
+	mu sync.Mutex
+}
+
+func NewOffsetRegistryService(offsetRepo OffsetRepository, machineRepo MachineRepository, writer OffsetWriter, publisher EventPublisher) *OffsetRegistryService {
+	return &OffsetRegistryService{
+		offsetRepo:  offsetRepo,
+		machineRepo: machineRepo,
+		writer:      writer,
+		publisher:   publisher,
+	}
+}
+
+func (s *OffsetRegistryService) Propose(ctx context.Context, machineID MachineID, register OffsetRegister, mode OffsetChangeMode, values OffsetValues, reason, proposedBy string) (*OffsetChange, error) {
+	machine, err := s.machineRepo.FindByID(ctx, machineID)
+	if err != nil {
+		return nil, err
+	}
+
+	current, err := s.current(ctx, machineID, register)
+	if err != nil {
+		return nil, err
+	}
+
+	change, err := ProposeOffsetChange(machine, register, mode, values, current, reason, proposedBy)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.offsetRepo.SaveChange(ctx, change); err != nil {
+		return nil, err
+	}
+	return change, nil
+}
+
+// Approve は変更を承認してコントローラーへ書き込み、成功した場合に登録簿へ新しい版として反映する。
+// 書き込みに失敗した変更は push_failed として ErrOffsetPushFailed を返す
+func (s *OffsetRegistryService) Approve(ctx context.Context, id OffsetChangeID, approvedBy, comment string) (*OffsetChange, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	change, err := s.offsetRepo.FindChangeByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	machine, err := s.machineRepo.FindByID(ctx, change.MachineID)
+	if err != nil {
+		return nil, err
+	}
+	if machine.IsDecommissioned() {
+		return nil, ErrMachineDecommissioned
+	}
+	if machine.Status.RunningState == StateOffline {
+		return nil, ErrMachineOffline
+	}
+
+	if err := change.Approve(approvedBy, comment); err != nil {
+		return nil, err
+	}
+
+	current, err := s.current(ctx, change.MachineID, change.Register)
+	if err != nil {
+		return nil, err
+	}
+	offset, err := change.Resolve(current)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.offsetRepo.UpdateChange(ctx, change); err != nil {
+		return nil, err
+	}
+
+	if err := s.writer.WriteOffset(ctx, machine, offset); err != nil {
+		// 書き込みの結果は要求が中断されても記録する
+		ctx = context.WithoutCancel(ctx)
+		if markErr := change.MarkPushFailed(err.Error()); markErr != nil {
+			return nil, markErr
+		}
+		if updateErr := s.offsetRepo.UpdateChange(ctx, change); updateErr != nil {
+			return nil, updateErr
+		}
+		return nil, fmt.Errorf("%w: %v", ErrOffsetPushFailed, err)
+	}
+
+	ctx = context.WithoutCancel(ctx)
+	if err := change.MarkApplied(offset); err != nil {
+		return nil, err
+	}
+	if err := s.offsetRepo.Apply(ctx, change, offset); err != nil {
+		return nil, err
+	}
+
+	return change, s.publisher.Publish(ctx, NewOffsetAppliedEvent(change))
+}
+
+func (s *OffsetRegistryService) Reject(ctx context.Context, id OffsetChangeID, rejectedBy, comment string) (*OffsetChange, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	change, err := s.offsetRepo.FindChangeByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := change.Reject(rejectedBy, comment); err != nil {
+		return nil, err
+	}
+	if err := s.offsetRepo.UpdateChange(ctx, change); err != nil {
+		return nil, err
+	}
+	return change, nil
+}
+
+// current は登録簿の現在の内容を返す。未登録の格納先は nil
+func (s *OffsetRegistryService) current(ctx context.Context, machineID MachineID, register OffsetRegister) (*MachineOffset, error) {
+	offset, err := s.offsetRepo.Find(ctx, machineID, register)
+	if errors.Is(err, ErrOffsetNotFound) {
+		return nil, nil
+	}
+	return offset, err
+}
@@ -0,0 +1,210 @@
+package domain
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type DeploymentID string
+
+type DeploymentStatus string
+
+const (
+	DeploymentQueued       DeploymentStatus = "queued"
+	DeploymentTransferring DeploymentStatus = "transferring"
+	DeploymentTransferred  DeploymentStatus = "transferred"
+	// DeploymentSkipped は機械が同じファイルハッシュのプログラムを保持しているため送信を省略した展開
+	DeploymentSkipped   DeploymentStatus = "skipped"
+	DeploymentFailed    DeploymentStatus = "failed"
+	DeploymentCancelled DeploymentStatus = "cancelled"
+)
+
+// ProgramTransferer は機械のコントローラーへプログラムを送信する（DNC/FTP 等）。
+// 送信したバイト数を返す。ErrTransferTemporary をラップしたエラーは再送の対象になる
+type ProgramTransferer interface {
+	Transfer(ctx context.Context, machine *Machine, program *NCProgram) (int64, error)
+}
+
+// TransferRetryPolicy は一時的な送信エラーの再送回数と待ち時間
+type TransferRetryPolicy struct {
+	MaxAttempts  int
+	InitialDelay time.Duration
+	MaxDelay     time.Duration
+}
+
+var defaultTransferRetryPolicy = TransferRetryPolicy{
+	MaxAttempts:  3,
+	InitialDelay: time.Second,
+	MaxDelay:     10 * time.Second,
+}
+
+// Delay は attempt 回目の送信が失敗した後の待ち時間
+func (p TransferRetryPolicy) Delay(attempt int) time.Duration {
+	d := p.InitialDelay << uint(attempt-1)
+	if d <= 0 || d > p.MaxDelay {
+		return p.MaxDelay
+	}
+	return d
+}
+
+// TransferAttempt は転送ログの1回分の送信記録
+type TransferAttempt struct {
+	Number    int
+	StartedAt time.Time
+	Duration  time.Duration
+	BytesSent int64
+	Error     string
+}
+
+// ProgramDeployment はプログラムの機械への展開要求と、その転送ログ
+type ProgramDeployment struct {
+	ID DeploymentID
+	// IdempotencyKey は機械ごとに一意。同じキーの再要求には既存の結果を返す
+	IdempotencyKey    string
+	MachineID         MachineID
+	ProgramID         NCProgramID
+	FileHash          string
+	ProductionOrderID string
+	JobID             NCJobID
+	Status            DeploymentStatus
+	Attempts          []TransferAttempt
+	ErrorMessage      string
+	CreatedAt         time.Time
+	CompletedAt       *time.Time
+	UpdatedAt         time.Time
+}
+
+// NewProgramDeployment は展開要求を送信待ちとして作成する。冪等キーが指定されない場合はトランザクションIDを採番する
+func NewProgramDeployment(idempotencyKey string, program *NCProgram, machineID MachineID, productionOrderID string) *ProgramDeployment {
+	id := uuid.New().String()
+	if idempotencyKey == "" {
+		idempotencyKey = id
+	}
+	now := time.Now()
+	return &ProgramDeployment{
+		ID:                DeploymentID("deploy-" + id[:8]),
+		IdempotencyKey:    idempotencyKey,
+		MachineID:         machineID,
+		ProgramID:         program.ID,
+		FileHash:          program.FileHash,
+		ProductionOrderID: productionOrderID,
+		Status:            DeploymentQueued,
+		CreatedAt:         now,
+		UpdatedAt:         now,
+	}
+}
+
+// Matches は同じ冪等キーの再要求が元の要求と同じ内容か判定する
+func (d *ProgramDeployment) Matches(programID NCProgramID, productionOrderID string) bool {
+	return d.ProgramID == programID && d.ProductionOrderID == productionOrderID
+}
+
+func (d *ProgramDeployment) IsDelivered() bool {
+	return d.Status == DeploymentTransferred || d.Status == DeploymentSkipped
+}
+
+func (d *ProgramDeployment) BytesSent() int64 {
+	var total int64
+	for _, attempt := range d.Attempts {
+		total += attempt.BytesSent
+	}
+	return total
+}
+
+func (d *ProgramDeployment) RecordAttempt(startedAt time.Time, duration time.Duration, bytesSent int64, err error) {
+	attempt := TransferAttempt{
+		Number:    len(d.Attempts) + 1,
+		StartedAt: startedAt,
+		Duration:  duration,
+		BytesSent: bytesSent,
+	}
+	if err != nil {
+		attempt.Error = err.Error()
+	}
+	d.Attempts = append(d.Attempts, attempt)
+	d.UpdatedAt = time.Now()
+}
+
+// IsRetryable は同じ冪等キーで再実行できる（失敗または取り消し済み）か判定する
+func (d *ProgramDeployment) IsRetryable() bool {
+	return d.Status == DeploymentFailed || d.Status == DeploymentCancelled
+}
+
+// Retry は失敗・取り消した展開を同じ冪等キーで送信待ちに戻す。転送ログは引き継ぐ
+func (d *ProgramDeployment) Retry() error {
+	if !d.IsRetryable() {
+		return ErrInvalidDeploymentStatus
+	}
+	d.Status = DeploymentQueued
+	d.ErrorMessage = ""
+	d.CompletedAt = nil
+	d.UpdatedAt = time.Now()
+	return nil
+}
+
+// Start は送信待ちの展開を送信中にする
+func (d *ProgramDeployment) Start() error {
+	if d.Status != DeploymentQueued {
+		return ErrInvalidDeploymentStatus
+	}
+	d.Status = DeploymentTransferring
+	d.UpdatedAt = time.Now()
+	return nil
+}
+
+// Requeue は送信を始める前の展開を送信待ちに戻す（機械が空いていない場合）
+func (d *ProgramDeployment) Requeue() error {
+	if d.Status != DeploymentTransferring {
+		return ErrInvalidDeploymentStatus
+	}
+	d.Status = DeploymentQueued
+	d.UpdatedAt = time.Now()
+	return nil
+}
+
+// Cancel は送信待ちまたは送信中の展開を取り消す
+func (d *ProgramDeployment) Cancel(reason string) error {
+	if d.Status != DeploymentQueued && d.Status != DeploymentTransferring {
+		return ErrInvalidDeploymentStatus
+	}
+	now := time.Now()
+	d.Status = DeploymentCancelled
+	d.ErrorMessage = reason
+	d.CompletedAt = &now
+	d.UpdatedAt = now
+	return nil
+}
+
+// Deliver は送信完了（skipped の場合は送信省略）としてジョブを紐づける
+func (d *ProgramDeployment) Deliver(jobID NCJobID, skipped bool) error {
+	status := DeploymentTransferred
+	if skipped {
+		status = DeploymentSkipped
+	}
+	if err := d.finish(status); err != nil {
+		return err
+	}
+	d.JobID = jobID
+	return nil
+}
+
+func (d *ProgramDeployment) Fail(message string) error {
+	if err := d.finish(DeploymentFailed); err != nil {
+		return err
+	}
+	d.ErrorMessage = message
+	return nil
+}
+
+func (d *ProgramDeployment) finish(status DeploymentStatus) error {
+	if d.Status != DeploymentTransferring {
+		return ErrInvalidDeploymentStatus
+	}
+	now := time.Now()
+	d.Status = status
+	d.CompletedAt = &now
+	d.UpdatedAt = now
+	return nil
+}
@@ -0,0 +1,177 @@
+package domain
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ProgramRequirements は NC プログラム本体から読み取った機械への要求
+type ProgramRequirements struct {
+	// Axes は指令している軸（X Y Z A B C）
+	Axes []string
+	// MaxSpindleSpeed は最大主軸回転数(min-1)。周速一定制御中は G50 の回転数上限を用いる
+	MaxSpindleSpeed int
+	// Options は使用するオプション機能の G コード（G05.1, G43.4 等）
+	Options []string
+	// Macros は G65/G66 で呼び出す機械常駐のカスタムマクロ（O9810 等）
+	Macros []string
+}
+
+// オプション機能として扱う G コード
+var controlOptionCodes = map[string]string{
+	"G05.1": "AI contour control",
+	"G07.1": "cylindrical interpolation",
+	"G12.1": "polar coordinate interpolation",
+	"G43.4": "tool center point control",
+	"G43.5": "tool center point control (type 2)",
+	"G68.2": "tilted working plane",
+}
+
+// 機械常駐のマクロとみなすプログラム番号の下限（O9000 番台は機械メーカー・プローブメーカーのマクロ）
+const minResidentMacroNumber = 9000
+
+var (
+	programAxes = map[string]bool{"X": true, "Y": true, "Z": true, "A": true, "B": true, "C": true}
+	// アドレスと値 "G43.4"、"X-12.5"、"Z[#101]"、"S#500"
+	programWord = regexp.MustCompile(`([A-Z])\s*([-+]?(?:\d+\.?\d*|\.\d+)|\[|#)`)
+	// マクロ文の行 "#100=1"、"IF [#1 GT 0] GOTO 10"、"WHILE [...] DO1"、"END1"
+	macroStatement = regexp.MustCompile(`^(?:N\d+\s*)?(?:#|IF\b|WHILE\b|GOTO\b|END\d)`)
+)
+
+// Requirements はプログラム本体から機械への要求を読み取る
+func (p *NCProgram) Requirements() ProgramRequirements {
+	return ExtractProgramRequirements(string(p.Content))
+}
+
+// ExtractProgramRequirements は NC プログラムの使用軸・主軸回転数・オプション G コード・常駐マクロ呼び出しを返す。
+// G65/G66 の引数、G10 のデータ設定、G04 のドウェル時間は軸の指令として扱わない
+func ExtractProgramRequirements(content string) ProgramRequirements {
+	axes := make(map[string]bool)
+	options := make(map[string]bool)
+	macros := make(map[string]bool)
+	var maxSpindleSpeed int
+	constantSurfaceSpeed := false
+
+	for _, line := range strings.Split(content, "\n") {
+		line = strings.ToUpper(strings.TrimSpace(commentPattern.ReplaceAllString(line, " ")))
+		if i := strings.Index(line, ";"); i >= 0 {
+			line = strings.TrimSpace(line[:i])
+		}
+		if line == "" || strings.HasPrefix(line, "%") || macroStatement.MatchString(line) {
+			continue
+		}
+
+		words := programWord.FindAllStringSubmatch(line, -1)
+		gCodes := make(map[string]bool)
+		for _, w := range words {
+			if w[1] == "G" {
+				if code, ok := normalizeGCode(w[2]); ok {
+					gCodes[code] = true
+				}
+			}
+		}
+		for code := range gCodes {
+			if _, ok := controlOptionCodes[code]; ok {
+				options[code] = true
+			}
+		}
+		if gCodes["G96"] {
+			constantSurfaceSpeed = true
+		}
+		if gCodes["G97"] {
+			constantSurfaceSpeed = false
+		}
+
+		if gCodes["G65"] || gCodes["G66"] {
+			for _, w := range words {
+				if w[1] != "P" {
+					continue
+				}
+				if number, err := strconv.Atoi(w[2]); err == nil && number >= minResidentMacroNumber {
+					macros[fmt.Sprintf("O%04d", number)] = true
+				}
+			}
+			continue
+		}
+
+		for _, w := range words {
+			switch {
+			case programAxes[w[1]]:
+				if gCodes["G10"] || (gCodes["G04"] && w[1] == "X") {
+					continue
+				}
+				axes[w[1]] = true
+			case w[1] == "S":
+				speed, err := strconv.ParseFloat(w[2], 64)
+				if err != nil {
+					continue
+				}
+				// G50 S は周速一定制御の回転数上限。周速一定制御中の S は周速(m/min)
+				if !gCodes["G50"] && constantSurfaceSpeed {
+					continue
+				}
+				if int(speed) > maxSpindleSpeed {
+					maxSpindleSpeed = int(speed)
+				}
+			}
+		}
+	}
+
+	return ProgramRequirements{
+		Axes:            sortedAxes(axes),
+		MaxSpindleSpeed: maxSpindleSpeed,
+		Options:         sortedKeys(options),
+		Macros:          sortedKeys(macros),
+	}
+}
+
+// normalizeGCode は G コードの値を 2桁表記にそろえる（"5.1" → "G05.1"、"0" → "G00"）
+func normalizeGCode(value string) (string, bool) {
+	value = strings.TrimPrefix(strings.TrimSpace(strings.ToUpper(value)), "G")
+	whole, fraction, hasFraction := strings.Cut(value, ".")
+	number, err := strconv.Atoi(whole)
+	if err != nil || number < 0 {
+		return "", false
+	}
+	code := fmt.Sprintf("G%02d", number)
+	if hasFraction {
+		if _, err := strconv.Atoi(fraction); err != nil {
+			return "", false
+		}
+		code += "." + fraction
+	}
+	return code, true
+}
+
+// normalizeMacro はマクロ番号を O 番号表記にそろえる（"9810"、"P9810" → "O9810"）
+func normalizeMacro(value string) (string, bool) {
+	value = strings.TrimLeft(strings.TrimSpace(strings.ToUpper(value)), "OP")
+	number, err := strconv.Atoi(value)
+	if err != nil || number <= 0 || number > 9999 {
+		return "", false
+	}
+	return fmt.Sprintf("O%04d", number), true
+}
+
+// sortedAxes は軸を直線軸 → 回転軸の順（X Y Z A B C U V W）に並べる
+func sortedAxes(axes map[string]bool) []string {
+	result := make([]string, 0, len(axes))
+	for _, axis := range []string{"X", "Y", "Z", "A", "B", "C", "U", "V", "W"} {
+		if axes[axis] {
+			result = append(result, axis)
+		}
+	}
+	return result
+}
+
+func sortedKeys(set map[string]bool) []string {
+	keys := make([]string, 0, len(set))
+	for key := range set {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
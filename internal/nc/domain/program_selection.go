@@ -0,0 +1,180 @@
+package domain
+
+import (
+	"fmt"
+	"sort"
+	"time"
+)
+
+// 初回合格率を比較に使う最小検査件数
+const minInspectionsForYield = 5
+
+// ProgramPerformance はプログラムの加工実績（検査結果と1個あたりの加工時間）
+type ProgramPerformance struct {
+	ProgramID       NCProgramID
+	InspectionCount int
+	PassedCount     int
+	CompletedJobs   int
+	AvgCycleTime    time.Duration
+}
+
+// FirstPassYield は初回合格率を返す。検査件数が少ない場合は ok=false
+func (p ProgramPerformance) FirstPassYield() (float64, bool) {
+	if p.InspectionCount < minInspectionsForYield {
+		return 0, false
+	}
+	return float64(p.PassedCount) / float64(p.InspectionCount), true
+}
+
+type ProgramCandidate struct {
+	Program     *NCProgram
+	Eligible    bool
+	Performance ProgramPerformance
+	Reasons     []string
+}
+
+// ProgramRecommendation は選定結果。Selected が nil の場合は条件を満たすプログラムが無い
+type ProgramRecommendation struct {
+	PartID     string
+	MachineID  MachineID
+	Selected   *ProgramCandidate
+	Candidates []*ProgramCandidate
+}
+
+// evaluateCandidates はリリース状態・機械仕様との適合・版の新しさで候補を絞り込む。
+// 版の新しさは方言ごとに比べる（変換版は変換元の版を置き換えない）
+func evaluateCandidates(programs []*NCProgram, machine *Machine) []*ProgramCandidate {
+	latest := make(map[string]*NCProgram)
+	for _, program := range programs {
+		if !program.IsReleased() {
+			continue
+		}
+		key := latestProgramKey(program)
+		if current, ok := latest[key]; !ok || CompareVersions(program.Version, current.Version) > 0 {
+			latest[key] = program
+		}
+	}
+
+	candidates := make([]*ProgramCandidate, len(programs))
+	for i, program := range programs {
+		candidate := &ProgramCandidate{Program: program, Eligible: true}
+		reject := func(reason string) {
+			candidate.Eligible = false
+			candidate.Reasons = append(candidate.Reasons, reason)
+		}
+
+		if !program.IsReleased() {
+			reject(fmt.Sprintf("status %s: not released", program.Status))
+		} else if newer := latest[latestProgramKey(program)]; newer != program {
+			reject(fmt.Sprintf("superseded by approved version %s (%s)", newer.Version, newer.ID))
+		}
+		for _, reason := range CheckCompatibility(program, machine).Reasons() {
+			reject(reason)
+		}
+
+		if candidate.Eligible {
+			candidate.Reasons = append(candidate.Reasons,
+				fmt.Sprintf("approved version %s is the latest release of %s", program.Version, program.Name),
+				fmt.Sprintf("compatible with %s and its capabilities", machine.Type),
+			)
+		}
+		candidates[i] = candidate
+	}
+
+	return candidates
+}
+
+func latestProgramKey(program *NCProgram) string {
+	return program.Name + "\x00" + string(program.Dialect)
+}
+
+// rankCandidates は有効な候補を 初回合格率 → 加工時間 → 承認日時 の順に並べる
+func rankCandidates(candidates []*ProgramCandidate, machineType string) {
+	for _, candidate := range candidates {
+		if !candidate.Eligible {
+			continue
+		}
+		p := candidate.Performance
+		if fpy, ok := p.FirstPassYield(); ok {
+			candidate.Reasons = append(candidate.Reasons,
+				fmt.Sprintf("first-pass yield %.1f%% (%d/%d inspections)", fpy*100, p.PassedCount, p.InspectionCount))
+		} else {
+			candidate.Reasons = append(candidate.Reasons,
+				fmt.Sprintf("insufficient inspection history (%d inspections)", p.InspectionCount))
+		}
+		if p.CompletedJobs > 0 {
+			candidate.Reasons = append(candidate.Reasons,
+				fmt.Sprintf("average cycle time %s per part over %d completed jobs on %s", p.AvgCycleTime.Round(time.Second), p.CompletedJobs, machineType))
+		} else {
+			candidate.Reasons = append(candidate.Reasons, "no completed jobs on "+machineType)
+		}
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return betterCandidate(candidates[i], candidates[j]) > 0
+	})
+}
+
+// betterCandidate は a が b より優先される場合に正を返す
+func betterCandidate(a, b *ProgramCandidate) int {
+	if a.Eligible != b.Eligible {
+		if a.Eligible {
+			return 1
+		}
+		return -1
+	}
+
+	fa, okA := a.Performance.FirstPassYield()
+	fb, okB := b.Performance.FirstPassYield()
+	if okA != okB {
+		if okA {
+			return 1
+		}
+		return -1
+	}
+	// 1ポイント未満の差は同等とみなす
+	if okA && (fa-fb > 0.01 || fb-fa > 0.01) {
+		if fa > fb {
+			return 1
+		}
+		return -1
+	}
+
+	ca, cb := a.Performance, b.Performance
+	if ca.CompletedJobs > 0 && cb.CompletedJobs > 0 && ca.AvgCycleTime != cb.AvgCycleTime {
+		if ca.AvgCycleTime < cb.AvgCycleTime {
+			return 1
+		}
+		return -1
+	}
+
+	ta, tb := a.Program.ApprovedAt, b.Program.ApprovedAt
+	if ta != nil && tb != nil && !ta.Equal(*tb) {
+		if ta.After(*tb) {
+			return 1
+		}
+		return -1
+	}
+	return CompareVersions(a.Program.Version, b.Program.Version)
+}
+
+// explainSelection は次点との比較理由を選定結果に追加する
+func explainSelection(selected, runnerUp *ProgramCandidate) {
+	fs, okS := selected.Performance.FirstPassYield()
+	fr, okR := runnerUp.Performance.FirstPassYield()
+	var reason string
+	switch {
+	case okS && !okR:
+		reason = "proven inspection history"
+	case okS && okR && fs-fr > 0.01:
+		reason = fmt.Sprintf("higher first-pass yield (%.1f%% vs %.1f%%)", fs*100, fr*100)
+	case selected.Performance.CompletedJobs > 0 && runnerUp.Performance.CompletedJobs > 0 &&
+		selected.Performance.AvgCycleTime < runnerUp.Performance.AvgCycleTime:
+		reason = fmt.Sprintf("shorter cycle time (%s vs %s)",
+			selected.Performance.AvgCycleTime.Round(time.Second), runnerUp.Performance.AvgCycleTime.Round(time.Second))
+	default:
+		reason = "more recently approved"
+	}
+	selected.Reasons = append(selected.Reasons,
+		fmt.Sprintf("ranked above %s %s: %s", runnerUp.Program.ID, runnerUp.Program.Version, reason))
+}
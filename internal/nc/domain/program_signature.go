@@ -0,0 +1,141 @@
+package domain
+
+import (
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"strings"
+	"time"
+)
+
+type SignerKeyID string
+
+type SignerKeyStatus string
+
+const (
+	SignerKeyActive  SignerKeyStatus = "active"
+	SignerKeyRevoked SignerKeyStatus = "revoked"
+)
+
+// SignerKey はプログラムのリリース承認に署名する承認者の Ed25519 公開鍵
+type SignerKey struct {
+	ID        SignerKeyID
+	Owner     string
+	PublicKey ed25519.PublicKey
+	Status    SignerKeyStatus
+	CreatedBy string
+	CreatedAt time.Time
+	RevokedBy string
+	RevokedAt *time.Time
+	// RevokeReason は失効の理由（鍵の漏えい、退職等）
+	RevokeReason string
+}
+
+// NewSignerKey は base64 の Ed25519 公開鍵を登録する。ID は公開鍵のフィンガープリント
+func NewSignerKey(owner, publicKey, createdBy string) (*SignerKey, error) {
+	owner = strings.TrimSpace(owner)
+	raw, err := base64.StdEncoding.DecodeString(strings.TrimSpace(publicKey))
+	if err != nil || len(raw) != ed25519.PublicKeySize || owner == "" {
+		return nil, ErrInvalidSignerKey
+	}
+
+	fingerprint := sha256.Sum256(raw)
+	return &SignerKey{
+		ID:        SignerKeyID("key-" + hex.EncodeToString(fingerprint[:8])),
+		Owner:     owner,
+		PublicKey: ed25519.PublicKey(raw),
+		Status:    SignerKeyActive,
+		CreatedBy: createdBy,
+		CreatedAt: time.Now(),
+	}, nil
+}
+
+func (k *SignerKey) IsActive() bool {
+	return k.Status == SignerKeyActive
+}
+
+// Revoke は鍵を失効させる。失効した鍵による署名は展開時の検証で拒否される
+func (k *SignerKey) Revoke(revokedBy, reason string) error {
+	if !k.IsActive() {
+		return ErrSignerKeyRevoked
+	}
+	now := time.Now()
+	k.Status = SignerKeyRevoked
+	k.RevokedBy = revokedBy
+	k.RevokedAt = &now
+	k.RevokeReason = strings.TrimSpace(reason)
+	return nil
+}
+
+// EncodedPublicKey は公開鍵の base64 表記
+func (k *SignerKey) EncodedPublicKey() string {
+	return base64.StdEncoding.EncodeToString(k.PublicKey)
+}
+
+// SigningPayload は承認者が署名するバイト列。プログラムID・名前・版・ファイルハッシュを改行で連結する
+func (p *NCProgram) SigningPayload() []byte {
+	return []byte(strings.Join([]string{
+		"goNexttask-nc-program-v1",
+		string(p.ID),
+		p.Name,
+		p.Version,
+		p.FileHash,
+	}, "\n"))
+}
+
+// Sign は承認者の鍵で作成された署名を検証してプログラムに記録する
+func (p *NCProgram) Sign(key *SignerKey, signature []byte, signedBy string) error {
+	if !key.IsActive() {
+		return ErrSignerKeyRevoked
+	}
+	if key.Owner != signedBy {
+		return ErrSignerKeyOwnerMismatch
+	}
+	if err := p.verifyContentHash(); err != nil {
+		return err
+	}
+	if !ed25519.Verify(key.PublicKey, p.SigningPayload(), signature) {
+		return ErrInvalidProgramSignature
+	}
+
+	now := time.Now()
+	p.Signature = signature
+	p.SignerKeyID = key.ID
+	p.SignedBy = signedBy
+	p.SignedAt = &now
+	p.UpdatedAt = now
+	return nil
+}
+
+func (p *NCProgram) IsSigned() bool {
+	return len(p.Signature) > 0
+}
+
+// VerifySignature は本体がファイルハッシュと一致し、署名が有効な鍵によるものか検証する
+func (p *NCProgram) VerifySignature(key *SignerKey) error {
+	if !p.IsSigned() {
+		return ErrProgramNotSigned
+	}
+	if err := p.verifyContentHash(); err != nil {
+		return err
+	}
+	if key == nil || key.ID != p.SignerKeyID {
+		return ErrSignerKeyNotFound
+	}
+	if !key.IsActive() {
+		return ErrSignerKeyRevoked
+	}
+	if !ed25519.Verify(key.PublicKey, p.SigningPayload(), p.Signature) {
+		return ErrInvalidProgramSignature
+	}
+	return nil
+}
+
+func (p *NCProgram) verifyContentHash() error {
+	hash := sha256.Sum256(p.Content)
+	if hex.EncodeToString(hash[:]) != p.FileHash {
+		return ErrProgramHashMismatch
+	}
+	return nil
+}
@@ -1,19 +1,136 @@
 package domain
 
-import "context"
+import (
+	"context"
+	"time"
+)
 
 type NCProgramRepository interface {
 	Save(ctx context.Context, program *NCProgram) error
 	FindByID(ctx context.Context, id NCProgramID) (*NCProgram, error)
 	FindByNameAndVersion(ctx context.Context, name, version string) (*NCProgram, error)
 	FindAll(ctx context.Context) ([]*NCProgram, error)
+	FindByPartID(ctx context.Context, partID string) ([]*NCProgram, error)
+	Update(ctx context.Context, program *NCProgram) error
 	Delete(ctx context.Context, id NCProgramID) error
 }
 
 type MachineRepository interface {
 	Save(ctx context.Context, machine *Machine) error
+	// FindByID は論理削除済みの機械も返す（過去ジョブの参照用）
 	FindByID(ctx context.Context, id MachineID) (*Machine, error)
+	// FindAll / FindAvailable は論理削除済みの機械を含まない
 	FindAll(ctx context.Context) ([]*Machine, error)
 	FindAvailable(ctx context.Context) ([]*Machine, error)
 	Update(ctx context.Context, machine *Machine) error
-}
\ No newline at end of file
+	// UpdateStatus は稼働状態・ジョブ・エラーの列のみ更新する（ハートビートと仕様は書き換えない）
+	UpdateStatus(ctx context.Context, machine *Machine) error
+	// MarkOfflineIfStale は最終ハートビートが cutoff 以前でオフラインでない機械のみオフラインにし、
+	// 更新前のステータスを返す。その間にハートビートや状態の変化が届いていた場合は更新せず nil
+	MarkOfflineIfStale(ctx context.Context, id MachineID, cutoff time.Time) (*MachineStatus, error)
+	// UpdateHeartbeat は最終ハートビートのみ更新し、オフラインの機械は停止状態へ戻す。
+	// 戻した場合は更新前のステータスを返し、それ以外は nil
+	UpdateHeartbeat(ctx context.Context, id MachineID, at time.Time) (*MachineStatus, error)
+}
+
+type MachineStatusHistoryRepository interface {
+	Append(ctx context.Context, change *MachineStatusChange) error
+	FindByMachineID(ctx context.Context, id MachineID, from, to time.Time) ([]*MachineStatusChange, error)
+}
+
+type TelemetryRepository interface {
+	Append(ctx context.Context, points []TelemetryPoint) error
+	FindByMachineID(ctx context.Context, id MachineID, dataItem string, from, to time.Time) ([]TelemetryPoint, error)
+}
+
+type ConnectorConfigRepository interface {
+	Save(ctx context.Context, config *ConnectorConfig) error
+	FindByMachineID(ctx context.Context, id MachineID) (*ConnectorConfig, error)
+	FindEnabledByProtocol(ctx context.Context, protocol ConnectorProtocol) ([]*ConnectorConfig, error)
+}
+
+type NCJobRepository interface {
+	Save(ctx context.Context, job *NCJob) error
+	Update(ctx context.Context, job *NCJob) error
+	FindByID(ctx context.Context, id NCJobID) (*NCJob, error)
+	// FindActiveByMachineID は機械で実行中のジョブを返す。無い場合は ErrNCJobNotFound
+	FindActiveByMachineID(ctx context.Context, id MachineID) (*NCJob, error)
+	FindByMachineID(ctx context.Context, id MachineID, from, to time.Time) ([]*NCJob, error)
+	// FindByProductionOrderID は製造オーダーのジョブを開始の新しい順に返す
+	FindByProductionOrderID(ctx context.Context, productionOrderID string) ([]*NCJob, error)
+}
+
+type DeploymentRepository interface {
+	// Save は同じ機械・冪等キーの展開が既にある場合 ErrDeploymentAlreadyExists を返す
+	Save(ctx context.Context, deployment *ProgramDeployment) error
+	Update(ctx context.Context, deployment *ProgramDeployment) error
+	FindByID(ctx context.Context, id DeploymentID) (*ProgramDeployment, error)
+	FindByIdempotencyKey(ctx context.Context, machineID MachineID, key string) (*ProgramDeployment, error)
+	// FindLatestDeliveredByMachineID は機械へ最後に届いた（transferred/skipped）展開を返す
+	FindLatestDeliveredByMachineID(ctx context.Context, machineID MachineID) (*ProgramDeployment, error)
+	FindByMachineID(ctx context.Context, machineID MachineID, from, to time.Time) ([]*ProgramDeployment, error)
+	// FindByStatus は指定状態の展開を登録順に返す（再起動時の送信待ちの復元用）
+	FindByStatus(ctx context.Context, statuses ...DeploymentStatus) ([]*ProgramDeployment, error)
+}
+
+type ToolLibraryRepository interface {
+	SaveItem(ctx context.Context, item *ToolItem) error
+	FindItemByID(ctx context.Context, id ToolItemID) (*ToolItem, error)
+	FindAllItems(ctx context.Context) ([]*ToolItem, error)
+	SaveAssembly(ctx context.Context, assembly *ToolAssembly) error
+	FindAssemblyByID(ctx context.Context, id ToolAssemblyID) (*ToolAssembly, error)
+	FindAllAssemblies(ctx context.Context) ([]*ToolAssembly, error)
+}
+
+type ToolMagazineRepository interface {
+	// Save は工具番号に装着済みの工具を置き換える
+	Save(ctx context.Context, tool *MagazineTool) error
+	Update(ctx context.Context, tool *MagazineTool) error
+	Delete(ctx context.Context, machineID MachineID, toolNumber int) error
+	Find(ctx context.Context, machineID MachineID, toolNumber int) (*MagazineTool, error)
+	FindByMachineID(ctx context.Context, machineID MachineID) ([]*MagazineTool, error)
+}
+
+type OffsetRepository interface {
+	Find(ctx context.Context, machineID MachineID, register OffsetRegister) (*MachineOffset, error)
+	FindByMachineID(ctx context.Context, machineID MachineID) ([]*MachineOffset, error)
+	SaveChange(ctx context.Context, change *OffsetChange) error
+	UpdateChange(ctx context.Context, change *OffsetChange) error
+	FindChangeByID(ctx context.Context, id OffsetChangeID) (*OffsetChange, error)
+	// FindChanges は機械のオフセット変更を新しい順に返す。register / status が空の場合は絞り込まない
+	FindChanges(ctx context.Context, machineID MachineID, register OffsetRegister, status OffsetChangeStatus) ([]*OffsetChange, error)
+	// Apply は反映済みの変更とオフセットの新しい版を1つのトランザクションで記録する。
+	// 登録簿の版が offset.Version-1 でなければ ErrOffsetVersionConflict を返す
+	Apply(ctx context.Context, change *OffsetChange, offset *MachineOffset) error
+}
+
+type MachineAlarmRepository interface {
+	Save(ctx context.Context, alarm *MachineAlarm) error
+	Update(ctx context.Context, alarm *MachineAlarm) error
+	FindActiveByMachineID(ctx context.Context, machineID MachineID) ([]*MachineAlarm, error)
+	// FindByMachineID はアラームを発生時刻の新しい順に返す
+	FindByMachineID(ctx context.Context, machineID MachineID, filter AlarmFilter) ([]*MachineAlarm, error)
+}
+
+type AlarmCatalogRepository interface {
+	// Save は同じ機種・アラーム番号の登録を置き換える
+	Save(ctx context.Context, entry *AlarmCatalogEntry) error
+	Delete(ctx context.Context, controllerModel, code string) error
+	// FindByControllerModel は機種のアラーム番号をキーに返す
+	FindByControllerModel(ctx context.Context, controllerModel string) (map[string]*AlarmCatalogEntry, error)
+}
+
+type SignerKeyRepository interface {
+	// Save は同じ公開鍵が登録済みの場合 ErrSignerKeyAlreadyExists を返す
+	Save(ctx context.Context, key *SignerKey) error
+	Update(ctx context.Context, key *SignerKey) error
+	FindByID(ctx context.Context, id SignerKeyID) (*SignerKey, error)
+	// FindAll は owner が空の場合すべての鍵を返す
+	FindAll(ctx context.Context, owner string) ([]*SignerKey, error)
+}
+
+// ProgramPerformanceRepository はジョブ実績と検査結果からプログラムの加工実績を集計する。
+// 加工時間は machineType の機械での実績に限る
+type ProgramPerformanceRepository interface {
+	FindByProgramIDs(ctx context.Context, ids []NCProgramID, machineType string) (map[NCProgramID]ProgramPerformance, error)
+}
@@ -3,70 +3,441 @@ package domain
 import (
 	"context"
 	"errors"
+	"fmt"
+	"time"
 )
 
 var (
-	ErrNCProgramNotFound      = errors.New("NC program not found")
-	ErrMachineNotFound        = errors.New("machine not found")
-	ErrMachineNotAvailable    = errors.New("machine is not available")
-	ErrIncompatibleProgram    = errors.New("program is not compatible with machine")
-	ErrTransferFailed         = errors.New("program transfer failed")
+	ErrNCProgramNotFound         = errors.New("NC program not found")
+	ErrMachineNotFound           = errors.New("machine not found")
+	ErrMachineNotAvailable       = errors.New("machine is not available")
+	ErrMachineBusy               = errors.New("machine is busy")
+	ErrIncompatibleProgram       = errors.New("program is not compatible with machine")
+	ErrTransferFailed            = errors.New("program transfer failed")
+	ErrInvalidMachine            = errors.New("machine id and name are required")
+	ErrInvalidMachineIP          = errors.New("invalid machine IP address")
+	ErrInvalidMachineType        = errors.New("invalid machine type")
+	ErrInvalidCapability         = errors.New("invalid machine capabilities")
+	ErrInvalidMachineSpec        = errors.New("invalid machine specification")
+	ErrMachineDecommissioned     = errors.New("machine is decommissioned")
+	ErrMachineAlreadyExists      = errors.New("machine already exists")
+	ErrInvalidRunningState       = errors.New("invalid machine running state")
+	ErrInvalidStatusTransition   = errors.New("invalid machine status transition")
+	ErrMachineOffline            = errors.New("machine is offline")
+	ErrInvalidConnectorConfig    = errors.New("invalid connector configuration")
+	ErrConnectorConfigNotFound   = errors.New("connector configuration not found")
+	ErrNCJobNotFound             = errors.New("NC job not found")
+	ErrNCJobFinished             = errors.New("NC job is already finished")
+	ErrInvalidProducedCount      = errors.New("produced count must not be negative")
+	ErrInvalidProgramStatus      = errors.New("invalid NC program status for this operation")
+	ErrTransferTemporary         = errors.New("temporary transfer error")
+	ErrDeploymentNotFound        = errors.New("program deployment not found")
+	ErrDeploymentAlreadyExists   = errors.New("program deployment already exists")
+	ErrIdempotencyKeyConflict    = errors.New("idempotency key was already used for a different deployment")
+	ErrInvalidDeploymentStatus   = errors.New("invalid deployment status for this operation")
+	ErrDeploymentCancelled       = errors.New("program deployment was cancelled")
+	ErrInvalidToolItem           = errors.New("tool item kind and code are required")
+	ErrToolItemNotFound          = errors.New("tool item not found")
+	ErrInvalidToolAssembly       = errors.New("invalid tool assembly")
+	ErrToolAssemblyNotFound      = errors.New("tool assembly not found")
+	ErrInvalidToolNumber         = errors.New("invalid tool number for machine magazine")
+	ErrToolNotLoaded             = errors.New("tool is not loaded in machine magazine")
+	ErrInvalidToolUsage          = errors.New("tool usage must not be negative")
+	ErrRequiredToolMissing       = errors.New("required tool is not loaded")
+	ErrToolWornOut               = errors.New("required tool has reached its tool life")
+	ErrInvalidOffsetRegister     = errors.New("offset register must be T<number> or G54-G59")
+	ErrInvalidOffsetValues       = errors.New("invalid offset values")
+	ErrInvalidOffsetChange       = errors.New("offset change mode, reason and proposer are required")
+	ErrOffsetNotFound            = errors.New("offset not found")
+	ErrOffsetChangeNotFound      = errors.New("offset change not found")
+	ErrInvalidOffsetChangeStatus = errors.New("invalid offset change status for this operation")
+	ErrOffsetSelfApproval        = errors.New("offset change must be approved by someone other than the proposer")
+	ErrOffsetVersionConflict     = errors.New("offset was changed after this change was proposed")
+	ErrOffsetPushFailed          = errors.New("failed to write offset to machine controller")
+	ErrInvalidAlarmCatalogEntry  = errors.New("controller model, alarm code and description are required")
+	ErrAlarmCatalogEntryNotFound = errors.New("alarm catalog entry not found")
+	ErrInvalidAlarmFilter        = errors.New("invalid alarm filter")
+	ErrInvalidControlSpec        = errors.New("invalid machine control specification (axes, options or macros)")
+	ErrInvalidSignerKey          = errors.New("signer key owner and base64 Ed25519 public key are required")
+	ErrSignerKeyNotFound         = errors.New("signer key not found")
+	ErrSignerKeyAlreadyExists    = errors.New("signer key is already registered")
+	ErrSignerKeyRevoked          = errors.New("signer key is revoked")
+	ErrSignerKeyOwnerMismatch    = errors.New("signer key does not belong to the signer")
+	ErrInvalidProgramSignature   = errors.New("invalid NC program signature")
+	ErrProgramNotSigned          = errors.New("NC program is not signed")
+	ErrProgramHashMismatch       = errors.New("NC program content does not match its file hash")
+	ErrInvalidDialect            = errors.New("NC dialect must be fanuc, siemens or heidenhain")
+	ErrUnsupportedTranslation    = errors.New("translation between these NC dialects is not supported")
+	ErrProgramAlreadyExists      = errors.New("NC program with the same name and version already exists")
 )
 
 type NCTransferService struct {
-	programRepo NCProgramRepository
-	machineRepo MachineRepository
+	programRepo     NCProgramRepository
+	machineRepo     MachineRepository
+	deploymentRepo  DeploymentRepository
+	transferer      ProgramTransferer
+	retryPolicy     TransferRetryPolicy
+	jobTracker      *NCJobTracker
+	statusRecorder  *MachineStatusRecorder
+	toolLife        *ToolLifeService
+	performanceRepo ProgramPerformanceRepository
+	signerKeyRepo   SignerKeyRepository
 }
 
-func NewNCTransferService(programRepo NCProgramRepository, machineRepo MachineRepository) *NCTransferService {
+func NewNCTransferService(programRepo NCProgramRepository, machineRepo MachineRepository, deploymentRepo DeploymentRepository, transferer ProgramTransferer, jobTracker *NCJobTracker, statusRecorder *MachineStatusRecorder, toolLife *ToolLifeService, performanceRepo ProgramPerformanceRepository, signerKeyRepo SignerKeyRepository) *NCTransferService {
 	return &NCTransferService{
-		programRepo: programRepo,
-		machineRepo: machineRepo,
+		programRepo:     programRepo,
+		machineRepo:     machineRepo,
+		deploymentRepo:  deploymentRepo,
+		transferer:      transferer,
+		retryPolicy:     defaultTransferRetryPolicy,
+		jobTracker:      jobTracker,
+		statusRecorder:  statusRecorder,
+		toolLife:        toolLife,
+		performanceRepo: performanceRepo,
+		signerKeyRepo:   signerKeyRepo,
 	}
 }
 
-func (s *NCTransferService) TransferProgram(ctx context.Context, programID NCProgramID, machineID MachineID) error {
+// RequestDeployment はプログラムの展開要求を検証し、送信待ちとして登録する。
+// 同じ冪等キーの展開が既にある場合はその結果を返す（replayed = true）。
+// ただし失敗・取り消した展開は同じ冪等キーで送信待ちに戻す
+func (s *NCTransferService) RequestDeployment(ctx context.Context, programID NCProgramID, machineID MachineID, productionOrderID, idempotencyKey string) (deployment *ProgramDeployment, replayed bool, err error) {
+	if idempotencyKey != "" {
+		existing, err := s.deploymentRepo.FindByIdempotencyKey(ctx, machineID, idempotencyKey)
+		switch {
+		case err == nil:
+			if !existing.Matches(programID, productionOrderID) {
+				return nil, false, ErrIdempotencyKeyConflict
+			}
+			if !existing.IsRetryable() {
+				return existing, true, nil
+			}
+			deployment = existing
+		case !errors.Is(err, ErrDeploymentNotFound):
+			return nil, false, err
+		}
+	}
+	
+	program, machine, err := s.deploymentTarget(ctx, programID, machineID)
+	if err != nil {
+		return nil, false, err
+	}
+	
+	if deployment != nil {
+		if err := deployment.Retry(); err != nil {
+			return nil, false, err
+		}
+		if err := s.deploymentRepo.Update(ctx, deployment); err != nil {
+			return nil, false, err
+		}
+		return deployment, false, nil
+	}
+	
+	deployment = NewProgramDeployment(idempotencyKey, program, machine.ID, productionOrderID)
+	if err := s.deploymentRepo.Save(ctx, deployment); err != nil {
+		if errors.Is(err, ErrDeploymentAlreadyExists) {
+			// 同じ冪等キーの要求が並行して登録された
+			existing, findErr := s.deploymentRepo.FindByIdempotencyKey(ctx, machineID, idempotencyKey)
+			if findErr != nil {
+				return nil, false, findErr
+			}
+			return existing, true, nil
+		}
+		return nil, false, err
+	}
+	
+	return deployment, false, nil
+}
+
+// deploymentTarget は展開対象のプログラムと機械を取得し、展開できる状態（機械仕様との適合、署名、使用工具の装着と寿命を含む）か検証する。
+// 送信の直前にも呼ばれるため、要求後の鍵の失効やプログラム本体の改ざんは送信前に検出される
+func (s *NCTransferService) deploymentTarget(ctx context.Context, programID NCProgramID, machineID MachineID) (*NCProgram, *Machine, error) {
 	program, err := s.programRepo.FindByID(ctx, programID)
 	if err != nil {
-		return ErrNCProgramNotFound
+		return nil, nil, ErrNCProgramNotFound
 	}
 	
 	machine, err := s.machineRepo.FindByID(ctx, machineID)
 	if err != nil {
-		return ErrMachineNotFound
+		return nil, nil, ErrMachineNotFound
 	}
 	
-	if !machine.IsAvailable() {
-		return ErrMachineNotAvailable
+	if machine.IsDecommissioned() {
+		return nil, nil, ErrMachineDecommissioned
 	}
 	
-	if !program.IsCompatibleWith(machine.Type) {
-		return ErrIncompatibleProgram
+	if machine.Status.RunningState == StateOffline {
+		return nil, nil, ErrMachineOffline
 	}
 	
-	// TODO: 実際のNC機器への転送ロジックを実装
-	// ここではシミュレーションとして成功を返す
+	if err := CheckCompatibility(program, machine).Err(); err != nil {
+		return nil, nil, err
+	}
 	
-	machine.StartJob(string(programID))
-	if err := s.machineRepo.Update(ctx, machine); err != nil {
-		return err
+	if err := s.verifySignature(ctx, program); err != nil {
+		return nil, nil, err
+	}
+	
+	if err := s.toolLife.CheckReadiness(ctx, machine, program); err != nil {
+		return nil, nil, err
+	}
+	
+	return program, machine, nil
+}
+
+// ExecuteDeployment は送信待ちの展開を実行し、プログラムを機械へ転送してジョブを登録する。
+// 機械が加工中などで空いていない場合は送信待ちのまま ErrMachineBusy を返す。
+// 同じ機械への展開は呼び出し側で直列化すること。送信完了前に ctx が ErrDeploymentCancelled を
+// 原因としてキャンセルされた場合は展開を取り消し、それ以外の中断は失敗として記録する
+func (s *NCTransferService) ExecuteDeployment(ctx context.Context, id DeploymentID) (*ProgramDeployment, error) {
+	// キャンセルとタイムアウトは送信にのみ適用し、展開の状態は必ず記録する
+	ctx, transferCtx := context.WithoutCancel(ctx), ctx
+	
+	deployment, err := s.deploymentRepo.FindByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	// 実行待ちの間に取り消された
+	if deployment.Status != DeploymentQueued {
+		return deployment, nil
+	}
+	
+	// 加工中などで空いていない機械への展開は送信待ちのまま返し、空いてから再実行させる
+	if machine, err := s.machineRepo.FindByID(ctx, deployment.MachineID); err == nil && machine.IsBusy() {
+		return deployment, s.waitForMachine(ctx, transferCtx, deployment)
+	}
+	
+	if err := deployment.Start(); err != nil {
+		return nil, err
+	}
+	if err := s.deploymentRepo.Update(ctx, deployment); err != nil {
+		return nil, err
+	}
+	
+	program, machine, err := s.deploymentTarget(ctx, deployment.ProgramID, deployment.MachineID)
+	if err != nil {
+		return deployment, s.failDeployment(ctx, deployment, err)
+	}
+	if machine.IsBusy() {
+		if cause := context.Cause(transferCtx); errors.Is(cause, ErrDeploymentCancelled) {
+			return deployment, s.cancelDeployment(ctx, deployment, cause)
+		}
+		if err := deployment.Requeue(); err != nil {
+			return nil, err
+		}
+		if err := s.deploymentRepo.Update(ctx, deployment); err != nil {
+			return nil, err
+		}
+		return deployment, ErrMachineBusy
+	}
+	
+	if cause := context.Cause(transferCtx); errors.Is(cause, ErrDeploymentCancelled) {
+		return deployment, s.cancelDeployment(ctx, deployment, cause)
+	}
+	
+	skipped, err := s.holdsProgram(ctx, machine.ID, program)
+	if err != nil {
+		return deployment, s.failDeployment(ctx, deployment, err)
+	}
+	if !skipped {
+		if err := s.send(transferCtx, deployment, machine, program); err != nil {
+			if cause := context.Cause(transferCtx); errors.Is(cause, ErrDeploymentCancelled) {
+				return deployment, s.cancelDeployment(ctx, deployment, cause)
+			}
+			return deployment, s.failDeployment(ctx, deployment, fmt.Errorf("%w: %v", ErrTransferFailed, err))
+		}
+	}
+	
+	// 送信中に届いたハートビートや状態の変化を上書きしないよう、読み直して稼働状態の列のみ更新する
+	machine, err = s.machineRepo.FindByID(ctx, machine.ID)
+	if err != nil {
+		return deployment, s.failDeployment(ctx, deployment, err)
+	}
+	job := NewNCJob(program, machine.ID, deployment.ProductionOrderID)
+	change, err := machine.StartJob(string(job.ID))
+	if err != nil {
+		return deployment, s.failDeployment(ctx, deployment, err)
+	}
+	if err := s.jobTracker.Start(ctx, job); err != nil {
+		return deployment, s.failDeployment(ctx, deployment, err)
+	}
+	if err := s.machineRepo.UpdateStatus(ctx, machine); err != nil {
+		return nil, err
+	}
+	if err := s.statusRecorder.Record(ctx, change); err != nil {
+		return nil, err
+	}
+	
+	if err := deployment.Deliver(job.ID, skipped); err != nil {
+		return nil, err
+	}
+	if err := s.deploymentRepo.Update(ctx, deployment); err != nil {
+		return nil, err
 	}
 	
-	return nil
+	return deployment, nil
+}
+
+// CancelDeployment は送信待ちの展開を取り消す。送信中の展開は実行中の ctx をキャンセルして取り消す
+func (s *NCTransferService) CancelDeployment(ctx context.Context, id DeploymentID, reason string) (*ProgramDeployment, error) {
+	deployment, err := s.deploymentRepo.FindByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if deployment.Status != DeploymentQueued {
+		return nil, ErrInvalidDeploymentStatus
+	}
+	if err := deployment.Cancel(reason); err != nil {
+		return nil, err
+	}
+	if err := s.deploymentRepo.Update(ctx, deployment); err != nil {
+		return nil, err
+	}
+	return deployment, nil
+}
+
+// AbandonDeployment はサーバー停止などで送信中のまま残った展開を失敗として記録する
+func (s *NCTransferService) AbandonDeployment(ctx context.Context, deployment *ProgramDeployment, reason string) error {
+	if err := deployment.Fail(reason); err != nil {
+		return err
+	}
+	return s.deploymentRepo.Update(ctx, deployment)
+}
+
+// verifySignature はプログラムの署名を登録済みの署名鍵で検証する
+func (s *NCTransferService) verifySignature(ctx context.Context, program *NCProgram) error {
+	if !program.IsSigned() {
+		return ErrProgramNotSigned
+	}
+	key, err := s.signerKeyRepo.FindByID(ctx, program.SignerKeyID)
+	if err != nil && !errors.Is(err, ErrSignerKeyNotFound) {
+		return err
+	}
+	return program.VerifySignature(key)
+}
+
+// holdsProgram は機械へ最後に届いたプログラムが同じファイルハッシュか判定する
+func (s *NCTransferService) holdsProgram(ctx context.Context, machineID MachineID, program *NCProgram) (bool, error) {
+	if program.FileHash == "" {
+		return false, nil
+	}
+	latest, err := s.deploymentRepo.FindLatestDeliveredByMachineID(ctx, machineID)
+	if errors.Is(err, ErrDeploymentNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return latest.FileHash == program.FileHash, nil
+}
+
+// send は一時的なエラーの間、再送ポリシーに従って送信を繰り返し、各回を転送ログに記録する
+func (s *NCTransferService) send(ctx context.Context, deployment *ProgramDeployment, machine *Machine, program *NCProgram) error {
+	for attempt := 1; ; attempt++ {
+		startedAt := time.Now()
+		bytesSent, err := s.transferer.Transfer(ctx, machine, program)
+		deployment.RecordAttempt(startedAt, time.Since(startedAt), bytesSent, err)
+		if err == nil {
+			return nil
+		}
+		if !errors.Is(err, ErrTransferTemporary) || attempt >= s.retryPolicy.MaxAttempts {
+			return err
+		}
+		
+		timer := time.NewTimer(s.retryPolicy.Delay(attempt))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// cancelDeployment は送信中に取り消された展開を記録し、元のエラーを返す
+func (s *NCTransferService) cancelDeployment(ctx context.Context, deployment *ProgramDeployment, cause error) error {
+	if err := deployment.Cancel(cause.Error()); err != nil {
+		return cause
+	}
+	if err := s.deploymentRepo.Update(ctx, deployment); err != nil {
+		return err
+	}
+	return cause
+}
+
+// waitForMachine は空いていない機械への送信待ちの展開について ErrMachineBusy を返す。
+// 実行中に取り消されていた場合は送信待ちに戻さず取り消しを記録する
+func (s *NCTransferService) waitForMachine(ctx, transferCtx context.Context, deployment *ProgramDeployment) error {
+	if cause := context.Cause(transferCtx); errors.Is(cause, ErrDeploymentCancelled) {
+		return s.cancelDeployment(ctx, deployment, cause)
+	}
+	return ErrMachineBusy
+}
+
+// failDeployment は展開を失敗として記録し、元のエラーを返す
+func (s *NCTransferService) failDeployment(ctx context.Context, deployment *ProgramDeployment, cause error) error {
+	if err := deployment.Fail(cause.Error()); err != nil {
+		return cause
+	}
+	if err := s.deploymentRepo.Update(ctx, deployment); err != nil {
+		return err
+	}
+	return cause
 }
 
-func (s *NCTransferService) SelectOptimalProgram(ctx context.Context, partID string, machineType string) (*NCProgram, error) {
-	// TODO: 部品IDと機械タイプから最適なNCプログラムを選定するロジック
-	programs, err := s.programRepo.FindAll(ctx)
+// SelectOptimalProgram は部品に紐づくリリース済みプログラムから、機械タイプ・加工能力に適合し、
+// 初回合格率と加工時間の実績が最も良いものを選定理由とともに返す
+func (s *NCTransferService) SelectOptimalProgram(ctx context.Context, partID string, machineID MachineID) (*ProgramRecommendation, error) {
+	machine, err := s.machineRepo.FindByID(ctx, machineID)
 	if err != nil {
 		return nil, err
 	}
+	if machine.IsDecommissioned() {
+		return nil, ErrMachineDecommissioned
+	}
 	
-	for _, program := range programs {
-		if program.IsCompatibleWith(machineType) {
-			return program, nil
+	programs, err := s.programRepo.FindByPartID(ctx, partID)
+	if err != nil {
+		return nil, err
+	}
+	if len(programs) == 0 {
+		return nil, ErrNCProgramNotFound
+	}
+	
+	candidates := evaluateCandidates(programs, machine)
+	
+	var eligibleIDs []NCProgramID
+	for _, candidate := range candidates {
+		if candidate.Eligible {
+			eligibleIDs = append(eligibleIDs, candidate.Program.ID)
 		}
 	}
+	if len(eligibleIDs) > 0 {
+		performances, err := s.performanceRepo.FindByProgramIDs(ctx, eligibleIDs, machine.Type)
+		if err != nil {
+			return nil, err
+		}
+		for _, candidate := range candidates {
+			if p, ok := performances[candidate.Program.ID]; ok {
+				candidate.Performance = p
+			}
+		}
+	}
+	
+	rankCandidates(candidates, machine.Type)
 	
-	return nil, ErrNCProgramNotFound
-}
\ No newline at end of file
+	recommendation := &ProgramRecommendation{
+		PartID:     partID,
+		MachineID:  machine.ID,
+		Candidates: candidates,
+	}
+	if candidates[0].Eligible {
+		recommendation.Selected = candidates[0]
+		if len(candidates) > 1 && candidates[1].Eligible {
+			explainSelection(candidates[0], candidates[1])
+		}
+	}
+	
+	return recommendation, nil
+}
@@ -0,0 +1,36 @@
+package domain
+
+import "time"
+
+type TelemetryCategory string
+
+const (
+	TelemetrySample    TelemetryCategory = "sample"
+	TelemetryEvent     TelemetryCategory = "event"
+	TelemetryCondition TelemetryCategory = "condition"
+)
+
+// TelemetryPoint は機械から収集した時系列データの1点
+type TelemetryPoint struct {
+	MachineID    MachineID
+	Source       string
+	DataItem     string
+	Category     TelemetryCategory
+	Value        string
+	NumericValue *float64
+	Timestamp    time.Time
+}
+
+// MachineObservation はコネクタが機械から取得した状態のスナップショット。
+// RunningState が空の場合、稼働状態は判定できなかったことを示す
+type MachineObservation struct {
+	MachineID    MachineID
+	Source       string
+	RunningState MachineRunningState
+	ProgramName  string
+	PartCount    *int
+	SpindleLoad  *float64
+	Alarms       []ObservedAlarm
+	ObservedAt   time.Time
+	Points       []TelemetryPoint
+}
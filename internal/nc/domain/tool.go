@@ -0,0 +1,160 @@
+package domain
+
+import (
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type ToolItemID string
+
+type ToolItemKind string
+
+const (
+	ToolHolder ToolItemKind = "holder"
+	ToolInsert ToolItemKind = "insert"
+	// ToolCutter はエンドミル・ドリル等のソリッド工具
+	ToolCutter ToolItemKind = "cutter"
+)
+
+// ToolItem は工具ライブラリの部品（ホルダー、インサート、ソリッド工具）
+type ToolItem struct {
+	ID          ToolItemID
+	Kind        ToolItemKind
+	Code        string
+	Description string
+	CreatedAt   time.Time
+}
+
+func NewToolItem(kind ToolItemKind, code, description string) (*ToolItem, error) {
+	item := &ToolItem{
+		ID:          ToolItemID("titem-" + uuid.New().String()[:8]),
+		Kind:        kind,
+		Code:        strings.TrimSpace(code),
+		Description: description,
+		CreatedAt:   time.Now(),
+	}
+	if item.Code == "" {
+		return nil, ErrInvalidToolItem
+	}
+	switch kind {
+	case ToolHolder, ToolInsert, ToolCutter:
+	default:
+		return nil, ErrInvalidToolItem
+	}
+	return item, nil
+}
+
+// IsCuttingEdge はアセンブリの刃先になれる部品か判定する
+func (i *ToolItem) IsCuttingEdge() bool {
+	return i.Kind == ToolInsert || i.Kind == ToolCutter
+}
+
+type ToolAssemblyID string
+
+// ToolLifeLimit は工具寿命の上限。0 の項目は管理しない
+type ToolLifeLimit struct {
+	CutTime   time.Duration
+	PartCount int
+}
+
+// ToolAssembly はホルダーと刃先（インサートまたはソリッド工具）を組み合わせた、マガジンに装着する単位
+type ToolAssembly struct {
+	ID            ToolAssemblyID
+	Name          string
+	HolderID      ToolItemID
+	CuttingItemID ToolItemID
+	Life          ToolLifeLimit
+	CreatedAt     time.Time
+	UpdatedAt     time.Time
+}
+
+func NewToolAssembly(name string, holder, cuttingItem *ToolItem, life ToolLifeLimit) (*ToolAssembly, error) {
+	if strings.TrimSpace(name) == "" || cuttingItem == nil || !cuttingItem.IsCuttingEdge() {
+		return nil, ErrInvalidToolAssembly
+	}
+	if holder != nil && holder.Kind != ToolHolder {
+		return nil, ErrInvalidToolAssembly
+	}
+	if life.CutTime < 0 || life.PartCount < 0 {
+		return nil, ErrInvalidToolAssembly
+	}
+
+	now := time.Now()
+	assembly := &ToolAssembly{
+		ID:            ToolAssemblyID("tasm-" + uuid.New().String()[:8]),
+		Name:          strings.TrimSpace(name),
+		CuttingItemID: cuttingItem.ID,
+		Life:          life,
+		CreatedAt:     now,
+		UpdatedAt:     now,
+	}
+	if holder != nil {
+		assembly.HolderID = holder.ID
+	}
+	return assembly, nil
+}
+
+// MagazineTool は機械のマガジンの工具番号に装着された工具と、その使用実績
+type MagazineTool struct {
+	MachineID  MachineID
+	ToolNumber int
+	AssemblyID ToolAssemblyID
+	Life       ToolLifeLimit
+	CutTime    time.Duration
+	PartCount  int
+	LoadedAt   time.Time
+	UpdatedAt  time.Time
+}
+
+// LoadTool は工具をマガジンへ装着する。新しい工具として使用実績は 0 から数える
+func LoadTool(machine *Machine, toolNumber int, assembly *ToolAssembly) (*MagazineTool, error) {
+	if machine.IsDecommissioned() {
+		return nil, ErrMachineDecommissioned
+	}
+	if toolNumber <= 0 || (machine.ToolMagazineSize > 0 && toolNumber > machine.ToolMagazineSize) {
+		return nil, ErrInvalidToolNumber
+	}
+
+	now := time.Now()
+	return &MagazineTool{
+		MachineID:  machine.ID,
+		ToolNumber: toolNumber,
+		AssemblyID: assembly.ID,
+		Life:       assembly.Life,
+		LoadedAt:   now,
+		UpdatedAt:  now,
+	}, nil
+}
+
+// RecordUsage は切削時間と加工数を加算する。この記録で寿命に達した場合 true を返す
+func (t *MagazineTool) RecordUsage(cutTime time.Duration, parts int) (bool, error) {
+	if cutTime < 0 || parts < 0 {
+		return false, ErrInvalidToolUsage
+	}
+	wasWornOut := t.IsWornOut()
+	t.CutTime += cutTime
+	t.PartCount += parts
+	t.UpdatedAt = time.Now()
+	return !wasWornOut && t.IsWornOut(), nil
+}
+
+func (t *MagazineTool) IsWornOut() bool {
+	if t.Life.CutTime > 0 && t.CutTime >= t.Life.CutTime {
+		return true
+	}
+	return t.Life.PartCount > 0 && t.PartCount >= t.Life.PartCount
+}
+
+// RemainingLife は寿命までの残り。管理していない項目は 0 を返す
+func (t *MagazineTool) RemainingLife() ToolLifeLimit {
+	var remaining ToolLifeLimit
+	if t.Life.CutTime > 0 && t.CutTime < t.Life.CutTime {
+		remaining.CutTime = t.Life.CutTime - t.CutTime
+	}
+	if t.Life.PartCount > 0 && t.PartCount < t.Life.PartCount {
+		remaining.PartCount = t.Life.PartCount - t.PartCount
+	}
+	return remaining
+}
@@ -0,0 +1,142 @@
+package domain
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// ToolLifeService はプログラムが必要とする工具の装着・寿命を確認し、加工実績から工具寿命を更新する
+type ToolLifeService struct {
+	programRepo  NCProgramRepository
+	machineRepo  MachineRepository
+	magazineRepo ToolMagazineRepository
+	publisher    EventPublisher
+}
+
+func NewToolLifeService(programRepo NCProgramRepository, machineRepo MachineRepository, magazineRepo ToolMagazineRepository, publisher EventPublisher) *ToolLifeService {
+	return &ToolLifeService{
+		programRepo:  programRepo,
+		machineRepo:  machineRepo,
+		magazineRepo: magazineRepo,
+		publisher:    publisher,
+	}
+}
+
+// CheckReadiness はプログラムの使用工具がすべて機械に装着され、寿命に達していないか確認する。
+// 未装着は ErrRequiredToolMissing、寿命切れは ErrToolWornOut を工具番号付きで返す
+func (s *ToolLifeService) CheckReadiness(ctx context.Context, machine *Machine, program *NCProgram) error {
+	requirements := program.RequiredToolsOn(machine.Type)
+	if len(requirements) == 0 {
+		return nil
+	}
+
+	loaded, err := s.magazine(ctx, machine.ID)
+	if err != nil {
+		return err
+	}
+
+	var missing, wornOut []string
+	for _, requirement := range requirements {
+		tool, ok := loaded[requirement.ToolNumber]
+		switch {
+		case !ok:
+			missing = append(missing, toolLabel(requirement))
+		case tool.IsWornOut():
+			wornOut = append(wornOut, toolLabel(requirement))
+		}
+	}
+
+	if len(missing) > 0 {
+		return fmt.Errorf("%w: %s", ErrRequiredToolMissing, strings.Join(missing, ", "))
+	}
+	if len(wornOut) > 0 {
+		return fmt.Errorf("%w: %s", ErrToolWornOut, strings.Join(wornOut, ", "))
+	}
+	return nil
+}
+
+// ReportUsage は機械やオペレーターから報告された工具の使用実績を記録する
+func (s *ToolLifeService) ReportUsage(ctx context.Context, machineID MachineID, toolNumber int, cutTime time.Duration, parts int) (*MagazineTool, error) {
+	tool, err := s.magazineRepo.Find(ctx, machineID, toolNumber)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.recordUsage(ctx, tool, cutTime, parts); err != nil {
+		return nil, err
+	}
+	return tool, nil
+}
+
+// RecordJobUsage は終了したジョブの加工数と加工時間を、プログラムが使用する装着工具へ加算する。
+// 工具ごとの切削時間は取得できないため、ジョブの加工時間を使用工具で等分した推定値とする
+func (s *ToolLifeService) RecordJobUsage(ctx context.Context, job *NCJob) error {
+	if job.EndedAt == nil {
+		return nil
+	}
+
+	program, err := s.programRepo.FindByID(ctx, job.ProgramID)
+	if err != nil {
+		return err
+	}
+	machine, err := s.machineRepo.FindByID(ctx, job.MachineID)
+	if err != nil {
+		return err
+	}
+	requirements := program.RequiredToolsOn(machine.Type)
+	if len(requirements) == 0 {
+		return nil
+	}
+
+	loaded, err := s.magazine(ctx, job.MachineID)
+	if err != nil {
+		return err
+	}
+
+	cutTime := job.EndedAt.Sub(job.StartedAt) / time.Duration(len(requirements))
+	for _, requirement := range requirements {
+		tool, ok := loaded[requirement.ToolNumber]
+		if !ok {
+			continue
+		}
+		if err := s.recordUsage(ctx, tool, cutTime, job.ProducedCount); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *ToolLifeService) recordUsage(ctx context.Context, tool *MagazineTool, cutTime time.Duration, parts int) error {
+	expired, err := tool.RecordUsage(cutTime, parts)
+	if err != nil {
+		return err
+	}
+	if err := s.magazineRepo.Update(ctx, tool); err != nil {
+		return err
+	}
+	if expired {
+		return s.publisher.Publish(ctx, NewToolLifeExpiredEvent(tool))
+	}
+	return nil
+}
+
+func (s *ToolLifeService) magazine(ctx context.Context, machineID MachineID) (map[int]*MagazineTool, error) {
+	tools, err := s.magazineRepo.FindByMachineID(ctx, machineID)
+	if err != nil {
+		return nil, err
+	}
+	loaded := make(map[int]*MagazineTool, len(tools))
+	for _, tool := range tools {
+		loaded[tool.ToolNumber] = tool
+	}
+	return loaded, nil
+}
+
+func toolLabel(requirement ToolRequirement) string {
+	label := fmt.Sprintf("T%02d", requirement.ToolNumber)
+	if requirement.Description != "" {
+		label += " (" + requirement.Description + ")"
+	}
+	return label
+}
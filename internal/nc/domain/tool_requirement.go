@@ -0,0 +1,101 @@
+package domain
+
+import (
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ToolRequirement はプログラムが使用する工具番号と、プログラム中のコメントによる工具の説明
+type ToolRequirement struct {
+	ToolNumber  int
+	Description string
+}
+
+var (
+	// 工具リストのコメント "(T01: ROUGH TURNING INSERT CNMG120408)"
+	toolListComment = regexp.MustCompile(`^\(\s*T(\d+)\s*:\s*(.*?)\s*\)$`)
+	toolWord        = regexp.MustCompile(`(?:^|[^A-Z#\[])T(\d+)`)
+	commentPattern  = regexp.MustCompile(`\([^)]*\)`)
+)
+
+// UsesToolOffsetWords は T ワードの下2桁を補正番号とする機械タイプ（旋盤・複合加工機）か判定する
+func UsesToolOffsetWords(machineType string) bool {
+	return machineType == "LATHE" || machineType == "MILL-TURN"
+}
+
+// RequiredTools はプログラム本体から使用する工具を抽出する。
+// 対象の機械タイプがすべて旋盤・複合加工機の場合だけ T ワードを工具番号と補正番号に分ける
+func (p *NCProgram) RequiredTools() []ToolRequirement {
+	toolOffset := len(p.MachineCompatibility) > 0
+	for _, machineType := range p.MachineCompatibility {
+		if !UsesToolOffsetWords(machineType) {
+			toolOffset = false
+		}
+	}
+	return ExtractToolRequirements(string(p.Content), toolOffset)
+}
+
+// RequiredToolsOn は machineType の機械で実行する場合の使用工具を抽出する
+func (p *NCProgram) RequiredToolsOn(machineType string) []ToolRequirement {
+	return ExtractToolRequirements(string(p.Content), UsesToolOffsetWords(machineType))
+}
+
+// ExtractToolRequirements は NC プログラムの T ワードと工具リストのコメントから使用工具を工具番号順に返す。
+// toolOffset の場合は3桁以上の T ワードの下2桁を補正番号とみなす（旋盤の T0101 は工具番号 1）。
+// それ以外はマシニングセンターの T123 のように T ワード全体を工具番号とする
+func ExtractToolRequirements(content string, toolOffset bool) []ToolRequirement {
+	descriptions := make(map[int]string)
+	used := make(map[int]bool)
+
+	for _, line := range strings.Split(content, "\n") {
+		line = strings.ToUpper(strings.TrimSpace(line))
+		if line == "" {
+			continue
+		}
+
+		if m := toolListComment.FindStringSubmatch(line); m != nil {
+			if number, err := strconv.Atoi(m[1]); err == nil && number > 0 {
+				descriptions[number] = m[2]
+				used[number] = true
+			}
+			continue
+		}
+
+		code := commentPattern.ReplaceAllString(line, " ")
+		for _, m := range toolWord.FindAllStringSubmatch(code, -1) {
+			number := toolNumber(m[1], toolOffset)
+			if number <= 0 {
+				continue
+			}
+			used[number] = true
+			// T ワードの後ろのコメントを説明とする "T0202 (6MM ENDMILL)"
+			if _, ok := descriptions[number]; !ok {
+				if comment := commentPattern.FindString(line); comment != "" {
+					descriptions[number] = strings.TrimSpace(strings.Trim(comment, "()"))
+				}
+			}
+		}
+	}
+
+	requirements := make([]ToolRequirement, 0, len(used))
+	for number := range used {
+		requirements = append(requirements, ToolRequirement{ToolNumber: number, Description: descriptions[number]})
+	}
+	sort.Slice(requirements, func(i, j int) bool {
+		return requirements[i].ToolNumber < requirements[j].ToolNumber
+	})
+	return requirements
+}
+
+func toolNumber(digits string, toolOffset bool) int {
+	if toolOffset && len(digits) >= 3 {
+		digits = digits[:len(digits)-2]
+	}
+	number, err := strconv.Atoi(digits)
+	if err != nil {
+		return 0
+	}
+	return number
+}
@@ -0,0 +1,48 @@
+package connector
+
+import (
+	"context"
+	"goNexttask/internal/nc/domain"
+	"math/rand"
+	"time"
+)
+
+// ObservationSink はコネクタが取得した機械の状態を受け取る（通常は NCUseCase）
+type ObservationSink interface {
+	IngestObservation(ctx context.Context, observation domain.MachineObservation) error
+}
+
+// Backoff は再接続の待ち時間を指数的に延ばす
+type Backoff struct {
+	Min     time.Duration
+	Max     time.Duration
+	attempt int
+}
+
+func (b *Backoff) Next() time.Duration {
+	d := b.Min << uint(b.attempt)
+	if d <= 0 || d > b.Max {
+		d = b.Max
+	} else {
+		b.attempt++
+	}
+	// 複数機械の同時再接続を避けるため ±20% のゆらぎを加える
+	jitter := time.Duration(rand.Int63n(int64(d)/5+1)) - d/10
+	return d + jitter
+}
+
+func (b *Backoff) Reset() {
+	b.attempt = 0
+}
+
+// sleep は ctx がキャンセルされた場合 false を返す
+func sleep(ctx context.Context, d time.Duration) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return false
+	case <-timer.C:
+		return true
+	}
+}
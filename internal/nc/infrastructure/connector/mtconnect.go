@@ -0,0 +1,267 @@
+package connector
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"goNexttask/internal/nc/domain"
+	"io"
+	"log"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+var errMTConnectInstanceChanged = errors.New("mtconnect agent instance changed")
+
+// MTConnectClient は MTConnect エージェントの REST API クライアント
+type MTConnectClient struct {
+	baseURL    string
+	device     string
+	httpClient *http.Client
+}
+
+func NewMTConnectClient(baseURL, device string, httpClient *http.Client) *MTConnectClient {
+	if httpClient == nil {
+		httpClient = &http.Client{}
+	}
+	return &MTConnectClient{
+		baseURL:    strings.TrimRight(baseURL, "/"),
+		device:     device,
+		httpClient: httpClient,
+	}
+}
+
+func (c *MTConnectClient) endpoint(request string, params url.Values) string {
+	path := c.baseURL
+	if c.device != "" {
+		path += "/" + url.PathEscape(c.device)
+	}
+	path += "/" + request
+	if len(params) > 0 {
+		path += "?" + params.Encode()
+	}
+	return path
+}
+
+// Current は /current を取得する
+func (c *MTConnectClient) Current(ctx context.Context) (*mtcStreams, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.endpoint("current", nil), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("mtconnect current: unexpected status %d", resp.StatusCode)
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	return parseMTConnectStreams(body)
+}
+
+// StreamSample は /sample を multipart/x-mixed-replace でストリーミングし、
+// 受信したドキュメントごとに handle を呼ぶ。ストリームが終了するか handle がエラーを返すと戻る
+func (c *MTConnectClient) StreamSample(ctx context.Context, from uint64, interval time.Duration, handle func(*mtcStreams) error) error {
+	params := url.Values{}
+	params.Set("from", strconv.FormatUint(from, 10))
+	params.Set("interval", strconv.FormatInt(interval.Milliseconds(), 10))
+	params.Set("count", "1000")
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.endpoint("sample", params), nil)
+	if err != nil {
+		return err
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("mtconnect sample: unexpected status %d", resp.StatusCode)
+	}
+
+	mediaType, mediaParams, err := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	if err != nil {
+		return err
+	}
+
+	// interval 非対応のエージェントは単一ドキュメントを返す
+	if !strings.HasPrefix(mediaType, "multipart/") {
+		body, err := io.ReadAll(resp.Body)
+		if err != nil {
+			return err
+		}
+		streams, err := parseMTConnectStreams(body)
+		if err != nil {
+			return err
+		}
+		return handle(streams)
+	}
+
+	reader := multipart.NewReader(resp.Body, mediaParams["boundary"])
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		body, err := io.ReadAll(part)
+		part.Close()
+		if err != nil {
+			return err
+		}
+		if len(strings.TrimSpace(string(body))) == 0 {
+			continue
+		}
+
+		streams, err := parseMTConnectStreams(body)
+		if err != nil {
+			return err
+		}
+		if err := handle(streams); err != nil {
+			return err
+		}
+	}
+}
+
+// MTConnectAdapter は1台の機械について /current で同期した後 /sample を購読し、
+// 状態を ObservationSink へ渡す。切断時はバックオフ付きで再接続する
+type MTConnectAdapter struct {
+	config  *domain.ConnectorConfig
+	client  *MTConnectClient
+	sink    ObservationSink
+	backoff Backoff
+}
+
+func NewMTConnectAdapter(config *domain.ConnectorConfig, client *MTConnectClient, sink ObservationSink) *MTConnectAdapter {
+	return &MTConnectAdapter{
+		config:  config,
+		client:  client,
+		sink:    sink,
+		backoff: Backoff{Min: time.Second, Max: time.Minute},
+	}
+}
+
+func (a *MTConnectAdapter) Run(ctx context.Context) {
+	for ctx.Err() == nil {
+		err := a.session(ctx)
+		if ctx.Err() != nil {
+			return
+		}
+		wait := a.backoff.Next()
+		log.Printf("MTConnect session for machine %s ended: %v (retry in %s)", a.config.MachineID, err, wait)
+		if !sleep(ctx, wait) {
+			return
+		}
+	}
+}
+
+func (a *MTConnectAdapter) session(ctx context.Context) error {
+	state := newMTConnectMachineState()
+
+	current, err := a.client.Current(ctx)
+	if err != nil {
+		return err
+	}
+	a.backoff.Reset()
+
+	if err := a.ingest(ctx, state, current); err != nil {
+		return err
+	}
+
+	instanceID := current.Header.InstanceID
+	next := current.Header.NextSequence
+
+	return a.client.StreamSample(ctx, next, a.config.PollInterval, func(streams *mtcStreams) error {
+		// エージェント再起動時はシーケンスが振り直されるため /current から再同期する
+		if streams.Header.InstanceID != instanceID {
+			return errMTConnectInstanceChanged
+		}
+		return a.ingest(ctx, state, streams)
+	})
+}
+
+func (a *MTConnectAdapter) ingest(ctx context.Context, state *mtcMachineState, streams *mtcStreams) error {
+	var points []domain.TelemetryPoint
+	for _, device := range streams.Devices {
+		if a.config.DeviceName != "" && device.Name != a.config.DeviceName {
+			continue
+		}
+		points = append(points, state.apply(a.config.MachineID, device)...)
+	}
+
+	return a.sink.IngestObservation(ctx, state.observation(a.config.MachineID, points))
+}
+
+// Manager は有効なコネクタ設定ごとにアダプタを起動する
+type Manager struct {
+	configRepo domain.ConnectorConfigRepository
+	sink       ObservationSink
+	httpClient *http.Client
+}
+
+func NewManager(configRepo domain.ConnectorConfigRepository, sink ObservationSink) *Manager {
+	return &Manager{
+		configRepo: configRepo,
+		sink:       sink,
+		httpClient: &http.Client{},
+	}
+}
+
+// Run は起動時点の設定でアダプタを起動し、全アダプタが終了（ctx のキャンセル）するまでブロックする
+func (m *Manager) Run(ctx context.Context) error {
+	var wg sync.WaitGroup
+
+	configs, err := m.configRepo.FindEnabledByProtocol(ctx, domain.ProtocolMTConnect)
+	if err != nil {
+		return err
+	}
+	for _, config := range configs {
+		client := NewMTConnectClient(config.Endpoint, config.DeviceName, m.httpClient)
+		adapter := NewMTConnectAdapter(config, client, m.sink)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			adapter.Run(ctx)
+		}()
+		log.Printf("MTConnect adapter started for machine %s (%s)", config.MachineID, config.Endpoint)
+	}
+
+	configs, err = m.configRepo.FindEnabledByProtocol(ctx, domain.ProtocolOPCUA)
+	if err != nil {
+		wg.Wait()
+		return err
+	}
+	for _, config := range configs {
+		adapter := NewOPCUAAdapter(config, m.sink)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			adapter.Run(ctx)
+		}()
+		log.Printf("OPC UA adapter started for machine %s (%s)", config.MachineID, config.Endpoint)
+	}
+
+	wg.Wait()
+	return nil
+}
@@ -0,0 +1,239 @@
+package connector
+
+import (
+	"encoding/xml"
+	"goNexttask/internal/nc/domain"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// MTConnectStreams は /current, /sample のレスポンス
+type mtcStreams struct {
+	XMLName xml.Name          `xml:"MTConnectStreams"`
+	Header  mtcHeader         `xml:"Header"`
+	Devices []mtcDeviceStream `xml:"Streams>DeviceStream"`
+}
+
+type mtcHeader struct {
+	InstanceID    uint64 `xml:"instanceId,attr"`
+	FirstSequence uint64 `xml:"firstSequence,attr"`
+	LastSequence  uint64 `xml:"lastSequence,attr"`
+	NextSequence  uint64 `xml:"nextSequence,attr"`
+}
+
+type mtcDeviceStream struct {
+	Name       string               `xml:"name,attr"`
+	UUID       string               `xml:"uuid,attr"`
+	Components []mtcComponentStream `xml:"ComponentStream"`
+}
+
+type mtcComponentStream struct {
+	Component string   `xml:"component,attr"`
+	Name      string   `xml:"name,attr"`
+	Samples   mtcItems `xml:"Samples"`
+	Events    mtcItems `xml:"Events"`
+	Condition mtcItems `xml:"Condition"`
+}
+
+type mtcItems struct {
+	Items []mtcObservation `xml:",any"`
+}
+
+type mtcObservation struct {
+	XMLName    xml.Name
+	DataItemID string `xml:"dataItemId,attr"`
+	Name       string `xml:"name,attr"`
+	Timestamp  string `xml:"timestamp,attr"`
+	Sequence   uint64 `xml:"sequence,attr"`
+	SubType    string `xml:"subType,attr"`
+	Type       string `xml:"type,attr"`
+	NativeCode string `xml:"nativeCode,attr"`
+	Value      string `xml:",chardata"`
+}
+
+func parseMTConnectStreams(data []byte) (*mtcStreams, error) {
+	var streams mtcStreams
+	if err := xml.Unmarshal(data, &streams); err != nil {
+		return nil, err
+	}
+	return &streams, nil
+}
+
+// mtcMachineState は差分で届く /sample を積み上げた機械の最新状態
+type mtcMachineState struct {
+	availability  string
+	execution     string
+	mode          string
+	emergencyStop string
+	program       string
+	partCount     *int
+	spindleLoad   *float64
+	conditions    map[string]domain.ObservedAlarm
+	lastObserved  time.Time
+}
+
+func newMTConnectMachineState() *mtcMachineState {
+	return &mtcMachineState{conditions: make(map[string]domain.ObservedAlarm)}
+}
+
+// apply はデバイスストリームを状態に反映し、時系列データ点を返す
+func (s *mtcMachineState) apply(machineID domain.MachineID, device mtcDeviceStream) []domain.TelemetryPoint {
+	var points []domain.TelemetryPoint
+
+	for _, component := range device.Components {
+		for _, item := range component.Samples.Items {
+			points = append(points, s.observe(machineID, component, item, domain.TelemetrySample))
+		}
+		for _, item := range component.Events.Items {
+			points = append(points, s.observe(machineID, component, item, domain.TelemetryEvent))
+		}
+		for _, item := range component.Condition.Items {
+			points = append(points, s.observe(machineID, component, item, domain.TelemetryCondition))
+		}
+	}
+
+	return points
+}
+
+func (s *mtcMachineState) observe(machineID domain.MachineID, component mtcComponentStream, item mtcObservation, category domain.TelemetryCategory) domain.TelemetryPoint {
+	value := strings.TrimSpace(item.Value)
+	element := item.XMLName.Local
+
+	switch category {
+	case domain.TelemetryCondition:
+		key := item.DataItemID
+		switch element {
+		case "Fault":
+			s.conditions[key] = mtcAlarm(item, value, domain.AlarmFault)
+		case "Warning":
+			s.conditions[key] = mtcAlarm(item, value, domain.AlarmWarning)
+		default:
+			delete(s.conditions, key)
+		}
+		value = element
+	default:
+		switch element {
+		case "Availability":
+			s.availability = value
+		case "Execution":
+			s.execution = value
+		case "ControllerMode":
+			s.mode = value
+		case "EmergencyStop":
+			s.emergencyStop = value
+		case "Program":
+			s.program = value
+		case "PartCount":
+			if n, err := strconv.Atoi(value); err == nil {
+				s.partCount = &n
+			}
+		case "Load":
+			if strings.EqualFold(component.Component, "Rotary") {
+				if f, err := strconv.ParseFloat(value, 64); err == nil {
+					s.spindleLoad = &f
+				}
+			}
+		}
+	}
+
+	timestamp, err := time.Parse(time.RFC3339Nano, item.Timestamp)
+	if err != nil {
+		timestamp = time.Now()
+	}
+	if timestamp.After(s.lastObserved) {
+		s.lastObserved = timestamp
+	}
+
+	dataItem := item.DataItemID
+	if item.Name != "" {
+		dataItem = item.Name
+	}
+
+	point := domain.TelemetryPoint{
+		MachineID: machineID,
+		Source:    string(domain.ProtocolMTConnect),
+		DataItem:  dataItem,
+		Category:  category,
+		Value:     value,
+		Timestamp: timestamp,
+	}
+	if f, err := strconv.ParseFloat(value, 64); err == nil {
+		point.NumericValue = &f
+	}
+	return point
+}
+
+// runningState は MTConnect の Availability / Execution / ControllerMode /
+// Condition を MachineRunningState に変換する。判定できない場合は空文字を返す
+func (s *mtcMachineState) runningState() domain.MachineRunningState {
+	if s.availability == "UNAVAILABLE" {
+		return domain.StateOffline
+	}
+	if s.hasFault() || s.emergencyStop == "TRIGGERED" {
+		return domain.StateAlarm
+	}
+	switch s.mode {
+	case "MANUAL", "MANUAL_DATA_INPUT", "EDIT":
+		return domain.StateSetup
+	}
+	switch s.execution {
+	case "ACTIVE":
+		return domain.StateRunning
+	case "READY", "PROGRAM_COMPLETED":
+		return domain.StateIdle
+	case "STOPPED", "INTERRUPTED", "FEED_HOLD", "OPTIONAL_STOP", "PROGRAM_STOPPED", "WAIT":
+		return domain.StateStopped
+	}
+	return ""
+}
+
+func (s *mtcMachineState) observation(machineID domain.MachineID, points []domain.TelemetryPoint) domain.MachineObservation {
+	alarms := make([]domain.ObservedAlarm, 0, len(s.conditions))
+	for _, alarm := range s.conditions {
+		alarms = append(alarms, alarm)
+	}
+	sort.Slice(alarms, func(i, j int) bool {
+		return alarms[i].Key() < alarms[j].Key()
+	})
+
+	observedAt := s.lastObserved
+	if observedAt.IsZero() {
+		observedAt = time.Now()
+	}
+
+	return domain.MachineObservation{
+		MachineID:    machineID,
+		Source:       string(domain.ProtocolMTConnect),
+		RunningState: s.runningState(),
+		ProgramName:  s.program,
+		PartCount:    s.partCount,
+		SpindleLoad:  s.spindleLoad,
+		Alarms:       alarms,
+		ObservedAt:   observedAt,
+		Points:       points,
+	}
+}
+
+func (s *mtcMachineState) hasFault() bool {
+	for _, alarm := range s.conditions {
+		if alarm.Severity == domain.AlarmFault {
+			return true
+		}
+	}
+	return false
+}
+
+// mtcAlarm は Condition の Fault / Warning をアラームに変換する。
+// nativeCode が無い場合はアラーム文の先頭から番号を取り出し、本文が無い場合は Condition の type を使う
+func mtcAlarm(item mtcObservation, value string, severity domain.AlarmSeverity) domain.ObservedAlarm {
+	alarm := domain.ParseAlarmText(value, severity)
+	if item.NativeCode != "" {
+		alarm = domain.ObservedAlarm{Code: strings.ToUpper(item.NativeCode), Severity: severity, Message: value}
+	}
+	if alarm.Message == "" {
+		alarm.Message = item.Type
+	}
+	return alarm
+}
@@ -0,0 +1,160 @@
+package connector
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"goNexttask/internal/nc/domain"
+	"log"
+	"sort"
+	"time"
+
+	"github.com/gopcua/opcua"
+	"github.com/gopcua/opcua/id"
+	"github.com/gopcua/opcua/ua"
+)
+
+// データ変化が無い間もハートビートを送り、接続断を検知するための確認間隔
+const opcuaKeepAliveInterval = 10 * time.Second
+
+var errOPCUADisconnected = errors.New("opcua connection lost")
+
+// OPCUAAdapter は1台の機械について設定されたノードを購読し、
+// 状態を ObservationSink へ渡す。切断時はバックオフ付きで再接続する
+type OPCUAAdapter struct {
+	config  *domain.ConnectorConfig
+	sink    ObservationSink
+	backoff Backoff
+}
+
+func NewOPCUAAdapter(config *domain.ConnectorConfig, sink ObservationSink) *OPCUAAdapter {
+	return &OPCUAAdapter{
+		config:  config,
+		sink:    sink,
+		backoff: Backoff{Min: time.Second, Max: time.Minute},
+	}
+}
+
+func (a *OPCUAAdapter) Run(ctx context.Context) {
+	for ctx.Err() == nil {
+		err := a.session(ctx)
+		if ctx.Err() != nil {
+			return
+		}
+		wait := a.backoff.Next()
+		log.Printf("OPC UA session for machine %s ended: %v (retry in %s)", a.config.MachineID, err, wait)
+		if !sleep(ctx, wait) {
+			return
+		}
+	}
+}
+
+func (a *OPCUAAdapter) session(ctx context.Context) error {
+	// 再接続はアダプタ側のバックオフで行うため、クライアントの自動再接続は無効にする
+	client, err := opcua.NewClient(a.config.Endpoint,
+		opcua.SecurityMode(ua.MessageSecurityModeNone),
+		opcua.AuthAnonymous(),
+		opcua.AutoReconnect(false),
+		opcua.DialTimeout(10*time.Second),
+		opcua.RequestTimeout(10*time.Second),
+	)
+	if err != nil {
+		return err
+	}
+
+	if err := client.Connect(ctx); err != nil {
+		return err
+	}
+	defer func() {
+		closeCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		client.Close(closeCtx)
+	}()
+
+	// クライアントハンドルはロール一覧のインデックス
+	roles := make([]string, 0, len(a.config.NodeIDs))
+	for role := range a.config.NodeIDs {
+		roles = append(roles, role)
+	}
+	sort.Strings(roles)
+
+	requests := make([]*ua.MonitoredItemCreateRequest, len(roles))
+	for i, role := range roles {
+		nodeID, err := ua.ParseNodeID(a.config.NodeIDs[role])
+		if err != nil {
+			return fmt.Errorf("opcua node %s: %w", role, err)
+		}
+		requests[i] = opcua.NewMonitoredItemCreateRequestWithDefaults(nodeID, ua.AttributeIDValue, uint32(i))
+	}
+
+	notifyCh := make(chan *opcua.PublishNotificationData, 16)
+	sub, err := client.Subscribe(ctx, &opcua.SubscriptionParameters{Interval: a.config.PollInterval}, notifyCh)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		cancelCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		sub.Cancel(cancelCtx)
+	}()
+
+	res, err := sub.Monitor(ctx, ua.TimestampsToReturnBoth, requests...)
+	if err != nil {
+		return err
+	}
+	for i, result := range res.Results {
+		if result.StatusCode != ua.StatusOK {
+			return fmt.Errorf("opcua monitor %s (%s): %w", roles[i], a.config.NodeIDs[roles[i]], result.StatusCode)
+		}
+	}
+	a.backoff.Reset()
+
+	state := &opcuaMachineState{}
+	serverState := client.Node(ua.NewNumericNodeID(0, id.Server_ServerStatus_State))
+
+	ticker := time.NewTicker(opcuaKeepAliveInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+
+		case <-ticker.C:
+			if client.State() != opcua.Connected {
+				return errOPCUADisconnected
+			}
+			if _, err := serverState.Value(ctx); err != nil {
+				return err
+			}
+			if err := a.sink.IngestObservation(ctx, state.observation(a.config.MachineID, a.config.StateMapping, nil)); err != nil {
+				log.Printf("OPC UA ingest for machine %s failed: %v", a.config.MachineID, err)
+			}
+
+		case notification := <-notifyCh:
+			if notification.Error != nil {
+				return notification.Error
+			}
+			change, ok := notification.Value.(*ua.DataChangeNotification)
+			if !ok {
+				continue
+			}
+
+			var points []domain.TelemetryPoint
+			for _, item := range change.MonitoredItems {
+				if item.Value == nil || int(item.ClientHandle) >= len(roles) {
+					continue
+				}
+				if item.Value.Status != ua.StatusOK {
+					log.Printf("OPC UA node %s for machine %s: %v", roles[item.ClientHandle], a.config.MachineID, item.Value.Status)
+					continue
+				}
+				points = append(points, state.apply(a.config.MachineID, roles[item.ClientHandle], item.Value))
+			}
+
+			if err := a.sink.IngestObservation(ctx, state.observation(a.config.MachineID, a.config.StateMapping, points)); err != nil {
+				log.Printf("OPC UA ingest for machine %s failed: %v", a.config.MachineID, err)
+			}
+		}
+	}
+}
@@ -0,0 +1,174 @@
+package connector
+
+import (
+	"fmt"
+	"goNexttask/internal/nc/domain"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gopcua/opcua/ua"
+)
+
+// 状態ノードの値が StateMapping に無い場合に使う一般的な表記
+var defaultOPCUAStates = map[string]domain.MachineRunningState{
+	"ACTIVE":      domain.StateRunning,
+	"EXECUTING":   domain.StateRunning,
+	"READY":       domain.StateIdle,
+	"STOPPED":     domain.StateStopped,
+	"FEED_HOLD":   domain.StateStopped,
+	"INTERRUPTED": domain.StateStopped,
+	"MANUAL":      domain.StateSetup,
+	"ALARM":       domain.StateAlarm,
+	"FAULT":       domain.StateAlarm,
+	"UNAVAILABLE": domain.StateOffline,
+}
+
+// opcuaMachineState はサブスクリプションで届く値を積み上げた機械の最新状態
+type opcuaMachineState struct {
+	state        string
+	program      string
+	partCount    *int
+	spindleLoad  *float64
+	alarms       []domain.ObservedAlarm
+	lastObserved time.Time
+}
+
+// apply はノードの値を役割に応じて状態に反映し、時系列データ点を返す
+func (s *opcuaMachineState) apply(machineID domain.MachineID, role string, value *ua.DataValue) domain.TelemetryPoint {
+	var raw interface{}
+	if value.Value != nil {
+		raw = value.Value.Value()
+	}
+	text := formatOPCUAValue(raw)
+
+	category := domain.TelemetryEvent
+	switch role {
+	case domain.OPCUANodeState:
+		s.state = text
+	case domain.OPCUANodeProgram:
+		s.program = text
+	case domain.OPCUANodePartCount:
+		if n, err := strconv.Atoi(text); err == nil {
+			s.partCount = &n
+		}
+	case domain.OPCUANodeSpindleLoad:
+		category = domain.TelemetrySample
+		if f, err := strconv.ParseFloat(text, 64); err == nil {
+			s.spindleLoad = &f
+		}
+	case domain.OPCUANodeAlarm:
+		category = domain.TelemetryCondition
+		s.alarms = opcuaAlarms(raw)
+	}
+
+	timestamp := value.SourceTimestamp
+	if timestamp.IsZero() {
+		timestamp = value.ServerTimestamp
+	}
+	if timestamp.IsZero() {
+		timestamp = time.Now()
+	}
+	if timestamp.After(s.lastObserved) {
+		s.lastObserved = timestamp
+	}
+
+	point := domain.TelemetryPoint{
+		MachineID: machineID,
+		Source:    string(domain.ProtocolOPCUA),
+		DataItem:  role,
+		Category:  category,
+		Value:     text,
+		Timestamp: timestamp,
+	}
+	if f, err := strconv.ParseFloat(text, 64); err == nil {
+		point.NumericValue = &f
+	}
+	return point
+}
+
+// runningState は状態ノードの値を MachineRunningState に変換する。
+// アラームが発生している場合はオフライン以外をアラームとして扱う。判定できない場合は空文字を返す
+func (s *opcuaMachineState) runningState(mapping map[string]domain.MachineRunningState) domain.MachineRunningState {
+	var state domain.MachineRunningState
+	if mapped, ok := mapping[s.state]; ok {
+		state = mapped
+	} else if mapped, ok := defaultOPCUAStates[strings.ToUpper(s.state)]; ok {
+		state = mapped
+	} else if candidate := domain.MachineRunningState(strings.ToLower(s.state)); candidate.IsValid() {
+		state = candidate
+	}
+
+	if len(s.alarms) > 0 && state != domain.StateOffline {
+		return domain.StateAlarm
+	}
+	return state
+}
+
+func (s *opcuaMachineState) observation(machineID domain.MachineID, mapping map[string]domain.MachineRunningState, points []domain.TelemetryPoint) domain.MachineObservation {
+	observedAt := s.lastObserved
+	if observedAt.IsZero() {
+		observedAt = time.Now()
+	}
+
+	return domain.MachineObservation{
+		MachineID:    machineID,
+		Source:       string(domain.ProtocolOPCUA),
+		RunningState: s.runningState(mapping),
+		ProgramName:  s.program,
+		PartCount:    s.partCount,
+		SpindleLoad:  s.spindleLoad,
+		Alarms:       append([]domain.ObservedAlarm(nil), s.alarms...),
+		ObservedAt:   observedAt,
+		Points:       points,
+	}
+}
+
+func formatOPCUAValue(v interface{}) string {
+	switch x := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return strings.TrimSpace(x)
+	case []string:
+		return strings.Join(x, "; ")
+	case *ua.LocalizedText:
+		if x == nil {
+			return ""
+		}
+		return strings.TrimSpace(x.Text)
+	case time.Time:
+		return x.Format(time.RFC3339Nano)
+	default:
+		return fmt.Sprint(x)
+	}
+}
+
+// opcuaAlarms はアラームノードの値を発生中アラームの一覧に変換する。
+// 文字列（配列）はアラーム文、真偽値・数値は発生フラグ／アラーム番号として扱う
+func opcuaAlarms(v interface{}) []domain.ObservedAlarm {
+	var alarms []domain.ObservedAlarm
+	switch x := v.(type) {
+	case nil:
+	case bool:
+		if x {
+			alarms = append(alarms, domain.ObservedAlarm{Severity: domain.AlarmFault, Message: "ALARM"})
+		}
+	case []string:
+		for _, text := range x {
+			if text = strings.TrimSpace(text); text != "" {
+				alarms = append(alarms, domain.ParseAlarmText(text, domain.AlarmFault))
+			}
+		}
+	default:
+		text := formatOPCUAValue(v)
+		if f, err := strconv.ParseFloat(text, 64); err == nil {
+			if f != 0 {
+				alarms = append(alarms, domain.ObservedAlarm{Code: text, Severity: domain.AlarmFault})
+			}
+		} else if text != "" {
+			alarms = append(alarms, domain.ParseAlarmText(text, domain.AlarmFault))
+		}
+	}
+	return alarms
+}
@@ -0,0 +1,29 @@
+package infrastructure
+
+import (
+	"context"
+	"fmt"
+	"goNexttask/internal/nc/domain"
+	"log"
+	"strings"
+)
+
+// LogOffsetWriter はオフセットの書き込みをログに記録するだけの暫定実装。
+// コントローラーへの書き込み（FOCAS、OPC UA の Write 等）を導入するまで使用する
+type LogOffsetWriter struct{}
+
+func NewLogOffsetWriter() *LogOffsetWriter {
+	return &LogOffsetWriter{}
+}
+
+func (w *LogOffsetWriter) WriteOffset(ctx context.Context, machine *domain.Machine, offset *domain.MachineOffset) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	values := make([]string, 0, len(offset.Values))
+	for _, field := range offset.Values.Fields() {
+		values = append(values, fmt.Sprintf("%s=%.4f", field, offset.Values[field]))
+	}
+	log.Printf("write offset machine=%s ip=%s register=%s version=%d %s", machine.ID, machine.IP, offset.Register, offset.Version, strings.Join(values, " "))
+	return nil
+}
@@ -0,0 +1,23 @@
+package infrastructure
+
+import (
+	"context"
+	"goNexttask/internal/nc/domain"
+	"log"
+)
+
+// LogProgramTransferer はプログラムの送信をログに記録するだけの暫定実装。
+// DNC/FTP による実機への送信を導入するまで使用する
+type LogProgramTransferer struct{}
+
+func NewLogProgramTransferer() *LogProgramTransferer {
+	return &LogProgramTransferer{}
+}
+
+func (t *LogProgramTransferer) Transfer(ctx context.Context, machine *domain.Machine, program *domain.NCProgram) (int64, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	log.Printf("transfer program=%s hash=%s machine=%s ip=%s bytes=%d", program.ID, program.FileHash, machine.ID, machine.IP, len(program.Content))
+	return int64(len(program.Content)), nil
+}
@@ -0,0 +1,195 @@
+package infrastructure
+
+import (
+	"context"
+	"database/sql"
+	"goNexttask/internal/nc/domain"
+)
+
+const (
+	machineAlarmColumns = `id, machine_id, code, severity, message, source, job_id, raised_at, cleared_at`
+	alarmCatalogColumns = `controller_model, code, description, recommended_action, updated_at`
+)
+
+type PostgresMachineAlarmRepository struct {
+	db *sql.DB
+}
+
+func NewPostgresMachineAlarmRepository(db *sql.DB) *PostgresMachineAlarmRepository {
+	return &PostgresMachineAlarmRepository{
+		db: db,
+	}
+}
+
+func (r *PostgresMachineAlarmRepository) Save(ctx context.Context, alarm *domain.MachineAlarm) error {
+	query := `INSERT INTO machine_alarms (` + machineAlarmColumns + `) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)`
+
+	_, err := r.db.ExecContext(ctx, query,
+		alarm.ID,
+		alarm.MachineID,
+		alarm.Code,
+		alarm.Severity,
+		alarm.Message,
+		alarm.Source,
+		sql.NullString{String: string(alarm.JobID), Valid: alarm.JobID != ""},
+		alarm.RaisedAt,
+		alarm.ClearedAt,
+	)
+
+	return err
+}
+
+func (r *PostgresMachineAlarmRepository) Update(ctx context.Context, alarm *domain.MachineAlarm) error {
+	query := `UPDATE machine_alarms SET cleared_at = $2 WHERE id = $1`
+
+	_, err := r.db.ExecContext(ctx, query, alarm.ID, alarm.ClearedAt)
+
+	return err
+}
+
+func (r *PostgresMachineAlarmRepository) FindActiveByMachineID(ctx context.Context, machineID domain.MachineID) ([]*domain.MachineAlarm, error) {
+	query := `
+		SELECT ` + machineAlarmColumns + `
+		FROM machine_alarms
+		WHERE machine_id = $1 AND cleared_at IS NULL
+		ORDER BY raised_at
+	`
+
+	return r.queryAlarms(ctx, query, machineID)
+}
+
+func (r *PostgresMachineAlarmRepository) FindByMachineID(ctx context.Context, machineID domain.MachineID, filter domain.AlarmFilter) ([]*domain.MachineAlarm, error) {
+	query := `
+		SELECT ` + machineAlarmColumns + `
+		FROM machine_alarms
+		WHERE machine_id = $1
+		  AND raised_at < $3 AND (cleared_at IS NULL OR cleared_at >= $2)
+		  AND ($4 = '' OR code = $4)
+		  AND ($5 = '' OR severity = $5)
+		  AND (NOT $6 OR cleared_at IS NULL)
+		ORDER BY raised_at DESC
+	`
+
+	return r.queryAlarms(ctx, query, machineID, filter.From, filter.To, filter.Code, filter.Severity, filter.ActiveOnly)
+}
+
+func (r *PostgresMachineAlarmRepository) queryAlarms(ctx context.Context, query string, args ...interface{}) ([]*domain.MachineAlarm, error) {
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var alarms []*domain.MachineAlarm
+
+	for rows.Next() {
+		var alarm domain.MachineAlarm
+		var jobID sql.NullString
+		var clearedAt sql.NullTime
+
+		err := rows.Scan(
+			&alarm.ID,
+			&alarm.MachineID,
+			&alarm.Code,
+			&alarm.Severity,
+			&alarm.Message,
+			&alarm.Source,
+			&jobID,
+			&alarm.RaisedAt,
+			&clearedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		alarm.JobID = domain.NCJobID(jobID.String)
+		if clearedAt.Valid {
+			alarm.ClearedAt = &clearedAt.Time
+		}
+		alarms = append(alarms, &alarm)
+	}
+
+	return alarms, rows.Err()
+}
+
+type PostgresAlarmCatalogRepository struct {
+	db *sql.DB
+}
+
+func NewPostgresAlarmCatalogRepository(db *sql.DB) *PostgresAlarmCatalogRepository {
+	return &PostgresAlarmCatalogRepository{
+		db: db,
+	}
+}
+
+func (r *PostgresAlarmCatalogRepository) Save(ctx context.Context, entry *domain.AlarmCatalogEntry) error {
+	query := `
+		INSERT INTO alarm_catalog (` + alarmCatalogColumns + `)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (controller_model, code) DO UPDATE
+		SET description = EXCLUDED.description, recommended_action = EXCLUDED.recommended_action,
+			updated_at = EXCLUDED.updated_at
+	`
+
+	_, err := r.db.ExecContext(ctx, query,
+		entry.ControllerModel,
+		entry.Code,
+		entry.Description,
+		entry.RecommendedAction,
+		entry.UpdatedAt,
+	)
+
+	return err
+}
+
+func (r *PostgresAlarmCatalogRepository) Delete(ctx context.Context, controllerModel, code string) error {
+	query := `DELETE FROM alarm_catalog WHERE controller_model = $1 AND code = $2`
+
+	result, err := r.db.ExecContext(ctx, query, controllerModel, code)
+	if err != nil {
+		return err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return domain.ErrAlarmCatalogEntryNotFound
+	}
+
+	return nil
+}
+
+func (r *PostgresAlarmCatalogRepository) FindByControllerModel(ctx context.Context, controllerModel string) (map[string]*domain.AlarmCatalogEntry, error) {
+	query := `SELECT ` + alarmCatalogColumns + ` FROM alarm_catalog WHERE controller_model = $1`
+
+	rows, err := r.db.QueryContext(ctx, query, controllerModel)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	entries := make(map[string]*domain.AlarmCatalogEntry)
+
+	for rows.Next() {
+		var entry domain.AlarmCatalogEntry
+		var recommendedAction sql.NullString
+
+		err := rows.Scan(
+			&entry.ControllerModel,
+			&entry.Code,
+			&entry.Description,
+			&recommendedAction,
+			&entry.UpdatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		entry.RecommendedAction = recommendedAction.String
+		entries[entry.Code] = &entry
+	}
+
+	return entries, rows.Err()
+}
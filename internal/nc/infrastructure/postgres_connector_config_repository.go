@@ -0,0 +1,143 @@
+package infrastructure
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"goNexttask/internal/nc/domain"
+	"time"
+)
+
+type PostgresConnectorConfigRepository struct {
+	db *sql.DB
+}
+
+func NewPostgresConnectorConfigRepository(db *sql.DB) *PostgresConnectorConfigRepository {
+	return &PostgresConnectorConfigRepository{
+		db: db,
+	}
+}
+
+func (r *PostgresConnectorConfigRepository) Save(ctx context.Context, config *domain.ConnectorConfig) error {
+	nodeIDsJSON, err := json.Marshal(config.NodeIDs)
+	if err != nil {
+		return err
+	}
+
+	stateMappingJSON, err := json.Marshal(config.StateMapping)
+	if err != nil {
+		return err
+	}
+
+	query := `
+		INSERT INTO machine_connectors (
+			machine_id, protocol, endpoint, device_name, poll_interval_ms,
+			node_ids, state_mapping, enabled, updated_at
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		ON CONFLICT (machine_id) DO UPDATE
+		SET protocol = EXCLUDED.protocol, endpoint = EXCLUDED.endpoint,
+			device_name = EXCLUDED.device_name, poll_interval_ms = EXCLUDED.poll_interval_ms,
+			node_ids = EXCLUDED.node_ids, state_mapping = EXCLUDED.state_mapping,
+			enabled = EXCLUDED.enabled, updated_at = EXCLUDED.updated_at
+	`
+
+	_, err = r.db.ExecContext(ctx, query,
+		config.MachineID,
+		config.Protocol,
+		config.Endpoint,
+		config.DeviceName,
+		config.PollInterval.Milliseconds(),
+		nodeIDsJSON,
+		stateMappingJSON,
+		config.Enabled,
+		config.UpdatedAt,
+	)
+
+	return err
+}
+
+func (r *PostgresConnectorConfigRepository) FindByMachineID(ctx context.Context, id domain.MachineID) (*domain.ConnectorConfig, error) {
+	query := `
+		SELECT machine_id, protocol, endpoint, device_name, poll_interval_ms,
+			node_ids, state_mapping, enabled, updated_at
+		FROM machine_connectors
+		WHERE machine_id = $1
+	`
+
+	config, err := scanConnectorConfig(r.db.QueryRowContext(ctx, query, id))
+	if err == sql.ErrNoRows {
+		return nil, domain.ErrConnectorConfigNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return config, nil
+}
+
+func (r *PostgresConnectorConfigRepository) FindEnabledByProtocol(ctx context.Context, protocol domain.ConnectorProtocol) ([]*domain.ConnectorConfig, error) {
+	query := `
+		SELECT c.machine_id, c.protocol, c.endpoint, c.device_name, c.poll_interval_ms,
+			c.node_ids, c.state_mapping, c.enabled, c.updated_at
+		FROM machine_connectors c
+		JOIN machines m ON m.id = c.machine_id
+		WHERE c.protocol = $1 AND c.enabled AND m.decommissioned_at IS NULL
+		ORDER BY c.machine_id
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, protocol)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var configs []*domain.ConnectorConfig
+
+	for rows.Next() {
+		config, err := scanConnectorConfig(rows)
+		if err != nil {
+			return nil, err
+		}
+		configs = append(configs, config)
+	}
+
+	return configs, rows.Err()
+}
+
+func scanConnectorConfig(row rowScanner) (*domain.ConnectorConfig, error) {
+	var config domain.ConnectorConfig
+	var deviceName sql.NullString
+	var pollIntervalMs int64
+	var nodeIDsJSON, stateMappingJSON []byte
+
+	err := row.Scan(
+		&config.MachineID,
+		&config.Protocol,
+		&config.Endpoint,
+		&deviceName,
+		&pollIntervalMs,
+		&nodeIDsJSON,
+		&stateMappingJSON,
+		&config.Enabled,
+		&config.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(nodeIDsJSON) > 0 {
+		if err := json.Unmarshal(nodeIDsJSON, &config.NodeIDs); err != nil {
+			return nil, err
+		}
+	}
+	if len(stateMappingJSON) > 0 {
+		if err := json.Unmarshal(stateMappingJSON, &config.StateMapping); err != nil {
+			return nil, err
+		}
+	}
+
+	config.DeviceName = deviceName.String
+	config.PollInterval = time.Duration(pollIntervalMs) * time.Millisecond
+
+	return &config, nil
+}
@@ -0,0 +1,259 @@
+package infrastructure
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"goNexttask/internal/nc/domain"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+const deploymentColumns = `id, idempotency_key, machine_id, program_id, file_hash, production_order_id,
+	job_id, status, attempts, bytes_sent, error_message, created_at, completed_at, updated_at`
+
+type PostgresDeploymentRepository struct {
+	db *sql.DB
+}
+
+func NewPostgresDeploymentRepository(db *sql.DB) *PostgresDeploymentRepository {
+	return &PostgresDeploymentRepository{
+		db: db,
+	}
+}
+
+// transferAttemptRecord は attempts 列(JSONB)に保存する送信記録
+type transferAttemptRecord struct {
+	Number     int       `json:"number"`
+	StartedAt  time.Time `json:"startedAt"`
+	DurationMs int64     `json:"durationMs"`
+	BytesSent  int64     `json:"bytesSent"`
+	Error      string    `json:"error,omitempty"`
+}
+
+func (r *PostgresDeploymentRepository) Save(ctx context.Context, deployment *domain.ProgramDeployment) error {
+	attemptsJSON, err := marshalTransferAttempts(deployment.Attempts)
+	if err != nil {
+		return err
+	}
+
+	query := `
+		INSERT INTO nc_deployments (` + deploymentColumns + `)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14)
+	`
+
+	_, err = r.db.ExecContext(ctx, query,
+		deployment.ID,
+		deployment.IdempotencyKey,
+		deployment.MachineID,
+		deployment.ProgramID,
+		deployment.FileHash,
+		deployment.ProductionOrderID,
+		deployment.JobID,
+		deployment.Status,
+		attemptsJSON,
+		deployment.BytesSent(),
+		deployment.ErrorMessage,
+		deployment.CreatedAt,
+		deployment.CompletedAt,
+		deployment.UpdatedAt,
+	)
+
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code.Name() == "unique_violation" {
+		return domain.ErrDeploymentAlreadyExists
+	}
+
+	return err
+}
+
+func (r *PostgresDeploymentRepository) Update(ctx context.Context, deployment *domain.ProgramDeployment) error {
+	attemptsJSON, err := marshalTransferAttempts(deployment.Attempts)
+	if err != nil {
+		return err
+	}
+
+	query := `
+		UPDATE nc_deployments
+		SET job_id = $2, status = $3, attempts = $4, bytes_sent = $5, error_message = $6,
+			completed_at = $7, updated_at = $8
+		WHERE id = $1
+	`
+
+	result, err := r.db.ExecContext(ctx, query,
+		deployment.ID,
+		deployment.JobID,
+		deployment.Status,
+		attemptsJSON,
+		deployment.BytesSent(),
+		deployment.ErrorMessage,
+		deployment.CompletedAt,
+		deployment.UpdatedAt,
+	)
+	if err != nil {
+		return err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return domain.ErrDeploymentNotFound
+	}
+
+	return nil
+}
+
+func (r *PostgresDeploymentRepository) FindByID(ctx context.Context, id domain.DeploymentID) (*domain.ProgramDeployment, error) {
+	query := `SELECT ` + deploymentColumns + ` FROM nc_deployments WHERE id = $1`
+
+	return r.findOne(ctx, query, id)
+}
+
+func (r *PostgresDeploymentRepository) FindByIdempotencyKey(ctx context.Context, machineID domain.MachineID, key string) (*domain.ProgramDeployment, error) {
+	query := `SELECT ` + deploymentColumns + ` FROM nc_deployments WHERE machine_id = $1 AND idempotency_key = $2`
+
+	return r.findOne(ctx, query, machineID, key)
+}
+
+func (r *PostgresDeploymentRepository) FindLatestDeliveredByMachineID(ctx context.Context, machineID domain.MachineID) (*domain.ProgramDeployment, error) {
+	query := `
+		SELECT ` + deploymentColumns + `
+		FROM nc_deployments
+		WHERE machine_id = $1 AND status IN ('transferred', 'skipped')
+		ORDER BY completed_at DESC
+		LIMIT 1
+	`
+
+	return r.findOne(ctx, query, machineID)
+}
+
+func (r *PostgresDeploymentRepository) FindByMachineID(ctx context.Context, machineID domain.MachineID, from, to time.Time) ([]*domain.ProgramDeployment, error) {
+	query := `
+		SELECT ` + deploymentColumns + `
+		FROM nc_deployments
+		WHERE machine_id = $1 AND created_at >= $2 AND created_at < $3
+		ORDER BY created_at DESC
+	`
+
+	return r.queryDeployments(ctx, query, machineID, from, to)
+}
+
+func (r *PostgresDeploymentRepository) FindByStatus(ctx context.Context, statuses ...domain.DeploymentStatus) ([]*domain.ProgramDeployment, error) {
+	values := make([]string, len(statuses))
+	for i, status := range statuses {
+		values[i] = string(status)
+	}
+
+	query := `
+		SELECT ` + deploymentColumns + `
+		FROM nc_deployments
+		WHERE status = ANY($1)
+		ORDER BY created_at
+	`
+
+	return r.queryDeployments(ctx, query, pq.Array(values))
+}
+
+func (r *PostgresDeploymentRepository) queryDeployments(ctx context.Context, query string, args ...interface{}) ([]*domain.ProgramDeployment, error) {
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var deployments []*domain.ProgramDeployment
+
+	for rows.Next() {
+		deployment, err := scanDeployment(rows)
+		if err != nil {
+			return nil, err
+		}
+		deployments = append(deployments, deployment)
+	}
+
+	return deployments, rows.Err()
+}
+
+func (r *PostgresDeploymentRepository) findOne(ctx context.Context, query string, args ...interface{}) (*domain.ProgramDeployment, error) {
+	deployment, err := scanDeployment(r.db.QueryRowContext(ctx, query, args...))
+	if err == sql.ErrNoRows {
+		return nil, domain.ErrDeploymentNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return deployment, nil
+}
+
+func marshalTransferAttempts(attempts []domain.TransferAttempt) ([]byte, error) {
+	records := make([]transferAttemptRecord, len(attempts))
+	for i, attempt := range attempts {
+		records[i] = transferAttemptRecord{
+			Number:     attempt.Number,
+			StartedAt:  attempt.StartedAt,
+			DurationMs: attempt.Duration.Milliseconds(),
+			BytesSent:  attempt.BytesSent,
+			Error:      attempt.Error,
+		}
+	}
+	return json.Marshal(records)
+}
+
+func scanDeployment(row rowScanner) (*domain.ProgramDeployment, error) {
+	var deployment domain.ProgramDeployment
+	var fileHash, productionOrderID, jobID, errorMessage sql.NullString
+	var completedAt sql.NullTime
+	var bytesSent int64
+	var attemptsJSON []byte
+
+	err := row.Scan(
+		&deployment.ID,
+		&deployment.IdempotencyKey,
+		&deployment.MachineID,
+		&deployment.ProgramID,
+		&fileHash,
+		&productionOrderID,
+		&jobID,
+		&deployment.Status,
+		&attemptsJSON,
+		&bytesSent,
+		&errorMessage,
+		&deployment.CreatedAt,
+		&completedAt,
+		&deployment.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	deployment.FileHash = fileHash.String
+	deployment.ProductionOrderID = productionOrderID.String
+	deployment.JobID = domain.NCJobID(jobID.String)
+	deployment.ErrorMessage = errorMessage.String
+	if completedAt.Valid {
+		deployment.CompletedAt = &completedAt.Time
+	}
+
+	if len(attemptsJSON) > 0 {
+		var records []transferAttemptRecord
+		if err := json.Unmarshal(attemptsJSON, &records); err != nil {
+			return nil, err
+		}
+		for _, record := range records {
+			deployment.Attempts = append(deployment.Attempts, domain.TransferAttempt{
+				Number:    record.Number,
+				StartedAt: record.StartedAt,
+				Duration:  time.Duration(record.DurationMs) * time.Millisecond,
+				BytesSent: record.BytesSent,
+				Error:     record.Error,
+			})
+		}
+	}
+
+	return &deployment, nil
+}
@@ -0,0 +1,214 @@
+package infrastructure
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"goNexttask/internal/nc/domain"
+	"time"
+)
+
+const ncJobColumns = `id, program_id, program_hash, machine_id, production_order_id, status,
+	started_at, ended_at, produced_count, part_counter_start, alarms, error_message,
+	created_at, updated_at`
+
+type PostgresNCJobRepository struct {
+	db *sql.DB
+}
+
+func NewPostgresNCJobRepository(db *sql.DB) *PostgresNCJobRepository {
+	return &PostgresNCJobRepository{
+		db: db,
+	}
+}
+
+func (r *PostgresNCJobRepository) Save(ctx context.Context, job *domain.NCJob) error {
+	alarmsJSON, err := json.Marshal(job.Alarms)
+	if err != nil {
+		return err
+	}
+
+	query := `
+		INSERT INTO nc_jobs (` + ncJobColumns + `)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14)
+	`
+
+	_, err = r.db.ExecContext(ctx, query,
+		job.ID,
+		job.ProgramID,
+		job.ProgramHash,
+		job.MachineID,
+		job.ProductionOrderID,
+		job.Status,
+		job.StartedAt,
+		job.EndedAt,
+		job.ProducedCount,
+		job.PartCounterStart,
+		alarmsJSON,
+		job.ErrorMessage,
+		job.CreatedAt,
+		job.UpdatedAt,
+	)
+
+	return err
+}
+
+func (r *PostgresNCJobRepository) Update(ctx context.Context, job *domain.NCJob) error {
+	alarmsJSON, err := json.Marshal(job.Alarms)
+	if err != nil {
+		return err
+	}
+
+	query := `
+		UPDATE nc_jobs
+		SET status = $2, ended_at = $3, produced_count = $4, part_counter_start = $5,
+			alarms = $6, error_message = $7, updated_at = $8
+		WHERE id = $1
+	`
+
+	result, err := r.db.ExecContext(ctx, query,
+		job.ID,
+		job.Status,
+		job.EndedAt,
+		job.ProducedCount,
+		job.PartCounterStart,
+		alarmsJSON,
+		job.ErrorMessage,
+		job.UpdatedAt,
+	)
+	if err != nil {
+		return err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return domain.ErrNCJobNotFound
+	}
+
+	return nil
+}
+
+func (r *PostgresNCJobRepository) FindByID(ctx context.Context, id domain.NCJobID) (*domain.NCJob, error) {
+	query := `SELECT ` + ncJobColumns + ` FROM nc_jobs WHERE id = $1`
+
+	job, err := scanNCJob(r.db.QueryRowContext(ctx, query, id))
+	if err == sql.ErrNoRows {
+		return nil, domain.ErrNCJobNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return job, nil
+}
+
+func (r *PostgresNCJobRepository) FindActiveByMachineID(ctx context.Context, id domain.MachineID) (*domain.NCJob, error) {
+	query := `
+		SELECT ` + ncJobColumns + `
+		FROM nc_jobs
+		WHERE machine_id = $1 AND status = 'running'
+		ORDER BY started_at DESC
+		LIMIT 1
+	`
+
+	job, err := scanNCJob(r.db.QueryRowContext(ctx, query, id))
+	if err == sql.ErrNoRows {
+		return nil, domain.ErrNCJobNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return job, nil
+}
+
+func (r *PostgresNCJobRepository) FindByMachineID(ctx context.Context, id domain.MachineID, from, to time.Time) ([]*domain.NCJob, error) {
+	query := `
+		SELECT ` + ncJobColumns + `
+		FROM nc_jobs
+		WHERE machine_id = $1 AND started_at >= $2 AND started_at < $3
+		ORDER BY started_at DESC
+	`
+
+	return r.findMany(ctx, query, id, from, to)
+}
+
+func (r *PostgresNCJobRepository) FindByProductionOrderID(ctx context.Context, productionOrderID string) ([]*domain.NCJob, error) {
+	query := `
+		SELECT ` + ncJobColumns + `
+		FROM nc_jobs
+		WHERE production_order_id = $1
+		ORDER BY started_at DESC
+	`
+
+	return r.findMany(ctx, query, productionOrderID)
+}
+
+func (r *PostgresNCJobRepository) findMany(ctx context.Context, query string, args ...interface{}) ([]*domain.NCJob, error) {
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var jobs []*domain.NCJob
+
+	for rows.Next() {
+		job, err := scanNCJob(rows)
+		if err != nil {
+			return nil, err
+		}
+		jobs = append(jobs, job)
+	}
+
+	return jobs, rows.Err()
+}
+
+func scanNCJob(row rowScanner) (*domain.NCJob, error) {
+	var job domain.NCJob
+	var programHash, productionOrderID, errorMessage sql.NullString
+	var endedAt sql.NullTime
+	var partCounterStart sql.NullInt64
+	var alarmsJSON []byte
+
+	err := row.Scan(
+		&job.ID,
+		&job.ProgramID,
+		&programHash,
+		&job.MachineID,
+		&productionOrderID,
+		&job.Status,
+		&job.StartedAt,
+		&endedAt,
+		&job.ProducedCount,
+		&partCounterStart,
+		&alarmsJSON,
+		&errorMessage,
+		&job.CreatedAt,
+		&job.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	job.ProgramHash = programHash.String
+	job.ProductionOrderID = productionOrderID.String
+	job.ErrorMessage = errorMessage.String
+	if endedAt.Valid {
+		job.EndedAt = &endedAt.Time
+	}
+	if partCounterStart.Valid {
+		start := int(partCounterStart.Int64)
+		job.PartCounterStart = &start
+	}
+	if len(alarmsJSON) > 0 {
+		if err := json.Unmarshal(alarmsJSON, &job.Alarms); err != nil {
+			return nil, err
+		}
+	}
+
+	return &job, nil
+}
@@ -0,0 +1,341 @@
+package infrastructure
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"goNexttask/internal/nc/domain"
+)
+
+const (
+	machineOffsetColumns = `machine_id, register, kind, offset_values, version, change_id, updated_by, updated_at`
+	offsetChangeColumns  = `id, machine_id, register, kind, mode, offset_values, base_version, previous_values,
+	result_values, status, reason, proposed_by, proposed_at, reviewed_by, reviewed_at, review_comment,
+	applied_at, error_message`
+)
+
+type PostgresOffsetRepository struct {
+	db *sql.DB
+}
+
+func NewPostgresOffsetRepository(db *sql.DB) *PostgresOffsetRepository {
+	return &PostgresOffsetRepository{
+		db: db,
+	}
+}
+
+// execer は *sql.DB と *sql.Tx の共通部分
+type execer interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+}
+
+func (r *PostgresOffsetRepository) Find(ctx context.Context, machineID domain.MachineID, register domain.OffsetRegister) (*domain.MachineOffset, error) {
+	query := `SELECT ` + machineOffsetColumns + ` FROM machine_offsets WHERE machine_id = $1 AND register = $2`
+
+	offset, err := scanMachineOffset(r.db.QueryRowContext(ctx, query, machineID, register))
+	if err == sql.ErrNoRows {
+		return nil, domain.ErrOffsetNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return offset, nil
+}
+
+func (r *PostgresOffsetRepository) FindByMachineID(ctx context.Context, machineID domain.MachineID) ([]*domain.MachineOffset, error) {
+	query := `SELECT ` + machineOffsetColumns + ` FROM machine_offsets WHERE machine_id = $1 ORDER BY kind, register`
+
+	rows, err := r.db.QueryContext(ctx, query, machineID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var offsets []*domain.MachineOffset
+
+	for rows.Next() {
+		offset, err := scanMachineOffset(rows)
+		if err != nil {
+			return nil, err
+		}
+		offsets = append(offsets, offset)
+	}
+
+	return offsets, rows.Err()
+}
+
+func (r *PostgresOffsetRepository) SaveChange(ctx context.Context, change *domain.OffsetChange) error {
+	valuesJSON, previousJSON, resultJSON, err := marshalOffsetChangeValues(change)
+	if err != nil {
+		return err
+	}
+
+	query := `
+		INSERT INTO offset_changes (` + offsetChangeColumns + `)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18)
+	`
+
+	_, err = r.db.ExecContext(ctx, query,
+		change.ID,
+		change.MachineID,
+		change.Register,
+		change.Register.Kind(),
+		change.Mode,
+		valuesJSON,
+		change.BaseVersion,
+		previousJSON,
+		resultJSON,
+		change.Status,
+		change.Reason,
+		change.ProposedBy,
+		change.ProposedAt,
+		change.ReviewedBy,
+		change.ReviewedAt,
+		change.ReviewComment,
+		change.AppliedAt,
+		change.ErrorMessage,
+	)
+
+	return err
+}
+
+func (r *PostgresOffsetRepository) UpdateChange(ctx context.Context, change *domain.OffsetChange) error {
+	return updateOffsetChange(ctx, r.db, change)
+}
+
+func (r *PostgresOffsetRepository) FindChangeByID(ctx context.Context, id domain.OffsetChangeID) (*domain.OffsetChange, error) {
+	query := `SELECT ` + offsetChangeColumns + ` FROM offset_changes WHERE id = $1`
+
+	change, err := scanOffsetChange(r.db.QueryRowContext(ctx, query, id))
+	if err == sql.ErrNoRows {
+		return nil, domain.ErrOffsetChangeNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return change, nil
+}
+
+func (r *PostgresOffsetRepository) FindChanges(ctx context.Context, machineID domain.MachineID, register domain.OffsetRegister, status domain.OffsetChangeStatus) ([]*domain.OffsetChange, error) {
+	query := `
+		SELECT ` + offsetChangeColumns + `
+		FROM offset_changes
+		WHERE machine_id = $1 AND ($2 = '' OR register = $2) AND ($3 = '' OR status = $3)
+		ORDER BY proposed_at DESC
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, machineID, register, status)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var changes []*domain.OffsetChange
+
+	for rows.Next() {
+		change, err := scanOffsetChange(rows)
+		if err != nil {
+			return nil, err
+		}
+		changes = append(changes, change)
+	}
+
+	return changes, rows.Err()
+}
+
+func (r *PostgresOffsetRepository) Apply(ctx context.Context, change *domain.OffsetChange, offset *domain.MachineOffset) error {
+	valuesJSON, err := json.Marshal(offset.Values)
+	if err != nil {
+		return err
+	}
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// 版が offset.Version-1 の場合だけ更新する。初版は未登録の場合だけ登録する
+	query := `
+		INSERT INTO machine_offsets (` + machineOffsetColumns + `)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		ON CONFLICT (machine_id, register) DO UPDATE
+		SET offset_values = EXCLUDED.offset_values, version = EXCLUDED.version,
+			change_id = EXCLUDED.change_id, updated_by = EXCLUDED.updated_by, updated_at = EXCLUDED.updated_at
+		WHERE machine_offsets.version = EXCLUDED.version - 1
+	`
+
+	result, err := tx.ExecContext(ctx, query,
+		offset.MachineID,
+		offset.Register,
+		offset.Register.Kind(),
+		valuesJSON,
+		offset.Version,
+		offset.ChangeID,
+		offset.UpdatedBy,
+		offset.UpdatedAt,
+	)
+	if err != nil {
+		return err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return domain.ErrOffsetVersionConflict
+	}
+
+	if err := updateOffsetChange(ctx, tx, change); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func updateOffsetChange(ctx context.Context, db execer, change *domain.OffsetChange) error {
+	_, previousJSON, resultJSON, err := marshalOffsetChangeValues(change)
+	if err != nil {
+		return err
+	}
+
+	query := `
+		UPDATE offset_changes
+		SET base_version = $2, previous_values = $3, result_values = $4, status = $5, reviewed_by = $6,
+			reviewed_at = $7, review_comment = $8, applied_at = $9, error_message = $10
+		WHERE id = $1
+	`
+
+	result, err := db.ExecContext(ctx, query,
+		change.ID,
+		change.BaseVersion,
+		previousJSON,
+		resultJSON,
+		change.Status,
+		change.ReviewedBy,
+		change.ReviewedAt,
+		change.ReviewComment,
+		change.AppliedAt,
+		change.ErrorMessage,
+	)
+	if err != nil {
+		return err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return domain.ErrOffsetChangeNotFound
+	}
+
+	return nil
+}
+
+// marshalOffsetChangeValues は変更値と反映前後の値を JSONB 列用に変換する。反映前の値が未確定なら NULL
+func marshalOffsetChangeValues(change *domain.OffsetChange) (values, previous, result []byte, err error) {
+	if values, err = json.Marshal(change.Values); err != nil {
+		return nil, nil, nil, err
+	}
+	if change.Previous != nil {
+		if previous, err = json.Marshal(change.Previous); err != nil {
+			return nil, nil, nil, err
+		}
+	}
+	if change.Result != nil {
+		if result, err = json.Marshal(change.Result); err != nil {
+			return nil, nil, nil, err
+		}
+	}
+	return values, previous, result, nil
+}
+
+func scanMachineOffset(row rowScanner) (*domain.MachineOffset, error) {
+	var offset domain.MachineOffset
+	var kind string
+	var valuesJSON []byte
+	var changeID, updatedBy sql.NullString
+
+	err := row.Scan(
+		&offset.MachineID,
+		&offset.Register,
+		&kind,
+		&valuesJSON,
+		&offset.Version,
+		&changeID,
+		&updatedBy,
+		&offset.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal(valuesJSON, &offset.Values); err != nil {
+		return nil, err
+	}
+	offset.ChangeID = domain.OffsetChangeID(changeID.String)
+	offset.UpdatedBy = updatedBy.String
+
+	return &offset, nil
+}
+
+func scanOffsetChange(row rowScanner) (*domain.OffsetChange, error) {
+	var change domain.OffsetChange
+	var kind string
+	var valuesJSON, previousJSON, resultJSON []byte
+	var reviewedBy, reviewComment, errorMessage sql.NullString
+	var reviewedAt, appliedAt sql.NullTime
+
+	err := row.Scan(
+		&change.ID,
+		&change.MachineID,
+		&change.Register,
+		&kind,
+		&change.Mode,
+		&valuesJSON,
+		&change.BaseVersion,
+		&previousJSON,
+		&resultJSON,
+		&change.Status,
+		&change.Reason,
+		&change.ProposedBy,
+		&change.ProposedAt,
+		&reviewedBy,
+		&reviewedAt,
+		&reviewComment,
+		&appliedAt,
+		&errorMessage,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal(valuesJSON, &change.Values); err != nil {
+		return nil, err
+	}
+	if len(previousJSON) > 0 {
+		if err := json.Unmarshal(previousJSON, &change.Previous); err != nil {
+			return nil, err
+		}
+	}
+	if len(resultJSON) > 0 {
+		if err := json.Unmarshal(resultJSON, &change.Result); err != nil {
+			return nil, err
+		}
+	}
+	change.ReviewedBy = reviewedBy.String
+	change.ReviewComment = reviewComment.String
+	change.ErrorMessage = errorMessage.String
+	if reviewedAt.Valid {
+		change.ReviewedAt = &reviewedAt.Time
+	}
+	if appliedAt.Valid {
+		change.AppliedAt = &appliedAt.Time
+	}
+
+	return &change, nil
+}
@@ -0,0 +1,128 @@
+package infrastructure
+
+import (
+	"context"
+	"database/sql"
+	"goNexttask/internal/nc/domain"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+type PostgresProgramPerformanceRepository struct {
+	db *sql.DB
+}
+
+func NewPostgresProgramPerformanceRepository(db *sql.DB) *PostgresProgramPerformanceRepository {
+	return &PostgresProgramPerformanceRepository{
+		db: db,
+	}
+}
+
+// FindByProgramIDs は生産オーダー・機械を介してジョブと検査結果を突き合わせ、初回合格率と加工時間を集計する
+func (r *PostgresProgramPerformanceRepository) FindByProgramIDs(ctx context.Context, ids []domain.NCProgramID, machineType string) (map[domain.NCProgramID]domain.ProgramPerformance, error) {
+	programIDs := make([]string, len(ids))
+	for i, id := range ids {
+		programIDs[i] = string(id)
+	}
+
+	performances := make(map[domain.NCProgramID]domain.ProgramPerformance, len(ids))
+	get := func(id domain.NCProgramID) domain.ProgramPerformance {
+		p, ok := performances[id]
+		if !ok {
+			p.ProgramID = id
+		}
+		return p
+	}
+
+	// 初回合格率はロット・工程ごとの最初の検査のみ数える（result はシード、final_result は API 登録）。
+	// 検査は 1 つのジョブに帰属させる。検査時に記録したジョブがあればそのジョブ、無ければ同じ製造オーダー・機械で
+	// 検査より前に開始した最新のジョブ
+	yieldQuery := `
+		WITH first_inspections AS (
+			SELECT DISTINCT ON (i.lot_number, i.operation)
+				i.production_order_id,
+				COALESCE(i.machine_id, '') AS machine_id,
+				COALESCE(i.manufacturing_record->>'ncJobId', '') AS job_id,
+				COALESCE(i.inspection_date, i.created_at) AS inspected_at,
+				COALESCE(i.result, i.final_result) AS result
+			FROM inspections i
+			WHERE COALESCE(i.result, i.final_result) IS NOT NULL
+				AND (i.status IS NULL OR i.status = 'completed')
+				AND i.production_order_id <> ''
+			ORDER BY i.lot_number, i.operation, COALESCE(i.inspection_date, i.created_at), i.id
+		)
+		SELECT j.program_id,
+			COUNT(*),
+			COUNT(*) FILTER (WHERE f.result = 'pass')
+		FROM first_inspections f
+		CROSS JOIN LATERAL (
+			SELECT nc_jobs.program_id
+			FROM nc_jobs
+			WHERE (f.job_id <> '' AND nc_jobs.id = f.job_id)
+				OR (f.job_id = ''
+					AND nc_jobs.production_order_id = f.production_order_id
+					AND (f.machine_id = '' OR nc_jobs.machine_id = f.machine_id)
+					AND nc_jobs.started_at <= f.inspected_at)
+			ORDER BY nc_jobs.started_at DESC
+			LIMIT 1
+		) j
+		WHERE j.program_id = ANY($1)
+		GROUP BY j.program_id
+	`
+
+	rows, err := r.db.QueryContext(ctx, yieldQuery, pq.Array(programIDs))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var id domain.NCProgramID
+		var inspections, passed int
+		if err := rows.Scan(&id, &inspections, &passed); err != nil {
+			return nil, err
+		}
+		p := get(id)
+		p.InspectionCount = inspections
+		p.PassedCount = passed
+		performances[id] = p
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	cycleQuery := `
+		SELECT j.program_id,
+			COUNT(*),
+			AVG(EXTRACT(EPOCH FROM (j.ended_at - j.started_at)) / j.produced_count)
+		FROM nc_jobs j
+		JOIN machines m ON m.id = j.machine_id
+		WHERE j.program_id = ANY($1)
+			AND m.machine_type = $2
+			AND j.status = 'completed'
+			AND j.produced_count > 0
+		GROUP BY j.program_id
+	`
+
+	cycleRows, err := r.db.QueryContext(ctx, cycleQuery, pq.Array(programIDs), machineType)
+	if err != nil {
+		return nil, err
+	}
+	defer cycleRows.Close()
+
+	for cycleRows.Next() {
+		var id domain.NCProgramID
+		var jobs int
+		var seconds float64
+		if err := cycleRows.Scan(&id, &jobs, &seconds); err != nil {
+			return nil, err
+		}
+		p := get(id)
+		p.CompletedJobs = jobs
+		p.AvgCycleTime = time.Duration(seconds * float64(time.Second))
+		performances[id] = p
+	}
+
+	return performances, cycleRows.Err()
+}
@@ -3,9 +3,13 @@ package infrastructure
 import (
 	"context"
 	"database/sql"
+	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"goNexttask/internal/nc/domain"
 	"time"
+
+	"github.com/lib/pq"
 )
 
 type PostgresNCProgramRepository struct {
@@ -18,52 +22,97 @@ func NewPostgresNCProgramRepository(db *sql.DB) *PostgresNCProgramRepository {
 	}
 }
 
+const ncProgramColumns = `
+	id, name, version, part_id, dialect, source_program_id, file_hash, signature, signer_key_id, signed_by, signed_at,
+	data, machine_compatibility, required_capabilities,
+	status, approved_by, approved_at, created_by, created_at, updated_at
+`
+
 func (r *PostgresNCProgramRepository) Save(ctx context.Context, program *domain.NCProgram) error {
 	compatibilityJSON, err := json.Marshal(program.MachineCompatibility)
 	if err != nil {
 		return err
 	}
 
+	capabilitiesJSON, err := json.Marshal(program.RequiredCapabilities)
+	if err != nil {
+		return err
+	}
+
 	query := `
-		INSERT INTO nc_programs (id, name, version, file_hash, machine_compatibility, created_by, created_at, updated_at)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		INSERT INTO nc_programs (` + ncProgramColumns + `)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19, $20)
 	`
 
 	_, err = r.db.ExecContext(ctx, query,
 		program.ID,
 		program.Name,
 		program.Version,
+		program.PartID,
+		program.Dialect,
+		program.SourceProgramID,
 		program.FileHash,
+		encodeSignature(program.Signature),
+		program.SignerKeyID,
+		program.SignedBy,
+		program.SignedAt,
+		string(program.Content),
 		string(compatibilityJSON),
+		string(capabilitiesJSON),
+		program.Status,
+		program.ApprovedBy,
+		program.ApprovedAt,
 		program.CreatedBy,
 		program.CreatedAt,
 		program.UpdatedAt,
 	)
 
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code.Name() == "unique_violation" {
+		return domain.ErrProgramAlreadyExists
+	}
+
 	return err
 }
 
-func (r *PostgresNCProgramRepository) FindByID(ctx context.Context, id domain.NCProgramID) (*domain.NCProgram, error) {
+func (r *PostgresNCProgramRepository) Update(ctx context.Context, program *domain.NCProgram) error {
 	query := `
-		SELECT id, name, version, file_hash, machine_compatibility, created_by, created_at, updated_at
-		FROM nc_programs
+		UPDATE nc_programs
+		SET status = $2, approved_by = $3, approved_at = $4,
+			signature = $5, signer_key_id = $6, signed_by = $7, signed_at = $8, updated_at = $9
 		WHERE id = $1
 	`
 
-	var program domain.NCProgram
-	var compatibilityJSON string
-
-	err := r.db.QueryRowContext(ctx, query, id).Scan(
-		&program.ID,
-		&program.Name,
-		&program.Version,
-		&program.FileHash,
-		&compatibilityJSON,
-		&program.CreatedBy,
-		&program.CreatedAt,
-		&program.UpdatedAt,
+	result, err := r.db.ExecContext(ctx, query,
+		program.ID,
+		program.Status,
+		program.ApprovedBy,
+		program.ApprovedAt,
+		encodeSignature(program.Signature),
+		program.SignerKeyID,
+		program.SignedBy,
+		program.SignedAt,
+		program.UpdatedAt,
 	)
+	if err != nil {
+		return err
+	}
 
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return domain.ErrNCProgramNotFound
+	}
+
+	return nil
+}
+
+func (r *PostgresNCProgramRepository) FindByID(ctx context.Context, id domain.NCProgramID) (*domain.NCProgram, error) {
+	query := `SELECT ` + ncProgramColumns + ` FROM nc_programs WHERE id = $1`
+
+	program, err := scanNCProgram(r.db.QueryRowContext(ctx, query, id))
 	if err == sql.ErrNoRows {
 		return nil, domain.ErrNCProgramNotFound
 	}
@@ -71,34 +120,13 @@ func (r *PostgresNCProgramRepository) FindByID(ctx context.Context, id domain.NC
 		return nil, err
 	}
 
-	if err := json.Unmarshal([]byte(compatibilityJSON), &program.MachineCompatibility); err != nil {
-		return nil, err
-	}
-
-	return &program, nil
+	return program, nil
 }
 
 func (r *PostgresNCProgramRepository) FindByNameAndVersion(ctx context.Context, name, version string) (*domain.NCProgram, error) {
-	query := `
-		SELECT id, name, version, file_hash, machine_compatibility, created_by, created_at, updated_at
-		FROM nc_programs
-		WHERE name = $1 AND version = $2
-	`
-
-	var program domain.NCProgram
-	var compatibilityJSON string
-
-	err := r.db.QueryRowContext(ctx, query, name, version).Scan(
-		&program.ID,
-		&program.Name,
-		&program.Version,
-		&program.FileHash,
-		&compatibilityJSON,
-		&program.CreatedBy,
-		&program.CreatedAt,
-		&program.UpdatedAt,
-	)
+	query := `SELECT ` + ncProgramColumns + ` FROM nc_programs WHERE name = $1 AND version = $2`
 
+	program, err := scanNCProgram(r.db.QueryRowContext(ctx, query, name, version))
 	if err == sql.ErrNoRows {
 		return nil, domain.ErrNCProgramNotFound
 	}
@@ -106,21 +134,23 @@ func (r *PostgresNCProgramRepository) FindByNameAndVersion(ctx context.Context,
 		return nil, err
 	}
 
-	if err := json.Unmarshal([]byte(compatibilityJSON), &program.MachineCompatibility); err != nil {
-		return nil, err
-	}
-
-	return &program, nil
+	return program, nil
 }
 
 func (r *PostgresNCProgramRepository) FindAll(ctx context.Context) ([]*domain.NCProgram, error) {
-	query := `
-		SELECT id, name, version, file_hash, machine_compatibility, created_by, created_at, updated_at
-		FROM nc_programs
-		ORDER BY created_at DESC
-	`
+	query := `SELECT ` + ncProgramColumns + ` FROM nc_programs ORDER BY created_at DESC`
+
+	return r.queryNCPrograms(ctx, query)
+}
 
-	rows, err := r.db.QueryContext(ctx, query)
+func (r *PostgresNCProgramRepository) FindByPartID(ctx context.Context, partID string) ([]*domain.NCProgram, error) {
+	query := `SELECT ` + ncProgramColumns + ` FROM nc_programs WHERE part_id = $1 ORDER BY created_at DESC`
+
+	return r.queryNCPrograms(ctx, query, partID)
+}
+
+func (r *PostgresNCProgramRepository) queryNCPrograms(ctx context.Context, query string, args ...interface{}) ([]*domain.NCProgram, error) {
+	rows, err := r.db.QueryContext(ctx, query, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -129,31 +159,97 @@ func (r *PostgresNCProgramRepository) FindAll(ctx context.Context) ([]*domain.NC
 	var programs []*domain.NCProgram
 
 	for rows.Next() {
-		var program domain.NCProgram
-		var compatibilityJSON string
-
-		err := rows.Scan(
-			&program.ID,
-			&program.Name,
-			&program.Version,
-			&program.FileHash,
-			&compatibilityJSON,
-			&program.CreatedBy,
-			&program.CreatedAt,
-			&program.UpdatedAt,
-		)
+		program, err := scanNCProgram(rows)
 		if err != nil {
 			return nil, err
 		}
+		programs = append(programs, program)
+	}
 
-		if err := json.Unmarshal([]byte(compatibilityJSON), &program.MachineCompatibility); err != nil {
+	return programs, rows.Err()
+}
+
+// scanNCProgram はシードデータ由来の NULL 列（name, file_hash 等）を空値として読み込む
+func scanNCProgram(row rowScanner) (*domain.NCProgram, error) {
+	var program domain.NCProgram
+	var name, partID, dialect, sourceProgramID, fileHash, content, compatibilityJSON, capabilitiesJSON sql.NullString
+	var signature, signerKeyID, signedBy sql.NullString
+	var approvedBy, createdBy sql.NullString
+	var approvedAt, signedAt, createdAt, updatedAt sql.NullTime
+
+	err := row.Scan(
+		&program.ID,
+		&name,
+		&program.Version,
+		&partID,
+		&dialect,
+		&sourceProgramID,
+		&fileHash,
+		&signature,
+		&signerKeyID,
+		&signedBy,
+		&signedAt,
+		&content,
+		&compatibilityJSON,
+		&capabilitiesJSON,
+		&program.Status,
+		&approvedBy,
+		&approvedAt,
+		&createdBy,
+		&createdAt,
+		&updatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	program.Name = name.String
+	program.PartID = partID.String
+	program.Dialect = domain.DialectFanuc
+	if dialect.Valid && dialect.String != "" {
+		program.Dialect = domain.Dialect(dialect.String)
+	}
+	program.SourceProgramID = domain.NCProgramID(sourceProgramID.String)
+	program.FileHash = fileHash.String
+	program.SignerKeyID = domain.SignerKeyID(signerKeyID.String)
+	program.SignedBy = signedBy.String
+	if signedAt.Valid {
+		program.SignedAt = &signedAt.Time
+	}
+	if signature.Valid && signature.String != "" {
+		if program.Signature, err = base64.StdEncoding.DecodeString(signature.String); err != nil {
 			return nil, err
 		}
+	}
+	program.Content = []byte(content.String)
+	program.ApprovedBy = approvedBy.String
+	program.CreatedBy = createdBy.String
+	program.CreatedAt = createdAt.Time
+	program.UpdatedAt = updatedAt.Time
+	if approvedAt.Valid {
+		program.ApprovedAt = &approvedAt.Time
+	}
 
-		programs = append(programs, &program)
+	if compatibilityJSON.Valid && compatibilityJSON.String != "" {
+		if err := json.Unmarshal([]byte(compatibilityJSON.String), &program.MachineCompatibility); err != nil {
+			return nil, err
+		}
+	}
+	if capabilitiesJSON.Valid && capabilitiesJSON.String != "" {
+		if err := json.Unmarshal([]byte(capabilitiesJSON.String), &program.RequiredCapabilities); err != nil {
+			return nil, err
+		}
 	}
 
-	return programs, nil
+	return &program, nil
+}
+
+// encodeSignature は署名を base64 で保存する。未署名は NULL
+func encodeSignature(signature []byte) sql.NullString {
+	if len(signature) == 0 {
+		return sql.NullString{}
+	}
+	return sql.NullString{String: base64.StdEncoding.EncodeToString(signature), Valid: true}
 }
 
 func (r *PostgresNCProgramRepository) Delete(ctx context.Context, id domain.NCProgramID) error {
@@ -172,154 +268,170 @@ func NewPostgresMachineRepository(db *sql.DB) *PostgresMachineRepository {
 	}
 }
 
-func (r *PostgresMachineRepository) Save(ctx context.Context, machine *domain.Machine) error {
-	capabilitiesJSON, err := json.Marshal(machine.Capabilities)
-	if err != nil {
-		return err
-	}
-
-	query := `
-		INSERT INTO machines (
-			id, name, ip_address, machine_type, capabilities,
-			running_state, current_job_id, last_heartbeat, error_message,
-			created_at, updated_at
-		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
-	`
-
-	_, err = r.db.ExecContext(ctx, query,
-		machine.ID,
-		machine.Name,
-		machine.IP,
-		machine.Type,
-		string(capabilitiesJSON),
-		machine.Status.RunningState,
-		machine.Status.CurrentJobID,
-		machine.Status.LastHeartbeat,
-		machine.Status.ErrorMessage,
-		machine.CreatedAt,
-		machine.UpdatedAt,
-	)
+const machineColumns = `
+	id, name, ip_address, machine_type, capabilities,
+	axis_travel_x, axis_travel_y, axis_travel_z, max_spindle_speed, tool_magazine_size, controller_model,
+	axes, control_options, macros,
+	running_state, current_job_id, last_heartbeat, error_message,
+	decommissioned_at, created_at, updated_at
+`
 
-	return err
+type rowScanner interface {
+	Scan(dest ...interface{}) error
 }
 
-func (r *PostgresMachineRepository) FindByID(ctx context.Context, id domain.MachineID) (*domain.Machine, error) {
-	query := `
-		SELECT id, name, ip_address, machine_type, capabilities,
-			   running_state, current_job_id, last_heartbeat, error_message,
-			   created_at, updated_at
-		FROM machines
-		WHERE id = $1
-	`
-
+func scanMachine(row rowScanner) (*domain.Machine, error) {
 	var machine domain.Machine
 	var capabilitiesJSON string
+	var controllerModel sql.NullString
+	var axesJSON, optionsJSON, macrosJSON sql.NullString
 	var currentJobID sql.NullString
 	var errorMessage sql.NullString
+	var decommissionedAt sql.NullTime
 
-	err := r.db.QueryRowContext(ctx, query, id).Scan(
+	err := row.Scan(
 		&machine.ID,
 		&machine.Name,
 		&machine.IP,
 		&machine.Type,
 		&capabilitiesJSON,
+		&machine.AxisTravel.X,
+		&machine.AxisTravel.Y,
+		&machine.AxisTravel.Z,
+		&machine.MaxSpindleSpeed,
+		&machine.ToolMagazineSize,
+		&controllerModel,
+		&axesJSON,
+		&optionsJSON,
+		&macrosJSON,
 		&machine.Status.RunningState,
 		&currentJobID,
 		&machine.Status.LastHeartbeat,
 		&errorMessage,
+		&decommissionedAt,
 		&machine.CreatedAt,
 		&machine.UpdatedAt,
 	)
-
-	if err == sql.ErrNoRows {
-		return nil, domain.ErrMachineNotFound
-	}
 	if err != nil {
 		return nil, err
 	}
 
+	if controllerModel.Valid {
+		machine.ControllerModel = controllerModel.String
+	}
 	if currentJobID.Valid {
 		machine.Status.CurrentJobID = currentJobID.String
 	}
 	if errorMessage.Valid {
 		machine.Status.ErrorMessage = errorMessage.String
 	}
+	if decommissionedAt.Valid {
+		t := decommissionedAt.Time
+		machine.DecommissionedAt = &t
+	}
 
 	if err := json.Unmarshal([]byte(capabilitiesJSON), &machine.Capabilities); err != nil {
 		return nil, err
 	}
+	for _, column := range []struct {
+		value  sql.NullString
+		target *[]string
+	}{
+		{axesJSON, &machine.Control.Axes},
+		{optionsJSON, &machine.Control.Options},
+		{macrosJSON, &machine.Control.Macros},
+	} {
+		if !column.value.Valid {
+			continue
+		}
+		if err := json.Unmarshal([]byte(column.value.String), column.target); err != nil {
+			return nil, err
+		}
+	}
 
 	return &machine, nil
 }
 
-func (r *PostgresMachineRepository) FindAll(ctx context.Context) ([]*domain.Machine, error) {
-	query := `
-		SELECT id, name, ip_address, machine_type, capabilities,
-			   running_state, current_job_id, last_heartbeat, error_message,
-			   created_at, updated_at
-		FROM machines
-		ORDER BY name
-	`
-
-	rows, err := r.db.QueryContext(ctx, query)
+func (r *PostgresMachineRepository) Save(ctx context.Context, machine *domain.Machine) error {
+	capabilitiesJSON, err := json.Marshal(machine.Capabilities)
 	if err != nil {
-		return nil, err
+		return err
+	}
+	control, err := marshalControlSpec(machine.Control)
+	if err != nil {
+		return err
 	}
-	defer rows.Close()
 
-	var machines []*domain.Machine
+	query := `
+		INSERT INTO machines (` + machineColumns + `)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19, $20, $21)
+	`
 
-	for rows.Next() {
-		var machine domain.Machine
-		var capabilitiesJSON string
-		var currentJobID sql.NullString
-		var errorMessage sql.NullString
-
-		err := rows.Scan(
-			&machine.ID,
-			&machine.Name,
-			&machine.IP,
-			&machine.Type,
-			&capabilitiesJSON,
-			&machine.Status.RunningState,
-			&currentJobID,
-			&machine.Status.LastHeartbeat,
-			&errorMessage,
-			&machine.CreatedAt,
-			&machine.UpdatedAt,
-		)
-		if err != nil {
-			return nil, err
-		}
+	_, err = r.db.ExecContext(ctx, query,
+		machine.ID,
+		machine.Name,
+		machine.IP,
+		machine.Type,
+		string(capabilitiesJSON),
+		machine.AxisTravel.X,
+		machine.AxisTravel.Y,
+		machine.AxisTravel.Z,
+		machine.MaxSpindleSpeed,
+		machine.ToolMagazineSize,
+		machine.ControllerModel,
+		control[0],
+		control[1],
+		control[2],
+		machine.Status.RunningState,
+		machine.Status.CurrentJobID,
+		machine.Status.LastHeartbeat,
+		machine.Status.ErrorMessage,
+		machine.DecommissionedAt,
+		machine.CreatedAt,
+		machine.UpdatedAt,
+	)
 
-		if currentJobID.Valid {
-			machine.Status.CurrentJobID = currentJobID.String
-		}
-		if errorMessage.Valid {
-			machine.Status.ErrorMessage = errorMessage.String
-		}
+	return err
+}
 
-		if err := json.Unmarshal([]byte(capabilitiesJSON), &machine.Capabilities); err != nil {
-			return nil, err
-		}
+func (r *PostgresMachineRepository) FindByID(ctx context.Context, id domain.MachineID) (*domain.Machine, error) {
+	query := `SELECT ` + machineColumns + ` FROM machines WHERE id = $1`
 
-		machines = append(machines, &machine)
+	machine, err := scanMachine(r.db.QueryRowContext(ctx, query, id))
+	if err == sql.ErrNoRows {
+		return nil, domain.ErrMachineNotFound
 	}
+	if err != nil {
+		return nil, err
+	}
+
+	return machine, nil
+}
 
-	return machines, nil
+func (r *PostgresMachineRepository) FindAll(ctx context.Context) ([]*domain.Machine, error) {
+	query := `
+		SELECT ` + machineColumns + `
+		FROM machines
+		WHERE decommissioned_at IS NULL
+		ORDER BY name
+	`
+
+	return r.queryMachines(ctx, query)
 }
 
 func (r *PostgresMachineRepository) FindAvailable(ctx context.Context) ([]*domain.Machine, error) {
 	query := `
-		SELECT id, name, ip_address, machine_type, capabilities,
-			   running_state, current_job_id, last_heartbeat, error_message,
-			   created_at, updated_at
+		SELECT ` + machineColumns + `
 		FROM machines
-		WHERE running_state = 'stopped'
+		WHERE running_state IN ('stopped', 'idle') AND decommissioned_at IS NULL
 		ORDER BY name
 	`
 
-	rows, err := r.db.QueryContext(ctx, query)
+	return r.queryMachines(ctx, query)
+}
+
+func (r *PostgresMachineRepository) queryMachines(ctx context.Context, query string, args ...interface{}) ([]*domain.Machine, error) {
+	rows, err := r.db.QueryContext(ctx, query, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -328,43 +440,14 @@ func (r *PostgresMachineRepository) FindAvailable(ctx context.Context) ([]*domai
 	var machines []*domain.Machine
 
 	for rows.Next() {
-		var machine domain.Machine
-		var capabilitiesJSON string
-		var currentJobID sql.NullString
-		var errorMessage sql.NullString
-
-		err := rows.Scan(
-			&machine.ID,
-			&machine.Name,
-			&machine.IP,
-			&machine.Type,
-			&capabilitiesJSON,
-			&machine.Status.RunningState,
-			&currentJobID,
-			&machine.Status.LastHeartbeat,
-			&errorMessage,
-			&machine.CreatedAt,
-			&machine.UpdatedAt,
-		)
+		machine, err := scanMachine(rows)
 		if err != nil {
 			return nil, err
 		}
-
-		if currentJobID.Valid {
-			machine.Status.CurrentJobID = currentJobID.String
-		}
-		if errorMessage.Valid {
-			machine.Status.ErrorMessage = errorMessage.String
-		}
-
-		if err := json.Unmarshal([]byte(capabilitiesJSON), &machine.Capabilities); err != nil {
-			return nil, err
-		}
-
-		machines = append(machines, &machine)
+		machines = append(machines, machine)
 	}
 
-	return machines, nil
+	return machines, rows.Err()
 }
 
 func (r *PostgresMachineRepository) Update(ctx context.Context, machine *domain.Machine) error {
@@ -372,12 +455,19 @@ func (r *PostgresMachineRepository) Update(ctx context.Context, machine *domain.
 	if err != nil {
 		return err
 	}
+	control, err := marshalControlSpec(machine.Control)
+	if err != nil {
+		return err
+	}
 
 	query := `
 		UPDATE machines
 		SET name = $2, ip_address = $3, machine_type = $4, capabilities = $5,
-			running_state = $6, current_job_id = $7, last_heartbeat = $8, error_message = $9,
-			updated_at = $10
+			axis_travel_x = $6, axis_travel_y = $7, axis_travel_z = $8,
+			max_spindle_speed = $9, tool_magazine_size = $10, controller_model = $11,
+			axes = $12, control_options = $13, macros = $14,
+			running_state = $15, current_job_id = $16, last_heartbeat = $17, error_message = $18,
+			decommissioned_at = $19, updated_at = $20
 		WHERE id = $1
 	`
 
@@ -387,12 +477,131 @@ func (r *PostgresMachineRepository) Update(ctx context.Context, machine *domain.
 		machine.IP,
 		machine.Type,
 		string(capabilitiesJSON),
+		machine.AxisTravel.X,
+		machine.AxisTravel.Y,
+		machine.AxisTravel.Z,
+		machine.MaxSpindleSpeed,
+		machine.ToolMagazineSize,
+		machine.ControllerModel,
+		control[0],
+		control[1],
+		control[2],
 		machine.Status.RunningState,
 		machine.Status.CurrentJobID,
 		machine.Status.LastHeartbeat,
 		machine.Status.ErrorMessage,
+		machine.DecommissionedAt,
+		time.Now(),
+	)
+
+	return err
+}
+
+func (r *PostgresMachineRepository) UpdateStatus(ctx context.Context, machine *domain.Machine) error {
+	query := `
+		UPDATE machines
+		SET running_state = $2, current_job_id = $3, error_message = $4, updated_at = $5
+		WHERE id = $1
+	`
+
+	_, err := r.db.ExecContext(ctx, query,
+		machine.ID,
+		machine.Status.RunningState,
+		machine.Status.CurrentJobID,
+		machine.Status.ErrorMessage,
 		time.Now(),
 	)
 
 	return err
-}
\ No newline at end of file
+}
+
+func (r *PostgresMachineRepository) MarkOfflineIfStale(ctx context.Context, id domain.MachineID, cutoff time.Time) (*domain.MachineStatus, error) {
+	query := `
+		UPDATE machines m
+		SET running_state = $3, error_message = $4, updated_at = $5
+		FROM (SELECT id, running_state, current_job_id, last_heartbeat FROM machines WHERE id = $1 FOR UPDATE) prev
+		WHERE m.id = prev.id
+		  AND m.last_heartbeat <= $2
+		  AND m.running_state <> $3
+		  AND m.decommissioned_at IS NULL
+		RETURNING prev.running_state, prev.current_job_id, prev.last_heartbeat
+	`
+
+	var status domain.MachineStatus
+	var currentJobID sql.NullString
+	err := r.db.QueryRowContext(ctx, query, id, cutoff, domain.StateOffline, "heartbeat timeout", time.Now()).Scan(
+		&status.RunningState,
+		&currentJobID,
+		&status.LastHeartbeat,
+	)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	status.CurrentJobID = currentJobID.String
+
+	return &status, nil
+}
+
+func (r *PostgresMachineRepository) UpdateHeartbeat(ctx context.Context, id domain.MachineID, at time.Time) (*domain.MachineStatus, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx, `
+		UPDATE machines SET last_heartbeat = $2, updated_at = $3
+		WHERE id = $1 AND decommissioned_at IS NULL
+	`, id, at, time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	// 状態の列は読み込んだ値で書き戻さず、オフラインのままの場合のみ停止状態へ戻す
+	var status domain.MachineStatus
+	var currentJobID sql.NullString
+	err = tx.QueryRowContext(ctx, `
+		UPDATE machines m
+		SET running_state = $2, current_job_id = '', error_message = '', updated_at = $4
+		FROM (SELECT id, running_state, current_job_id, last_heartbeat FROM machines WHERE id = $1 FOR UPDATE) prev
+		WHERE m.id = prev.id
+		  AND m.running_state = $3
+		  AND m.decommissioned_at IS NULL
+		RETURNING prev.running_state, prev.current_job_id, prev.last_heartbeat
+	`, id, domain.StateStopped, domain.StateOffline, time.Now()).Scan(
+		&status.RunningState,
+		&currentJobID,
+		&status.LastHeartbeat,
+	)
+	if err == sql.ErrNoRows {
+		return nil, tx.Commit()
+	}
+	if err != nil {
+		return nil, err
+	}
+	status.CurrentJobID = currentJobID.String
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return &status, nil
+}
+
+// marshalControlSpec は制御軸・オプション・マクロをそれぞれ JSON 配列の文字列にする
+func marshalControlSpec(control domain.ControlSpec) ([3]string, error) {
+	var columns [3]string
+	for i, values := range [][]string{control.Axes, control.Options, control.Macros} {
+		if values == nil {
+			values = []string{}
+		}
+		data, err := json.Marshal(values)
+		if err != nil {
+			return columns, err
+		}
+		columns[i] = string(data)
+	}
+	return columns, nil
+}
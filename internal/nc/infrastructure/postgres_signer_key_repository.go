@@ -0,0 +1,143 @@
+package infrastructure
+
+import (
+	"context"
+	"crypto/ed25519"
+	"database/sql"
+	"encoding/base64"
+	"errors"
+	"goNexttask/internal/nc/domain"
+
+	"github.com/lib/pq"
+)
+
+const signerKeyColumns = `id, owner, public_key, status, created_by, created_at, revoked_by, revoked_at, revoke_reason`
+
+type PostgresSignerKeyRepository struct {
+	db *sql.DB
+}
+
+func NewPostgresSignerKeyRepository(db *sql.DB) *PostgresSignerKeyRepository {
+	return &PostgresSignerKeyRepository{
+		db: db,
+	}
+}
+
+func (r *PostgresSignerKeyRepository) Save(ctx context.Context, key *domain.SignerKey) error {
+	query := `INSERT INTO signer_keys (` + signerKeyColumns + `) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)`
+
+	_, err := r.db.ExecContext(ctx, query,
+		key.ID,
+		key.Owner,
+		key.EncodedPublicKey(),
+		key.Status,
+		key.CreatedBy,
+		key.CreatedAt,
+		key.RevokedBy,
+		key.RevokedAt,
+		key.RevokeReason,
+	)
+
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code.Name() == "unique_violation" {
+		return domain.ErrSignerKeyAlreadyExists
+	}
+
+	return err
+}
+
+func (r *PostgresSignerKeyRepository) Update(ctx context.Context, key *domain.SignerKey) error {
+	query := `UPDATE signer_keys SET status = $2, revoked_by = $3, revoked_at = $4, revoke_reason = $5 WHERE id = $1`
+
+	result, err := r.db.ExecContext(ctx, query, key.ID, key.Status, key.RevokedBy, key.RevokedAt, key.RevokeReason)
+	if err != nil {
+		return err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return domain.ErrSignerKeyNotFound
+	}
+
+	return nil
+}
+
+func (r *PostgresSignerKeyRepository) FindByID(ctx context.Context, id domain.SignerKeyID) (*domain.SignerKey, error) {
+	query := `SELECT ` + signerKeyColumns + ` FROM signer_keys WHERE id = $1`
+
+	key, err := scanSignerKey(r.db.QueryRowContext(ctx, query, id))
+	if err == sql.ErrNoRows {
+		return nil, domain.ErrSignerKeyNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return key, nil
+}
+
+func (r *PostgresSignerKeyRepository) FindAll(ctx context.Context, owner string) ([]*domain.SignerKey, error) {
+	query := `
+		SELECT ` + signerKeyColumns + `
+		FROM signer_keys
+		WHERE ($1 = '' OR owner = $1)
+		ORDER BY owner, created_at DESC
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, owner)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var keys []*domain.SignerKey
+
+	for rows.Next() {
+		key, err := scanSignerKey(rows)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+
+	return keys, rows.Err()
+}
+
+func scanSignerKey(row rowScanner) (*domain.SignerKey, error) {
+	var key domain.SignerKey
+	var publicKey string
+	var createdBy, revokedBy, revokeReason sql.NullString
+	var revokedAt sql.NullTime
+
+	err := row.Scan(
+		&key.ID,
+		&key.Owner,
+		&publicKey,
+		&key.Status,
+		&createdBy,
+		&key.CreatedAt,
+		&revokedBy,
+		&revokedAt,
+		&revokeReason,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	raw, err := base64.StdEncoding.DecodeString(publicKey)
+	if err != nil {
+		return nil, err
+	}
+	key.PublicKey = ed25519.PublicKey(raw)
+	key.CreatedBy = createdBy.String
+	key.RevokedBy = revokedBy.String
+	key.RevokeReason = revokeReason.String
+	if revokedAt.Valid {
+		key.RevokedAt = &revokedAt.Time
+	}
+
+	return &key, nil
+}
@@ -0,0 +1,79 @@
+package infrastructure
+
+import (
+	"context"
+	"database/sql"
+	"goNexttask/internal/nc/domain"
+	"time"
+)
+
+type PostgresMachineStatusHistoryRepository struct {
+	db *sql.DB
+}
+
+func NewPostgresMachineStatusHistoryRepository(db *sql.DB) *PostgresMachineStatusHistoryRepository {
+	return &PostgresMachineStatusHistoryRepository{
+		db: db,
+	}
+}
+
+func (r *PostgresMachineStatusHistoryRepository) Append(ctx context.Context, change *domain.MachineStatusChange) error {
+	query := `
+		INSERT INTO machine_status_history (
+			machine_id, from_state, to_state, job_id, error_message, changed_at
+		) VALUES ($1, $2, $3, $4, $5, $6)
+	`
+
+	_, err := r.db.ExecContext(ctx, query,
+		change.MachineID,
+		change.FromState,
+		change.ToState,
+		change.JobID,
+		change.ErrorMessage,
+		change.ChangedAt,
+	)
+
+	return err
+}
+
+func (r *PostgresMachineStatusHistoryRepository) FindByMachineID(ctx context.Context, id domain.MachineID, from, to time.Time) ([]*domain.MachineStatusChange, error) {
+	query := `
+		SELECT machine_id, from_state, to_state, job_id, error_message, changed_at
+		FROM machine_status_history
+		WHERE machine_id = $1 AND changed_at >= $2 AND changed_at < $3
+		ORDER BY changed_at, id
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, id, from, to)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var changes []*domain.MachineStatusChange
+
+	for rows.Next() {
+		var change domain.MachineStatusChange
+		var jobID sql.NullString
+		var errorMessage sql.NullString
+
+		err := rows.Scan(
+			&change.MachineID,
+			&change.FromState,
+			&change.ToState,
+			&jobID,
+			&errorMessage,
+			&change.ChangedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		change.JobID = jobID.String
+		change.ErrorMessage = errorMessage.String
+
+		changes = append(changes, &change)
+	}
+
+	return changes, rows.Err()
+}
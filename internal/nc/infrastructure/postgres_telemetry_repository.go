@@ -0,0 +1,98 @@
+package infrastructure
+
+import (
+	"context"
+	"database/sql"
+	"goNexttask/internal/nc/domain"
+	"time"
+)
+
+type PostgresTelemetryRepository struct {
+	db *sql.DB
+}
+
+func NewPostgresTelemetryRepository(db *sql.DB) *PostgresTelemetryRepository {
+	return &PostgresTelemetryRepository{
+		db: db,
+	}
+}
+
+func (r *PostgresTelemetryRepository) Append(ctx context.Context, points []domain.TelemetryPoint) error {
+	if len(points) == 0 {
+		return nil
+	}
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	query := `
+		INSERT INTO machine_telemetry (
+			machine_id, source, data_item, category, value, numeric_value, observed_at
+		) VALUES ($1, $2, $3, $4, $5, $6, $7)
+	`
+
+	for _, point := range points {
+		_, err = tx.ExecContext(ctx, query,
+			point.MachineID,
+			point.Source,
+			point.DataItem,
+			point.Category,
+			point.Value,
+			point.NumericValue,
+			point.Timestamp,
+		)
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+func (r *PostgresTelemetryRepository) FindByMachineID(ctx context.Context, id domain.MachineID, dataItem string, from, to time.Time) ([]domain.TelemetryPoint, error) {
+	query := `
+		SELECT machine_id, source, data_item, category, value, numeric_value, observed_at
+		FROM machine_telemetry
+		WHERE machine_id = $1 AND ($2 = '' OR data_item = $2)
+		  AND observed_at >= $3 AND observed_at < $4
+		ORDER BY observed_at, id
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, id, dataItem, from, to)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var points []domain.TelemetryPoint
+
+	for rows.Next() {
+		var point domain.TelemetryPoint
+		var numericValue sql.NullFloat64
+
+		err := rows.Scan(
+			&point.MachineID,
+			&point.Source,
+			&point.DataItem,
+			&point.Category,
+			&point.Value,
+			&numericValue,
+			&point.Timestamp,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		if numericValue.Valid {
+			v := numericValue.Float64
+			point.NumericValue = &v
+		}
+
+		points = append(points, point)
+	}
+
+	return points, rows.Err()
+}
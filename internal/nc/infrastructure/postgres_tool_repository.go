@@ -0,0 +1,315 @@
+package infrastructure
+
+import (
+	"context"
+	"database/sql"
+	"goNexttask/internal/nc/domain"
+	"time"
+)
+
+const (
+	toolItemColumns     = `id, kind, code, description, created_at`
+	toolAssemblyColumns = `id, name, holder_id, cutting_item_id, life_cut_time_sec, life_part_count, created_at, updated_at`
+	magazineToolColumns = `machine_id, tool_number, assembly_id, life_cut_time_sec, life_part_count,
+	cut_time_sec, part_count, loaded_at, updated_at`
+)
+
+type PostgresToolLibraryRepository struct {
+	db *sql.DB
+}
+
+func NewPostgresToolLibraryRepository(db *sql.DB) *PostgresToolLibraryRepository {
+	return &PostgresToolLibraryRepository{
+		db: db,
+	}
+}
+
+func (r *PostgresToolLibraryRepository) SaveItem(ctx context.Context, item *domain.ToolItem) error {
+	query := `INSERT INTO tool_items (` + toolItemColumns + `) VALUES ($1, $2, $3, $4, $5)`
+
+	_, err := r.db.ExecContext(ctx, query,
+		item.ID,
+		item.Kind,
+		item.Code,
+		item.Description,
+		item.CreatedAt,
+	)
+
+	return err
+}
+
+func (r *PostgresToolLibraryRepository) FindItemByID(ctx context.Context, id domain.ToolItemID) (*domain.ToolItem, error) {
+	query := `SELECT ` + toolItemColumns + ` FROM tool_items WHERE id = $1`
+
+	item, err := scanToolItem(r.db.QueryRowContext(ctx, query, id))
+	if err == sql.ErrNoRows {
+		return nil, domain.ErrToolItemNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return item, nil
+}
+
+func (r *PostgresToolLibraryRepository) FindAllItems(ctx context.Context) ([]*domain.ToolItem, error) {
+	query := `SELECT ` + toolItemColumns + ` FROM tool_items ORDER BY kind, code`
+
+	rows, err := r.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var items []*domain.ToolItem
+
+	for rows.Next() {
+		item, err := scanToolItem(rows)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+
+	return items, rows.Err()
+}
+
+func (r *PostgresToolLibraryRepository) SaveAssembly(ctx context.Context, assembly *domain.ToolAssembly) error {
+	query := `INSERT INTO tool_assemblies (` + toolAssemblyColumns + `) VALUES ($1, $2, $3, $4, $5, $6, $7, $8)`
+
+	_, err := r.db.ExecContext(ctx, query,
+		assembly.ID,
+		assembly.Name,
+		sql.NullString{String: string(assembly.HolderID), Valid: assembly.HolderID != ""},
+		assembly.CuttingItemID,
+		assembly.Life.CutTime.Seconds(),
+		assembly.Life.PartCount,
+		assembly.CreatedAt,
+		assembly.UpdatedAt,
+	)
+
+	return err
+}
+
+func (r *PostgresToolLibraryRepository) FindAssemblyByID(ctx context.Context, id domain.ToolAssemblyID) (*domain.ToolAssembly, error) {
+	query := `SELECT ` + toolAssemblyColumns + ` FROM tool_assemblies WHERE id = $1`
+
+	assembly, err := scanToolAssembly(r.db.QueryRowContext(ctx, query, id))
+	if err == sql.ErrNoRows {
+		return nil, domain.ErrToolAssemblyNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return assembly, nil
+}
+
+func (r *PostgresToolLibraryRepository) FindAllAssemblies(ctx context.Context) ([]*domain.ToolAssembly, error) {
+	query := `SELECT ` + toolAssemblyColumns + ` FROM tool_assemblies ORDER BY name`
+
+	rows, err := r.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var assemblies []*domain.ToolAssembly
+
+	for rows.Next() {
+		assembly, err := scanToolAssembly(rows)
+		if err != nil {
+			return nil, err
+		}
+		assemblies = append(assemblies, assembly)
+	}
+
+	return assemblies, rows.Err()
+}
+
+func scanToolItem(row rowScanner) (*domain.ToolItem, error) {
+	var item domain.ToolItem
+	var description sql.NullString
+
+	if err := row.Scan(&item.ID, &item.Kind, &item.Code, &description, &item.CreatedAt); err != nil {
+		return nil, err
+	}
+	item.Description = description.String
+
+	return &item, nil
+}
+
+func scanToolAssembly(row rowScanner) (*domain.ToolAssembly, error) {
+	var assembly domain.ToolAssembly
+	var holderID sql.NullString
+	var lifeCutTimeSec float64
+
+	err := row.Scan(
+		&assembly.ID,
+		&assembly.Name,
+		&holderID,
+		&assembly.CuttingItemID,
+		&lifeCutTimeSec,
+		&assembly.Life.PartCount,
+		&assembly.CreatedAt,
+		&assembly.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	assembly.HolderID = domain.ToolItemID(holderID.String)
+	assembly.Life.CutTime = secondsToDuration(lifeCutTimeSec)
+
+	return &assembly, nil
+}
+
+type PostgresToolMagazineRepository struct {
+	db *sql.DB
+}
+
+func NewPostgresToolMagazineRepository(db *sql.DB) *PostgresToolMagazineRepository {
+	return &PostgresToolMagazineRepository{
+		db: db,
+	}
+}
+
+func (r *PostgresToolMagazineRepository) Save(ctx context.Context, tool *domain.MagazineTool) error {
+	query := `
+		INSERT INTO machine_tools (` + magazineToolColumns + `)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		ON CONFLICT (machine_id, tool_number) DO UPDATE SET
+			assembly_id = EXCLUDED.assembly_id,
+			life_cut_time_sec = EXCLUDED.life_cut_time_sec,
+			life_part_count = EXCLUDED.life_part_count,
+			cut_time_sec = EXCLUDED.cut_time_sec,
+			part_count = EXCLUDED.part_count,
+			loaded_at = EXCLUDED.loaded_at,
+			updated_at = EXCLUDED.updated_at
+	`
+
+	_, err := r.db.ExecContext(ctx, query,
+		tool.MachineID,
+		tool.ToolNumber,
+		tool.AssemblyID,
+		tool.Life.CutTime.Seconds(),
+		tool.Life.PartCount,
+		tool.CutTime.Seconds(),
+		tool.PartCount,
+		tool.LoadedAt,
+		tool.UpdatedAt,
+	)
+
+	return err
+}
+
+func (r *PostgresToolMagazineRepository) Update(ctx context.Context, tool *domain.MagazineTool) error {
+	query := `
+		UPDATE machine_tools
+		SET cut_time_sec = $3, part_count = $4, updated_at = $5
+		WHERE machine_id = $1 AND tool_number = $2
+	`
+
+	result, err := r.db.ExecContext(ctx, query,
+		tool.MachineID,
+		tool.ToolNumber,
+		tool.CutTime.Seconds(),
+		tool.PartCount,
+		tool.UpdatedAt,
+	)
+	if err != nil {
+		return err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return domain.ErrToolNotLoaded
+	}
+
+	return nil
+}
+
+func (r *PostgresToolMagazineRepository) Delete(ctx context.Context, machineID domain.MachineID, toolNumber int) error {
+	query := `DELETE FROM machine_tools WHERE machine_id = $1 AND tool_number = $2`
+
+	result, err := r.db.ExecContext(ctx, query, machineID, toolNumber)
+	if err != nil {
+		return err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return domain.ErrToolNotLoaded
+	}
+
+	return nil
+}
+
+func (r *PostgresToolMagazineRepository) Find(ctx context.Context, machineID domain.MachineID, toolNumber int) (*domain.MagazineTool, error) {
+	query := `SELECT ` + magazineToolColumns + ` FROM machine_tools WHERE machine_id = $1 AND tool_number = $2`
+
+	tool, err := scanMagazineTool(r.db.QueryRowContext(ctx, query, machineID, toolNumber))
+	if err == sql.ErrNoRows {
+		return nil, domain.ErrToolNotLoaded
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return tool, nil
+}
+
+func (r *PostgresToolMagazineRepository) FindByMachineID(ctx context.Context, machineID domain.MachineID) ([]*domain.MagazineTool, error) {
+	query := `SELECT ` + magazineToolColumns + ` FROM machine_tools WHERE machine_id = $1 ORDER BY tool_number`
+
+	rows, err := r.db.QueryContext(ctx, query, machineID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var tools []*domain.MagazineTool
+
+	for rows.Next() {
+		tool, err := scanMagazineTool(rows)
+		if err != nil {
+			return nil, err
+		}
+		tools = append(tools, tool)
+	}
+
+	return tools, rows.Err()
+}
+
+func scanMagazineTool(row rowScanner) (*domain.MagazineTool, error) {
+	var tool domain.MagazineTool
+	var lifeCutTimeSec, cutTimeSec float64
+
+	err := row.Scan(
+		&tool.MachineID,
+		&tool.ToolNumber,
+		&tool.AssemblyID,
+		&lifeCutTimeSec,
+		&tool.Life.PartCount,
+		&cutTimeSec,
+		&tool.PartCount,
+		&tool.LoadedAt,
+		&tool.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	tool.Life.CutTime = secondsToDuration(lifeCutTimeSec)
+	tool.CutTime = secondsToDuration(cutTimeSec)
+
+	return &tool, nil
+}
+
+func secondsToDuration(seconds float64) time.Duration {
+	return time.Duration(seconds * float64(time.Second))
+}
@@ -0,0 +1,185 @@
+package http
+
+import (
+	"encoding/json"
+	"goNexttask/internal/nc/application"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+)
+
+type AlarmCatalogEntryRequest struct {
+	Description       string `json:"description"`
+	RecommendedAction string `json:"recommendedAction"`
+}
+
+type AlarmCatalogEntryResponse struct {
+	ControllerModel   string `json:"controllerModel"`
+	Code              string `json:"code"`
+	Description       string `json:"description"`
+	RecommendedAction string `json:"recommendedAction,omitempty"`
+	UpdatedAt         string `json:"updatedAt"`
+}
+
+type MachineAlarmResponse struct {
+	ID                string  `json:"id"`
+	Code              string  `json:"code,omitempty"`
+	Severity          string  `json:"severity"`
+	Message           string  `json:"message"`
+	Source            string  `json:"source"`
+	JobID             string  `json:"jobId,omitempty"`
+	RaisedAt          string  `json:"raisedAt"`
+	ClearedAt         string  `json:"clearedAt,omitempty"`
+	DurationSec       float64 `json:"durationSec"`
+	Description       string  `json:"description,omitempty"`
+	RecommendedAction string  `json:"recommendedAction,omitempty"`
+}
+
+type AlarmSummaryResponse struct {
+	Code             string  `json:"code,omitempty"`
+	Severity         string  `json:"severity"`
+	Description      string  `json:"description"`
+	Occurrences      int     `json:"occurrences"`
+	TotalDurationSec float64 `json:"totalDurationSec"`
+	LastRaisedAt     string  `json:"lastRaisedAt"`
+}
+
+type MachineAlarmsResponse struct {
+	MachineID       string                 `json:"machineId"`
+	ControllerModel string                 `json:"controllerModel,omitempty"`
+	Alarms          []MachineAlarmResponse `json:"alarms"`
+	Summary         []AlarmSummaryResponse `json:"summary"`
+}
+
+// GetMachineAlarms は機械のアラーム履歴とアラーム番号ごとの集計を返す。
+// from/to は RFC3339（省略時は直近24時間）、code / severity（fault|warning）/ active=true で絞り込める
+func (h *NCHandler) GetMachineAlarms(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	query := r.URL.Query()
+
+	from, to, err := parseTimeRange(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var activeOnly bool
+	if v := query.Get("active"); v != "" {
+		if activeOnly, err = strconv.ParseBool(v); err != nil {
+			http.Error(w, "Invalid active parameter", http.StatusBadRequest)
+			return
+		}
+	}
+
+	output, err := h.useCase.GetMachineAlarms(r.Context(), application.MachineAlarmsInput{
+		MachineID:  vars["id"],
+		From:       from,
+		To:         to,
+		Code:       query.Get("code"),
+		Severity:   query.Get("severity"),
+		ActiveOnly: activeOnly,
+	})
+	if err != nil {
+		http.Error(w, err.Error(), errorStatus(err))
+		return
+	}
+
+	response := MachineAlarmsResponse{
+		MachineID:       output.MachineID,
+		ControllerModel: output.ControllerModel,
+		Alarms:          make([]MachineAlarmResponse, len(output.Alarms)),
+		Summary:         make([]AlarmSummaryResponse, len(output.Summary)),
+	}
+	for i, alarm := range output.Alarms {
+		response.Alarms[i] = MachineAlarmResponse{
+			ID:                alarm.ID,
+			Code:              alarm.Code,
+			Severity:          alarm.Severity,
+			Message:           alarm.Message,
+			Source:            alarm.Source,
+			JobID:             alarm.JobID,
+			RaisedAt:          alarm.RaisedAt,
+			ClearedAt:         alarm.ClearedAt,
+			DurationSec:       alarm.DurationSec,
+			Description:       alarm.Description,
+			RecommendedAction: alarm.RecommendedAction,
+		}
+	}
+	for i, summary := range output.Summary {
+		response.Summary[i] = AlarmSummaryResponse{
+			Code:             summary.Code,
+			Severity:         summary.Severity,
+			Description:      summary.Description,
+			Occurrences:      summary.Occurrences,
+			TotalDurationSec: summary.TotalDurationSec,
+			LastRaisedAt:     summary.LastRaisedAt,
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+func (h *NCHandler) SaveAlarmCatalogEntry(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+
+	var req AlarmCatalogEntryRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	output, err := h.useCase.SaveAlarmCatalogEntry(r.Context(), application.AlarmCatalogEntryInput{
+		ControllerModel:   vars["controllerModel"],
+		Code:              vars["code"],
+		Description:       req.Description,
+		RecommendedAction: req.RecommendedAction,
+	})
+	if err != nil {
+		http.Error(w, err.Error(), errorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(toAlarmCatalogEntryResponse(output))
+}
+
+func (h *NCHandler) GetAlarmCatalog(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+
+	outputs, err := h.useCase.GetAlarmCatalog(r.Context(), vars["controllerModel"])
+	if err != nil {
+		http.Error(w, err.Error(), errorStatus(err))
+		return
+	}
+
+	responses := make([]AlarmCatalogEntryResponse, len(outputs))
+	for i, output := range outputs {
+		responses[i] = toAlarmCatalogEntryResponse(output)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(responses)
+}
+
+func (h *NCHandler) DeleteAlarmCatalogEntry(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+
+	if err := h.useCase.DeleteAlarmCatalogEntry(r.Context(), vars["controllerModel"], vars["code"]); err != nil {
+		http.Error(w, err.Error(), errorStatus(err))
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func toAlarmCatalogEntryResponse(output *application.AlarmCatalogEntryOutput) AlarmCatalogEntryResponse {
+	return AlarmCatalogEntryResponse{
+		ControllerModel:   output.ControllerModel,
+		Code:              output.Code,
+		Description:       output.Description,
+		RecommendedAction: output.RecommendedAction,
+		UpdatedAt:         output.UpdatedAt,
+	}
+}
@@ -0,0 +1,138 @@
+package http
+
+import (
+	"encoding/json"
+	"goNexttask/internal/nc/application"
+	"net/http"
+	"strconv"
+)
+
+type ProgramRequirementsResponse struct {
+	ProgramID            string   `json:"programId"`
+	Name                 string   `json:"name"`
+	Version              string   `json:"version"`
+	PartID               string   `json:"partId,omitempty"`
+	Status               string   `json:"status"`
+	MachineCompatibility []string `json:"machineCompatibility,omitempty"`
+	RequiredCapabilities []string `json:"requiredCapabilities,omitempty"`
+	Axes                 []string `json:"axes"`
+	MaxSpindleSpeed      int      `json:"maxSpindleSpeed"`
+	Options              []string `json:"options"`
+	Macros               []string `json:"macros"`
+}
+
+type MachineCapabilitiesResponse struct {
+	MachineID       string   `json:"machineId"`
+	Name            string   `json:"name"`
+	Type            string   `json:"type"`
+	Capabilities    []string `json:"capabilities"`
+	Axes            []string `json:"axes"`
+	MaxSpindleSpeed int      `json:"maxSpindleSpeed"`
+	Options         []string `json:"options"`
+	Macros          []string `json:"macros"`
+}
+
+type CompatibilityIssueResponse struct {
+	Requirement string `json:"requirement"`
+	Required    string `json:"required"`
+	Available   string `json:"available,omitempty"`
+	Message     string `json:"message"`
+}
+
+type CompatibilityResponse struct {
+	ProgramID  string                       `json:"programId"`
+	MachineID  string                       `json:"machineId"`
+	Compatible bool                         `json:"compatible"`
+	Mismatches []CompatibilityIssueResponse `json:"mismatches"`
+	Unverified []CompatibilityIssueResponse `json:"unverified,omitempty"`
+}
+
+type CompatibilityMatrixResponse struct {
+	Programs []ProgramRequirementsResponse `json:"programs"`
+	Machines []MachineCapabilitiesResponse `json:"machines"`
+	Matrix   []CompatibilityResponse       `json:"matrix"`
+}
+
+// GetCompatibilityMatrix はプログラム × 機械の適合表を返す。
+// partId / programId / machineId で対象を絞り込み、released=true でリリース済みのプログラムのみにする
+func (h *NCHandler) GetCompatibilityMatrix(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	var releasedOnly bool
+	if v := query.Get("released"); v != "" {
+		var err error
+		if releasedOnly, err = strconv.ParseBool(v); err != nil {
+			http.Error(w, "Invalid released parameter", http.StatusBadRequest)
+			return
+		}
+	}
+
+	output, err := h.useCase.GetCompatibilityMatrix(r.Context(), application.CompatibilityMatrixInput{
+		PartID:       query.Get("partId"),
+		ProgramID:    query.Get("programId"),
+		MachineID:    query.Get("machineId"),
+		ReleasedOnly: releasedOnly,
+	})
+	if err != nil {
+		http.Error(w, err.Error(), errorStatus(err))
+		return
+	}
+
+	response := CompatibilityMatrixResponse{
+		Programs: make([]ProgramRequirementsResponse, len(output.Programs)),
+		Machines: make([]MachineCapabilitiesResponse, len(output.Machines)),
+		Matrix:   make([]CompatibilityResponse, len(output.Cells)),
+	}
+	for i, program := range output.Programs {
+		response.Programs[i] = ProgramRequirementsResponse{
+			ProgramID:            program.ProgramID,
+			Name:                 program.Name,
+			Version:              program.Version,
+			PartID:               program.PartID,
+			Status:               program.Status,
+			MachineCompatibility: program.MachineCompatibility,
+			RequiredCapabilities: program.RequiredCapabilities,
+			Axes:                 program.Axes,
+			MaxSpindleSpeed:      program.MaxSpindleSpeed,
+			Options:              program.Options,
+			Macros:               program.Macros,
+		}
+	}
+	for i, machine := range output.Machines {
+		response.Machines[i] = MachineCapabilitiesResponse{
+			MachineID:       machine.MachineID,
+			Name:            machine.Name,
+			Type:            machine.Type,
+			Capabilities:    machine.Capabilities,
+			Axes:            machine.Axes,
+			MaxSpindleSpeed: machine.MaxSpindleSpeed,
+			Options:         machine.Options,
+			Macros:          machine.Macros,
+		}
+	}
+	for i, cell := range output.Cells {
+		response.Matrix[i] = CompatibilityResponse{
+			ProgramID:  cell.ProgramID,
+			MachineID:  cell.MachineID,
+			Compatible: cell.Compatible,
+			Mismatches: toCompatibilityIssueResponses(cell.Mismatches),
+			Unverified: toCompatibilityIssueResponses(cell.Unverified),
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+func toCompatibilityIssueResponses(outputs []*application.CompatibilityIssueOutput) []CompatibilityIssueResponse {
+	responses := make([]CompatibilityIssueResponse, len(outputs))
+	for i, output := range outputs {
+		responses[i] = CompatibilityIssueResponse{
+			Requirement: output.Requirement,
+			Required:    output.Required,
+			Available:   output.Available,
+			Message:     output.Message,
+		}
+	}
+	return responses
+}
@@ -0,0 +1,125 @@
+package http
+
+import (
+	"encoding/json"
+	"goNexttask/internal/nc/application"
+	"goNexttask/pkg/auth"
+	"net/http"
+
+	"github.com/gorilla/mux"
+)
+
+type TransferAttemptResponse struct {
+	Number     int    `json:"number"`
+	StartedAt  string `json:"startedAt"`
+	DurationMs int64  `json:"durationMs"`
+	BytesSent  int64  `json:"bytesSent"`
+	Error      string `json:"error,omitempty"`
+}
+
+type DeploymentResponse struct {
+	ID                string                    `json:"id"`
+	IdempotencyKey    string                    `json:"idempotencyKey"`
+	MachineID         string                    `json:"machineId"`
+	ProgramID         string                    `json:"programId"`
+	FileHash          string                    `json:"fileHash"`
+	ProductionOrderID string                    `json:"productionOrderId,omitempty"`
+	JobID             string                    `json:"jobId,omitempty"`
+	Status            string                    `json:"status"`
+	Attempts          []TransferAttemptResponse `json:"attempts"`
+	BytesSent         int64                     `json:"bytesSent"`
+	ErrorMessage      string                    `json:"errorMessage,omitempty"`
+	CreatedAt         string                    `json:"createdAt"`
+	CompletedAt       string                    `json:"completedAt,omitempty"`
+}
+
+func (h *NCHandler) GetDeployment(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+
+	output, err := h.useCase.GetDeployment(r.Context(), vars["id"])
+	if err != nil {
+		http.Error(w, err.Error(), errorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(toDeploymentResponse(output))
+}
+
+// CancelDeployment は送信待ちの展開を取り消す。送信中の展開は中断を要求して 202 を返し、
+// 結果（cancelled、または中断前に送信が完了した場合は transferred）はポーリングで確認する
+func (h *NCHandler) CancelDeployment(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+
+	var cancelledBy string
+	if claims, ok := auth.GetUserFromContext(r.Context()); ok {
+		cancelledBy = claims.Email
+	}
+
+	output, err := h.useCase.CancelDeployment(r.Context(), vars["id"], cancelledBy)
+	if err != nil {
+		http.Error(w, err.Error(), errorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if output.Status == "cancelled" {
+		w.WriteHeader(http.StatusOK)
+	} else {
+		w.WriteHeader(http.StatusAccepted)
+	}
+	json.NewEncoder(w).Encode(toDeploymentResponse(output))
+}
+
+// GetMachineDeployments は機械へのプログラム展開と転送ログを新しい順に返す。from/to は RFC3339（省略時は直近24時間）
+func (h *NCHandler) GetMachineDeployments(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+
+	from, to, err := parseTimeRange(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	outputs, err := h.useCase.GetMachineDeployments(r.Context(), vars["id"], from, to)
+	if err != nil {
+		http.Error(w, err.Error(), errorStatus(err))
+		return
+	}
+
+	responses := make([]DeploymentResponse, len(outputs))
+	for i, output := range outputs {
+		responses[i] = toDeploymentResponse(output)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(responses)
+}
+
+func toDeploymentResponse(output *application.DeploymentOutput) DeploymentResponse {
+	response := DeploymentResponse{
+		ID:                output.ID,
+		IdempotencyKey:    output.IdempotencyKey,
+		MachineID:         output.MachineID,
+		ProgramID:         output.ProgramID,
+		FileHash:          output.FileHash,
+		ProductionOrderID: output.ProductionOrderID,
+		JobID:             output.JobID,
+		Status:            output.Status,
+		Attempts:          make([]TransferAttemptResponse, len(output.Attempts)),
+		BytesSent:         output.BytesSent,
+		ErrorMessage:      output.ErrorMessage,
+		CreatedAt:         output.CreatedAt,
+		CompletedAt:       output.CompletedAt,
+	}
+	for i, attempt := range output.Attempts {
+		response.Attempts[i] = TransferAttemptResponse{
+			Number:     attempt.Number,
+			StartedAt:  attempt.StartedAt,
+			DurationMs: attempt.DurationMs,
+			BytesSent:  attempt.BytesSent,
+			Error:      attempt.Error,
+		}
+	}
+	return response
+}
@@ -0,0 +1,85 @@
+package http
+
+import (
+	"encoding/json"
+	"goNexttask/internal/nc/application"
+	"goNexttask/pkg/auth"
+	"net/http"
+
+	"github.com/gorilla/mux"
+)
+
+// TranslateProgramRequest は方言変換の要求。targetDialect と targetMachineId のどちらかを指定する
+type TranslateProgramRequest struct {
+	TargetDialect   string `json:"targetDialect,omitempty"`
+	TargetMachineID string `json:"targetMachineId,omitempty"`
+	Version         string `json:"version,omitempty"`
+}
+
+type UntranslatedConstructResponse struct {
+	Line      int    `json:"line"`
+	Text      string `json:"text"`
+	Construct string `json:"construct"`
+	Reason    string `json:"reason"`
+}
+
+type TranslationResponse struct {
+	Program         ProgramResponse                 `json:"program"`
+	SourceProgramID string                          `json:"sourceProgramId"`
+	SourceDialect   string                          `json:"sourceDialect"`
+	TargetDialect   string                          `json:"targetDialect"`
+	Translated      map[string]int                  `json:"translated"`
+	Untranslated    []UntranslatedConstructResponse `json:"untranslated"`
+	Complete        bool                            `json:"complete"`
+}
+
+// TranslateProgram はプログラムを別の方言に変換したドラフト版を作成する。変換版は通常どおり承認が必要
+func (h *NCHandler) TranslateProgram(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+
+	claims, ok := auth.GetUserFromContext(r.Context())
+	if !ok {
+		http.Error(w, "User not found in context", http.StatusUnauthorized)
+		return
+	}
+
+	var req TranslateProgramRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	output, err := h.useCase.TranslateProgram(r.Context(), application.TranslateProgramInput{
+		ProgramID:       vars["id"],
+		TargetDialect:   req.TargetDialect,
+		TargetMachineID: req.TargetMachineID,
+		Version:         req.Version,
+		CreatedBy:       claims.Email,
+	})
+	if err != nil {
+		http.Error(w, err.Error(), errorStatus(err))
+		return
+	}
+
+	response := TranslationResponse{
+		Program:         toProgramResponse(output.Program),
+		SourceProgramID: output.Program.SourceProgramID,
+		SourceDialect:   output.SourceDialect,
+		TargetDialect:   output.TargetDialect,
+		Translated:      output.Translated,
+		Untranslated:    make([]UntranslatedConstructResponse, len(output.Untranslated)),
+		Complete:        output.Complete,
+	}
+	for i, u := range output.Untranslated {
+		response.Untranslated[i] = UntranslatedConstructResponse{
+			Line:      u.Line,
+			Text:      u.Text,
+			Construct: u.Construct,
+			Reason:    u.Reason,
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(response)
+}
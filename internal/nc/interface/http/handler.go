@@ -2,9 +2,12 @@ package http
 
 import (
 	"encoding/json"
+	"errors"
 	"goNexttask/internal/nc/application"
+	"goNexttask/internal/nc/domain"
 	"io"
 	"net/http"
+	"time"
 
 	"github.com/gorilla/mux"
 )
@@ -22,16 +25,62 @@ func NewNCHandler(useCase *application.NCUseCase) *NCHandler {
 func (h *NCHandler) RegisterRoutes(router *mux.Router) {
 	router.HandleFunc("/nc/programs", h.RegisterProgram).Methods("POST")
 	router.HandleFunc("/nc/programs", h.GetAllPrograms).Methods("GET")
+	router.HandleFunc("/nc/programs/recommend", h.RecommendProgram).Methods("GET")
+	router.HandleFunc("/nc/programs/{id}/approve", h.ApproveProgram).Methods("POST")
+	router.HandleFunc("/nc/programs/{id}/sign", h.SignProgram).Methods("POST")
+	router.HandleFunc("/nc/programs/{id}/signature", h.GetProgramSignature).Methods("GET")
+	router.HandleFunc("/nc/programs/{id}/tools", h.GetProgramTools).Methods("GET")
+	router.HandleFunc("/nc/programs/{id}/translate", h.TranslateProgram).Methods("POST")
+	router.HandleFunc("/nc/tools/items", h.CreateToolItem).Methods("POST")
+	router.HandleFunc("/nc/tools/items", h.GetToolItems).Methods("GET")
+	router.HandleFunc("/nc/tools/assemblies", h.CreateToolAssembly).Methods("POST")
+	router.HandleFunc("/nc/tools/assemblies", h.GetToolAssemblies).Methods("GET")
+	router.HandleFunc("/nc/compatibility", h.GetCompatibilityMatrix).Methods("GET")
+	router.HandleFunc("/nc/signer-keys", h.RegisterSignerKey).Methods("POST")
+	router.HandleFunc("/nc/signer-keys", h.GetSignerKeys).Methods("GET")
+	router.HandleFunc("/nc/signer-keys/{id}/revoke", h.RevokeSignerKey).Methods("POST")
+	router.HandleFunc("/nc/alarm-catalog/{controllerModel}", h.GetAlarmCatalog).Methods("GET")
+	router.HandleFunc("/nc/alarm-catalog/{controllerModel}/{code}", h.SaveAlarmCatalogEntry).Methods("PUT")
+	router.HandleFunc("/nc/alarm-catalog/{controllerModel}/{code}", h.DeleteAlarmCatalogEntry).Methods("DELETE")
+	router.HandleFunc("/nc/machines", h.RegisterMachine).Methods("POST")
+	router.HandleFunc("/nc/machines", h.GetAllMachines).Methods("GET")
+	router.HandleFunc("/nc/machines/{id}", h.GetMachine).Methods("GET")
+	router.HandleFunc("/nc/machines/{id}", h.UpdateMachine).Methods("PUT")
+	router.HandleFunc("/nc/machines/{id}", h.DecommissionMachine).Methods("DELETE")
 	router.HandleFunc("/nc/machines/{id}/deploy", h.DeployProgram).Methods("POST")
+	router.HandleFunc("/nc/machines/{id}/deployments", h.GetMachineDeployments).Methods("GET")
 	router.HandleFunc("/nc/machines/{id}/status", h.GetMachineStatus).Methods("GET")
 	router.HandleFunc("/nc/machines/{id}/status", h.UpdateMachineStatus).Methods("POST")
+	router.HandleFunc("/nc/machines/{id}/status/history", h.GetMachineStatusHistory).Methods("GET")
+	router.HandleFunc("/nc/machines/{id}/heartbeat", h.RecordHeartbeat).Methods("POST")
+	router.HandleFunc("/nc/machines/{id}/connector", h.ConfigureConnector).Methods("PUT")
+	router.HandleFunc("/nc/machines/{id}/connector", h.GetConnectorConfig).Methods("GET")
+	router.HandleFunc("/nc/machines/{id}/telemetry", h.GetTelemetry).Methods("GET")
+	router.HandleFunc("/nc/machines/{id}/jobs", h.GetMachineJobs).Methods("GET")
+	router.HandleFunc("/nc/machines/{id}/magazine", h.GetMagazine).Methods("GET")
+	router.HandleFunc("/nc/machines/{id}/magazine/{toolNumber}", h.LoadTool).Methods("PUT")
+	router.HandleFunc("/nc/machines/{id}/magazine/{toolNumber}", h.UnloadTool).Methods("DELETE")
+	router.HandleFunc("/nc/machines/{id}/magazine/{toolNumber}/usage", h.ReportToolUsage).Methods("POST")
+	router.HandleFunc("/nc/machines/{id}/alarms", h.GetMachineAlarms).Methods("GET")
+	router.HandleFunc("/nc/machines/{id}/offsets", h.GetMachineOffsets).Methods("GET")
+	router.HandleFunc("/nc/machines/{id}/offsets/changes", h.ProposeOffsetChange).Methods("POST")
+	router.HandleFunc("/nc/machines/{id}/offsets/changes", h.GetOffsetChanges).Methods("GET")
+	router.HandleFunc("/nc/jobs/{id}", h.GetJob).Methods("GET")
+	router.HandleFunc("/nc/deployments/{id}", h.GetDeployment).Methods("GET")
+	router.HandleFunc("/nc/deployments/{id}/cancel", h.CancelDeployment).Methods("POST")
+	router.HandleFunc("/nc/offsets/changes/{id}", h.GetOffsetChange).Methods("GET")
+	router.HandleFunc("/nc/offsets/changes/{id}/approve", h.ApproveOffsetChange).Methods("POST")
+	router.HandleFunc("/nc/offsets/changes/{id}/reject", h.RejectOffsetChange).Methods("POST")
 }
 
 type RegisterProgramRequest struct {
 	Name                 string   `json:"name"`
 	Version              string   `json:"version"`
+	PartID               string   `json:"partId"`
 	Content              string   `json:"content"`
 	MachineCompatibility []string `json:"machineCompatibility"`
+	RequiredCapabilities []string `json:"requiredCapabilities"`
+	Dialect              string   `json:"dialect,omitempty"`
 	CreatedBy            string   `json:"createdBy"`
 }
 
@@ -39,20 +88,35 @@ type ProgramResponse struct {
 	ID                   string   `json:"id"`
 	Name                 string   `json:"name"`
 	Version              string   `json:"version"`
+	PartID               string   `json:"partId,omitempty"`
+	Dialect              string   `json:"dialect"`
+	SourceProgramID      string   `json:"sourceProgramId,omitempty"`
 	FileHash             string   `json:"fileHash"`
+	Signature            string   `json:"signature,omitempty"`
+	SignerKeyID          string   `json:"signerKeyId,omitempty"`
+	SignedBy             string   `json:"signedBy,omitempty"`
+	SignedAt             string   `json:"signedAt,omitempty"`
 	MachineCompatibility []string `json:"machineCompatibility"`
+	RequiredCapabilities []string `json:"requiredCapabilities,omitempty"`
+	Status               string   `json:"status"`
+	ApprovedBy           string   `json:"approvedBy,omitempty"`
+	ApprovedAt           string   `json:"approvedAt,omitempty"`
 	CreatedBy            string   `json:"createdBy"`
 	CreatedAt            string   `json:"createdAt"`
 }
 
 type DeployRequest struct {
-	ProgramID string `json:"programId"`
+	ProgramID         string `json:"programId"`
+	ProductionOrderID string `json:"productionOrderId,omitempty"`
+	// IdempotencyKey は Idempotency-Key ヘッダーでも指定できる
+	IdempotencyKey string `json:"idempotencyKey,omitempty"`
 }
 
 type MachineStatusRequest struct {
-	RunningState string `json:"runningState"`
-	CurrentJobID string `json:"currentJobId,omitempty"`
-	ErrorMessage string `json:"errorMessage,omitempty"`
+	RunningState  string `json:"runningState"`
+	CurrentJobID  string `json:"currentJobId,omitempty"`
+	ErrorMessage  string `json:"errorMessage,omitempty"`
+	ProducedCount *int   `json:"producedCount,omitempty"`
 }
 
 type MachineStatusResponse struct {
@@ -63,6 +127,54 @@ type MachineStatusResponse struct {
 	RunningState  string `json:"runningState"`
 	CurrentJobID  string `json:"currentJobId"`
 	LastHeartbeat string `json:"lastHeartbeat"`
+	ErrorMessage  string `json:"errorMessage,omitempty"`
+}
+
+type MachineStatusChangeResponse struct {
+	MachineID    string `json:"machineId"`
+	FromState    string `json:"fromState"`
+	ToState      string `json:"toState"`
+	JobID        string `json:"jobId,omitempty"`
+	ErrorMessage string `json:"errorMessage,omitempty"`
+	ChangedAt    string `json:"changedAt"`
+}
+
+type MachineRequest struct {
+	ID               string   `json:"id,omitempty"`
+	Name             string   `json:"name"`
+	IP               string   `json:"ip"`
+	Type             string   `json:"type"`
+	Capabilities     []string `json:"capabilities"`
+	AxisTravelX      float64  `json:"axisTravelX"`
+	AxisTravelY      float64  `json:"axisTravelY"`
+	AxisTravelZ      float64  `json:"axisTravelZ"`
+	MaxSpindleSpeed  int      `json:"maxSpindleSpeed"`
+	ToolMagazineSize int      `json:"toolMagazineSize"`
+	ControllerModel  string   `json:"controllerModel"`
+	Axes             []string `json:"axes"`
+	ControlOptions   []string `json:"controlOptions"`
+	Macros           []string `json:"macros"`
+}
+
+type MachineResponse struct {
+	ID               string   `json:"id"`
+	Name             string   `json:"name"`
+	IP               string   `json:"ip"`
+	Type             string   `json:"type"`
+	Capabilities     []string `json:"capabilities"`
+	AxisTravelX      float64  `json:"axisTravelX"`
+	AxisTravelY      float64  `json:"axisTravelY"`
+	AxisTravelZ      float64  `json:"axisTravelZ"`
+	MaxSpindleSpeed  int      `json:"maxSpindleSpeed"`
+	ToolMagazineSize int      `json:"toolMagazineSize"`
+	ControllerModel  string   `json:"controllerModel"`
+	Axes             []string `json:"axes,omitempty"`
+	ControlOptions   []string `json:"controlOptions,omitempty"`
+	Macros           []string `json:"macros,omitempty"`
+	RunningState     string   `json:"runningState"`
+	DecommissionedAt string   `json:"decommissionedAt,omitempty"`
+	CreatedAt        string   `json:"createdAt"`
+	UpdatedAt        string   `json:"updatedAt"`
 }
 
 func (h *NCHandler) RegisterProgram(w http.ResponseWriter, r *http.Request) {
@@ -75,30 +187,23 @@ func (h *NCHandler) RegisterProgram(w http.ResponseWriter, r *http.Request) {
 	input := application.RegisterNCProgramInput{
 		Name:                 req.Name,
 		Version:              req.Version,
+		PartID:               req.PartID,
 		Content:              []byte(req.Content),
 		MachineCompatibility: req.MachineCompatibility,
+		RequiredCapabilities: req.RequiredCapabilities,
+		Dialect:              req.Dialect,
 		CreatedBy:            req.CreatedBy,
 	}
 
 	output, err := h.useCase.RegisterNCProgram(r.Context(), input)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		http.Error(w, err.Error(), errorStatus(err))
 		return
 	}
 
-	response := ProgramResponse{
-		ID:                   output.ID,
-		Name:                 output.Name,
-		Version:              output.Version,
-		FileHash:             output.FileHash,
-		MachineCompatibility: output.MachineCompatibility,
-		CreatedBy:            output.CreatedBy,
-		CreatedAt:            output.CreatedAt,
-	}
-
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusCreated)
-	json.NewEncoder(w).Encode(response)
+	json.NewEncoder(w).Encode(toProgramResponse(output))
 }
 
 func (h *NCHandler) GetAllPrograms(w http.ResponseWriter, r *http.Request) {
@@ -110,15 +215,7 @@ func (h *NCHandler) GetAllPrograms(w http.ResponseWriter, r *http.Request) {
 
 	responses := make([]ProgramResponse, len(outputs))
 	for i, output := range outputs {
-		responses[i] = ProgramResponse{
-			ID:                   output.ID,
-			Name:                 output.Name,
-			Version:              output.Version,
-			FileHash:             output.FileHash,
-			MachineCompatibility: output.MachineCompatibility,
-			CreatedBy:            output.CreatedBy,
-			CreatedAt:            output.CreatedAt,
-		}
+		responses[i] = toProgramResponse(output)
 	}
 
 	w.Header().Set("Content-Type", "application/json")
@@ -141,18 +238,34 @@ func (h *NCHandler) DeployProgram(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	idempotencyKey := req.IdempotencyKey
+	if idempotencyKey == "" {
+		idempotencyKey = r.Header.Get("Idempotency-Key")
+	}
+
 	input := application.DeployProgramInput{
-		ProgramID: req.ProgramID,
-		MachineID: machineID,
+		ProgramID:         req.ProgramID,
+		MachineID:         machineID,
+		ProductionOrderID: req.ProductionOrderID,
+		IdempotencyKey:    idempotencyKey,
 	}
 
-	if err := h.useCase.DeployProgram(r.Context(), input); err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+	output, err := h.useCase.DeployProgram(r.Context(), input)
+	if err != nil {
+		http.Error(w, err.Error(), errorStatus(err))
 		return
 	}
 
-	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(map[string]string{"status": "Program deployed successfully"})
+	// 転送は待ち行列のワーカーが行うため、受付結果を返して状態はポーリングで確認させる
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Location", "/api/v1/nc/deployments/"+output.ID)
+	if output.Replayed {
+		w.Header().Set("Idempotent-Replayed", "true")
+		w.WriteHeader(http.StatusOK)
+	} else {
+		w.WriteHeader(http.StatusAccepted)
+	}
+	json.NewEncoder(w).Encode(toDeploymentResponse(output))
 }
 
 func (h *NCHandler) GetMachineStatus(w http.ResponseWriter, r *http.Request) {
@@ -173,6 +286,7 @@ func (h *NCHandler) GetMachineStatus(w http.ResponseWriter, r *http.Request) {
 		RunningState:  output.RunningState,
 		CurrentJobID:  output.CurrentJobID,
 		LastHeartbeat: output.LastHeartbeat,
+		ErrorMessage:  output.ErrorMessage,
 	}
 
 	w.Header().Set("Content-Type", "application/json")
@@ -180,13 +294,283 @@ func (h *NCHandler) GetMachineStatus(w http.ResponseWriter, r *http.Request) {
 }
 
 func (h *NCHandler) UpdateMachineStatus(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	machineID := vars["id"]
+
 	var req MachineStatusRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		http.Error(w, "Invalid request body", http.StatusBadRequest)
 		return
 	}
 
-	// TODO: Convert request to domain.MachineStatus and update
+	input := application.UpdateMachineStatusInput{
+		MachineID:     machineID,
+		RunningState:  req.RunningState,
+		CurrentJobID:  req.CurrentJobID,
+		ErrorMessage:  req.ErrorMessage,
+		ProducedCount: req.ProducedCount,
+	}
+
+	if err := h.useCase.UpdateMachineStatus(r.Context(), input); err != nil {
+		http.Error(w, err.Error(), errorStatus(err))
+		return
+	}
+
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(map[string]string{"status": "Machine status updated"})
-}
\ No newline at end of file
+}
+
+// RecordHeartbeat はコントローラー／エッジエージェントからの生存通知を受け付ける
+func (h *NCHandler) RecordHeartbeat(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+
+	if err := h.useCase.RecordHeartbeat(r.Context(), vars["id"]); err != nil {
+		http.Error(w, err.Error(), errorStatus(err))
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GetMachineStatusHistory は稼働状態の変化履歴を返す。from/to は RFC3339（省略時は直近24時間）
+func (h *NCHandler) GetMachineStatusHistory(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	machineID := vars["id"]
+
+	from, to, err := parseTimeRange(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	outputs, err := h.useCase.GetMachineStatusHistory(r.Context(), machineID, from, to)
+	if err != nil {
+		http.Error(w, err.Error(), errorStatus(err))
+		return
+	}
+
+	responses := make([]MachineStatusChangeResponse, len(outputs))
+	for i, output := range outputs {
+		responses[i] = MachineStatusChangeResponse{
+			MachineID:    output.MachineID,
+			FromState:    output.FromState,
+			ToState:      output.ToState,
+			JobID:        output.JobID,
+			ErrorMessage: output.ErrorMessage,
+			ChangedAt:    output.ChangedAt,
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(responses)
+}
+
+func (h *NCHandler) RegisterMachine(w http.ResponseWriter, r *http.Request) {
+	var req MachineRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	output, err := h.useCase.RegisterMachine(r.Context(), toMachineInput(req))
+	if err != nil {
+		http.Error(w, err.Error(), errorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(toMachineResponse(output))
+}
+
+func (h *NCHandler) GetAllMachines(w http.ResponseWriter, r *http.Request) {
+	outputs, err := h.useCase.GetAllMachines(r.Context())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	responses := make([]MachineResponse, len(outputs))
+	for i, output := range outputs {
+		responses[i] = toMachineResponse(output)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(responses)
+}
+
+func (h *NCHandler) GetMachine(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+
+	output, err := h.useCase.GetMachine(r.Context(), vars["id"])
+	if err != nil {
+		http.Error(w, err.Error(), errorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(toMachineResponse(output))
+}
+
+func (h *NCHandler) UpdateMachine(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+
+	var req MachineRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	input := toMachineInput(req)
+	input.ID = vars["id"]
+
+	output, err := h.useCase.UpdateMachine(r.Context(), input)
+	if err != nil {
+		http.Error(w, err.Error(), errorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(toMachineResponse(output))
+}
+
+func (h *NCHandler) DecommissionMachine(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+
+	if err := h.useCase.DecommissionMachine(r.Context(), vars["id"]); err != nil {
+		http.Error(w, err.Error(), errorStatus(err))
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func toMachineInput(req MachineRequest) application.MachineInput {
+	return application.MachineInput{
+		ID:               req.ID,
+		Name:             req.Name,
+		IP:               req.IP,
+		Type:             req.Type,
+		Capabilities:     req.Capabilities,
+		AxisTravelX:      req.AxisTravelX,
+		AxisTravelY:      req.AxisTravelY,
+		AxisTravelZ:      req.AxisTravelZ,
+		MaxSpindleSpeed:  req.MaxSpindleSpeed,
+		ToolMagazineSize: req.ToolMagazineSize,
+		ControllerModel:  req.ControllerModel,
+		Axes:             req.Axes,
+		ControlOptions:   req.ControlOptions,
+		Macros:           req.Macros,
+	}
+}
+
+func toMachineResponse(output *application.MachineOutput) MachineResponse {
+	return MachineResponse{
+		ID:               output.ID,
+		Name:             output.Name,
+		IP:               output.IP,
+		Type:             output.Type,
+		Capabilities:     output.Capabilities,
+		AxisTravelX:      output.AxisTravelX,
+		AxisTravelY:      output.AxisTravelY,
+		AxisTravelZ:      output.AxisTravelZ,
+		MaxSpindleSpeed:  output.MaxSpindleSpeed,
+		ToolMagazineSize: output.ToolMagazineSize,
+		ControllerModel:  output.ControllerModel,
+		Axes:             output.Axes,
+		ControlOptions:   output.ControlOptions,
+		Macros:           output.Macros,
+		RunningState:     output.RunningState,
+		DecommissionedAt: output.DecommissionedAt,
+		CreatedAt:        output.CreatedAt,
+		UpdatedAt:        output.UpdatedAt,
+	}
+}
+
+// errorStatus はドメインエラーをHTTPステータスに変換する
+func errorStatus(err error) int {
+	switch {
+	case errors.Is(err, domain.ErrMachineNotFound),
+		errors.Is(err, domain.ErrNCProgramNotFound),
+		errors.Is(err, domain.ErrConnectorConfigNotFound),
+		errors.Is(err, domain.ErrNCJobNotFound),
+		errors.Is(err, domain.ErrDeploymentNotFound),
+		errors.Is(err, domain.ErrToolItemNotFound),
+		errors.Is(err, domain.ErrToolAssemblyNotFound),
+		errors.Is(err, domain.ErrToolNotLoaded),
+		errors.Is(err, domain.ErrOffsetNotFound),
+		errors.Is(err, domain.ErrOffsetChangeNotFound),
+		errors.Is(err, domain.ErrAlarmCatalogEntryNotFound),
+		errors.Is(err, domain.ErrSignerKeyNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, domain.ErrMachineAlreadyExists),
+		errors.Is(err, domain.ErrMachineDecommissioned),
+		errors.Is(err, domain.ErrMachineNotAvailable),
+		errors.Is(err, domain.ErrInvalidStatusTransition),
+		errors.Is(err, domain.ErrMachineOffline),
+		errors.Is(err, domain.ErrNCJobFinished),
+		errors.Is(err, domain.ErrInvalidProgramStatus),
+		errors.Is(err, domain.ErrIdempotencyKeyConflict),
+		errors.Is(err, domain.ErrInvalidDeploymentStatus),
+		errors.Is(err, domain.ErrRequiredToolMissing),
+		errors.Is(err, domain.ErrToolWornOut),
+		errors.Is(err, domain.ErrInvalidOffsetChangeStatus),
+		errors.Is(err, domain.ErrOffsetVersionConflict),
+		errors.Is(err, domain.ErrSignerKeyAlreadyExists),
+		errors.Is(err, domain.ErrSignerKeyRevoked),
+		errors.Is(err, domain.ErrProgramNotSigned),
+		errors.Is(err, domain.ErrProgramHashMismatch),
+		errors.Is(err, domain.ErrProgramAlreadyExists):
+		return http.StatusConflict
+	case errors.Is(err, domain.ErrInvalidMachine),
+		errors.Is(err, domain.ErrInvalidMachineIP),
+		errors.Is(err, domain.ErrInvalidMachineType),
+		errors.Is(err, domain.ErrInvalidCapability),
+		errors.Is(err, domain.ErrInvalidMachineSpec),
+		errors.Is(err, domain.ErrInvalidControlSpec),
+		errors.Is(err, domain.ErrInvalidDialect),
+		errors.Is(err, domain.ErrUnsupportedTranslation),
+		errors.Is(err, domain.ErrInvalidRunningState),
+		errors.Is(err, domain.ErrInvalidConnectorConfig),
+		errors.Is(err, domain.ErrInvalidProducedCount),
+		errors.Is(err, domain.ErrIncompatibleProgram),
+		errors.Is(err, domain.ErrInvalidToolItem),
+		errors.Is(err, domain.ErrInvalidToolAssembly),
+		errors.Is(err, domain.ErrInvalidToolNumber),
+		errors.Is(err, domain.ErrInvalidToolUsage),
+		errors.Is(err, domain.ErrInvalidOffsetRegister),
+		errors.Is(err, domain.ErrInvalidOffsetValues),
+		errors.Is(err, domain.ErrInvalidOffsetChange),
+		errors.Is(err, domain.ErrInvalidAlarmCatalogEntry),
+		errors.Is(err, domain.ErrInvalidAlarmFilter),
+		errors.Is(err, domain.ErrInvalidSignerKey),
+		errors.Is(err, domain.ErrInvalidProgramSignature):
+		return http.StatusBadRequest
+	case errors.Is(err, domain.ErrOffsetSelfApproval),
+		errors.Is(err, domain.ErrSignerKeyOwnerMismatch):
+		return http.StatusForbidden
+	case errors.Is(err, domain.ErrTransferFailed),
+		errors.Is(err, domain.ErrOffsetPushFailed):
+		return http.StatusBadGateway
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// parseTimeRange はクエリの from/to（RFC3339）を読み取る。省略時は直近24時間
+func parseTimeRange(r *http.Request) (time.Time, time.Time, error) {
+	to := time.Now()
+	from := to.Add(-24 * time.Hour)
+	var err error
+	if v := r.URL.Query().Get("from"); v != "" {
+		if from, err = time.Parse(time.RFC3339, v); err != nil {
+			return from, to, errors.New("Invalid from parameter")
+		}
+	}
+	if v := r.URL.Query().Get("to"); v != "" {
+		if to, err = time.Parse(time.RFC3339, v); err != nil {
+			return from, to, errors.New("Invalid to parameter")
+		}
+	}
+	return from, to, nil
+}
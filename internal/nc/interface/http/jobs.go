@@ -0,0 +1,77 @@
+package http
+
+import (
+	"encoding/json"
+	"goNexttask/internal/nc/application"
+	"net/http"
+
+	"github.com/gorilla/mux"
+)
+
+type NCJobResponse struct {
+	ID                string   `json:"id"`
+	ProgramID         string   `json:"programId"`
+	ProgramHash       string   `json:"programHash"`
+	MachineID         string   `json:"machineId"`
+	ProductionOrderID string   `json:"productionOrderId,omitempty"`
+	Status            string   `json:"status"`
+	StartedAt         string   `json:"startedAt"`
+	EndedAt           string   `json:"endedAt,omitempty"`
+	ProducedCount     int      `json:"producedCount"`
+	Alarms            []string `json:"alarms,omitempty"`
+	ErrorMessage      string   `json:"errorMessage,omitempty"`
+}
+
+func (h *NCHandler) GetJob(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+
+	output, err := h.useCase.GetJob(r.Context(), vars["id"])
+	if err != nil {
+		http.Error(w, err.Error(), errorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(toNCJobResponse(output))
+}
+
+// GetMachineJobs は機械で実行されたジョブを開始日時の新しい順に返す。from/to は RFC3339（省略時は直近24時間）
+func (h *NCHandler) GetMachineJobs(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+
+	from, to, err := parseTimeRange(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	outputs, err := h.useCase.GetMachineJobs(r.Context(), vars["id"], from, to)
+	if err != nil {
+		http.Error(w, err.Error(), errorStatus(err))
+		return
+	}
+
+	responses := make([]NCJobResponse, len(outputs))
+	for i, output := range outputs {
+		responses[i] = toNCJobResponse(output)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(responses)
+}
+
+func toNCJobResponse(output *application.NCJobOutput) NCJobResponse {
+	return NCJobResponse{
+		ID:                output.ID,
+		ProgramID:         output.ProgramID,
+		ProgramHash:       output.ProgramHash,
+		MachineID:         output.MachineID,
+		ProductionOrderID: output.ProductionOrderID,
+		Status:            output.Status,
+		StartedAt:         output.StartedAt,
+		EndedAt:           output.EndedAt,
+		ProducedCount:     output.ProducedCount,
+		Alarms:            output.Alarms,
+		ErrorMessage:      output.ErrorMessage,
+	}
+}
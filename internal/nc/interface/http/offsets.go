@@ -0,0 +1,215 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"goNexttask/internal/nc/application"
+	"goNexttask/pkg/auth"
+	"net/http"
+
+	"github.com/gorilla/mux"
+)
+
+// オフセット変更の承認・却下ができるロール
+var offsetApproverRoles = []string{"admin", "engineer"}
+
+type ProposeOffsetChangeRequest struct {
+	Register string             `json:"register"`
+	Mode     string             `json:"mode"`
+	Values   map[string]float64 `json:"values"`
+	Reason   string             `json:"reason"`
+}
+
+type ReviewOffsetChangeRequest struct {
+	Comment string `json:"comment"`
+}
+
+type MachineOffsetResponse struct {
+	MachineID string             `json:"machineId"`
+	Register  string             `json:"register"`
+	Kind      string             `json:"kind"`
+	Values    map[string]float64 `json:"values"`
+	Version   int                `json:"version"`
+	ChangeID  string             `json:"changeId,omitempty"`
+	UpdatedBy string             `json:"updatedBy,omitempty"`
+	UpdatedAt string             `json:"updatedAt"`
+}
+
+type OffsetChangeResponse struct {
+	ID            string             `json:"id"`
+	MachineID     string             `json:"machineId"`
+	Register      string             `json:"register"`
+	Kind          string             `json:"kind"`
+	Mode          string             `json:"mode"`
+	Values        map[string]float64 `json:"values"`
+	BaseVersion   int                `json:"baseVersion"`
+	Previous      map[string]float64 `json:"previous,omitempty"`
+	Result        map[string]float64 `json:"result,omitempty"`
+	Status        string             `json:"status"`
+	Reason        string             `json:"reason"`
+	ProposedBy    string             `json:"proposedBy"`
+	ProposedAt    string             `json:"proposedAt"`
+	ReviewedBy    string             `json:"reviewedBy,omitempty"`
+	ReviewedAt    string             `json:"reviewedAt,omitempty"`
+	ReviewComment string             `json:"reviewComment,omitempty"`
+	AppliedAt     string             `json:"appliedAt,omitempty"`
+	ErrorMessage  string             `json:"errorMessage,omitempty"`
+}
+
+func (h *NCHandler) GetMachineOffsets(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+
+	outputs, err := h.useCase.GetMachineOffsets(r.Context(), vars["id"])
+	if err != nil {
+		http.Error(w, err.Error(), errorStatus(err))
+		return
+	}
+
+	responses := make([]MachineOffsetResponse, len(outputs))
+	for i, output := range outputs {
+		responses[i] = toMachineOffsetResponse(output)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(responses)
+}
+
+// ProposeOffsetChange はオフセット変更を提案する。提案者は認証ユーザー
+func (h *NCHandler) ProposeOffsetChange(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+
+	claims, ok := auth.GetUserFromContext(r.Context())
+	if !ok {
+		http.Error(w, "User not found in context", http.StatusUnauthorized)
+		return
+	}
+
+	var req ProposeOffsetChangeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	output, err := h.useCase.ProposeOffsetChange(r.Context(), application.ProposeOffsetChangeInput{
+		MachineID:  vars["id"],
+		Register:   req.Register,
+		Mode:       req.Mode,
+		Values:     req.Values,
+		Reason:     req.Reason,
+		ProposedBy: claims.Email,
+	})
+	if err != nil {
+		http.Error(w, err.Error(), errorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(toOffsetChangeResponse(output))
+}
+
+// GetOffsetChanges はオフセット変更の履歴を返す。register / status で絞り込める
+func (h *NCHandler) GetOffsetChanges(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	query := r.URL.Query()
+
+	outputs, err := h.useCase.GetOffsetChanges(r.Context(), vars["id"], query.Get("register"), query.Get("status"))
+	if err != nil {
+		http.Error(w, err.Error(), errorStatus(err))
+		return
+	}
+
+	responses := make([]OffsetChangeResponse, len(outputs))
+	for i, output := range outputs {
+		responses[i] = toOffsetChangeResponse(output)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(responses)
+}
+
+func (h *NCHandler) GetOffsetChange(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+
+	output, err := h.useCase.GetOffsetChange(r.Context(), vars["id"])
+	if err != nil {
+		http.Error(w, err.Error(), errorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(toOffsetChangeResponse(output))
+}
+
+// ApproveOffsetChange は変更を承認してコントローラーへ書き込む。書き込みに失敗した場合は 502
+func (h *NCHandler) ApproveOffsetChange(w http.ResponseWriter, r *http.Request) {
+	h.reviewOffsetChange(w, r, h.useCase.ApproveOffsetChange)
+}
+
+func (h *NCHandler) RejectOffsetChange(w http.ResponseWriter, r *http.Request) {
+	h.reviewOffsetChange(w, r, h.useCase.RejectOffsetChange)
+}
+
+func (h *NCHandler) reviewOffsetChange(w http.ResponseWriter, r *http.Request, review func(ctx context.Context, changeID, reviewedBy, comment string) (*application.OffsetChangeOutput, error)) {
+	vars := mux.Vars(r)
+
+	claims, ok := auth.GetUserFromContext(r.Context())
+	if !ok || !auth.HasAnyRole(r.Context(), offsetApproverRoles...) {
+		http.Error(w, "Insufficient permissions to review offset changes", http.StatusForbidden)
+		return
+	}
+
+	// コメントは任意のため本文は省略できる
+	var req ReviewOffsetChangeRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+	}
+
+	output, err := review(r.Context(), vars["id"], claims.Email, req.Comment)
+	if err != nil {
+		http.Error(w, err.Error(), errorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(toOffsetChangeResponse(output))
+}
+
+func toMachineOffsetResponse(output *application.MachineOffsetOutput) MachineOffsetResponse {
+	return MachineOffsetResponse{
+		MachineID: output.MachineID,
+		Register:  output.Register,
+		Kind:      output.Kind,
+		Values:    output.Values,
+		Version:   output.Version,
+		ChangeID:  output.ChangeID,
+		UpdatedBy: output.UpdatedBy,
+		UpdatedAt: output.UpdatedAt,
+	}
+}
+
+func toOffsetChangeResponse(output *application.OffsetChangeOutput) OffsetChangeResponse {
+	return OffsetChangeResponse{
+		ID:            output.ID,
+		MachineID:     output.MachineID,
+		Register:      output.Register,
+		Kind:          output.Kind,
+		Mode:          output.Mode,
+		Values:        output.Values,
+		BaseVersion:   output.BaseVersion,
+		Previous:      output.Previous,
+		Result:        output.Result,
+		Status:        output.Status,
+		Reason:        output.Reason,
+		ProposedBy:    output.ProposedBy,
+		ProposedAt:    output.ProposedAt,
+		ReviewedBy:    output.ReviewedBy,
+		ReviewedAt:    output.ReviewedAt,
+		ReviewComment: output.ReviewComment,
+		AppliedAt:     output.AppliedAt,
+		ErrorMessage:  output.ErrorMessage,
+	}
+}
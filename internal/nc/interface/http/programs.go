@@ -0,0 +1,202 @@
+package http
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"goNexttask/internal/nc/application"
+	"goNexttask/pkg/auth"
+	"net/http"
+
+	"github.com/gorilla/mux"
+)
+
+// プログラムのリリース承認ができるロール
+var programApproverRoles = []string{"admin", "engineer"}
+
+type ProgramCandidateResponse struct {
+	Program         ProgramResponse `json:"program"`
+	Eligible        bool            `json:"eligible"`
+	FirstPassYield  *float64        `json:"firstPassYield,omitempty"`
+	InspectionCount int             `json:"inspectionCount"`
+	CompletedJobs   int             `json:"completedJobs"`
+	AvgCycleTimeSec *float64        `json:"avgCycleTimeSec,omitempty"`
+	Reasons         []string        `json:"reasons"`
+}
+
+type ProgramRecommendationResponse struct {
+	PartID      string                     `json:"partId"`
+	MachineID   string                     `json:"machineId"`
+	Recommended *ProgramCandidateResponse  `json:"recommended"`
+	Candidates  []ProgramCandidateResponse `json:"candidates"`
+}
+
+// SignProgramRequest は承認者の鍵による署名。signature は GET /nc/programs/{id}/signature の payload に対する
+// Ed25519 署名の base64
+type SignProgramRequest struct {
+	SignerKeyID string `json:"signerKeyId"`
+	Signature   string `json:"signature"`
+}
+
+type ProgramSignatureResponse struct {
+	ProgramID   string `json:"programId"`
+	FileHash    string `json:"fileHash"`
+	Payload     string `json:"payload"`
+	Signature   string `json:"signature,omitempty"`
+	SignerKeyID string `json:"signerKeyId,omitempty"`
+	SignedBy    string `json:"signedBy,omitempty"`
+	SignedAt    string `json:"signedAt,omitempty"`
+	Valid       bool   `json:"valid"`
+	Error       string `json:"error,omitempty"`
+}
+
+// ApproveProgram は承認者の署名を検証してプログラムをリリースする
+func (h *NCHandler) ApproveProgram(w http.ResponseWriter, r *http.Request) {
+	input, ok := decodeSignProgramInput(w, r, "Insufficient permissions to approve NC programs")
+	if !ok {
+		return
+	}
+
+	output, err := h.useCase.ApproveProgram(r.Context(), input)
+	if err != nil {
+		http.Error(w, err.Error(), errorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(toProgramResponse(output))
+}
+
+// SignProgram はリリース済みのプログラムに署名し直す
+func (h *NCHandler) SignProgram(w http.ResponseWriter, r *http.Request) {
+	input, ok := decodeSignProgramInput(w, r, "Insufficient permissions to sign NC programs")
+	if !ok {
+		return
+	}
+
+	output, err := h.useCase.SignProgram(r.Context(), input)
+	if err != nil {
+		http.Error(w, err.Error(), errorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(toProgramResponse(output))
+}
+
+// GetProgramSignature は署名対象の payload と現在の署名の検証結果を返す
+func (h *NCHandler) GetProgramSignature(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+
+	output, err := h.useCase.GetProgramSignature(r.Context(), vars["id"])
+	if err != nil {
+		http.Error(w, err.Error(), errorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(ProgramSignatureResponse{
+		ProgramID:   output.ProgramID,
+		FileHash:    output.FileHash,
+		Payload:     output.Payload,
+		Signature:   output.Signature,
+		SignerKeyID: output.SignerKeyID,
+		SignedBy:    output.SignedBy,
+		SignedAt:    output.SignedAt,
+		Valid:       output.Valid,
+		Error:       output.Error,
+	})
+}
+
+// decodeSignProgramInput は承認ロールを確認し、署名者を認証ユーザーとして署名を読み取る
+func decodeSignProgramInput(w http.ResponseWriter, r *http.Request, forbidden string) (application.SignProgramInput, bool) {
+	claims, ok := auth.GetUserFromContext(r.Context())
+	if !ok || !auth.HasAnyRole(r.Context(), programApproverRoles...) {
+		http.Error(w, forbidden, http.StatusForbidden)
+		return application.SignProgramInput{}, false
+	}
+
+	var req SignProgramRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return application.SignProgramInput{}, false
+	}
+	signature, err := base64.StdEncoding.DecodeString(req.Signature)
+	if err != nil || req.SignerKeyID == "" || len(signature) == 0 {
+		http.Error(w, "signerKeyId and base64 signature are required", http.StatusBadRequest)
+		return application.SignProgramInput{}, false
+	}
+
+	return application.SignProgramInput{
+		ProgramID:   mux.Vars(r)["id"],
+		SignedBy:    claims.Email,
+		SignerKeyID: req.SignerKeyID,
+		Signature:   signature,
+	}, true
+}
+
+// RecommendProgram は部品と機械に最適なプログラムを選定理由とともに返す。
+// 条件を満たすプログラムが無い場合 recommended は null で、各候補の除外理由を返す
+func (h *NCHandler) RecommendProgram(w http.ResponseWriter, r *http.Request) {
+	partID := r.URL.Query().Get("partId")
+	machineID := r.URL.Query().Get("machineId")
+	if partID == "" || machineID == "" {
+		http.Error(w, "partId and machineId are required", http.StatusBadRequest)
+		return
+	}
+
+	output, err := h.useCase.RecommendProgram(r.Context(), partID, machineID)
+	if err != nil {
+		http.Error(w, err.Error(), errorStatus(err))
+		return
+	}
+
+	response := ProgramRecommendationResponse{
+		PartID:     output.PartID,
+		MachineID:  output.MachineID,
+		Candidates: make([]ProgramCandidateResponse, len(output.Candidates)),
+	}
+	for i, candidate := range output.Candidates {
+		response.Candidates[i] = toProgramCandidateResponse(candidate)
+		if candidate == output.Selected {
+			response.Recommended = &response.Candidates[i]
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+func toProgramCandidateResponse(output *application.ProgramCandidateOutput) ProgramCandidateResponse {
+	return ProgramCandidateResponse{
+		Program:         toProgramResponse(output.Program),
+		Eligible:        output.Eligible,
+		FirstPassYield:  output.FirstPassYield,
+		InspectionCount: output.InspectionCount,
+		CompletedJobs:   output.CompletedJobs,
+		AvgCycleTimeSec: output.AvgCycleTimeSec,
+		Reasons:         output.Reasons,
+	}
+}
+
+func toProgramResponse(output *application.NCProgramOutput) ProgramResponse {
+	return ProgramResponse{
+		ID:                   output.ID,
+		Name:                 output.Name,
+		Version:              output.Version,
+		PartID:               output.PartID,
+		Dialect:              output.Dialect,
+		SourceProgramID:      output.SourceProgramID,
+		FileHash:             output.FileHash,
+		Signature:            output.Signature,
+		SignerKeyID:          output.SignerKeyID,
+		SignedBy:             output.SignedBy,
+		SignedAt:             output.SignedAt,
+		MachineCompatibility: output.MachineCompatibility,
+		RequiredCapabilities: output.RequiredCapabilities,
+		Status:               output.Status,
+		ApprovedBy:           output.ApprovedBy,
+		ApprovedAt:           output.ApprovedAt,
+		CreatedBy:            output.CreatedBy,
+		CreatedAt:            output.CreatedAt,
+	}
+}
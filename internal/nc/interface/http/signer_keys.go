@@ -0,0 +1,129 @@
+package http
+
+import (
+	"encoding/json"
+	"goNexttask/internal/nc/application"
+	"goNexttask/pkg/auth"
+	"net/http"
+
+	"github.com/gorilla/mux"
+)
+
+// SignerKeyRequest は公開鍵の登録。owner を省略すると認証ユーザーの鍵として登録する（他人の鍵は admin のみ）
+type SignerKeyRequest struct {
+	Owner     string `json:"owner,omitempty"`
+	PublicKey string `json:"publicKey"`
+}
+
+type RevokeSignerKeyRequest struct {
+	Reason string `json:"reason"`
+}
+
+type SignerKeyResponse struct {
+	ID           string `json:"id"`
+	Owner        string `json:"owner"`
+	PublicKey    string `json:"publicKey"`
+	Status       string `json:"status"`
+	CreatedBy    string `json:"createdBy,omitempty"`
+	CreatedAt    string `json:"createdAt"`
+	RevokedBy    string `json:"revokedBy,omitempty"`
+	RevokedAt    string `json:"revokedAt,omitempty"`
+	RevokeReason string `json:"revokeReason,omitempty"`
+}
+
+func (h *NCHandler) RegisterSignerKey(w http.ResponseWriter, r *http.Request) {
+	claims, ok := auth.GetUserFromContext(r.Context())
+	if !ok || !auth.HasAnyRole(r.Context(), programApproverRoles...) {
+		http.Error(w, "Insufficient permissions to register signer keys", http.StatusForbidden)
+		return
+	}
+
+	var req SignerKeyRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	owner := req.Owner
+	if owner == "" {
+		owner = claims.Email
+	}
+	if owner != claims.Email && !auth.HasAnyRole(r.Context(), "admin") {
+		http.Error(w, "Only admin can register signer keys for other users", http.StatusForbidden)
+		return
+	}
+
+	output, err := h.useCase.RegisterSignerKey(r.Context(), application.RegisterSignerKeyInput{
+		Owner:     owner,
+		PublicKey: req.PublicKey,
+		CreatedBy: claims.Email,
+	})
+	if err != nil {
+		http.Error(w, err.Error(), errorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(toSignerKeyResponse(output))
+}
+
+func (h *NCHandler) GetSignerKeys(w http.ResponseWriter, r *http.Request) {
+	outputs, err := h.useCase.GetSignerKeys(r.Context(), r.URL.Query().Get("owner"))
+	if err != nil {
+		http.Error(w, err.Error(), errorStatus(err))
+		return
+	}
+
+	responses := make([]SignerKeyResponse, len(outputs))
+	for i, output := range outputs {
+		responses[i] = toSignerKeyResponse(output)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(responses)
+}
+
+// RevokeSignerKey は署名鍵を失効させる。本人または admin のみ
+func (h *NCHandler) RevokeSignerKey(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+
+	claims, ok := auth.GetUserFromContext(r.Context())
+	if !ok {
+		http.Error(w, "User not found in context", http.StatusUnauthorized)
+		return
+	}
+
+	var req RevokeSignerKeyRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	output, err := h.useCase.RevokeSignerKey(r.Context(), application.RevokeSignerKeyInput{
+		KeyID:     vars["id"],
+		RevokedBy: claims.Email,
+		Reason:    req.Reason,
+		AnyOwner:  auth.HasAnyRole(r.Context(), "admin"),
+	})
+	if err != nil {
+		http.Error(w, err.Error(), errorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(toSignerKeyResponse(output))
+}
+
+func toSignerKeyResponse(output *application.SignerKeyOutput) SignerKeyResponse {
+	return SignerKeyResponse{
+		ID:           output.ID,
+		Owner:        output.Owner,
+		PublicKey:    output.PublicKey,
+		Status:       output.Status,
+		CreatedBy:    output.CreatedBy,
+		CreatedAt:    output.CreatedAt,
+		RevokedBy:    output.RevokedBy,
+		RevokedAt:    output.RevokedAt,
+		RevokeReason: output.RevokeReason,
+	}
+}
@@ -0,0 +1,128 @@
+package http
+
+import (
+	"encoding/json"
+	"goNexttask/internal/nc/application"
+	"net/http"
+
+	"github.com/gorilla/mux"
+)
+
+type ConnectorConfigRequest struct {
+	Protocol       string            `json:"protocol"`
+	Endpoint       string            `json:"endpoint"`
+	DeviceName     string            `json:"deviceName"`
+	PollIntervalMs int               `json:"pollIntervalMs"`
+	NodeIDs        map[string]string `json:"nodeIds"`
+	StateMapping   map[string]string `json:"stateMapping"`
+	Enabled        bool              `json:"enabled"`
+}
+
+type ConnectorConfigResponse struct {
+	MachineID      string            `json:"machineId"`
+	Protocol       string            `json:"protocol"`
+	Endpoint       string            `json:"endpoint"`
+	DeviceName     string            `json:"deviceName,omitempty"`
+	PollIntervalMs int64             `json:"pollIntervalMs"`
+	NodeIDs        map[string]string `json:"nodeIds,omitempty"`
+	StateMapping   map[string]string `json:"stateMapping,omitempty"`
+	Enabled        bool              `json:"enabled"`
+	UpdatedAt      string            `json:"updatedAt"`
+}
+
+type TelemetryPointResponse struct {
+	DataItem     string   `json:"dataItem"`
+	Category     string   `json:"category"`
+	Value        string   `json:"value"`
+	NumericValue *float64 `json:"numericValue,omitempty"`
+	Source       string   `json:"source"`
+	Timestamp    string   `json:"timestamp"`
+}
+
+func (h *NCHandler) ConfigureConnector(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+
+	var req ConnectorConfigRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	input := application.ConnectorConfigInput{
+		MachineID:      vars["id"],
+		Protocol:       req.Protocol,
+		Endpoint:       req.Endpoint,
+		DeviceName:     req.DeviceName,
+		PollIntervalMs: req.PollIntervalMs,
+		NodeIDs:        req.NodeIDs,
+		StateMapping:   req.StateMapping,
+		Enabled:        req.Enabled,
+	}
+
+	output, err := h.useCase.ConfigureConnector(r.Context(), input)
+	if err != nil {
+		http.Error(w, err.Error(), errorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(toConnectorConfigResponse(output))
+}
+
+func (h *NCHandler) GetConnectorConfig(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+
+	output, err := h.useCase.GetConnectorConfig(r.Context(), vars["id"])
+	if err != nil {
+		http.Error(w, err.Error(), errorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(toConnectorConfigResponse(output))
+}
+
+func (h *NCHandler) GetTelemetry(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+
+	from, to, err := parseTimeRange(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	outputs, err := h.useCase.GetTelemetry(r.Context(), vars["id"], r.URL.Query().Get("dataItem"), from, to)
+	if err != nil {
+		http.Error(w, err.Error(), errorStatus(err))
+		return
+	}
+
+	responses := make([]TelemetryPointResponse, len(outputs))
+	for i, output := range outputs {
+		responses[i] = TelemetryPointResponse{
+			DataItem:     output.DataItem,
+			Category:     output.Category,
+			Value:        output.Value,
+			NumericValue: output.NumericValue,
+			Source:       output.Source,
+			Timestamp:    output.Timestamp,
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(responses)
+}
+
+func toConnectorConfigResponse(output *application.ConnectorConfigOutput) ConnectorConfigResponse {
+	return ConnectorConfigResponse{
+		MachineID:      output.MachineID,
+		Protocol:       output.Protocol,
+		Endpoint:       output.Endpoint,
+		DeviceName:     output.DeviceName,
+		PollIntervalMs: output.PollIntervalMs,
+		NodeIDs:        output.NodeIDs,
+		StateMapping:   output.StateMapping,
+		Enabled:        output.Enabled,
+		UpdatedAt:      output.UpdatedAt,
+	}
+}
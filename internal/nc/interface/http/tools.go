@@ -0,0 +1,295 @@
+package http
+
+import (
+	"encoding/json"
+	"goNexttask/internal/nc/application"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+)
+
+type ToolItemRequest struct {
+	Kind        string `json:"kind"`
+	Code        string `json:"code"`
+	Description string `json:"description"`
+}
+
+type ToolItemResponse struct {
+	ID          string `json:"id"`
+	Kind        string `json:"kind"`
+	Code        string `json:"code"`
+	Description string `json:"description,omitempty"`
+	CreatedAt   string `json:"createdAt"`
+}
+
+type ToolAssemblyRequest struct {
+	Name           string  `json:"name"`
+	HolderID       string  `json:"holderId,omitempty"`
+	CuttingItemID  string  `json:"cuttingItemId"`
+	LifeCutTimeSec float64 `json:"lifeCutTimeSec"`
+	LifePartCount  int     `json:"lifePartCount"`
+}
+
+type ToolAssemblyResponse struct {
+	ID             string  `json:"id"`
+	Name           string  `json:"name"`
+	HolderID       string  `json:"holderId,omitempty"`
+	CuttingItemID  string  `json:"cuttingItemId"`
+	LifeCutTimeSec float64 `json:"lifeCutTimeSec"`
+	LifePartCount  int     `json:"lifePartCount"`
+	CreatedAt      string  `json:"createdAt"`
+}
+
+type ToolRequirementResponse struct {
+	ToolNumber  int    `json:"toolNumber"`
+	Description string `json:"description,omitempty"`
+}
+
+type LoadToolRequest struct {
+	AssemblyID string `json:"assemblyId"`
+}
+
+type ToolUsageRequest struct {
+	CutTimeSec float64 `json:"cutTimeSec"`
+	PartCount  int     `json:"partCount"`
+}
+
+type MagazineToolResponse struct {
+	MachineID           string  `json:"machineId"`
+	ToolNumber          int     `json:"toolNumber"`
+	AssemblyID          string  `json:"assemblyId"`
+	LifeCutTimeSec      float64 `json:"lifeCutTimeSec"`
+	LifePartCount       int     `json:"lifePartCount"`
+	CutTimeSec          float64 `json:"cutTimeSec"`
+	PartCount           int     `json:"partCount"`
+	RemainingCutTimeSec float64 `json:"remainingCutTimeSec"`
+	RemainingPartCount  int     `json:"remainingPartCount"`
+	WornOut             bool    `json:"wornOut"`
+	LoadedAt            string  `json:"loadedAt"`
+	UpdatedAt           string  `json:"updatedAt"`
+}
+
+func (h *NCHandler) CreateToolItem(w http.ResponseWriter, r *http.Request) {
+	var req ToolItemRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	output, err := h.useCase.CreateToolItem(r.Context(), application.ToolItemInput{
+		Kind:        req.Kind,
+		Code:        req.Code,
+		Description: req.Description,
+	})
+	if err != nil {
+		http.Error(w, err.Error(), errorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(toToolItemResponse(output))
+}
+
+func (h *NCHandler) GetToolItems(w http.ResponseWriter, r *http.Request) {
+	outputs, err := h.useCase.GetToolItems(r.Context())
+	if err != nil {
+		http.Error(w, err.Error(), errorStatus(err))
+		return
+	}
+
+	responses := make([]ToolItemResponse, len(outputs))
+	for i, output := range outputs {
+		responses[i] = toToolItemResponse(output)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(responses)
+}
+
+func (h *NCHandler) CreateToolAssembly(w http.ResponseWriter, r *http.Request) {
+	var req ToolAssemblyRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	output, err := h.useCase.CreateToolAssembly(r.Context(), application.ToolAssemblyInput{
+		Name:           req.Name,
+		HolderID:       req.HolderID,
+		CuttingItemID:  req.CuttingItemID,
+		LifeCutTimeSec: req.LifeCutTimeSec,
+		LifePartCount:  req.LifePartCount,
+	})
+	if err != nil {
+		http.Error(w, err.Error(), errorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(toToolAssemblyResponse(output))
+}
+
+func (h *NCHandler) GetToolAssemblies(w http.ResponseWriter, r *http.Request) {
+	outputs, err := h.useCase.GetToolAssemblies(r.Context())
+	if err != nil {
+		http.Error(w, err.Error(), errorStatus(err))
+		return
+	}
+
+	responses := make([]ToolAssemblyResponse, len(outputs))
+	for i, output := range outputs {
+		responses[i] = toToolAssemblyResponse(output)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(responses)
+}
+
+func (h *NCHandler) GetProgramTools(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+
+	outputs, err := h.useCase.GetProgramTools(r.Context(), vars["id"], r.URL.Query().Get("machineId"))
+	if err != nil {
+		http.Error(w, err.Error(), errorStatus(err))
+		return
+	}
+
+	responses := make([]ToolRequirementResponse, len(outputs))
+	for i, output := range outputs {
+		responses[i] = ToolRequirementResponse{
+			ToolNumber:  output.ToolNumber,
+			Description: output.Description,
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(responses)
+}
+
+func (h *NCHandler) GetMagazine(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+
+	outputs, err := h.useCase.GetMagazine(r.Context(), vars["id"])
+	if err != nil {
+		http.Error(w, err.Error(), errorStatus(err))
+		return
+	}
+
+	responses := make([]MagazineToolResponse, len(outputs))
+	for i, output := range outputs {
+		responses[i] = toMagazineToolResponse(output)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(responses)
+}
+
+func (h *NCHandler) LoadTool(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+
+	toolNumber, err := strconv.Atoi(vars["toolNumber"])
+	if err != nil {
+		http.Error(w, "Invalid tool number", http.StatusBadRequest)
+		return
+	}
+
+	var req LoadToolRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	output, err := h.useCase.LoadTool(r.Context(), vars["id"], toolNumber, req.AssemblyID)
+	if err != nil {
+		http.Error(w, err.Error(), errorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(toMagazineToolResponse(output))
+}
+
+func (h *NCHandler) UnloadTool(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+
+	toolNumber, err := strconv.Atoi(vars["toolNumber"])
+	if err != nil {
+		http.Error(w, "Invalid tool number", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.useCase.UnloadTool(r.Context(), vars["id"], toolNumber); err != nil {
+		http.Error(w, err.Error(), errorStatus(err))
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ReportToolUsage は工具ごとの切削時間(秒)と加工数を加算する。寿命に達すると ToolLifeExpired を発行する
+func (h *NCHandler) ReportToolUsage(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+
+	toolNumber, err := strconv.Atoi(vars["toolNumber"])
+	if err != nil {
+		http.Error(w, "Invalid tool number", http.StatusBadRequest)
+		return
+	}
+
+	var req ToolUsageRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	output, err := h.useCase.ReportToolUsage(r.Context(), vars["id"], toolNumber, req.CutTimeSec, req.PartCount)
+	if err != nil {
+		http.Error(w, err.Error(), errorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(toMagazineToolResponse(output))
+}
+
+func toToolItemResponse(output *application.ToolItemOutput) ToolItemResponse {
+	return ToolItemResponse{
+		ID:          output.ID,
+		Kind:        output.Kind,
+		Code:        output.Code,
+		Description: output.Description,
+		CreatedAt:   output.CreatedAt,
+	}
+}
+
+func toToolAssemblyResponse(output *application.ToolAssemblyOutput) ToolAssemblyResponse {
+	return ToolAssemblyResponse{
+		ID:             output.ID,
+		Name:           output.Name,
+		HolderID:       output.HolderID,
+		CuttingItemID:  output.CuttingItemID,
+		LifeCutTimeSec: output.LifeCutTimeSec,
+		LifePartCount:  output.LifePartCount,
+		CreatedAt:      output.CreatedAt,
+	}
+}
+
+func toMagazineToolResponse(output *application.MagazineToolOutput) MagazineToolResponse {
+	return MagazineToolResponse{
+		MachineID:           output.MachineID,
+		ToolNumber:          output.ToolNumber,
+		AssemblyID:          output.AssemblyID,
+		LifeCutTimeSec:      output.LifeCutTimeSec,
+		LifePartCount:       output.LifePartCount,
+		CutTimeSec:          output.CutTimeSec,
+		PartCount:           output.PartCount,
+		RemainingCutTimeSec: output.RemainingCutTimeSec,
+		RemainingPartCount:  output.RemainingPartCount,
+		WornOut:             output.WornOut,
+		LoadedAt:            output.LoadedAt,
+		UpdatedAt:           output.UpdatedAt,
+	}
+}
@@ -20,6 +20,9 @@ type ProductionOrderOutput struct {
 	OrderNumber      string
 	PartID           string
 	Quantity         int
+	GoodQuantity     int
+	ReworkQuantity   int
+	ScrapQuantity    int
 	Status           string
 	PlannedStartDate time.Time
 	PlannedEndDate   time.Time
@@ -27,6 +30,15 @@ type ProductionOrderOutput struct {
 	UpdatedAt        time.Time
 }
 
+// RecordQualityDispositionInput は品質の不適合処置で確定した数量。DispositionID は処置の ID（NCR の ID）
+type RecordQualityDispositionInput struct {
+	OrderID       string
+	DispositionID string
+	Good          int
+	Rework        int
+	Scrap         int
+}
+
 type ProductionUseCase struct {
 	repo               domain.ProductionOrderRepository
 	schedulingService  *domain.ProductionSchedulingService
@@ -63,6 +75,9 @@ func (uc *ProductionUseCase) CreateProductionOrder(ctx context.Context, input Cr
 		OrderNumber:      order.OrderNumber,
 		PartID:           string(order.PartID),
 		Quantity:         order.Quantity,
+		GoodQuantity:     order.GoodQuantity,
+		ReworkQuantity:   order.ReworkQuantity,
+		ScrapQuantity:    order.ScrapQuantity,
 		Status:           string(order.Status),
 		PlannedStartDate: order.Schedule.PlannedStart,
 		PlannedEndDate:   order.Schedule.PlannedEnd,
@@ -82,6 +97,9 @@ func (uc *ProductionUseCase) GetProductionOrder(ctx context.Context, id string)
 		OrderNumber:      order.OrderNumber,
 		PartID:           string(order.PartID),
 		Quantity:         order.Quantity,
+		GoodQuantity:     order.GoodQuantity,
+		ReworkQuantity:   order.ReworkQuantity,
+		ScrapQuantity:    order.ScrapQuantity,
 		Status:           string(order.Status),
 		PlannedStartDate: order.Schedule.PlannedStart,
 		PlannedEndDate:   order.Schedule.PlannedEnd,
@@ -103,6 +121,9 @@ func (uc *ProductionUseCase) GetAllProductionOrders(ctx context.Context) ([]*Pro
 			OrderNumber:      order.OrderNumber,
 			PartID:           string(order.PartID),
 			Quantity:         order.Quantity,
+			GoodQuantity:     order.GoodQuantity,
+			ReworkQuantity:   order.ReworkQuantity,
+			ScrapQuantity:    order.ScrapQuantity,
 			Status:           string(order.Status),
 			PlannedStartDate: order.Schedule.PlannedStart,
 			PlannedEndDate:   order.Schedule.PlannedEnd,
@@ -138,4 +159,19 @@ func (uc *ProductionUseCase) CompleteProduction(ctx context.Context, id string)
 	}
 	
 	return uc.repo.Update(ctx, order)
+}
+
+// RecordQualityDisposition は不適合処置の数量を製造オーダーの良品・手直し・不良数に反映する。
+// 同じ DispositionID の処置は 1 回だけ反映する
+func (uc *ProductionUseCase) RecordQualityDisposition(ctx context.Context, input RecordQualityDispositionInput) error {
+	order, err := uc.repo.FindByID(ctx, domain.ProductionOrderID(input.OrderID))
+	if err != nil {
+		return err
+	}
+	
+	if err := order.RecordQualityDisposition(input.Good, input.Rework, input.Scrap); err != nil {
+		return err
+	}
+	
+	return uc.repo.ApplyQualityDisposition(ctx, order.ID, input.DispositionID, input.Good, input.Rework, input.Scrap)
 }
\ No newline at end of file
@@ -25,10 +25,14 @@ type ProductionOrder struct {
 	OrderNumber string
 	PartID      PartID
 	Quantity    int
-	Status      ProductionOrderStatus
-	Schedule    Schedule
-	CreatedAt   time.Time
-	UpdatedAt   time.Time
+	// 品質の不適合処置で確定した良品（特別採用）・手直し・不良（廃却・返品）の数量
+	GoodQuantity   int
+	ReworkQuantity int
+	ScrapQuantity  int
+	Status         ProductionOrderStatus
+	Schedule       Schedule
+	CreatedAt      time.Time
+	UpdatedAt      time.Time
 }
 
 type Schedule struct {
@@ -78,6 +82,25 @@ func (po *ProductionOrder) Delay() error {
 	return nil
 }
 
+// RecordQualityDisposition は不適合処置の数量を加算する。合計はオーダー数量を超えられない
+func (po *ProductionOrder) RecordQualityDisposition(good, rework, scrap int) error {
+	if good < 0 || rework < 0 || scrap < 0 {
+		return ErrInvalidQuantity
+	}
+	if po.Status == StatusCancelled {
+		return ErrInvalidStateTransition
+	}
+	total := po.GoodQuantity + po.ReworkQuantity + po.ScrapQuantity + good + rework + scrap
+	if total > po.Quantity {
+		return ErrInvalidQuantity
+	}
+	po.GoodQuantity += good
+	po.ReworkQuantity += rework
+	po.ScrapQuantity += scrap
+	po.UpdatedAt = time.Now()
+	return nil
+}
+
 func (po *ProductionOrder) Cancel() error {
 	if po.Status == StatusCompleted || po.Status == StatusCancelled {
 		return ErrInvalidStateTransition
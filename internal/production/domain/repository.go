@@ -7,5 +7,8 @@ type ProductionOrderRepository interface {
 	FindByID(ctx context.Context, id ProductionOrderID) (*ProductionOrder, error)
 	FindAll(ctx context.Context) ([]*ProductionOrder, error)
 	Update(ctx context.Context, order *ProductionOrder) error
+	// ApplyQualityDisposition は不適合処置の数量をオーダーに加算する。同じ dispositionID は 1 回だけ反映し、
+	// 並行した更新で合計がオーダー数量を超える場合・取消済みの場合は反映しない
+	ApplyQualityDisposition(ctx context.Context, id ProductionOrderID, dispositionID string, good, rework, scrap int) error
 	Delete(ctx context.Context, id ProductionOrderID) error
 }
\ No newline at end of file
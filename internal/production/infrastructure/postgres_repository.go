@@ -5,6 +5,7 @@ import (
 	"database/sql"
 	"encoding/json"
 	"goNexttask/internal/production/domain"
+	"time"
 )
 
 type PostgresProductionOrderRepository struct {
@@ -27,8 +28,9 @@ func (r *PostgresProductionOrderRepository) Save(ctx context.Context, order *dom
 		INSERT INTO production_orders (
 			id, order_number, part_id, quantity, status,
 			planned_start_date, planned_end_date, assigned_machines,
-			created_at, updated_at
-		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+			created_at, updated_at,
+			good_quantity, rework_quantity, scrap_quantity
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)
 	`
 
 	_, err = r.db.ExecContext(ctx, query,
@@ -42,6 +44,9 @@ func (r *PostgresProductionOrderRepository) Save(ctx context.Context, order *dom
 		string(machinesJSON),
 		order.CreatedAt,
 		order.UpdatedAt,
+		order.GoodQuantity,
+		order.ReworkQuantity,
+		order.ScrapQuantity,
 	)
 
 	return err
@@ -51,7 +56,8 @@ func (r *PostgresProductionOrderRepository) FindByID(ctx context.Context, id dom
 	query := `
 		SELECT id, order_number, part_id, quantity, status,
 			   planned_start_date, planned_end_date, assigned_machines,
-			   created_at, updated_at
+			   created_at, updated_at,
+			   good_quantity, rework_quantity, scrap_quantity
 		FROM production_orders
 		WHERE id = $1
 	`
@@ -70,6 +76,9 @@ func (r *PostgresProductionOrderRepository) FindByID(ctx context.Context, id dom
 		&machinesJSON,
 		&order.CreatedAt,
 		&order.UpdatedAt,
+		&order.GoodQuantity,
+		&order.ReworkQuantity,
+		&order.ScrapQuantity,
 	)
 
 	if err == sql.ErrNoRows {
@@ -90,7 +99,8 @@ func (r *PostgresProductionOrderRepository) FindAll(ctx context.Context) ([]*dom
 	query := `
 		SELECT id, order_number, part_id, quantity, status,
 			   planned_start_date, planned_end_date, assigned_machines,
-			   created_at, updated_at
+			   created_at, updated_at,
+			   good_quantity, rework_quantity, scrap_quantity
 		FROM production_orders
 		ORDER BY created_at DESC
 	`
@@ -118,6 +128,9 @@ func (r *PostgresProductionOrderRepository) FindAll(ctx context.Context) ([]*dom
 			&machinesJSON,
 			&order.CreatedAt,
 			&order.UpdatedAt,
+			&order.GoodQuantity,
+			&order.ReworkQuantity,
+			&order.ScrapQuantity,
 		)
 		if err != nil {
 			return nil, err
@@ -133,6 +146,7 @@ func (r *PostgresProductionOrderRepository) FindAll(ctx context.Context) ([]*dom
 	return orders, nil
 }
 
+// Update は指示の内容と状態を更新する。良品・手直し・廃棄の数量は並行した反映を上書きしないよう ApplyQualityDisposition だけが書き込む
 func (r *PostgresProductionOrderRepository) Update(ctx context.Context, order *domain.ProductionOrder) error {
 	machinesJSON, err := json.Marshal(order.Schedule.AssignedMachines)
 	if err != nil {
@@ -162,6 +176,52 @@ func (r *PostgresProductionOrderRepository) Update(ctx context.Context, order *d
 	return err
 }
 
+func (r *PostgresProductionOrderRepository) ApplyQualityDisposition(ctx context.Context, id domain.ProductionOrderID, dispositionID string, good, rework, scrap int) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.ExecContext(ctx, `
+		INSERT INTO production_quality_dispositions (disposition_id, production_order_id, good_quantity, rework_quantity, scrap_quantity)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (disposition_id) DO NOTHING
+	`, dispositionID, id, good, rework, scrap)
+	if err != nil {
+		return err
+	}
+	inserted, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	// 反映済み
+	if inserted == 0 {
+		return tx.Commit()
+	}
+
+	// 読み込んだ数量に足して書き戻すと並行した反映が失われるため、列に加算する
+	result, err = tx.ExecContext(ctx, `
+		UPDATE production_orders
+		SET good_quantity = good_quantity + $2, rework_quantity = rework_quantity + $3,
+			scrap_quantity = scrap_quantity + $4, updated_at = $5
+		WHERE id = $1 AND status <> 'cancelled'
+		  AND good_quantity + rework_quantity + scrap_quantity + $2 + $3 + $4 <= quantity
+	`, id, good, rework, scrap, time.Now())
+	if err != nil {
+		return err
+	}
+	updated, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if updated == 0 {
+		return domain.ErrInvalidQuantity
+	}
+
+	return tx.Commit()
+}
+
 func (r *PostgresProductionOrderRepository) Delete(ctx context.Context, id domain.ProductionOrderID) error {
 	query := `DELETE FROM production_orders WHERE id = $1`
 	_, err := r.db.ExecContext(ctx, query, id)
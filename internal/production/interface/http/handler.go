@@ -41,6 +41,9 @@ type OrderResponse struct {
 	OrderNumber      string    `json:"orderNumber"`
 	PartID           string    `json:"partId"`
 	Quantity         int       `json:"quantity"`
+	GoodQuantity     int       `json:"goodQuantity"`
+	ReworkQuantity   int       `json:"reworkQuantity"`
+	ScrapQuantity    int       `json:"scrapQuantity"`
 	Status           string    `json:"status"`
 	PlannedStartDate time.Time `json:"plannedStartDate"`
 	PlannedEndDate   time.Time `json:"plannedEndDate"`
@@ -75,6 +78,9 @@ func (h *ProductionHandler) CreateOrder(w http.ResponseWriter, r *http.Request)
 		OrderNumber:      output.OrderNumber,
 		PartID:           output.PartID,
 		Quantity:         output.Quantity,
+		GoodQuantity:     output.GoodQuantity,
+		ReworkQuantity:   output.ReworkQuantity,
+		ScrapQuantity:    output.ScrapQuantity,
 		Status:           output.Status,
 		PlannedStartDate: output.PlannedStartDate,
 		PlannedEndDate:   output.PlannedEndDate,
@@ -102,6 +108,9 @@ func (h *ProductionHandler) GetOrder(w http.ResponseWriter, r *http.Request) {
 		OrderNumber:      output.OrderNumber,
 		PartID:           output.PartID,
 		Quantity:         output.Quantity,
+		GoodQuantity:     output.GoodQuantity,
+		ReworkQuantity:   output.ReworkQuantity,
+		ScrapQuantity:    output.ScrapQuantity,
 		Status:           output.Status,
 		PlannedStartDate: output.PlannedStartDate,
 		PlannedEndDate:   output.PlannedEndDate,
@@ -127,6 +136,9 @@ func (h *ProductionHandler) GetAllOrders(w http.ResponseWriter, r *http.Request)
 			OrderNumber:      output.OrderNumber,
 			PartID:           output.PartID,
 			Quantity:         output.Quantity,
+			GoodQuantity:     output.GoodQuantity,
+			ReworkQuantity:   output.ReworkQuantity,
+			ScrapQuantity:    output.ScrapQuantity,
 			Status:           output.Status,
 			PlannedStartDate: output.PlannedStartDate,
 			PlannedEndDate:   output.PlannedEndDate,
@@ -0,0 +1,173 @@
+package application
+
+import (
+	"context"
+	"goNexttask/internal/quality/domain"
+	"time"
+)
+
+// AnalyticsInput は集計する検査の条件と集計の軸。ProgramID は NC プログラムの版ごとの ID
+type AnalyticsInput struct {
+	PartID     string
+	MachineID  string
+	ProgramID  string
+	OperatorID string
+	From       *time.Time
+	To         *time.Time
+	GroupBy    string
+}
+
+type ParetoEntryOutput struct {
+	Key                  string
+	Count                int
+	Percentage           float64
+	CumulativePercentage float64
+}
+
+type DefectTrendOutput struct {
+	WeekStart         time.Time
+	Inspections       int
+	FailedInspections int
+	Defects           int
+	Counts            map[string]int
+}
+
+type ParetoOutput struct {
+	GroupBy           string
+	Inspections       int
+	FailedInspections int
+	TotalDefects      int
+	Entries           []*ParetoEntryOutput
+	Trend             []*DefectTrendOutput
+}
+
+type YieldOutput struct {
+	Key              string
+	FirstInspections int
+	FirstPassed      int
+	FirstPassYield   float64
+}
+
+type YieldTrendOutput struct {
+	WeekStart time.Time
+	YieldOutput
+}
+
+type FirstPassYieldOutput struct {
+	GroupBy       string
+	Overall       YieldOutput
+	Groups        []*YieldOutput
+	Trend         []*YieldTrendOutput
+	Reinspections int
+}
+
+// GetDefectPareto は複数のロットの不具合をパレート図と週ごとの推移にまとめる。GroupBy が空の場合は不具合コードごと
+func (uc *QualityUseCase) GetDefectPareto(ctx context.Context, input AnalyticsInput) (*ParetoOutput, error) {
+	groupBy := domain.DimensionDefect
+	if input.GroupBy != "" {
+		var err error
+		if groupBy, err = domain.ParseAnalyticsDimension(input.GroupBy); err != nil {
+			return nil, err
+		}
+	}
+
+	outcomes, err := uc.findOutcomes(ctx, input)
+	if err != nil {
+		return nil, err
+	}
+
+	pareto := domain.BuildDefectPareto(outcomes, groupBy)
+
+	output := &ParetoOutput{
+		GroupBy:           string(pareto.Dimension),
+		Inspections:       pareto.Inspections,
+		FailedInspections: pareto.FailedInspections,
+		TotalDefects:      pareto.TotalDefects,
+		Entries:           make([]*ParetoEntryOutput, len(pareto.Entries)),
+		Trend:             make([]*DefectTrendOutput, len(pareto.Trend)),
+	}
+	for i, e := range pareto.Entries {
+		output.Entries[i] = &ParetoEntryOutput{
+			Key:                  e.Key,
+			Count:                e.Count,
+			Percentage:           e.Percentage,
+			CumulativePercentage: e.CumulativePercentage,
+		}
+	}
+	for i, p := range pareto.Trend {
+		output.Trend[i] = &DefectTrendOutput{
+			WeekStart:         p.WeekStart,
+			Inspections:       p.Inspections,
+			FailedInspections: p.FailedInspections,
+			Defects:           p.Defects,
+			Counts:            p.Counts,
+		}
+	}
+
+	return output, nil
+}
+
+// GetFirstPassYield はロット・工程ごとの最初の検査の合格率を返す。GroupBy が空の場合は全体と週ごとのみ
+func (uc *QualityUseCase) GetFirstPassYield(ctx context.Context, input AnalyticsInput) (*FirstPassYieldOutput, error) {
+	var groupBy domain.AnalyticsDimension
+	if input.GroupBy != "" {
+		var err error
+		groupBy, err = domain.ParseAnalyticsDimension(input.GroupBy)
+		// 不具合コードは検査ごとの値ではないため初回合格率の軸にできない
+		if err != nil || groupBy == domain.DimensionDefect {
+			return nil, domain.ErrInvalidAnalyticsQuery
+		}
+	}
+
+	outcomes, err := uc.findOutcomes(ctx, input)
+	if err != nil {
+		return nil, err
+	}
+
+	report := domain.CalculateFirstPassYield(outcomes, groupBy)
+
+	output := &FirstPassYieldOutput{
+		GroupBy:       string(report.Dimension),
+		Overall:       convertToYieldOutput(report.Overall),
+		Groups:        make([]*YieldOutput, len(report.Groups)),
+		Trend:         make([]*YieldTrendOutput, len(report.Trend)),
+		Reinspections: report.Reinspections,
+	}
+	for i, g := range report.Groups {
+		group := convertToYieldOutput(g)
+		output.Groups[i] = &group
+	}
+	for i, p := range report.Trend {
+		output.Trend[i] = &YieldTrendOutput{
+			WeekStart:   p.WeekStart,
+			YieldOutput: convertToYieldOutput(p.YieldGroup),
+		}
+	}
+
+	return output, nil
+}
+
+func (uc *QualityUseCase) findOutcomes(ctx context.Context, input AnalyticsInput) ([]domain.InspectionOutcome, error) {
+	filter := domain.AnalyticsFilter{
+		PartID:     input.PartID,
+		MachineID:  input.MachineID,
+		ProgramID:  input.ProgramID,
+		OperatorID: input.OperatorID,
+		From:       input.From,
+		To:         input.To,
+	}
+	if err := filter.Validate(); err != nil {
+		return nil, err
+	}
+
+	return uc.repo.FindOutcomes(ctx, filter)
+}
+
+func convertToYieldOutput(g domain.YieldGroup) YieldOutput {
+	return YieldOutput{
+		Key:              g.Key,
+		FirstInspections: g.FirstInspections,
+		FirstPassed:      g.FirstPassed,
+		FirstPassYield:   g.FirstPassYield,
+	}
+}
@@ -0,0 +1,57 @@
+package application
+
+import (
+	"context"
+	"goNexttask/internal/quality/domain"
+	"log"
+	"time"
+)
+
+// CAPAReminder は CAPA の処置の期日を定期的に確認し、期日を過ぎた処置を担当者へ通知する
+type CAPAReminder struct {
+	capaService *domain.CAPAService
+	// repeat は同じ処置を再び通知するまでの間隔
+	repeat   time.Duration
+	interval time.Duration
+}
+
+func NewCAPAReminder(
+	capaRepo domain.CAPARepository,
+	ncrRepo domain.NonconformanceRepository,
+	alertRepo domain.QualityAlertRepository,
+	publisher domain.EventPublisher,
+	repeat time.Duration,
+	interval time.Duration,
+) *CAPAReminder {
+	return &CAPAReminder{
+		capaService: domain.NewCAPAService(capaRepo, ncrRepo, alertRepo, publisher),
+		repeat:      repeat,
+		interval:    interval,
+	}
+}
+
+// Run は ctx がキャンセルされるまで確認を続ける
+func (m *CAPAReminder) Run(ctx context.Context) {
+	ticker := time.NewTicker(m.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := m.Check(ctx); err != nil {
+				log.Printf("CAPA reminder check failed: %v", err)
+			}
+		}
+	}
+}
+
+// Check は期日を過ぎた処置を通知する
+func (m *CAPAReminder) Check(ctx context.Context) error {
+	reminded, err := m.capaService.RemindOverdue(ctx, time.Now(), m.repeat)
+	if reminded > 0 {
+		log.Printf("Sent %d overdue CAPA action reminders", reminded)
+	}
+	return err
+}
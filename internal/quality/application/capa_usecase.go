@@ -0,0 +1,275 @@
+package application
+
+import (
+	"context"
+	"errors"
+	"goNexttask/internal/quality/domain"
+	"time"
+)
+
+// 他での保存と競合した CAPA の更新を読み直してやり直す回数の上限
+const maxCAPAUpdateAttempts = 3
+
+// OpenCAPAInput は CAPA の起票。Sources は 1 件以上の NCR・管理図のアラート
+type OpenCAPAInput struct {
+	Title       string
+	Description string
+	Sources     []CAPASourceInput
+	OpenedBy    string
+}
+
+type CAPASourceInput struct {
+	// Type は nonconformance または spc_alert
+	Type      string
+	Reference string
+}
+
+// AnalyzeRootCauseInput はなぜなぜ分析と特性要因図の分類。RootCause を省略すると最後のなぜを使う
+type AnalyzeRootCauseInput struct {
+	CAPAID     string
+	Whys       []string
+	Category   string
+	RootCause  string
+	AnalyzedBy string
+}
+
+// AddCAPAActionInput の Type は corrective または preventive
+type AddCAPAActionInput struct {
+	CAPAID      string
+	Type        string
+	Description string
+	Owner       string
+	DueDate     time.Time
+}
+
+type CompleteCAPAActionInput struct {
+	CAPAID       string
+	ActionNumber int
+	CompletedBy  string
+}
+
+type VerifyEffectivenessInput struct {
+	CAPAID     string
+	Effective  bool
+	Evidence   string
+	VerifiedBy string
+}
+
+type CAPAOutput struct {
+	ID            string
+	Title         string
+	Description   string
+	Sources       []CAPASourceOutput
+	DefectCodes   []string
+	Status        string
+	RootCause     *RootCauseOutput
+	Actions       []CAPAActionOutput
+	Verifications []EffectivenessCheckOutput
+	OpenedBy      string
+	CreatedAt     time.Time
+	UpdatedAt     time.Time
+}
+
+type CAPASourceOutput struct {
+	Type      string
+	Reference string
+}
+
+type RootCauseOutput struct {
+	Whys       []string
+	Category   string
+	RootCause  string
+	AnalyzedBy string
+	AnalyzedAt time.Time
+}
+
+type CAPAActionOutput struct {
+	Number      int
+	Type        string
+	Description string
+	Owner       string
+	DueDate     time.Time
+	CompletedBy string
+	CompletedAt *time.Time
+	Overdue     bool
+}
+
+type EffectivenessCheckOutput struct {
+	Effective  bool
+	Evidence   string
+	VerifiedBy string
+	VerifiedAt time.Time
+}
+
+// OpenCAPA は NCR・管理図のアラートを紐付けて CAPA を起票する
+func (uc *QualityUseCase) OpenCAPA(ctx context.Context, input OpenCAPAInput) (*CAPAOutput, error) {
+	sources := make([]domain.CAPASource, len(input.Sources))
+	for i, s := range input.Sources {
+		sources[i] = domain.CAPASource{
+			Type:      domain.CAPASourceType(s.Type),
+			Reference: s.Reference,
+		}
+	}
+
+	capa, err := uc.capaService.Open(ctx, input.Title, input.Description, sources, input.OpenedBy)
+	if err != nil {
+		return nil, err
+	}
+
+	return convertToCAPAOutput(capa), nil
+}
+
+func (uc *QualityUseCase) GetCAPA(ctx context.Context, id string) (*CAPAOutput, error) {
+	capa, err := uc.capaRepo.FindByID(ctx, domain.CAPAID(id))
+	if err != nil {
+		return nil, err
+	}
+
+	return convertToCAPAOutput(capa), nil
+}
+
+// ListCAPAs は CAPA を起票の新しい順に返す。overdue の場合は期日を過ぎた処置のある CAPA のみ
+func (uc *QualityUseCase) ListCAPAs(ctx context.Context, status string, overdue bool) ([]*CAPAOutput, error) {
+	capas, err := uc.capaRepo.FindAll(ctx, domain.CAPAStatus(status))
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	outputs := make([]*CAPAOutput, 0, len(capas))
+	for _, capa := range capas {
+		if overdue && len(capa.OverdueActions(now)) == 0 {
+			continue
+		}
+		outputs = append(outputs, convertToCAPAOutput(capa))
+	}
+
+	return outputs, nil
+}
+
+func (uc *QualityUseCase) AnalyzeRootCause(ctx context.Context, input AnalyzeRootCauseInput) (*CAPAOutput, error) {
+	return uc.updateCAPA(ctx, input.CAPAID, func(capa *domain.CAPA) error {
+		category, err := domain.ParseFishboneCategory(input.Category)
+		if err != nil {
+			return err
+		}
+		return capa.AnalyzeRootCause(input.Whys, category, input.RootCause, input.AnalyzedBy)
+	})
+}
+
+func (uc *QualityUseCase) AddCAPAAction(ctx context.Context, input AddCAPAActionInput) (*CAPAOutput, error) {
+	return uc.updateCAPA(ctx, input.CAPAID, func(capa *domain.CAPA) error {
+		_, err := capa.AddAction(domain.CAPAActionType(input.Type), input.Description, input.Owner, input.DueDate)
+		return err
+	})
+}
+
+func (uc *QualityUseCase) CompleteCAPAAction(ctx context.Context, input CompleteCAPAActionInput) (*CAPAOutput, error) {
+	return uc.updateCAPA(ctx, input.CAPAID, func(capa *domain.CAPA) error {
+		return capa.CompleteAction(input.ActionNumber, input.CompletedBy)
+	})
+}
+
+// VerifyEffectiveness は処置の有効性を記録する。有効でない場合は追加の処置が必要になる
+func (uc *QualityUseCase) VerifyEffectiveness(ctx context.Context, input VerifyEffectivenessInput) (*CAPAOutput, error) {
+	return uc.updateCAPA(ctx, input.CAPAID, func(capa *domain.CAPA) error {
+		return capa.VerifyEffectiveness(input.Effective, input.Evidence, input.VerifiedBy)
+	})
+}
+
+// SuggestCAPAs は CAPA の無いまま再発している不具合を返す。days・minOccurrences が 0 の場合は既定値
+func (uc *QualityUseCase) SuggestCAPAs(ctx context.Context, days, minOccurrences int) ([]domain.CAPASuggestion, error) {
+	window := domain.DefaultRecurrenceWindow
+	if days > 0 {
+		window = time.Duration(days) * 24 * time.Hour
+	}
+	if minOccurrences == 0 {
+		minOccurrences = domain.DefaultRecurrenceThreshold
+	}
+
+	return uc.defectAnalysisService.SuggestCAPAs(ctx, time.Now().Add(-window), minOccurrences)
+}
+
+// updateCAPA は CAPA を読み込んで変更し、保存する。読み込んだ後に他で保存されていた場合（期日超過の通知など）は
+// 読み直して変更し直す
+func (uc *QualityUseCase) updateCAPA(ctx context.Context, id string, change func(*domain.CAPA) error) (*CAPAOutput, error) {
+	for attempt := 1; ; attempt++ {
+		capa, err := uc.capaRepo.FindByID(ctx, domain.CAPAID(id))
+		if err != nil {
+			return nil, err
+		}
+
+		if err := change(capa); err != nil {
+			return nil, err
+		}
+
+		err = uc.capaRepo.Update(ctx, capa)
+		if errors.Is(err, domain.ErrCAPAConflict) && attempt < maxCAPAUpdateAttempts {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		return convertToCAPAOutput(capa), nil
+	}
+}
+
+func convertToCAPAOutput(capa *domain.CAPA) *CAPAOutput {
+	sources := make([]CAPASourceOutput, len(capa.Sources))
+	for i, s := range capa.Sources {
+		sources[i] = CAPASourceOutput{
+			Type:      string(s.Type),
+			Reference: s.Reference,
+		}
+	}
+
+	now := time.Now()
+	actions := make([]CAPAActionOutput, len(capa.Actions))
+	for i, a := range capa.Actions {
+		actions[i] = CAPAActionOutput{
+			Number:      a.Number,
+			Type:        string(a.Type),
+			Description: a.Description,
+			Owner:       a.Owner,
+			DueDate:     a.DueDate,
+			CompletedBy: a.CompletedBy,
+			CompletedAt: a.CompletedAt,
+			Overdue:     a.IsOverdue(now),
+		}
+	}
+
+	verifications := make([]EffectivenessCheckOutput, len(capa.Verifications))
+	for i, v := range capa.Verifications {
+		verifications[i] = EffectivenessCheckOutput{
+			Effective:  v.Effective,
+			Evidence:   v.Evidence,
+			VerifiedBy: v.VerifiedBy,
+			VerifiedAt: v.VerifiedAt,
+		}
+	}
+
+	output := &CAPAOutput{
+		ID:            string(capa.ID),
+		Title:         capa.Title,
+		Description:   capa.Description,
+		Sources:       sources,
+		DefectCodes:   capa.DefectCodes,
+		Status:        string(capa.Status),
+		Actions:       actions,
+		Verifications: verifications,
+		OpenedBy:      capa.OpenedBy,
+		CreatedAt:     capa.CreatedAt,
+		UpdatedAt:     capa.UpdatedAt,
+	}
+	if capa.RootCause != nil {
+		output.RootCause = &RootCauseOutput{
+			Whys:       capa.RootCause.Whys,
+			Category:   string(capa.RootCause.Category),
+			RootCause:  capa.RootCause.RootCause,
+			AnalyzedBy: capa.RootCause.AnalyzedBy,
+			AnalyzedAt: capa.RootCause.AnalyzedAt,
+		}
+	}
+	return output
+}
@@ -0,0 +1,109 @@
+package application
+
+import (
+	"context"
+	"goNexttask/internal/quality/domain"
+	"time"
+)
+
+// CapabilityInput は工程能力の対象範囲。ParameterName を省略すると全パラメーターを評価する
+type CapabilityInput struct {
+	LotNumber     string
+	PartID        string
+	ParameterName string
+	From          *time.Time
+	To            *time.Time
+	// MinSamples は信頼できる評価に必要なサンプル数（0 の場合は既定値）
+	MinSamples int
+}
+
+type NormalityOutput struct {
+	Method    string
+	Statistic float64
+	PValue    float64
+	Normal    bool
+}
+
+type CapabilityOutput struct {
+	ParameterName string
+	Unit          string
+	SampleSize    int
+	SubgroupCount int
+	Mean          float64
+	SigmaWithin   float64
+	SigmaOverall  float64
+	SigmaMethod   string
+	Target        float64
+	LSL           *float64
+	USL           *float64
+	Cp            *float64
+	Cpk           *float64
+	Pp            *float64
+	Ppk           *float64
+	Normality     *NormalityOutput
+	Warnings      []string
+}
+
+// GetCapability は測定結果から Cp/Cpk/Pp/Ppk を計算する
+func (uc *QualityUseCase) GetCapability(ctx context.Context, input CapabilityInput) ([]*CapabilityOutput, error) {
+	filter := domain.MeasurementFilter{
+		LotNumber:     input.LotNumber,
+		PartID:        input.PartID,
+		ParameterName: input.ParameterName,
+		From:          input.From,
+		To:            input.To,
+	}
+	if err := filter.Validate(); err != nil {
+		return nil, err
+	}
+	if input.MinSamples < 0 {
+		return nil, domain.ErrInvalidMeasurementFilter
+	}
+
+	samples, err := uc.repo.FindMeasurements(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	studies := domain.AnalyzeCapability(samples, input.MinSamples)
+	if len(studies) == 0 && input.ParameterName != "" {
+		studies = append(studies, domain.NewCapabilityStudy(input.ParameterName, nil, input.MinSamples))
+	}
+
+	outputs := make([]*CapabilityOutput, len(studies))
+	for i, study := range studies {
+		outputs[i] = convertToCapabilityOutput(study)
+	}
+
+	return outputs, nil
+}
+
+func convertToCapabilityOutput(study *domain.CapabilityStudy) *CapabilityOutput {
+	output := &CapabilityOutput{
+		ParameterName: study.ParameterName,
+		Unit:          study.Unit,
+		SampleSize:    study.SampleSize,
+		SubgroupCount: study.SubgroupCount,
+		Mean:          study.Mean,
+		SigmaWithin:   study.SigmaWithin,
+		SigmaOverall:  study.SigmaOverall,
+		SigmaMethod:   string(study.SigmaMethod),
+		Target:        study.Target,
+		LSL:           study.LSL,
+		USL:           study.USL,
+		Cp:            study.Cp,
+		Cpk:           study.Cpk,
+		Pp:            study.Pp,
+		Ppk:           study.Ppk,
+		Warnings:      study.Warnings,
+	}
+	if study.Normality != nil {
+		output.Normality = &NormalityOutput{
+			Method:    study.Normality.Method,
+			Statistic: study.Normality.Statistic,
+			PValue:    study.Normality.PValue,
+			Normal:    study.Normality.Normal,
+		}
+	}
+	return output
+}
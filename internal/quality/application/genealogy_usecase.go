@@ -0,0 +1,72 @@
+package application
+
+import (
+	"context"
+	"goNexttask/internal/quality/domain"
+)
+
+type GenealogyOutput struct {
+	LotNumber    string
+	Direction    string
+	Depth        int
+	Root         *GenealogyNodeOutput
+	MaterialLots []string
+	WIPLots      []string
+	FinishedLots []string
+}
+
+type GenealogyNodeOutput struct {
+	Type        string
+	ID          string
+	ProductType string
+	Quantity    int
+	PartID      string
+	OrderNumber string
+	Status      string
+	Result      string
+	MachineID   string
+	Children    []*GenealogyNodeOutput
+}
+
+// GetGenealogy はロットの系譜を返す。direction が空の場合は backward、depth が 0 の場合は既定の段数
+func (uc *QualityUseCase) GetGenealogy(ctx context.Context, lotNumber, direction string, depth int) (*GenealogyOutput, error) {
+	d, err := domain.ParseGenealogyDirection(direction)
+	if err != nil {
+		return nil, err
+	}
+
+	genealogy, err := uc.genealogyService.Trace(ctx, lotNumber, d, depth)
+	if err != nil {
+		return nil, err
+	}
+
+	return &GenealogyOutput{
+		LotNumber:    genealogy.LotNumber,
+		Direction:    string(genealogy.Direction),
+		Depth:        genealogy.Depth,
+		Root:         convertToGenealogyNodeOutput(genealogy.Root),
+		MaterialLots: genealogy.MaterialLots,
+		WIPLots:      genealogy.WIPLots,
+		FinishedLots: genealogy.FinishedLots,
+	}, nil
+}
+
+func convertToGenealogyNodeOutput(node *domain.GenealogyNode) *GenealogyNodeOutput {
+	children := make([]*GenealogyNodeOutput, len(node.Children))
+	for i, child := range node.Children {
+		children[i] = convertToGenealogyNodeOutput(child)
+	}
+
+	return &GenealogyNodeOutput{
+		Type:        string(node.Type),
+		ID:          node.ID,
+		ProductType: node.ProductType,
+		Quantity:    node.Quantity,
+		PartID:      node.PartID,
+		OrderNumber: node.OrderNumber,
+		Status:      node.Status,
+		Result:      node.Result,
+		MachineID:   node.MachineID,
+		Children:    children,
+	}
+}
@@ -0,0 +1,228 @@
+package application
+
+import (
+	"context"
+	"errors"
+	"goNexttask/internal/quality/domain"
+	"time"
+)
+
+// CreateInspectionPlanInput は部品・工程の検査計画。既存の計画がある場合は次の版になる
+type CreateInspectionPlanInput struct {
+	PartID          string
+	Operation       string
+	AQL             float64
+	InspectionLevel string
+	Characteristics []PlanCharacteristicInput
+	CreatedBy       string
+}
+
+// PlanCharacteristicInput の規格は MeasurementInput と同じ優先順で解釈する
+type PlanCharacteristicInput struct {
+	ParameterName         string
+	CharacteristicType    string
+	TargetValue           float64
+	Tolerance             float64
+	UpperDeviation        *float64
+	LowerDeviation        *float64
+	UpperLimit            *float64
+	LowerLimit            *float64
+	MaterialCondition     string
+	MaterialConditionSize *float64
+	FeatureOfSize         string
+	Unit                  string
+	Instrument            string
+	// Frequency は sampled（既定）または once_per_lot
+	Frequency string
+	Required  bool
+}
+
+type InspectionPlanOutput struct {
+	ID              string
+	PartID          string
+	Operation       string
+	Revision        int
+	AQL             float64
+	InspectionLevel string
+	Characteristics []PlanCharacteristicOutput
+	CreatedBy       string
+	CreatedAt       time.Time
+}
+
+type PlanCharacteristicOutput struct {
+	ParameterName         string
+	CharacteristicType    string
+	TargetValue           float64
+	Tolerance             float64
+	UpperDeviation        *float64
+	LowerDeviation        *float64
+	UpperLimit            *float64
+	LowerLimit            *float64
+	MaterialCondition     string
+	MaterialConditionSize *float64
+	FeatureOfSize         string
+	Unit                  string
+	Instrument            string
+	Frequency             string
+	Required              bool
+}
+
+type SamplingPlanInput struct {
+	LotSize         int
+	AQL             float64
+	InspectionLevel string
+}
+
+type SamplingPlanOutput struct {
+	LotSize         int
+	AQL             float64
+	InspectionLevel string
+	CodeLetter      string
+	SampleSize      int
+	Accept          int
+	Reject          int
+}
+
+// CreateInspectionPlan は検査計画を登録する。同じ部品・工程の計画は版を上げて追加する
+func (uc *QualityUseCase) CreateInspectionPlan(ctx context.Context, input CreateInspectionPlanInput) (*InspectionPlanOutput, error) {
+	level, err := domain.ParseInspectionLevel(input.InspectionLevel)
+	if err != nil {
+		return nil, err
+	}
+
+	characteristics := make([]domain.PlanCharacteristic, len(input.Characteristics))
+	for i, c := range input.Characteristics {
+		characteristicType, err := domain.ParseCharacteristicType(c.CharacteristicType)
+		if err != nil {
+			return nil, err
+		}
+		characteristics[i] = domain.PlanCharacteristic{
+			ParameterName: c.ParameterName,
+			Spec: domain.ToleranceSpec{
+				Type:                  characteristicType,
+				Target:                c.TargetValue,
+				Tolerance:             c.Tolerance,
+				UpperDeviation:        c.UpperDeviation,
+				LowerDeviation:        c.LowerDeviation,
+				UpperLimit:            c.UpperLimit,
+				LowerLimit:            c.LowerLimit,
+				MaterialCondition:     domain.MaterialCondition(c.MaterialCondition),
+				MaterialConditionSize: c.MaterialConditionSize,
+				Feature:               domain.FeatureOfSize(c.FeatureOfSize),
+			},
+			Unit:       c.Unit,
+			Instrument: c.Instrument,
+			Frequency:  domain.InspectionFrequency(c.Frequency),
+			Required:   c.Required,
+		}
+	}
+
+	plan, err := domain.NewInspectionPlan(input.PartID, input.Operation, input.AQL, level, characteristics, input.CreatedBy)
+	if err != nil {
+		return nil, err
+	}
+
+	latest, err := uc.planRepo.FindLatest(ctx, plan.PartID, plan.Operation)
+	switch {
+	case err == nil:
+		plan.Revision = latest.Revision + 1
+	case !errors.Is(err, domain.ErrInspectionPlanNotFound):
+		return nil, err
+	}
+
+	if err := uc.planRepo.Save(ctx, plan); err != nil {
+		return nil, err
+	}
+
+	return convertToInspectionPlanOutput(plan), nil
+}
+
+func (uc *QualityUseCase) GetInspectionPlan(ctx context.Context, id string) (*InspectionPlanOutput, error) {
+	plan, err := uc.planRepo.FindByID(ctx, domain.InspectionPlanID(id))
+	if err != nil {
+		return nil, err
+	}
+
+	return convertToInspectionPlanOutput(plan), nil
+}
+
+// ListInspectionPlans は部品の検査計画を全版返す。operation を指定するとその工程のみ
+func (uc *QualityUseCase) ListInspectionPlans(ctx context.Context, partID, operation string) ([]*InspectionPlanOutput, error) {
+	if partID == "" {
+		return nil, domain.ErrInvalidInspectionPlan
+	}
+
+	plans, err := uc.planRepo.FindByPart(ctx, partID, operation)
+	if err != nil {
+		return nil, err
+	}
+
+	outputs := make([]*InspectionPlanOutput, len(plans))
+	for i, plan := range plans {
+		outputs[i] = convertToInspectionPlanOutput(plan)
+	}
+
+	return outputs, nil
+}
+
+// GetSamplingPlan はロットサイズ・AQL・検査水準から ISO 2859-1 の抜取方式を求める
+func (uc *QualityUseCase) GetSamplingPlan(ctx context.Context, input SamplingPlanInput) (*SamplingPlanOutput, error) {
+	level, err := domain.ParseInspectionLevel(input.InspectionLevel)
+	if err != nil {
+		return nil, err
+	}
+
+	plan, err := domain.NewSamplingPlan(input.LotSize, input.AQL, level)
+	if err != nil {
+		return nil, err
+	}
+
+	return convertToSamplingPlanOutput(plan), nil
+}
+
+func convertToInspectionPlanOutput(plan *domain.InspectionPlan) *InspectionPlanOutput {
+	characteristics := make([]PlanCharacteristicOutput, len(plan.Characteristics))
+	for i, c := range plan.Characteristics {
+		characteristics[i] = PlanCharacteristicOutput{
+			ParameterName:         c.ParameterName,
+			CharacteristicType:    string(c.Spec.Type),
+			TargetValue:           c.Spec.Target,
+			Tolerance:             c.Spec.Tolerance,
+			UpperDeviation:        c.Spec.UpperDeviation,
+			LowerDeviation:        c.Spec.LowerDeviation,
+			UpperLimit:            c.Spec.UpperLimit,
+			LowerLimit:            c.Spec.LowerLimit,
+			MaterialCondition:     string(c.Spec.MaterialCondition),
+			MaterialConditionSize: c.Spec.MaterialConditionSize,
+			FeatureOfSize:         string(c.Spec.Feature),
+			Unit:                  c.Unit,
+			Instrument:            c.Instrument,
+			Frequency:             string(c.Frequency),
+			Required:              c.Required,
+		}
+	}
+
+	return &InspectionPlanOutput{
+		ID:              string(plan.ID),
+		PartID:          plan.PartID,
+		Operation:       plan.Operation,
+		Revision:        plan.Revision,
+		AQL:             plan.AQL,
+		InspectionLevel: string(plan.InspectionLevel),
+		Characteristics: characteristics,
+		CreatedBy:       plan.CreatedBy,
+		CreatedAt:       plan.CreatedAt,
+	}
+}
+
+func convertToSamplingPlanOutput(plan domain.SamplingPlan) *SamplingPlanOutput {
+	return &SamplingPlanOutput{
+		LotSize:         plan.LotSize,
+		AQL:             plan.AQL,
+		InspectionLevel: string(plan.Level),
+		CodeLetter:      plan.CodeLetter,
+		SampleSize:      plan.SampleSize,
+		Accept:          plan.Accept,
+		Reject:          plan.Reject,
+	}
+}
@@ -0,0 +1,132 @@
+package application
+
+import (
+	"context"
+	"goNexttask/internal/quality/domain"
+	"time"
+)
+
+// RecordMeasurementsInput は 1 個のサンプルを 1 台の測定器で測った寸法。MeasuredAt を省略すると登録時刻
+type RecordMeasurementsInput struct {
+	InspectionID string
+	InstrumentID string
+	MeasuredAt   time.Time
+	SampleNumber int
+	Measurements []MeasurementInput
+}
+
+type FailInspectionInput struct {
+	InspectionID string
+	Reason       string
+}
+
+// RecordMeasurements は検査中の検査に測定を追加する
+func (uc *QualityUseCase) RecordMeasurements(ctx context.Context, input RecordMeasurementsInput) (*InspectionOutput, error) {
+	inspection, err := uc.repo.FindByID(ctx, domain.InspectionID(input.InspectionID))
+	if err != nil {
+		return nil, err
+	}
+
+	plan, err := uc.findPlan(ctx, inspection)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := record(inspection, plan, input.InstrumentID, input.MeasuredAt, input.SampleNumber, input.Measurements); err != nil {
+		return nil, err
+	}
+
+	if err := uc.repo.Update(ctx, inspection); err != nil {
+		return nil, err
+	}
+
+	return convertToReviewOutput(inspection, plan), nil
+}
+
+// CompleteInspection は検査中の検査の合否を判定して完了し、管理図で判定する。不合格の場合は NCR を起票する
+func (uc *QualityUseCase) CompleteInspection(ctx context.Context, id string) (*InspectionOutput, error) {
+	inspection, err := uc.repo.FindByID(ctx, domain.InspectionID(id))
+	if err != nil {
+		return nil, err
+	}
+
+	plan, err := uc.findPlan(ctx, inspection)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := complete(inspection, plan); err != nil {
+		return nil, err
+	}
+
+	if err := uc.repo.Update(ctx, inspection); err != nil {
+		return nil, err
+	}
+
+	return uc.evaluateCompleted(ctx, inspection), nil
+}
+
+// FailInspection は検査中の検査を中止する。中止した検査の測定は工程能力・管理図に使わない
+func (uc *QualityUseCase) FailInspection(ctx context.Context, input FailInspectionInput) (*InspectionOutput, error) {
+	inspection, err := uc.repo.FindByID(ctx, domain.InspectionID(input.InspectionID))
+	if err != nil {
+		return nil, err
+	}
+
+	if err := inspection.Fail(input.Reason); err != nil {
+		return nil, err
+	}
+
+	if err := uc.repo.Update(ctx, inspection); err != nil {
+		return nil, err
+	}
+
+	return convertToInspectionOutput(inspection), nil
+}
+
+// findPlan は検査に割り当てた検査計画を返す。計画の無い検査は nil
+func (uc *QualityUseCase) findPlan(ctx context.Context, inspection *domain.Inspection) (*domain.InspectionPlan, error) {
+	if inspection.PlanID == "" {
+		return nil, nil
+	}
+	return uc.planRepo.FindByID(ctx, inspection.PlanID)
+}
+
+// record は 1 回の測定の寸法を判定して検査に追加する
+func record(inspection *domain.Inspection, plan *domain.InspectionPlan, instrumentID string, measuredAt time.Time, sampleNumber int, inputs []MeasurementInput) error {
+	measurement := domain.Measurement{
+		Dimensions:   make(map[string]float64, len(inputs)),
+		InstrumentID: instrumentID,
+		MeasuredAt:   measuredAt,
+		SampleNumber: sampleNumber,
+	}
+
+	results := make([]domain.MeasurementResult, len(inputs))
+	for i, m := range inputs {
+		result, err := measure(plan, m)
+		if err != nil {
+			return err
+		}
+		measurement.Dimensions[m.ParameterName] = m.MeasuredValue
+		results[i] = result
+	}
+
+	return inspection.Record(measurement, results)
+}
+
+// complete は検査計画がある場合は抜取方式で、無い場合は全測定の合否でロットを判定する
+func complete(inspection *domain.Inspection, plan *domain.InspectionPlan) error {
+	if plan != nil {
+		return inspection.CompleteWithPlan(plan)
+	}
+	return inspection.Complete()
+}
+
+// convertToReviewOutput は検査中の検査に不足している必須特性を加えて返す
+func convertToReviewOutput(inspection *domain.Inspection, plan *domain.InspectionPlan) *InspectionOutput {
+	output := convertToInspectionOutput(inspection)
+	if plan != nil && inspection.Status == domain.InspectionStatusPending {
+		output.MissingCharacteristics = inspection.MissingCharacteristics(plan)
+	}
+	return output
+}
@@ -0,0 +1,180 @@
+package application
+
+import (
+	"context"
+	"goNexttask/internal/quality/domain"
+	"time"
+)
+
+// ProposeDispositionInput は NCR の対象数量に割り当てる処置。数量の合計は対象数量と一致させる
+type ProposeDispositionInput struct {
+	NonconformanceID string
+	Dispositions     []DispositionLineInput
+	ProposedBy       string
+}
+
+type DispositionLineInput struct {
+	// Disposition は use_as_is・rework・scrap・return_to_supplier
+	Disposition string
+	Quantity    int
+}
+
+// ReviewDispositionInput は MRB による処置の承認・差し戻し
+type ReviewDispositionInput struct {
+	NonconformanceID string
+	ReviewedBy       string
+	Comment          string
+}
+
+type NonconformanceOutput struct {
+	ID                string
+	InspectionID      string
+	ProductionOrderID string
+	LotNumber         string
+	AffectedQuantity  int
+	Defects           []DefectOutput
+	Status            string
+	Dispositions      []DispositionLineOutput
+	ProposedBy        string
+	ProposedAt        *time.Time
+	ApprovedBy        string
+	ApprovedAt        *time.Time
+	MRBComment        string
+	CreatedAt         time.Time
+	UpdatedAt         time.Time
+}
+
+type DefectOutput struct {
+	Code          string
+	ParameterName string
+	Count         int
+}
+
+type DispositionLineOutput struct {
+	Disposition string
+	Quantity    int
+}
+
+func (uc *QualityUseCase) GetNonconformance(ctx context.Context, id string) (*NonconformanceOutput, error) {
+	ncr, err := uc.ncrRepo.FindByID(ctx, domain.NonconformanceID(id))
+	if err != nil {
+		return nil, err
+	}
+
+	return convertToNonconformanceOutput(ncr), nil
+}
+
+// ListNonconformances は NCR を起票の新しい順に返す。status・lotNumber は空の場合は絞り込まない
+func (uc *QualityUseCase) ListNonconformances(ctx context.Context, status, lotNumber string) ([]*NonconformanceOutput, error) {
+	ncrs, err := uc.ncrRepo.FindAll(ctx, domain.NonconformanceStatus(status), lotNumber)
+	if err != nil {
+		return nil, err
+	}
+
+	outputs := make([]*NonconformanceOutput, len(ncrs))
+	for i, ncr := range ncrs {
+		outputs[i] = convertToNonconformanceOutput(ncr)
+	}
+
+	return outputs, nil
+}
+
+// ProposeDisposition は NCR の処置を提案し、MRB の承認待ちにする
+func (uc *QualityUseCase) ProposeDisposition(ctx context.Context, input ProposeDispositionInput) (*NonconformanceOutput, error) {
+	ncr, err := uc.ncrRepo.FindByID(ctx, domain.NonconformanceID(input.NonconformanceID))
+	if err != nil {
+		return nil, err
+	}
+
+	lines := make([]domain.DispositionLine, len(input.Dispositions))
+	for i, line := range input.Dispositions {
+		disposition, err := domain.ParseDisposition(line.Disposition)
+		if err != nil {
+			return nil, err
+		}
+		lines[i] = domain.DispositionLine{
+			Disposition: disposition,
+			Quantity:    line.Quantity,
+		}
+	}
+
+	from := ncr.Status
+	if err := ncr.ProposeDisposition(lines, input.ProposedBy); err != nil {
+		return nil, err
+	}
+
+	if err := uc.ncrRepo.Update(ctx, ncr, from); err != nil {
+		return nil, err
+	}
+
+	return convertToNonconformanceOutput(ncr), nil
+}
+
+// ApproveDisposition は MRB が処置を承認し、製造オーダーの良品・手直し・不良数に反映する
+func (uc *QualityUseCase) ApproveDisposition(ctx context.Context, input ReviewDispositionInput) (*NonconformanceOutput, error) {
+	ncr, err := uc.ncrRepo.FindByID(ctx, domain.NonconformanceID(input.NonconformanceID))
+	if err != nil {
+		return nil, err
+	}
+
+	if err := uc.ncrService.Approve(ctx, ncr, input.ReviewedBy, input.Comment); err != nil {
+		return nil, err
+	}
+
+	return convertToNonconformanceOutput(ncr), nil
+}
+
+// RejectDisposition は MRB が処置を差し戻す
+func (uc *QualityUseCase) RejectDisposition(ctx context.Context, input ReviewDispositionInput) (*NonconformanceOutput, error) {
+	ncr, err := uc.ncrRepo.FindByID(ctx, domain.NonconformanceID(input.NonconformanceID))
+	if err != nil {
+		return nil, err
+	}
+
+	from := ncr.Status
+	if err := ncr.Reject(input.ReviewedBy, input.Comment); err != nil {
+		return nil, err
+	}
+
+	if err := uc.ncrRepo.Update(ctx, ncr, from); err != nil {
+		return nil, err
+	}
+
+	return convertToNonconformanceOutput(ncr), nil
+}
+
+func convertToNonconformanceOutput(ncr *domain.Nonconformance) *NonconformanceOutput {
+	defects := make([]DefectOutput, len(ncr.Defects))
+	for i, d := range ncr.Defects {
+		defects[i] = DefectOutput{
+			Code:          d.Code,
+			ParameterName: d.ParameterName,
+			Count:         d.Count,
+		}
+	}
+	dispositions := make([]DispositionLineOutput, len(ncr.Dispositions))
+	for i, line := range ncr.Dispositions {
+		dispositions[i] = DispositionLineOutput{
+			Disposition: string(line.Disposition),
+			Quantity:    line.Quantity,
+		}
+	}
+
+	return &NonconformanceOutput{
+		ID:                string(ncr.ID),
+		InspectionID:      string(ncr.InspectionID),
+		ProductionOrderID: ncr.ProductionOrderID,
+		LotNumber:         ncr.LotNumber,
+		AffectedQuantity:  ncr.AffectedQuantity,
+		Defects:           defects,
+		Status:            string(ncr.Status),
+		Dispositions:      dispositions,
+		ProposedBy:        ncr.ProposedBy,
+		ProposedAt:        ncr.ProposedAt,
+		ApprovedBy:        ncr.ApprovedBy,
+		ApprovedAt:        ncr.ApprovedAt,
+		MRBComment:        ncr.MRBComment,
+		CreatedAt:         ncr.CreatedAt,
+		UpdatedAt:         ncr.UpdatedAt,
+	}
+}
@@ -2,7 +2,10 @@ package application
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"goNexttask/internal/quality/domain"
+	"log"
 	"time"
 )
 
@@ -11,14 +14,38 @@ type CreateInspectionInput struct {
 	LotNumber         string
 	InspectorID       string
 	Measurements      []MeasurementInput
+	// Operation を指定すると製造オーダーの部品の検査計画に従い、LotSize から抜取方式を決める
+	Operation string
+	LotSize   int
+	// Open の場合は検査中（pending）のまま登録し、測定を追加してから完了または中止する
+	Open bool
+	// MachineID は製品を加工した機械。製造オーダーの NC ジョブが複数の機械にある場合の絞り込みにも使う
+	MachineID string
 }
 
+// MeasurementInput の規格は UpperLimit/LowerLimit（片側規格は一方のみ）、
+// UpperDeviation/LowerDeviation（+0.02/-0.00 など）、Tolerance（±）の順に優先する
 type MeasurementInput struct {
-	ParameterName string
-	MeasuredValue float64
-	TargetValue   float64
-	Tolerance     float64
-	Unit          string
+	ParameterName         string
+	MeasuredValue         float64
+	TargetValue           float64
+	Tolerance             float64
+	Unit                  string
+	// CharacteristicType は dimension（既定）・surface_roughness・幾何公差（flatness, position 等）
+	CharacteristicType    string
+	UpperDeviation        *float64
+	LowerDeviation        *float64
+	UpperLimit            *float64
+	LowerLimit            *float64
+	MaterialCondition     string
+	MaterialConditionSize *float64
+	FeatureOfSize         string
+	ActualSize            *float64
+	// SampleNumber は抜取サンプルの番号（同じ番号の測定は同じ 1 個の製品）
+	SampleNumber          int
+	// InstrumentID・MeasuredAt は測定器と測定日時（省略時は登録時刻）
+	InstrumentID          string
+	MeasuredAt            time.Time
 }
 
 type InspectionOutput struct {
@@ -30,32 +57,120 @@ type InspectionOutput struct {
 	FinalResult       string
 	Measurements      []MeasurementOutput
 	CreatedAt         time.Time
+	// SPCViolations はこの検査で該当した管理図の判定ルール（登録時のみ）
+	SPCViolations []RuleViolationOutput
+	// 検査計画に従った検査の場合のみ設定する
+	PlanID             string
+	Operation          string
+	LotSize            int
+	Sampling           *SamplingPlanOutput
+	NonconformingCount int
+	// MissingCharacteristics は検査中の検査で測定が不足している必須特性
+	MissingCharacteristics []string
+	FailureReason          string
+	// NonconformanceID は不合格の検査から起票した NCR
+	NonconformanceID string
+	MachineID        string
+	Manufacturing    *ManufacturingRecordOutput
 }
 
 type MeasurementOutput struct {
-	ParameterName string
-	MeasuredValue float64
-	TargetValue   float64
-	Tolerance     float64
-	Unit          string
-	Pass          bool
+	ParameterName      string
+	MeasuredValue      float64
+	TargetValue        float64
+	Tolerance          float64
+	Unit               string
+	Pass               bool
+	CharacteristicType string
+	LowerLimit         *float64
+	UpperLimit         *float64
+	MaterialCondition  string
+	BonusTolerance     float64
+	SampleNumber       int
+	InstrumentID       string
+	MeasuredAt         time.Time
 }
 
 type TraceabilityOutput struct {
 	LotNumber   string
 	Inspections []InspectionOutput
 	PassRate    float64
+	// NCProgramVersion・MachineID・Tools はロットを最後に加工したジョブのもの
+	NCProgramVersion string
+	MachineID        string
+	Tools            []ToolSnapshotOutput
+	Manufacturing    []ManufacturingRecordOutput
+}
+
+// ManufacturingRecordOutput は製品を加工したジョブのプログラム・機械と、その時点の工具・オフセット
+type ManufacturingRecordOutput struct {
+	NCJobID        string
+	ProgramID      string
+	ProgramName    string
+	ProgramVersion string
+	ProgramHash    string
+	MachineID      string
+	JobStartedAt   time.Time
+	Tools          []ToolSnapshotOutput
+	Offsets        []OffsetSnapshotOutput
+	// Snapshot が false の記録は照会時に解決したもので、工具・オフセットを含まない
+	Snapshot   bool
+	CapturedAt time.Time
+}
+
+type ToolSnapshotOutput struct {
+	ToolNumber   int
+	Description  string
+	AssemblyID   string
+	AssemblyName string
+	CutTimeSec   float64
+	PartCount    int
+	LoadedAt     time.Time
+}
+
+type OffsetSnapshotOutput struct {
+	Register string
+	Values   map[string]float64
+	Version  int
+	ChangeID string
 }
 
 type QualityUseCase struct {
 	repo                  domain.InspectionRepository
+	planRepo              domain.InspectionPlanRepository
 	defectAnalysisService *domain.DefectAnalysisService
+	spcService            *domain.SPCService
+	ncrRepo               domain.NonconformanceRepository
+	ncrService            *domain.NonconformanceService
+	capaRepo              domain.CAPARepository
+	capaService           *domain.CAPAService
+	history               domain.ManufacturingHistory
+	genealogyService      *domain.GenealogyService
 }
 
-func NewQualityUseCase(repo domain.InspectionRepository) *QualityUseCase {
+func NewQualityUseCase(
+	repo domain.InspectionRepository,
+	planRepo domain.InspectionPlanRepository,
+	limitsRepo domain.ControlLimitsRepository,
+	alertRepo domain.QualityAlertRepository,
+	ncrRepo domain.NonconformanceRepository,
+	capaRepo domain.CAPARepository,
+	genealogyRepo domain.GenealogyRepository,
+	feedback domain.ProductionFeedback,
+	history domain.ManufacturingHistory,
+	publisher domain.EventPublisher,
+) *QualityUseCase {
 	return &QualityUseCase{
 		repo:                  repo,
-		defectAnalysisService: domain.NewDefectAnalysisService(repo),
+		planRepo:              planRepo,
+		defectAnalysisService: domain.NewDefectAnalysisService(repo, ncrRepo, capaRepo, history),
+		spcService:            domain.NewSPCService(repo, limitsRepo, alertRepo, publisher),
+		ncrRepo:               ncrRepo,
+		ncrService:            domain.NewNonconformanceService(ncrRepo, feedback, publisher),
+		capaRepo:              capaRepo,
+		capaService:           domain.NewCAPAService(capaRepo, ncrRepo, alertRepo, publisher),
+		history:               history,
+		genealogyService:      domain.NewGenealogyService(genealogyRepo, repo),
 	}
 }
 
@@ -66,29 +181,43 @@ func (uc *QualityUseCase) CreateInspection(ctx context.Context, input CreateInsp
 		input.InspectorID,
 	)
 	
+	var plan *domain.InspectionPlan
+	if input.Operation != "" {
+		var err error
+		plan, err = uc.planRepo.FindLatestForOrder(ctx, input.ProductionOrderID, input.Operation)
+		if err != nil {
+			return nil, err
+		}
+		if err := inspection.AssignPlan(plan, input.LotSize); err != nil {
+			return nil, err
+		}
+	}
+	
+	// 一括登録では入力の測定を 1 件ずつ別の測定として記録する
 	for _, m := range input.Measurements {
-		deviation := m.MeasuredValue - m.TargetValue
-		pass := deviation >= -m.Tolerance && deviation <= m.Tolerance
-		
-		result := domain.MeasurementResult{
-			ParameterName: m.ParameterName,
-			MeasuredValue: m.MeasuredValue,
-			TargetValue:   m.TargetValue,
-			Tolerance:     m.Tolerance,
-			Unit:          m.Unit,
-			Pass:          pass,
+		if err := record(inspection, plan, m.InstrumentID, m.MeasuredAt, m.SampleNumber, []MeasurementInput{m}); err != nil {
+			return nil, err
+		}
+	}
+	
+	uc.recordManufacturing(ctx, inspection, input.MachineID)
+	
+	if input.Open {
+		if err := uc.repo.Save(ctx, inspection); err != nil {
+			return nil, err
 		}
-		
-		inspection.AddMeasurement(result)
+		return convertToReviewOutput(inspection, plan), nil
 	}
 	
-	inspection.Complete()
+	if err := complete(inspection, plan); err != nil {
+		return nil, err
+	}
 	
 	if err := uc.repo.Save(ctx, inspection); err != nil {
 		return nil, err
 	}
 	
-	return convertToInspectionOutput(inspection), nil
+	return uc.evaluateCompleted(ctx, inspection), nil
 }
 
 func (uc *QualityUseCase) GetInspection(ctx context.Context, id string) (*InspectionOutput, error) {
@@ -97,11 +226,31 @@ func (uc *QualityUseCase) GetInspection(ctx context.Context, id string) (*Inspec
 		return nil, err
 	}
 	
-	return convertToInspectionOutput(inspection), nil
+	if inspection.Status != domain.InspectionStatusPending {
+		output := convertToInspectionOutput(inspection)
+		if inspection.FinalResult == domain.ResultFail {
+			ncr, err := uc.ncrRepo.FindByInspectionID(ctx, inspection.ID)
+			if err != nil && !errors.Is(err, domain.ErrNonconformanceNotFound) {
+				return nil, err
+			}
+			if ncr != nil {
+				output.NonconformanceID = string(ncr.ID)
+			}
+		}
+		return output, nil
+	}
+	
+	plan, err := uc.findPlan(ctx, inspection)
+	if err != nil {
+		return nil, err
+	}
+	
+	return convertToReviewOutput(inspection, plan), nil
 }
 
+// GetTraceability はロットの検査と、製品を加工したプログラム・機械・工具・オフセットを返す
 func (uc *QualityUseCase) GetTraceability(ctx context.Context, lotNumber string) (*TraceabilityOutput, error) {
-	inspections, err := uc.repo.FindByLotNumber(ctx, lotNumber)
+	info, err := uc.defectAnalysisService.GetTraceability(ctx, lotNumber)
 	if err != nil {
 		return nil, err
 	}
@@ -111,15 +260,24 @@ func (uc *QualityUseCase) GetTraceability(ctx context.Context, lotNumber string)
 		return nil, err
 	}
 	
-	outputs := make([]InspectionOutput, len(inspections))
-	for i, inspection := range inspections {
+	outputs := make([]InspectionOutput, len(info.Inspections))
+	for i, inspection := range info.Inspections {
 		outputs[i] = *convertToInspectionOutput(inspection)
 	}
 	
+	manufacturing := make([]ManufacturingRecordOutput, len(info.Manufacturing))
+	for i := range info.Manufacturing {
+		manufacturing[i] = *convertToManufacturingRecordOutput(&info.Manufacturing[i])
+	}
+	
 	return &TraceabilityOutput{
-		LotNumber:   lotNumber,
-		Inspections: outputs,
-		PassRate:    analysis.PassRate,
+		LotNumber:        lotNumber,
+		Inspections:      outputs,
+		PassRate:         analysis.PassRate,
+		NCProgramVersion: info.NCProgramVersion,
+		MachineID:        info.MachineID,
+		Tools:            convertToToolSnapshotOutputs(info.ToolInfo),
+		Manufacturing:    manufacturing,
 	}, nil
 }
 
@@ -131,23 +289,162 @@ func convertToInspectionOutput(inspection *domain.Inspection) *InspectionOutput
 	measurements := make([]MeasurementOutput, len(inspection.Results))
 	for i, result := range inspection.Results {
 		measurements[i] = MeasurementOutput{
-			ParameterName: result.ParameterName,
-			MeasuredValue: result.MeasuredValue,
-			TargetValue:   result.TargetValue,
-			Tolerance:     result.Tolerance,
-			Unit:          result.Unit,
-			Pass:          result.Pass,
+			ParameterName:      result.ParameterName,
+			MeasuredValue:      result.MeasuredValue,
+			TargetValue:        result.TargetValue,
+			Tolerance:          result.Tolerance,
+			Unit:               result.Unit,
+			Pass:               result.Pass,
+			CharacteristicType: string(result.Characteristic.Type),
+			LowerLimit:         result.Characteristic.LowerLimit,
+			UpperLimit:         result.Characteristic.UpperLimit,
+			MaterialCondition:  string(result.Characteristic.MaterialCondition),
+			BonusTolerance:     result.Characteristic.BonusTolerance(),
+			SampleNumber:       result.SampleNumber,
+			InstrumentID:       result.InstrumentID,
+			MeasuredAt:         result.MeasuredAt,
 		}
 	}
 	
-	return &InspectionOutput{
-		ID:                string(inspection.ID),
-		ProductionOrderID: inspection.ProductionOrderID,
-		LotNumber:         inspection.LotNumber,
-		InspectorID:       inspection.InspectorID,
-		Status:            string(inspection.Status),
-		FinalResult:       string(inspection.FinalResult),
-		Measurements:      measurements,
-		CreatedAt:         inspection.CreatedAt,
+	output := &InspectionOutput{
+		ID:                 string(inspection.ID),
+		ProductionOrderID:  inspection.ProductionOrderID,
+		LotNumber:          inspection.LotNumber,
+		InspectorID:        inspection.InspectorID,
+		Status:             string(inspection.Status),
+		FinalResult:        string(inspection.FinalResult),
+		Measurements:       measurements,
+		CreatedAt:          inspection.CreatedAt,
+		PlanID:             string(inspection.PlanID),
+		Operation:          inspection.Operation,
+		LotSize:            inspection.LotSize,
+		NonconformingCount: inspection.NonconformingCount,
+		FailureReason:      inspection.FailureReason,
+		MachineID:          inspection.MachineID,
+	}
+	if inspection.Sampling != nil {
+		output.Sampling = convertToSamplingPlanOutput(*inspection.Sampling)
+	}
+	if inspection.Manufacturing != nil {
+		output.Manufacturing = convertToManufacturingRecordOutput(inspection.Manufacturing)
+	}
+	return output
+}
+
+func convertToManufacturingRecordOutput(record *domain.ManufacturingRecord) *ManufacturingRecordOutput {
+	offsets := make([]OffsetSnapshotOutput, len(record.Offsets))
+	for i, o := range record.Offsets {
+		offsets[i] = OffsetSnapshotOutput{
+			Register: o.Register,
+			Values:   o.Values,
+			Version:  o.Version,
+			ChangeID: o.ChangeID,
+		}
 	}
-}
\ No newline at end of file
+	
+	return &ManufacturingRecordOutput{
+		NCJobID:        record.NCJobID,
+		ProgramID:      record.ProgramID,
+		ProgramName:    record.ProgramName,
+		ProgramVersion: record.ProgramVersion,
+		ProgramHash:    record.ProgramHash,
+		MachineID:      record.MachineID,
+		JobStartedAt:   record.JobStartedAt,
+		Tools:          convertToToolSnapshotOutputs(record.Tools),
+		Offsets:        offsets,
+		Snapshot:       record.IsSnapshot(),
+		CapturedAt:     record.CapturedAt,
+	}
+}
+
+func convertToToolSnapshotOutputs(tools []domain.ToolSnapshot) []ToolSnapshotOutput {
+	outputs := make([]ToolSnapshotOutput, len(tools))
+	for i, t := range tools {
+		outputs[i] = ToolSnapshotOutput{
+			ToolNumber:   t.ToolNumber,
+			Description:  t.Description,
+			AssemblyID:   t.AssemblyID,
+			AssemblyName: t.AssemblyName,
+			CutTimeSec:   t.CutTime.Seconds(),
+			PartCount:    t.PartCount,
+			LoadedAt:     t.LoadedAt,
+		}
+	}
+	return outputs
+}
+
+// recordManufacturing は製品を加工した NC のジョブと、その時点の工具・オフセットを検査に記録する。
+// ジョブが見つからない・解決に失敗した場合も検査の登録は取り消さない
+func (uc *QualityUseCase) recordManufacturing(ctx context.Context, inspection *domain.Inspection, machineID string) {
+	inspection.MachineID = machineID
+	if inspection.ProductionOrderID == "" && machineID == "" {
+		return
+	}
+	
+	record, err := uc.history.Resolve(ctx, inspection.ProductionOrderID, machineID, inspection.CreatedAt)
+	if errors.Is(err, domain.ErrManufacturingRecordNotFound) {
+		return
+	}
+	if err != nil {
+		log.Printf("resolving manufacturing record failed for inspection %s: %v", inspection.ID, err)
+		return
+	}
+	inspection.AttachManufacturingRecord(record)
+}
+
+// evaluateCompleted は完了した検査を管理図で判定し、不合格の場合は NCR を起票する。
+// 判定・起票に失敗しても検査の登録は取り消さない
+func (uc *QualityUseCase) evaluateCompleted(ctx context.Context, inspection *domain.Inspection) *InspectionOutput {
+	violations, err := uc.spcService.Evaluate(ctx, inspection)
+	if err != nil {
+		log.Printf("SPC evaluation failed for inspection %s: %v", inspection.ID, err)
+	}
+	
+	output := convertToInspectionOutput(inspection)
+	output.SPCViolations = convertToRuleViolationOutputs(violations)
+	
+	if inspection.FinalResult == domain.ResultFail {
+		ncr, err := uc.ncrService.Raise(ctx, inspection)
+		if err != nil {
+			log.Printf("raising nonconformance failed for inspection %s: %v", inspection.ID, err)
+		} else {
+			output.NonconformanceID = string(ncr.ID)
+		}
+	}
+	return output
+}
+
+// measure は検査計画にある特性を計画の規格で、それ以外を入力の規格で判定する
+func measure(plan *domain.InspectionPlan, m MeasurementInput) (domain.MeasurementResult, error) {
+	if plan != nil {
+		if c, ok := plan.Characteristic(m.ParameterName); ok {
+			return c.Measure(m.MeasuredValue, m.ActualSize)
+		}
+	}
+	return newMeasurementResult(m)
+}
+
+// newMeasurementResult は入力の公差指定から規格を作り、ドメインで合否を判定する
+func newMeasurementResult(m MeasurementInput) (domain.MeasurementResult, error) {
+	characteristicType, err := domain.ParseCharacteristicType(m.CharacteristicType)
+	if err != nil {
+		return domain.MeasurementResult{}, fmt.Errorf("%s: %w", m.ParameterName, err)
+	}
+	characteristic, err := domain.NewCharacteristic(domain.ToleranceSpec{
+		Type:                  characteristicType,
+		Target:                m.TargetValue,
+		Tolerance:             m.Tolerance,
+		UpperDeviation:        m.UpperDeviation,
+		LowerDeviation:        m.LowerDeviation,
+		UpperLimit:            m.UpperLimit,
+		LowerLimit:            m.LowerLimit,
+		MaterialCondition:     domain.MaterialCondition(m.MaterialCondition),
+		MaterialConditionSize: m.MaterialConditionSize,
+		Feature:               domain.FeatureOfSize(m.FeatureOfSize),
+		ActualSize:            m.ActualSize,
+	})
+	if err != nil {
+		return domain.MeasurementResult{}, fmt.Errorf("%s: %w", m.ParameterName, err)
+	}
+	return domain.NewMeasurementResult(m.ParameterName, m.MeasuredValue, m.TargetValue, m.Unit, characteristic)
+}
@@ -0,0 +1,195 @@
+package application
+
+import (
+	"context"
+	"goNexttask/internal/quality/domain"
+	"time"
+)
+
+// ControlChartInput は管理図の対象。SubgroupSize が 0 の場合は固定した管理限界か管理図の種類の既定値
+type ControlChartInput struct {
+	ParameterName string
+	PartID        string
+	ChartType     string
+	SubgroupSize  int
+	From          *time.Time
+	To            *time.Time
+}
+
+// FreezeControlLimitsInput はベースライン期間 [BaselineFrom, BaselineTo) から管理限界を固定する要求
+type FreezeControlLimitsInput struct {
+	ParameterName string
+	PartID        string
+	ChartType     string
+	SubgroupSize  int
+	BaselineFrom  time.Time
+	BaselineTo    time.Time
+	FrozenBy      string
+}
+
+type ControlLimitsOutput struct {
+	ParameterName    string
+	PartID           string
+	ChartType        string
+	SubgroupSize     int
+	Center           float64
+	UCL              float64
+	LCL              float64
+	DispersionCenter float64
+	DispersionUCL    float64
+	DispersionLCL    float64
+	BaselineFrom     time.Time
+	BaselineTo       time.Time
+	BaselinePoints   int
+	FrozenBy         string
+	FrozenAt         time.Time
+}
+
+type ChartPointOutput struct {
+	Index         int
+	InspectionIDs []string
+	MeasuredAt    time.Time
+	SampleSize    int
+	Value         float64
+	Dispersion    *float64
+}
+
+type RuleViolationOutput struct {
+	ParameterName string
+	ChartType     string
+	Chart         string
+	Rule          int
+	Description   string
+	StartIndex    int
+	PointIndex    int
+	Value         float64
+}
+
+type ControlChartOutput struct {
+	ParameterName  string
+	PartID         string
+	ChartType      string
+	SubgroupSize   int
+	Frozen         bool
+	Limits         *ControlLimitsOutput
+	Points         []ChartPointOutput
+	PendingSamples int
+	Violations     []RuleViolationOutput
+	Warnings       []string
+}
+
+// GetControlChart は測定値から管理図を作成し、判定ルールに該当した打点を返す
+func (uc *QualityUseCase) GetControlChart(ctx context.Context, input ControlChartInput) (*ControlChartOutput, error) {
+	chartType, err := domain.ParseChartType(input.ChartType)
+	if err != nil {
+		return nil, err
+	}
+	if input.SubgroupSize < 0 {
+		return nil, domain.ErrInvalidSubgroupSize
+	}
+
+	chart, err := uc.spcService.Chart(ctx, domain.ChartQuery{
+		ParameterName: input.ParameterName,
+		PartID:        input.PartID,
+		ChartType:     chartType,
+		SubgroupSize:  input.SubgroupSize,
+		From:          input.From,
+		To:            input.To,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	output := &ControlChartOutput{
+		ParameterName:  chart.ParameterName,
+		PartID:         chart.PartID,
+		ChartType:      string(chart.ChartType),
+		SubgroupSize:   chart.SubgroupSize,
+		Frozen:         chart.Frozen,
+		Points:         make([]ChartPointOutput, len(chart.Points)),
+		PendingSamples: chart.PendingSamples,
+		Violations:     convertToRuleViolationOutputs(chart.Violations),
+		Warnings:       chart.Warnings,
+	}
+	if chart.Limits != nil {
+		output.Limits = convertToControlLimitsOutput(chart.Limits)
+	}
+	for i, p := range chart.Points {
+		ids := make([]string, len(p.InspectionIDs))
+		for j, id := range p.InspectionIDs {
+			ids[j] = string(id)
+		}
+		output.Points[i] = ChartPointOutput{
+			Index:         p.Index,
+			InspectionIDs: ids,
+			MeasuredAt:    p.MeasuredAt,
+			SampleSize:    p.SampleSize,
+			Value:         p.Value,
+			Dispersion:    p.Dispersion,
+		}
+	}
+
+	return output, nil
+}
+
+// FreezeControlLimits はベースライン期間の測定値から管理限界を計算して固定する。以降の検査はこの限界で判定する
+func (uc *QualityUseCase) FreezeControlLimits(ctx context.Context, input FreezeControlLimitsInput) (*ControlLimitsOutput, error) {
+	chartType, err := domain.ParseChartType(input.ChartType)
+	if err != nil {
+		return nil, err
+	}
+	if input.SubgroupSize < 0 {
+		return nil, domain.ErrInvalidSubgroupSize
+	}
+
+	limits, err := uc.spcService.FreezeLimits(ctx, domain.ChartQuery{
+		ParameterName: input.ParameterName,
+		PartID:        input.PartID,
+		ChartType:     chartType,
+		SubgroupSize:  input.SubgroupSize,
+		From:          &input.BaselineFrom,
+		To:            &input.BaselineTo,
+	}, input.FrozenBy)
+	if err != nil {
+		return nil, err
+	}
+
+	return convertToControlLimitsOutput(limits), nil
+}
+
+func convertToControlLimitsOutput(limits *domain.ControlLimits) *ControlLimitsOutput {
+	return &ControlLimitsOutput{
+		ParameterName:    limits.ParameterName,
+		PartID:           limits.PartID,
+		ChartType:        string(limits.ChartType),
+		SubgroupSize:     limits.SubgroupSize,
+		Center:           limits.Center,
+		UCL:              limits.UCL,
+		LCL:              limits.LCL,
+		DispersionCenter: limits.DispersionCenter,
+		DispersionUCL:    limits.DispersionUCL,
+		DispersionLCL:    limits.DispersionLCL,
+		BaselineFrom:     limits.BaselineFrom,
+		BaselineTo:       limits.BaselineTo,
+		BaselinePoints:   limits.BaselinePoints,
+		FrozenBy:         limits.FrozenBy,
+		FrozenAt:         limits.FrozenAt,
+	}
+}
+
+func convertToRuleViolationOutputs(violations []domain.RuleViolation) []RuleViolationOutput {
+	outputs := make([]RuleViolationOutput, len(violations))
+	for i, v := range violations {
+		outputs[i] = RuleViolationOutput{
+			ParameterName: v.ParameterName,
+			ChartType:     string(v.ChartType),
+			Chart:         v.Chart,
+			Rule:          int(v.Rule),
+			Description:   v.Description,
+			StartIndex:    v.StartIndex,
+			PointIndex:    v.PointIndex,
+			Value:         v.Value,
+		}
+	}
+	return outputs
+}
@@ -0,0 +1,31 @@
+package domain
+
+import (
+	"fmt"
+	"time"
+)
+
+// QualityAlert は品質上の注意を記録する（quality_alerts）
+type QualityAlert struct {
+	ID           int64
+	InspectionID InspectionID
+	AlertType    string
+	Message      string
+	CreatedAt    time.Time
+}
+
+// NewSPCAlert は管理図の判定ルールへの該当をアラートにする。種別は "spc_rule_<番号>"
+func NewSPCAlert(inspection *Inspection, limits *ControlLimits, violation RuleViolation) *QualityAlert {
+	partID := limits.PartID
+	if partID == "" {
+		partID = "all parts"
+	}
+	return &QualityAlert{
+		InspectionID: inspection.ID,
+		AlertType:    fmt.Sprintf("spc_rule_%d", violation.Rule),
+		Message: fmt.Sprintf("%s (%s, %s chart %s): Nelson rule %d - %s at point %d (value=%g, CL=%g, UCL=%g, LCL=%g)",
+			limits.ParameterName, partID, limits.ChartType, violation.Chart, violation.Rule, violation.Description,
+			violation.PointIndex, violation.Value, limits.Center, limits.UCL, limits.LCL),
+		CreatedAt: time.Now(),
+	}
+}
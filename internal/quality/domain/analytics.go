@@ -0,0 +1,274 @@
+package domain
+
+import (
+	"sort"
+	"time"
+)
+
+// UnspecifiedDefectCode は不合格の測定が記録されていない不合格の検査の不具合コード
+const UnspecifiedDefectCode = "UNSPECIFIED"
+
+// unknownKey は集計の軸の値が記録されていない検査の集計先
+const unknownKey = "unknown"
+
+// AnalyticsDimension はパレート図・初回合格率の集計の軸
+type AnalyticsDimension string
+
+const (
+	DimensionDefect   AnalyticsDimension = "defect"
+	DimensionPart     AnalyticsDimension = "part"
+	DimensionMachine  AnalyticsDimension = "machine"
+	DimensionProgram  AnalyticsDimension = "program"
+	DimensionOperator AnalyticsDimension = "operator"
+)
+
+func ParseAnalyticsDimension(s string) (AnalyticsDimension, error) {
+	switch d := AnalyticsDimension(s); d {
+	case DimensionDefect, DimensionPart, DimensionMachine, DimensionProgram, DimensionOperator:
+		return d, nil
+	default:
+		return "", ErrInvalidAnalyticsQuery
+	}
+}
+
+// AnalyticsFilter は集計する検査の条件。空の条件では絞り込まない。ProgramID は NC プログラムの版ごとの ID
+type AnalyticsFilter struct {
+	PartID     string
+	MachineID  string
+	ProgramID  string
+	OperatorID string
+	From       *time.Time
+	To         *time.Time
+}
+
+func (f AnalyticsFilter) Validate() error {
+	if f.From != nil && f.To != nil && !f.From.Before(*f.To) {
+		return ErrInvalidAnalyticsQuery
+	}
+	return nil
+}
+
+// InspectionOutcome は完了した検査の合否と集計の軸。OperatorID は作業者（記録が無い場合は検査員）
+type InspectionOutcome struct {
+	InspectionID InspectionID
+	LotNumber    string
+	Operation    string
+	PartID       string
+	MachineID    string
+	ProgramID    string
+	OperatorID   string
+	InspectedAt  time.Time
+	Passed       bool
+	// Reinspection は同じロット・工程の2回目以降の検査（集計の条件に関係なく、すべての検査の中での順番で決める）
+	Reinspection bool
+	// DefectCodes は不合格の測定ごとの不具合コード
+	DefectCodes []string
+}
+
+// Defects は検査の不具合コード。不合格で測定の記録が無い検査は UNSPECIFIED を 1 件とする
+func (o InspectionOutcome) Defects() []string {
+	if !o.Passed && len(o.DefectCodes) == 0 {
+		return []string{UnspecifiedDefectCode}
+	}
+	return o.DefectCodes
+}
+
+// key は検査の集計の軸の値。不具合の軸は検査ごとではなく不具合コードごとのため扱わない
+func (o InspectionOutcome) key(dimension AnalyticsDimension) string {
+	var key string
+	switch dimension {
+	case DimensionPart:
+		key = o.PartID
+	case DimensionMachine:
+		key = o.MachineID
+	case DimensionProgram:
+		key = o.ProgramID
+	case DimensionOperator:
+		key = o.OperatorID
+	}
+	if key == "" {
+		return unknownKey
+	}
+	return key
+}
+
+type ParetoEntry struct {
+	Key                  string
+	Count                int
+	Percentage           float64
+	CumulativePercentage float64
+}
+
+// DefectTrendPoint は週（月曜始まり、UTC）ごとの不具合の件数
+type DefectTrendPoint struct {
+	WeekStart         time.Time
+	Inspections       int
+	FailedInspections int
+	Defects           int
+	Counts            map[string]int
+}
+
+// DefectPareto は不具合の件数を軸の値ごとに多い順に並べ、累積比率を付けたもの
+type DefectPareto struct {
+	Dimension         AnalyticsDimension
+	Inspections       int
+	FailedInspections int
+	TotalDefects      int
+	Entries           []ParetoEntry
+	Trend             []DefectTrendPoint
+}
+
+// BuildDefectPareto は不具合を dimension の値ごとに数える。不具合の軸以外では検査の不具合をその検査の軸の値に数える
+func BuildDefectPareto(outcomes []InspectionOutcome, dimension AnalyticsDimension) *DefectPareto {
+	pareto := &DefectPareto{
+		Dimension:   dimension,
+		Inspections: len(outcomes),
+	}
+
+	counts := make(map[string]int)
+	weeks := make(map[time.Time]*DefectTrendPoint)
+	for _, o := range outcomes {
+		week := WeekStart(o.InspectedAt)
+		point, ok := weeks[week]
+		if !ok {
+			point = &DefectTrendPoint{WeekStart: week, Counts: make(map[string]int)}
+			weeks[week] = point
+		}
+		point.Inspections++
+		if !o.Passed {
+			pareto.FailedInspections++
+			point.FailedInspections++
+		}
+
+		for _, code := range o.Defects() {
+			key := code
+			if dimension != DimensionDefect {
+				key = o.key(dimension)
+			}
+			counts[key]++
+			point.Counts[key]++
+			point.Defects++
+			pareto.TotalDefects++
+		}
+	}
+
+	for key, count := range counts {
+		pareto.Entries = append(pareto.Entries, ParetoEntry{Key: key, Count: count})
+	}
+	sort.Slice(pareto.Entries, func(i, j int) bool {
+		if pareto.Entries[i].Count != pareto.Entries[j].Count {
+			return pareto.Entries[i].Count > pareto.Entries[j].Count
+		}
+		return pareto.Entries[i].Key < pareto.Entries[j].Key
+	})
+	cumulative := 0
+	for i := range pareto.Entries {
+		cumulative += pareto.Entries[i].Count
+		pareto.Entries[i].Percentage = float64(pareto.Entries[i].Count) / float64(pareto.TotalDefects) * 100
+		pareto.Entries[i].CumulativePercentage = float64(cumulative) / float64(pareto.TotalDefects) * 100
+	}
+
+	for _, point := range weeks {
+		pareto.Trend = append(pareto.Trend, *point)
+	}
+	sort.Slice(pareto.Trend, func(i, j int) bool {
+		return pareto.Trend[i].WeekStart.Before(pareto.Trend[j].WeekStart)
+	})
+	return pareto
+}
+
+// YieldGroup は初回の検査の件数と、そのうち合格した件数
+type YieldGroup struct {
+	Key              string
+	FirstInspections int
+	FirstPassed      int
+	FirstPassYield   float64
+}
+
+// YieldTrendPoint は週（月曜始まり、UTC）ごとの初回合格率
+type YieldTrendPoint struct {
+	WeekStart time.Time
+	YieldGroup
+}
+
+// FirstPassYieldReport は全体・軸の値ごと・週ごとの初回合格率。Reinspections は手直し後などの 2 回目以降の検査の件数
+type FirstPassYieldReport struct {
+	Dimension     AnalyticsDimension
+	Overall       YieldGroup
+	Groups        []YieldGroup
+	Trend         []YieldTrendPoint
+	Reinspections int
+}
+
+// CalculateFirstPassYield はロット・工程ごとに最初の検査だけを数え、合格した割合を求める。
+// 最初の検査が集計の条件から外れたロット・工程の再検査は数えない。dimension が空の場合は全体と週ごとのみ
+func CalculateFirstPassYield(outcomes []InspectionOutcome, dimension AnalyticsDimension) *FirstPassYieldReport {
+	report := &FirstPassYieldReport{Dimension: dimension}
+
+	groups := make(map[string]*YieldGroup)
+	weeks := make(map[time.Time]*YieldTrendPoint)
+	for _, o := range outcomes {
+		if o.Reinspection {
+			report.Reinspections++
+			continue
+		}
+
+		week := WeekStart(o.InspectedAt)
+		point, ok := weeks[week]
+		if !ok {
+			point = &YieldTrendPoint{WeekStart: week}
+			weeks[week] = point
+		}
+		targets := []*YieldGroup{&report.Overall, &point.YieldGroup}
+		if dimension != "" {
+			key := o.key(dimension)
+			group, ok := groups[key]
+			if !ok {
+				group = &YieldGroup{Key: key}
+				groups[key] = group
+			}
+			targets = append(targets, group)
+		}
+		for _, g := range targets {
+			g.FirstInspections++
+			if o.Passed {
+				g.FirstPassed++
+			}
+		}
+	}
+
+	report.Overall.calculate()
+	for _, group := range groups {
+		group.calculate()
+		report.Groups = append(report.Groups, *group)
+	}
+	// 初回合格率の低い順（改善の対象の順）
+	sort.Slice(report.Groups, func(i, j int) bool {
+		if report.Groups[i].FirstPassYield != report.Groups[j].FirstPassYield {
+			return report.Groups[i].FirstPassYield < report.Groups[j].FirstPassYield
+		}
+		return report.Groups[i].Key < report.Groups[j].Key
+	})
+	for _, point := range weeks {
+		point.calculate()
+		report.Trend = append(report.Trend, *point)
+	}
+	sort.Slice(report.Trend, func(i, j int) bool {
+		return report.Trend[i].WeekStart.Before(report.Trend[j].WeekStart)
+	})
+	return report
+}
+
+func (g *YieldGroup) calculate() {
+	if g.FirstInspections > 0 {
+		g.FirstPassYield = float64(g.FirstPassed) / float64(g.FirstInspections) * 100
+	}
+}
+
+// WeekStart は t を含む週の月曜 0 時（UTC）を返す
+func WeekStart(t time.Time) time.Time {
+	t = t.UTC()
+	offset := (int(t.Weekday()) + 6) % 7
+	day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+	return day.AddDate(0, 0, -offset)
+}
@@ -0,0 +1,491 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type CAPAID string
+
+// CAPAStatus は是正・予防処置（CAPA）の状態
+type CAPAStatus string
+
+const (
+	// CAPAStatusOpen は根本原因の分析待ち
+	CAPAStatusOpen CAPAStatus = "open"
+	// CAPAStatusInProgress は根本原因を分析し、処置を実施している状態
+	CAPAStatusInProgress CAPAStatus = "in_progress"
+	// CAPAStatusVerification は全ての処置が完了し、有効性の確認を待っている状態
+	CAPAStatusVerification CAPAStatus = "verification"
+	// CAPAStatusClosed は処置が有効と確認された状態
+	CAPAStatusClosed CAPAStatus = "closed"
+)
+
+// CAPASourceType は CAPA の発端になった記録の種類
+type CAPASourceType string
+
+const (
+	CAPASourceNonconformance CAPASourceType = "nonconformance"
+	CAPASourceSPCAlert       CAPASourceType = "spc_alert"
+)
+
+// CAPASource は CAPA に紐付けた NCR または管理図のアラート。Reference は NCR の ID かアラートの ID
+type CAPASource struct {
+	Type      CAPASourceType
+	Reference string
+}
+
+// FishboneCategory は特性要因図（4M + 測定・環境）の要因の分類
+type FishboneCategory string
+
+const (
+	FishboneMan         FishboneCategory = "man"
+	FishboneMachine     FishboneCategory = "machine"
+	FishboneMethod      FishboneCategory = "method"
+	FishboneMaterial    FishboneCategory = "material"
+	FishboneMeasurement FishboneCategory = "measurement"
+	FishboneEnvironment FishboneCategory = "environment"
+)
+
+// ParseFishboneCategory は要因の分類を検証する
+func ParseFishboneCategory(s string) (FishboneCategory, error) {
+	switch c := FishboneCategory(s); c {
+	case FishboneMan, FishboneMachine, FishboneMethod, FishboneMaterial, FishboneMeasurement, FishboneEnvironment:
+		return c, nil
+	}
+	return "", fmt.Errorf("%w: unknown fishbone category %s", ErrInvalidCAPA, s)
+}
+
+// RootCauseAnalysis はなぜなぜ分析（Whys は問いを掘り下げた順）と特性要因図の分類による根本原因
+type RootCauseAnalysis struct {
+	Whys       []string
+	Category   FishboneCategory
+	RootCause  string
+	AnalyzedBy string
+	AnalyzedAt time.Time
+}
+
+// CAPAActionType は処置の種類
+type CAPAActionType string
+
+const (
+	CAPAActionCorrective CAPAActionType = "corrective"
+	CAPAActionPreventive CAPAActionType = "preventive"
+)
+
+// CAPAAction は担当者と期日のある処置。Number は CAPA 内の 1 始まりの連番
+type CAPAAction struct {
+	Number      int
+	Type        CAPAActionType
+	Description string
+	Owner       string
+	DueDate     time.Time
+	CompletedBy string
+	CompletedAt *time.Time
+	// RemindedAt は期日超過を最後に通知した日時
+	RemindedAt *time.Time
+}
+
+// IsOverdue は未完了のまま期日を過ぎているかを返す
+func (a CAPAAction) IsOverdue(now time.Time) bool {
+	return a.CompletedAt == nil && now.After(a.DueDate)
+}
+
+// EffectivenessCheck は処置の有効性の確認結果
+type EffectivenessCheck struct {
+	Effective  bool
+	Evidence   string
+	VerifiedBy string
+	VerifiedAt time.Time
+}
+
+// CAPA は NCR や管理図のアラートを発端に、根本原因・処置・有効性の確認を記録する（ISO 9001 10.2）
+type CAPA struct {
+	ID          CAPAID
+	Title       string
+	Description string
+	Sources     []CAPASource
+	// DefectCodes は紐付けた NCR の不具合コード。再発した不具合に CAPA があるかの判定に使う
+	DefectCodes []string
+	Status      CAPAStatus
+	RootCause   *RootCauseAnalysis
+	Actions     []CAPAAction
+	// Verifications は有効性の確認の履歴（有効でなかった確認を含む）
+	Verifications []EffectivenessCheck
+	OpenedBy      string
+	CreatedAt     time.Time
+	UpdatedAt     time.Time
+	// Version は保存のたびに増える版。読み込んだ後に他で保存された CAPA の更新は ErrCAPAConflict
+	Version int
+}
+
+// NewCAPA は NCR・管理図のアラートを 1 件以上紐付けて CAPA を起票する
+func NewCAPA(title, description string, sources []CAPASource, defectCodes []string, openedBy string) (*CAPA, error) {
+	if title == "" {
+		return nil, fmt.Errorf("%w: title is required", ErrInvalidCAPA)
+	}
+	if len(sources) == 0 {
+		return nil, fmt.Errorf("%w: at least one nonconformance or SPC alert is required", ErrInvalidCAPA)
+	}
+
+	seen := make(map[CAPASource]bool)
+	for _, source := range sources {
+		switch source.Type {
+		case CAPASourceNonconformance, CAPASourceSPCAlert:
+		default:
+			return nil, fmt.Errorf("%w: unknown source type %s", ErrInvalidCAPA, source.Type)
+		}
+		if source.Reference == "" {
+			return nil, fmt.Errorf("%w: %s reference is required", ErrInvalidCAPA, source.Type)
+		}
+		if seen[source] {
+			return nil, fmt.Errorf("%w: duplicate source %s %s", ErrInvalidCAPA, source.Type, source.Reference)
+		}
+		seen[source] = true
+	}
+
+	now := time.Now()
+	return &CAPA{
+		ID:          CAPAID("capa-" + uuid.New().String()[:8]),
+		Title:       title,
+		Description: description,
+		Sources:     sources,
+		DefectCodes: uniqueSorted(defectCodes),
+		Status:      CAPAStatusOpen,
+		OpenedBy:    openedBy,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}, nil
+}
+
+func uniqueSorted(values []string) []string {
+	seen := make(map[string]bool)
+	var result []string
+	for _, v := range values {
+		if v == "" || seen[v] {
+			continue
+		}
+		seen[v] = true
+		result = append(result, v)
+	}
+	sort.Strings(result)
+	return result
+}
+
+// AnalyzeRootCause は根本原因を記録する。処置の実施中でも分析をやり直せる
+func (c *CAPA) AnalyzeRootCause(whys []string, category FishboneCategory, rootCause, analyzedBy string) error {
+	if c.Status != CAPAStatusOpen && c.Status != CAPAStatusInProgress {
+		return ErrInvalidStateTransition
+	}
+	if len(whys) == 0 {
+		return fmt.Errorf("%w: at least one why is required", ErrInvalidCAPA)
+	}
+	for _, why := range whys {
+		if strings.TrimSpace(why) == "" {
+			return fmt.Errorf("%w: whys must not be empty", ErrInvalidCAPA)
+		}
+	}
+	if _, err := ParseFishboneCategory(string(category)); err != nil {
+		return err
+	}
+	if rootCause == "" {
+		// 根本原因を省略した場合は最後のなぜを根本原因とする
+		rootCause = whys[len(whys)-1]
+	}
+
+	now := time.Now()
+	c.RootCause = &RootCauseAnalysis{
+		Whys:       whys,
+		Category:   category,
+		RootCause:  rootCause,
+		AnalyzedBy: analyzedBy,
+		AnalyzedAt: now,
+	}
+	c.Status = CAPAStatusInProgress
+	c.UpdatedAt = now
+	return nil
+}
+
+// AddAction は処置を追加する。根本原因の分析後に追加でき、有効性の確認待ちの場合は処置の実施中に戻す
+func (c *CAPA) AddAction(actionType CAPAActionType, description, owner string, dueDate time.Time) (*CAPAAction, error) {
+	if c.RootCause == nil || c.Status == CAPAStatusClosed {
+		return nil, ErrInvalidStateTransition
+	}
+	if actionType != CAPAActionCorrective && actionType != CAPAActionPreventive {
+		return nil, fmt.Errorf("%w: unknown action type %s", ErrInvalidCAPA, actionType)
+	}
+	if description == "" || owner == "" {
+		return nil, fmt.Errorf("%w: action description and owner are required", ErrInvalidCAPA)
+	}
+	if dueDate.IsZero() {
+		return nil, fmt.Errorf("%w: action due date is required", ErrInvalidCAPA)
+	}
+
+	c.Actions = append(c.Actions, CAPAAction{
+		Number:      len(c.Actions) + 1,
+		Type:        actionType,
+		Description: description,
+		Owner:       owner,
+		DueDate:     dueDate,
+	})
+	c.Status = CAPAStatusInProgress
+	c.UpdatedAt = time.Now()
+	return &c.Actions[len(c.Actions)-1], nil
+}
+
+// CompleteAction は処置を完了する。全ての処置が完了すると有効性の確認待ちになる
+func (c *CAPA) CompleteAction(number int, completedBy string) error {
+	if c.Status != CAPAStatusInProgress {
+		return ErrInvalidStateTransition
+	}
+	action := c.action(number)
+	if action == nil {
+		return fmt.Errorf("%w: action %d", ErrCAPAActionNotFound, number)
+	}
+	if action.CompletedAt != nil {
+		return ErrInvalidStateTransition
+	}
+
+	now := time.Now()
+	action.CompletedBy = completedBy
+	action.CompletedAt = &now
+	if len(c.OpenActions()) == 0 {
+		c.Status = CAPAStatusVerification
+	}
+	c.UpdatedAt = now
+	return nil
+}
+
+func (c *CAPA) action(number int) *CAPAAction {
+	for i := range c.Actions {
+		if c.Actions[i].Number == number {
+			return &c.Actions[i]
+		}
+	}
+	return nil
+}
+
+// OpenActions は未完了の処置を返す
+func (c *CAPA) OpenActions() []CAPAAction {
+	var open []CAPAAction
+	for _, a := range c.Actions {
+		if a.CompletedAt == nil {
+			open = append(open, a)
+		}
+	}
+	return open
+}
+
+// OverdueActions は期日を過ぎた未完了の処置を返す
+func (c *CAPA) OverdueActions(now time.Time) []CAPAAction {
+	var overdue []CAPAAction
+	for _, a := range c.Actions {
+		if a.IsOverdue(now) {
+			overdue = append(overdue, a)
+		}
+	}
+	return overdue
+}
+
+// VerifyEffectiveness は処置の有効性を確認する。有効なら CAPA を閉じ、有効でなければ処置の実施中に戻す
+// （追加の処置が必要）
+func (c *CAPA) VerifyEffectiveness(effective bool, evidence, verifiedBy string) error {
+	if c.Status != CAPAStatusVerification {
+		return ErrInvalidStateTransition
+	}
+	if evidence == "" {
+		return fmt.Errorf("%w: evidence is required to verify effectiveness", ErrInvalidCAPA)
+	}
+
+	now := time.Now()
+	c.Verifications = append(c.Verifications, EffectivenessCheck{
+		Effective:  effective,
+		Evidence:   evidence,
+		VerifiedBy: verifiedBy,
+		VerifiedAt: now,
+	})
+	if effective {
+		c.Status = CAPAStatusClosed
+	} else {
+		c.Status = CAPAStatusInProgress
+	}
+	c.UpdatedAt = now
+	return nil
+}
+
+// CoversDefect は CAPA が不具合コードを扱っているかを返す
+func (c *CAPA) CoversDefect(code string) bool {
+	for _, d := range c.DefectCodes {
+		if d == code {
+			return true
+		}
+	}
+	return false
+}
+
+// CAPAService は CAPA の起票時に紐付ける記録を確認し、期日を過ぎた処置を通知する
+type CAPAService struct {
+	repo      CAPARepository
+	ncrRepo   NonconformanceRepository
+	alertRepo QualityAlertRepository
+	publisher EventPublisher
+}
+
+func NewCAPAService(repo CAPARepository, ncrRepo NonconformanceRepository, alertRepo QualityAlertRepository, publisher EventPublisher) *CAPAService {
+	return &CAPAService{
+		repo:      repo,
+		ncrRepo:   ncrRepo,
+		alertRepo: alertRepo,
+		publisher: publisher,
+	}
+}
+
+// Open は紐付ける NCR・アラートが存在することを確認して CAPA を起票する。不具合コードは NCR から引き継ぐ
+func (s *CAPAService) Open(ctx context.Context, title, description string, sources []CAPASource, openedBy string) (*CAPA, error) {
+	var defectCodes []string
+	for _, source := range sources {
+		switch source.Type {
+		case CAPASourceNonconformance:
+			ncr, err := s.ncrRepo.FindByID(ctx, NonconformanceID(source.Reference))
+			if err != nil {
+				return nil, err
+			}
+			for _, d := range ncr.Defects {
+				defectCodes = append(defectCodes, d.Code)
+			}
+		case CAPASourceSPCAlert:
+			id, err := strconv.ParseInt(source.Reference, 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("%w: invalid SPC alert id %s", ErrInvalidCAPA, source.Reference)
+			}
+			if _, err := s.alertRepo.FindByID(ctx, id); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	capa, err := NewCAPA(title, description, sources, defectCodes, openedBy)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.repo.Save(ctx, capa); err != nil {
+		return nil, err
+	}
+	return capa, nil
+}
+
+// RemindOverdue は期日を過ぎた未完了の処置を担当者へ通知する。同じ処置の通知は interval ごとに 1 回
+func (s *CAPAService) RemindOverdue(ctx context.Context, now time.Time, interval time.Duration) (int, error) {
+	capas, err := s.repo.FindActive(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	reminded := 0
+	for _, capa := range capas {
+		var overdue []CAPAAction
+		for i := range capa.Actions {
+			action := &capa.Actions[i]
+			if !action.IsOverdue(now) || (action.RemindedAt != nil && now.Sub(*action.RemindedAt) < interval) {
+				continue
+			}
+			remindedAt := now
+			action.RemindedAt = &remindedAt
+			overdue = append(overdue, *action)
+		}
+		if len(overdue) == 0 {
+			continue
+		}
+
+		// 通知した日時を先に保存する。読み込んだ後に処置の完了などが保存されていた場合は次回に回す
+		if err := s.repo.Update(ctx, capa); err != nil {
+			if errors.Is(err, ErrCAPAConflict) {
+				continue
+			}
+			return reminded, err
+		}
+		for _, action := range overdue {
+			if err := s.publisher.Publish(ctx, NewCAPAActionOverdueEvent(capa, action, now)); err != nil {
+				return reminded, err
+			}
+			reminded++
+		}
+	}
+	return reminded, nil
+}
+
+// CAPASuggestion は CAPA の無いまま複数の NCR で再発している不具合
+type CAPASuggestion struct {
+	DefectCode        string
+	ParameterName     string
+	Occurrences       int
+	NonconformanceIDs []NonconformanceID
+	LotNumbers        []string
+}
+
+// SuggestCAPAs は since 以降に起票した NCR のうち、同じ不具合コードが minOccurrences 件以上の NCR にあり、
+// 未完了の CAPA で扱っていないものを再発の多い順に返す
+func (s *DefectAnalysisService) SuggestCAPAs(ctx context.Context, since time.Time, minOccurrences int) ([]CAPASuggestion, error) {
+	if minOccurrences < 2 {
+		return nil, fmt.Errorf("%w: a recurring defect needs at least 2 occurrences", ErrInvalidCAPA)
+	}
+
+	ncrs, err := s.ncrRepo.FindCreatedSince(ctx, since)
+	if err != nil {
+		return nil, err
+	}
+	capas, err := s.capaRepo.FindActive(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	byCode := make(map[string]*CAPASuggestion)
+	lots := make(map[string]map[string]bool)
+	for _, ncr := range ncrs {
+		for _, d := range ncr.Defects {
+			suggestion, ok := byCode[d.Code]
+			if !ok {
+				suggestion = &CAPASuggestion{DefectCode: d.Code, ParameterName: d.ParameterName}
+				byCode[d.Code] = suggestion
+				lots[d.Code] = make(map[string]bool)
+			}
+			suggestion.Occurrences++
+			suggestion.NonconformanceIDs = append(suggestion.NonconformanceIDs, ncr.ID)
+			if !lots[d.Code][ncr.LotNumber] {
+				lots[d.Code][ncr.LotNumber] = true
+				suggestion.LotNumbers = append(suggestion.LotNumbers, ncr.LotNumber)
+			}
+		}
+	}
+
+	var suggestions []CAPASuggestion
+	for code, suggestion := range byCode {
+		if suggestion.Occurrences < minOccurrences || coveredByCAPA(capas, code) {
+			continue
+		}
+		suggestions = append(suggestions, *suggestion)
+	}
+	sort.Slice(suggestions, func(i, j int) bool {
+		if suggestions[i].Occurrences != suggestions[j].Occurrences {
+			return suggestions[i].Occurrences > suggestions[j].Occurrences
+		}
+		return suggestions[i].DefectCode < suggestions[j].DefectCode
+	})
+	return suggestions, nil
+}
+
+func coveredByCAPA(capas []*CAPA, code string) bool {
+	for _, capa := range capas {
+		if capa.CoversDefect(code) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,229 @@
+package domain
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"time"
+)
+
+// DefaultMinCapabilitySamples は工程能力の評価に必要な既定の最小サンプル数
+const DefaultMinCapabilitySamples = 30
+
+// MeasurementSample は工程能力・管理図の計算に使う 1 件の測定値
+type MeasurementSample struct {
+	InspectionID  InspectionID
+	LotNumber     string
+	ParameterName string
+	Value         float64
+	TargetValue   float64
+	// LowerLimit・UpperLimit は規格の下限・上限。片側規格では一方が nil
+	LowerLimit *float64
+	UpperLimit *float64
+	Unit       string
+	Pass       bool
+	MeasuredAt time.Time
+}
+
+// MeasurementFilter は測定値の抽出条件。空の条件では絞り込まない
+type MeasurementFilter struct {
+	LotNumber     string
+	PartID        string
+	ParameterName string
+	From          *time.Time
+	To            *time.Time
+}
+
+func (f MeasurementFilter) Validate() error {
+	if f.From != nil && f.To != nil && !f.From.Before(*f.To) {
+		return ErrInvalidMeasurementFilter
+	}
+	return nil
+}
+
+// SigmaMethod は群内標準偏差の推定方法
+type SigmaMethod string
+
+const (
+	// SigmaPooled はロットを群とした合併標準偏差
+	SigmaPooled SigmaMethod = "pooled"
+	// SigmaMovingRange は時系列の移動範囲の平均 / d2（ロットごとに 1 件しか無い場合）
+	SigmaMovingRange SigmaMethod = "moving_range"
+)
+
+// CapabilityStudy はパラメーターごとの工程能力。指数は計算できない場合 nil
+type CapabilityStudy struct {
+	ParameterName string
+	Unit          string
+	SampleSize    int
+	SubgroupCount int
+	Mean          float64
+	// SigmaWithin は群内（短期）の標準偏差で Cp/Cpk に使う
+	SigmaWithin float64
+	// SigmaOverall は全体（長期）の標準偏差で Pp/Ppk に使う
+	SigmaOverall float64
+	SigmaMethod  SigmaMethod
+	Target       float64
+	// LSL・USL は規格の下限・上限。片側規格では Cp・Pp は計算せず、Cpk・Ppk は片側の指数
+	LSL       *float64
+	USL       *float64
+	Cp        *float64
+	Cpk       *float64
+	Pp        *float64
+	Ppk       *float64
+	Normality *NormalityTest
+	// Warnings はデータ不足・規格の変更・非正規性など、結果の信頼性に関する注意
+	Warnings []string
+}
+
+// AnalyzeCapability は測定値をパラメーターごとに分けて工程能力を計算する
+func AnalyzeCapability(samples []MeasurementSample, minSamples int) []*CapabilityStudy {
+	byParameter := make(map[string][]MeasurementSample)
+	for _, s := range samples {
+		byParameter[s.ParameterName] = append(byParameter[s.ParameterName], s)
+	}
+
+	names := make([]string, 0, len(byParameter))
+	for name := range byParameter {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	studies := make([]*CapabilityStudy, len(names))
+	for i, name := range names {
+		studies[i] = NewCapabilityStudy(name, byParameter[name], minSamples)
+	}
+	return studies
+}
+
+// NewCapabilityStudy は 1 つのパラメーターの測定値から工程能力を計算する。
+// 規格は最新の測定に記録した下限・上限（片側規格は一方のみ）を用い、サンプル数が minSamples 未満の場合は警告を付ける
+func NewCapabilityStudy(parameter string, samples []MeasurementSample, minSamples int) *CapabilityStudy {
+	if minSamples <= 0 {
+		minSamples = DefaultMinCapabilitySamples
+	}
+	study := &CapabilityStudy{ParameterName: parameter, SampleSize: len(samples)}
+	if len(samples) == 0 {
+		study.Warnings = append(study.Warnings, "no measurements in the selected range")
+		return study
+	}
+
+	sorted := append([]MeasurementSample(nil), samples...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].MeasuredAt.Before(sorted[j].MeasuredAt)
+	})
+
+	latest := sorted[len(sorted)-1]
+	study.Unit = latest.Unit
+	study.Target = latest.TargetValue
+	study.LSL = latest.LowerLimit
+	study.USL = latest.UpperLimit
+
+	values := make([]float64, len(sorted))
+	specChanged := false
+	for i, s := range sorted {
+		values[i] = s.Value
+		if !sameLimit(s.LowerLimit, latest.LowerLimit) || !sameLimit(s.UpperLimit, latest.UpperLimit) {
+			specChanged = true
+		}
+	}
+	if specChanged {
+		study.Warnings = append(study.Warnings, "specification changed within the range; the latest limits are used")
+	}
+	if len(values) < minSamples {
+		study.Warnings = append(study.Warnings,
+			fmt.Sprintf("insufficient data: %d samples, at least %d required for a reliable estimate", len(values), minSamples))
+	}
+
+	study.Mean = mean(values)
+	if len(values) < 2 {
+		study.Warnings = append(study.Warnings, "at least 2 samples are required to estimate sigma")
+		return study
+	}
+	study.SigmaOverall = sampleStdDev(values)
+	study.SigmaWithin, study.SigmaMethod, study.SubgroupCount = withinSigma(sorted)
+
+	switch {
+	case study.LSL == nil && study.USL == nil:
+		study.Warnings = append(study.Warnings, "no specification limits; capability indices are not computed")
+	case study.LSL != nil && study.USL != nil && *study.USL <= *study.LSL:
+		study.Warnings = append(study.Warnings, "specification has no width; capability indices are not computed")
+	case study.SigmaOverall == 0:
+		study.Warnings = append(study.Warnings, "measurements have no variation; capability indices are not computed")
+	default:
+		study.Pp, study.Ppk = capabilityIndices(study.Mean, study.SigmaOverall, study.LSL, study.USL)
+		if study.SigmaWithin > 0 {
+			study.Cp, study.Cpk = capabilityIndices(study.Mean, study.SigmaWithin, study.LSL, study.USL)
+		}
+	}
+
+	study.Normality = andersonDarling(values)
+	switch {
+	case study.Normality == nil:
+		study.Warnings = append(study.Warnings,
+			fmt.Sprintf("normality is not tested with fewer than %d samples or no variation", minNormalityTestSamples))
+	case !study.Normality.Normal:
+		study.Warnings = append(study.Warnings,
+			fmt.Sprintf("data is not normally distributed (Anderson-Darling p=%.3f); indices may be misleading", study.Normality.PValue))
+	}
+
+	return study
+}
+
+// withinSigma は群内標準偏差を推定する。2 件以上あるロットがあればロットを群とした合併標準偏差、
+// 無ければ時系列の移動範囲から推定する
+func withinSigma(sorted []MeasurementSample) (float64, SigmaMethod, int) {
+	lots := make(map[string][]float64)
+	var order []string
+	for _, s := range sorted {
+		if _, ok := lots[s.LotNumber]; !ok {
+			order = append(order, s.LotNumber)
+		}
+		lots[s.LotNumber] = append(lots[s.LotNumber], s.Value)
+	}
+
+	var ss float64
+	var df int
+	for _, lot := range order {
+		values := lots[lot]
+		if len(values) < 2 {
+			continue
+		}
+		sd := sampleStdDev(values)
+		ss += sd * sd * float64(len(values)-1)
+		df += len(values) - 1
+	}
+	if df > 0 {
+		return math.Sqrt(ss/float64(df)) / c4(df+1), SigmaPooled, len(order)
+	}
+
+	var mr float64
+	for i := 1; i < len(sorted); i++ {
+		mr += math.Abs(sorted[i].Value - sorted[i-1].Value)
+	}
+	return mr / float64(len(sorted)-1) / d2[2], SigmaMovingRange, len(sorted)
+}
+
+// capabilityIndices は Cp（両側規格のみ）と Cpk（片側規格では Cpu または Cpl）を計算する
+func capabilityIndices(mu, sigma float64, lsl, usl *float64) (*float64, *float64) {
+	var potential *float64
+	actual := math.Inf(1)
+	if lsl != nil {
+		actual = (mu - *lsl) / (3 * sigma)
+	}
+	if usl != nil {
+		actual = math.Min(actual, (*usl-mu)/(3*sigma))
+	}
+	if lsl != nil && usl != nil {
+		cp := (*usl - *lsl) / (6 * sigma)
+		potential = &cp
+	}
+	return potential, &actual
+}
+
+func sameLimit(a, b *float64) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	return *a == *b
+}
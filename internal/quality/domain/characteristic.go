@@ -0,0 +1,292 @@
+package domain
+
+import (
+	"fmt"
+	"math"
+)
+
+// CharacteristicType は測定する特性の種類。寸法・表面粗さ以外は幾何公差（GD&T）
+type CharacteristicType string
+
+const (
+	CharacteristicDimension        CharacteristicType = "dimension"
+	CharacteristicSurfaceRoughness CharacteristicType = "surface_roughness"
+	CharacteristicStraightness     CharacteristicType = "straightness"
+	CharacteristicFlatness         CharacteristicType = "flatness"
+	CharacteristicCircularity      CharacteristicType = "circularity"
+	CharacteristicCylindricity     CharacteristicType = "cylindricity"
+	CharacteristicProfileLine      CharacteristicType = "profile_line"
+	CharacteristicProfileSurface   CharacteristicType = "profile_surface"
+	CharacteristicParallelism      CharacteristicType = "parallelism"
+	CharacteristicPerpendicularity CharacteristicType = "perpendicularity"
+	CharacteristicAngularity       CharacteristicType = "angularity"
+	CharacteristicPosition         CharacteristicType = "position"
+	CharacteristicConcentricity    CharacteristicType = "concentricity"
+	CharacteristicSymmetry         CharacteristicType = "symmetry"
+	CharacteristicCircularRunout   CharacteristicType = "circular_runout"
+	CharacteristicTotalRunout      CharacteristicType = "total_runout"
+)
+
+// characteristicTypes は種類ごとに幾何公差か、最大・最小実体公差方式を指定できるかを表す
+var characteristicTypes = map[CharacteristicType]struct{ geometric, materialCondition bool }{
+	CharacteristicDimension:        {false, false},
+	CharacteristicSurfaceRoughness: {false, false},
+	CharacteristicStraightness:     {true, true},
+	CharacteristicFlatness:         {true, false},
+	CharacteristicCircularity:      {true, false},
+	CharacteristicCylindricity:     {true, false},
+	CharacteristicProfileLine:      {true, false},
+	CharacteristicProfileSurface:   {true, false},
+	CharacteristicParallelism:      {true, true},
+	CharacteristicPerpendicularity: {true, true},
+	CharacteristicAngularity:       {true, true},
+	CharacteristicPosition:         {true, true},
+	CharacteristicConcentricity:    {true, false},
+	CharacteristicSymmetry:         {true, false},
+	CharacteristicCircularRunout:   {true, false},
+	CharacteristicTotalRunout:      {true, false},
+}
+
+// ParseCharacteristicType は特性の種類を解釈する。空の場合は寸法
+func ParseCharacteristicType(s string) (CharacteristicType, error) {
+	if s == "" {
+		return CharacteristicDimension, nil
+	}
+	if _, ok := characteristicTypes[CharacteristicType(s)]; !ok {
+		return "", fmt.Errorf("%w: unknown type %s", ErrInvalidCharacteristic, s)
+	}
+	return CharacteristicType(s), nil
+}
+
+// IsGeometric は公差域の大きさで判定する幾何公差（測定値は 0 以上、上限のみ）か
+func (t CharacteristicType) IsGeometric() bool {
+	return characteristicTypes[t].geometric
+}
+
+// MaterialCondition は幾何公差の公差方式（RFS: 形体のサイズに無関係、MMC: 最大実体、LMC: 最小実体）
+type MaterialCondition string
+
+const (
+	MaterialConditionRFS MaterialCondition = "rfs"
+	MaterialConditionMMC MaterialCondition = "mmc"
+	MaterialConditionLMC MaterialCondition = "lmc"
+)
+
+// FeatureOfSize はサイズ形体の種類。MMC / LMC がサイズ公差の上下どちらの側かを決める
+type FeatureOfSize string
+
+const (
+	// FeatureExternal は軸・ピン等の外側形体（MMC が最大サイズ）
+	FeatureExternal FeatureOfSize = "external"
+	// FeatureInternal は穴・溝等の内側形体（MMC が最小サイズ）
+	FeatureInternal FeatureOfSize = "internal"
+)
+
+// ToleranceSpec は図面の公差の指定。上下限（片側規格は一方のみ）、目標値からの偏差
+// （+0.02/-0.00 など）、± 対称公差の順に優先する
+type ToleranceSpec struct {
+	Type           CharacteristicType
+	Target         float64
+	Tolerance      float64
+	UpperDeviation *float64
+	LowerDeviation *float64
+	UpperLimit     *float64
+	LowerLimit     *float64
+	// MaterialCondition が MMC / LMC の場合、実測サイズが MMC / LMC サイズから Feature に応じた向きに離れた分をボーナス公差として加える
+	MaterialCondition     MaterialCondition
+	MaterialConditionSize *float64
+	ActualSize            *float64
+	Feature               FeatureOfSize
+}
+
+// Characteristic は特性の規格。LowerLimit・UpperLimit が nil の側は規格が無い
+type Characteristic struct {
+	Type                  CharacteristicType
+	LowerLimit            *float64
+	UpperLimit            *float64
+	MaterialCondition     MaterialCondition
+	MaterialConditionSize *float64
+	ActualSize            *float64
+	Feature               FeatureOfSize
+}
+
+// NewCharacteristic は公差の指定から規格を作成する。幾何公差は目標値 0・上限のみで、
+// 公差方式は真直度・姿勢・位置の公差にだけ指定できる
+func NewCharacteristic(spec ToleranceSpec) (Characteristic, error) {
+	if spec.Type == "" {
+		spec.Type = CharacteristicDimension
+	}
+	kind, ok := characteristicTypes[spec.Type]
+	if !ok {
+		return Characteristic{}, fmt.Errorf("%w: unknown type %s", ErrInvalidCharacteristic, spec.Type)
+	}
+
+	c := Characteristic{
+		Type:                  spec.Type,
+		MaterialCondition:     spec.MaterialCondition,
+		MaterialConditionSize: spec.MaterialConditionSize,
+		ActualSize:            spec.ActualSize,
+		Feature:               spec.Feature,
+	}
+
+	switch {
+	case spec.UpperLimit != nil || spec.LowerLimit != nil:
+		c.LowerLimit, c.UpperLimit = spec.LowerLimit, spec.UpperLimit
+	case spec.UpperDeviation != nil || spec.LowerDeviation != nil:
+		if spec.UpperDeviation != nil {
+			c.UpperLimit = limit(spec.Target + *spec.UpperDeviation)
+		}
+		if spec.LowerDeviation != nil {
+			c.LowerLimit = limit(spec.Target + *spec.LowerDeviation)
+		}
+	case kind.geometric:
+		c.UpperLimit = limit(spec.Target + spec.Tolerance)
+	default:
+		if spec.Tolerance < 0 {
+			return Characteristic{}, fmt.Errorf("%w: tolerance must not be negative", ErrInvalidCharacteristic)
+		}
+		c.LowerLimit = limit(spec.Target - spec.Tolerance)
+		c.UpperLimit = limit(spec.Target + spec.Tolerance)
+	}
+
+	if c.LowerLimit != nil && c.UpperLimit != nil && *c.LowerLimit > *c.UpperLimit {
+		return Characteristic{}, fmt.Errorf("%w: lower limit %g exceeds upper limit %g", ErrInvalidCharacteristic, *c.LowerLimit, *c.UpperLimit)
+	}
+	if kind.geometric {
+		if c.UpperLimit == nil || *c.UpperLimit < 0 || c.LowerLimit != nil {
+			return Characteristic{}, fmt.Errorf("%w: %s requires a single non-negative tolerance zone", ErrInvalidCharacteristic, c.Type)
+		}
+	}
+
+	switch c.Feature {
+	case "", FeatureExternal, FeatureInternal:
+	default:
+		return Characteristic{}, fmt.Errorf("%w: unknown feature of size %s", ErrInvalidCharacteristic, c.Feature)
+	}
+
+	switch c.MaterialCondition {
+	case "":
+		if kind.geometric {
+			c.MaterialCondition = MaterialConditionRFS
+		}
+	case MaterialConditionRFS:
+	case MaterialConditionMMC, MaterialConditionLMC:
+		if !kind.materialCondition {
+			return Characteristic{}, fmt.Errorf("%w: %s cannot be specified at %s", ErrInvalidCharacteristic, c.Type, c.MaterialCondition)
+		}
+		if c.MaterialConditionSize == nil || c.ActualSize == nil {
+			return Characteristic{}, fmt.Errorf("%w: %s requires the %s size and the actual feature size", ErrInvalidCharacteristic, c.MaterialCondition, c.MaterialCondition)
+		}
+		if c.Feature != FeatureExternal && c.Feature != FeatureInternal {
+			return Characteristic{}, fmt.Errorf("%w: %s requires the feature of size (external or internal)", ErrInvalidCharacteristic, c.MaterialCondition)
+		}
+	default:
+		return Characteristic{}, fmt.Errorf("%w: unknown material condition %s", ErrInvalidCharacteristic, c.MaterialCondition)
+	}
+
+	return c, nil
+}
+
+// BonusTolerance は MMC / LMC 指定時に形体のサイズが MMC / LMC から離れた分だけ増える公差。
+// 実測サイズが MMC / LMC の境界を越えている場合は 0
+func (c Characteristic) BonusTolerance() float64 {
+	departure, ok := c.materialConditionDeparture()
+	if !ok || departure < 0 {
+		return 0
+	}
+	return roundTolerance(departure)
+}
+
+// materialConditionDeparture は実測サイズが MMC / LMC サイズから公差の増える向きに離れた量。
+// 負の場合は実測サイズが MMC / LMC の境界を越えている。MMC / LMC 指定でなければ false
+func (c Characteristic) materialConditionDeparture() (float64, bool) {
+	if c.MaterialCondition != MaterialConditionMMC && c.MaterialCondition != MaterialConditionLMC {
+		return 0, false
+	}
+	if c.MaterialConditionSize == nil || c.ActualSize == nil {
+		return 0, false
+	}
+
+	// 外側形体の MMC・内側形体の LMC は最大サイズのため、小さくなる向きに公差が増える
+	departure := *c.ActualSize - *c.MaterialConditionSize
+	if (c.MaterialCondition == MaterialConditionMMC) == (c.Feature == FeatureExternal) {
+		departure = -departure
+	}
+	return departure, true
+}
+
+// Evaluate は測定値が規格内か判定する。上限にはボーナス公差を加え、実測サイズが MMC / LMC の境界を越えていれば不合格とする
+func (c Characteristic) Evaluate(measured float64) (bool, error) {
+	if math.IsNaN(measured) || math.IsInf(measured, 0) {
+		return false, fmt.Errorf("%w: measured value is not a number", ErrInvalidMeasurement)
+	}
+	if c.Type.IsGeometric() && measured < 0 {
+		return false, fmt.Errorf("%w: %s must not be negative", ErrInvalidMeasurement, c.Type)
+	}
+	if departure, ok := c.materialConditionDeparture(); ok && roundTolerance(departure) < 0 {
+		return false, nil
+	}
+	if c.LowerLimit != nil && measured < *c.LowerLimit {
+		return false, nil
+	}
+	if c.UpperLimit != nil && measured > roundTolerance(*c.UpperLimit+c.BonusTolerance()) {
+		return false, nil
+	}
+	return true, nil
+}
+
+// NewMeasurementResult は規格で合否を判定した測定結果を作成する
+func NewMeasurementResult(parameterName string, measured, target float64, unit string, c Characteristic) (MeasurementResult, error) {
+	if parameterName == "" {
+		return MeasurementResult{}, fmt.Errorf("%w: parameter name is required", ErrInvalidMeasurement)
+	}
+	pass, err := c.Evaluate(measured)
+	if err != nil {
+		return MeasurementResult{}, fmt.Errorf("%s: %w", parameterName, err)
+	}
+
+	return MeasurementResult{
+		ParameterName:  parameterName,
+		MeasuredValue:  measured,
+		TargetValue:    target,
+		Tolerance:      c.halfWidth(target),
+		Unit:           unit,
+		Pass:           pass,
+		Characteristic: c,
+	}, nil
+}
+
+// halfWidth は従来の ± 公差として記録する値（目標値から遠い方の規格までの距離）
+func (c Characteristic) halfWidth(target float64) float64 {
+	var w float64
+	if c.LowerLimit != nil {
+		w = math.Abs(target - *c.LowerLimit)
+	}
+	if c.UpperLimit != nil {
+		w = math.Max(w, math.Abs(*c.UpperLimit-target))
+	}
+	return roundTolerance(w)
+}
+
+// roundTolerance は上下限の差から生じる浮動小数点の誤差を取り除く
+func roundTolerance(v float64) float64 {
+	return math.Round(v*1e9) / 1e9
+}
+
+// LegacyCharacteristic は規格の上下限を持たない測定結果（目標値 ± 公差）の規格
+func LegacyCharacteristic(target, tolerance float64) Characteristic {
+	return Characteristic{
+		Type:       CharacteristicDimension,
+		LowerLimit: limit(target - tolerance),
+		UpperLimit: limit(target + tolerance),
+	}
+}
+
+func float64Ptr(v float64) *float64 {
+	return &v
+}
+
+// limit は目標値と公差の和から生じる浮動小数点の誤差を取り除いた規格値（12.3 - 0.01 を 12.29 にする）
+func limit(v float64) *float64 {
+	return float64Ptr(roundTolerance(v))
+}
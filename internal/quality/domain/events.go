@@ -0,0 +1,123 @@
+package domain
+
+import (
+	"context"
+	"time"
+)
+
+type EventType string
+
+const (
+	// EventProcessOutOfControl は管理図の判定ルールに該当したことを通知する
+	EventProcessOutOfControl EventType = "ProcessOutOfControl"
+	// EventNonconformanceRaised は不合格の検査から不適合報告（NCR）を起票したことを通知する
+	EventNonconformanceRaised EventType = "NonconformanceRaised"
+	// EventCAPAActionOverdue は CAPA の処置が期日を過ぎても完了していないことを担当者へ通知する
+	EventCAPAActionOverdue EventType = "CAPAActionOverdue"
+)
+
+type DomainEvent interface {
+	GetEventType() EventType
+	GetOccurredAt() time.Time
+	GetAggregateID() string
+}
+
+// EventPublisher はドメインイベントをメッセージバス等へ配信する
+type EventPublisher interface {
+	Publish(ctx context.Context, event DomainEvent) error
+}
+
+type InspectionEvent struct {
+	EventType    EventType
+	InspectionID InspectionID
+	OccurredAt   time.Time
+	Payload      map[string]interface{}
+}
+
+func (e InspectionEvent) GetEventType() EventType {
+	return e.EventType
+}
+
+func (e InspectionEvent) GetOccurredAt() time.Time {
+	return e.OccurredAt
+}
+
+func (e InspectionEvent) GetAggregateID() string {
+	return string(e.InspectionID)
+}
+
+// NewProcessOutOfControlEvent は検査の測定値が管理図の判定ルールに該当したことを通知する
+func NewProcessOutOfControlEvent(inspection *Inspection, limits *ControlLimits, violation RuleViolation) DomainEvent {
+	return InspectionEvent{
+		EventType:    EventProcessOutOfControl,
+		InspectionID: inspection.ID,
+		OccurredAt:   inspection.UpdatedAt,
+		Payload: map[string]interface{}{
+			"lotNumber":     inspection.LotNumber,
+			"parameterName": limits.ParameterName,
+			"partId":        limits.PartID,
+			"chartType":     limits.ChartType,
+			"chart":         violation.Chart,
+			"rule":          violation.Rule,
+			"description":   violation.Description,
+			"value":         violation.Value,
+			"pointIndex":    violation.PointIndex,
+		},
+	}
+}
+
+// NewNonconformanceRaisedEvent は NCR の起票を、起票の元になった検査のイベントとして通知する
+func NewNonconformanceRaisedEvent(ncr *Nonconformance) DomainEvent {
+	codes := make([]string, len(ncr.Defects))
+	for i, d := range ncr.Defects {
+		codes[i] = d.Code
+	}
+	return InspectionEvent{
+		EventType:    EventNonconformanceRaised,
+		InspectionID: ncr.InspectionID,
+		OccurredAt:   ncr.CreatedAt,
+		Payload: map[string]interface{}{
+			"nonconformanceId":  ncr.ID,
+			"productionOrderId": ncr.ProductionOrderID,
+			"lotNumber":         ncr.LotNumber,
+			"affectedQuantity":  ncr.AffectedQuantity,
+			"defectCodes":       codes,
+		},
+	}
+}
+
+type CAPAEvent struct {
+	EventType  EventType
+	CAPAID     CAPAID
+	OccurredAt time.Time
+	Payload    map[string]interface{}
+}
+
+func (e CAPAEvent) GetEventType() EventType {
+	return e.EventType
+}
+
+func (e CAPAEvent) GetOccurredAt() time.Time {
+	return e.OccurredAt
+}
+
+func (e CAPAEvent) GetAggregateID() string {
+	return string(e.CAPAID)
+}
+
+// NewCAPAActionOverdueEvent は期日を過ぎた処置を担当者へ通知する
+func NewCAPAActionOverdueEvent(capa *CAPA, action CAPAAction, now time.Time) DomainEvent {
+	return CAPAEvent{
+		EventType:  EventCAPAActionOverdue,
+		CAPAID:     capa.ID,
+		OccurredAt: now,
+		Payload: map[string]interface{}{
+			"title":        capa.Title,
+			"actionNumber": action.Number,
+			"description":  action.Description,
+			"owner":        action.Owner,
+			"dueDate":      action.DueDate,
+			"daysOverdue":  int(now.Sub(action.DueDate).Hours() / 24),
+		},
+	}
+}
@@ -0,0 +1,317 @@
+package domain
+
+import (
+	"context"
+	"sort"
+	"time"
+)
+
+// 系譜をたどる製造オーダーの段数の既定値と上限
+const (
+	DefaultGenealogyDepth = 5
+	MaxGenealogyDepth     = 20
+)
+
+// GenealogyDirection は forward（材料ロット→使用した製品ロット）または backward（製品ロット→材料ロット）
+type GenealogyDirection string
+
+const (
+	GenealogyForward  GenealogyDirection = "forward"
+	GenealogyBackward GenealogyDirection = "backward"
+)
+
+func ParseGenealogyDirection(s string) (GenealogyDirection, error) {
+	switch d := GenealogyDirection(s); d {
+	case "":
+		return GenealogyBackward, nil
+	case GenealogyForward, GenealogyBackward:
+		return d, nil
+	default:
+		return "", ErrInvalidGenealogyQuery
+	}
+}
+
+type LotMovementDirection string
+
+const (
+	LotMovementIn  LotMovementDirection = "in"
+	LotMovementOut LotMovementDirection = "out"
+)
+
+// LotMovement はロットの入出庫。製造オーダーの出庫は材料の投入、入庫は製品の産出
+type LotMovement struct {
+	ID                string
+	LotNumber         string
+	ProductType       string
+	Quantity          int
+	Direction         LotMovementDirection
+	ProductionOrderID string
+	TransactionDate   time.Time
+}
+
+// GenealogyOrder は系譜に含める製造オーダーと、ジョブを実行した機械（ジョブが無い場合は割り当てた機械）
+type GenealogyOrder struct {
+	ID          string
+	OrderNumber string
+	PartID      string
+	Quantity    int
+	Status      string
+	MachineIDs  []string
+}
+
+// GenealogyRepository は入出庫と製造オーダーからロットの系譜を読み取る
+type GenealogyRepository interface {
+	// FindMovementsByLot / FindMovementsByOrder は入出庫を取引日時の順に返す
+	FindMovementsByLot(ctx context.Context, lotNumber string) ([]LotMovement, error)
+	FindMovementsByOrder(ctx context.Context, productionOrderID string) ([]LotMovement, error)
+	// FindOrder は製造オーダーを返す。production_orders に無いオーダーは ID と機械のみ
+	FindOrder(ctx context.Context, productionOrderID string) (*GenealogyOrder, error)
+}
+
+type GenealogyNodeType string
+
+const (
+	// 製造オーダーで産出していないロットは材料、産出して別のオーダーへ投入したロットは仕掛品
+	NodeMaterialLot     GenealogyNodeType = "material_lot"
+	NodeWIPLot          GenealogyNodeType = "wip_lot"
+	NodeFinishedLot     GenealogyNodeType = "finished_lot"
+	NodeProductionOrder GenealogyNodeType = "production_order"
+	NodeMachine         GenealogyNodeType = "machine"
+	NodeInspection      GenealogyNodeType = "inspection"
+)
+
+// GenealogyNode は系譜の木の節。Quantity はロットの場合は親のオーダーへの投入数・産出数（根は入庫の合計）、
+// オーダーの場合は指示数
+type GenealogyNode struct {
+	Type        GenealogyNodeType
+	ID          string
+	ProductType string
+	Quantity    int
+	PartID      string
+	OrderNumber string
+	Status      string
+	Result      string
+	MachineID   string
+	Children    []*GenealogyNode
+}
+
+// LotGenealogy は系譜の木と、たどったロットの種類ごとの一覧（リコールの対象の確認用）
+type LotGenealogy struct {
+	LotNumber    string
+	Direction    GenealogyDirection
+	Depth        int
+	Root         *GenealogyNode
+	MaterialLots []string
+	WIPLots      []string
+	FinishedLots []string
+}
+
+// GenealogyService は入出庫・製造オーダー・検査からロットの系譜をたどる
+type GenealogyService struct {
+	repo           GenealogyRepository
+	inspectionRepo InspectionRepository
+}
+
+func NewGenealogyService(repo GenealogyRepository, inspectionRepo InspectionRepository) *GenealogyService {
+	return &GenealogyService{
+		repo:           repo,
+		inspectionRepo: inspectionRepo,
+	}
+}
+
+// Trace は lotNumber から depth 段の製造オーダーまで系譜をたどる。depth が 0 の場合は既定値。
+// 入出庫も検査も無いロットは ErrLotNotFound
+func (s *GenealogyService) Trace(ctx context.Context, lotNumber string, direction GenealogyDirection, depth int) (*LotGenealogy, error) {
+	if lotNumber == "" || depth < 0 || depth > MaxGenealogyDepth {
+		return nil, ErrInvalidGenealogyQuery
+	}
+	if depth == 0 {
+		depth = DefaultGenealogyDepth
+	}
+
+	t := &genealogyTracer{
+		service:   s,
+		direction: direction,
+		lots:      make(map[string]GenealogyNodeType),
+	}
+	root, found, err := t.lot(ctx, lotNumber, nil, depth, map[string]bool{})
+	if err != nil {
+		return nil, err
+	}
+	if !found {
+		return nil, ErrLotNotFound
+	}
+
+	genealogy := &LotGenealogy{
+		LotNumber: lotNumber,
+		Direction: direction,
+		Depth:     depth,
+		Root:      root,
+	}
+	for lot, nodeType := range t.lots {
+		switch nodeType {
+		case NodeMaterialLot:
+			genealogy.MaterialLots = append(genealogy.MaterialLots, lot)
+		case NodeWIPLot:
+			genealogy.WIPLots = append(genealogy.WIPLots, lot)
+		case NodeFinishedLot:
+			genealogy.FinishedLots = append(genealogy.FinishedLots, lot)
+		}
+	}
+	sort.Strings(genealogy.MaterialLots)
+	sort.Strings(genealogy.WIPLots)
+	sort.Strings(genealogy.FinishedLots)
+	return genealogy, nil
+}
+
+// genealogyTracer は 1 回の照会でたどったロットを記録する
+type genealogyTracer struct {
+	service   *GenealogyService
+	direction GenealogyDirection
+	lots      map[string]GenealogyNodeType
+}
+
+// lot はロットの節を作る。via は親のオーダーとの入出庫（根は nil）。
+// orders はたどってきたオーダー（循環の防止用）
+func (t *genealogyTracer) lot(ctx context.Context, lotNumber string, via []LotMovement, depth int, orders map[string]bool) (*GenealogyNode, bool, error) {
+	movements, err := t.service.repo.FindMovementsByLot(ctx, lotNumber)
+	if err != nil {
+		return nil, false, err
+	}
+	inspections, err := t.service.inspectionRepo.FindByLotNumber(ctx, lotNumber)
+	if err != nil {
+		return nil, false, err
+	}
+
+	// 前工程（backward）は産出したオーダー、後工程（forward）は投入したオーダーをたどる
+	next := LotMovementOut
+	if t.direction == GenealogyBackward {
+		next = LotMovementIn
+	}
+
+	node := &GenealogyNode{ID: lotNumber}
+	var produced, consumed bool
+	var nextOrders []string
+	seen := make(map[string]bool)
+	for _, m := range movements {
+		node.ProductType = m.ProductType
+		if via == nil && m.Direction == LotMovementIn {
+			node.Quantity += m.Quantity
+		}
+		if m.ProductionOrderID == "" {
+			continue
+		}
+		switch m.Direction {
+		case LotMovementIn:
+			produced = true
+		case LotMovementOut:
+			consumed = true
+		}
+		if m.Direction == next && !seen[m.ProductionOrderID] {
+			seen[m.ProductionOrderID] = true
+			nextOrders = append(nextOrders, m.ProductionOrderID)
+		}
+	}
+	for _, m := range via {
+		node.Quantity += m.Quantity
+	}
+
+	switch {
+	case !produced:
+		node.Type = NodeMaterialLot
+	case consumed:
+		node.Type = NodeWIPLot
+	default:
+		node.Type = NodeFinishedLot
+	}
+	t.lots[lotNumber] = node.Type
+
+	for _, inspection := range inspections {
+		node.Children = append(node.Children, &GenealogyNode{
+			Type:      NodeInspection,
+			ID:        string(inspection.ID),
+			Status:    string(inspection.Status),
+			Result:    string(inspection.FinalResult),
+			MachineID: inspection.MachineID,
+		})
+	}
+
+	if depth > 0 {
+		for _, orderID := range nextOrders {
+			if orders[orderID] {
+				continue
+			}
+			child, err := t.order(ctx, orderID, depth, withOrder(orders, orderID))
+			if err != nil {
+				return nil, false, err
+			}
+			node.Children = append(node.Children, child)
+		}
+	}
+
+	return node, len(movements) > 0 || len(inspections) > 0, nil
+}
+
+// order は製造オーダーの節と、その機械・投入（backward）または産出（forward）したロットの節を作る
+func (t *genealogyTracer) order(ctx context.Context, orderID string, depth int, orders map[string]bool) (*GenealogyNode, error) {
+	order, err := t.service.repo.FindOrder(ctx, orderID)
+	if err != nil {
+		return nil, err
+	}
+	movements, err := t.service.repo.FindMovementsByOrder(ctx, orderID)
+	if err != nil {
+		return nil, err
+	}
+
+	node := &GenealogyNode{
+		Type:        NodeProductionOrder,
+		ID:          orderID,
+		Quantity:    order.Quantity,
+		PartID:      order.PartID,
+		OrderNumber: order.OrderNumber,
+		Status:      order.Status,
+	}
+	for _, machineID := range order.MachineIDs {
+		node.Children = append(node.Children, &GenealogyNode{
+			Type: NodeMachine,
+			ID:   machineID,
+		})
+	}
+
+	// backward は投入（出庫）したロット、forward は産出（入庫）したロット
+	next := LotMovementIn
+	if t.direction == GenealogyBackward {
+		next = LotMovementOut
+	}
+
+	var lots []string
+	byLot := make(map[string][]LotMovement)
+	for _, m := range movements {
+		if m.Direction != next {
+			continue
+		}
+		if _, ok := byLot[m.LotNumber]; !ok {
+			lots = append(lots, m.LotNumber)
+		}
+		byLot[m.LotNumber] = append(byLot[m.LotNumber], m)
+	}
+
+	for _, lotNumber := range lots {
+		child, _, err := t.lot(ctx, lotNumber, byLot[lotNumber], depth-1, orders)
+		if err != nil {
+			return nil, err
+		}
+		node.Children = append(node.Children, child)
+	}
+
+	return node, nil
+}
+
+func withOrder(orders map[string]bool, orderID string) map[string]bool {
+	path := make(map[string]bool, len(orders)+1)
+	for id := range orders {
+		path[id] = true
+	}
+	path[orderID] = true
+	return path
+}
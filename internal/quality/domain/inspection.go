@@ -1,6 +1,7 @@
 package domain
 
 import (
+	"fmt"
 	"time"
 )
 
@@ -31,21 +32,41 @@ type Inspection struct {
 	FinalResult       InspectionResult
 	CreatedAt         time.Time
 	UpdatedAt         time.Time
+	// FailureReason は検査を中止（failed）した理由
+	FailureReason string
+	// 検査計画に従った検査の場合のみ設定する
+	PlanID             InspectionPlanID
+	Operation          string
+	LotSize            int
+	Sampling           *SamplingPlan
+	NonconformingCount int
+	// MachineID・Manufacturing は製品を加工した機械と、ジョブのプログラム・工具・オフセット
+	MachineID     string
+	Manufacturing *ManufacturingRecord
 }
 
 type MeasurementResult struct {
-	ParameterName string
-	MeasuredValue float64
-	TargetValue   float64
-	Tolerance     float64
-	Unit          string
-	Pass          bool
+	ParameterName  string
+	MeasuredValue  float64
+	TargetValue    float64
+	Tolerance      float64
+	Unit           string
+	Pass           bool
+	// SampleNumber は抜取サンプルの番号（1 始まり、0 は未指定）
+	SampleNumber   int
+	// InstrumentID・MeasuredAt は測定に使った測定器と測定日時
+	InstrumentID   string
+	MeasuredAt     time.Time
+	// Characteristic は合否の判定に使った規格（上下限・幾何公差の種類）
+	Characteristic Characteristic
 }
 
+// Measurement は 1 個のサンプルを 1 台の測定器で測った寸法（パラメーター名→測定値）
 type Measurement struct {
 	Dimensions   map[string]float64
 	InstrumentID string
 	MeasuredAt   time.Time
+	SampleNumber int
 }
 
 func NewInspection(productionOrderID, lotNumber, inspectorID string) *Inspection {
@@ -67,7 +88,40 @@ func (i *Inspection) AddMeasurement(result MeasurementResult) {
 	i.UpdatedAt = time.Now()
 }
 
-func (i *Inspection) Complete() {
+// Record は測定の寸法を判定した結果を検査中の検査に追加する。測定器・測定日時（省略時は現在）・
+// サンプル番号は測定から引き継ぐ
+func (i *Inspection) Record(m Measurement, results []MeasurementResult) error {
+	if i.Status != InspectionStatusPending {
+		return ErrInvalidStateTransition
+	}
+	if len(results) == 0 {
+		return fmt.Errorf("%w: no dimensions measured", ErrInvalidMeasurement)
+	}
+	for _, result := range results {
+		if v, ok := m.Dimensions[result.ParameterName]; !ok || v != result.MeasuredValue {
+			return fmt.Errorf("%w: %s is not a dimension of the measurement", ErrInvalidMeasurement, result.ParameterName)
+		}
+	}
+
+	measuredAt := m.MeasuredAt
+	if measuredAt.IsZero() {
+		measuredAt = time.Now()
+	}
+	for _, result := range results {
+		result.InstrumentID = m.InstrumentID
+		result.MeasuredAt = measuredAt
+		result.SampleNumber = m.SampleNumber
+		i.AddMeasurement(result)
+	}
+	return nil
+}
+
+// Complete は全ての測定が合格ならロットを合格として検査を完了する
+func (i *Inspection) Complete() error {
+	if i.Status != InspectionStatusPending {
+		return ErrInvalidStateTransition
+	}
+
 	allPass := true
 	for _, result := range i.Results {
 		if !result.Pass {
@@ -84,6 +138,19 @@ func (i *Inspection) Complete() {
 	
 	i.Status = InspectionStatusCompleted
 	i.UpdatedAt = time.Now()
+	return nil
+}
+
+// Fail は測定器の故障などで検査を中止する。中止した検査のロットは不合格とする
+func (i *Inspection) Fail(reason string) error {
+	if i.Status != InspectionStatusPending {
+		return ErrInvalidStateTransition
+	}
+	i.Status = InspectionStatusFailed
+	i.FinalResult = ResultFail
+	i.FailureReason = reason
+	i.UpdatedAt = time.Now()
+	return nil
 }
 
 func (i *Inspection) IsPassed() bool {
@@ -0,0 +1,262 @@
+package domain
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type InspectionPlanID string
+
+// InspectionFrequency は特性を測定する頻度
+type InspectionFrequency string
+
+const (
+	// FrequencySampled は抜取方式のサンプルごとに測定する
+	FrequencySampled InspectionFrequency = "sampled"
+	// FrequencyOncePerLot はロットごとに 1 回（初品など）測定する
+	FrequencyOncePerLot InspectionFrequency = "once_per_lot"
+)
+
+// PlanCharacteristic は検査計画で測定する特性。規格は測定時の入力より優先する
+type PlanCharacteristic struct {
+	ParameterName string
+	Spec          ToleranceSpec
+	Unit          string
+	Instrument    string
+	Frequency     InspectionFrequency
+	Required      bool
+}
+
+// InspectionPlan は部品・工程ごとの検査計画。同じ部品・工程では最新の版を使う
+type InspectionPlan struct {
+	ID              InspectionPlanID
+	PartID          string
+	Operation       string
+	Revision        int
+	AQL             float64
+	InspectionLevel InspectionLevel
+	Characteristics []PlanCharacteristic
+	CreatedBy       string
+	CreatedAt       time.Time
+}
+
+// NewInspectionPlan は検査計画の第 1 版を作成する。版は既存の計画に続けて採番し直す
+func NewInspectionPlan(partID, operation string, aql float64, level InspectionLevel, characteristics []PlanCharacteristic, createdBy string) (*InspectionPlan, error) {
+	if partID == "" || operation == "" {
+		return nil, fmt.Errorf("%w: part and operation are required", ErrInvalidInspectionPlan)
+	}
+	if _, err := findAQL(aql); err != nil {
+		return nil, err
+	}
+	if level == "" {
+		level = InspectionLevelII
+	}
+	if _, ok := codeLetterIndexes[level]; !ok {
+		return nil, fmt.Errorf("%w: unknown inspection level %s", ErrInvalidSamplingPlan, level)
+	}
+	if len(characteristics) == 0 {
+		return nil, fmt.Errorf("%w: at least one characteristic is required", ErrInvalidInspectionPlan)
+	}
+
+	seen := make(map[string]bool)
+	for i := range characteristics {
+		c := &characteristics[i]
+		if c.ParameterName == "" {
+			return nil, fmt.Errorf("%w: parameter name is required", ErrInvalidInspectionPlan)
+		}
+		if seen[c.ParameterName] {
+			return nil, fmt.Errorf("%w: duplicate characteristic %s", ErrInvalidInspectionPlan, c.ParameterName)
+		}
+		seen[c.ParameterName] = true
+
+		switch c.Frequency {
+		case "":
+			c.Frequency = FrequencySampled
+		case FrequencySampled, FrequencyOncePerLot:
+		default:
+			return nil, fmt.Errorf("%w: %s: unknown frequency %s", ErrInvalidInspectionPlan, c.ParameterName, c.Frequency)
+		}
+
+		// 実測サイズは測定時に決まるため、MMC / LMC サイズを仮に使って規格を確認する
+		spec := c.Spec
+		spec.ActualSize = spec.MaterialConditionSize
+		if _, err := NewCharacteristic(spec); err != nil {
+			return nil, fmt.Errorf("%s: %w", c.ParameterName, err)
+		}
+	}
+
+	return &InspectionPlan{
+		ID:              InspectionPlanID("plan-" + uuid.New().String()[:8]),
+		PartID:          partID,
+		Operation:       operation,
+		Revision:        1,
+		AQL:             aql,
+		InspectionLevel: level,
+		Characteristics: characteristics,
+		CreatedBy:       createdBy,
+		CreatedAt:       time.Now(),
+	}, nil
+}
+
+// SamplingPlan はロットサイズに対する抜取方式を求める
+func (p *InspectionPlan) SamplingPlan(lotSize int) (SamplingPlan, error) {
+	return NewSamplingPlan(lotSize, p.AQL, p.InspectionLevel)
+}
+
+// Characteristic は特性名で計画の特性を探す
+func (p *InspectionPlan) Characteristic(parameterName string) (PlanCharacteristic, bool) {
+	for _, c := range p.Characteristics {
+		if c.ParameterName == parameterName {
+			return c, true
+		}
+	}
+	return PlanCharacteristic{}, false
+}
+
+// Measure は計画の規格で測定値の合否を判定する。MMC / LMC の実測サイズは測定ごとに指定する
+func (c PlanCharacteristic) Measure(measured float64, actualSize *float64) (MeasurementResult, error) {
+	spec := c.Spec
+	spec.ActualSize = actualSize
+	characteristic, err := NewCharacteristic(spec)
+	if err != nil {
+		return MeasurementResult{}, fmt.Errorf("%s: %w", c.ParameterName, err)
+	}
+	return NewMeasurementResult(c.ParameterName, measured, spec.Target, c.Unit, characteristic)
+}
+
+// requiredCount は必須特性に必要なサンプル数
+func (c PlanCharacteristic) requiredCount(sampleSize int) int {
+	if c.Frequency == FrequencyOncePerLot {
+		return 1
+	}
+	return sampleSize
+}
+
+// MissingCharacteristics は測定したサンプル数が不足している必須特性を「名前 (サンプル数/必要数)」の形で返す。
+// 同じサンプル番号の再測定は 1 個と数え、番号の無い測定は 1 個ずつ数える
+func (p *InspectionPlan) MissingCharacteristics(results []MeasurementResult, sampleSize int) []string {
+	counts := make(map[string]int)
+	for _, r := range p.latestResults(results) {
+		counts[r.ParameterName]++
+	}
+
+	var missing []string
+	for _, c := range p.Characteristics {
+		if !c.Required {
+			continue
+		}
+		if need := c.requiredCount(sampleSize); counts[c.ParameterName] < need {
+			missing = append(missing, fmt.Sprintf("%s (%d/%d)", c.ParameterName, counts[c.ParameterName], need))
+		}
+	}
+	return missing
+}
+
+// latestResults は計画の特性の測定のうち、同じサンプルの再測定は最後に記録したものだけを残す。
+// ロットごとに測定する特性はサンプル番号に関係なく最後の測定を残す
+func (p *InspectionPlan) latestResults(results []MeasurementResult) []MeasurementResult {
+	return latestResults(results, func(parameterName string) bool {
+		c, ok := p.Characteristic(parameterName)
+		return ok && c.Frequency == FrequencyOncePerLot
+	})
+}
+
+// latestResults は同じ特性・サンプル番号の測定が複数ある場合に最後に記録した測定（再測定）だけを残す。
+// onceForLot が true の特性はサンプル番号に関係なく最後の測定だけを残す。番号の無い測定は別々の製品としてすべて残す
+func latestResults(results []MeasurementResult, onceForLot func(parameterName string) bool) []MeasurementResult {
+	type sampleKey struct {
+		parameterName string
+		sampleNumber  int
+	}
+	keyOf := func(r MeasurementResult) (sampleKey, bool) {
+		if onceForLot != nil && onceForLot(r.ParameterName) {
+			return sampleKey{parameterName: r.ParameterName}, true
+		}
+		return sampleKey{r.ParameterName, r.SampleNumber}, r.SampleNumber > 0
+	}
+
+	latest := make(map[sampleKey]int)
+	for i, r := range results {
+		if key, ok := keyOf(r); ok {
+			latest[key] = i
+		}
+	}
+
+	effective := make([]MeasurementResult, 0, len(results))
+	for i, r := range results {
+		if key, ok := keyOf(r); ok && latest[key] != i {
+			continue
+		}
+		effective = append(effective, r)
+	}
+	return effective
+}
+
+// AssignPlan は検査計画とロットサイズから抜取方式を決めて検査に割り当てる
+func (i *Inspection) AssignPlan(plan *InspectionPlan, lotSize int) error {
+	sampling, err := plan.SamplingPlan(lotSize)
+	if err != nil {
+		return err
+	}
+
+	i.PlanID = plan.ID
+	i.Operation = plan.Operation
+	i.LotSize = lotSize
+	i.Sampling = &sampling
+	i.UpdatedAt = time.Now()
+	return nil
+}
+
+// MissingCharacteristics は割り当てた検査計画の必須特性のうち、測定数が不足しているものを返す
+func (i *Inspection) MissingCharacteristics(plan *InspectionPlan) []string {
+	if i.Sampling == nil {
+		return nil
+	}
+	return plan.MissingCharacteristics(i.Results, i.Sampling.SampleSize)
+}
+
+// CompleteWithPlan は割り当てた検査計画の抜取方式でロットの合否を判定して検査を完了する。不適合品は
+// サンプル番号ごとに数え（番号の無い測定は 1 個ずつ）、ロットごとに測定する特性の不合格はそれだけで
+// ロットを不合格にする。同じサンプルを再測定した特性は最後に記録した測定で判定する
+func (i *Inspection) CompleteWithPlan(plan *InspectionPlan) error {
+	if i.Status != InspectionStatusPending {
+		return ErrInvalidStateTransition
+	}
+	if i.Sampling == nil || i.PlanID != plan.ID {
+		return fmt.Errorf("%w: plan %s is not assigned to the inspection", ErrInvalidInspectionPlan, plan.ID)
+	}
+	if missing := i.MissingCharacteristics(plan); len(missing) > 0 {
+		return fmt.Errorf("%w: %s", ErrIncompleteInspection, strings.Join(missing, ", "))
+	}
+
+	nonconforming := make(map[int]bool)
+	unnumbered := 0
+	lotCharacteristicFailed := false
+	for _, r := range plan.latestResults(i.Results) {
+		if r.Pass {
+			continue
+		}
+		if c, ok := plan.Characteristic(r.ParameterName); ok && c.Frequency == FrequencyOncePerLot {
+			lotCharacteristicFailed = true
+			continue
+		}
+		if r.SampleNumber > 0 {
+			nonconforming[r.SampleNumber] = true
+		} else {
+			unnumbered++
+		}
+	}
+	i.NonconformingCount = len(nonconforming) + unnumbered
+
+	if i.Sampling.IsAccepted(i.NonconformingCount) && !lotCharacteristicFailed {
+		i.FinalResult = ResultPass
+	} else {
+		i.FinalResult = ResultFail
+	}
+	i.Status = InspectionStatusCompleted
+	i.UpdatedAt = time.Now()
+	return nil
+}
@@ -0,0 +1,73 @@
+package domain
+
+import (
+	"context"
+	"time"
+)
+
+// ManufacturingRecord は検査した製品を加工したジョブのプログラム・機械と、その時点の工具・オフセット
+type ManufacturingRecord struct {
+	NCJobID        string
+	ProgramID      string
+	ProgramName    string
+	ProgramVersion string
+	// ProgramHash はジョブ開始時に機械へ送ったプログラムのハッシュ
+	ProgramHash  string
+	MachineID    string
+	JobStartedAt time.Time
+	Tools        []ToolSnapshot
+	Offsets      []OffsetSnapshot
+	// CapturedAt は工具・オフセットを読み取った日時。照会時に解決した記録はゼロ値
+	CapturedAt time.Time
+}
+
+// IsSnapshot は検査の登録時に工具・オフセットを読み取った記録か判定する
+func (r *ManufacturingRecord) IsSnapshot() bool {
+	return !r.CapturedAt.IsZero()
+}
+
+// withoutSnapshot は照会時に解決した記録からジョブ・プログラム・機械以外を除く。
+// 照会時点の工具・オフセットは加工時と同じとは限らないため返さない
+func (r *ManufacturingRecord) withoutSnapshot() *ManufacturingRecord {
+	return &ManufacturingRecord{
+		NCJobID:        r.NCJobID,
+		ProgramID:      r.ProgramID,
+		ProgramName:    r.ProgramName,
+		ProgramVersion: r.ProgramVersion,
+		ProgramHash:    r.ProgramHash,
+		MachineID:      r.MachineID,
+		JobStartedAt:   r.JobStartedAt,
+	}
+}
+
+// ToolSnapshot は工具番号に装着された工具アセンブリと、その時点までの使用実績
+type ToolSnapshot struct {
+	ToolNumber   int
+	Description  string
+	AssemblyID   string
+	AssemblyName string
+	CutTime      time.Duration
+	PartCount    int
+	LoadedAt     time.Time
+}
+
+// OffsetSnapshot はオフセット登録簿の 1 件と、その版を反映した変更
+type OffsetSnapshot struct {
+	Register string
+	Values   map[string]float64
+	Version  int
+	ChangeID string
+}
+
+// ManufacturingHistory は製造オーダー（または機械）で at までに開始したジョブから加工の記録を解決する。
+// 該当するジョブが無い場合は ErrManufacturingRecordNotFound を返す
+type ManufacturingHistory interface {
+	Resolve(ctx context.Context, productionOrderID, machineID string, at time.Time) (*ManufacturingRecord, error)
+}
+
+// AttachManufacturingRecord は検査に加工の記録を紐付ける
+func (i *Inspection) AttachManufacturingRecord(record *ManufacturingRecord) {
+	i.Manufacturing = record
+	i.MachineID = record.MachineID
+	i.UpdatedAt = time.Now()
+}
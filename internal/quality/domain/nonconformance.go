@@ -0,0 +1,309 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type NonconformanceID string
+
+// NonconformanceStatus は不適合報告（NCR）の処置の状態
+type NonconformanceStatus string
+
+const (
+	// NonconformanceStatusOpen は処置が未提案、または MRB で差し戻された状態
+	NonconformanceStatusOpen NonconformanceStatus = "open"
+	// NonconformanceStatusProposed は処置を提案し MRB の承認を待っている状態
+	NonconformanceStatusProposed NonconformanceStatus = "proposed"
+	// NonconformanceStatusClosed は MRB が処置を承認し、製造オーダーの数量に反映した状態
+	NonconformanceStatusClosed NonconformanceStatus = "closed"
+)
+
+// Disposition は不適合品の処置
+type Disposition string
+
+const (
+	DispositionUseAsIs          Disposition = "use_as_is"
+	DispositionRework           Disposition = "rework"
+	DispositionScrap            Disposition = "scrap"
+	DispositionReturnToSupplier Disposition = "return_to_supplier"
+)
+
+// ParseDisposition は処置の名前を検証する
+func ParseDisposition(s string) (Disposition, error) {
+	switch d := Disposition(s); d {
+	case DispositionUseAsIs, DispositionRework, DispositionScrap, DispositionReturnToSupplier:
+		return d, nil
+	}
+	return "", fmt.Errorf("%w: unknown disposition %s", ErrInvalidDisposition, s)
+}
+
+// Defect は不適合の内容。Code は「特性の種類:パラメーター名」（DIMENSION:diameter など）
+type Defect struct {
+	Code          string
+	ParameterName string
+	Count         int
+}
+
+// DispositionLine は処置ごとの数量
+type DispositionLine struct {
+	Disposition Disposition
+	Quantity    int
+}
+
+// Nonconformance は不合格になった検査から起票する不適合報告。対象ロットの数量に処置を割り当て、
+// MRB（品質ロール）の承認で確定する
+type Nonconformance struct {
+	ID                NonconformanceID
+	InspectionID      InspectionID
+	ProductionOrderID string
+	LotNumber         string
+	AffectedQuantity  int
+	Defects           []Defect
+	Status            NonconformanceStatus
+	Dispositions      []DispositionLine
+	ProposedBy        string
+	ProposedAt        *time.Time
+	ApprovedBy        string
+	ApprovedAt        *time.Time
+	// MRBComment は承認または差し戻しのコメント
+	MRBComment string
+	CreatedAt  time.Time
+	UpdatedAt  time.Time
+}
+
+// NewNonconformanceFromInspection は不合格で完了した検査から NCR を起票する。対象数量は
+// ロットサイズ（未指定の場合は不適合品の数）
+func NewNonconformanceFromInspection(inspection *Inspection) (*Nonconformance, error) {
+	if inspection.Status != InspectionStatusCompleted || inspection.FinalResult != ResultFail {
+		return nil, fmt.Errorf("%w: inspection %s is not a rejected lot", ErrInvalidStateTransition, inspection.ID)
+	}
+
+	defects := defectsOf(inspection.Results)
+	affected := inspection.LotSize
+	if affected <= 0 {
+		affected = inspection.NonconformingCount
+	}
+	if affected <= 0 {
+		affected = nonconformingItems(inspection.Results)
+	}
+
+	now := time.Now()
+	return &Nonconformance{
+		ID:                NonconformanceID("ncr-" + uuid.New().String()[:8]),
+		InspectionID:      inspection.ID,
+		ProductionOrderID: inspection.ProductionOrderID,
+		LotNumber:         inspection.LotNumber,
+		AffectedQuantity:  affected,
+		Defects:           defects,
+		Status:            NonconformanceStatusOpen,
+		CreatedAt:         now,
+		UpdatedAt:         now,
+	}, nil
+}
+
+// defectsOf は不合格の測定を不具合コードごとに数える
+func defectsOf(results []MeasurementResult) []Defect {
+	index := make(map[string]int)
+	var defects []Defect
+	for _, r := range results {
+		if r.Pass {
+			continue
+		}
+		code := DefectCode(r)
+		if i, ok := index[code]; ok {
+			defects[i].Count++
+			continue
+		}
+		index[code] = len(defects)
+		defects = append(defects, Defect{Code: code, ParameterName: r.ParameterName, Count: 1})
+	}
+	sort.SliceStable(defects, func(a, b int) bool { return defects[a].Code < defects[b].Code })
+	return defects
+}
+
+// DefectCode は測定の不具合コード。特性の種類が無い測定は寸法として扱う
+func DefectCode(r MeasurementResult) string {
+	characteristicType := r.Characteristic.Type
+	if characteristicType == "" {
+		characteristicType = CharacteristicDimension
+	}
+	return strings.ToUpper(string(characteristicType)) + ":" + r.ParameterName
+}
+
+// nonconformingItems は不合格の測定があるサンプルの数（番号の無い測定は 1 個ずつ）。
+// 同じサンプルの再測定は最後に記録した測定で判定する
+func nonconformingItems(results []MeasurementResult) int {
+	samples := make(map[int]bool)
+	count := 0
+	for _, r := range latestResults(results, nil) {
+		if r.Pass {
+			continue
+		}
+		if r.SampleNumber > 0 {
+			samples[r.SampleNumber] = true
+		} else {
+			count++
+		}
+	}
+	return count + len(samples)
+}
+
+// ProposeDisposition は対象数量の処置を提案する。数量の合計は対象数量と一致させる
+func (n *Nonconformance) ProposeDisposition(lines []DispositionLine, proposedBy string) error {
+	if n.Status != NonconformanceStatusOpen {
+		return ErrInvalidStateTransition
+	}
+	if len(lines) == 0 {
+		return fmt.Errorf("%w: at least one disposition is required", ErrInvalidDisposition)
+	}
+
+	total := 0
+	seen := make(map[Disposition]bool)
+	for _, line := range lines {
+		if _, err := ParseDisposition(string(line.Disposition)); err != nil {
+			return err
+		}
+		if seen[line.Disposition] {
+			return fmt.Errorf("%w: duplicate disposition %s", ErrInvalidDisposition, line.Disposition)
+		}
+		seen[line.Disposition] = true
+		if line.Quantity <= 0 {
+			return fmt.Errorf("%w: %s quantity must be positive", ErrInvalidDisposition, line.Disposition)
+		}
+		total += line.Quantity
+	}
+	if total != n.AffectedQuantity {
+		return fmt.Errorf("%w: dispositions cover %d of %d affected items", ErrInvalidDisposition, total, n.AffectedQuantity)
+	}
+
+	now := time.Now()
+	n.Dispositions = lines
+	n.ProposedBy = proposedBy
+	n.ProposedAt = &now
+	n.Status = NonconformanceStatusProposed
+	n.UpdatedAt = now
+	return nil
+}
+
+// Approve は MRB が提案された処置を承認して NCR を閉じる
+func (n *Nonconformance) Approve(approvedBy, comment string) error {
+	if n.Status != NonconformanceStatusProposed {
+		return ErrInvalidStateTransition
+	}
+
+	now := time.Now()
+	n.ApprovedBy = approvedBy
+	n.ApprovedAt = &now
+	n.MRBComment = comment
+	n.Status = NonconformanceStatusClosed
+	n.UpdatedAt = now
+	return nil
+}
+
+// Reject は MRB が提案された処置を差し戻す。処置は提案し直す
+func (n *Nonconformance) Reject(rejectedBy, comment string) error {
+	if n.Status != NonconformanceStatusProposed {
+		return ErrInvalidStateTransition
+	}
+	if comment == "" {
+		return fmt.Errorf("%w: a comment is required to reject a disposition", ErrInvalidDisposition)
+	}
+
+	n.Dispositions = nil
+	n.ProposedBy = ""
+	n.ProposedAt = nil
+	n.MRBComment = rejectedBy + ": " + comment
+	n.Status = NonconformanceStatusOpen
+	n.UpdatedAt = time.Now()
+	return nil
+}
+
+// QuantityByOutcome は処置を製造オーダーの良品（特別採用）・手直し・不良（廃却・返品）の数量にまとめる
+func (n *Nonconformance) QuantityByOutcome() (good, rework, scrap int) {
+	for _, line := range n.Dispositions {
+		switch line.Disposition {
+		case DispositionUseAsIs:
+			good += line.Quantity
+		case DispositionRework:
+			rework += line.Quantity
+		case DispositionScrap, DispositionReturnToSupplier:
+			scrap += line.Quantity
+		}
+	}
+	return good, rework, scrap
+}
+
+// ProductionFeedback は承認した処置の数量を製造オーダーへ反映する
+type ProductionFeedback interface {
+	// RecordDisposition は dispositionID（NCR の ID）ごとに 1 回だけ数量を反映する
+	RecordDisposition(ctx context.Context, dispositionID, productionOrderID string, good, rework, scrap int) error
+}
+
+// NonconformanceService は不合格の検査から NCR を起票し、承認した処置を製造オーダーへ反映する
+type NonconformanceService struct {
+	repo      NonconformanceRepository
+	feedback  ProductionFeedback
+	publisher EventPublisher
+}
+
+func NewNonconformanceService(repo NonconformanceRepository, feedback ProductionFeedback, publisher EventPublisher) *NonconformanceService {
+	return &NonconformanceService{
+		repo:      repo,
+		feedback:  feedback,
+		publisher: publisher,
+	}
+}
+
+// Raise は不合格で完了した検査の NCR を起票する。起票済みの場合は既存の NCR を返す
+func (s *NonconformanceService) Raise(ctx context.Context, inspection *Inspection) (*Nonconformance, error) {
+	existing, err := s.repo.FindByInspectionID(ctx, inspection.ID)
+	if err == nil {
+		return existing, nil
+	}
+	if !errors.Is(err, ErrNonconformanceNotFound) {
+		return nil, err
+	}
+
+	ncr, err := NewNonconformanceFromInspection(inspection)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.repo.Save(ctx, ncr); err != nil {
+		if errors.Is(err, ErrNonconformanceConflict) {
+			return s.repo.FindByInspectionID(ctx, inspection.ID)
+		}
+		return nil, err
+	}
+
+	if err := s.publisher.Publish(ctx, NewNonconformanceRaisedEvent(ncr)); err != nil {
+		return nil, err
+	}
+	return ncr, nil
+}
+
+// Approve は処置を承認して NCR を閉じてから、製造オーダーの良品・手直し・不良数に反映する。
+// 並行した承認は 1 件のみ成功する。閉じた NCR の再承認は反映のみ再試行する（反映は NCR ごとに 1 回）
+func (s *NonconformanceService) Approve(ctx context.Context, ncr *Nonconformance, approvedBy, comment string) error {
+	if ncr.Status != NonconformanceStatusClosed {
+		from := ncr.Status
+		if err := ncr.Approve(approvedBy, comment); err != nil {
+			return err
+		}
+		if err := s.repo.Update(ctx, ncr, from); err != nil {
+			return err
+		}
+	}
+
+	if ncr.ProductionOrderID == "" {
+		return nil
+	}
+	good, rework, scrap := ncr.QuantityByOutcome()
+	return s.feedback.RecordDisposition(ctx, string(ncr.ID), ncr.ProductionOrderID, good, rework, scrap)
+}
@@ -1,6 +1,9 @@
 package domain
 
-import "context"
+import (
+	"context"
+	"time"
+)
 
 type InspectionRepository interface {
 	Save(ctx context.Context, inspection *Inspection) error
@@ -8,4 +11,57 @@ type InspectionRepository interface {
 	FindByLotNumber(ctx context.Context, lotNumber string) ([]*Inspection, error)
 	FindByProductionOrderID(ctx context.Context, orderID string) ([]*Inspection, error)
 	Update(ctx context.Context, inspection *Inspection) error
-}
\ No newline at end of file
+	// FindMeasurements は条件に合う測定値を測定日時の順に返す
+	FindMeasurements(ctx context.Context, filter MeasurementFilter) ([]MeasurementSample, error)
+	// FindOutcomes は条件に合う完了した検査を検査日時の順に返す
+	FindOutcomes(ctx context.Context, filter AnalyticsFilter) ([]InspectionOutcome, error)
+}
+
+// ControlLimitsRepository は固定した管理限界を保存する。同じパラメーター・部品・管理図の種類は置き換える
+type ControlLimitsRepository interface {
+	Save(ctx context.Context, limits *ControlLimits) error
+	Find(ctx context.Context, parameterName, partID string, chartType ChartType) (*ControlLimits, error)
+	FindByParameter(ctx context.Context, parameterName string) ([]*ControlLimits, error)
+}
+
+type QualityAlertRepository interface {
+	Save(ctx context.Context, alert *QualityAlert) error
+	FindByID(ctx context.Context, id int64) (*QualityAlert, error)
+}
+
+// InspectionPlanRepository は検査計画を版ごとに保存する。同じ部品・工程・版の計画は重複できない
+type InspectionPlanRepository interface {
+	Save(ctx context.Context, plan *InspectionPlan) error
+	FindByID(ctx context.Context, id InspectionPlanID) (*InspectionPlan, error)
+	// FindLatest は部品・工程の最新版を返す
+	FindLatest(ctx context.Context, partID, operation string) (*InspectionPlan, error)
+	// FindLatestForOrder は製造オーダーの部品について工程の最新版を返す
+	FindLatestForOrder(ctx context.Context, productionOrderID, operation string) (*InspectionPlan, error)
+	// FindByPart は部品の計画を工程・版の順に返す。operation が空の場合は全工程
+	FindByPart(ctx context.Context, partID, operation string) ([]*InspectionPlan, error)
+}
+
+// NonconformanceRepository は NCR を保存する。1 件の検査から起票できる NCR は 1 件
+type NonconformanceRepository interface {
+	Save(ctx context.Context, ncr *Nonconformance) error
+	// Update は状態が from の NCR のみ更新する。並行して状態が変わっていた場合は ErrInvalidStateTransition
+	Update(ctx context.Context, ncr *Nonconformance, from NonconformanceStatus) error
+	FindByID(ctx context.Context, id NonconformanceID) (*Nonconformance, error)
+	FindByInspectionID(ctx context.Context, inspectionID InspectionID) (*Nonconformance, error)
+	// FindAll は NCR を起票の新しい順に返す。status・lotNumber が空の場合は絞り込まない
+	FindAll(ctx context.Context, status NonconformanceStatus, lotNumber string) ([]*Nonconformance, error)
+	// FindCreatedSince は since 以降に起票した NCR を起票の順に返す
+	FindCreatedSince(ctx context.Context, since time.Time) ([]*Nonconformance, error)
+}
+
+// CAPARepository は CAPA を保存する。根本原因・処置・有効性の確認は CAPA と一緒に保存する
+type CAPARepository interface {
+	Save(ctx context.Context, capa *CAPA) error
+	// Update は読み込んだ版の CAPA のみ更新して版を進める。他で保存されていた場合は ErrCAPAConflict
+	Update(ctx context.Context, capa *CAPA) error
+	FindByID(ctx context.Context, id CAPAID) (*CAPA, error)
+	// FindAll は CAPA を起票の新しい順に返す。status が空の場合は全件
+	FindAll(ctx context.Context, status CAPAStatus) ([]*CAPA, error)
+	// FindActive は閉じていない CAPA を返す
+	FindActive(ctx context.Context) ([]*CAPA, error)
+}
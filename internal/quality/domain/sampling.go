@@ -0,0 +1,153 @@
+package domain
+
+import (
+	"fmt"
+	"math"
+)
+
+// InspectionLevel は ISO 2859-1 の検査水準（通常検査水準 I/II/III、特別検査水準 S-1〜S-4）
+type InspectionLevel string
+
+const (
+	InspectionLevelS1  InspectionLevel = "S-1"
+	InspectionLevelS2  InspectionLevel = "S-2"
+	InspectionLevelS3  InspectionLevel = "S-3"
+	InspectionLevelS4  InspectionLevel = "S-4"
+	InspectionLevelI   InspectionLevel = "I"
+	InspectionLevelII  InspectionLevel = "II"
+	InspectionLevelIII InspectionLevel = "III"
+)
+
+// SamplingPlan は ISO 2859-1 の並検査・1 回抜取方式の抜取表から求めたサンプル数と合格判定個数
+type SamplingPlan struct {
+	AQL        float64
+	Level      InspectionLevel
+	LotSize    int
+	CodeLetter string
+	// SampleSize がロットサイズ以上になる場合はロット全数を検査する
+	SampleSize int
+	Accept     int
+	Reject     int
+}
+
+// IsAccepted は不適合品の数からロットの合否を判定する
+func (p SamplingPlan) IsAccepted(nonconforming int) bool {
+	return nonconforming <= p.Accept
+}
+
+// codeLetters はサンプル文字とサンプル数（表 2-A）
+var codeLetters = []struct {
+	letter     string
+	sampleSize int
+}{
+	{"A", 2}, {"B", 3}, {"C", 5}, {"D", 8}, {"E", 13}, {"F", 20}, {"G", 32}, {"H", 50},
+	{"J", 80}, {"K", 125}, {"L", 200}, {"M", 315}, {"N", 500}, {"P", 800}, {"Q", 1250}, {"R", 2000},
+}
+
+// lotSizeUpperBounds はロットサイズの区分（表 1）。最後の区分は上限なし
+var lotSizeUpperBounds = []int{8, 15, 25, 50, 90, 150, 280, 500, 1200, 3200, 10000, 35000, 150000, 500000}
+
+// codeLetterIndexes は検査水準・ロットサイズの区分ごとのサンプル文字（codeLetters の添字）
+var codeLetterIndexes = map[InspectionLevel][]int{
+	InspectionLevelS1:  {0, 0, 0, 0, 1, 1, 1, 1, 2, 2, 2, 2, 3, 3, 3},
+	InspectionLevelS2:  {0, 0, 0, 1, 1, 1, 2, 2, 2, 3, 3, 3, 4, 4, 4},
+	InspectionLevelS3:  {0, 0, 1, 1, 2, 2, 3, 3, 4, 4, 5, 5, 6, 6, 7},
+	InspectionLevelS4:  {0, 0, 1, 2, 2, 3, 4, 4, 5, 6, 6, 7, 8, 8, 9},
+	InspectionLevelI:   {0, 0, 1, 2, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12},
+	InspectionLevelII:  {0, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14},
+	InspectionLevelIII: {1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15},
+}
+
+// aqlValues は不適合品パーセントで使う AQL（表 2-A の 0.010〜10）
+var aqlValues = []float64{0.010, 0.015, 0.025, 0.040, 0.065, 0.10, 0.15, 0.25, 0.40, 0.65, 1.0, 1.5, 2.5, 4.0, 6.5, 10}
+
+// 表 2-A ではサンプル文字と AQL の添字の和が等しい欄は同じ判定個数になる。
+// 和が firstDiagonal の欄が Ac=0、以降は ↑・↓ の欄を挟んで acceptNumbers の順に増える
+const firstDiagonal = 14
+
+var acceptNumbers = map[int]int{14: 0, 17: 1, 18: 2, 19: 3, 20: 5, 21: 7, 22: 10, 23: 14, 24: 21}
+
+// ParseInspectionLevel は検査水準を解釈する。空の場合は通常検査水準 II
+func ParseInspectionLevel(s string) (InspectionLevel, error) {
+	if s == "" {
+		return InspectionLevelII, nil
+	}
+	if _, ok := codeLetterIndexes[InspectionLevel(s)]; !ok {
+		return "", fmt.Errorf("%w: unknown inspection level %s", ErrInvalidSamplingPlan, s)
+	}
+	return InspectionLevel(s), nil
+}
+
+// NewSamplingPlan はロットサイズ・AQL・検査水準から抜取方式を求める
+func NewSamplingPlan(lotSize int, aql float64, level InspectionLevel) (SamplingPlan, error) {
+	if lotSize < 2 {
+		return SamplingPlan{}, fmt.Errorf("%w: lot size must be at least 2", ErrInvalidSamplingPlan)
+	}
+	indexes, ok := codeLetterIndexes[level]
+	if !ok {
+		return SamplingPlan{}, fmt.Errorf("%w: unknown inspection level %s", ErrInvalidSamplingPlan, level)
+	}
+	aqlIndex, err := findAQL(aql)
+	if err != nil {
+		return SamplingPlan{}, err
+	}
+
+	bucket := len(lotSizeUpperBounds)
+	for i, upper := range lotSizeUpperBounds {
+		if lotSize <= upper {
+			bucket = i
+			break
+		}
+	}
+
+	letter := resolveArrow(indexes[bucket], aqlIndex)
+	accept := acceptNumbers[letter+aqlIndex]
+	plan := SamplingPlan{
+		AQL:        aql,
+		Level:      level,
+		LotSize:    lotSize,
+		CodeLetter: codeLetters[letter].letter,
+		SampleSize: codeLetters[letter].sampleSize,
+		Accept:     accept,
+		Reject:     accept + 1,
+	}
+	if plan.SampleSize > lotSize {
+		plan.SampleSize = lotSize
+	}
+	return plan, nil
+}
+
+// findAQL は AQL が表 2-A の優先数か確認し、その添字を返す
+func findAQL(aql float64) (int, error) {
+	for i, v := range aqlValues {
+		if math.Abs(v-aql) < 1e-9 {
+			return i, nil
+		}
+	}
+	return 0, fmt.Errorf("%w: AQL %g is not a preferred value between 0.010 and 10", ErrInvalidSamplingPlan, aql)
+}
+
+// resolveArrow は表 2-A の矢印をたどり、判定個数のある欄のサンプル文字を返す
+func resolveArrow(letter, aqlIndex int) int {
+	last := len(codeLetters) - 1
+	switch diagonal := letter + aqlIndex; {
+	case diagonal < firstDiagonal:
+		// ↓: Ac=0 の欄までサンプル数を増やす
+		return firstDiagonal - aqlIndex
+	case diagonal == firstDiagonal+1:
+		// ↑: 1 つ上の欄（Ac=0）を使う。最初のサンプル文字では Ac=1 の欄まで下る
+		if letter == 0 {
+			return firstDiagonal + 3 - aqlIndex
+		}
+		return letter - 1
+	case diagonal == firstDiagonal+2:
+		// ↓: 1 つ下の欄（Ac=1）を使う。最後のサンプル文字では ↑ の欄まで戻る
+		if letter == last {
+			return letter - 2
+		}
+		return letter + 1
+	case diagonal > 24:
+		return 24 - aqlIndex
+	}
+	return letter
+}
@@ -3,20 +3,59 @@ package domain
 import (
 	"context"
 	"errors"
+	"sort"
+	"time"
+)
+
+// 不具合の再発を判定する期間と NCR の件数（不良分析で CAPA の起票を提案する既定値）
+const (
+	DefaultRecurrenceWindow    = 90 * 24 * time.Hour
+	DefaultRecurrenceThreshold = 3
 )
 
 var (
-	ErrInspectionNotFound = errors.New("inspection not found")
-	ErrInvalidMeasurement = errors.New("invalid measurement")
+	ErrInspectionNotFound       = errors.New("inspection not found")
+	ErrInvalidCharacteristic    = errors.New("invalid characteristic specification")
+	ErrInvalidMeasurement       = errors.New("invalid measurement")
+	ErrInvalidMeasurementFilter = errors.New("invalid measurement filter: from must be before to")
+	ErrInvalidChartType         = errors.New("invalid control chart type")
+	ErrInvalidSubgroupSize      = errors.New("invalid subgroup size")
+	ErrInsufficientBaseline     = errors.New("insufficient baseline data for control limits")
+	ErrControlLimitsNotFound    = errors.New("control limits not found")
+	ErrInspectionPlanNotFound   = errors.New("inspection plan not found")
+	ErrInspectionPlanConflict   = errors.New("inspection plan revision already exists")
+	ErrInvalidInspectionPlan    = errors.New("invalid inspection plan")
+	ErrInvalidSamplingPlan      = errors.New("invalid sampling plan")
+	ErrIncompleteInspection     = errors.New("required characteristics are not measured")
+	ErrInvalidStateTransition   = errors.New("invalid state transition")
+	ErrNonconformanceNotFound   = errors.New("nonconformance report not found")
+	ErrNonconformanceConflict   = errors.New("nonconformance report already raised for the inspection")
+	ErrInvalidDisposition       = errors.New("invalid disposition")
+	ErrQualityAlertNotFound     = errors.New("quality alert not found")
+	ErrCAPANotFound             = errors.New("CAPA not found")
+	ErrCAPAActionNotFound       = errors.New("CAPA action not found")
+	ErrInvalidCAPA              = errors.New("invalid CAPA")
+	ErrCAPAConflict             = errors.New("CAPA was modified concurrently")
+	ErrLotNotFound              = errors.New("lot not found")
+	ErrInvalidGenealogyQuery    = errors.New("invalid genealogy query")
+	ErrInvalidAnalyticsQuery    = errors.New("invalid analytics query")
+	// ErrManufacturingRecordNotFound は検査した製品を加工した NC のジョブが見つからない
+	ErrManufacturingRecordNotFound = errors.New("manufacturing record not found")
 )
 
 type DefectAnalysisService struct {
-	repo InspectionRepository
+	repo     InspectionRepository
+	ncrRepo  NonconformanceRepository
+	capaRepo CAPARepository
+	history  ManufacturingHistory
 }
 
-func NewDefectAnalysisService(repo InspectionRepository) *DefectAnalysisService {
+func NewDefectAnalysisService(repo InspectionRepository, ncrRepo NonconformanceRepository, capaRepo CAPARepository, history ManufacturingHistory) *DefectAnalysisService {
 	return &DefectAnalysisService{
-		repo: repo,
+		repo:     repo,
+		ncrRepo:  ncrRepo,
+		capaRepo: capaRepo,
+		history:  history,
 	}
 }
 
@@ -53,6 +92,20 @@ func (s *DefectAnalysisService) AnalyzeDefects(ctx context.Context, lotNumber st
 	
 	analysis.PassRate = float64(analysis.PassedSamples) / float64(analysis.TotalSamples) * 100
 	
+	// このロットの不具合のうち、他のロットでも再発していて CAPA の無いものは起票を提案する
+	suggestions, err := s.SuggestCAPAs(ctx, time.Now().Add(-DefaultRecurrenceWindow), DefaultRecurrenceThreshold)
+	if err != nil {
+		return nil, err
+	}
+	for _, suggestion := range suggestions {
+		for _, lot := range suggestion.LotNumbers {
+			if lot == lotNumber {
+				analysis.SuggestedCAPAs = append(analysis.SuggestedCAPAs, suggestion)
+				break
+			}
+		}
+	}
+	
 	return analysis, nil
 }
 
@@ -63,8 +116,12 @@ type DefectAnalysis struct {
 	FailedSamples int
 	PassRate      float64
 	DefectTypes   map[string]int
+	// SuggestedCAPAs は CAPA の無いまま再発している、このロットの不具合
+	SuggestedCAPAs []CAPASuggestion
 }
 
+// GetTraceability はロットの検査と、製品を加工したジョブのプログラム・機械・工具・オフセットを返す。
+// 加工の記録の無い検査は検査日時までに開始したジョブから解決する（ジョブ・プログラム・機械のみで、工具・オフセットは含まない）
 func (s *DefectAnalysisService) GetTraceability(ctx context.Context, lotNumber string) (*TraceabilityInfo, error) {
 	inspections, err := s.repo.FindByLotNumber(ctx, lotNumber)
 	if err != nil {
@@ -78,16 +135,50 @@ func (s *DefectAnalysisService) GetTraceability(ctx context.Context, lotNumber s
 	info := &TraceabilityInfo{
 		LotNumber:   lotNumber,
 		Inspections: inspections,
-		// TODO: NCプログラムバージョン、工具情報、機械ログとの関連付け
+	}
+	
+	jobs := make(map[string]bool)
+	for _, inspection := range inspections {
+		if inspection.Manufacturing == nil && (inspection.ProductionOrderID != "" || inspection.MachineID != "") {
+			record, err := s.history.Resolve(ctx, inspection.ProductionOrderID, inspection.MachineID, inspection.CreatedAt)
+			if err != nil && !errors.Is(err, ErrManufacturingRecordNotFound) {
+				return nil, err
+			}
+			if record != nil {
+				inspection.Manufacturing = record.withoutSnapshot()
+				inspection.MachineID = record.MachineID
+			}
+		}
+		
+		record := inspection.Manufacturing
+		if record == nil || jobs[record.NCJobID] {
+			continue
+		}
+		jobs[record.NCJobID] = true
+		info.Manufacturing = append(info.Manufacturing, *record)
+	}
+	
+	sort.SliceStable(info.Manufacturing, func(i, j int) bool {
+		return info.Manufacturing[i].JobStartedAt.Before(info.Manufacturing[j].JobStartedAt)
+	})
+	if n := len(info.Manufacturing); n > 0 {
+		latest := info.Manufacturing[n-1]
+		info.NCProgramVersion = latest.ProgramVersion
+		info.MachineID = latest.MachineID
+		info.ToolInfo = latest.Tools
 	}
 	
 	return info, nil
 }
 
+// TraceabilityInfo の NCProgramVersion・MachineID・ToolInfo はロットを最後に加工したジョブのもの。
+// そのジョブの記録が照会時に解決したものの場合、ToolInfo は空
 type TraceabilityInfo struct {
 	LotNumber        string
 	Inspections      []*Inspection
 	NCProgramVersion string
 	MachineID        string
-	ToolInfo         map[string]interface{}
-}
\ No newline at end of file
+	ToolInfo         []ToolSnapshot
+	// Manufacturing はロットを加工したジョブごとの記録（ジョブの開始順）
+	Manufacturing []ManufacturingRecord
+}
@@ -0,0 +1,435 @@
+package domain
+
+import (
+	"fmt"
+	"math"
+	"time"
+)
+
+// ChartType は管理図の種類
+type ChartType string
+
+const (
+	ChartXbarR ChartType = "xbar_r"
+	ChartXbarS ChartType = "xbar_s"
+	ChartIMR   ChartType = "i_mr"
+	// ChartP・ChartNP は測定の合否から作る計数値の管理図
+	ChartP  ChartType = "p"
+	ChartNP ChartType = "np"
+)
+
+// MinBaselinePoints は管理限界を固定するために必要な打点数
+const MinBaselinePoints = 20
+
+// ParseChartType は管理図の種類を解釈する。空の場合は I-MR 管理図
+func ParseChartType(s string) (ChartType, error) {
+	switch ChartType(s) {
+	case "":
+		return ChartIMR, nil
+	case ChartXbarR, ChartXbarS, ChartIMR, ChartP, ChartNP:
+		return ChartType(s), nil
+	}
+	return "", fmt.Errorf("%w: %s", ErrInvalidChartType, s)
+}
+
+// DefaultSubgroupSize は管理図の種類ごとの既定のサブグループサイズ
+func (t ChartType) DefaultSubgroupSize() int {
+	switch t {
+	case ChartIMR:
+		return 1
+	case ChartP, ChartNP:
+		return 25
+	}
+	return 5
+}
+
+// ValidateSubgroupSize は管理図の種類に使えるサブグループサイズかを確認する
+func (t ChartType) ValidateSubgroupSize(n int) error {
+	var ok bool
+	switch t {
+	case ChartIMR:
+		ok = n == 1
+	case ChartXbarR:
+		ok = n >= 2 && n <= 10
+	case ChartXbarS:
+		ok = n >= 2 && n <= 25
+	case ChartP, ChartNP:
+		ok = n >= 1
+	}
+	if !ok {
+		return fmt.Errorf("%w: %d for %s chart", ErrInvalidSubgroupSize, n, t)
+	}
+	return nil
+}
+
+// PrimaryChart・DispersionChart は打点する統計量の名前（X̄/R、I/MR 等）
+func (t ChartType) PrimaryChart() string {
+	switch t {
+	case ChartXbarR, ChartXbarS:
+		return "xbar"
+	case ChartIMR:
+		return "i"
+	}
+	return string(t)
+}
+
+func (t ChartType) DispersionChart() string {
+	switch t {
+	case ChartXbarR:
+		return "r"
+	case ChartXbarS:
+		return "s"
+	case ChartIMR:
+		return "mr"
+	}
+	return ""
+}
+
+// ControlLimits はベースライン期間から計算して固定した管理限界。
+// パラメーター・部品・管理図の種類ごとに 1 件で、固定し直すと置き換わる
+type ControlLimits struct {
+	ParameterName string
+	// PartID が空の場合は部品を問わずパラメーター名で集計する
+	PartID       string
+	ChartType    ChartType
+	SubgroupSize int
+	Center       float64
+	UCL          float64
+	LCL          float64
+	// Dispersion* は R / S / MR 管理図の限界。計数値の管理図では 0
+	DispersionCenter float64
+	DispersionUCL    float64
+	DispersionLCL    float64
+	BaselineFrom     time.Time
+	BaselineTo       time.Time
+	BaselinePoints   int
+	FrozenBy         string
+	FrozenAt         time.Time
+}
+
+// ChartPoint は管理図の 1 打点。サブグループは測定日時の順に SubgroupSize 件ずつ区切る
+type ChartPoint struct {
+	Index         int
+	InspectionIDs []InspectionID
+	MeasuredAt    time.Time
+	SampleSize    int
+	Value         float64
+	// Dispersion は範囲・標準偏差・移動範囲。計数値の管理図と I-MR の最初の点では nil
+	Dispersion *float64
+}
+
+// BuildChartPoints は測定値をサブグループに分けて打点を計算する。
+// 末尾の件数が足りないサブグループは打点せず、件数を pending として返す
+func BuildChartPoints(chartType ChartType, samples []MeasurementSample, subgroupSize int) ([]ChartPoint, int) {
+	complete := len(samples) / subgroupSize * subgroupSize
+	points := make([]ChartPoint, 0, complete/subgroupSize)
+
+	for start := 0; start < complete; start += subgroupSize {
+		group := samples[start : start+subgroupSize]
+		point := ChartPoint{
+			Index:      len(points),
+			MeasuredAt: group[len(group)-1].MeasuredAt,
+			SampleSize: len(group),
+		}
+		values := make([]float64, len(group))
+		defectives := 0
+		for i, s := range group {
+			values[i] = s.Value
+			if !s.Pass {
+				defectives++
+			}
+			if i == 0 || group[i-1].InspectionID != s.InspectionID {
+				point.InspectionIDs = append(point.InspectionIDs, s.InspectionID)
+			}
+		}
+
+		switch chartType {
+		case ChartXbarR:
+			point.Value = mean(values)
+			r := rangeOf(values)
+			point.Dispersion = &r
+		case ChartXbarS:
+			point.Value = mean(values)
+			s := sampleStdDev(values)
+			point.Dispersion = &s
+		case ChartIMR:
+			point.Value = values[0]
+			if len(points) > 0 {
+				mr := math.Abs(point.Value - points[len(points)-1].Value)
+				point.Dispersion = &mr
+			}
+		case ChartP:
+			point.Value = float64(defectives) / float64(len(group))
+		case ChartNP:
+			point.Value = float64(defectives)
+		}
+		points = append(points, point)
+	}
+
+	return points, len(samples) - complete
+}
+
+// EstimateLimits は打点から管理限界を計算する（X̄-R: A2/D3/D4、X̄-S: c4 による A3/B3/B4、I-MR: MR̄/d2）
+func EstimateLimits(chartType ChartType, points []ChartPoint, subgroupSize int) (*ControlLimits, error) {
+	if len(points) < 2 {
+		return nil, fmt.Errorf("%w: %d points", ErrInsufficientBaseline, len(points))
+	}
+	if err := chartType.ValidateSubgroupSize(subgroupSize); err != nil {
+		return nil, err
+	}
+
+	values := make([]float64, len(points))
+	var dispersions []float64
+	for i, p := range points {
+		values[i] = p.Value
+		if p.Dispersion != nil {
+			dispersions = append(dispersions, *p.Dispersion)
+		}
+	}
+
+	limits := &ControlLimits{
+		ChartType:      chartType,
+		SubgroupSize:   subgroupSize,
+		Center:         mean(values),
+		BaselineFrom:   points[0].MeasuredAt,
+		BaselineTo:     points[len(points)-1].MeasuredAt,
+		BaselinePoints: len(points),
+	}
+	n := float64(subgroupSize)
+
+	switch chartType {
+	case ChartXbarR:
+		rBar := mean(dispersions)
+		limits.UCL = limits.Center + xbarRConstants[subgroupSize].a2*rBar
+		limits.LCL = limits.Center - xbarRConstants[subgroupSize].a2*rBar
+		limits.DispersionCenter = rBar
+		limits.DispersionUCL = xbarRConstants[subgroupSize].d4 * rBar
+		limits.DispersionLCL = xbarRConstants[subgroupSize].d3 * rBar
+	case ChartXbarS:
+		sBar := mean(dispersions)
+		c := c4(subgroupSize)
+		a3 := 3 / (c * math.Sqrt(n))
+		spread := 3 * math.Sqrt(1-c*c) / c
+		limits.UCL = limits.Center + a3*sBar
+		limits.LCL = limits.Center - a3*sBar
+		limits.DispersionCenter = sBar
+		limits.DispersionUCL = (1 + spread) * sBar
+		limits.DispersionLCL = math.Max(0, 1-spread) * sBar
+	case ChartIMR:
+		mrBar := mean(dispersions)
+		limits.UCL = limits.Center + 3*mrBar/d2[2]
+		limits.LCL = limits.Center - 3*mrBar/d2[2]
+		limits.DispersionCenter = mrBar
+		limits.DispersionUCL = xbarRConstants[2].d4 * mrBar
+	case ChartP:
+		p := limits.Center
+		sigma := math.Sqrt(p * (1 - p) / n)
+		limits.UCL = math.Min(1, p+3*sigma)
+		limits.LCL = math.Max(0, p-3*sigma)
+	case ChartNP:
+		p := limits.Center / n
+		sigma := math.Sqrt(n * p * (1 - p))
+		limits.UCL = math.Min(n, limits.Center+3*sigma)
+		limits.LCL = math.Max(0, limits.Center-3*sigma)
+	}
+
+	if (chartType == ChartXbarR || chartType == ChartXbarS || chartType == ChartIMR) && limits.UCL == limits.LCL {
+		return nil, fmt.Errorf("%w: measurements have no variation", ErrInsufficientBaseline)
+	}
+	return limits, nil
+}
+
+// NelsonRule は Nelson の判定ルールの番号（1〜8）。ルール 1・2・5・6 は Western Electric ルールに相当する
+type NelsonRule int
+
+var nelsonRuleDescriptions = map[NelsonRule]string{
+	1: "point beyond 3 sigma",
+	2: "9 points in a row on the same side of the center line",
+	3: "6 points in a row steadily increasing or decreasing",
+	4: "14 points in a row alternating up and down",
+	5: "2 of 3 points beyond 2 sigma on the same side",
+	6: "4 of 5 points beyond 1 sigma on the same side",
+	7: "15 points in a row within 1 sigma",
+	8: "8 points in a row beyond 1 sigma on either side",
+}
+
+func (r NelsonRule) Description() string {
+	return nelsonRuleDescriptions[r]
+}
+
+// RuleViolation は判定ルールに該当した打点の並び。PointIndex はパターンの最後の打点
+type RuleViolation struct {
+	ParameterName string
+	ChartType     ChartType
+	Rule          NelsonRule
+	// Chart は該当した統計量（xbar / r / s / i / mr / p / np）
+	Chart       string
+	StartIndex  int
+	PointIndex  int
+	Value       float64
+	Description string
+}
+
+// EvaluateNelsonRules は打点を管理限界で判定する。中心の管理図には 8 ルールすべて、
+// ばらつきの管理図にはルール 1（管理限界外）のみを適用する
+func EvaluateNelsonRules(points []ChartPoint, limits *ControlLimits) []RuleViolation {
+	values := make([]float64, len(points))
+	for i, p := range points {
+		values[i] = p.Value
+	}
+	violations := evaluateRules(values, limits.Center, limits.UCL, limits.LCL, limits.ChartType.PrimaryChart())
+
+	if chart := limits.ChartType.DispersionChart(); chart != "" {
+		for _, p := range points {
+			if p.Dispersion == nil {
+				continue
+			}
+			if *p.Dispersion > limits.DispersionUCL || *p.Dispersion < limits.DispersionLCL {
+				violations = append(violations, RuleViolation{
+					Rule:        1,
+					Chart:       chart,
+					StartIndex:  p.Index,
+					PointIndex:  p.Index,
+					Value:       *p.Dispersion,
+					Description: NelsonRule(1).Description(),
+				})
+			}
+		}
+	}
+
+	for i := range violations {
+		violations[i].ParameterName = limits.ParameterName
+		violations[i].ChartType = limits.ChartType
+	}
+	return violations
+}
+
+func evaluateRules(values []float64, center, ucl, lcl float64, chart string) []RuleViolation {
+	// ゾーンの幅は上側の管理限界から求める（計数値の管理図では下側の限界が 0 で切られるため）
+	sigma := (ucl - center) / 3
+	var violations []RuleViolation
+	hit := func(rule NelsonRule, start, end int) {
+		violations = append(violations, RuleViolation{
+			Rule:        rule,
+			Chart:       chart,
+			StartIndex:  start,
+			PointIndex:  end,
+			Value:       values[end],
+			Description: rule.Description(),
+		})
+	}
+	// side は中心線より上なら 1、下なら -1、中心線上は 0
+	side := func(v float64) int {
+		switch {
+		case v > center:
+			return 1
+		case v < center:
+			return -1
+		}
+		return 0
+	}
+	beyond := func(v float64, k float64, s int) bool {
+		if s > 0 {
+			return v > center+k*sigma
+		}
+		return v < center-k*sigma
+	}
+
+	for i, v := range values {
+		if v > ucl || v < lcl {
+			hit(1, i, i)
+		}
+		if i >= 8 && sameSide(values[i-8:i+1], side) {
+			hit(2, i-8, i)
+		}
+		if i >= 5 && monotonic(values[i-5:i+1]) {
+			hit(3, i-5, i)
+		}
+		if i >= 13 && alternating(values[i-13:i+1]) {
+			hit(4, i-13, i)
+		}
+		if sigma <= 0 {
+			continue
+		}
+		for _, s := range []int{1, -1} {
+			if i >= 2 && beyond(v, 2, s) && countBeyond(values[i-2:i+1], func(x float64) bool { return beyond(x, 2, s) }) >= 2 {
+				hit(5, i-2, i)
+			}
+			if i >= 4 && beyond(v, 1, s) && countBeyond(values[i-4:i+1], func(x float64) bool { return beyond(x, 1, s) }) >= 4 {
+				hit(6, i-4, i)
+			}
+		}
+		if i >= 14 && countBeyond(values[i-14:i+1], func(x float64) bool { return math.Abs(x-center) < sigma }) == 15 {
+			hit(7, i-14, i)
+		}
+		if i >= 7 && countBeyond(values[i-7:i+1], func(x float64) bool { return math.Abs(x-center) > sigma }) == 8 &&
+			!sameSide(values[i-7:i+1], side) {
+			hit(8, i-7, i)
+		}
+	}
+	return violations
+}
+
+func sameSide(values []float64, side func(float64) int) bool {
+	s := side(values[0])
+	if s == 0 {
+		return false
+	}
+	for _, v := range values[1:] {
+		if side(v) != s {
+			return false
+		}
+	}
+	return true
+}
+
+func monotonic(values []float64) bool {
+	increasing, decreasing := true, true
+	for i := 1; i < len(values); i++ {
+		increasing = increasing && values[i] > values[i-1]
+		decreasing = decreasing && values[i] < values[i-1]
+	}
+	return increasing || decreasing
+}
+
+func alternating(values []float64) bool {
+	for i := 2; i < len(values); i++ {
+		prev, cur := values[i-1]-values[i-2], values[i]-values[i-1]
+		if prev == 0 || cur == 0 || (prev > 0) == (cur > 0) {
+			return false
+		}
+	}
+	return true
+}
+
+func countBeyond(values []float64, match func(float64) bool) int {
+	count := 0
+	for _, v := range values {
+		if match(v) {
+			count++
+		}
+	}
+	return count
+}
+
+func rangeOf(values []float64) float64 {
+	lo, hi := values[0], values[0]
+	for _, v := range values[1:] {
+		lo = math.Min(lo, v)
+		hi = math.Max(hi, v)
+	}
+	return hi - lo
+}
+
+// xbarRConstants は X̄-R 管理図の係数（サブグループサイズ 2〜10）
+var xbarRConstants = map[int]struct{ a2, d3, d4 float64 }{
+	2:  {1.880, 0, 3.267},
+	3:  {1.023, 0, 2.574},
+	4:  {0.729, 0, 2.282},
+	5:  {0.577, 0, 2.114},
+	6:  {0.483, 0, 2.004},
+	7:  {0.419, 0.076, 1.924},
+	8:  {0.373, 0.136, 1.864},
+	9:  {0.337, 0.184, 1.816},
+	10: {0.308, 0.223, 1.777},
+}
@@ -0,0 +1,256 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ControlChart は管理図の打点・管理限界・判定ルールへの該当
+type ControlChart struct {
+	ParameterName string
+	PartID        string
+	ChartType     ChartType
+	SubgroupSize  int
+	// Frozen が false の場合、Limits は表示範囲の打点から計算した試行の管理限界
+	Frozen         bool
+	Limits         *ControlLimits
+	Points         []ChartPoint
+	PendingSamples int
+	Violations     []RuleViolation
+	Warnings       []string
+}
+
+// ChartQuery は管理図の対象。SubgroupSize が 0 の場合は固定した管理限界か管理図の種類の既定値を使う
+type ChartQuery struct {
+	ParameterName string
+	PartID        string
+	ChartType     ChartType
+	SubgroupSize  int
+	From          *time.Time
+	To            *time.Time
+}
+
+// SPCService は管理図の作成・管理限界の固定と、検査ごとの判定ルールの評価を行う
+type SPCService struct {
+	repo       InspectionRepository
+	limitsRepo ControlLimitsRepository
+	alertRepo  QualityAlertRepository
+	publisher  EventPublisher
+}
+
+func NewSPCService(repo InspectionRepository, limitsRepo ControlLimitsRepository, alertRepo QualityAlertRepository, publisher EventPublisher) *SPCService {
+	return &SPCService{
+		repo:       repo,
+		limitsRepo: limitsRepo,
+		alertRepo:  alertRepo,
+		publisher:  publisher,
+	}
+}
+
+// Chart は管理図を作成する。管理限界が固定済みの場合はベースラインの開始からサブグループを区切り、
+// From より前の打点は表示しない（検査時の評価と同じ打点になる）
+func (s *SPCService) Chart(ctx context.Context, query ChartQuery) (*ControlChart, error) {
+	if query.From != nil && query.To != nil && !query.From.Before(*query.To) {
+		return nil, ErrInvalidMeasurementFilter
+	}
+
+	frozen, err := s.limitsRepo.Find(ctx, query.ParameterName, query.PartID, query.ChartType)
+	if err != nil && !errors.Is(err, ErrControlLimitsNotFound) {
+		return nil, err
+	}
+
+	chart := &ControlChart{
+		ParameterName: query.ParameterName,
+		PartID:        query.PartID,
+		ChartType:     query.ChartType,
+		SubgroupSize:  query.SubgroupSize,
+	}
+	filter := MeasurementFilter{
+		PartID:        query.PartID,
+		ParameterName: query.ParameterName,
+		From:          query.From,
+		To:            query.To,
+	}
+
+	if frozen != nil {
+		if chart.SubgroupSize != 0 && chart.SubgroupSize != frozen.SubgroupSize {
+			return nil, fmt.Errorf("%w: control limits are frozen with subgroup size %d", ErrInvalidSubgroupSize, frozen.SubgroupSize)
+		}
+		chart.SubgroupSize = frozen.SubgroupSize
+		chart.Frozen = true
+		chart.Limits = frozen
+		filter.From = &frozen.BaselineFrom
+	} else if chart.SubgroupSize == 0 {
+		chart.SubgroupSize = query.ChartType.DefaultSubgroupSize()
+	}
+	if err := query.ChartType.ValidateSubgroupSize(chart.SubgroupSize); err != nil {
+		return nil, err
+	}
+
+	samples, err := s.repo.FindMeasurements(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	points, pending := BuildChartPoints(query.ChartType, samples, chart.SubgroupSize)
+	chart.PendingSamples = pending
+
+	if !chart.Frozen {
+		chart.Warnings = append(chart.Warnings, "control limits are not frozen; trial limits are computed from the displayed points")
+		limits, err := EstimateLimits(query.ChartType, points, chart.SubgroupSize)
+		switch {
+		case errors.Is(err, ErrInsufficientBaseline):
+			chart.Warnings = append(chart.Warnings, err.Error())
+		case err != nil:
+			return nil, err
+		default:
+			limits.ParameterName = query.ParameterName
+			limits.PartID = query.PartID
+			chart.Limits = limits
+			if len(points) < MinBaselinePoints {
+				chart.Warnings = append(chart.Warnings,
+					fmt.Sprintf("trial limits from %d points; at least %d are recommended", len(points), MinBaselinePoints))
+			}
+		}
+	}
+
+	if chart.Limits != nil {
+		chart.Violations = EvaluateNelsonRules(points, chart.Limits)
+	}
+
+	// 固定した管理限界ではベースラインから区切った打点のうち表示範囲のものだけを返す
+	if chart.Frozen && query.From != nil {
+		first := len(points)
+		for i, p := range points {
+			if !p.MeasuredAt.Before(*query.From) {
+				first = i
+				break
+			}
+		}
+		points = points[first:]
+		var visible []RuleViolation
+		for _, v := range chart.Violations {
+			if v.PointIndex >= first {
+				visible = append(visible, v)
+			}
+		}
+		chart.Violations = visible
+	}
+	chart.Points = points
+
+	return chart, nil
+}
+
+// FreezeLimits はベースライン期間 [from, to) の測定値から管理限界を計算して固定する
+func (s *SPCService) FreezeLimits(ctx context.Context, query ChartQuery, frozenBy string) (*ControlLimits, error) {
+	if query.From == nil || query.To == nil || !query.From.Before(*query.To) {
+		return nil, fmt.Errorf("%w: baseline period is required", ErrInvalidMeasurementFilter)
+	}
+	subgroupSize := query.SubgroupSize
+	if subgroupSize == 0 {
+		subgroupSize = query.ChartType.DefaultSubgroupSize()
+	}
+	if err := query.ChartType.ValidateSubgroupSize(subgroupSize); err != nil {
+		return nil, err
+	}
+
+	samples, err := s.repo.FindMeasurements(ctx, MeasurementFilter{
+		PartID:        query.PartID,
+		ParameterName: query.ParameterName,
+		From:          query.From,
+		To:            query.To,
+	})
+	if err != nil {
+		return nil, err
+	}
+	points, _ := BuildChartPoints(query.ChartType, samples, subgroupSize)
+	if len(points) < MinBaselinePoints {
+		return nil, fmt.Errorf("%w: %d points in the baseline, at least %d required", ErrInsufficientBaseline, len(points), MinBaselinePoints)
+	}
+
+	limits, err := EstimateLimits(query.ChartType, points, subgroupSize)
+	if err != nil {
+		return nil, err
+	}
+	limits.ParameterName = query.ParameterName
+	limits.PartID = query.PartID
+	limits.BaselineFrom = *query.From
+	limits.BaselineTo = *query.To
+	limits.FrozenBy = frozenBy
+	limits.FrozenAt = time.Now()
+
+	if err := s.limitsRepo.Save(ctx, limits); err != nil {
+		return nil, err
+	}
+	return limits, nil
+}
+
+// Evaluate は検査の測定値で完成した打点を固定した管理限界で判定し、該当したルールを
+// quality_alerts に記録してイベントを配信する。管理限界が固定されていないパラメーターは評価しない
+func (s *SPCService) Evaluate(ctx context.Context, inspection *Inspection) ([]RuleViolation, error) {
+	var violations []RuleViolation
+	seen := make(map[string]bool)
+
+	for _, result := range inspection.Results {
+		if seen[result.ParameterName] {
+			continue
+		}
+		seen[result.ParameterName] = true
+
+		limitsList, err := s.limitsRepo.FindByParameter(ctx, result.ParameterName)
+		if err != nil {
+			return violations, err
+		}
+		for _, limits := range limitsList {
+			hits, err := s.evaluateLimits(ctx, inspection, limits)
+			if err != nil {
+				return violations, err
+			}
+			violations = append(violations, hits...)
+		}
+	}
+
+	return violations, nil
+}
+
+func (s *SPCService) evaluateLimits(ctx context.Context, inspection *Inspection, limits *ControlLimits) ([]RuleViolation, error) {
+	samples, err := s.repo.FindMeasurements(ctx, MeasurementFilter{
+		PartID:        limits.PartID,
+		ParameterName: limits.ParameterName,
+		From:          &limits.BaselineFrom,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	points, _ := BuildChartPoints(limits.ChartType, samples, limits.SubgroupSize)
+	if len(points) == 0 || !points[len(points)-1].includes(inspection.ID) {
+		return nil, nil
+	}
+	last := points[len(points)-1].Index
+
+	var hits []RuleViolation
+	for _, v := range EvaluateNelsonRules(points, limits) {
+		if v.PointIndex != last {
+			continue
+		}
+		if err := s.alertRepo.Save(ctx, NewSPCAlert(inspection, limits, v)); err != nil {
+			return hits, err
+		}
+		if err := s.publisher.Publish(ctx, NewProcessOutOfControlEvent(inspection, limits, v)); err != nil {
+			return hits, err
+		}
+		hits = append(hits, v)
+	}
+	return hits, nil
+}
+
+func (p ChartPoint) includes(id InspectionID) bool {
+	for _, inspectionID := range p.InspectionIDs {
+		if inspectionID == id {
+			return true
+		}
+	}
+	return false
+}
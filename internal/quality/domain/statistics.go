@@ -0,0 +1,104 @@
+package domain
+
+import (
+	"math"
+	"sort"
+)
+
+// 統計計算の補助関数（工程能力・管理図で共通に使う）
+
+func mean(values []float64) float64 {
+	if len(values) == 0 {
+		return 0
+	}
+	var sum float64
+	for _, v := range values {
+		sum += v
+	}
+	return sum / float64(len(values))
+}
+
+// sampleStdDev は不偏分散による標準偏差（n-1）を返す
+func sampleStdDev(values []float64) float64 {
+	if len(values) < 2 {
+		return 0
+	}
+	m := mean(values)
+	var ss float64
+	for _, v := range values {
+		ss += (v - m) * (v - m)
+	}
+	return math.Sqrt(ss / float64(len(values)-1))
+}
+
+// c4 は標準偏差の不偏化定数
+func c4(n int) float64 {
+	if n < 2 {
+		return 1
+	}
+	a, _ := math.Lgamma(float64(n) / 2)
+	b, _ := math.Lgamma(float64(n-1) / 2)
+	return math.Sqrt(2/float64(n-1)) * math.Exp(a-b)
+}
+
+// d2 は範囲から標準偏差を推定する係数（サブグループサイズ 2〜10）
+var d2 = map[int]float64{
+	2: 1.128, 3: 1.693, 4: 2.059, 5: 2.326, 6: 2.534, 7: 2.704, 8: 2.847, 9: 2.970, 10: 3.078,
+}
+
+func normalCDF(z float64) float64 {
+	return 0.5 * (1 + math.Erf(z/math.Sqrt2))
+}
+
+// minNormalityTestSamples は正規性の検定に必要な最小サンプル数
+const minNormalityTestSamples = 8
+
+// NormalityTest は Anderson-Darling 検定の結果。PValue が 0.05 以上なら正規分布とみなす
+type NormalityTest struct {
+	Method    string
+	Statistic float64
+	PValue    float64
+	Normal    bool
+}
+
+// andersonDarling は平均・標準偏差を標本から推定した正規性の Anderson-Darling 検定を行う（修正統計量 A²* と p 値の近似式は D'Agostino & Stephens）
+func andersonDarling(values []float64) *NormalityTest {
+	n := len(values)
+	sd := sampleStdDev(values)
+	if n < minNormalityTestSamples || sd == 0 {
+		return nil
+	}
+	m := mean(values)
+	sorted := append([]float64(nil), values...)
+	sort.Float64s(sorted)
+
+	const eps = 1e-12
+	var s float64
+	for i := 0; i < n; i++ {
+		lower := math.Max(normalCDF((sorted[i]-m)/sd), eps)
+		upper := math.Max(1-normalCDF((sorted[n-1-i]-m)/sd), eps)
+		s += float64(2*i+1) * (math.Log(lower) + math.Log(upper))
+	}
+	a2 := -float64(n) - s/float64(n)
+	a := a2 * (1 + 0.75/float64(n) + 2.25/float64(n*n))
+
+	var p float64
+	switch {
+	case a >= 0.6:
+		p = math.Exp(1.2937 - 5.709*a + 0.0186*a*a)
+	case a >= 0.34:
+		p = math.Exp(0.9177 - 4.279*a - 1.38*a*a)
+	case a >= 0.2:
+		p = 1 - math.Exp(-8.318+42.796*a-59.938*a*a)
+	default:
+		p = 1 - math.Exp(-13.436+101.14*a-223.73*a*a)
+	}
+	p = math.Min(math.Max(p, 0), 1)
+
+	return &NormalityTest{
+		Method:    "anderson_darling",
+		Statistic: a,
+		PValue:    p,
+		Normal:    p >= 0.05,
+	}
+}
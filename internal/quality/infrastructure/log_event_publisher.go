@@ -0,0 +1,25 @@
+package infrastructure
+
+import (
+	"context"
+	"encoding/json"
+	"goNexttask/internal/quality/domain"
+	"log"
+)
+
+// LogEventPublisher はドメインイベントを構造化ログとして出力する。
+// メッセージバス導入までの暫定実装
+type LogEventPublisher struct{}
+
+func NewLogEventPublisher() *LogEventPublisher {
+	return &LogEventPublisher{}
+}
+
+func (p *LogEventPublisher) Publish(ctx context.Context, event domain.DomainEvent) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	log.Printf("event=%s aggregate=%s payload=%s", event.GetEventType(), event.GetAggregateID(), body)
+	return nil
+}
@@ -0,0 +1,69 @@
+package infrastructure
+
+import (
+	"context"
+	"errors"
+	ncApp "goNexttask/internal/nc/application"
+	ncDomain "goNexttask/internal/nc/domain"
+	"goNexttask/internal/quality/domain"
+	"time"
+)
+
+// NCManufacturingHistory は NC のユースケースからジョブ・プログラム・工具・オフセットを読み取り、加工の記録にする
+type NCManufacturingHistory struct {
+	useCase *ncApp.NCUseCase
+}
+
+func NewNCManufacturingHistory(useCase *ncApp.NCUseCase) *NCManufacturingHistory {
+	return &NCManufacturingHistory{
+		useCase: useCase,
+	}
+}
+
+func (h *NCManufacturingHistory) Resolve(ctx context.Context, productionOrderID, machineID string, at time.Time) (*domain.ManufacturingRecord, error) {
+	output, err := h.useCase.GetManufacturingRecord(ctx, productionOrderID, machineID, at)
+	if errors.Is(err, ncDomain.ErrNCJobNotFound) {
+		return nil, domain.ErrManufacturingRecordNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	record := &domain.ManufacturingRecord{
+		NCJobID:        output.Job.ID,
+		ProgramID:      output.Job.ProgramID,
+		ProgramName:    output.ProgramName,
+		ProgramVersion: output.ProgramVersion,
+		ProgramHash:    output.Job.ProgramHash,
+		MachineID:      output.Job.MachineID,
+		JobStartedAt:   parseTimestamp(output.Job.StartedAt),
+		CapturedAt:     time.Now(),
+	}
+	for _, t := range output.Tools {
+		record.Tools = append(record.Tools, domain.ToolSnapshot{
+			ToolNumber:   t.ToolNumber,
+			Description:  t.Description,
+			AssemblyID:   t.AssemblyID,
+			AssemblyName: t.AssemblyName,
+			CutTime:      time.Duration(t.CutTimeSec * float64(time.Second)),
+			PartCount:    t.PartCount,
+			LoadedAt:     parseTimestamp(t.LoadedAt),
+		})
+	}
+	for _, o := range output.Offsets {
+		record.Offsets = append(record.Offsets, domain.OffsetSnapshot{
+			Register: o.Register,
+			Values:   o.Values,
+			Version:  o.Version,
+			ChangeID: o.ChangeID,
+		})
+	}
+
+	return record, nil
+}
+
+// parseTimestamp は NC のユースケースが返す日時の文字列を読む。読めない場合はゼロ値
+func parseTimestamp(s string) time.Time {
+	t, _ := time.Parse("2006-01-02T15:04:05Z", s)
+	return t
+}
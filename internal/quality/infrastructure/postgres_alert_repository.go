@@ -0,0 +1,57 @@
+package infrastructure
+
+import (
+	"context"
+	"database/sql"
+	"goNexttask/internal/quality/domain"
+)
+
+type PostgresQualityAlertRepository struct {
+	db *sql.DB
+}
+
+func NewPostgresQualityAlertRepository(db *sql.DB) *PostgresQualityAlertRepository {
+	return &PostgresQualityAlertRepository{
+		db: db,
+	}
+}
+
+func (r *PostgresQualityAlertRepository) Save(ctx context.Context, alert *domain.QualityAlert) error {
+	query := `
+		INSERT INTO quality_alerts (inspection_id, alert_type, message, created_at)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id
+	`
+
+	return r.db.QueryRowContext(ctx, query,
+		alert.InspectionID,
+		alert.AlertType,
+		alert.Message,
+		alert.CreatedAt,
+	).Scan(&alert.ID)
+}
+
+func (r *PostgresQualityAlertRepository) FindByID(ctx context.Context, id int64) (*domain.QualityAlert, error) {
+	query := `
+		SELECT id, inspection_id, alert_type, message, created_at
+		FROM quality_alerts
+		WHERE id = $1
+	`
+
+	var alert domain.QualityAlert
+	err := r.db.QueryRowContext(ctx, query, id).Scan(
+		&alert.ID,
+		&alert.InspectionID,
+		&alert.AlertType,
+		&alert.Message,
+		&alert.CreatedAt,
+	)
+	if err == sql.ErrNoRows {
+		return nil, domain.ErrQualityAlertNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &alert, nil
+}
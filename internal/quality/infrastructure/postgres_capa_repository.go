@@ -0,0 +1,337 @@
+package infrastructure
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"goNexttask/internal/quality/domain"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+const capaColumns = `id, title, description, sources, defect_codes, status, root_cause, actions, verifications,
+	opened_by, created_at, updated_at, version`
+
+// capaSourceRecord・rootCauseRecord・capaActionRecord・effectivenessCheckRecord は JSONB 列に保存する内容
+type capaSourceRecord struct {
+	Type      string `json:"type"`
+	Reference string `json:"reference"`
+}
+
+type rootCauseRecord struct {
+	Whys       []string  `json:"whys"`
+	Category   string    `json:"category"`
+	RootCause  string    `json:"rootCause"`
+	AnalyzedBy string    `json:"analyzedBy"`
+	AnalyzedAt time.Time `json:"analyzedAt"`
+}
+
+type capaActionRecord struct {
+	Number      int        `json:"number"`
+	Type        string     `json:"type"`
+	Description string     `json:"description"`
+	Owner       string     `json:"owner"`
+	DueDate     time.Time  `json:"dueDate"`
+	CompletedBy string     `json:"completedBy,omitempty"`
+	CompletedAt *time.Time `json:"completedAt,omitempty"`
+	RemindedAt  *time.Time `json:"remindedAt,omitempty"`
+}
+
+type effectivenessCheckRecord struct {
+	Effective  bool      `json:"effective"`
+	Evidence   string    `json:"evidence"`
+	VerifiedBy string    `json:"verifiedBy"`
+	VerifiedAt time.Time `json:"verifiedAt"`
+}
+
+type PostgresCAPARepository struct {
+	db *sql.DB
+}
+
+func NewPostgresCAPARepository(db *sql.DB) *PostgresCAPARepository {
+	return &PostgresCAPARepository{
+		db: db,
+	}
+}
+
+func (r *PostgresCAPARepository) Save(ctx context.Context, capa *domain.CAPA) error {
+	record, err := marshalCAPA(capa)
+	if err != nil {
+		return err
+	}
+
+	capa.Version = 1
+	query := `INSERT INTO capas (` + capaColumns + `) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)`
+
+	_, err = r.db.ExecContext(ctx, query,
+		capa.ID,
+		capa.Title,
+		capa.Description,
+		record.sources,
+		pq.Array(capa.DefectCodes),
+		capa.Status,
+		nullJSON(record.rootCause),
+		record.actions,
+		record.verifications,
+		capa.OpenedBy,
+		capa.CreatedAt,
+		capa.UpdatedAt,
+		capa.Version,
+	)
+
+	return err
+}
+
+func (r *PostgresCAPARepository) Update(ctx context.Context, capa *domain.CAPA) error {
+	record, err := marshalCAPA(capa)
+	if err != nil {
+		return err
+	}
+
+	query := `
+		UPDATE capas
+		SET status = $2, root_cause = $3, actions = $4, verifications = $5, updated_at = $6, version = version + 1
+		WHERE id = $1 AND version = $7
+	`
+
+	result, err := r.db.ExecContext(ctx, query,
+		capa.ID,
+		capa.Status,
+		nullJSON(record.rootCause),
+		record.actions,
+		record.verifications,
+		capa.UpdatedAt,
+		capa.Version,
+	)
+	if err != nil {
+		return err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		// 存在しないか、読み込んだ後に他で保存された
+		if _, err := r.FindByID(ctx, capa.ID); err != nil {
+			return err
+		}
+		return domain.ErrCAPAConflict
+	}
+	capa.Version++
+
+	return nil
+}
+
+func (r *PostgresCAPARepository) FindByID(ctx context.Context, id domain.CAPAID) (*domain.CAPA, error) {
+	query := `SELECT ` + capaColumns + ` FROM capas WHERE id = $1`
+
+	capa, err := scanCAPA(r.db.QueryRowContext(ctx, query, id))
+	if err == sql.ErrNoRows {
+		return nil, domain.ErrCAPANotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return capa, nil
+}
+
+func (r *PostgresCAPARepository) FindAll(ctx context.Context, status domain.CAPAStatus) ([]*domain.CAPA, error) {
+	query := `
+		SELECT ` + capaColumns + `
+		FROM capas
+		WHERE ($1 = '' OR status = $1)
+		ORDER BY created_at DESC
+	`
+
+	return r.findMany(ctx, query, status)
+}
+
+func (r *PostgresCAPARepository) FindActive(ctx context.Context) ([]*domain.CAPA, error) {
+	query := `
+		SELECT ` + capaColumns + `
+		FROM capas
+		WHERE status <> $1
+		ORDER BY created_at
+	`
+
+	return r.findMany(ctx, query, domain.CAPAStatusClosed)
+}
+
+func (r *PostgresCAPARepository) findMany(ctx context.Context, query string, args ...interface{}) ([]*domain.CAPA, error) {
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var capas []*domain.CAPA
+
+	for rows.Next() {
+		capa, err := scanCAPA(rows)
+		if err != nil {
+			return nil, err
+		}
+		capas = append(capas, capa)
+	}
+
+	return capas, rows.Err()
+}
+
+// capaJSON は CAPA の JSONB 列。根本原因が未分析の場合 rootCause は NULL
+type capaJSON struct {
+	sources       []byte
+	rootCause     []byte
+	actions       []byte
+	verifications []byte
+}
+
+func marshalCAPA(capa *domain.CAPA) (*capaJSON, error) {
+	sources := make([]capaSourceRecord, len(capa.Sources))
+	for i, s := range capa.Sources {
+		sources[i] = capaSourceRecord{
+			Type:      string(s.Type),
+			Reference: s.Reference,
+		}
+	}
+	actions := make([]capaActionRecord, len(capa.Actions))
+	for i, a := range capa.Actions {
+		actions[i] = capaActionRecord{
+			Number:      a.Number,
+			Type:        string(a.Type),
+			Description: a.Description,
+			Owner:       a.Owner,
+			DueDate:     a.DueDate,
+			CompletedBy: a.CompletedBy,
+			CompletedAt: a.CompletedAt,
+			RemindedAt:  a.RemindedAt,
+		}
+	}
+	verifications := make([]effectivenessCheckRecord, len(capa.Verifications))
+	for i, v := range capa.Verifications {
+		verifications[i] = effectivenessCheckRecord{
+			Effective:  v.Effective,
+			Evidence:   v.Evidence,
+			VerifiedBy: v.VerifiedBy,
+			VerifiedAt: v.VerifiedAt,
+		}
+	}
+
+	var record capaJSON
+	var err error
+	if record.sources, err = json.Marshal(sources); err != nil {
+		return nil, err
+	}
+	if record.actions, err = json.Marshal(actions); err != nil {
+		return nil, err
+	}
+	if record.verifications, err = json.Marshal(verifications); err != nil {
+		return nil, err
+	}
+	if capa.RootCause != nil {
+		record.rootCause, err = json.Marshal(rootCauseRecord{
+			Whys:       capa.RootCause.Whys,
+			Category:   string(capa.RootCause.Category),
+			RootCause:  capa.RootCause.RootCause,
+			AnalyzedBy: capa.RootCause.AnalyzedBy,
+			AnalyzedAt: capa.RootCause.AnalyzedAt,
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return &record, nil
+}
+
+func scanCAPA(row rowScanner) (*domain.CAPA, error) {
+	var capa domain.CAPA
+	var record capaJSON
+	var defectCodes pq.StringArray
+
+	err := row.Scan(
+		&capa.ID,
+		&capa.Title,
+		&capa.Description,
+		&record.sources,
+		&defectCodes,
+		&capa.Status,
+		&record.rootCause,
+		&record.actions,
+		&record.verifications,
+		&capa.OpenedBy,
+		&capa.CreatedAt,
+		&capa.UpdatedAt,
+		&capa.Version,
+	)
+	if err != nil {
+		return nil, err
+	}
+	capa.DefectCodes = defectCodes
+
+	var sources []capaSourceRecord
+	if err := json.Unmarshal(record.sources, &sources); err != nil {
+		return nil, err
+	}
+	for _, s := range sources {
+		capa.Sources = append(capa.Sources, domain.CAPASource{
+			Type:      domain.CAPASourceType(s.Type),
+			Reference: s.Reference,
+		})
+	}
+
+	if record.rootCause != nil {
+		var rootCause rootCauseRecord
+		if err := json.Unmarshal(record.rootCause, &rootCause); err != nil {
+			return nil, err
+		}
+		capa.RootCause = &domain.RootCauseAnalysis{
+			Whys:       rootCause.Whys,
+			Category:   domain.FishboneCategory(rootCause.Category),
+			RootCause:  rootCause.RootCause,
+			AnalyzedBy: rootCause.AnalyzedBy,
+			AnalyzedAt: rootCause.AnalyzedAt,
+		}
+	}
+
+	var actions []capaActionRecord
+	if err := json.Unmarshal(record.actions, &actions); err != nil {
+		return nil, err
+	}
+	for _, a := range actions {
+		capa.Actions = append(capa.Actions, domain.CAPAAction{
+			Number:      a.Number,
+			Type:        domain.CAPAActionType(a.Type),
+			Description: a.Description,
+			Owner:       a.Owner,
+			DueDate:     a.DueDate,
+			CompletedBy: a.CompletedBy,
+			CompletedAt: a.CompletedAt,
+			RemindedAt:  a.RemindedAt,
+		})
+	}
+
+	var verifications []effectivenessCheckRecord
+	if err := json.Unmarshal(record.verifications, &verifications); err != nil {
+		return nil, err
+	}
+	for _, v := range verifications {
+		capa.Verifications = append(capa.Verifications, domain.EffectivenessCheck{
+			Effective:  v.Effective,
+			Evidence:   v.Evidence,
+			VerifiedBy: v.VerifiedBy,
+			VerifiedAt: v.VerifiedAt,
+		})
+	}
+
+	return &capa, nil
+}
+
+// nullJSON は空の JSONB 列を NULL として書き込む
+func nullJSON(b []byte) interface{} {
+	if b == nil {
+		return nil
+	}
+	return b
+}
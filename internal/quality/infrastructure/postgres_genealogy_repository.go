@@ -0,0 +1,129 @@
+package infrastructure
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"goNexttask/internal/quality/domain"
+)
+
+const lotMovementColumns = `id, lot_number, product_type, quantity, in_out, production_order_id, transaction_date`
+
+// PostgresGenealogyRepository は lot_inventory の入出庫と production_orders・nc_jobs からロットの系譜を読み取る
+type PostgresGenealogyRepository struct {
+	db *sql.DB
+}
+
+func NewPostgresGenealogyRepository(db *sql.DB) *PostgresGenealogyRepository {
+	return &PostgresGenealogyRepository{
+		db: db,
+	}
+}
+
+func (r *PostgresGenealogyRepository) FindMovementsByLot(ctx context.Context, lotNumber string) ([]domain.LotMovement, error) {
+	query := `
+		SELECT ` + lotMovementColumns + `
+		FROM lot_inventory
+		WHERE lot_number = $1
+		ORDER BY transaction_date, id
+	`
+
+	return r.findMovements(ctx, query, lotNumber)
+}
+
+func (r *PostgresGenealogyRepository) FindMovementsByOrder(ctx context.Context, productionOrderID string) ([]domain.LotMovement, error) {
+	query := `
+		SELECT ` + lotMovementColumns + `
+		FROM lot_inventory
+		WHERE production_order_id = $1
+		ORDER BY transaction_date, id
+	`
+
+	return r.findMovements(ctx, query, productionOrderID)
+}
+
+// FindOrder の機械は NC のジョブを実行した機械。ジョブが無い場合は製造オーダーに割り当てた機械
+func (r *PostgresGenealogyRepository) FindOrder(ctx context.Context, productionOrderID string) (*domain.GenealogyOrder, error) {
+	order := &domain.GenealogyOrder{ID: productionOrderID}
+	var assignedMachines sql.NullString
+
+	query := `
+		SELECT order_number, part_id, quantity, status, assigned_machines
+		FROM production_orders
+		WHERE id = $1
+	`
+
+	err := r.db.QueryRowContext(ctx, query, productionOrderID).Scan(
+		&order.OrderNumber,
+		&order.PartID,
+		&order.Quantity,
+		&order.Status,
+		&assignedMachines,
+	)
+	if err != nil && err != sql.ErrNoRows {
+		return nil, err
+	}
+
+	jobQuery := `
+		SELECT machine_id
+		FROM nc_jobs
+		WHERE production_order_id = $1
+		GROUP BY machine_id
+		ORDER BY MIN(started_at)
+	`
+
+	rows, err := r.db.QueryContext(ctx, jobQuery, productionOrderID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var machineID string
+		if err := rows.Scan(&machineID); err != nil {
+			return nil, err
+		}
+		order.MachineIDs = append(order.MachineIDs, machineID)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if len(order.MachineIDs) == 0 && assignedMachines.String != "" {
+		if err := json.Unmarshal([]byte(assignedMachines.String), &order.MachineIDs); err != nil {
+			return nil, err
+		}
+	}
+
+	return order, nil
+}
+
+func (r *PostgresGenealogyRepository) findMovements(ctx context.Context, query string, args ...interface{}) ([]domain.LotMovement, error) {
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var movements []domain.LotMovement
+
+	for rows.Next() {
+		var m domain.LotMovement
+		var productionOrderID sql.NullString
+		if err := rows.Scan(
+			&m.ID,
+			&m.LotNumber,
+			&m.ProductType,
+			&m.Quantity,
+			&m.Direction,
+			&productionOrderID,
+			&m.TransactionDate,
+		); err != nil {
+			return nil, err
+		}
+		m.ProductionOrderID = productionOrderID.String
+		movements = append(movements, m)
+	}
+
+	return movements, rows.Err()
+}
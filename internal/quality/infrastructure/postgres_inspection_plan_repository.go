@@ -0,0 +1,210 @@
+package infrastructure
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"goNexttask/internal/quality/domain"
+
+	"github.com/lib/pq"
+)
+
+const inspectionPlanColumns = `id, part_id, operation, revision, aql, inspection_level, characteristics, created_by, created_at`
+
+// planCharacteristicRecord は characteristics 列（JSONB）に保存する特性
+type planCharacteristicRecord struct {
+	ParameterName         string   `json:"parameterName"`
+	CharacteristicType    string   `json:"characteristicType"`
+	Target                float64  `json:"target"`
+	Tolerance             float64  `json:"tolerance"`
+	UpperDeviation        *float64 `json:"upperDeviation,omitempty"`
+	LowerDeviation        *float64 `json:"lowerDeviation,omitempty"`
+	UpperLimit            *float64 `json:"upperLimit,omitempty"`
+	LowerLimit            *float64 `json:"lowerLimit,omitempty"`
+	MaterialCondition     string   `json:"materialCondition,omitempty"`
+	MaterialConditionSize *float64 `json:"materialConditionSize,omitempty"`
+	FeatureOfSize         string   `json:"featureOfSize,omitempty"`
+	Unit                  string   `json:"unit"`
+	Instrument            string   `json:"instrument,omitempty"`
+	Frequency             string   `json:"frequency"`
+	Required              bool     `json:"required"`
+}
+
+type PostgresInspectionPlanRepository struct {
+	db *sql.DB
+}
+
+func NewPostgresInspectionPlanRepository(db *sql.DB) *PostgresInspectionPlanRepository {
+	return &PostgresInspectionPlanRepository{
+		db: db,
+	}
+}
+
+func (r *PostgresInspectionPlanRepository) Save(ctx context.Context, plan *domain.InspectionPlan) error {
+	characteristicsJSON, err := marshalPlanCharacteristics(plan.Characteristics)
+	if err != nil {
+		return err
+	}
+
+	query := `INSERT INTO inspection_plans (` + inspectionPlanColumns + `) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)`
+
+	_, err = r.db.ExecContext(ctx, query,
+		plan.ID,
+		plan.PartID,
+		plan.Operation,
+		plan.Revision,
+		plan.AQL,
+		plan.InspectionLevel,
+		characteristicsJSON,
+		plan.CreatedBy,
+		plan.CreatedAt,
+	)
+
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code.Name() == "unique_violation" {
+		return domain.ErrInspectionPlanConflict
+	}
+
+	return err
+}
+
+func (r *PostgresInspectionPlanRepository) FindByID(ctx context.Context, id domain.InspectionPlanID) (*domain.InspectionPlan, error) {
+	query := `SELECT ` + inspectionPlanColumns + ` FROM inspection_plans WHERE id = $1`
+	return r.findOne(ctx, query, id)
+}
+
+func (r *PostgresInspectionPlanRepository) FindLatest(ctx context.Context, partID, operation string) (*domain.InspectionPlan, error) {
+	query := `
+		SELECT ` + inspectionPlanColumns + `
+		FROM inspection_plans
+		WHERE part_id = $1 AND operation = $2
+		ORDER BY revision DESC
+		LIMIT 1
+	`
+	return r.findOne(ctx, query, partID, operation)
+}
+
+func (r *PostgresInspectionPlanRepository) FindLatestForOrder(ctx context.Context, productionOrderID, operation string) (*domain.InspectionPlan, error) {
+	query := `
+		SELECT p.id, p.part_id, p.operation, p.revision, p.aql, p.inspection_level,
+			   p.characteristics, p.created_by, p.created_at
+		FROM inspection_plans p
+		JOIN production_orders o ON o.part_id = p.part_id
+		WHERE o.id = $1 AND p.operation = $2
+		ORDER BY p.revision DESC
+		LIMIT 1
+	`
+	return r.findOne(ctx, query, productionOrderID, operation)
+}
+
+func (r *PostgresInspectionPlanRepository) FindByPart(ctx context.Context, partID, operation string) ([]*domain.InspectionPlan, error) {
+	query := `
+		SELECT ` + inspectionPlanColumns + `
+		FROM inspection_plans
+		WHERE part_id = $1 AND ($2 = '' OR operation = $2)
+		ORDER BY operation, revision DESC
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, partID, operation)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var plans []*domain.InspectionPlan
+
+	for rows.Next() {
+		plan, err := scanInspectionPlan(rows)
+		if err != nil {
+			return nil, err
+		}
+		plans = append(plans, plan)
+	}
+
+	return plans, rows.Err()
+}
+
+func (r *PostgresInspectionPlanRepository) findOne(ctx context.Context, query string, args ...interface{}) (*domain.InspectionPlan, error) {
+	plan, err := scanInspectionPlan(r.db.QueryRowContext(ctx, query, args...))
+	if err == sql.ErrNoRows {
+		return nil, domain.ErrInspectionPlanNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return plan, nil
+}
+
+func marshalPlanCharacteristics(characteristics []domain.PlanCharacteristic) ([]byte, error) {
+	records := make([]planCharacteristicRecord, len(characteristics))
+	for i, c := range characteristics {
+		records[i] = planCharacteristicRecord{
+			ParameterName:         c.ParameterName,
+			CharacteristicType:    string(c.Spec.Type),
+			Target:                c.Spec.Target,
+			Tolerance:             c.Spec.Tolerance,
+			UpperDeviation:        c.Spec.UpperDeviation,
+			LowerDeviation:        c.Spec.LowerDeviation,
+			UpperLimit:            c.Spec.UpperLimit,
+			LowerLimit:            c.Spec.LowerLimit,
+			MaterialCondition:     string(c.Spec.MaterialCondition),
+			MaterialConditionSize: c.Spec.MaterialConditionSize,
+			FeatureOfSize:         string(c.Spec.Feature),
+			Unit:                  c.Unit,
+			Instrument:            c.Instrument,
+			Frequency:             string(c.Frequency),
+			Required:              c.Required,
+		}
+	}
+	return json.Marshal(records)
+}
+
+func scanInspectionPlan(row rowScanner) (*domain.InspectionPlan, error) {
+	var plan domain.InspectionPlan
+	var characteristicsJSON []byte
+
+	err := row.Scan(
+		&plan.ID,
+		&plan.PartID,
+		&plan.Operation,
+		&plan.Revision,
+		&plan.AQL,
+		&plan.InspectionLevel,
+		&characteristicsJSON,
+		&plan.CreatedBy,
+		&plan.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	var records []planCharacteristicRecord
+	if err := json.Unmarshal(characteristicsJSON, &records); err != nil {
+		return nil, err
+	}
+	for _, record := range records {
+		plan.Characteristics = append(plan.Characteristics, domain.PlanCharacteristic{
+			ParameterName: record.ParameterName,
+			Spec: domain.ToleranceSpec{
+				Type:                  domain.CharacteristicType(record.CharacteristicType),
+				Target:                record.Target,
+				Tolerance:             record.Tolerance,
+				UpperDeviation:        record.UpperDeviation,
+				LowerDeviation:        record.LowerDeviation,
+				UpperLimit:            record.UpperLimit,
+				LowerLimit:            record.LowerLimit,
+				MaterialCondition:     domain.MaterialCondition(record.MaterialCondition),
+				MaterialConditionSize: record.MaterialConditionSize,
+				Feature:               domain.FeatureOfSize(record.FeatureOfSize),
+			},
+			Unit:       record.Unit,
+			Instrument: record.Instrument,
+			Frequency:  domain.InspectionFrequency(record.Frequency),
+			Required:   record.Required,
+		})
+	}
+
+	return &plan, nil
+}
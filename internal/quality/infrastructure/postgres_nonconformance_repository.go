@@ -0,0 +1,267 @@
+package infrastructure
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"goNexttask/internal/quality/domain"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+const nonconformanceColumns = `id, inspection_id, production_order_id, lot_number, affected_quantity, defects, status,
+	dispositions, proposed_by, proposed_at, approved_by, approved_at, mrb_comment, created_at, updated_at`
+
+// defectRecord・dispositionLineRecord は defects・dispositions 列（JSONB）に保存する内容
+type defectRecord struct {
+	Code          string `json:"code"`
+	ParameterName string `json:"parameterName"`
+	Count         int    `json:"count"`
+}
+
+type dispositionLineRecord struct {
+	Disposition string `json:"disposition"`
+	Quantity    int    `json:"quantity"`
+}
+
+type PostgresNonconformanceRepository struct {
+	db *sql.DB
+}
+
+func NewPostgresNonconformanceRepository(db *sql.DB) *PostgresNonconformanceRepository {
+	return &PostgresNonconformanceRepository{
+		db: db,
+	}
+}
+
+func (r *PostgresNonconformanceRepository) Save(ctx context.Context, ncr *domain.Nonconformance) error {
+	defectsJSON, dispositionsJSON, err := marshalNonconformance(ncr)
+	if err != nil {
+		return err
+	}
+
+	query := `INSERT INTO nonconformance_reports (` + nonconformanceColumns + `)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15)`
+
+	_, err = r.db.ExecContext(ctx, query,
+		ncr.ID,
+		ncr.InspectionID,
+		ncr.ProductionOrderID,
+		ncr.LotNumber,
+		ncr.AffectedQuantity,
+		defectsJSON,
+		ncr.Status,
+		dispositionsJSON,
+		ncr.ProposedBy,
+		ncr.ProposedAt,
+		ncr.ApprovedBy,
+		ncr.ApprovedAt,
+		ncr.MRBComment,
+		ncr.CreatedAt,
+		ncr.UpdatedAt,
+	)
+
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code.Name() == "unique_violation" {
+		return domain.ErrNonconformanceConflict
+	}
+
+	return err
+}
+
+func (r *PostgresNonconformanceRepository) Update(ctx context.Context, ncr *domain.Nonconformance, from domain.NonconformanceStatus) error {
+	_, dispositionsJSON, err := marshalNonconformance(ncr)
+	if err != nil {
+		return err
+	}
+
+	query := `
+		UPDATE nonconformance_reports
+		SET status = $2, dispositions = $3, proposed_by = $4, proposed_at = $5,
+			approved_by = $6, approved_at = $7, mrb_comment = $8, updated_at = $9
+		WHERE id = $1 AND status = $10
+	`
+
+	result, err := r.db.ExecContext(ctx, query,
+		ncr.ID,
+		ncr.Status,
+		dispositionsJSON,
+		ncr.ProposedBy,
+		ncr.ProposedAt,
+		ncr.ApprovedBy,
+		ncr.ApprovedAt,
+		ncr.MRBComment,
+		ncr.UpdatedAt,
+		from,
+	)
+	if err != nil {
+		return err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		// 存在しないか、別の要求で状態が変わった
+		if _, err := r.FindByID(ctx, ncr.ID); err != nil {
+			return err
+		}
+		return domain.ErrInvalidStateTransition
+	}
+
+	return nil
+}
+
+func (r *PostgresNonconformanceRepository) FindByID(ctx context.Context, id domain.NonconformanceID) (*domain.Nonconformance, error) {
+	query := `SELECT ` + nonconformanceColumns + ` FROM nonconformance_reports WHERE id = $1`
+	return r.findOne(ctx, query, id)
+}
+
+func (r *PostgresNonconformanceRepository) FindByInspectionID(ctx context.Context, inspectionID domain.InspectionID) (*domain.Nonconformance, error) {
+	query := `SELECT ` + nonconformanceColumns + ` FROM nonconformance_reports WHERE inspection_id = $1`
+	return r.findOne(ctx, query, inspectionID)
+}
+
+func (r *PostgresNonconformanceRepository) FindAll(ctx context.Context, status domain.NonconformanceStatus, lotNumber string) ([]*domain.Nonconformance, error) {
+	query := `
+		SELECT ` + nonconformanceColumns + `
+		FROM nonconformance_reports
+		WHERE ($1 = '' OR status = $1) AND ($2 = '' OR lot_number = $2)
+		ORDER BY created_at DESC
+	`
+
+	return r.findMany(ctx, query, status, lotNumber)
+}
+
+func (r *PostgresNonconformanceRepository) FindCreatedSince(ctx context.Context, since time.Time) ([]*domain.Nonconformance, error) {
+	query := `
+		SELECT ` + nonconformanceColumns + `
+		FROM nonconformance_reports
+		WHERE created_at >= $1
+		ORDER BY created_at
+	`
+
+	return r.findMany(ctx, query, since)
+}
+
+func (r *PostgresNonconformanceRepository) findMany(ctx context.Context, query string, args ...interface{}) ([]*domain.Nonconformance, error) {
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ncrs []*domain.Nonconformance
+
+	for rows.Next() {
+		ncr, err := scanNonconformance(rows)
+		if err != nil {
+			return nil, err
+		}
+		ncrs = append(ncrs, ncr)
+	}
+
+	return ncrs, rows.Err()
+}
+
+func (r *PostgresNonconformanceRepository) findOne(ctx context.Context, query string, args ...interface{}) (*domain.Nonconformance, error) {
+	ncr, err := scanNonconformance(r.db.QueryRowContext(ctx, query, args...))
+	if err == sql.ErrNoRows {
+		return nil, domain.ErrNonconformanceNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return ncr, nil
+}
+
+func marshalNonconformance(ncr *domain.Nonconformance) ([]byte, []byte, error) {
+	defects := make([]defectRecord, len(ncr.Defects))
+	for i, d := range ncr.Defects {
+		defects[i] = defectRecord{
+			Code:          d.Code,
+			ParameterName: d.ParameterName,
+			Count:         d.Count,
+		}
+	}
+	dispositions := make([]dispositionLineRecord, len(ncr.Dispositions))
+	for i, line := range ncr.Dispositions {
+		dispositions[i] = dispositionLineRecord{
+			Disposition: string(line.Disposition),
+			Quantity:    line.Quantity,
+		}
+	}
+
+	defectsJSON, err := json.Marshal(defects)
+	if err != nil {
+		return nil, nil, err
+	}
+	dispositionsJSON, err := json.Marshal(dispositions)
+	if err != nil {
+		return nil, nil, err
+	}
+	return defectsJSON, dispositionsJSON, nil
+}
+
+func scanNonconformance(row rowScanner) (*domain.Nonconformance, error) {
+	var ncr domain.Nonconformance
+	var defectsJSON, dispositionsJSON []byte
+	var proposedAt, approvedAt sql.NullTime
+
+	err := row.Scan(
+		&ncr.ID,
+		&ncr.InspectionID,
+		&ncr.ProductionOrderID,
+		&ncr.LotNumber,
+		&ncr.AffectedQuantity,
+		&defectsJSON,
+		&ncr.Status,
+		&dispositionsJSON,
+		&ncr.ProposedBy,
+		&proposedAt,
+		&ncr.ApprovedBy,
+		&approvedAt,
+		&ncr.MRBComment,
+		&ncr.CreatedAt,
+		&ncr.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if proposedAt.Valid {
+		ncr.ProposedAt = &proposedAt.Time
+	}
+	if approvedAt.Valid {
+		ncr.ApprovedAt = &approvedAt.Time
+	}
+
+	var defects []defectRecord
+	if err := json.Unmarshal(defectsJSON, &defects); err != nil {
+		return nil, err
+	}
+	for _, d := range defects {
+		ncr.Defects = append(ncr.Defects, domain.Defect{
+			Code:          d.Code,
+			ParameterName: d.ParameterName,
+			Count:         d.Count,
+		})
+	}
+
+	var dispositions []dispositionLineRecord
+	if err := json.Unmarshal(dispositionsJSON, &dispositions); err != nil {
+		return nil, err
+	}
+	for _, line := range dispositions {
+		ncr.Dispositions = append(ncr.Dispositions, domain.DispositionLine{
+			Disposition: domain.Disposition(line.Disposition),
+			Quantity:    line.Quantity,
+		})
+	}
+
+	return &ncr, nil
+}
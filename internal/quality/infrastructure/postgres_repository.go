@@ -3,9 +3,68 @@ package infrastructure
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
 	"goNexttask/internal/quality/domain"
+	"time"
+
+	"github.com/lib/pq"
 )
 
+const inspectionColumns = `id, production_order_id, lot_number, inspector_id,
+	status, final_result, created_at, updated_at,
+	plan_id, operation, lot_size, sampling_plan, nonconforming_count, failure_reason,
+	machine_id, manufacturing_record`
+
+const measurementColumns = `parameter_name, measured_value, target_value, tolerance, unit, pass,
+	characteristic_type, lower_limit, upper_limit, material_condition, material_condition_size, actual_size, feature_of_size,
+	sample_number, instrument_id, measured_at`
+
+// samplingPlanRecord は sampling_plan 列（JSONB）に保存する抜取方式
+type samplingPlanRecord struct {
+	AQL        float64 `json:"aql"`
+	Level      string  `json:"level"`
+	LotSize    int     `json:"lotSize"`
+	CodeLetter string  `json:"codeLetter"`
+	SampleSize int     `json:"sampleSize"`
+	Accept     int     `json:"accept"`
+	Reject     int     `json:"reject"`
+}
+
+// manufacturingRecordRecord は manufacturing_record 列（JSONB）に保存する加工の記録
+type manufacturingRecordRecord struct {
+	NCJobID        string                 `json:"ncJobId"`
+	ProgramID      string                 `json:"programId"`
+	ProgramName    string                 `json:"programName"`
+	ProgramVersion string                 `json:"programVersion"`
+	ProgramHash    string                 `json:"programHash"`
+	MachineID      string                 `json:"machineId"`
+	JobStartedAt   time.Time              `json:"jobStartedAt"`
+	Tools          []toolSnapshotRecord   `json:"tools"`
+	Offsets        []offsetSnapshotRecord `json:"offsets"`
+	CapturedAt     time.Time              `json:"capturedAt"`
+}
+
+type toolSnapshotRecord struct {
+	ToolNumber   int       `json:"toolNumber"`
+	Description  string    `json:"description,omitempty"`
+	AssemblyID   string    `json:"assemblyId"`
+	AssemblyName string    `json:"assemblyName"`
+	CutTimeSec   float64   `json:"cutTimeSec"`
+	PartCount    int       `json:"partCount"`
+	LoadedAt     time.Time `json:"loadedAt"`
+}
+
+type offsetSnapshotRecord struct {
+	Register string             `json:"register"`
+	Values   map[string]float64 `json:"values"`
+	Version  int                `json:"version"`
+	ChangeID string             `json:"changeId,omitempty"`
+}
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
 type PostgresInspectionRepository struct {
 	db *sql.DB
 }
@@ -23,12 +82,20 @@ func (r *PostgresInspectionRepository) Save(ctx context.Context, inspection *dom
 	}
 	defer tx.Rollback()
 
+	samplingJSON, err := marshalSamplingPlan(inspection.Sampling)
+	if err != nil {
+		return err
+	}
+
+	manufacturingJSON, err := marshalManufacturingRecord(inspection.Manufacturing)
+	if err != nil {
+		return err
+	}
+
 	// Insert inspection
 	inspectionQuery := `
-		INSERT INTO inspections (
-			id, production_order_id, lot_number, inspector_id,
-			status, final_result, created_at, updated_at
-		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		INSERT INTO inspections (` + inspectionColumns + `)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17)
 	`
 
 	_, err = tx.ExecContext(ctx, inspectionQuery,
@@ -40,60 +107,36 @@ func (r *PostgresInspectionRepository) Save(ctx context.Context, inspection *dom
 		inspection.FinalResult,
 		inspection.CreatedAt,
 		inspection.UpdatedAt,
+		inspection.PlanID,
+		inspection.Operation,
+		inspection.LotSize,
+		samplingJSON,
+		inspection.NonconformingCount,
+		inspection.FailureReason,
+		nullString(inspection.MachineID),
+		nullJSON(manufacturingJSON),
 	)
 	if err != nil {
 		return err
 	}
 
 	// Insert measurement results
-	measurementQuery := `
-		INSERT INTO measurement_results (
-			inspection_id, parameter_name, measured_value,
-			target_value, tolerance, unit, pass
-		) VALUES ($1, $2, $3, $4, $5, $6, $7)
-	`
-
-	for _, result := range inspection.Results {
-		_, err = tx.ExecContext(ctx, measurementQuery,
-			inspection.ID,
-			result.ParameterName,
-			result.MeasuredValue,
-			result.TargetValue,
-			result.Tolerance,
-			result.Unit,
-			result.Pass,
-		)
-		if err != nil {
-			return err
-		}
+	if err := insertMeasurementResults(ctx, tx, inspection); err != nil {
+		return err
 	}
 
 	return tx.Commit()
 }
 
 func (r *PostgresInspectionRepository) FindByID(ctx context.Context, id domain.InspectionID) (*domain.Inspection, error) {
-	inspection := &domain.Inspection{}
-
 	// Get inspection
 	inspectionQuery := `
-		SELECT id, production_order_id, lot_number, inspector_id,
-			   status, final_result, created_at, updated_at
+		SELECT ` + inspectionColumns + `
 		FROM inspections
 		WHERE id = $1
 	`
 
-	var finalResult sql.NullString
-	err := r.db.QueryRowContext(ctx, inspectionQuery, id).Scan(
-		&inspection.ID,
-		&inspection.ProductionOrderID,
-		&inspection.LotNumber,
-		&inspection.InspectorID,
-		&inspection.Status,
-		&finalResult,
-		&inspection.CreatedAt,
-		&inspection.UpdatedAt,
-	)
-
+	inspection, err := scanInspection(r.db.QueryRowContext(ctx, inspectionQuery, id))
 	if err == sql.ErrNoRows {
 		return nil, domain.ErrInspectionNotFound
 	}
@@ -101,14 +144,9 @@ func (r *PostgresInspectionRepository) FindByID(ctx context.Context, id domain.I
 		return nil, err
 	}
 
-	if finalResult.Valid {
-		inspection.FinalResult = domain.InspectionResult(finalResult.String)
-	}
-
 	// Get measurement results
 	measurementQuery := `
-		SELECT parameter_name, measured_value, target_value,
-			   tolerance, unit, pass
+		SELECT ` + measurementColumns + `
 		FROM measurement_results
 		WHERE inspection_id = $1
 		ORDER BY id
@@ -121,15 +159,7 @@ func (r *PostgresInspectionRepository) FindByID(ctx context.Context, id domain.I
 	defer rows.Close()
 
 	for rows.Next() {
-		var result domain.MeasurementResult
-		err := rows.Scan(
-			&result.ParameterName,
-			&result.MeasuredValue,
-			&result.TargetValue,
-			&result.Tolerance,
-			&result.Unit,
-			&result.Pass,
-		)
+		result, err := scanMeasurementResult(rows)
 		if err != nil {
 			return nil, err
 		}
@@ -141,8 +171,7 @@ func (r *PostgresInspectionRepository) FindByID(ctx context.Context, id domain.I
 
 func (r *PostgresInspectionRepository) FindByLotNumber(ctx context.Context, lotNumber string) ([]*domain.Inspection, error) {
 	query := `
-		SELECT id, production_order_id, lot_number, inspector_id,
-			   status, final_result, created_at, updated_at
+		SELECT ` + inspectionColumns + `
 		FROM inspections
 		WHERE lot_number = $1
 		ORDER BY created_at DESC
@@ -157,31 +186,14 @@ func (r *PostgresInspectionRepository) FindByLotNumber(ctx context.Context, lotN
 	var inspections []*domain.Inspection
 
 	for rows.Next() {
-		inspection := &domain.Inspection{}
-		var finalResult sql.NullString
-
-		err := rows.Scan(
-			&inspection.ID,
-			&inspection.ProductionOrderID,
-			&inspection.LotNumber,
-			&inspection.InspectorID,
-			&inspection.Status,
-			&finalResult,
-			&inspection.CreatedAt,
-			&inspection.UpdatedAt,
-		)
+		inspection, err := scanInspection(rows)
 		if err != nil {
 			return nil, err
 		}
 
-		if finalResult.Valid {
-			inspection.FinalResult = domain.InspectionResult(finalResult.String)
-		}
-
 		// Get measurement results for each inspection
 		measurementQuery := `
-			SELECT parameter_name, measured_value, target_value,
-				   tolerance, unit, pass
+			SELECT ` + measurementColumns + `
 			FROM measurement_results
 			WHERE inspection_id = $1
 			ORDER BY id
@@ -193,15 +205,7 @@ func (r *PostgresInspectionRepository) FindByLotNumber(ctx context.Context, lotN
 		}
 
 		for measurementRows.Next() {
-			var result domain.MeasurementResult
-			err := measurementRows.Scan(
-				&result.ParameterName,
-				&result.MeasuredValue,
-				&result.TargetValue,
-				&result.Tolerance,
-				&result.Unit,
-				&result.Pass,
-			)
+			result, err := scanMeasurementResult(measurementRows)
 			if err != nil {
 				measurementRows.Close()
 				return nil, err
@@ -218,8 +222,7 @@ func (r *PostgresInspectionRepository) FindByLotNumber(ctx context.Context, lotN
 
 func (r *PostgresInspectionRepository) FindByProductionOrderID(ctx context.Context, orderID string) ([]*domain.Inspection, error) {
 	query := `
-		SELECT id, production_order_id, lot_number, inspector_id,
-			   status, final_result, created_at, updated_at
+		SELECT ` + inspectionColumns + `
 		FROM inspections
 		WHERE production_order_id = $1
 		ORDER BY created_at DESC
@@ -234,27 +237,11 @@ func (r *PostgresInspectionRepository) FindByProductionOrderID(ctx context.Conte
 	var inspections []*domain.Inspection
 
 	for rows.Next() {
-		inspection := &domain.Inspection{}
-		var finalResult sql.NullString
-
-		err := rows.Scan(
-			&inspection.ID,
-			&inspection.ProductionOrderID,
-			&inspection.LotNumber,
-			&inspection.InspectorID,
-			&inspection.Status,
-			&finalResult,
-			&inspection.CreatedAt,
-			&inspection.UpdatedAt,
-		)
+		inspection, err := scanInspection(rows)
 		if err != nil {
 			return nil, err
 		}
 
-		if finalResult.Valid {
-			inspection.FinalResult = domain.InspectionResult(finalResult.String)
-		}
-
 		inspections = append(inspections, inspection)
 	}
 
@@ -268,11 +255,23 @@ func (r *PostgresInspectionRepository) Update(ctx context.Context, inspection *d
 	}
 	defer tx.Rollback()
 
+	samplingJSON, err := marshalSamplingPlan(inspection.Sampling)
+	if err != nil {
+		return err
+	}
+
+	manufacturingJSON, err := marshalManufacturingRecord(inspection.Manufacturing)
+	if err != nil {
+		return err
+	}
+
 	// Update inspection
 	inspectionQuery := `
 		UPDATE inspections
 		SET production_order_id = $2, lot_number = $3, inspector_id = $4,
-			status = $5, final_result = $6, updated_at = $7
+			status = $5, final_result = $6, updated_at = $7,
+			plan_id = $8, operation = $9, lot_size = $10, sampling_plan = $11, nonconforming_count = $12,
+			failure_reason = $13, machine_id = $14, manufacturing_record = $15
 		WHERE id = $1
 	`
 
@@ -284,6 +283,14 @@ func (r *PostgresInspectionRepository) Update(ctx context.Context, inspection *d
 		inspection.Status,
 		inspection.FinalResult,
 		inspection.UpdatedAt,
+		inspection.PlanID,
+		inspection.Operation,
+		inspection.LotSize,
+		samplingJSON,
+		inspection.NonconformingCount,
+		inspection.FailureReason,
+		nullString(inspection.MachineID),
+		nullJSON(manufacturingJSON),
 	)
 	if err != nil {
 		return err
@@ -297,15 +304,167 @@ func (r *PostgresInspectionRepository) Update(ctx context.Context, inspection *d
 	}
 
 	// Insert new measurement results
-	measurementQuery := `
-		INSERT INTO measurement_results (
-			inspection_id, parameter_name, measured_value,
-			target_value, tolerance, unit, pass
-		) VALUES ($1, $2, $3, $4, $5, $6, $7)
+	if err := insertMeasurementResults(ctx, tx, inspection); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// FindMeasurements は測定値を測定日時（無い場合は検査の日時）の順に返す。中止した検査は除き、部品は検査の製造オーダーから絞り込む
+func (r *PostgresInspectionRepository) FindMeasurements(ctx context.Context, filter domain.MeasurementFilter) ([]domain.MeasurementSample, error) {
+	query := `
+		SELECT i.id, i.lot_number, m.parameter_name, m.measured_value, m.target_value,
+			   CASE WHEN m.lower_limit IS NULL AND m.upper_limit IS NULL THEN m.target_value - m.tolerance ELSE m.lower_limit END,
+			   CASE WHEN m.lower_limit IS NULL AND m.upper_limit IS NULL THEN m.target_value + m.tolerance ELSE m.upper_limit END,
+			   m.unit, m.pass, COALESCE(m.measured_at, i.inspection_date, i.created_at)
+		FROM measurement_results m
+		JOIN inspections i ON i.id = m.inspection_id
+		LEFT JOIN production_orders o ON o.id = i.production_order_id
+		WHERE ($1 = '' OR i.lot_number = $1)
+		  AND ($2 = '' OR o.part_id = $2)
+		  AND ($3 = '' OR m.parameter_name = $3)
+		  AND ($4::timestamp IS NULL OR COALESCE(m.measured_at, i.inspection_date, i.created_at) >= $4)
+		  AND ($5::timestamp IS NULL OR COALESCE(m.measured_at, i.inspection_date, i.created_at) < $5)
+		  AND (i.status IS NULL OR i.status <> 'failed')
+		ORDER BY COALESCE(m.measured_at, i.inspection_date, i.created_at), m.id
+	`
+
+	rows, err := r.db.QueryContext(ctx, query,
+		filter.LotNumber,
+		filter.PartID,
+		filter.ParameterName,
+		filter.From,
+		filter.To,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var samples []domain.MeasurementSample
+
+	for rows.Next() {
+		var sample domain.MeasurementSample
+		err := rows.Scan(
+			&sample.InspectionID,
+			&sample.LotNumber,
+			&sample.ParameterName,
+			&sample.Value,
+			&sample.TargetValue,
+			&sample.LowerLimit,
+			&sample.UpperLimit,
+			&sample.Unit,
+			&sample.Pass,
+			&sample.MeasuredAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		samples = append(samples, sample)
+	}
+
+	return samples, rows.Err()
+}
+
+// FindOutcomes は完了した検査の合否と不合格の測定の不具合コードを検査日時の順に返す。
+// 状態の無い検査（測定のみ記録した検査）は合否があれば含め、プログラムは検査時に記録したジョブから絞り込む。
+// 再検査かどうかは条件で絞り込む前のすべての検査の中での、ロット・工程ごとの順番で決める
+// （期間外や別の作業者・機械の最初の検査がある検査は再検査）
+func (r *PostgresInspectionRepository) FindOutcomes(ctx context.Context, filter domain.AnalyticsFilter) ([]domain.InspectionOutcome, error) {
+	query := `
+		WITH attempts AS (
+			SELECT id, ROW_NUMBER() OVER (
+				PARTITION BY lot_number, operation
+				ORDER BY COALESCE(inspection_date, created_at), id
+			) AS attempt
+			FROM inspections
+			WHERE (status IS NULL OR status = 'completed')
+			  AND COALESCE(final_result, result) IS NOT NULL
+		)
+		SELECT i.id, i.lot_number, i.operation, COALESCE(o.part_id, ''),
+			   COALESCE(i.machine_id, i.manufacturing_record->>'machineId', ''),
+			   COALESCE(i.manufacturing_record->>'programId', ''),
+			   COALESCE(NULLIF(i.operator_id, ''), i.inspector_id, ''),
+			   COALESCE(i.inspection_date, i.created_at),
+			   COALESCE(i.final_result, i.result) = 'pass',
+			   a.attempt > 1,
+			   COALESCE(array_agg(m.characteristic_type ORDER BY m.id) FILTER (WHERE NOT m.pass), '{}'),
+			   COALESCE(array_agg(m.parameter_name ORDER BY m.id) FILTER (WHERE NOT m.pass), '{}')
+		FROM inspections i
+		JOIN attempts a ON a.id = i.id
+		LEFT JOIN production_orders o ON o.id = i.production_order_id
+		LEFT JOIN measurement_results m ON m.inspection_id = i.id
+		WHERE ($1 = '' OR o.part_id = $1)
+		  AND ($2 = '' OR COALESCE(i.machine_id, i.manufacturing_record->>'machineId') = $2)
+		  AND ($3 = '' OR i.manufacturing_record->>'programId' = $3)
+		  AND ($4 = '' OR COALESCE(NULLIF(i.operator_id, ''), i.inspector_id) = $4)
+		  AND ($5::timestamp IS NULL OR COALESCE(i.inspection_date, i.created_at) >= $5)
+		  AND ($6::timestamp IS NULL OR COALESCE(i.inspection_date, i.created_at) < $6)
+		GROUP BY i.id, o.part_id, a.attempt
+		ORDER BY COALESCE(i.inspection_date, i.created_at), i.id
+	`
+
+	rows, err := r.db.QueryContext(ctx, query,
+		filter.PartID,
+		filter.MachineID,
+		filter.ProgramID,
+		filter.OperatorID,
+		filter.From,
+		filter.To,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var outcomes []domain.InspectionOutcome
+
+	for rows.Next() {
+		var outcome domain.InspectionOutcome
+		var characteristicTypes, parameterNames pq.StringArray
+		err := rows.Scan(
+			&outcome.InspectionID,
+			&outcome.LotNumber,
+			&outcome.Operation,
+			&outcome.PartID,
+			&outcome.MachineID,
+			&outcome.ProgramID,
+			&outcome.OperatorID,
+			&outcome.InspectedAt,
+			&outcome.Passed,
+			&outcome.Reinspection,
+			&characteristicTypes,
+			&parameterNames,
+		)
+		if err != nil {
+			return nil, err
+		}
+		for i, parameterName := range parameterNames {
+			outcome.DefectCodes = append(outcome.DefectCodes, domain.DefectCode(domain.MeasurementResult{
+				ParameterName:  parameterName,
+				Characteristic: domain.Characteristic{Type: domain.CharacteristicType(characteristicTypes[i])},
+			}))
+		}
+		outcomes = append(outcomes, outcome)
+	}
+
+	return outcomes, rows.Err()
+}
+
+func insertMeasurementResults(ctx context.Context, tx *sql.Tx, inspection *domain.Inspection) error {
+	query := `
+		INSERT INTO measurement_results (inspection_id, ` + measurementColumns + `)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17)
 	`
 
 	for _, result := range inspection.Results {
-		_, err = tx.ExecContext(ctx, measurementQuery,
+		c := result.Characteristic
+		characteristicType := c.Type
+		if characteristicType == "" {
+			characteristicType = domain.CharacteristicDimension
+		}
+		_, err := tx.ExecContext(ctx, query,
 			inspection.ID,
 			result.ParameterName,
 			result.MeasuredValue,
@@ -313,11 +472,228 @@ func (r *PostgresInspectionRepository) Update(ctx context.Context, inspection *d
 			result.Tolerance,
 			result.Unit,
 			result.Pass,
+			characteristicType,
+			c.LowerLimit,
+			c.UpperLimit,
+			c.MaterialCondition,
+			c.MaterialConditionSize,
+			c.ActualSize,
+			c.Feature,
+			result.SampleNumber,
+			result.InstrumentID,
+			nullTime(result.MeasuredAt),
 		)
 		if err != nil {
 			return err
 		}
 	}
+	return nil
+}
 
-	return tx.Commit()
-}
\ No newline at end of file
+func marshalSamplingPlan(plan *domain.SamplingPlan) ([]byte, error) {
+	if plan == nil {
+		return nil, nil
+	}
+	return json.Marshal(samplingPlanRecord{
+		AQL:        plan.AQL,
+		Level:      string(plan.Level),
+		LotSize:    plan.LotSize,
+		CodeLetter: plan.CodeLetter,
+		SampleSize: plan.SampleSize,
+		Accept:     plan.Accept,
+		Reject:     plan.Reject,
+	})
+}
+
+func marshalManufacturingRecord(record *domain.ManufacturingRecord) ([]byte, error) {
+	if record == nil {
+		return nil, nil
+	}
+	tools := make([]toolSnapshotRecord, len(record.Tools))
+	for i, t := range record.Tools {
+		tools[i] = toolSnapshotRecord{
+			ToolNumber:   t.ToolNumber,
+			Description:  t.Description,
+			AssemblyID:   t.AssemblyID,
+			AssemblyName: t.AssemblyName,
+			CutTimeSec:   t.CutTime.Seconds(),
+			PartCount:    t.PartCount,
+			LoadedAt:     t.LoadedAt,
+		}
+	}
+	offsets := make([]offsetSnapshotRecord, len(record.Offsets))
+	for i, o := range record.Offsets {
+		offsets[i] = offsetSnapshotRecord{
+			Register: o.Register,
+			Values:   o.Values,
+			Version:  o.Version,
+			ChangeID: o.ChangeID,
+		}
+	}
+	return json.Marshal(manufacturingRecordRecord{
+		NCJobID:        record.NCJobID,
+		ProgramID:      record.ProgramID,
+		ProgramName:    record.ProgramName,
+		ProgramVersion: record.ProgramVersion,
+		ProgramHash:    record.ProgramHash,
+		MachineID:      record.MachineID,
+		JobStartedAt:   record.JobStartedAt,
+		Tools:          tools,
+		Offsets:        offsets,
+		CapturedAt:     record.CapturedAt,
+	})
+}
+
+func unmarshalManufacturingRecord(record manufacturingRecordRecord) *domain.ManufacturingRecord {
+	manufacturing := &domain.ManufacturingRecord{
+		NCJobID:        record.NCJobID,
+		ProgramID:      record.ProgramID,
+		ProgramName:    record.ProgramName,
+		ProgramVersion: record.ProgramVersion,
+		ProgramHash:    record.ProgramHash,
+		MachineID:      record.MachineID,
+		JobStartedAt:   record.JobStartedAt,
+		CapturedAt:     record.CapturedAt,
+	}
+	for _, t := range record.Tools {
+		manufacturing.Tools = append(manufacturing.Tools, domain.ToolSnapshot{
+			ToolNumber:   t.ToolNumber,
+			Description:  t.Description,
+			AssemblyID:   t.AssemblyID,
+			AssemblyName: t.AssemblyName,
+			CutTime:      time.Duration(t.CutTimeSec * float64(time.Second)),
+			PartCount:    t.PartCount,
+			LoadedAt:     t.LoadedAt,
+		})
+	}
+	for _, o := range record.Offsets {
+		manufacturing.Offsets = append(manufacturing.Offsets, domain.OffsetSnapshot{
+			Register: o.Register,
+			Values:   o.Values,
+			Version:  o.Version,
+			ChangeID: o.ChangeID,
+		})
+	}
+	return manufacturing
+}
+
+func scanInspection(row rowScanner) (*domain.Inspection, error) {
+	inspection := &domain.Inspection{}
+	var finalResult, machineID sql.NullString
+	var samplingJSON, manufacturingJSON []byte
+
+	err := row.Scan(
+		&inspection.ID,
+		&inspection.ProductionOrderID,
+		&inspection.LotNumber,
+		&inspection.InspectorID,
+		&inspection.Status,
+		&finalResult,
+		&inspection.CreatedAt,
+		&inspection.UpdatedAt,
+		&inspection.PlanID,
+		&inspection.Operation,
+		&inspection.LotSize,
+		&samplingJSON,
+		&inspection.NonconformingCount,
+		&inspection.FailureReason,
+		&machineID,
+		&manufacturingJSON,
+	)
+	if err != nil {
+		return nil, err
+	}
+	inspection.MachineID = machineID.String
+
+	if finalResult.Valid {
+		inspection.FinalResult = domain.InspectionResult(finalResult.String)
+	}
+
+	if len(samplingJSON) > 0 {
+		var record samplingPlanRecord
+		if err := json.Unmarshal(samplingJSON, &record); err != nil {
+			return nil, err
+		}
+		inspection.Sampling = &domain.SamplingPlan{
+			AQL:        record.AQL,
+			Level:      domain.InspectionLevel(record.Level),
+			LotSize:    record.LotSize,
+			CodeLetter: record.CodeLetter,
+			SampleSize: record.SampleSize,
+			Accept:     record.Accept,
+			Reject:     record.Reject,
+		}
+	}
+
+	if len(manufacturingJSON) > 0 {
+		var record manufacturingRecordRecord
+		if err := json.Unmarshal(manufacturingJSON, &record); err != nil {
+			return nil, err
+		}
+		inspection.Manufacturing = unmarshalManufacturingRecord(record)
+	}
+
+	return inspection, nil
+}
+
+// scanMeasurementResult は測定結果を読み込む。上下限の無い従来の行は目標値 ± 公差を規格とする
+func scanMeasurementResult(row rowScanner) (domain.MeasurementResult, error) {
+	var result domain.MeasurementResult
+	var lower, upper, mcSize, actualSize sql.NullFloat64
+	var characteristicType, materialCondition, feature string
+	var measuredAt sql.NullTime
+	err := row.Scan(
+		&result.ParameterName,
+		&result.MeasuredValue,
+		&result.TargetValue,
+		&result.Tolerance,
+		&result.Unit,
+		&result.Pass,
+		&characteristicType,
+		&lower,
+		&upper,
+		&materialCondition,
+		&mcSize,
+		&actualSize,
+		&feature,
+		&result.SampleNumber,
+		&result.InstrumentID,
+		&measuredAt,
+	)
+	if err != nil {
+		return result, err
+	}
+	if measuredAt.Valid {
+		result.MeasuredAt = measuredAt.Time
+	}
+
+	if !lower.Valid && !upper.Valid {
+		result.Characteristic = domain.LegacyCharacteristic(result.TargetValue, result.Tolerance)
+		return result, nil
+	}
+	result.Characteristic = domain.Characteristic{
+		Type:                  domain.CharacteristicType(characteristicType),
+		LowerLimit:            nullFloat64(lower),
+		UpperLimit:            nullFloat64(upper),
+		MaterialCondition:     domain.MaterialCondition(materialCondition),
+		MaterialConditionSize: nullFloat64(mcSize),
+		ActualSize:            nullFloat64(actualSize),
+		Feature:               domain.FeatureOfSize(feature),
+	}
+	return result, nil
+}
+
+func nullFloat64(v sql.NullFloat64) *float64 {
+	if !v.Valid {
+		return nil
+	}
+	return &v.Float64
+}
+
+func nullString(s string) sql.NullString {
+	return sql.NullString{String: s, Valid: s != ""}
+}
+
+func nullTime(t time.Time) sql.NullTime {
+	return sql.NullTime{Time: t, Valid: !t.IsZero()}
+}
@@ -0,0 +1,115 @@
+package infrastructure
+
+import (
+	"context"
+	"database/sql"
+	"goNexttask/internal/quality/domain"
+)
+
+const controlLimitsColumns = `parameter_name, part_id, chart_type, subgroup_size, center_line, ucl, lcl,
+	dispersion_center_line, dispersion_ucl, dispersion_lcl, baseline_from, baseline_to, baseline_points, frozen_by, frozen_at`
+
+type PostgresControlLimitsRepository struct {
+	db *sql.DB
+}
+
+func NewPostgresControlLimitsRepository(db *sql.DB) *PostgresControlLimitsRepository {
+	return &PostgresControlLimitsRepository{
+		db: db,
+	}
+}
+
+func (r *PostgresControlLimitsRepository) Save(ctx context.Context, limits *domain.ControlLimits) error {
+	query := `
+		INSERT INTO spc_control_limits (` + controlLimitsColumns + `)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15)
+		ON CONFLICT (parameter_name, part_id, chart_type) DO UPDATE
+		SET subgroup_size = EXCLUDED.subgroup_size, center_line = EXCLUDED.center_line,
+			ucl = EXCLUDED.ucl, lcl = EXCLUDED.lcl,
+			dispersion_center_line = EXCLUDED.dispersion_center_line,
+			dispersion_ucl = EXCLUDED.dispersion_ucl, dispersion_lcl = EXCLUDED.dispersion_lcl,
+			baseline_from = EXCLUDED.baseline_from, baseline_to = EXCLUDED.baseline_to,
+			baseline_points = EXCLUDED.baseline_points, frozen_by = EXCLUDED.frozen_by, frozen_at = EXCLUDED.frozen_at
+	`
+
+	_, err := r.db.ExecContext(ctx, query,
+		limits.ParameterName,
+		limits.PartID,
+		limits.ChartType,
+		limits.SubgroupSize,
+		limits.Center,
+		limits.UCL,
+		limits.LCL,
+		limits.DispersionCenter,
+		limits.DispersionUCL,
+		limits.DispersionLCL,
+		limits.BaselineFrom,
+		limits.BaselineTo,
+		limits.BaselinePoints,
+		limits.FrozenBy,
+		limits.FrozenAt,
+	)
+	return err
+}
+
+func (r *PostgresControlLimitsRepository) Find(ctx context.Context, parameterName, partID string, chartType domain.ChartType) (*domain.ControlLimits, error) {
+	query := `SELECT ` + controlLimitsColumns + ` FROM spc_control_limits
+		WHERE parameter_name = $1 AND part_id = $2 AND chart_type = $3`
+
+	limits, err := scanControlLimits(r.db.QueryRowContext(ctx, query, parameterName, partID, chartType))
+	if err == sql.ErrNoRows {
+		return nil, domain.ErrControlLimitsNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return limits, nil
+}
+
+func (r *PostgresControlLimitsRepository) FindByParameter(ctx context.Context, parameterName string) ([]*domain.ControlLimits, error) {
+	query := `SELECT ` + controlLimitsColumns + ` FROM spc_control_limits
+		WHERE parameter_name = $1 ORDER BY part_id, chart_type`
+
+	rows, err := r.db.QueryContext(ctx, query, parameterName)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var result []*domain.ControlLimits
+	for rows.Next() {
+		limits, err := scanControlLimits(rows)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, limits)
+	}
+
+	return result, rows.Err()
+}
+
+func scanControlLimits(row rowScanner) (*domain.ControlLimits, error) {
+	var limits domain.ControlLimits
+	err := row.Scan(
+		&limits.ParameterName,
+		&limits.PartID,
+		&limits.ChartType,
+		&limits.SubgroupSize,
+		&limits.Center,
+		&limits.UCL,
+		&limits.LCL,
+		&limits.DispersionCenter,
+		&limits.DispersionUCL,
+		&limits.DispersionLCL,
+		&limits.BaselineFrom,
+		&limits.BaselineTo,
+		&limits.BaselinePoints,
+		&limits.FrozenBy,
+		&limits.FrozenAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &limits, nil
+}
@@ -0,0 +1,27 @@
+package infrastructure
+
+import (
+	"context"
+	prodApp "goNexttask/internal/production/application"
+)
+
+// ProductionOrderFeedback は承認した不適合処置の数量を製造オーダーのユースケースへ渡す
+type ProductionOrderFeedback struct {
+	useCase *prodApp.ProductionUseCase
+}
+
+func NewProductionOrderFeedback(useCase *prodApp.ProductionUseCase) *ProductionOrderFeedback {
+	return &ProductionOrderFeedback{
+		useCase: useCase,
+	}
+}
+
+func (f *ProductionOrderFeedback) RecordDisposition(ctx context.Context, dispositionID, productionOrderID string, good, rework, scrap int) error {
+	return f.useCase.RecordQualityDisposition(ctx, prodApp.RecordQualityDispositionInput{
+		OrderID:       productionOrderID,
+		DispositionID: dispositionID,
+		Good:          good,
+		Rework:        rework,
+		Scrap:         scrap,
+	})
+}
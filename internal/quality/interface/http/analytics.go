@@ -0,0 +1,199 @@
+package http
+
+import (
+	"encoding/json"
+	"goNexttask/internal/quality/application"
+	"net/http"
+)
+
+type AnalyticsFilterResponse struct {
+	PartID     string `json:"partId,omitempty"`
+	MachineID  string `json:"machineId,omitempty"`
+	ProgramID  string `json:"programId,omitempty"`
+	OperatorID string `json:"operatorId,omitempty"`
+	From       string `json:"from,omitempty"`
+	To         string `json:"to,omitempty"`
+}
+
+type ParetoEntryResponse struct {
+	Key                  string  `json:"key"`
+	Count                int     `json:"count"`
+	Percentage           float64 `json:"percentage"`
+	CumulativePercentage float64 `json:"cumulativePercentage"`
+}
+
+// DefectTrendResponse の weekStart は週の月曜（UTC）、counts は軸の値ごとの不具合の件数
+type DefectTrendResponse struct {
+	WeekStart         string         `json:"weekStart"`
+	Inspections       int            `json:"inspections"`
+	FailedInspections int            `json:"failedInspections"`
+	Defects           int            `json:"defects"`
+	Counts            map[string]int `json:"counts"`
+}
+
+type ParetoResponse struct {
+	Filter            AnalyticsFilterResponse `json:"filter"`
+	GroupBy           string                  `json:"groupBy"`
+	Inspections       int                     `json:"inspections"`
+	FailedInspections int                     `json:"failedInspections"`
+	TotalDefects      int                     `json:"totalDefects"`
+	Entries           []ParetoEntryResponse   `json:"entries"`
+	Trend             []DefectTrendResponse   `json:"trend"`
+}
+
+type YieldResponse struct {
+	Key              string  `json:"key,omitempty"`
+	FirstInspections int     `json:"firstInspections"`
+	FirstPassed      int     `json:"firstPassed"`
+	FirstPassYield   float64 `json:"firstPassYield"`
+}
+
+type YieldTrendResponse struct {
+	WeekStart        string  `json:"weekStart"`
+	FirstInspections int     `json:"firstInspections"`
+	FirstPassed      int     `json:"firstPassed"`
+	FirstPassYield   float64 `json:"firstPassYield"`
+}
+
+type FirstPassYieldResponse struct {
+	Filter        AnalyticsFilterResponse `json:"filter"`
+	GroupBy       string                  `json:"groupBy,omitempty"`
+	Overall       YieldResponse           `json:"overall"`
+	Groups        []YieldResponse         `json:"groups"`
+	Trend         []YieldTrendResponse    `json:"trend"`
+	Reinspections int                     `json:"reinspections"`
+}
+
+// GetDefectPareto は部品・機械・プログラム・作業者・期間で絞り込んだ検査の不具合のパレート図と週ごとの推移を返す。
+// groupBy は defect（既定）・part・machine・program・operator
+func (h *QualityHandler) GetDefectPareto(w http.ResponseWriter, r *http.Request) {
+	input, ok := parseAnalyticsInput(w, r)
+	if !ok {
+		return
+	}
+
+	output, err := h.useCase.GetDefectPareto(r.Context(), input)
+	if err != nil {
+		http.Error(w, err.Error(), errorStatus(err))
+		return
+	}
+
+	response := ParetoResponse{
+		Filter:            toAnalyticsFilterResponse(input),
+		GroupBy:           output.GroupBy,
+		Inspections:       output.Inspections,
+		FailedInspections: output.FailedInspections,
+		TotalDefects:      output.TotalDefects,
+		Entries:           make([]ParetoEntryResponse, len(output.Entries)),
+		Trend:             make([]DefectTrendResponse, len(output.Trend)),
+	}
+	for i, e := range output.Entries {
+		response.Entries[i] = ParetoEntryResponse{
+			Key:                  e.Key,
+			Count:                e.Count,
+			Percentage:           e.Percentage,
+			CumulativePercentage: e.CumulativePercentage,
+		}
+	}
+	for i, p := range output.Trend {
+		response.Trend[i] = DefectTrendResponse{
+			WeekStart:         p.WeekStart.Format("2006-01-02"),
+			Inspections:       p.Inspections,
+			FailedInspections: p.FailedInspections,
+			Defects:           p.Defects,
+			Counts:            p.Counts,
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// GetFirstPassYield はロット・工程ごとの最初の検査の合格率を全体・軸の値ごと・週ごとに返す。
+// groupBy は part・machine・program・operator（省略時は全体と週ごとのみ）
+func (h *QualityHandler) GetFirstPassYield(w http.ResponseWriter, r *http.Request) {
+	input, ok := parseAnalyticsInput(w, r)
+	if !ok {
+		return
+	}
+
+	output, err := h.useCase.GetFirstPassYield(r.Context(), input)
+	if err != nil {
+		http.Error(w, err.Error(), errorStatus(err))
+		return
+	}
+
+	response := FirstPassYieldResponse{
+		Filter:        toAnalyticsFilterResponse(input),
+		GroupBy:       output.GroupBy,
+		Overall:       toYieldResponse(output.Overall),
+		Groups:        make([]YieldResponse, len(output.Groups)),
+		Trend:         make([]YieldTrendResponse, len(output.Trend)),
+		Reinspections: output.Reinspections,
+	}
+	for i, g := range output.Groups {
+		response.Groups[i] = toYieldResponse(*g)
+	}
+	for i, p := range output.Trend {
+		response.Trend[i] = YieldTrendResponse{
+			WeekStart:        p.WeekStart.Format("2006-01-02"),
+			FirstInspections: p.FirstInspections,
+			FirstPassed:      p.FirstPassed,
+			FirstPassYield:   p.FirstPassYield,
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// parseAnalyticsInput はクエリパラメーターを読む。不正な場合は 400 を返して false
+func parseAnalyticsInput(w http.ResponseWriter, r *http.Request) (application.AnalyticsInput, bool) {
+	query := r.URL.Query()
+
+	from, err := parseOptionalTime(r, "from")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return application.AnalyticsInput{}, false
+	}
+	to, err := parseOptionalTime(r, "to")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return application.AnalyticsInput{}, false
+	}
+
+	return application.AnalyticsInput{
+		PartID:     query.Get("part"),
+		MachineID:  query.Get("machine"),
+		ProgramID:  query.Get("program"),
+		OperatorID: query.Get("operator"),
+		From:       from,
+		To:         to,
+		GroupBy:    query.Get("groupBy"),
+	}, true
+}
+
+func toAnalyticsFilterResponse(input application.AnalyticsInput) AnalyticsFilterResponse {
+	response := AnalyticsFilterResponse{
+		PartID:     input.PartID,
+		MachineID:  input.MachineID,
+		ProgramID:  input.ProgramID,
+		OperatorID: input.OperatorID,
+	}
+	if input.From != nil {
+		response.From = input.From.Format("2006-01-02T15:04:05Z")
+	}
+	if input.To != nil {
+		response.To = input.To.Format("2006-01-02T15:04:05Z")
+	}
+	return response
+}
+
+func toYieldResponse(o application.YieldOutput) YieldResponse {
+	return YieldResponse{
+		Key:              o.Key,
+		FirstInspections: o.FirstInspections,
+		FirstPassed:      o.FirstPassed,
+		FirstPassYield:   o.FirstPassYield,
+	}
+}
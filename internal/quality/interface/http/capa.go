@@ -0,0 +1,403 @@
+package http
+
+import (
+	"encoding/json"
+	"goNexttask/internal/quality/application"
+	"goNexttask/internal/quality/domain"
+	"goNexttask/pkg/auth"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// capaEditorRoles は CAPA の起票・根本原因・処置・有効性の確認を記録できるロール
+var capaEditorRoles = []string{"admin", "quality"}
+
+// OpenCAPARequest の sources は 1 件以上（type は nonconformance または spc_alert）
+type OpenCAPARequest struct {
+	Title       string              `json:"title"`
+	Description string              `json:"description"`
+	Sources     []CAPASourceRequest `json:"sources"`
+}
+
+type CAPASourceRequest struct {
+	Type      string `json:"type"`
+	Reference string `json:"reference"`
+}
+
+// AnalyzeRootCauseRequest の category は man・machine・method・material・measurement・environment
+type AnalyzeRootCauseRequest struct {
+	Whys      []string `json:"whys"`
+	Category  string   `json:"category"`
+	RootCause string   `json:"rootCause,omitempty"`
+}
+
+// AddCAPAActionRequest の type は corrective または preventive、dueDate は RFC3339
+type AddCAPAActionRequest struct {
+	Type        string    `json:"type"`
+	Description string    `json:"description"`
+	Owner       string    `json:"owner"`
+	DueDate     time.Time `json:"dueDate"`
+}
+
+type VerifyEffectivenessRequest struct {
+	Effective bool   `json:"effective"`
+	Evidence  string `json:"evidence"`
+}
+
+type CAPAResponse struct {
+	ID            string                       `json:"id"`
+	Title         string                       `json:"title"`
+	Description   string                       `json:"description"`
+	Sources       []CAPASourceResponse         `json:"sources"`
+	DefectCodes   []string                     `json:"defectCodes"`
+	Status        string                       `json:"status"`
+	RootCause     *RootCauseResponse           `json:"rootCause,omitempty"`
+	Actions       []CAPAActionResponse         `json:"actions"`
+	Verifications []EffectivenessCheckResponse `json:"verifications"`
+	OpenedBy      string                       `json:"openedBy"`
+	CreatedAt     string                       `json:"createdAt"`
+	UpdatedAt     string                       `json:"updatedAt"`
+}
+
+type CAPASourceResponse struct {
+	Type      string `json:"type"`
+	Reference string `json:"reference"`
+}
+
+type RootCauseResponse struct {
+	Whys       []string `json:"whys"`
+	Category   string   `json:"category"`
+	RootCause  string   `json:"rootCause"`
+	AnalyzedBy string   `json:"analyzedBy"`
+	AnalyzedAt string   `json:"analyzedAt"`
+}
+
+type CAPAActionResponse struct {
+	Number      int    `json:"number"`
+	Type        string `json:"type"`
+	Description string `json:"description"`
+	Owner       string `json:"owner"`
+	DueDate     string `json:"dueDate"`
+	CompletedBy string `json:"completedBy,omitempty"`
+	CompletedAt string `json:"completedAt,omitempty"`
+	Overdue     bool   `json:"overdue"`
+}
+
+type EffectivenessCheckResponse struct {
+	Effective  bool   `json:"effective"`
+	Evidence   string `json:"evidence"`
+	VerifiedBy string `json:"verifiedBy"`
+	VerifiedAt string `json:"verifiedAt"`
+}
+
+type CAPASuggestionResponse struct {
+	DefectCode        string   `json:"defectCode"`
+	ParameterName     string   `json:"parameterName"`
+	Occurrences       int      `json:"occurrences"`
+	NonconformanceIDs []string `json:"nonconformanceIds"`
+	LotNumbers        []string `json:"lotNumbers"`
+}
+
+// OpenCAPA は NCR・管理図のアラートを紐付けて CAPA を起票する（admin / quality）
+func (h *QualityHandler) OpenCAPA(w http.ResponseWriter, r *http.Request) {
+	claims, ok := auth.GetUserFromContext(r.Context())
+	if !ok || !auth.HasAnyRole(r.Context(), capaEditorRoles...) {
+		http.Error(w, "Only quality engineers can open CAPAs", http.StatusForbidden)
+		return
+	}
+
+	var req OpenCAPARequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	sources := make([]application.CAPASourceInput, len(req.Sources))
+	for i, s := range req.Sources {
+		sources[i] = application.CAPASourceInput{
+			Type:      s.Type,
+			Reference: s.Reference,
+		}
+	}
+
+	output, err := h.useCase.OpenCAPA(r.Context(), application.OpenCAPAInput{
+		Title:       req.Title,
+		Description: req.Description,
+		Sources:     sources,
+		OpenedBy:    claims.Email,
+	})
+	if err != nil {
+		http.Error(w, err.Error(), errorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(toCAPAResponse(output))
+}
+
+// ListCAPAs は CAPA を起票の新しい順に返す。status で絞り込み、overdue=true で期日超過の処置がある CAPA のみ
+func (h *QualityHandler) ListCAPAs(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	outputs, err := h.useCase.ListCAPAs(r.Context(), query.Get("status"), query.Get("overdue") == "true")
+	if err != nil {
+		http.Error(w, err.Error(), errorStatus(err))
+		return
+	}
+
+	response := make([]CAPAResponse, len(outputs))
+	for i, output := range outputs {
+		response[i] = toCAPAResponse(output)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+func (h *QualityHandler) GetCAPA(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+
+	output, err := h.useCase.GetCAPA(r.Context(), vars["id"])
+	if err != nil {
+		http.Error(w, err.Error(), errorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(toCAPAResponse(output))
+}
+
+// AnalyzeRootCause はなぜなぜ分析と特性要因図の分類を記録する（admin / quality）
+func (h *QualityHandler) AnalyzeRootCause(w http.ResponseWriter, r *http.Request) {
+	claims, ok := auth.GetUserFromContext(r.Context())
+	if !ok || !auth.HasAnyRole(r.Context(), capaEditorRoles...) {
+		http.Error(w, "Only quality engineers can record root causes", http.StatusForbidden)
+		return
+	}
+
+	vars := mux.Vars(r)
+
+	var req AnalyzeRootCauseRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	output, err := h.useCase.AnalyzeRootCause(r.Context(), application.AnalyzeRootCauseInput{
+		CAPAID:     vars["id"],
+		Whys:       req.Whys,
+		Category:   req.Category,
+		RootCause:  req.RootCause,
+		AnalyzedBy: claims.Email,
+	})
+	if err != nil {
+		http.Error(w, err.Error(), errorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(toCAPAResponse(output))
+}
+
+// AddCAPAAction は担当者と期日のある処置を追加する（admin / quality）
+func (h *QualityHandler) AddCAPAAction(w http.ResponseWriter, r *http.Request) {
+	if !auth.HasAnyRole(r.Context(), capaEditorRoles...) {
+		http.Error(w, "Only quality engineers can plan CAPA actions", http.StatusForbidden)
+		return
+	}
+
+	vars := mux.Vars(r)
+
+	var req AddCAPAActionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	output, err := h.useCase.AddCAPAAction(r.Context(), application.AddCAPAActionInput{
+		CAPAID:      vars["id"],
+		Type:        req.Type,
+		Description: req.Description,
+		Owner:       req.Owner,
+		DueDate:     req.DueDate,
+	})
+	if err != nil {
+		http.Error(w, err.Error(), errorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(toCAPAResponse(output))
+}
+
+// CompleteCAPAAction は処置の完了を記録する。完了者はログインユーザー
+func (h *QualityHandler) CompleteCAPAAction(w http.ResponseWriter, r *http.Request) {
+	claims, ok := auth.GetUserFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	vars := mux.Vars(r)
+	number, err := strconv.Atoi(vars["number"])
+	if err != nil {
+		http.Error(w, "Invalid action number", http.StatusBadRequest)
+		return
+	}
+
+	output, err := h.useCase.CompleteCAPAAction(r.Context(), application.CompleteCAPAActionInput{
+		CAPAID:       vars["id"],
+		ActionNumber: number,
+		CompletedBy:  claims.Email,
+	})
+	if err != nil {
+		http.Error(w, err.Error(), errorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(toCAPAResponse(output))
+}
+
+// VerifyEffectiveness は処置の有効性の確認を記録する（admin / quality）。有効なら CAPA を閉じる
+func (h *QualityHandler) VerifyEffectiveness(w http.ResponseWriter, r *http.Request) {
+	claims, ok := auth.GetUserFromContext(r.Context())
+	if !ok || !auth.HasAnyRole(r.Context(), capaEditorRoles...) {
+		http.Error(w, "Only quality engineers can verify effectiveness", http.StatusForbidden)
+		return
+	}
+
+	vars := mux.Vars(r)
+
+	var req VerifyEffectivenessRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	output, err := h.useCase.VerifyEffectiveness(r.Context(), application.VerifyEffectivenessInput{
+		CAPAID:     vars["id"],
+		Effective:  req.Effective,
+		Evidence:   req.Evidence,
+		VerifiedBy: claims.Email,
+	})
+	if err != nil {
+		http.Error(w, err.Error(), errorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(toCAPAResponse(output))
+}
+
+// SuggestCAPAs は CAPA の無いまま再発している不具合を返す（days 既定 90、min 既定 3）
+func (h *QualityHandler) SuggestCAPAs(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	days, err := parseOptionalInt(query.Get("days"))
+	if err != nil {
+		http.Error(w, "Invalid days parameter", http.StatusBadRequest)
+		return
+	}
+	minOccurrences, err := parseOptionalInt(query.Get("min"))
+	if err != nil {
+		http.Error(w, "Invalid min parameter", http.StatusBadRequest)
+		return
+	}
+
+	suggestions, err := h.useCase.SuggestCAPAs(r.Context(), days, minOccurrences)
+	if err != nil {
+		http.Error(w, err.Error(), errorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(toCAPASuggestionResponses(suggestions))
+}
+
+// parseOptionalInt は未指定の場合 0 を返す
+func parseOptionalInt(v string) (int, error) {
+	if v == "" {
+		return 0, nil
+	}
+	return strconv.Atoi(v)
+}
+
+func toCAPAResponse(capa *application.CAPAOutput) CAPAResponse {
+	sources := make([]CAPASourceResponse, len(capa.Sources))
+	for i, s := range capa.Sources {
+		sources[i] = CAPASourceResponse{
+			Type:      s.Type,
+			Reference: s.Reference,
+		}
+	}
+	actions := make([]CAPAActionResponse, len(capa.Actions))
+	for i, a := range capa.Actions {
+		actions[i] = CAPAActionResponse{
+			Number:      a.Number,
+			Type:        a.Type,
+			Description: a.Description,
+			Owner:       a.Owner,
+			DueDate:     a.DueDate.Format("2006-01-02T15:04:05Z"),
+			CompletedBy: a.CompletedBy,
+			CompletedAt: formatOptionalTime(a.CompletedAt),
+			Overdue:     a.Overdue,
+		}
+	}
+	verifications := make([]EffectivenessCheckResponse, len(capa.Verifications))
+	for i, v := range capa.Verifications {
+		verifications[i] = EffectivenessCheckResponse{
+			Effective:  v.Effective,
+			Evidence:   v.Evidence,
+			VerifiedBy: v.VerifiedBy,
+			VerifiedAt: v.VerifiedAt.Format("2006-01-02T15:04:05Z"),
+		}
+	}
+
+	response := CAPAResponse{
+		ID:            capa.ID,
+		Title:         capa.Title,
+		Description:   capa.Description,
+		Sources:       sources,
+		DefectCodes:   capa.DefectCodes,
+		Status:        capa.Status,
+		Actions:       actions,
+		Verifications: verifications,
+		OpenedBy:      capa.OpenedBy,
+		CreatedAt:     capa.CreatedAt.Format("2006-01-02T15:04:05Z"),
+		UpdatedAt:     capa.UpdatedAt.Format("2006-01-02T15:04:05Z"),
+	}
+	if capa.RootCause != nil {
+		response.RootCause = &RootCauseResponse{
+			Whys:       capa.RootCause.Whys,
+			Category:   capa.RootCause.Category,
+			RootCause:  capa.RootCause.RootCause,
+			AnalyzedBy: capa.RootCause.AnalyzedBy,
+			AnalyzedAt: capa.RootCause.AnalyzedAt.Format("2006-01-02T15:04:05Z"),
+		}
+	}
+	return response
+}
+
+func toCAPASuggestionResponses(suggestions []domain.CAPASuggestion) []CAPASuggestionResponse {
+	response := make([]CAPASuggestionResponse, len(suggestions))
+	for i, s := range suggestions {
+		ids := make([]string, len(s.NonconformanceIDs))
+		for j, id := range s.NonconformanceIDs {
+			ids[j] = string(id)
+		}
+		response[i] = CAPASuggestionResponse{
+			DefectCode:        s.DefectCode,
+			ParameterName:     s.ParameterName,
+			Occurrences:       s.Occurrences,
+			NonconformanceIDs: ids,
+			LotNumbers:        s.LotNumbers,
+		}
+	}
+	return response
+}
@@ -0,0 +1,140 @@
+package http
+
+import (
+	"encoding/json"
+	"goNexttask/internal/quality/application"
+	"net/http"
+	"strconv"
+)
+
+type CapabilityFilterResponse struct {
+	LotNumber     string `json:"lotNumber,omitempty"`
+	PartID        string `json:"partId,omitempty"`
+	ParameterName string `json:"parameterName,omitempty"`
+	From          string `json:"from,omitempty"`
+	To            string `json:"to,omitempty"`
+	MinSamples    int    `json:"minSamples,omitempty"`
+}
+
+type NormalityResponse struct {
+	Method    string  `json:"method"`
+	Statistic float64 `json:"statistic"`
+	PValue    float64 `json:"pValue"`
+	Normal    bool    `json:"normal"`
+}
+
+type CapabilityStudyResponse struct {
+	ParameterName string             `json:"parameterName"`
+	Unit          string             `json:"unit"`
+	SampleSize    int                `json:"sampleSize"`
+	SubgroupCount int                `json:"subgroupCount"`
+	Mean          float64            `json:"mean"`
+	SigmaWithin   float64            `json:"sigmaWithin"`
+	SigmaOverall  float64            `json:"sigmaOverall"`
+	SigmaMethod   string             `json:"sigmaMethod,omitempty"`
+	Target        float64            `json:"target"`
+	LSL           *float64           `json:"lsl"`
+	USL           *float64           `json:"usl"`
+	Cp            *float64           `json:"cp"`
+	Cpk           *float64           `json:"cpk"`
+	Pp            *float64           `json:"pp"`
+	Ppk           *float64           `json:"ppk"`
+	Normality     *NormalityResponse `json:"normality"`
+	Warnings      []string           `json:"warnings"`
+}
+
+type CapabilityResponse struct {
+	Filter  CapabilityFilterResponse  `json:"filter"`
+	Studies []CapabilityStudyResponse `json:"studies"`
+}
+
+// GetCapability はロット・部品・期間で絞り込んだ測定結果の工程能力を返す
+func (h *QualityHandler) GetCapability(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	from, err := parseOptionalTime(r, "from")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	to, err := parseOptionalTime(r, "to")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	minSamples := 0
+	if v := query.Get("minSamples"); v != "" {
+		if minSamples, err = strconv.Atoi(v); err != nil || minSamples < 0 {
+			http.Error(w, "Invalid minSamples parameter", http.StatusBadRequest)
+			return
+		}
+	}
+
+	input := application.CapabilityInput{
+		LotNumber:     query.Get("lot"),
+		PartID:        query.Get("part"),
+		ParameterName: query.Get("parameter"),
+		From:          from,
+		To:            to,
+		MinSamples:    minSamples,
+	}
+
+	outputs, err := h.useCase.GetCapability(r.Context(), input)
+	if err != nil {
+		http.Error(w, err.Error(), errorStatus(err))
+		return
+	}
+
+	response := CapabilityResponse{
+		Filter: CapabilityFilterResponse{
+			LotNumber:     input.LotNumber,
+			PartID:        input.PartID,
+			ParameterName: input.ParameterName,
+			MinSamples:    input.MinSamples,
+		},
+		Studies: make([]CapabilityStudyResponse, len(outputs)),
+	}
+	if from != nil {
+		response.Filter.From = from.Format("2006-01-02T15:04:05Z")
+	}
+	if to != nil {
+		response.Filter.To = to.Format("2006-01-02T15:04:05Z")
+	}
+
+	for i, o := range outputs {
+		study := CapabilityStudyResponse{
+			ParameterName: o.ParameterName,
+			Unit:          o.Unit,
+			SampleSize:    o.SampleSize,
+			SubgroupCount: o.SubgroupCount,
+			Mean:          o.Mean,
+			SigmaWithin:   o.SigmaWithin,
+			SigmaOverall:  o.SigmaOverall,
+			SigmaMethod:   o.SigmaMethod,
+			Target:        o.Target,
+			LSL:           o.LSL,
+			USL:           o.USL,
+			Cp:            o.Cp,
+			Cpk:           o.Cpk,
+			Pp:            o.Pp,
+			Ppk:           o.Ppk,
+			Warnings:      o.Warnings,
+		}
+		if study.Warnings == nil {
+			study.Warnings = []string{}
+		}
+		if o.Normality != nil {
+			study.Normality = &NormalityResponse{
+				Method:    o.Normality.Method,
+				Statistic: o.Normality.Statistic,
+				PValue:    o.Normality.PValue,
+				Normal:    o.Normality.Normal,
+			}
+		}
+		response.Studies[i] = study
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
@@ -0,0 +1,91 @@
+package http
+
+import (
+	"encoding/json"
+	"goNexttask/internal/quality/application"
+	"net/http"
+
+	"github.com/gorilla/mux"
+)
+
+type GenealogyResponse struct {
+	LotNumber    string                 `json:"lotNumber"`
+	Direction    string                 `json:"direction"`
+	Depth        int                    `json:"depth"`
+	Root         *GenealogyNodeResponse `json:"root"`
+	MaterialLots []string               `json:"materialLots"`
+	WIPLots      []string               `json:"wipLots"`
+	FinishedLots []string               `json:"finishedLots"`
+}
+
+// GenealogyNodeResponse の type は material_lot・wip_lot・finished_lot・production_order・machine・inspection
+type GenealogyNodeResponse struct {
+	Type        string                   `json:"type"`
+	ID          string                   `json:"id"`
+	ProductType string                   `json:"productType,omitempty"`
+	Quantity    int                      `json:"quantity,omitempty"`
+	PartID      string                   `json:"partId,omitempty"`
+	OrderNumber string                   `json:"orderNumber,omitempty"`
+	Status      string                   `json:"status,omitempty"`
+	Result      string                   `json:"result,omitempty"`
+	MachineID   string                   `json:"machineId,omitempty"`
+	Children    []*GenealogyNodeResponse `json:"children,omitempty"`
+}
+
+// GetGenealogy はロットの系譜を返す。direction は forward（使用先）・backward（使用した材料、既定）、
+// depth はたどる製造オーダーの段数
+func (h *QualityHandler) GetGenealogy(w http.ResponseWriter, r *http.Request) {
+	lotNumber := mux.Vars(r)["lot"]
+	query := r.URL.Query()
+
+	depth, err := parseOptionalInt(query.Get("depth"))
+	if err != nil {
+		http.Error(w, "Invalid depth parameter", http.StatusBadRequest)
+		return
+	}
+
+	output, err := h.useCase.GetGenealogy(r.Context(), lotNumber, query.Get("direction"), depth)
+	if err != nil {
+		http.Error(w, err.Error(), errorStatus(err))
+		return
+	}
+
+	response := GenealogyResponse{
+		LotNumber:    output.LotNumber,
+		Direction:    output.Direction,
+		Depth:        output.Depth,
+		Root:         toGenealogyNodeResponse(output.Root),
+		MaterialLots: nonNilStrings(output.MaterialLots),
+		WIPLots:      nonNilStrings(output.WIPLots),
+		FinishedLots: nonNilStrings(output.FinishedLots),
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+func toGenealogyNodeResponse(node *application.GenealogyNodeOutput) *GenealogyNodeResponse {
+	response := &GenealogyNodeResponse{
+		Type:        node.Type,
+		ID:          node.ID,
+		ProductType: node.ProductType,
+		Quantity:    node.Quantity,
+		PartID:      node.PartID,
+		OrderNumber: node.OrderNumber,
+		Status:      node.Status,
+		Result:      node.Result,
+		MachineID:   node.MachineID,
+	}
+	for _, child := range node.Children {
+		response.Children = append(response.Children, toGenealogyNodeResponse(child))
+	}
+	return response
+}
+
+// nonNilStrings は該当の無い一覧を null ではなく [] で返す
+func nonNilStrings(s []string) []string {
+	if s == nil {
+		return []string{}
+	}
+	return s
+}
@@ -2,8 +2,11 @@ package http
 
 import (
 	"encoding/json"
+	"errors"
 	"goNexttask/internal/quality/application"
+	"goNexttask/internal/quality/domain"
 	"net/http"
+	"time"
 
 	"github.com/gorilla/mux"
 )
@@ -21,8 +24,34 @@ func NewQualityHandler(useCase *application.QualityUseCase) *QualityHandler {
 func (h *QualityHandler) RegisterRoutes(router *mux.Router) {
 	router.HandleFunc("/quality/inspections", h.CreateInspection).Methods("POST")
 	router.HandleFunc("/quality/inspections/{id}", h.GetInspection).Methods("GET")
+	router.HandleFunc("/quality/inspections/{id}/measurements", h.RecordMeasurements).Methods("POST")
+	router.HandleFunc("/quality/inspections/{id}/complete", h.CompleteInspection).Methods("POST")
+	router.HandleFunc("/quality/inspections/{id}/fail", h.FailInspection).Methods("POST")
 	router.HandleFunc("/quality/traceability", h.GetTraceability).Methods("GET")
+	router.HandleFunc("/quality/genealogy/{lot}", h.GetGenealogy).Methods("GET")
 	router.HandleFunc("/quality/defect-analysis", h.AnalyzeDefects).Methods("GET")
+	router.HandleFunc("/quality/analytics/pareto", h.GetDefectPareto).Methods("GET")
+	router.HandleFunc("/quality/analytics/fpy", h.GetFirstPassYield).Methods("GET")
+	router.HandleFunc("/quality/capability", h.GetCapability).Methods("GET")
+	router.HandleFunc("/quality/spc/{parameter}", h.GetControlChart).Methods("GET")
+	router.HandleFunc("/quality/spc/{parameter}/limits", h.FreezeControlLimits).Methods("PUT")
+	router.HandleFunc("/quality/plans", h.CreateInspectionPlan).Methods("POST")
+	router.HandleFunc("/quality/plans", h.ListInspectionPlans).Methods("GET")
+	router.HandleFunc("/quality/plans/{id}", h.GetInspectionPlan).Methods("GET")
+	router.HandleFunc("/quality/sampling", h.GetSamplingPlan).Methods("GET")
+	router.HandleFunc("/quality/ncrs", h.ListNonconformances).Methods("GET")
+	router.HandleFunc("/quality/ncrs/{id}", h.GetNonconformance).Methods("GET")
+	router.HandleFunc("/quality/ncrs/{id}/disposition", h.ProposeDisposition).Methods("PUT")
+	router.HandleFunc("/quality/ncrs/{id}/approve", h.ApproveDisposition).Methods("POST")
+	router.HandleFunc("/quality/ncrs/{id}/reject", h.RejectDisposition).Methods("POST")
+	router.HandleFunc("/quality/capas", h.OpenCAPA).Methods("POST")
+	router.HandleFunc("/quality/capas", h.ListCAPAs).Methods("GET")
+	router.HandleFunc("/quality/capas/suggestions", h.SuggestCAPAs).Methods("GET")
+	router.HandleFunc("/quality/capas/{id}", h.GetCAPA).Methods("GET")
+	router.HandleFunc("/quality/capas/{id}/root-cause", h.AnalyzeRootCause).Methods("PUT")
+	router.HandleFunc("/quality/capas/{id}/actions", h.AddCAPAAction).Methods("POST")
+	router.HandleFunc("/quality/capas/{id}/actions/{number}/complete", h.CompleteCAPAAction).Methods("POST")
+	router.HandleFunc("/quality/capas/{id}/verification", h.VerifyEffectiveness).Methods("POST")
 }
 
 type CreateInspectionRequest struct {
@@ -30,40 +59,87 @@ type CreateInspectionRequest struct {
 	LotNumber         string                  `json:"lotNumber"`
 	InspectorID       string                  `json:"inspectorId"`
 	Measurements      []MeasurementRequest    `json:"measurements"`
+	// Operation を指定すると部品の検査計画に従って lotSize から抜取方式を決める
+	Operation         string                  `json:"operation,omitempty"`
+	LotSize           int                     `json:"lotSize,omitempty"`
+	// Open が true の場合は検査中（pending）のまま登録し、測定を追加してから完了する
+	Open              bool                    `json:"open,omitempty"`
+	// MachineID は製品を加工した機械。NC のジョブ・工具・オフセットを検査に記録する
+	MachineID string `json:"machineId,omitempty"`
 }
 
+// MeasurementRequest の規格は upperLimit/lowerLimit（片側規格は一方のみ）、
+// upperDeviation/lowerDeviation、tolerance（±）の順に優先する
 type MeasurementRequest struct {
-	ParameterName string  `json:"parameterName"`
-	MeasuredValue float64 `json:"measuredValue"`
-	TargetValue   float64 `json:"targetValue"`
-	Tolerance     float64 `json:"tolerance"`
-	Unit          string  `json:"unit"`
+	ParameterName         string    `json:"parameterName"`
+	MeasuredValue         float64   `json:"measuredValue"`
+	TargetValue           float64   `json:"targetValue"`
+	Tolerance             float64   `json:"tolerance"`
+	Unit                  string    `json:"unit"`
+	CharacteristicType    string    `json:"characteristicType,omitempty"`
+	UpperDeviation        *float64  `json:"upperDeviation,omitempty"`
+	LowerDeviation        *float64  `json:"lowerDeviation,omitempty"`
+	UpperLimit            *float64  `json:"upperLimit,omitempty"`
+	LowerLimit            *float64  `json:"lowerLimit,omitempty"`
+	MaterialCondition     string    `json:"materialCondition,omitempty"`
+	MaterialConditionSize *float64  `json:"materialConditionSize,omitempty"`
+	FeatureOfSize         string    `json:"featureOfSize,omitempty"`
+	ActualSize            *float64  `json:"actualSize,omitempty"`
+	SampleNumber          int       `json:"sampleNumber,omitempty"`
+	InstrumentID          string    `json:"instrumentId,omitempty"`
+	MeasuredAt            time.Time `json:"measuredAt"`
 }
 
 type InspectionResponse struct {
-	ID                string                 `json:"id"`
-	ProductionOrderID string                 `json:"productionOrderId"`
-	LotNumber         string                 `json:"lotNumber"`
-	InspectorID       string                 `json:"inspectorId"`
-	Status            string                 `json:"status"`
-	FinalResult       string                 `json:"finalResult"`
-	Measurements      []MeasurementResponse  `json:"measurements"`
-	CreatedAt         string                 `json:"createdAt"`
+	ID                 string                  `json:"id"`
+	ProductionOrderID  string                  `json:"productionOrderId"`
+	LotNumber          string                  `json:"lotNumber"`
+	InspectorID        string                  `json:"inspectorId"`
+	Status             string                  `json:"status"`
+	FinalResult        string                  `json:"finalResult"`
+	Measurements       []MeasurementResponse   `json:"measurements"`
+	CreatedAt          string                  `json:"createdAt"`
+	SPCViolations      []RuleViolationResponse `json:"spcViolations,omitempty"`
+	PlanID             string                  `json:"planId,omitempty"`
+	Operation          string                  `json:"operation,omitempty"`
+	LotSize            int                     `json:"lotSize,omitempty"`
+	Sampling           *SamplingPlanResponse   `json:"sampling,omitempty"`
+	NonconformingCount int                     `json:"nonconformingCount"`
+	// MissingCharacteristics は検査中の検査で測定が不足している必須特性
+	MissingCharacteristics []string `json:"missingCharacteristics,omitempty"`
+	FailureReason          string   `json:"failureReason,omitempty"`
+	NonconformanceID       string   `json:"nonconformanceId,omitempty"`
+	MachineID              string   `json:"machineId,omitempty"`
+	// Manufacturing は製品を加工したジョブのプログラム・工具・オフセット
+	Manufacturing *ManufacturingRecordResponse `json:"manufacturing,omitempty"`
 }
 
 type MeasurementResponse struct {
-	ParameterName string  `json:"parameterName"`
-	MeasuredValue float64 `json:"measuredValue"`
-	TargetValue   float64 `json:"targetValue"`
-	Tolerance     float64 `json:"tolerance"`
-	Unit          string  `json:"unit"`
-	Pass          bool    `json:"pass"`
+	ParameterName      string   `json:"parameterName"`
+	MeasuredValue      float64  `json:"measuredValue"`
+	TargetValue        float64  `json:"targetValue"`
+	Tolerance          float64  `json:"tolerance"`
+	Unit               string   `json:"unit"`
+	Pass               bool     `json:"pass"`
+	CharacteristicType string   `json:"characteristicType"`
+	LowerLimit         *float64 `json:"lowerLimit"`
+	UpperLimit         *float64 `json:"upperLimit"`
+	MaterialCondition  string   `json:"materialCondition,omitempty"`
+	BonusTolerance     float64  `json:"bonusTolerance,omitempty"`
+	SampleNumber       int      `json:"sampleNumber,omitempty"`
+	InstrumentID       string   `json:"instrumentId,omitempty"`
+	MeasuredAt         string   `json:"measuredAt,omitempty"`
 }
 
+// TraceabilityResponse の ncProgramVersion・machineId・tools はロットを最後に加工したジョブのもの
 type TraceabilityResponse struct {
-	LotNumber   string               `json:"lotNumber"`
-	Inspections []InspectionResponse `json:"inspections"`
-	PassRate    float64              `json:"passRate"`
+	LotNumber        string                        `json:"lotNumber"`
+	Inspections      []InspectionResponse          `json:"inspections"`
+	PassRate         float64                       `json:"passRate"`
+	NCProgramVersion string                        `json:"ncProgramVersion,omitempty"`
+	MachineID        string                        `json:"machineId,omitempty"`
+	Tools            []ToolSnapshotResponse        `json:"tools"`
+	Manufacturing    []ManufacturingRecordResponse `json:"manufacturing"`
 }
 
 type DefectAnalysisResponse struct {
@@ -73,6 +149,8 @@ type DefectAnalysisResponse struct {
 	FailedSamples int            `json:"failedSamples"`
 	PassRate      float64        `json:"passRate"`
 	DefectTypes   map[string]int `json:"defectTypes"`
+	// SuggestedCAPAs は CAPA の無いまま他のロットでも再発している、このロットの不具合
+	SuggestedCAPAs []CAPASuggestionResponse `json:"suggestedCapas,omitempty"`
 }
 
 func (h *QualityHandler) CreateInspection(w http.ResponseWriter, r *http.Request) {
@@ -82,52 +160,25 @@ func (h *QualityHandler) CreateInspection(w http.ResponseWriter, r *http.Request
 		return
 	}
 
-	measurements := make([]application.MeasurementInput, len(req.Measurements))
-	for i, m := range req.Measurements {
-		measurements[i] = application.MeasurementInput{
-			ParameterName: m.ParameterName,
-			MeasuredValue: m.MeasuredValue,
-			TargetValue:   m.TargetValue,
-			Tolerance:     m.Tolerance,
-			Unit:          m.Unit,
-		}
-	}
-
 	input := application.CreateInspectionInput{
 		ProductionOrderID: req.ProductionOrderID,
 		LotNumber:         req.LotNumber,
 		InspectorID:       req.InspectorID,
-		Measurements:      measurements,
+		Measurements:      toMeasurementInputs(req.Measurements),
+		Operation:         req.Operation,
+		LotSize:           req.LotSize,
+		Open:              req.Open,
+		MachineID:         req.MachineID,
 	}
 
 	output, err := h.useCase.CreateInspection(r.Context(), input)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		http.Error(w, err.Error(), errorStatus(err))
 		return
 	}
 
-	measurementResponses := make([]MeasurementResponse, len(output.Measurements))
-	for i, m := range output.Measurements {
-		measurementResponses[i] = MeasurementResponse{
-			ParameterName: m.ParameterName,
-			MeasuredValue: m.MeasuredValue,
-			TargetValue:   m.TargetValue,
-			Tolerance:     m.Tolerance,
-			Unit:          m.Unit,
-			Pass:          m.Pass,
-		}
-	}
-
-	response := InspectionResponse{
-		ID:                output.ID,
-		ProductionOrderID: output.ProductionOrderID,
-		LotNumber:         output.LotNumber,
-		InspectorID:       output.InspectorID,
-		Status:            output.Status,
-		FinalResult:       output.FinalResult,
-		Measurements:      measurementResponses,
-		CreatedAt:         output.CreatedAt.Format("2006-01-02T15:04:05Z"),
-	}
+	response := toInspectionResponse(*output)
+	response.SPCViolations = toRuleViolationResponses(output.SPCViolations)
 
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusCreated)
@@ -144,28 +195,7 @@ func (h *QualityHandler) GetInspection(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	measurementResponses := make([]MeasurementResponse, len(output.Measurements))
-	for i, m := range output.Measurements {
-		measurementResponses[i] = MeasurementResponse{
-			ParameterName: m.ParameterName,
-			MeasuredValue: m.MeasuredValue,
-			TargetValue:   m.TargetValue,
-			Tolerance:     m.Tolerance,
-			Unit:          m.Unit,
-			Pass:          m.Pass,
-		}
-	}
-
-	response := InspectionResponse{
-		ID:                output.ID,
-		ProductionOrderID: output.ProductionOrderID,
-		LotNumber:         output.LotNumber,
-		InspectorID:       output.InspectorID,
-		Status:            output.Status,
-		FinalResult:       output.FinalResult,
-		Measurements:      measurementResponses,
-		CreatedAt:         output.CreatedAt.Format("2006-01-02T15:04:05Z"),
-	}
+	response := toInspectionResponse(*output)
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(response)
@@ -180,40 +210,28 @@ func (h *QualityHandler) GetTraceability(w http.ResponseWriter, r *http.Request)
 
 	output, err := h.useCase.GetTraceability(r.Context(), lotNumber)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		http.Error(w, err.Error(), errorStatus(err))
 		return
 	}
 
 	inspectionResponses := make([]InspectionResponse, len(output.Inspections))
 	for i, insp := range output.Inspections {
-		measurementResponses := make([]MeasurementResponse, len(insp.Measurements))
-		for j, m := range insp.Measurements {
-			measurementResponses[j] = MeasurementResponse{
-				ParameterName: m.ParameterName,
-				MeasuredValue: m.MeasuredValue,
-				TargetValue:   m.TargetValue,
-				Tolerance:     m.Tolerance,
-				Unit:          m.Unit,
-				Pass:          m.Pass,
-			}
-		}
+		inspectionResponses[i] = toInspectionResponse(insp)
+	}
 
-		inspectionResponses[i] = InspectionResponse{
-			ID:                insp.ID,
-			ProductionOrderID: insp.ProductionOrderID,
-			LotNumber:         insp.LotNumber,
-			InspectorID:       insp.InspectorID,
-			Status:            insp.Status,
-			FinalResult:       insp.FinalResult,
-			Measurements:      measurementResponses,
-			CreatedAt:         insp.CreatedAt.Format("2006-01-02T15:04:05Z"),
-		}
+	manufacturing := make([]ManufacturingRecordResponse, len(output.Manufacturing))
+	for i, record := range output.Manufacturing {
+		manufacturing[i] = toManufacturingRecordResponse(record)
 	}
 
 	response := TraceabilityResponse{
-		LotNumber:   output.LotNumber,
-		Inspections: inspectionResponses,
-		PassRate:    output.PassRate,
+		LotNumber:        output.LotNumber,
+		Inspections:      inspectionResponses,
+		PassRate:         output.PassRate,
+		NCProgramVersion: output.NCProgramVersion,
+		MachineID:        output.MachineID,
+		Tools:            toToolSnapshotResponses(output.Tools),
+		Manufacturing:    manufacturing,
 	}
 
 	w.Header().Set("Content-Type", "application/json")
@@ -234,14 +252,147 @@ func (h *QualityHandler) AnalyzeDefects(w http.ResponseWriter, r *http.Request)
 	}
 
 	response := DefectAnalysisResponse{
-		LotNumber:     analysis.LotNumber,
-		TotalSamples:  analysis.TotalSamples,
-		PassedSamples: analysis.PassedSamples,
-		FailedSamples: analysis.FailedSamples,
-		PassRate:      analysis.PassRate,
-		DefectTypes:   analysis.DefectTypes,
+		LotNumber:      analysis.LotNumber,
+		TotalSamples:   analysis.TotalSamples,
+		PassedSamples:  analysis.PassedSamples,
+		FailedSamples:  analysis.FailedSamples,
+		PassRate:       analysis.PassRate,
+		DefectTypes:    analysis.DefectTypes,
+		SuggestedCAPAs: toCAPASuggestionResponses(analysis.SuggestedCAPAs),
 	}
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(response)
-}
\ No newline at end of file
+}
+
+// errorStatus はドメインエラーを HTTP ステータスに変換する
+func errorStatus(err error) int {
+	switch {
+	case errors.Is(err, domain.ErrInspectionNotFound),
+		errors.Is(err, domain.ErrControlLimitsNotFound),
+		errors.Is(err, domain.ErrInspectionPlanNotFound),
+		errors.Is(err, domain.ErrNonconformanceNotFound),
+		errors.Is(err, domain.ErrQualityAlertNotFound),
+		errors.Is(err, domain.ErrCAPANotFound),
+		errors.Is(err, domain.ErrCAPAActionNotFound),
+		errors.Is(err, domain.ErrLotNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, domain.ErrInspectionPlanConflict),
+		errors.Is(err, domain.ErrInvalidStateTransition),
+		errors.Is(err, domain.ErrNonconformanceConflict),
+		errors.Is(err, domain.ErrCAPAConflict):
+		return http.StatusConflict
+	case errors.Is(err, domain.ErrInvalidMeasurement),
+		errors.Is(err, domain.ErrInvalidCharacteristic),
+		errors.Is(err, domain.ErrInvalidMeasurementFilter),
+		errors.Is(err, domain.ErrInvalidChartType),
+		errors.Is(err, domain.ErrInvalidSubgroupSize),
+		errors.Is(err, domain.ErrInsufficientBaseline),
+		errors.Is(err, domain.ErrInvalidInspectionPlan),
+		errors.Is(err, domain.ErrInvalidSamplingPlan),
+		errors.Is(err, domain.ErrIncompleteInspection),
+		errors.Is(err, domain.ErrInvalidDisposition),
+		errors.Is(err, domain.ErrInvalidCAPA),
+		errors.Is(err, domain.ErrInvalidGenealogyQuery),
+		errors.Is(err, domain.ErrInvalidAnalyticsQuery):
+		return http.StatusBadRequest
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// parseOptionalTime は RFC3339 のクエリパラメーターを読む。未指定の場合は nil
+func parseOptionalTime(r *http.Request, name string) (*time.Time, error) {
+	v := r.URL.Query().Get(name)
+	if v == "" {
+		return nil, nil
+	}
+	t, err := time.Parse(time.RFC3339, v)
+	if err != nil {
+		return nil, errors.New("Invalid " + name + " parameter")
+	}
+	return &t, nil
+}
+
+func toInspectionResponse(o application.InspectionOutput) InspectionResponse {
+	measurementResponses := make([]MeasurementResponse, len(o.Measurements))
+	for i, m := range o.Measurements {
+		measurementResponses[i] = toMeasurementResponse(m)
+	}
+
+	response := InspectionResponse{
+		ID:                 o.ID,
+		ProductionOrderID:  o.ProductionOrderID,
+		LotNumber:          o.LotNumber,
+		InspectorID:        o.InspectorID,
+		Status:             o.Status,
+		FinalResult:        o.FinalResult,
+		Measurements:       measurementResponses,
+		CreatedAt:          o.CreatedAt.Format("2006-01-02T15:04:05Z"),
+		PlanID:                 o.PlanID,
+		Operation:              o.Operation,
+		LotSize:                o.LotSize,
+		NonconformingCount:     o.NonconformingCount,
+		MissingCharacteristics: o.MissingCharacteristics,
+		FailureReason:          o.FailureReason,
+		NonconformanceID:       o.NonconformanceID,
+		MachineID:              o.MachineID,
+	}
+	if o.Sampling != nil {
+		sampling := toSamplingPlanResponse(o.Sampling)
+		response.Sampling = &sampling
+	}
+	if o.Manufacturing != nil {
+		manufacturing := toManufacturingRecordResponse(*o.Manufacturing)
+		response.Manufacturing = &manufacturing
+	}
+	return response
+}
+
+func toMeasurementInputs(requests []MeasurementRequest) []application.MeasurementInput {
+	measurements := make([]application.MeasurementInput, len(requests))
+	for i, m := range requests {
+		measurements[i] = application.MeasurementInput{
+			ParameterName:         m.ParameterName,
+			MeasuredValue:         m.MeasuredValue,
+			TargetValue:           m.TargetValue,
+			Tolerance:             m.Tolerance,
+			Unit:                  m.Unit,
+			CharacteristicType:    m.CharacteristicType,
+			UpperDeviation:        m.UpperDeviation,
+			LowerDeviation:        m.LowerDeviation,
+			UpperLimit:            m.UpperLimit,
+			LowerLimit:            m.LowerLimit,
+			MaterialCondition:     m.MaterialCondition,
+			MaterialConditionSize: m.MaterialConditionSize,
+			FeatureOfSize:         m.FeatureOfSize,
+			ActualSize:            m.ActualSize,
+			SampleNumber:          m.SampleNumber,
+			InstrumentID:          m.InstrumentID,
+			MeasuredAt:            m.MeasuredAt,
+		}
+	}
+	return measurements
+}
+
+func toMeasurementResponse(m application.MeasurementOutput) MeasurementResponse {
+	response := MeasurementResponse{
+		ParameterName:      m.ParameterName,
+		MeasuredValue:      m.MeasuredValue,
+		TargetValue:        m.TargetValue,
+		Tolerance:          m.Tolerance,
+		Unit:               m.Unit,
+		Pass:               m.Pass,
+		CharacteristicType: m.CharacteristicType,
+		LowerLimit:         m.LowerLimit,
+		UpperLimit:         m.UpperLimit,
+		MaterialCondition:  m.MaterialCondition,
+		BonusTolerance:     m.BonusTolerance,
+		SampleNumber:       m.SampleNumber,
+		InstrumentID:       m.InstrumentID,
+	}
+	if !m.MeasuredAt.IsZero() {
+		response.MeasuredAt = m.MeasuredAt.Format("2006-01-02T15:04:05Z")
+	}
+	return response
+}
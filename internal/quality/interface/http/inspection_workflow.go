@@ -0,0 +1,89 @@
+package http
+
+import (
+	"encoding/json"
+	"goNexttask/internal/quality/application"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// RecordMeasurementsRequest は 1 個のサンプル（sampleNumber）を 1 台の測定器で測った寸法。
+// measuredAt（RFC3339）を省略すると登録時刻
+type RecordMeasurementsRequest struct {
+	InstrumentID string               `json:"instrumentId"`
+	MeasuredAt   time.Time            `json:"measuredAt"`
+	SampleNumber int                  `json:"sampleNumber,omitempty"`
+	Measurements []MeasurementRequest `json:"measurements"`
+}
+
+type FailInspectionRequest struct {
+	Reason string `json:"reason"`
+}
+
+// RecordMeasurements は検査中の検査に測定を追加する
+func (h *QualityHandler) RecordMeasurements(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+
+	var req RecordMeasurementsRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	output, err := h.useCase.RecordMeasurements(r.Context(), application.RecordMeasurementsInput{
+		InspectionID: vars["id"],
+		InstrumentID: req.InstrumentID,
+		MeasuredAt:   req.MeasuredAt,
+		SampleNumber: req.SampleNumber,
+		Measurements: toMeasurementInputs(req.Measurements),
+	})
+	if err != nil {
+		http.Error(w, err.Error(), errorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(toInspectionResponse(*output))
+}
+
+// CompleteInspection は検査中の検査の合否を判定して完了する
+func (h *QualityHandler) CompleteInspection(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+
+	output, err := h.useCase.CompleteInspection(r.Context(), vars["id"])
+	if err != nil {
+		http.Error(w, err.Error(), errorStatus(err))
+		return
+	}
+
+	response := toInspectionResponse(*output)
+	response.SPCViolations = toRuleViolationResponses(output.SPCViolations)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// FailInspection は検査中の検査を中止する
+func (h *QualityHandler) FailInspection(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+
+	var req FailInspectionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	output, err := h.useCase.FailInspection(r.Context(), application.FailInspectionInput{
+		InspectionID: vars["id"],
+		Reason:       req.Reason,
+	})
+	if err != nil {
+		http.Error(w, err.Error(), errorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(toInspectionResponse(*output))
+}
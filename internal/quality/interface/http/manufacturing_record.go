@@ -0,0 +1,83 @@
+package http
+
+import (
+	"goNexttask/internal/quality/application"
+)
+
+// ManufacturingRecordResponse は製品を加工したジョブのプログラム・機械と、その時点の工具・オフセット。
+// snapshot が false の記録は照会時に解決したもので、工具・オフセットを含まない
+type ManufacturingRecordResponse struct {
+	NCJobID        string                   `json:"ncJobId"`
+	ProgramID      string                   `json:"programId"`
+	ProgramName    string                   `json:"programName"`
+	ProgramVersion string                   `json:"programVersion"`
+	ProgramHash    string                   `json:"programHash"`
+	MachineID      string                   `json:"machineId"`
+	JobStartedAt   string                   `json:"jobStartedAt"`
+	Tools          []ToolSnapshotResponse   `json:"tools"`
+	Offsets        []OffsetSnapshotResponse `json:"offsets"`
+	Snapshot       bool                     `json:"snapshot"`
+	CapturedAt     string                   `json:"capturedAt,omitempty"`
+}
+
+type ToolSnapshotResponse struct {
+	ToolNumber   int     `json:"toolNumber"`
+	Description  string  `json:"description,omitempty"`
+	AssemblyID   string  `json:"assemblyId"`
+	AssemblyName string  `json:"assemblyName"`
+	CutTimeSec   float64 `json:"cutTimeSec"`
+	PartCount    int     `json:"partCount"`
+	LoadedAt     string  `json:"loadedAt"`
+}
+
+type OffsetSnapshotResponse struct {
+	Register string             `json:"register"`
+	Values   map[string]float64 `json:"values"`
+	Version  int                `json:"version"`
+	ChangeID string             `json:"changeId,omitempty"`
+}
+
+func toManufacturingRecordResponse(o application.ManufacturingRecordOutput) ManufacturingRecordResponse {
+	offsets := make([]OffsetSnapshotResponse, len(o.Offsets))
+	for i, offset := range o.Offsets {
+		offsets[i] = OffsetSnapshotResponse{
+			Register: offset.Register,
+			Values:   offset.Values,
+			Version:  offset.Version,
+			ChangeID: offset.ChangeID,
+		}
+	}
+
+	response := ManufacturingRecordResponse{
+		NCJobID:        o.NCJobID,
+		ProgramID:      o.ProgramID,
+		ProgramName:    o.ProgramName,
+		ProgramVersion: o.ProgramVersion,
+		ProgramHash:    o.ProgramHash,
+		MachineID:      o.MachineID,
+		JobStartedAt:   o.JobStartedAt.Format("2006-01-02T15:04:05Z"),
+		Tools:          toToolSnapshotResponses(o.Tools),
+		Offsets:        offsets,
+		Snapshot:       o.Snapshot,
+	}
+	if o.Snapshot {
+		response.CapturedAt = o.CapturedAt.Format("2006-01-02T15:04:05Z")
+	}
+	return response
+}
+
+func toToolSnapshotResponses(tools []application.ToolSnapshotOutput) []ToolSnapshotResponse {
+	responses := make([]ToolSnapshotResponse, len(tools))
+	for i, t := range tools {
+		responses[i] = ToolSnapshotResponse{
+			ToolNumber:   t.ToolNumber,
+			Description:  t.Description,
+			AssemblyID:   t.AssemblyID,
+			AssemblyName: t.AssemblyName,
+			CutTimeSec:   t.CutTimeSec,
+			PartCount:    t.PartCount,
+			LoadedAt:     t.LoadedAt.Format("2006-01-02T15:04:05Z"),
+		}
+	}
+	return responses
+}
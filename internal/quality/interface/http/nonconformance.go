@@ -0,0 +1,214 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"goNexttask/internal/quality/application"
+	"goNexttask/pkg/auth"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// mrbRoles は不適合処置を承認・差し戻しできるロール（MRB）
+var mrbRoles = []string{"admin", "quality"}
+
+// ProposeDispositionRequest の数量の合計は NCR の対象数量と一致させる
+type ProposeDispositionRequest struct {
+	Dispositions []DispositionLineRequest `json:"dispositions"`
+}
+
+type DispositionLineRequest struct {
+	// Disposition は use_as_is・rework・scrap・return_to_supplier
+	Disposition string `json:"disposition"`
+	Quantity    int    `json:"quantity"`
+}
+
+// ReviewDispositionRequest の comment は差し戻しの場合は必須
+type ReviewDispositionRequest struct {
+	Comment string `json:"comment"`
+}
+
+type NonconformanceResponse struct {
+	ID                string                    `json:"id"`
+	InspectionID      string                    `json:"inspectionId"`
+	ProductionOrderID string                    `json:"productionOrderId"`
+	LotNumber         string                    `json:"lotNumber"`
+	AffectedQuantity  int                       `json:"affectedQuantity"`
+	Defects           []DefectResponse          `json:"defects"`
+	Status            string                    `json:"status"`
+	Dispositions      []DispositionLineResponse `json:"dispositions"`
+	ProposedBy        string                    `json:"proposedBy,omitempty"`
+	ProposedAt        string                    `json:"proposedAt,omitempty"`
+	ApprovedBy        string                    `json:"approvedBy,omitempty"`
+	ApprovedAt        string                    `json:"approvedAt,omitempty"`
+	MRBComment        string                    `json:"mrbComment,omitempty"`
+	CreatedAt         string                    `json:"createdAt"`
+	UpdatedAt         string                    `json:"updatedAt"`
+}
+
+type DefectResponse struct {
+	Code          string `json:"code"`
+	ParameterName string `json:"parameterName"`
+	Count         int    `json:"count"`
+}
+
+type DispositionLineResponse struct {
+	Disposition string `json:"disposition"`
+	Quantity    int    `json:"quantity"`
+}
+
+// ListNonconformances は NCR を起票の新しい順に返す。status・lot で絞り込める
+func (h *QualityHandler) ListNonconformances(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	outputs, err := h.useCase.ListNonconformances(r.Context(), query.Get("status"), query.Get("lot"))
+	if err != nil {
+		http.Error(w, err.Error(), errorStatus(err))
+		return
+	}
+
+	response := make([]NonconformanceResponse, len(outputs))
+	for i, output := range outputs {
+		response[i] = toNonconformanceResponse(output)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+func (h *QualityHandler) GetNonconformance(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+
+	output, err := h.useCase.GetNonconformance(r.Context(), vars["id"])
+	if err != nil {
+		http.Error(w, err.Error(), errorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(toNonconformanceResponse(output))
+}
+
+// ProposeDisposition は NCR の処置を提案する。提案者はログインユーザー
+func (h *QualityHandler) ProposeDisposition(w http.ResponseWriter, r *http.Request) {
+	claims, ok := auth.GetUserFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	vars := mux.Vars(r)
+
+	var req ProposeDispositionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	lines := make([]application.DispositionLineInput, len(req.Dispositions))
+	for i, line := range req.Dispositions {
+		lines[i] = application.DispositionLineInput{
+			Disposition: line.Disposition,
+			Quantity:    line.Quantity,
+		}
+	}
+
+	output, err := h.useCase.ProposeDisposition(r.Context(), application.ProposeDispositionInput{
+		NonconformanceID: vars["id"],
+		Dispositions:     lines,
+		ProposedBy:       claims.Email,
+	})
+	if err != nil {
+		http.Error(w, err.Error(), errorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(toNonconformanceResponse(output))
+}
+
+// ApproveDisposition は MRB（admin / quality）が処置を承認し、製造オーダーの数量に反映する
+func (h *QualityHandler) ApproveDisposition(w http.ResponseWriter, r *http.Request) {
+	h.reviewDisposition(w, r, h.useCase.ApproveDisposition)
+}
+
+// RejectDisposition は MRB（admin / quality）が処置を差し戻す
+func (h *QualityHandler) RejectDisposition(w http.ResponseWriter, r *http.Request) {
+	h.reviewDisposition(w, r, h.useCase.RejectDisposition)
+}
+
+// reviewDisposition は承認・差し戻しに共通する権限の確認とリクエストの読み込みを行う
+func (h *QualityHandler) reviewDisposition(w http.ResponseWriter, r *http.Request, review func(context.Context, application.ReviewDispositionInput) (*application.NonconformanceOutput, error)) {
+	claims, ok := auth.GetUserFromContext(r.Context())
+	if !ok || !auth.HasAnyRole(r.Context(), mrbRoles...) {
+		http.Error(w, "Only the MRB (quality) can review dispositions", http.StatusForbidden)
+		return
+	}
+
+	vars := mux.Vars(r)
+
+	var req ReviewDispositionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	output, err := review(r.Context(), application.ReviewDispositionInput{
+		NonconformanceID: vars["id"],
+		ReviewedBy:       claims.Email,
+		Comment:          req.Comment,
+	})
+	if err != nil {
+		http.Error(w, err.Error(), errorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(toNonconformanceResponse(output))
+}
+
+func toNonconformanceResponse(ncr *application.NonconformanceOutput) NonconformanceResponse {
+	defects := make([]DefectResponse, len(ncr.Defects))
+	for i, d := range ncr.Defects {
+		defects[i] = DefectResponse{
+			Code:          d.Code,
+			ParameterName: d.ParameterName,
+			Count:         d.Count,
+		}
+	}
+	dispositions := make([]DispositionLineResponse, len(ncr.Dispositions))
+	for i, line := range ncr.Dispositions {
+		dispositions[i] = DispositionLineResponse{
+			Disposition: line.Disposition,
+			Quantity:    line.Quantity,
+		}
+	}
+
+	return NonconformanceResponse{
+		ID:                ncr.ID,
+		InspectionID:      ncr.InspectionID,
+		ProductionOrderID: ncr.ProductionOrderID,
+		LotNumber:         ncr.LotNumber,
+		AffectedQuantity:  ncr.AffectedQuantity,
+		Defects:           defects,
+		Status:            ncr.Status,
+		Dispositions:      dispositions,
+		ProposedBy:        ncr.ProposedBy,
+		ProposedAt:        formatOptionalTime(ncr.ProposedAt),
+		ApprovedBy:        ncr.ApprovedBy,
+		ApprovedAt:        formatOptionalTime(ncr.ApprovedAt),
+		MRBComment:        ncr.MRBComment,
+		CreatedAt:         ncr.CreatedAt.Format("2006-01-02T15:04:05Z"),
+		UpdatedAt:         ncr.UpdatedAt.Format("2006-01-02T15:04:05Z"),
+	}
+}
+
+// formatOptionalTime は未設定の日時を空文字にする
+func formatOptionalTime(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+	return t.Format("2006-01-02T15:04:05Z")
+}
@@ -0,0 +1,248 @@
+package http
+
+import (
+	"encoding/json"
+	"goNexttask/internal/quality/application"
+	"goNexttask/pkg/auth"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+)
+
+// inspectionPlanEditorRoles は検査計画を登録できるロール
+var inspectionPlanEditorRoles = []string{"admin", "quality"}
+
+// CreateInspectionPlanRequest は部品・工程の検査計画。aql は ISO 2859-1 の優先数、inspectionLevel は省略時 II
+type CreateInspectionPlanRequest struct {
+	PartID          string                      `json:"partId"`
+	Operation       string                      `json:"operation"`
+	AQL             float64                     `json:"aql"`
+	InspectionLevel string                      `json:"inspectionLevel,omitempty"`
+	Characteristics []PlanCharacteristicRequest `json:"characteristics"`
+}
+
+// PlanCharacteristicRequest の規格は MeasurementRequest と同じ優先順で解釈する
+type PlanCharacteristicRequest struct {
+	ParameterName         string   `json:"parameterName"`
+	CharacteristicType    string   `json:"characteristicType,omitempty"`
+	TargetValue           float64  `json:"targetValue"`
+	Tolerance             float64  `json:"tolerance"`
+	UpperDeviation        *float64 `json:"upperDeviation,omitempty"`
+	LowerDeviation        *float64 `json:"lowerDeviation,omitempty"`
+	UpperLimit            *float64 `json:"upperLimit,omitempty"`
+	LowerLimit            *float64 `json:"lowerLimit,omitempty"`
+	MaterialCondition     string   `json:"materialCondition,omitempty"`
+	MaterialConditionSize *float64 `json:"materialConditionSize,omitempty"`
+	FeatureOfSize         string   `json:"featureOfSize,omitempty"`
+	Unit                  string   `json:"unit"`
+	Instrument            string   `json:"instrument,omitempty"`
+	Frequency             string   `json:"frequency,omitempty"`
+	Required              bool     `json:"required"`
+}
+
+type InspectionPlanResponse struct {
+	ID              string                       `json:"id"`
+	PartID          string                       `json:"partId"`
+	Operation       string                       `json:"operation"`
+	Revision        int                          `json:"revision"`
+	AQL             float64                      `json:"aql"`
+	InspectionLevel string                       `json:"inspectionLevel"`
+	Characteristics []PlanCharacteristicResponse `json:"characteristics"`
+	CreatedBy       string                       `json:"createdBy"`
+	CreatedAt       string                       `json:"createdAt"`
+}
+
+type PlanCharacteristicResponse struct {
+	ParameterName         string   `json:"parameterName"`
+	CharacteristicType    string   `json:"characteristicType"`
+	TargetValue           float64  `json:"targetValue"`
+	Tolerance             float64  `json:"tolerance"`
+	UpperDeviation        *float64 `json:"upperDeviation,omitempty"`
+	LowerDeviation        *float64 `json:"lowerDeviation,omitempty"`
+	UpperLimit            *float64 `json:"upperLimit,omitempty"`
+	LowerLimit            *float64 `json:"lowerLimit,omitempty"`
+	MaterialCondition     string   `json:"materialCondition,omitempty"`
+	MaterialConditionSize *float64 `json:"materialConditionSize,omitempty"`
+	FeatureOfSize         string   `json:"featureOfSize,omitempty"`
+	Unit                  string   `json:"unit"`
+	Instrument            string   `json:"instrument,omitempty"`
+	Frequency             string   `json:"frequency"`
+	Required              bool     `json:"required"`
+}
+
+type SamplingPlanResponse struct {
+	LotSize         int     `json:"lotSize"`
+	AQL             float64 `json:"aql"`
+	InspectionLevel string  `json:"inspectionLevel"`
+	CodeLetter      string  `json:"codeLetter"`
+	SampleSize      int     `json:"sampleSize"`
+	Accept          int     `json:"accept"`
+	Reject          int     `json:"reject"`
+}
+
+// CreateInspectionPlan は検査計画を登録する（admin / quality）。同じ部品・工程では次の版になる
+func (h *QualityHandler) CreateInspectionPlan(w http.ResponseWriter, r *http.Request) {
+	claims, ok := auth.GetUserFromContext(r.Context())
+	if !ok || !auth.HasAnyRole(r.Context(), inspectionPlanEditorRoles...) {
+		http.Error(w, "Only quality engineers can define inspection plans", http.StatusForbidden)
+		return
+	}
+
+	var req CreateInspectionPlanRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	characteristics := make([]application.PlanCharacteristicInput, len(req.Characteristics))
+	for i, c := range req.Characteristics {
+		characteristics[i] = application.PlanCharacteristicInput{
+			ParameterName:         c.ParameterName,
+			CharacteristicType:    c.CharacteristicType,
+			TargetValue:           c.TargetValue,
+			Tolerance:             c.Tolerance,
+			UpperDeviation:        c.UpperDeviation,
+			LowerDeviation:        c.LowerDeviation,
+			UpperLimit:            c.UpperLimit,
+			LowerLimit:            c.LowerLimit,
+			MaterialCondition:     c.MaterialCondition,
+			MaterialConditionSize: c.MaterialConditionSize,
+			FeatureOfSize:         c.FeatureOfSize,
+			Unit:                  c.Unit,
+			Instrument:            c.Instrument,
+			Frequency:             c.Frequency,
+			Required:              c.Required,
+		}
+	}
+
+	output, err := h.useCase.CreateInspectionPlan(r.Context(), application.CreateInspectionPlanInput{
+		PartID:          req.PartID,
+		Operation:       req.Operation,
+		AQL:             req.AQL,
+		InspectionLevel: req.InspectionLevel,
+		Characteristics: characteristics,
+		CreatedBy:       claims.Email,
+	})
+	if err != nil {
+		http.Error(w, err.Error(), errorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(toInspectionPlanResponse(output))
+}
+
+func (h *QualityHandler) GetInspectionPlan(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+
+	output, err := h.useCase.GetInspectionPlan(r.Context(), vars["id"])
+	if err != nil {
+		http.Error(w, err.Error(), errorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(toInspectionPlanResponse(output))
+}
+
+// ListInspectionPlans は部品の検査計画を工程・版の順に返す
+func (h *QualityHandler) ListInspectionPlans(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	partID := query.Get("part")
+	if partID == "" {
+		http.Error(w, "Part is required", http.StatusBadRequest)
+		return
+	}
+
+	outputs, err := h.useCase.ListInspectionPlans(r.Context(), partID, query.Get("operation"))
+	if err != nil {
+		http.Error(w, err.Error(), errorStatus(err))
+		return
+	}
+
+	response := make([]InspectionPlanResponse, len(outputs))
+	for i, output := range outputs {
+		response[i] = toInspectionPlanResponse(output)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// GetSamplingPlan はロットサイズ・AQL・検査水準からサンプル数と合格判定個数を返す
+func (h *QualityHandler) GetSamplingPlan(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	lotSize, err := strconv.Atoi(query.Get("lotSize"))
+	if err != nil {
+		http.Error(w, "Invalid lotSize parameter", http.StatusBadRequest)
+		return
+	}
+	aql, err := strconv.ParseFloat(query.Get("aql"), 64)
+	if err != nil {
+		http.Error(w, "Invalid aql parameter", http.StatusBadRequest)
+		return
+	}
+
+	output, err := h.useCase.GetSamplingPlan(r.Context(), application.SamplingPlanInput{
+		LotSize:         lotSize,
+		AQL:             aql,
+		InspectionLevel: query.Get("level"),
+	})
+	if err != nil {
+		http.Error(w, err.Error(), errorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(toSamplingPlanResponse(output))
+}
+
+func toInspectionPlanResponse(plan *application.InspectionPlanOutput) InspectionPlanResponse {
+	characteristics := make([]PlanCharacteristicResponse, len(plan.Characteristics))
+	for i, c := range plan.Characteristics {
+		characteristics[i] = PlanCharacteristicResponse{
+			ParameterName:         c.ParameterName,
+			CharacteristicType:    c.CharacteristicType,
+			TargetValue:           c.TargetValue,
+			Tolerance:             c.Tolerance,
+			UpperDeviation:        c.UpperDeviation,
+			LowerDeviation:        c.LowerDeviation,
+			UpperLimit:            c.UpperLimit,
+			LowerLimit:            c.LowerLimit,
+			MaterialCondition:     c.MaterialCondition,
+			MaterialConditionSize: c.MaterialConditionSize,
+			FeatureOfSize:         c.FeatureOfSize,
+			Unit:                  c.Unit,
+			Instrument:            c.Instrument,
+			Frequency:             c.Frequency,
+			Required:              c.Required,
+		}
+	}
+
+	return InspectionPlanResponse{
+		ID:              plan.ID,
+		PartID:          plan.PartID,
+		Operation:       plan.Operation,
+		Revision:        plan.Revision,
+		AQL:             plan.AQL,
+		InspectionLevel: plan.InspectionLevel,
+		Characteristics: characteristics,
+		CreatedBy:       plan.CreatedBy,
+		CreatedAt:       plan.CreatedAt.Format("2006-01-02T15:04:05Z"),
+	}
+}
+
+func toSamplingPlanResponse(plan *application.SamplingPlanOutput) SamplingPlanResponse {
+	return SamplingPlanResponse{
+		LotSize:         plan.LotSize,
+		AQL:             plan.AQL,
+		InspectionLevel: plan.InspectionLevel,
+		CodeLetter:      plan.CodeLetter,
+		SampleSize:      plan.SampleSize,
+		Accept:          plan.Accept,
+		Reject:          plan.Reject,
+	}
+}
@@ -0,0 +1,219 @@
+package http
+
+import (
+	"encoding/json"
+	"goNexttask/internal/quality/application"
+	"goNexttask/pkg/auth"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// controlLimitApproverRoles は管理限界を固定できるロール
+var controlLimitApproverRoles = []string{"admin", "quality"}
+
+type ControlLimitsResponse struct {
+	ParameterName    string  `json:"parameterName"`
+	PartID           string  `json:"partId,omitempty"`
+	ChartType        string  `json:"chartType"`
+	SubgroupSize     int     `json:"subgroupSize"`
+	Center           float64 `json:"centerLine"`
+	UCL              float64 `json:"ucl"`
+	LCL              float64 `json:"lcl"`
+	DispersionCenter float64 `json:"dispersionCenterLine,omitempty"`
+	DispersionUCL    float64 `json:"dispersionUcl,omitempty"`
+	DispersionLCL    float64 `json:"dispersionLcl,omitempty"`
+	BaselineFrom     string  `json:"baselineFrom"`
+	BaselineTo       string  `json:"baselineTo"`
+	BaselinePoints   int     `json:"baselinePoints"`
+	FrozenBy         string  `json:"frozenBy,omitempty"`
+	FrozenAt         string  `json:"frozenAt,omitempty"`
+}
+
+type ChartPointResponse struct {
+	Index         int      `json:"index"`
+	InspectionIDs []string `json:"inspectionIds"`
+	MeasuredAt    string   `json:"measuredAt"`
+	SampleSize    int      `json:"sampleSize"`
+	Value         float64  `json:"value"`
+	Dispersion    *float64 `json:"dispersion,omitempty"`
+}
+
+type RuleViolationResponse struct {
+	ParameterName string  `json:"parameterName"`
+	ChartType     string  `json:"chartType"`
+	Chart         string  `json:"chart"`
+	Rule          int     `json:"rule"`
+	Description   string  `json:"description"`
+	StartIndex    int     `json:"startIndex"`
+	PointIndex    int     `json:"pointIndex"`
+	Value         float64 `json:"value"`
+}
+
+type ControlChartResponse struct {
+	ParameterName  string                  `json:"parameterName"`
+	PartID         string                  `json:"partId,omitempty"`
+	ChartType      string                  `json:"chartType"`
+	SubgroupSize   int                     `json:"subgroupSize"`
+	Frozen         bool                    `json:"frozen"`
+	Limits         *ControlLimitsResponse  `json:"limits"`
+	Points         []ChartPointResponse    `json:"points"`
+	PendingSamples int                     `json:"pendingSamples"`
+	Violations     []RuleViolationResponse `json:"violations"`
+	Warnings       []string                `json:"warnings"`
+}
+
+// FreezeControlLimitsRequest はベースライン期間（RFC3339、to は含まない）から管理限界を固定する要求
+type FreezeControlLimitsRequest struct {
+	PartID       string    `json:"partId,omitempty"`
+	ChartType    string    `json:"chartType"`
+	SubgroupSize int       `json:"subgroupSize,omitempty"`
+	BaselineFrom time.Time `json:"baselineFrom"`
+	BaselineTo   time.Time `json:"baselineTo"`
+}
+
+// GetControlChart はパラメーターの管理図（打点・管理限界・判定ルールへの該当）を返す
+func (h *QualityHandler) GetControlChart(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	query := r.URL.Query()
+
+	from, err := parseOptionalTime(r, "from")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	to, err := parseOptionalTime(r, "to")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	subgroupSize := 0
+	if v := query.Get("subgroupSize"); v != "" {
+		if subgroupSize, err = strconv.Atoi(v); err != nil {
+			http.Error(w, "Invalid subgroupSize parameter", http.StatusBadRequest)
+			return
+		}
+	}
+
+	output, err := h.useCase.GetControlChart(r.Context(), application.ControlChartInput{
+		ParameterName: vars["parameter"],
+		PartID:        query.Get("part"),
+		ChartType:     query.Get("chart"),
+		SubgroupSize:  subgroupSize,
+		From:          from,
+		To:            to,
+	})
+	if err != nil {
+		http.Error(w, err.Error(), errorStatus(err))
+		return
+	}
+
+	response := ControlChartResponse{
+		ParameterName:  output.ParameterName,
+		PartID:         output.PartID,
+		ChartType:      output.ChartType,
+		SubgroupSize:   output.SubgroupSize,
+		Frozen:         output.Frozen,
+		Points:         make([]ChartPointResponse, len(output.Points)),
+		PendingSamples: output.PendingSamples,
+		Violations:     toRuleViolationResponses(output.Violations),
+		Warnings:       output.Warnings,
+	}
+	if response.Warnings == nil {
+		response.Warnings = []string{}
+	}
+	if output.Limits != nil {
+		limits := toControlLimitsResponse(output.Limits)
+		response.Limits = &limits
+	}
+	for i, p := range output.Points {
+		response.Points[i] = ChartPointResponse{
+			Index:         p.Index,
+			InspectionIDs: p.InspectionIDs,
+			MeasuredAt:    p.MeasuredAt.Format("2006-01-02T15:04:05Z"),
+			SampleSize:    p.SampleSize,
+			Value:         p.Value,
+			Dispersion:    p.Dispersion,
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// FreezeControlLimits はベースライン期間から管理限界を計算して固定する（admin / quality）
+func (h *QualityHandler) FreezeControlLimits(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+
+	claims, ok := auth.GetUserFromContext(r.Context())
+	if !ok || !auth.HasAnyRole(r.Context(), controlLimitApproverRoles...) {
+		http.Error(w, "Only quality engineers can freeze control limits", http.StatusForbidden)
+		return
+	}
+
+	var req FreezeControlLimitsRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	output, err := h.useCase.FreezeControlLimits(r.Context(), application.FreezeControlLimitsInput{
+		ParameterName: vars["parameter"],
+		PartID:        req.PartID,
+		ChartType:     req.ChartType,
+		SubgroupSize:  req.SubgroupSize,
+		BaselineFrom:  req.BaselineFrom,
+		BaselineTo:    req.BaselineTo,
+		FrozenBy:      claims.Email,
+	})
+	if err != nil {
+		http.Error(w, err.Error(), errorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(toControlLimitsResponse(output))
+}
+
+func toControlLimitsResponse(limits *application.ControlLimitsOutput) ControlLimitsResponse {
+	response := ControlLimitsResponse{
+		ParameterName:    limits.ParameterName,
+		PartID:           limits.PartID,
+		ChartType:        limits.ChartType,
+		SubgroupSize:     limits.SubgroupSize,
+		Center:           limits.Center,
+		UCL:              limits.UCL,
+		LCL:              limits.LCL,
+		DispersionCenter: limits.DispersionCenter,
+		DispersionUCL:    limits.DispersionUCL,
+		DispersionLCL:    limits.DispersionLCL,
+		BaselineFrom:     limits.BaselineFrom.Format("2006-01-02T15:04:05Z"),
+		BaselineTo:       limits.BaselineTo.Format("2006-01-02T15:04:05Z"),
+		BaselinePoints:   limits.BaselinePoints,
+		FrozenBy:         limits.FrozenBy,
+	}
+	if !limits.FrozenAt.IsZero() {
+		response.FrozenAt = limits.FrozenAt.Format("2006-01-02T15:04:05Z")
+	}
+	return response
+}
+
+func toRuleViolationResponses(violations []application.RuleViolationOutput) []RuleViolationResponse {
+	responses := make([]RuleViolationResponse, len(violations))
+	for i, v := range violations {
+		responses[i] = RuleViolationResponse{
+			ParameterName: v.ParameterName,
+			ChartType:     v.ChartType,
+			Chart:         v.Chart,
+			Rule:          v.Rule,
+			Description:   v.Description,
+			StartIndex:    v.StartIndex,
+			PointIndex:    v.PointIndex,
+			Value:         v.Value,
+		}
+	}
+	return responses
+}
@@ -40,4 +40,18 @@ func AuthMiddleware(jwtManager *JWTManager) func(http.Handler) http.Handler {
 func GetUserFromContext(ctx context.Context) (*Claims, bool) {
 	claims, ok := ctx.Value(UserContextKey).(*Claims)
 	return claims, ok
-}
\ No newline at end of file
+}
+
+// HasAnyRole は認証ユーザーが roles のいずれかのロールを持つか判定する
+func HasAnyRole(ctx context.Context, roles ...string) bool {
+	claims, ok := GetUserFromContext(ctx)
+	if !ok {
+		return false
+	}
+	for _, role := range roles {
+		if claims.Role == role {
+			return true
+		}
+	}
+	return false
+}
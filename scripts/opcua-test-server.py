@@ -0,0 +1,84 @@
+#!/usr/bin/env python3
+"""OPC UA コネクタ検証用のテストサーバー
+
+旋盤1台分のノードを公開し、段取り待ち → 加工 → 完了のサイクルを繰り返す。
+3サイクルごとにアラームを発生させる。
+
+    pip install asyncua
+    python3 scripts/opcua-test-server.py --port 4840
+
+公開ノード（ns は起動ログに表示される。通常 2）:
+    ns=2;s=Machine.State        String  READY / ACTIVE / STOPPED
+    ns=2;s=Machine.Program      String  実行中プログラム名
+    ns=2;s=Machine.PartCount    Int32   加工完了数
+    ns=2;s=Machine.Alarm        String  発生中アラーム（空文字はアラームなし）
+    ns=2;s=Machine.SpindleLoad  Double  主軸負荷(%)
+"""
+
+import argparse
+import asyncio
+import logging
+import random
+
+from asyncua import Server, ua
+
+NAMESPACE = "urn:goNexttask:opcua-test-server"
+
+
+async def main(port: int, cycle: float) -> None:
+    server = Server()
+    await server.init()
+    server.set_endpoint(f"opc.tcp://0.0.0.0:{port}/")
+    server.set_server_name("goNexttask OPC UA test server")
+    server.set_security_policy([ua.SecurityPolicyType.NoSecurity])
+
+    ns = await server.register_namespace(NAMESPACE)
+    machine = await server.nodes.objects.add_object(ua.NodeId("Machine", ns), "Machine")
+
+    async def variable(name, value, variant_type):
+        return await machine.add_variable(ua.NodeId(f"Machine.{name}", ns), name, value, variant_type)
+
+    state = await variable("State", "READY", ua.VariantType.String)
+    program = await variable("Program", "", ua.VariantType.String)
+    part_count = await variable("PartCount", 0, ua.VariantType.Int32)
+    alarm = await variable("Alarm", "", ua.VariantType.String)
+    spindle_load = await variable("SpindleLoad", 0.0, ua.VariantType.Double)
+
+    logging.info("namespace index=%d endpoint=opc.tcp://localhost:%d/", ns, port)
+
+    async with server:
+        count = 0
+        while True:
+            await state.write_value("READY")
+            await spindle_load.write_value(0.0)
+            await asyncio.sleep(cycle / 2)
+
+            await program.write_value("O1001")
+            await state.write_value("ACTIVE")
+            logging.info("cycle start")
+            for _ in range(int(cycle)):
+                await spindle_load.write_value(round(random.uniform(35.0, 70.0), 1))
+                await asyncio.sleep(1)
+
+            count += 1
+            if count % 3 == 0:
+                await alarm.write_value("EX1001 SPINDLE OVERLOAD")
+                await state.write_value("STOPPED")
+                logging.info("alarm raised")
+                await asyncio.sleep(cycle / 2)
+                await alarm.write_value("")
+                logging.info("alarm reset")
+            else:
+                await part_count.write_value(count, ua.VariantType.Int32)
+                logging.info("cycle complete parts=%d", count)
+
+
+if __name__ == "__main__":
+    parser = argparse.ArgumentParser(description=__doc__, formatter_class=argparse.RawDescriptionHelpFormatter)
+    parser.add_argument("--port", type=int, default=4840)
+    parser.add_argument("--cycle", type=float, default=10.0, help="1サイクルの加工時間(秒)")
+    args = parser.parse_args()
+
+    logging.basicConfig(level=logging.INFO, format="%(asctime)s %(message)s")
+    logging.getLogger("asyncua").setLevel(logging.WARNING)
+    asyncio.run(main(args.port, args.cycle))
@@ -40,15 +40,34 @@ func dropAllTables(db *sql.DB) error {
 	
 	tables := []string{
 		"measurement_results",  // 外部キー依存があるため先に削除
+		"spc_control_limits",
+		"inspection_plans",
+		"capas",
+		"nonconformance_reports",
+		"nc_deployments",
+		"machine_offsets",
+		"offset_changes",
+		"machine_alarms",
+		"alarm_catalog",
+		"signer_keys",
+		"machine_tools",
+		"tool_assemblies",
+		"tool_items",
+		"nc_jobs",
+		"machine_status_history",
+		"machine_telemetry",
+		"machine_connectors",
 		"inspections",
 		"lot_inventory",
 		"production_plans",
+		"production_quality_dispositions",
 		"production_orders",
 		"nc_programs",
 		"machines",
 		"users",
 		"schema_migrations",
 		"quality_adjustments",  // feedback.goで使用される可能性
+		"quality_alerts",
 	}
 	
 	for _, table := range tables {
@@ -153,6 +172,9 @@ func createProductionTables(db *sql.DB) error {
 		order_number VARCHAR(128) NOT NULL UNIQUE,
 		part_id VARCHAR(64) NOT NULL,
 		quantity INT NOT NULL,
+		good_quantity INT NOT NULL DEFAULT 0,
+		rework_quantity INT NOT NULL DEFAULT 0,
+		scrap_quantity INT NOT NULL DEFAULT 0,
 		status VARCHAR(32) NOT NULL CHECK (status IN ('planned', 'in_progress', 'completed', 'delayed', 'cancelled')),
 		planned_start_date TIMESTAMP NOT NULL,
 		planned_end_date TIMESTAMP NOT NULL,
@@ -185,11 +207,27 @@ func createProductionTables(db *sql.DB) error {
 		return fmt.Errorf("failed to create production_plans: %w", err)
 	}
 	log.Println("Created table: production_plans")
+	
+	// 製造オーダーへ反映した不適合処置（NCR ごとに 1 回だけ数量を反映する）
+	query3 := `
+	CREATE TABLE IF NOT EXISTS production_quality_dispositions (
+		disposition_id VARCHAR(64) PRIMARY KEY,
+		production_order_id VARCHAR(64) NOT NULL REFERENCES production_orders(id) ON DELETE CASCADE,
+		good_quantity INT NOT NULL DEFAULT 0,
+		rework_quantity INT NOT NULL DEFAULT 0,
+		scrap_quantity INT NOT NULL DEFAULT 0,
+		applied_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+	)`
+	
+	if _, err := db.Exec(query3); err != nil {
+		return fmt.Errorf("failed to create production_quality_dispositions: %w", err)
+	}
+	log.Println("Created table: production_quality_dispositions")
 	return nil
 }
 
 func createNCTables(db *sql.DB) error {
-	// NCプログラム管理
+	// NCプログラム管理（status 未指定のシードプログラムはリリース済みとして扱う）
 	query1 := `
 	CREATE TABLE IF NOT EXISTS nc_programs (
 		id VARCHAR(64) PRIMARY KEY,
@@ -197,8 +235,18 @@ func createNCTables(db *sql.DB) error {
 		part_id VARCHAR(64),
 		machine_id VARCHAR(64),
 		version VARCHAR(32) NOT NULL,
+		dialect VARCHAR(32) NOT NULL DEFAULT 'fanuc',
+		source_program_id VARCHAR(64),
 		file_hash VARCHAR(256),
+		signature TEXT,
+		signer_key_id VARCHAR(64),
+		signed_by VARCHAR(128),
+		signed_at TIMESTAMP,
 		machine_compatibility TEXT,
+		required_capabilities TEXT,
+		status VARCHAR(16) NOT NULL DEFAULT 'approved' CHECK (status IN ('draft', 'approved', 'obsolete')),
+		approved_by VARCHAR(128),
+		approved_at TIMESTAMP,
 		data TEXT NOT NULL,
 		created_by VARCHAR(128),
 		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
@@ -218,10 +266,20 @@ func createNCTables(db *sql.DB) error {
 		ip_address VARCHAR(45) NOT NULL,
 		machine_type VARCHAR(64) NOT NULL,
 		capabilities TEXT,
-		running_state VARCHAR(32) NOT NULL CHECK (running_state IN ('running', 'stopped', 'error')),
+		axis_travel_x DECIMAL(10, 3) NOT NULL DEFAULT 0,
+		axis_travel_y DECIMAL(10, 3) NOT NULL DEFAULT 0,
+		axis_travel_z DECIMAL(10, 3) NOT NULL DEFAULT 0,
+		max_spindle_speed INT NOT NULL DEFAULT 0,
+		tool_magazine_size INT NOT NULL DEFAULT 0,
+		controller_model VARCHAR(128),
+		axes TEXT,
+		control_options TEXT,
+		macros TEXT,
+		running_state VARCHAR(32) NOT NULL CHECK (running_state IN ('running', 'stopped', 'error', 'idle', 'setup', 'maintenance', 'alarm', 'offline')),
 		current_job_id VARCHAR(64),
 		last_heartbeat TIMESTAMP,
 		error_message TEXT,
+		decommissioned_at TIMESTAMP,
 		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
 		updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
 	)`
@@ -230,6 +288,262 @@ func createNCTables(db *sql.DB) error {
 		return fmt.Errorf("failed to create machines: %w", err)
 	}
 	log.Println("Created table: machines")
+	
+	// 稼働状態履歴（追記のみ。稼働率・停止時間の算出に使用）
+	query3 := `
+	CREATE TABLE IF NOT EXISTS machine_status_history (
+		id BIGSERIAL PRIMARY KEY,
+		machine_id VARCHAR(64) NOT NULL REFERENCES machines(id),
+		from_state VARCHAR(32) NOT NULL,
+		to_state VARCHAR(32) NOT NULL,
+		job_id VARCHAR(64),
+		error_message TEXT,
+		changed_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+	)`
+	
+	if _, err := db.Exec(query3); err != nil {
+		return fmt.Errorf("failed to create machine_status_history: %w", err)
+	}
+	log.Println("Created table: machine_status_history")
+	
+	// テレメトリ収集設定（MTConnect等）
+	query4 := `
+	CREATE TABLE IF NOT EXISTS machine_connectors (
+		machine_id VARCHAR(64) PRIMARY KEY REFERENCES machines(id),
+		protocol VARCHAR(32) NOT NULL,
+		endpoint VARCHAR(512) NOT NULL,
+		device_name VARCHAR(128),
+		poll_interval_ms INT NOT NULL DEFAULT 1000,
+		node_ids JSONB,
+		state_mapping JSONB,
+		enabled BOOLEAN NOT NULL DEFAULT TRUE,
+		updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+	)`
+	
+	if _, err := db.Exec(query4); err != nil {
+		return fmt.Errorf("failed to create machine_connectors: %w", err)
+	}
+	log.Println("Created table: machine_connectors")
+	
+	// 機械テレメトリ（時系列）
+	query5 := `
+	CREATE TABLE IF NOT EXISTS machine_telemetry (
+		id BIGSERIAL PRIMARY KEY,
+		machine_id VARCHAR(64) NOT NULL REFERENCES machines(id),
+		source VARCHAR(32) NOT NULL,
+		data_item VARCHAR(128) NOT NULL,
+		category VARCHAR(16) NOT NULL CHECK (category IN ('sample', 'event', 'condition')),
+		value TEXT,
+		numeric_value DOUBLE PRECISION,
+		observed_at TIMESTAMP NOT NULL
+	)`
+	
+	if _, err := db.Exec(query5); err != nil {
+		return fmt.Errorf("failed to create machine_telemetry: %w", err)
+	}
+	log.Println("Created table: machine_telemetry")
+	
+	// NCジョブ（プログラム転送から加工終了までの実行記録）
+	query6 := `
+	CREATE TABLE IF NOT EXISTS nc_jobs (
+		id VARCHAR(64) PRIMARY KEY,
+		program_id VARCHAR(64) NOT NULL REFERENCES nc_programs(id),
+		program_hash VARCHAR(256),
+		machine_id VARCHAR(64) NOT NULL REFERENCES machines(id),
+		production_order_id VARCHAR(64),
+		status VARCHAR(16) NOT NULL CHECK (status IN ('running', 'completed', 'error', 'aborted')),
+		started_at TIMESTAMP NOT NULL,
+		ended_at TIMESTAMP,
+		produced_count INT NOT NULL DEFAULT 0,
+		part_counter_start INT,
+		alarms JSONB,
+		error_message TEXT,
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+	)`
+	
+	if _, err := db.Exec(query6); err != nil {
+		return fmt.Errorf("failed to create nc_jobs: %w", err)
+	}
+	log.Println("Created table: nc_jobs")
+	
+	// プログラム展開（冪等キーと転送ログ）
+	query7 := `
+	CREATE TABLE IF NOT EXISTS nc_deployments (
+		id VARCHAR(64) PRIMARY KEY,
+		idempotency_key VARCHAR(128) NOT NULL,
+		machine_id VARCHAR(64) NOT NULL REFERENCES machines(id),
+		program_id VARCHAR(64) NOT NULL REFERENCES nc_programs(id),
+		file_hash VARCHAR(256),
+		production_order_id VARCHAR(64),
+		job_id VARCHAR(64),
+		status VARCHAR(16) NOT NULL CHECK (status IN ('queued', 'transferring', 'transferred', 'skipped', 'failed', 'cancelled')),
+		attempts JSONB,
+		bytes_sent BIGINT NOT NULL DEFAULT 0,
+		error_message TEXT,
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		completed_at TIMESTAMP,
+		updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		UNIQUE (machine_id, idempotency_key)
+	)`
+	
+	if _, err := db.Exec(query7); err != nil {
+		return fmt.Errorf("failed to create nc_deployments: %w", err)
+	}
+	log.Println("Created table: nc_deployments")
+	
+	// 工具ライブラリ（ホルダー・インサート・ソリッド工具とアセンブリ）
+	query8 := `
+	CREATE TABLE IF NOT EXISTS tool_items (
+		id VARCHAR(64) PRIMARY KEY,
+		kind VARCHAR(16) NOT NULL CHECK (kind IN ('holder', 'insert', 'cutter')),
+		code VARCHAR(128) NOT NULL,
+		description TEXT,
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+	)`
+	
+	if _, err := db.Exec(query8); err != nil {
+		return fmt.Errorf("failed to create tool_items: %w", err)
+	}
+	log.Println("Created table: tool_items")
+	
+	query9 := `
+	CREATE TABLE IF NOT EXISTS tool_assemblies (
+		id VARCHAR(64) PRIMARY KEY,
+		name VARCHAR(128) NOT NULL,
+		holder_id VARCHAR(64) REFERENCES tool_items(id),
+		cutting_item_id VARCHAR(64) NOT NULL REFERENCES tool_items(id),
+		life_cut_time_sec DOUBLE PRECISION NOT NULL DEFAULT 0,
+		life_part_count INT NOT NULL DEFAULT 0,
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+	)`
+	
+	if _, err := db.Exec(query9); err != nil {
+		return fmt.Errorf("failed to create tool_assemblies: %w", err)
+	}
+	log.Println("Created table: tool_assemblies")
+	
+	// 機械のマガジンに装着された工具と工具寿命
+	query10 := `
+	CREATE TABLE IF NOT EXISTS machine_tools (
+		machine_id VARCHAR(64) NOT NULL REFERENCES machines(id),
+		tool_number INT NOT NULL CHECK (tool_number > 0),
+		assembly_id VARCHAR(64) NOT NULL REFERENCES tool_assemblies(id),
+		life_cut_time_sec DOUBLE PRECISION NOT NULL DEFAULT 0,
+		life_part_count INT NOT NULL DEFAULT 0,
+		cut_time_sec DOUBLE PRECISION NOT NULL DEFAULT 0,
+		part_count INT NOT NULL DEFAULT 0,
+		loaded_at TIMESTAMP NOT NULL,
+		updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		PRIMARY KEY (machine_id, tool_number)
+	)`
+	
+	if _, err := db.Exec(query10); err != nil {
+		return fmt.Errorf("failed to create machine_tools: %w", err)
+	}
+	log.Println("Created table: machine_tools")
+	
+	// オフセット変更の提案・承認・反映の記録
+	query11 := `
+	CREATE TABLE IF NOT EXISTS offset_changes (
+		id VARCHAR(64) PRIMARY KEY,
+		machine_id VARCHAR(64) NOT NULL REFERENCES machines(id),
+		register VARCHAR(8) NOT NULL,
+		kind VARCHAR(8) NOT NULL CHECK (kind IN ('tool', 'work')),
+		mode VARCHAR(16) NOT NULL CHECK (mode IN ('absolute', 'incremental')),
+		offset_values JSONB NOT NULL,
+		base_version INT NOT NULL DEFAULT 0,
+		previous_values JSONB,
+		result_values JSONB,
+		status VARCHAR(16) NOT NULL DEFAULT 'proposed',
+		reason TEXT NOT NULL,
+		proposed_by VARCHAR(255) NOT NULL,
+		proposed_at TIMESTAMP NOT NULL,
+		reviewed_by VARCHAR(255),
+		reviewed_at TIMESTAMP,
+		review_comment TEXT,
+		applied_at TIMESTAMP,
+		error_message TEXT
+	)`
+	
+	if _, err := db.Exec(query11); err != nil {
+		return fmt.Errorf("failed to create offset_changes: %w", err)
+	}
+	log.Println("Created table: offset_changes")
+	
+	// 機械ごとのオフセット登録簿（工具オフセット T01〜、ワークオフセット G54〜G59）
+	query12 := `
+	CREATE TABLE IF NOT EXISTS machine_offsets (
+		machine_id VARCHAR(64) NOT NULL REFERENCES machines(id),
+		register VARCHAR(8) NOT NULL,
+		kind VARCHAR(8) NOT NULL CHECK (kind IN ('tool', 'work')),
+		offset_values JSONB NOT NULL,
+		version INT NOT NULL CHECK (version > 0),
+		change_id VARCHAR(64) REFERENCES offset_changes(id),
+		updated_by VARCHAR(255),
+		updated_at TIMESTAMP NOT NULL,
+		PRIMARY KEY (machine_id, register)
+	)`
+	
+	if _, err := db.Exec(query12); err != nil {
+		return fmt.Errorf("failed to create machine_offsets: %w", err)
+	}
+	log.Println("Created table: machine_offsets")
+	
+	// 機械のアラームの発生・解除の記録
+	query13 := `
+	CREATE TABLE IF NOT EXISTS machine_alarms (
+		id VARCHAR(64) PRIMARY KEY,
+		machine_id VARCHAR(64) NOT NULL REFERENCES machines(id),
+		code VARCHAR(64) NOT NULL DEFAULT '',
+		severity VARCHAR(16) NOT NULL CHECK (severity IN ('fault', 'warning')),
+		message TEXT NOT NULL DEFAULT '',
+		source VARCHAR(32) NOT NULL,
+		job_id VARCHAR(64) REFERENCES nc_jobs(id),
+		raised_at TIMESTAMP NOT NULL,
+		cleared_at TIMESTAMP
+	)`
+	
+	if _, err := db.Exec(query13); err != nil {
+		return fmt.Errorf("failed to create machine_alarms: %w", err)
+	}
+	log.Println("Created table: machine_alarms")
+	
+	// コントローラー機種ごとのアラーム番号の説明と推奨処置
+	query14 := `
+	CREATE TABLE IF NOT EXISTS alarm_catalog (
+		controller_model VARCHAR(128) NOT NULL,
+		code VARCHAR(64) NOT NULL,
+		description TEXT NOT NULL,
+		recommended_action TEXT,
+		updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		PRIMARY KEY (controller_model, code)
+	)`
+	
+	if _, err := db.Exec(query14); err != nil {
+		return fmt.Errorf("failed to create alarm_catalog: %w", err)
+	}
+	log.Println("Created table: alarm_catalog")
+	
+	// NCプログラムの署名に使う承認者の公開鍵（Ed25519）
+	query15 := `
+	CREATE TABLE IF NOT EXISTS signer_keys (
+		id VARCHAR(64) PRIMARY KEY,
+		owner VARCHAR(128) NOT NULL,
+		public_key TEXT NOT NULL UNIQUE,
+		status VARCHAR(16) NOT NULL CHECK (status IN ('active', 'revoked')),
+		created_by VARCHAR(128),
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		revoked_by VARCHAR(128),
+		revoked_at TIMESTAMP,
+		revoke_reason TEXT
+	)`
+	
+	if _, err := db.Exec(query15); err != nil {
+		return fmt.Errorf("failed to create signer_keys: %w", err)
+	}
+	log.Println("Created table: signer_keys")
 	return nil
 }
 
@@ -248,6 +562,13 @@ func createQualityTables(db *sql.DB) error {
 		final_result VARCHAR(16) CHECK (final_result IN ('pass', 'fail')),
 		measured_values JSONB,
 		inspection_date TIMESTAMP,
+		plan_id VARCHAR(64) NOT NULL DEFAULT '',
+		operation VARCHAR(64) NOT NULL DEFAULT '',
+		lot_size INT NOT NULL DEFAULT 0,
+		sampling_plan JSONB,
+		nonconforming_count INT NOT NULL DEFAULT 0,
+		failure_reason TEXT NOT NULL DEFAULT '',
+		manufacturing_record JSONB,
 		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
 		updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
 	)`
@@ -268,6 +589,16 @@ func createQualityTables(db *sql.DB) error {
 		tolerance DECIMAL(10, 4) NOT NULL,
 		unit VARCHAR(32) NOT NULL,
 		pass BOOLEAN NOT NULL,
+		characteristic_type VARCHAR(32) NOT NULL DEFAULT 'dimension',
+		lower_limit DECIMAL(10, 4),
+		upper_limit DECIMAL(10, 4),
+		material_condition VARCHAR(8) NOT NULL DEFAULT '' CHECK (material_condition IN ('', 'rfs', 'mmc', 'lmc')),
+		material_condition_size DECIMAL(10, 4),
+		actual_size DECIMAL(10, 4),
+		feature_of_size VARCHAR(8) NOT NULL DEFAULT '' CHECK (feature_of_size IN ('', 'external', 'internal')),
+		sample_number INT NOT NULL DEFAULT 0,
+		instrument_id VARCHAR(64) NOT NULL DEFAULT '',
+		measured_at TIMESTAMP,
 		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
 	)`
 	
@@ -275,10 +606,106 @@ func createQualityTables(db *sql.DB) error {
 		return fmt.Errorf("failed to create measurement_results: %w", err)
 	}
 	log.Println("Created table: measurement_results")
+	
+	// 管理図の固定した管理限界（パラメーター・部品・管理図の種類ごと。part_id が空の場合は全部品）
+	query3 := `
+	CREATE TABLE IF NOT EXISTS spc_control_limits (
+		parameter_name VARCHAR(128) NOT NULL,
+		part_id VARCHAR(64) NOT NULL DEFAULT '',
+		chart_type VARCHAR(16) NOT NULL CHECK (chart_type IN ('xbar_r', 'xbar_s', 'i_mr', 'p', 'np')),
+		subgroup_size INT NOT NULL CHECK (subgroup_size > 0),
+		center_line DOUBLE PRECISION NOT NULL,
+		ucl DOUBLE PRECISION NOT NULL,
+		lcl DOUBLE PRECISION NOT NULL,
+		dispersion_center_line DOUBLE PRECISION NOT NULL DEFAULT 0,
+		dispersion_ucl DOUBLE PRECISION NOT NULL DEFAULT 0,
+		dispersion_lcl DOUBLE PRECISION NOT NULL DEFAULT 0,
+		baseline_from TIMESTAMP NOT NULL,
+		baseline_to TIMESTAMP NOT NULL,
+		baseline_points INT NOT NULL,
+		frozen_by VARCHAR(255) NOT NULL DEFAULT '',
+		frozen_at TIMESTAMP NOT NULL,
+		PRIMARY KEY (parameter_name, part_id, chart_type)
+	)`
+	
+	if _, err := db.Exec(query3); err != nil {
+		return fmt.Errorf("failed to create spc_control_limits: %w", err)
+	}
+	log.Println("Created table: spc_control_limits")
+	
+	// 検査計画（部品・工程ごとに版を重ねる。characteristics は特性・規格・測定器・頻度の配列）
+	query4 := `
+	CREATE TABLE IF NOT EXISTS inspection_plans (
+		id VARCHAR(64) PRIMARY KEY,
+		part_id VARCHAR(64) NOT NULL,
+		operation VARCHAR(64) NOT NULL,
+		revision INT NOT NULL CHECK (revision > 0),
+		aql DOUBLE PRECISION NOT NULL,
+		inspection_level VARCHAR(8) NOT NULL CHECK (inspection_level IN ('S-1', 'S-2', 'S-3', 'S-4', 'I', 'II', 'III')),
+		characteristics JSONB NOT NULL,
+		created_by VARCHAR(255) NOT NULL DEFAULT '',
+		created_at TIMESTAMP NOT NULL,
+		UNIQUE (part_id, operation, revision)
+	)`
+	
+	if _, err := db.Exec(query4); err != nil {
+		return fmt.Errorf("failed to create inspection_plans: %w", err)
+	}
+	log.Println("Created table: inspection_plans")
+	
+	// 不適合報告（NCR）。不合格の検査 1 件につき 1 件、処置は MRB の承認で製造オーダーの数量に反映する
+	query5 := `
+	CREATE TABLE IF NOT EXISTS nonconformance_reports (
+		id VARCHAR(64) PRIMARY KEY,
+		inspection_id VARCHAR(64) NOT NULL UNIQUE REFERENCES inspections(id) ON DELETE CASCADE,
+		production_order_id VARCHAR(64) NOT NULL DEFAULT '',
+		lot_number VARCHAR(64) NOT NULL,
+		affected_quantity INT NOT NULL CHECK (affected_quantity >= 0),
+		defects JSONB NOT NULL,
+		status VARCHAR(16) NOT NULL CHECK (status IN ('open', 'proposed', 'closed')),
+		dispositions JSONB NOT NULL,
+		proposed_by VARCHAR(255) NOT NULL DEFAULT '',
+		proposed_at TIMESTAMP,
+		approved_by VARCHAR(255) NOT NULL DEFAULT '',
+		approved_at TIMESTAMP,
+		mrb_comment TEXT NOT NULL DEFAULT '',
+		created_at TIMESTAMP NOT NULL,
+		updated_at TIMESTAMP NOT NULL
+	)`
+	
+	if _, err := db.Exec(query5); err != nil {
+		return fmt.Errorf("failed to create nonconformance_reports: %w", err)
+	}
+	log.Println("Created table: nonconformance_reports")
+	
+	// 是正・予防処置（CAPA）。sources は紐付けた NCR・管理図のアラート、root_cause はなぜなぜ分析と要因の分類、
+	// actions は担当者・期日のある処置、verifications は有効性の確認の履歴
+	query6 := `
+	CREATE TABLE IF NOT EXISTS capas (
+		id VARCHAR(64) PRIMARY KEY,
+		title VARCHAR(255) NOT NULL,
+		description TEXT NOT NULL DEFAULT '',
+		sources JSONB NOT NULL,
+		defect_codes TEXT[] NOT NULL DEFAULT '{}',
+		status VARCHAR(16) NOT NULL CHECK (status IN ('open', 'in_progress', 'verification', 'closed')),
+		root_cause JSONB,
+		actions JSONB NOT NULL,
+		verifications JSONB NOT NULL,
+		opened_by VARCHAR(255) NOT NULL DEFAULT '',
+		created_at TIMESTAMP NOT NULL,
+		updated_at TIMESTAMP NOT NULL,
+		version INT NOT NULL DEFAULT 1
+	)`
+	
+	if _, err := db.Exec(query6); err != nil {
+		return fmt.Errorf("failed to create capas: %w", err)
+	}
+	log.Println("Created table: capas")
 	return nil
 }
 
 func createInventoryTables(db *sql.DB) error {
+	// ロットの入出庫。production_order_id のある出庫は製造オーダーへの材料の投入、入庫は製品の産出
 	query := `
 	CREATE TABLE IF NOT EXISTS lot_inventory (
 		id VARCHAR(64) PRIMARY KEY,
@@ -286,6 +713,7 @@ func createInventoryTables(db *sql.DB) error {
 		product_type VARCHAR(128) NOT NULL,
 		quantity INT NOT NULL,
 		in_out VARCHAR(8) NOT NULL CHECK (in_out IN ('in', 'out')),
+		production_order_id VARCHAR(64),
 		transaction_date TIMESTAMP NOT NULL,
 		location VARCHAR(128),
 		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
@@ -362,17 +790,41 @@ func createIndexes(db *sql.DB) error {
 		
 		// machines
 		"CREATE INDEX IF NOT EXISTS idx_machines_state ON machines(running_state)",
+		"CREATE INDEX IF NOT EXISTS idx_machine_status_history_machine ON machine_status_history(machine_id, changed_at)",
+		"CREATE INDEX IF NOT EXISTS idx_machine_telemetry_machine ON machine_telemetry(machine_id, data_item, observed_at)",
+		"CREATE INDEX IF NOT EXISTS idx_machines_active ON machines(decommissioned_at) WHERE decommissioned_at IS NULL",
+		"CREATE INDEX IF NOT EXISTS idx_nc_programs_part ON nc_programs(part_id, status)",
+		"CREATE INDEX IF NOT EXISTS idx_nc_programs_source ON nc_programs(source_program_id)",
+		"CREATE INDEX IF NOT EXISTS idx_nc_jobs_machine ON nc_jobs(machine_id, started_at)",
+		"CREATE INDEX IF NOT EXISTS idx_nc_jobs_running ON nc_jobs(machine_id) WHERE status = 'running'",
+		"CREATE INDEX IF NOT EXISTS idx_nc_jobs_order ON nc_jobs(production_order_id)",
+		"CREATE INDEX IF NOT EXISTS idx_nc_deployments_machine ON nc_deployments(machine_id, created_at)",
+		"CREATE INDEX IF NOT EXISTS idx_nc_deployments_active ON nc_deployments(created_at) WHERE status IN ('queued', 'transferring')",
+		"CREATE INDEX IF NOT EXISTS idx_nc_deployments_delivered ON nc_deployments(machine_id, completed_at) WHERE status IN ('transferred', 'skipped')",
+		"CREATE INDEX IF NOT EXISTS idx_offset_changes_machine ON offset_changes(machine_id, proposed_at)",
+		"CREATE INDEX IF NOT EXISTS idx_offset_changes_pending ON offset_changes(machine_id) WHERE status IN ('proposed', 'push_failed')",
+		"CREATE INDEX IF NOT EXISTS idx_machine_alarms_machine ON machine_alarms(machine_id, raised_at)",
+		"CREATE INDEX IF NOT EXISTS idx_machine_alarms_active ON machine_alarms(machine_id) WHERE cleared_at IS NULL",
+		"CREATE INDEX IF NOT EXISTS idx_machine_alarms_code ON machine_alarms(machine_id, code)",
+		"CREATE INDEX IF NOT EXISTS idx_signer_keys_owner ON signer_keys(owner)",
 		
 		// inspections
 		"CREATE INDEX IF NOT EXISTS idx_inspections_lot ON inspections(lot_number)",
 		"CREATE INDEX IF NOT EXISTS idx_inspections_order ON inspections(production_order_id)",
 		"CREATE INDEX IF NOT EXISTS idx_inspections_result ON inspections(result)",
 		"CREATE INDEX IF NOT EXISTS idx_inspections_date ON inspections(inspection_date)",
+		"CREATE INDEX IF NOT EXISTS idx_measurement_results_parameter ON measurement_results(parameter_name)",
+		"CREATE INDEX IF NOT EXISTS idx_quality_alerts_inspection ON quality_alerts(inspection_id)",
+		"CREATE INDEX IF NOT EXISTS idx_nonconformance_reports_status ON nonconformance_reports(status)",
+		"CREATE INDEX IF NOT EXISTS idx_nonconformance_reports_lot ON nonconformance_reports(lot_number)",
+		"CREATE INDEX IF NOT EXISTS idx_nonconformance_reports_created ON nonconformance_reports(created_at)",
+		"CREATE INDEX IF NOT EXISTS idx_capas_status ON capas(status)",
 		
 		// lot_inventory
 		"CREATE INDEX IF NOT EXISTS idx_lot_inventory_lot ON lot_inventory(lot_number)",
 		"CREATE INDEX IF NOT EXISTS idx_lot_inventory_product ON lot_inventory(product_type)",
 		"CREATE INDEX IF NOT EXISTS idx_lot_inventory_date ON lot_inventory(transaction_date)",
+		"CREATE INDEX IF NOT EXISTS idx_lot_inventory_order ON lot_inventory(production_order_id)",
 		
 		// users
 		"CREATE INDEX IF NOT EXISTS idx_users_email ON users(email)",
@@ -7,6 +7,10 @@ import (
 	"log"
 	"math"
 	"time"
+
+	qualityDomain "goNexttask/internal/quality/domain"
+
+	"github.com/google/uuid"
 )
 
 // QualityFeedbackController 品質フィードバック制御システム
@@ -63,7 +67,10 @@ func (qfc *QualityFeedbackController) ProcessInspectionFeedback(inspectionID str
 	// Cpk値の取得
 	cpk, ok := measuredData["cpk"].(float64)
 	if !ok {
-		cpk = qfc.calculateCpk(measuredData)
+		cpk, err = qfc.calculateCpk(inspection)
+		if err != nil {
+			return fmt.Errorf("failed to calculate cpk: %w", err)
+		}
 	}
 
 	// 制御誤差の計算
@@ -96,14 +103,57 @@ type CorrectiveAction struct {
 	Type       string
 	Parameters map[string]float64
 	NCProgramID string
+	MachineID   string
 	Description string
 }
 
-// calculateCpk Cpk値の計算
-func (qfc *QualityFeedbackController) calculateCpk(measuredData map[string]interface{}) float64 {
-	// 簡易的なCpk計算（実際はより複雑な統計処理が必要）
-	// ここでは仮の値を返す
-	return 1.5
+// calculateCpk ロットの測定結果からパラメーターごとのCpkを計算し、最も低い値を返す
+// 計算できない場合（データ不足・公差なし等）は目標値を返し、補正を行わない
+func (qfc *QualityFeedbackController) calculateCpk(inspection *Inspection) (float64, error) {
+	query := `
+		SELECT m.parameter_name, m.measured_value, m.target_value,
+		       CASE WHEN m.lower_limit IS NULL AND m.upper_limit IS NULL THEN m.target_value - m.tolerance ELSE m.lower_limit END,
+		       CASE WHEN m.lower_limit IS NULL AND m.upper_limit IS NULL THEN m.target_value + m.tolerance ELSE m.upper_limit END,
+		       m.unit, COALESCE(i.inspection_date, i.created_at)
+		FROM measurement_results m
+		JOIN inspections i ON i.id = m.inspection_id
+		WHERE i.lot_number = $1
+	`
+
+	rows, err := qfc.db.Query(query, inspection.LotNumber)
+	if err != nil {
+		return 0, err
+	}
+	defer rows.Close()
+
+	var samples []qualityDomain.MeasurementSample
+	for rows.Next() {
+		sample := qualityDomain.MeasurementSample{LotNumber: inspection.LotNumber}
+		if err := rows.Scan(&sample.ParameterName, &sample.Value, &sample.TargetValue,
+			&sample.LowerLimit, &sample.UpperLimit, &sample.Unit, &sample.MeasuredAt); err != nil {
+			return 0, err
+		}
+		samples = append(samples, sample)
+	}
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	cpk := qfc.targetCpk
+	found := false
+	for _, study := range qualityDomain.AnalyzeCapability(samples, 0) {
+		if study.Cpk == nil {
+			continue
+		}
+		if !found || *study.Cpk < cpk {
+			cpk = *study.Cpk
+			found = true
+		}
+	}
+	if !found {
+		log.Printf("Cpk could not be calculated for lot %s; no correction applied", inspection.LotNumber)
+	}
+	return cpk, nil
 }
 
 // calculatePIDCorrection PID制御による補正値計算
@@ -136,6 +186,7 @@ func (qfc *QualityFeedbackController) determineCorrectiveAction(
 ) CorrectiveAction {
 	action := CorrectiveAction{
 		Parameters: make(map[string]float64),
+		MachineID:  inspection.MachineID,
 	}
 	
 	// エラーの大きさに応じたアクション決定
@@ -161,6 +212,11 @@ func (qfc *QualityFeedbackController) determineCorrectiveAction(
 	var measuredData map[string]interface{}
 	json.Unmarshal(inspection.MeasuredValues, &measuredData)
 	
+	// 補正対象の工具（補正番号）
+	if toolNumber, ok := measuredData["tool_number"].(float64); ok {
+		action.Parameters["tool_number"] = toolNumber
+	}
+	
 	if failureMode, ok := measuredData["failure_mode"].(string); ok {
 		switch failureMode {
 		case "diameter_oversize":
@@ -211,18 +267,39 @@ func (qfc *QualityFeedbackController) updateNCProgram(action CorrectiveAction) e
 }
 
 // updateToolOffset 工具オフセットの更新
+// 摩耗補正はオフセット登録簿への変更提案として登録し、承認後にコントローラーへ書き込まれる
 func (qfc *QualityFeedbackController) updateToolOffset(action CorrectiveAction) error {
-	// 工具オフセットテーブルへの更新（実際のシステムではマシンコントローラーへの通信）
-	log.Printf("Updating tool wear offset: %.4fmm", action.Parameters["wear_offset"])
+	toolNumber := int(action.Parameters["tool_number"])
+	if action.MachineID == "" || toolNumber <= 0 {
+		// 補正対象の工具が特定できない場合は調整履歴にのみ記録
+		log.Printf("Tool wear offset adjustment without target tool: %.4fmm", action.Parameters["wear_offset"])
+		
+		query := `
+			INSERT INTO quality_adjustments (type, parameters, executed_at)
+			VALUES ($1, $2, CURRENT_TIMESTAMP)
+		`
+		
+		parametersJSON, _ := json.Marshal(action.Parameters)
+		_, err := qfc.db.Exec(query, action.Type, parametersJSON)
+		return err
+	}
+	
+	register := fmt.Sprintf("T%02d", toolNumber)
+	log.Printf("Proposing tool wear offset: machine=%s register=%s %.4fmm", action.MachineID, register, action.Parameters["wear_offset"])
 	
-	// ここでは仮想的な更新を記録
 	query := `
-		INSERT INTO quality_adjustments (type, parameters, executed_at)
-		VALUES ($1, $2, CURRENT_TIMESTAMP)
+		INSERT INTO offset_changes (
+			id, machine_id, register, kind, mode, offset_values, base_version,
+			status, reason, proposed_by, proposed_at
+		) VALUES (
+			$1, $2, $3, 'tool', 'incremental', $4,
+			COALESCE((SELECT version FROM machine_offsets WHERE machine_id = $2 AND register = $3), 0),
+			'proposed', $5, 'quality-feedback', CURRENT_TIMESTAMP
+		)
 	`
 	
-	parametersJSON, _ := json.Marshal(action.Parameters)
-	_, err := qfc.db.Exec(query, action.Type, parametersJSON)
+	valuesJSON, _ := json.Marshal(map[string]float64{"radiusWear": action.Parameters["wear_offset"]})
+	_, err := qfc.db.Exec(query, "ofs-"+uuid.New().String()[:8], action.MachineID, register, valuesJSON, action.Description)
 	
 	return err
 }
@@ -523,12 +523,13 @@ func seedInspections(db *sql.DB) error {
 
 // LotInventory represents a lot inventory record
 type LotInventory struct {
-	ID              string
-	LotNumber       string
-	ProductType     string
-	Quantity        int
-	InOut           string
-	TransactionDate time.Time
+	ID                string
+	LotNumber         string
+	ProductType       string
+	Quantity          int
+	InOut             string
+	ProductionOrderID string
+	TransactionDate   time.Time
 }
 
 func seedLotInventory(db *sql.DB) error {
@@ -582,14 +583,34 @@ func seedLotInventory(db *sql.DB) error {
 			InOut:           "in",
 			TransactionDate: time.Date(2024, 1, 16, 9, 30, 0, 0, time.UTC),
 		},
+		// 材料の投入（製造オーダーへの出庫）
+		{
+			ID:                "INV-2024-023",
+			LotNumber:         "MAT-SCM440H-20240110",
+			ProductType:       "原材料_SCM440H_φ50",
+			Quantity:          100,
+			InOut:             "out",
+			ProductionOrderID: "ORD-AUTO-001",
+			TransactionDate:   time.Date(2024, 1, 15, 8, 0, 0, 0, time.UTC),
+		},
+		{
+			ID:                "INV-2024-024",
+			LotNumber:         "MAT-S45C-20240112",
+			ProductType:       "原材料_S45C_φ30",
+			Quantity:          200,
+			InOut:             "out",
+			ProductionOrderID: "ORD-AUTO-002",
+			TransactionDate:   time.Date(2024, 1, 18, 8, 0, 0, 0, time.UTC),
+		},
 		// 半製品（工程間移動）
 		{
-			ID:              "INV-2024-007",
-			LotNumber:       "LOT-AUTO-20240115-01",
-			ProductType:     "半製品_シャフト_旋削完了",
-			Quantity:        100,
-			InOut:           "in",
-			TransactionDate: time.Date(2024, 1, 17, 15, 0, 0, 0, time.UTC),
+			ID:                "INV-2024-007",
+			LotNumber:         "LOT-AUTO-20240115-01",
+			ProductType:       "半製品_シャフト_旋削完了",
+			Quantity:          100,
+			InOut:             "in",
+			ProductionOrderID: "ORD-AUTO-001",
+			TransactionDate:   time.Date(2024, 1, 17, 15, 0, 0, 0, time.UTC),
 		},
 		{
 			ID:              "INV-2024-008",
@@ -633,12 +654,13 @@ func seedLotInventory(db *sql.DB) error {
 			TransactionDate: time.Date(2024, 1, 21, 10, 0, 0, 0, time.UTC),
 		},
 		{
-			ID:              "INV-2024-013",
-			LotNumber:       "LOT-AUTO-20240118-01",
-			ProductType:     "完成品_精密シャフト",
-			Quantity:        195,
-			InOut:           "in",
-			TransactionDate: time.Date(2024, 1, 25, 16, 0, 0, 0, time.UTC),
+			ID:                "INV-2024-013",
+			LotNumber:         "LOT-AUTO-20240118-01",
+			ProductType:       "完成品_精密シャフト",
+			Quantity:          195,
+			InOut:             "in",
+			ProductionOrderID: "ORD-AUTO-002",
+			TransactionDate:   time.Date(2024, 1, 25, 16, 0, 0, 0, time.UTC),
 		},
 		{
 			ID:              "INV-2024-014",
@@ -719,13 +741,13 @@ func seedLotInventory(db *sql.DB) error {
 	}
 
 	query := `INSERT INTO lot_inventory 
-		(id, lot_number, product_type, quantity, in_out, transaction_date) 
-		VALUES ($1, $2, $3, $4, $5, $6)`
+		(id, lot_number, product_type, quantity, in_out, production_order_id, transaction_date) 
+		VALUES ($1, $2, $3, $4, $5, NULLIF($6, ''), $7)`
 
 	for _, inventory := range inventories {
 		if _, err := db.Exec(query,
 			inventory.ID, inventory.LotNumber, inventory.ProductType,
-			inventory.Quantity, inventory.InOut, inventory.TransactionDate); err != nil {
+			inventory.Quantity, inventory.InOut, inventory.ProductionOrderID, inventory.TransactionDate); err != nil {
 			return fmt.Errorf("failed to insert lot inventory %s: %w", inventory.ID, err)
 		}
 	}